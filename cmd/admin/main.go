@@ -0,0 +1,416 @@
+// Command admin inspects the state of a DB in object storage without opening it.
+//
+//	go run ./cmd/admin -dir /tmp/bucket -path db filters
+//
+// Commands:
+//
+//	filters  report the number of SSTables built with each filter configuration
+//	levels   report the number of entries and tombstones in each level
+//	dump     print every entry and range tombstone of every SSTable, including
+//	         tombstones and the sequence number and creation time of each entry
+//	verify   compare the checkpoint in the manifest version given by -checkpoint
+//	         with the live DB, and report keys which diverge although they were
+//	         not written after the checkpoint. Exits with status 3 if any do.
+//
+//	go run ./cmd/admin -dir /tmp/bucket -path db -checkpoint 12 -sample 0.1 verify
+//
+// dump and verify also accept the paths of SSTables copied out of object
+// storage, which are read from the local filesystem without -dir, credentials
+// or network access. dump prints the entries and range tombstones of each
+// SSTable, and verify decodes every block of each SSTable and checks the keys
+// are sorted and agree with the SSTable info. verify exits with status 3 if any
+// SSTable is corrupt.
+//
+//	go run ./cmd/admin dump incident/wal/00000000000000000042.sst
+//	go run ./cmd/admin verify incident/compacted/*.sst
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/thanos-io/objstore"
+	"github.com/thanos-io/objstore/providers/filesystem"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/state"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory of the filesystem object store, required unless files are given")
+	dbPath := flag.String("path", "", "path of the DB within the object store")
+	checkpoint := flag.Uint64("checkpoint", 0, "manifest version of the checkpoint compared by verify")
+	start := flag.String("start", "", "first key compared by verify")
+	end := flag.String("end", "", "key before which verify stops comparing")
+	sample := flag.Float64("sample", 0, "fraction of keys compared by verify, zero compares every key")
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		if err := runFiles(flag.Arg(0), flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			if errors.Is(err, errCorrupt) {
+				os.Exit(3)
+			}
+			os.Exit(2)
+		}
+		return
+	}
+
+	if *dir == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if flag.Arg(0) == "verify" {
+		opts := slatedb.VerifyOptions{SampleRate: *sample}
+		if *start != "" {
+			opts.Start = []byte(*start)
+		}
+		if *end != "" {
+			opts.End = []byte(*end)
+		}
+		diverged, err := verify(*dir, *dbPath, *checkpoint, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+		if diverged {
+			os.Exit(3)
+		}
+		return
+	}
+
+	if err := run(*dir, *dbPath, flag.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+func run(dir string, dbPath string, command string) error {
+	bucket, err := filesystem.NewBucket(dir)
+	if err != nil {
+		return fmt.Errorf("while opening object store: %w", err)
+	}
+
+	layout, err := store.LoadLayout(context.Background(), store.NewBucketObjectStore(bucket), dbPath)
+	if err != nil {
+		return fmt.Errorf("while loading layout: %w", err)
+	}
+	manifestStore := store.NewManifestStore(dbPath, bucket)
+	manifestStore.SetLayout(layout)
+	stored, err := store.LoadStoredManifest(manifestStore)
+	if err != nil {
+		return fmt.Errorf("while loading manifest: %w", err)
+	}
+	manifest, ok := stored.Get()
+	if !ok {
+		return fmt.Errorf("no DB found at '%s'", dbPath)
+	}
+
+	switch command {
+	case "filters":
+		return printFilterConfigs(manifest.DbState())
+	case "levels":
+		return printLevelStats(manifest.DbState())
+	case "dump":
+		return dumpEntries(bucket, dbPath, layout, manifest.DbState())
+	default:
+		return fmt.Errorf("unknown command '%s'", command)
+	}
+}
+
+// printFilterConfigs prints the distribution of filter configurations across
+// the SSTables of the DB, most common first
+func printFilterConfigs(core *state.CoreStateSnapshot) error {
+	counts := slatedb.FilterConfigs(core)
+	configs := make([]slatedb.FilterConfig, 0, len(counts))
+	total := 0
+	for conf, count := range counts {
+		configs = append(configs, conf)
+		total += count
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if counts[configs[i]] != counts[configs[j]] {
+			return counts[configs[i]] > counts[configs[j]]
+		}
+		return configs[i].String() < configs[j].String()
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "POLICY\tBITS PER KEY\tSSTABLES\tPERCENT")
+	for _, conf := range configs {
+		policy := conf.Policy
+		if policy == "" {
+			policy = "none"
+		}
+		bitsPerKey := "-"
+		if conf.BitsPerKey > 0 {
+			bitsPerKey = fmt.Sprint(conf.BitsPerKey)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.1f%%\n", policy, bitsPerKey, counts[conf],
+			100*float64(counts[conf])/float64(total))
+	}
+	return w.Flush()
+}
+
+// printLevelStats prints the number of entries and tombstones in L0 and each
+// sorted run of the DB
+func printLevelStats(core *state.CoreStateSnapshot) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LEVEL\tSSTABLES\tENTRIES\tLIVE\tTOMBSTONES\tDELETE RATIO")
+	for _, level := range slatedb.LevelStatsOf(core) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.1f%%\n", level.Level, level.SSTables, level.Entries,
+			level.LiveEntries(), level.Tombstones, 100*level.DeleteRatio())
+	}
+	return w.Flush()
+}
+
+// dumpEntries prints every entry and range tombstone of L0 and each sorted run of
+// the DB, newest level first. Unlike a read, the dump includes tombstones and every
+// version of a key still held by the levels, so operators can see why a key is
+// invisible.
+func dumpEntries(bucket objstore.Bucket, dbPath string, layout config.ObjectStoreLayout, core *state.CoreStateSnapshot) error {
+	tableStore := store.NewTableStore(bucket, sstable.DefaultConfig(), dbPath)
+	tableStore.SetLayout(layout)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LEVEL\tSSTABLE\tKEY\tKIND\tSEQ\tCREATED\tVALUE")
+
+	dump := func(level string, handles []sstable.Handle) error {
+		for i := range handles {
+			if err := dumpSST(w, tableStore, level, &handles[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := dump("L0", core.L0); err != nil {
+		return err
+	}
+	for _, sr := range core.Compacted {
+		if err := dump(fmt.Sprintf("SR %d", sr.ID), sr.SSTList); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func dumpSST(w *tabwriter.Writer, tableStore *store.TableStore, level string, handle *sstable.Handle) error {
+	tombstones, err := tableStore.ReadRangeTombstones(context.Background(), handle)
+	if err != nil {
+		return fmt.Errorf("while reading range tombstones of sst '%s': %w", handle.Id.Value, err)
+	}
+	dumpRangeTombstones(w, level, handle.Id.Value, tombstones)
+
+	iter, err := sstable.NewIterator(context.Background(), handle, tableStore)
+	if err != nil {
+		return fmt.Errorf("while opening sst '%s': %w", handle.Id.Value, err)
+	}
+	return dumpIterator(w, level, handle.Id.Value, iter)
+}
+
+// dumpRangeTombstones prints the range tombstones of the SSTable named name, whose
+// key is the range [start, end) the tombstone deletes. An empty start or end
+// leaves the range unbounded.
+func dumpRangeTombstones(w *tabwriter.Writer, level string, name string, tombstones types.RangeTombstones) {
+	for _, t := range tombstones {
+		fmt.Fprintf(w, "%s\t%s\t[%q, %q)\trange tombstone\t%d\t-\t-\n", level, name,
+			block.Truncate(t.Start, 40), block.Truncate(t.End, 40), t.Seq)
+	}
+}
+
+// dumpIterator prints every entry of the SSTable named name read by iter
+func dumpIterator(w *tabwriter.Writer, level string, name string, iter *sstable.Iterator) error {
+	for {
+		entry, ok := iter.NextEntry(context.Background())
+		if !ok {
+			break
+		}
+		created := "-"
+		if !entry.Created.IsZero() {
+			created = entry.Created.UTC().Format(time.RFC3339Nano)
+		}
+		value := "-"
+		if !entry.Value.IsTombstone() {
+			value = fmt.Sprintf("%q", block.Truncate(entry.Value.Value, 40))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%q\t%s\t%d\t%s\t%s\n", level, name,
+			block.Truncate(entry.Key, 40), kindName(entry.Value.Kind), entry.Seq, created, value)
+	}
+	if warn := iter.Warnings(); warn != nil && !warn.Empty() {
+		return fmt.Errorf("while reading sst '%s': %w", name, warn)
+	}
+	return nil
+}
+
+// errCorrupt is returned by runFiles if verify finds a corrupt SSTable
+var errCorrupt = errors.New("corrupt SSTables found")
+
+// runFiles runs the command against the SSTable files at paths
+func runFiles(command string, paths []string) error {
+	switch command {
+	case "dump":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LEVEL\tSSTABLE\tKEY\tKIND\tSEQ\tCREATED\tVALUE")
+		for _, path := range paths {
+			reader, err := openSSTFile(path)
+			if err != nil {
+				return err
+			}
+			tombstones, err := reader.RangeTombstones()
+			if err != nil {
+				return fmt.Errorf("while reading range tombstones of sst '%s': %w", path, err)
+			}
+			dumpRangeTombstones(w, "-", path, tombstones)
+			iter, err := reader.Iterator()
+			if err != nil {
+				return fmt.Errorf("while opening sst '%s': %w", path, err)
+			}
+			if err := dumpIterator(w, "-", path, iter); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	case "verify":
+		corrupt := 0
+		for _, path := range paths {
+			entries, tombstones, err := verifySSTFile(path)
+			if err != nil {
+				fmt.Printf("%s: corrupt: %s\n", path, err)
+				corrupt++
+				continue
+			}
+			fmt.Printf("%s: ok, %d entries, %d tombstones\n", path, entries, tombstones)
+		}
+		if corrupt > 0 {
+			return fmt.Errorf("%w: %d of %d", errCorrupt, corrupt, len(paths))
+		}
+		return nil
+	default:
+		return fmt.Errorf("command '%s' does not accept files", command)
+	}
+}
+
+// openSSTFile reads the footer, info and index of the SSTable file at path. The
+// ID of the SSTable is not known outside of the manifest, so entries are
+// reported against the path.
+func openSSTFile(path string) (*sstable.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("while reading '%s': %w", path, err)
+	}
+	reader, err := sstable.NewReader(sstable.NewIDCompacted(ulid.ULID{}), sstable.NewBytesBlob(data))
+	if err != nil {
+		return nil, fmt.Errorf("while opening sst '%s': %w", path, err)
+	}
+	return reader, nil
+}
+
+// verifySSTFile decodes every block of the SSTable file at path, and checks the
+// entries are sorted by key, newest version first, and agree with the key range
+// and counts recorded in the SSTable info
+func verifySSTFile(path string) (entries uint64, tombstones uint64, err error) {
+	reader, err := openSSTFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	iter, err := reader.Iterator()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var first, prev types.RowEntry
+	for {
+		entry, ok := iter.NextEntry(context.Background())
+		if !ok {
+			break
+		}
+		if entries == 0 {
+			first = entry
+		} else if cmp := bytes.Compare(prev.Key, entry.Key); cmp > 0 || (cmp == 0 && prev.Seq <= entry.Seq) {
+			return 0, 0, fmt.Errorf("entry '%s' (seq %d) is out of order after '%s' (seq %d)",
+				entry.Key, entry.Seq, prev.Key, prev.Seq)
+		}
+		if entry.Value.IsTombstone() {
+			tombstones++
+		}
+		entries++
+		prev = entry
+	}
+	if err := iter.Warnings().If(); err != nil {
+		return 0, 0, err
+	}
+
+	info := reader.Info()
+	if entries > 0 && !bytes.Equal(first.Key, info.FirstKey) {
+		return 0, 0, fmt.Errorf("first key '%s' does not match info first key '%s'", first.Key, info.FirstKey)
+	}
+	// SSTables written before the last key and counts were recorded leave them empty
+	if len(info.LastKey) > 0 && !bytes.Equal(prev.Key, info.LastKey) {
+		return 0, 0, fmt.Errorf("last key '%s' does not match info last key '%s'", prev.Key, info.LastKey)
+	}
+	if info.EntryCount > 0 && (entries != info.EntryCount || tombstones != info.TombstoneCount) {
+		return 0, 0, fmt.Errorf("%d entries and %d tombstones do not match info counts %d and %d",
+			entries, tombstones, info.EntryCount, info.TombstoneCount)
+	}
+	return entries, tombstones, nil
+}
+
+// verify prints the keys which diverge between the checkpoint and the live DB,
+// and returns true if there are any
+func verify(dir string, dbPath string, checkpoint uint64, opts slatedb.VerifyOptions) (bool, error) {
+	bucket, err := filesystem.NewBucket(dir)
+	if err != nil {
+		return false, fmt.Errorf("while opening object store: %w", err)
+	}
+
+	report, err := slatedb.VerifyCheckpoint(context.Background(), bucket, dbPath, checkpoint, opts)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Printf("checkpoint manifest %d (seq %d) against live manifest %d\n",
+		report.CheckpointManifestID, report.CheckpointSeq, report.LiveManifestID)
+	fmt.Printf("compared %d keys, skipped %d keys written after the checkpoint, %d diverged\n",
+		report.Compared, report.Skipped, len(report.Divergences))
+	if len(report.Divergences) == 0 {
+		return false, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tCHECKPOINT\tLIVE")
+	format := func(value []byte) string {
+		if value == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%q", block.Truncate(value, 40))
+	}
+	for _, d := range report.Divergences {
+		fmt.Fprintf(w, "%q\t%s\t%s\n", block.Truncate(d.Key, 40), format(d.Checkpoint), format(d.Live))
+	}
+	return true, w.Flush()
+}
+
+// kindName returns the name of the kind of an entry printed by dump
+func kindName(kind types.Kind) string {
+	switch kind {
+	case types.KindTombStone:
+		return "tombstone"
+	case types.KindMerge:
+		return "merge"
+	default:
+		return "value"
+	}
+}
@@ -0,0 +1,78 @@
+// Command fixture generates the fixtures of the cross-version compatibility tests,
+// a DB for each variant of compress.Codec written with a fixed seed, holding
+// puts, deletes, merges and range deletes spread over a sorted run, L0 and the
+// WAL. Each release adds its fixtures to internal/compat/testdata/fixtures, and
+// TestReadFixtures verifies that the fixtures of every release remain readable.
+// Opening a DB writes to it, so the fixtures are not verified in place.
+//
+//	go run ./cmd/fixture -dir internal/compat/testdata/fixtures/<release>
+//
+// A subset of the variants is generated with -variants, such as -variants none,zstd.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/thanos-io/objstore/providers/filesystem"
+
+	"github.com/slatedb/slatedb-go/internal/compat"
+)
+
+func main() {
+	dir := flag.String("dir", "", "directory the fixture of each variant is written to (required)")
+	variants := flag.String("variants", "", "comma separated variants to generate, empty generates every variant")
+	flag.Parse()
+
+	if *dir == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := generate(*dir, *variants); err != nil {
+		slog.Error("generating fixtures failed", "error", err)
+		os.Exit(2)
+	}
+}
+
+func generate(dir string, names string) error {
+	variants := compat.FixtureVariants()
+	if names != "" {
+		variants = nil
+		for _, name := range strings.Split(names, ",") {
+			variant, ok := compat.FixtureVariantByName(name)
+			if !ok {
+				return fmt.Errorf("unknown variant '%s'", name)
+			}
+			variants = append(variants, variant)
+		}
+	}
+
+	ctx := context.Background()
+	for _, variant := range variants {
+		variantDir := filepath.Join(dir, variant.Name)
+		// A fixture is written to an empty directory, as the existing objects of
+		// a DB would be mixed with the objects of the new fixture
+		if entries, err := os.ReadDir(variantDir); err == nil && len(entries) > 0 {
+			return fmt.Errorf("directory '%s' is not empty", variantDir)
+		}
+		if err := os.MkdirAll(variantDir, 0o755); err != nil {
+			return err
+		}
+
+		bucket, err := filesystem.NewBucket(variantDir)
+		if err != nil {
+			return err
+		}
+		if err := compat.GenerateFixture(ctx, bucket, variant); err != nil {
+			return fmt.Errorf("while generating variant '%s': %w", variant.Name, err)
+		}
+		slog.Info("generated fixture", "variant", variant.Name, "dir", variantDir)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/thanos-io/objstore"
 
 	"github.com/slatedb/slatedb-go/slatedb"
+	"github.com/slatedb/slatedb-go/slatedb/config"
 )
 
 func main() {
@@ -16,18 +17,18 @@ func main() {
 	bucket := objstore.NewInMemBucket()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	db, _ := slatedb.Open(ctx, "/tmp/testDB", bucket)
+	db, _ := slatedb.Open(ctx, "/tmp/testDB", bucket, config.DefaultDBOptions())
 
 	key := []byte("key1")
 	value := []byte("value1")
 
-	db.Put(key, value)
+	db.Put(ctx, key, value)
 	fmt.Println("Put:", string(key), string(value))
 
 	data, _ := db.Get(ctx, key)
 	fmt.Println("Get:", string(key), string(data))
 
-	db.Delete(key)
+	db.Delete(ctx, key)
 	_, err := db.Get(ctx, key)
 	if err != nil && err.Error() == "key not found" {
 		fmt.Println("Delete:", string(key))
@@ -0,0 +1,463 @@
+// Command soak runs a mixed workload against a DB for a long period of time,
+// periodically crashing and restarting the writer. After every restart the
+// state of each key is verified against a model of the acknowledged writes, and
+// diagnostics are written when an invariant is violated.
+//
+// The workload runs in a worker sub process so a crash can be simulated by
+// killing the process, which loses any writes not yet durable.
+//
+//	go run ./cmd/soak -dir /tmp/soak -duration 4h -crash-interval 30s
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/samber/mo"
+	"github.com/thanos-io/objstore"
+	"github.com/thanos-io/objstore/providers/filesystem"
+
+	"github.com/slatedb/slatedb-go/internal/soak"
+	"github.com/slatedb/slatedb-go/slatedb"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+const dbPath = "soak"
+
+type Config struct {
+	Dir           string
+	DiagDir       string
+	Duration      time.Duration
+	CrashInterval time.Duration
+	CrashRatio    float64
+	Keys          int
+	Workers       int
+	ValueSize     int
+	Seed          int64
+
+	// Set when running as the worker sub process
+	Worker   bool
+	StartSeq uint64
+}
+
+func main() {
+	var conf Config
+	flag.StringVar(&conf.Dir, "dir", "", "directory of the filesystem object store (required)")
+	flag.StringVar(&conf.DiagDir, "diag-dir", ".", "directory diagnostics are written to when an invariant is violated")
+	flag.DurationVar(&conf.Duration, "duration", time.Hour, "how long to run the workload")
+	flag.DurationVar(&conf.CrashInterval, "crash-interval", 30*time.Second, "how long the worker runs between restarts")
+	flag.Float64Var(&conf.CrashRatio, "crash-ratio", 0.75, "fraction of restarts which kill the worker instead of closing the DB")
+	flag.IntVar(&conf.Keys, "keys", 10_000, "number of keys in the key space")
+	flag.IntVar(&conf.Workers, "workers", 8, "number of concurrent workers")
+	flag.IntVar(&conf.ValueSize, "value-size", 128, "size of the random payload of each value")
+	flag.Int64Var(&conf.Seed, "seed", time.Now().UnixNano(), "seed of the workload")
+	flag.BoolVar(&conf.Worker, "worker", false, "run as the worker sub process")
+	flag.Uint64Var(&conf.StartSeq, "start-seq", 1, "first sequence used by the worker")
+	flag.Parse()
+
+	if conf.Dir == "" || conf.Workers <= 0 || conf.Keys < conf.Workers {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var err error
+	if conf.Worker {
+		err = runWorker(ctx, conf)
+	} else {
+		err = runSupervisor(ctx, conf)
+	}
+	if err != nil {
+		slog.Error("soak test failed", "error", err)
+		os.Exit(2)
+	}
+}
+
+func newBucket(conf Config) (objstore.Bucket, error) {
+	return filesystem.NewBucket(conf.Dir)
+}
+
+// ------------------------------------------------
+// Supervisor
+// ------------------------------------------------
+
+// Diagnostics are written as JSON when an invariant is violated
+type Diagnostics struct {
+	Seed       int64             `json:"seed"`
+	Cycle      int               `json:"cycle"`
+	Violations []*soak.Violation `json:"violations"`
+	Objects    map[string]int64  `json:"objects"`
+}
+
+func runSupervisor(ctx context.Context, conf Config) error {
+	// The model starts empty, so the DB must also start empty
+	bucket, err := newBucket(conf)
+	if err != nil {
+		return err
+	}
+	var existing bool
+	err = bucket.Iter(ctx, dbPath, func(string) error {
+		existing = true
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("while listing objects: %w", err)
+	}
+	if existing {
+		return fmt.Errorf("'%s' already contains a DB, the soak test must start with an empty store", conf.Dir)
+	}
+
+	model := soak.NewModel()
+	rnd := rand.New(rand.NewSource(conf.Seed))
+
+	nextSeq := uint64(1)
+	deadline := time.Now().Add(conf.Duration)
+	slog.Info("starting soak test", "seed", conf.Seed, "dir", conf.Dir, "duration", conf.Duration)
+
+	for cycle := 1; time.Now().Before(deadline) && ctx.Err() == nil; cycle++ {
+		crash := rnd.Float64() < conf.CrashRatio
+		maxSeq, err := runWorkerProcess(ctx, conf, rnd.Int63(), nextSeq, crash, model)
+		if err != nil {
+			return fmt.Errorf("while running worker in cycle %d: %w", cycle, err)
+		}
+		nextSeq = max(nextSeq, maxSeq+1)
+
+		violations, err := verify(ctx, conf, model)
+		if err != nil {
+			return fmt.Errorf("while verifying cycle %d: %w", cycle, err)
+		}
+		if len(violations) != 0 {
+			path, err := writeDiagnostics(ctx, conf, cycle, violations)
+			if err != nil {
+				slog.Error("unable to write diagnostics", "error", err)
+			}
+			for _, v := range violations {
+				slog.Error("invariant violated", "cycle", cycle, "violation", v.Error())
+			}
+			return fmt.Errorf("%d invariant violations in cycle %d, diagnostics written to '%s'",
+				len(violations), cycle, path)
+		}
+		slog.Info("cycle verified", "cycle", cycle, "crash", crash, "writes", nextSeq-1)
+	}
+	return nil
+}
+
+// runWorkerProcess runs the worker sub process for the crash interval and applies
+// the events it reports to the model. It returns the highest sequence reported.
+func runWorkerProcess(
+	ctx context.Context,
+	conf Config,
+	seed int64,
+	startSeq uint64,
+	crash bool,
+	model *soak.Model,
+) (uint64, error) {
+	cmd := exec.Command(os.Args[0],
+		"-worker",
+		"-dir", conf.Dir,
+		"-keys", strconv.Itoa(conf.Keys),
+		"-workers", strconv.Itoa(conf.Workers),
+		"-value-size", strconv.Itoa(conf.ValueSize),
+		"-seed", strconv.FormatInt(seed, 10),
+		"-start-seq", strconv.FormatUint(startSeq, 10),
+	)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	var maxSeq uint64
+	var readErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			event, err := soak.ParseEvent(scanner.Text())
+			if err != nil {
+				readErr = err
+				continue
+			}
+			maxSeq = max(maxSeq, event.Seq)
+			model.Apply(event)
+		}
+	}()
+
+	select {
+	case <-time.After(conf.CrashInterval):
+	case <-ctx.Done():
+	}
+
+	if crash {
+		_ = cmd.Process.Kill()
+	} else {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+	}
+	<-done
+	err = cmd.Wait()
+
+	var exitErr *exec.ExitError
+	if crash && errors.As(err, &exitErr) && !exitErr.Exited() {
+		// The worker was killed as expected
+		err = nil
+	}
+	if err != nil {
+		return maxSeq, fmt.Errorf("worker exited with: %w", err)
+	}
+	return maxSeq, readErr
+}
+
+// verify reads every key of the key space and checks it against the model
+func verify(ctx context.Context, conf Config, model *soak.Model) ([]*soak.Violation, error) {
+	bucket, err := newBucket(conf)
+	if err != nil {
+		return nil, err
+	}
+	db, err := slatedb.Open(ctx, dbPath, bucket, config.DefaultDBOptions())
+	if err != nil {
+		return nil, fmt.Errorf("while opening DB: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var violations []*soak.Violation
+	for i := 0; i < conf.Keys; i++ {
+		key := soak.Key(i)
+		observed := mo.None[uint64]()
+
+		value, err := db.Get(ctx, key)
+		if err != nil && !errors.Is(err, common.ErrKeyNotFound) {
+			return nil, fmt.Errorf("while reading key '%s': %w", key, err)
+		}
+		if err == nil {
+			seq, err := soak.DecodeValue(key, value)
+			if err != nil {
+				violations = append(violations, &soak.Violation{Key: string(key), Reason: err.Error()})
+				continue
+			}
+			observed = mo.Some(seq)
+		}
+
+		if v := model.Verify(string(key), observed); v != nil {
+			violations = append(violations, v)
+		}
+	}
+	return violations, nil
+}
+
+func writeDiagnostics(ctx context.Context, conf Config, cycle int, violations []*soak.Violation) (string, error) {
+	diag := Diagnostics{
+		Seed:       conf.Seed,
+		Cycle:      cycle,
+		Violations: violations,
+		Objects:    make(map[string]int64),
+	}
+
+	// Record every object in the store, so the state can be inspected after the fact
+	bucket, err := newBucket(conf)
+	if err != nil {
+		return "", err
+	}
+	err = bucket.Iter(ctx, dbPath, func(name string) error {
+		attrs, err := bucket.Attributes(ctx, name)
+		if err != nil {
+			return err
+		}
+		diag.Objects[name] = attrs.Size
+		return nil
+	}, objstore.WithRecursiveIter())
+	if err != nil {
+		return "", fmt.Errorf("while listing objects: %w", err)
+	}
+
+	path := filepath.Join(conf.DiagDir, fmt.Sprintf("soak-%d-cycle-%d.json", conf.Seed, cycle))
+	buf, err := json.MarshalIndent(diag, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, buf, 0o644)
+}
+
+// ------------------------------------------------
+// Worker
+// ------------------------------------------------
+
+// eventWriter reports events to the supervisor, one event per line
+type eventWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (e *eventWriter) report(event soak.Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = fmt.Fprintln(e.w, event.String())
+}
+
+func runWorker(ctx context.Context, conf Config) error {
+	bucket, err := newBucket(conf)
+	if err != nil {
+		return err
+	}
+
+	db, err := slatedb.Open(ctx, dbPath, bucket, config.DefaultDBOptions())
+	if err != nil {
+		return fmt.Errorf("while opening DB: %w", err)
+	}
+
+	events := &eventWriter{w: os.Stdout}
+	var seq atomic.Uint64
+	seq.Store(conf.StartSeq - 1)
+
+	errCh := make(chan error, conf.Workers)
+	var wg sync.WaitGroup
+	for w := 0; w < conf.Workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			gen := soak.NewGenerator(conf.Seed+int64(worker), conf.Keys, conf.ValueSize)
+			for ctx.Err() == nil {
+				if err := runOp(ctx, db, gen, worker, conf.Workers, &seq, events); err != nil {
+					errCh <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errCh)
+
+	// Only reached when the supervisor asks for a clean shutdown
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("while closing DB: %w", err)
+	}
+	return <-errCh
+}
+
+// runOp runs a single randomly chosen operation on the keys owned by the worker
+func runOp(
+	ctx context.Context,
+	db *slatedb.DB,
+	gen *soak.Generator,
+	worker int,
+	workers int,
+	seq *atomic.Uint64,
+	events *eventWriter,
+) error {
+	durable := config.WriteOptions{AwaitDurable: true}
+	key := soak.Key(gen.KeyIndex(worker, workers))
+	r := gen.Intn(100)
+
+	switch {
+	case r < 50:
+		s := seq.Add(1)
+		events.report(soak.Event{Op: soak.OpPut, Key: string(key), Seq: s})
+		if err := db.PutWithOptions(ctx, key, gen.Value(key, s), durable); err != nil {
+			return unacked(ctx, err)
+		}
+		events.report(soak.Event{Op: soak.OpPut, Key: string(key), Seq: s, Acked: true})
+	case r < 60:
+		// Never acknowledged, so the write may or may not survive a crash
+		s := seq.Add(1)
+		events.report(soak.Event{Op: soak.OpPut, Key: string(key), Seq: s})
+		if err := db.PutWithOptions(ctx, key, gen.Value(key, s), config.WriteOptions{AwaitDurable: false}); err != nil {
+			return unacked(ctx, err)
+		}
+	case r < 70:
+		s := seq.Add(1)
+		events.report(soak.Event{Op: soak.OpDelete, Key: string(key), Seq: s})
+		if err := db.DeleteWithOptions(ctx, key, durable); err != nil {
+			return unacked(ctx, err)
+		}
+		events.report(soak.Event{Op: soak.OpDelete, Key: string(key), Seq: s, Acked: true})
+	case r < 80:
+		return writeBatch(ctx, db, gen, worker, workers, seq, events)
+	default:
+		value, err := db.Get(ctx, key)
+		if errors.Is(err, common.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			slog.Warn("get failed", "key", string(key), "error", err)
+			return nil
+		}
+		if _, err := soak.DecodeValue(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBatch writes a batch of puts and deletes of distinct keys
+func writeBatch(
+	ctx context.Context,
+	db *slatedb.DB,
+	gen *soak.Generator,
+	worker int,
+	workers int,
+	seq *atomic.Uint64,
+	events *eventWriter,
+) error {
+	batch := slatedb.NewWriteBatch()
+	var written []soak.Event
+	seen := make(map[string]bool)
+
+	for i := 0; i < 1+gen.Intn(8); i++ {
+		key := soak.Key(gen.KeyIndex(worker, workers))
+		if seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+
+		event := soak.Event{Op: soak.OpPut, Key: string(key), Seq: seq.Add(1)}
+		if gen.Intn(4) == 0 {
+			event.Op = soak.OpDelete
+			batch.Delete(key)
+		} else {
+			batch.Put(key, gen.Value(key, event.Seq))
+		}
+		events.report(event)
+		written = append(written, event)
+	}
+
+	if err := db.Write(ctx, batch, config.WriteOptions{AwaitDurable: true}); err != nil {
+		return unacked(ctx, err)
+	}
+	for _, event := range written {
+		event.Acked = true
+		events.report(event)
+	}
+	return nil
+}
+
+// unacked returns the error of a write which was not acknowledged. A write cut
+// short by the end of the run may or may not survive, like any write which is
+// never acknowledged, so it is not an error.
+func unacked(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("while writing: %w", err)
+}
@@ -0,0 +1,35 @@
+// Package compat verifies the on-disk format of SSTables, WAL objects and manifests
+// against golden files, so changes to the format are deliberate and objects written
+// by earlier releases remain readable. The objects are not compatible with the
+// upstream Rust implementation: the SSTable footer, with its "SLTB" magic number
+// and format versions, exists only in this implementation. No objects written by
+// Rust are checked in, and nothing verifies that either implementation reads the
+// objects written by the other.
+//
+// The golden files are kept in testdata:
+//
+//   - testdata/go contains objects written by this implementation. They are
+//     regenerated with `go test ./internal/compat -update` whenever the format
+//     changes intentionally, and can be read by other implementations to verify
+//     they decode objects written by Go.
+//   - testdata/go_v1 contains SSTables written by this implementation with the
+//     version 1 footer, which must remain readable.
+//   - testdata/go_v2 contains SSTables written by this implementation with the
+//     version 2 footer, whose blocks hold version 0 rows keyed by user key
+//     rather than by internal key, which must remain readable.
+//   - testdata/fixtures/<release>/<variant> contains complete DBs generated by
+//     cmd/fixture, a DB for each FixtureVariant. Each release which changes the
+//     format adds its fixtures, and TestReadFixtures verifies that the fixtures of
+//     every release hold the contents of FixtureModel.
+//
+// Every *.sst object must contain the entries of the golden data set, and every
+// *.manifest object must decode along with the SSTable info it references.
+//
+// The golden data set holds the keys "key-000" through "key-099" with the values
+// "value-000" through "value-099", where every key ending in 5 is a tombstone.
+//
+// The fixtures hold the writes generated from FixtureSeed: puts, deletes, merges
+// with an append operator and range deletes, spread over a sorted run, L0 and the
+// WAL, so reading a fixture compacts, merges and replays data written by the
+// release which generated it.
+package compat
@@ -0,0 +1,318 @@
+package compat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/samber/mo"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/compress"
+	"github.com/slatedb/slatedb-go/slatedb"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+const (
+	// FixtureSeed seeds the writes of every fixture, so the fixtures generated by
+	// each release hold the same data and are verified against the same model
+	FixtureSeed = 20241111
+
+	// FixturePath is the path of the DB within the bucket of a fixture
+	FixturePath = "db"
+
+	// fixtureKeys is the number of keys in the key space of the fixture
+	fixtureKeys = 200
+)
+
+// FixtureVariant is a DB generated by each release, which differs from the other
+// variants by the options the DB is written with
+type FixtureVariant struct {
+	// Name is the name of the directory of the variant
+	Name string
+	// CompressionCodec is the codec the SSTables are written with
+	CompressionCodec compress.Codec
+	// CompressionDictSize is the size of the dictionary SSTables are compressed
+	// with, zero for no dictionary
+	CompressionDictSize uint64
+}
+
+// FixtureVariants returns every variant generated by cmd/fixture
+func FixtureVariants() []FixtureVariant {
+	return []FixtureVariant{
+		{Name: "none", CompressionCodec: compress.CodecNone},
+		{Name: "snappy", CompressionCodec: compress.CodecSnappy},
+		{Name: "zlib", CompressionCodec: compress.CodecZlib},
+		{Name: "lz4", CompressionCodec: compress.CodecLz4},
+		{Name: "zstd", CompressionCodec: compress.CodecZstd},
+		{Name: "zstd_dict", CompressionCodec: compress.CodecZstd, CompressionDictSize: 1024},
+	}
+}
+
+// FixtureVariantByName returns the variant with the provided name
+func FixtureVariantByName(name string) (FixtureVariant, bool) {
+	for _, v := range FixtureVariants() {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return FixtureVariant{}, false
+}
+
+// Options returns the options a fixture of the variant is written and read with.
+// Small blocks and SSTables spread the few keys of the fixture over many blocks.
+func (v FixtureVariant) Options() config.DBOptions {
+	return config.DBOptions{
+		DisableBackgroundTasks: true,
+		BlockSize:              256,
+		MinFilterKeys:          10,
+		L0SSTSizeBytes:         1024 * 1024,
+		CompressionCodec:       v.CompressionCodec,
+		CompressionDictSize:    v.CompressionDictSize,
+		MergeOperator:          appendOperator{},
+		CompactorOptions:       &config.CompactorOptions{MaxSSTSize: 4096},
+	}
+}
+
+// appendOperator appends each merge operand to the value of the key, separated by
+// a comma. The merge operator of a fixture must never change, as the operands in
+// the fixtures of previous releases are merged when they are read.
+type appendOperator struct{}
+
+func (appendOperator) Merge(_ []byte, existing mo.Option[[]byte], operand []byte) []byte {
+	value, ok := existing.Get()
+	if !ok {
+		return operand
+	}
+	return append(append(append([]byte{}, value...), ','), operand...)
+}
+
+type fixtureOpKind int
+
+const (
+	opPut fixtureOpKind = iota
+	opDelete
+	opMerge
+	opDeleteRange
+)
+
+// fixtureOp is a single write to the fixture
+type fixtureOp struct {
+	kind  fixtureOpKind
+	key   []byte
+	end   []byte
+	value []byte
+}
+
+// fixturePhases returns the writes of the fixture, split into phases which end in
+// different parts of the DB. The writes of the first phase are compacted into a
+// sorted run, the writes of the second phase are flushed to L0, and the writes of
+// the last phase are only in the WAL, so reading the fixture replays them.
+func fixturePhases() [][]fixtureOp {
+	rnd := rand.New(rand.NewSource(FixtureSeed))
+	value := func() []byte {
+		buf := make([]byte, 16+rnd.Intn(48))
+		for i := range buf {
+			buf[i] = 'a' + byte(rnd.Intn(26))
+		}
+		return buf
+	}
+	key := func() []byte {
+		return fixtureKey(rnd.Intn(fixtureKeys))
+	}
+	mixed := func(puts, deletes, merges, rangeDeletes int) []fixtureOp {
+		var ops []fixtureOp
+		for i := 0; i < puts; i++ {
+			ops = append(ops, fixtureOp{kind: opPut, key: key(), value: value()})
+		}
+		for i := 0; i < deletes; i++ {
+			ops = append(ops, fixtureOp{kind: opDelete, key: key()})
+		}
+		for i := 0; i < merges; i++ {
+			ops = append(ops, fixtureOp{kind: opMerge, key: key(), value: value()[:8]})
+		}
+		for i := 0; i < rangeDeletes; i++ {
+			start := rnd.Intn(fixtureKeys - 10)
+			ops = append(ops, fixtureOp{kind: opDeleteRange, key: fixtureKey(start),
+				end: fixtureKey(start + 1 + rnd.Intn(10))})
+		}
+		rnd.Shuffle(len(ops), func(i, j int) { ops[i], ops[j] = ops[j], ops[i] })
+		return ops
+	}
+
+	compacted := make([]fixtureOp, 0, fixtureKeys)
+	for i := 0; i < fixtureKeys; i++ {
+		compacted = append(compacted, fixtureOp{kind: opPut, key: fixtureKey(i), value: value()})
+	}
+	return [][]fixtureOp{
+		append(compacted, mixed(0, 20, 20, 2)...),
+		mixed(40, 15, 20, 2),
+		mixed(10, 5, 5, 1),
+	}
+}
+
+func fixtureKey(i int) []byte {
+	return []byte(fmt.Sprintf("key-%04d", i))
+}
+
+// FixtureModel returns the contents every fixture must hold, keyed by key
+func FixtureModel() map[string][]byte {
+	model := make(map[string][]byte)
+	for _, phase := range fixturePhases() {
+		for _, op := range phase {
+			switch op.kind {
+			case opPut:
+				model[string(op.key)] = op.value
+			case opDelete:
+				delete(model, string(op.key))
+			case opMerge:
+				existing := mo.None[[]byte]()
+				if value, ok := model[string(op.key)]; ok {
+					existing = mo.Some(value)
+				}
+				model[string(op.key)] = appendOperator{}.Merge(op.key, existing, op.value)
+			case opDeleteRange:
+				for k := range model {
+					if k >= string(op.key) && k < string(op.end) {
+						delete(model, k)
+					}
+				}
+			}
+		}
+	}
+	return model
+}
+
+// GenerateFixture writes the fixture of the variant to the bucket at FixturePath
+func GenerateFixture(ctx context.Context, bucket objstore.Bucket, variant FixtureVariant) error {
+	db, err := slatedb.Open(ctx, FixturePath, bucket, variant.Options())
+	if err != nil {
+		return fmt.Errorf("while opening DB: %w", err)
+	}
+
+	phases := fixturePhases()
+	// The first phase is flushed in several L0 SSTables, which are compacted into
+	// a sorted run
+	const flushes = 4
+	compacted := phases[0]
+	for i := 0; i < flushes; i++ {
+		if err := writeFixtureOps(ctx, db, compacted[i*len(compacted)/flushes:(i+1)*len(compacted)/flushes]); err != nil {
+			return errors.Join(fmt.Errorf("while writing: %w", err), db.Close())
+		}
+		if err := db.FlushMemtableToL0(ctx); err != nil {
+			return errors.Join(fmt.Errorf("while flushing memtable: %w", err), db.Close())
+		}
+	}
+	if err := db.CompactOnce(ctx); err != nil {
+		return errors.Join(fmt.Errorf("while compacting: %w", err), db.Close())
+	}
+
+	if err := writeFixtureOps(ctx, db, phases[1]); err != nil {
+		return errors.Join(fmt.Errorf("while writing: %w", err), db.Close())
+	}
+	if err := db.FlushMemtableToL0(ctx); err != nil {
+		return errors.Join(fmt.Errorf("while flushing memtable: %w", err), db.Close())
+	}
+
+	// Close flushes the WAL but not the mutable memtable, so the last phase
+	// remains only in the WAL
+	if err := writeFixtureOps(ctx, db, phases[2]); err != nil {
+		return errors.Join(fmt.Errorf("while writing: %w", err), db.Close())
+	}
+	return db.Close()
+}
+
+// writeFixtureOps writes the ops, the last of which awaits durability, which
+// flushes the WAL of a DB without background tasks
+func writeFixtureOps(ctx context.Context, db *slatedb.DB, ops []fixtureOp) error {
+	for i, op := range ops {
+		opts := config.WriteOptions{AwaitDurable: i == len(ops)-1}
+		var err error
+		switch op.kind {
+		case opPut:
+			err = db.PutWithOptions(ctx, op.key, op.value, opts)
+		case opDelete:
+			err = db.DeleteWithOptions(ctx, op.key, opts)
+		case opMerge:
+			err = db.MergeWithOptions(ctx, op.key, op.value, opts)
+		case opDeleteRange:
+			err = db.DeleteRangeWithOptions(ctx, op.key, op.end, opts)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyFixture opens the fixture of the variant in the bucket, which is written
+// to, and verifies that it holds the contents of FixtureModel. Every key is read
+// with DB.Get, then the memtable replayed from the WAL is flushed so that every
+// key can also be read with DB.Scan.
+func VerifyFixture(ctx context.Context, bucket objstore.Bucket, variant FixtureVariant) error {
+	db, err := slatedb.Open(ctx, FixturePath, bucket, variant.Options())
+	if err != nil {
+		return fmt.Errorf("while opening DB: %w", err)
+	}
+	err = verifyFixture(ctx, db)
+	return errors.Join(err, db.Close())
+}
+
+func verifyFixture(ctx context.Context, db *slatedb.DB) error {
+	model := FixtureModel()
+	var errs []error
+	for i := 0; i < fixtureKeys; i++ {
+		key := fixtureKey(i)
+		value, err := db.Get(ctx, key)
+		expected, ok := model[string(key)]
+		switch {
+		case !ok && errors.Is(err, common.ErrKeyNotFound):
+		case !ok && err == nil:
+			errs = append(errs, fmt.Errorf("key '%s' must be deleted, got '%s'", key, value))
+		case err != nil:
+			errs = append(errs, fmt.Errorf("while getting key '%s': %w", key, err))
+		case !bytes.Equal(expected, value):
+			errs = append(errs, fmt.Errorf("key '%s' must be '%s', got '%s'", key, expected, value))
+		}
+	}
+
+	if err := db.FlushMemtableToL0(ctx); err != nil {
+		return errors.Join(append(errs, fmt.Errorf("while flushing memtable: %w", err))...)
+	}
+	opts := config.DefaultScanOptions()
+	opts.ReadLevel = config.Flushed
+	iter, err := db.Scan(ctx, nil, nil, opts)
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("while scanning: %w", err))...)
+	}
+	defer iter.Close()
+
+	keys := make([]string, 0, len(model))
+	for k := range model {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var scanned []string
+	for {
+		kv, ok := iter.Next(ctx)
+		if !ok {
+			break
+		}
+		scanned = append(scanned, string(kv.Key))
+		if expected, ok := model[string(kv.Key)]; ok && !bytes.Equal(expected, kv.Value) {
+			errs = append(errs, fmt.Errorf("scanned key '%s' must be '%s', got '%s'", kv.Key, expected, kv.Value))
+		}
+	}
+	if !iter.Warnings().Empty() {
+		errs = append(errs, fmt.Errorf("scan warnings: %s", iter.Warnings().String()))
+	}
+	if strings.Join(scanned, ",") != strings.Join(keys, ",") {
+		errs = append(errs, fmt.Errorf("scanned %d keys, expected %d", len(scanned), len(keys)))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package compat
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+// TestGenerateFixture verifies the fixture written by this release
+func TestGenerateFixture(t *testing.T) {
+	ctx := context.Background()
+	variant, _ := FixtureVariantByName("zstd_dict")
+	bucket := objstore.NewInMemBucket()
+	require.NoError(t, GenerateFixture(ctx, bucket, variant))
+	require.NoError(t, VerifyFixture(ctx, bucket, variant))
+}
+
+// TestReadFixtures verifies the fixtures generated by every previous release,
+// which are kept in testdata/fixtures/<release>/<variant>
+func TestReadFixtures(t *testing.T) {
+	releases, err := os.ReadDir(filepath.Join("testdata", "fixtures"))
+	if os.IsNotExist(err) {
+		t.Skip("no fixtures in 'testdata/fixtures'")
+	}
+	require.NoError(t, err)
+
+	for _, release := range releases {
+		t.Run(release.Name(), func(t *testing.T) {
+			dir := filepath.Join("testdata", "fixtures", release.Name())
+			variants, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			for _, v := range variants {
+				t.Run(v.Name(), func(t *testing.T) {
+					variant, ok := FixtureVariantByName(v.Name())
+					require.True(t, ok, "unknown fixture variant '%s'", v.Name())
+					// Opening the DB writes to it, so the fixture is copied to
+					// a bucket in memory rather than opened in place
+					bucket := loadFixture(t, filepath.Join(dir, v.Name()))
+					require.NoError(t, VerifyFixture(context.Background(), bucket, variant))
+				})
+			}
+		})
+	}
+}
+
+// loadFixture copies every file below dir to a bucket in memory
+func loadFixture(t *testing.T, dir string) objstore.Bucket {
+	bucket := objstore.NewInMemBucket()
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return bucket.Upload(context.Background(), filepath.ToSlash(name), bytes.NewReader(data))
+	})
+	require.NoError(t, err)
+	return bucket
+}
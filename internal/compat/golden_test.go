@@ -0,0 +1,191 @@
+package compat
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slatedb/slatedb-go/internal/compress"
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/compaction"
+	"github.com/slatedb/slatedb-go/slatedb/manifest"
+	"github.com/slatedb/slatedb-go/slatedb/state"
+)
+
+var update = flag.Bool("update", false, "regenerate the Go golden files")
+
+// goldenEntries returns the golden data set described in the package documentation
+func goldenEntries() []types.KeyValue {
+	entries := make([]types.KeyValue, 0, 100)
+	for i := 0; i < 100; i++ {
+		kv := types.KeyValue{Key: []byte(fmt.Sprintf("key-%03d", i))}
+		if i%10 != 5 {
+			kv.Value = []byte(fmt.Sprintf("value-%03d", i))
+		}
+		entries = append(entries, kv)
+	}
+	return entries
+}
+
+func buildGoldenSST(t *testing.T, conf sstable.Config) *sstable.Table {
+	builder := sstable.NewBuilder(conf)
+	for _, kv := range goldenEntries() {
+		require.NoError(t, builder.AddValue(kv.Key, kv.Value))
+	}
+	table, err := builder.Build()
+	require.NoError(t, err)
+	return table
+}
+
+func goldenSSTConfig(codec compress.Codec) sstable.Config {
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = 256
+	conf.Compression = codec
+	return conf
+}
+
+// goldenObjects returns the objects written by this implementation, keyed by file name
+func goldenObjects(t *testing.T) map[string][]byte {
+	compacted := buildGoldenSST(t, goldenSSTConfig(compress.CodecNone))
+	snappy := buildGoldenSST(t, goldenSSTConfig(compress.CodecSnappy))
+
+	// WAL objects are SSTables of the writes in the order they were flushed,
+	// which are too small to include a filter.
+	walConf := goldenSSTConfig(compress.CodecNone)
+	walConf.MinFilterKeys = 1000
+	wal := buildGoldenSST(t, walConf)
+
+	core := &state.CoreStateSnapshot{
+		L0LastCompacted: mo.None[ulid.ULID](),
+		L0: []sstable.Handle{
+			*sstable.NewHandle(sstable.NewIDCompacted(ulid.MustParse("01HQ8Z5Q6X0000000000000001")), compacted.Info),
+		},
+		Compacted: []compaction.SortedRun{{
+			ID: 1,
+			SSTList: []sstable.Handle{
+				*sstable.NewHandle(sstable.NewIDCompacted(ulid.MustParse("01HQ8Z5Q6X0000000000000002")), snappy.Info),
+			},
+		}},
+	}
+	core.NextWalSstID.Store(3)
+	core.LastCompactedWalSSTID.Store(1)
+
+	m := &manifest.Manifest{
+		Core: core.ToCoreState(),
+		FormatOptions: mo.Some(manifest.FormatOptions{
+			Comparator:         "bytewise",
+			BlockFormatVersion: block.FormatVersion,
+			CompressionCodec:   compress.CodecNone,
+		}),
+	}
+	m.WriterEpoch.Store(1)
+	m.CompactorEpoch.Store(1)
+
+	return map[string][]byte{
+		"compacted.sst":        sstable.EncodeTable(compacted),
+		"compacted_snappy.sst": sstable.EncodeTable(snappy),
+		"wal.sst":              sstable.EncodeTable(wal),
+		"db.manifest":          manifest.FlatBufferManifestCodec{}.Encode(m),
+	}
+}
+
+// TestGoGolden ensures objects written by this implementation are byte for byte
+// identical to the golden files, such that format changes are always deliberate.
+func TestGoGolden(t *testing.T) {
+	for name, data := range goldenObjects(t) {
+		path := filepath.Join("testdata", "go", name)
+		if *update {
+			require.NoError(t, os.WriteFile(path, data, 0o644))
+			continue
+		}
+
+		golden, err := os.ReadFile(path)
+		require.NoError(t, err, "run `go test ./internal/compat -update` to create the golden files")
+		assert.Equal(t, golden, data, "object '%s' differs from the golden file", name)
+	}
+}
+
+// TestReadGolden verifies the golden files of every format version are decoded
+func TestReadGolden(t *testing.T) {
+	for _, impl := range []string{"go", "go_v1", "go_v2"} {
+		t.Run(impl, func(t *testing.T) {
+			dir := filepath.Join("testdata", impl)
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+
+			for _, file := range files {
+				data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+				require.NoError(t, err)
+
+				switch {
+				case strings.HasSuffix(file.Name(), ".sst"):
+					t.Run(file.Name(), func(t *testing.T) { verifyGoldenSST(t, data) })
+				case strings.HasSuffix(file.Name(), ".manifest"):
+					t.Run(file.Name(), func(t *testing.T) { verifyGoldenManifest(t, data) })
+				}
+			}
+		})
+	}
+}
+
+func verifyGoldenSST(t *testing.T, data []byte) {
+	ctx := context.Background()
+	reader, err := sstable.NewReader(sstable.NewIDWal(0), sstable.NewBytesBlob(data))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("key-000"), reader.Info().FirstKey)
+
+	iter, err := reader.Iterator()
+	require.NoError(t, err)
+	for _, expected := range goldenEntries() {
+		entry, ok := iter.NextEntry(ctx)
+		require.True(t, ok, "missing key '%s'", expected.Key)
+		assert.Equal(t, expected.Key, entry.Key)
+		if expected.Value == nil {
+			assert.True(t, entry.Value.IsTombstone(), "key '%s' must be a tombstone", expected.Key)
+		} else {
+			assert.Equal(t, expected.Value, entry.Value.Value)
+		}
+	}
+	_, ok := iter.NextEntry(ctx)
+	assert.False(t, ok)
+	assert.True(t, iter.Warnings().Empty(), iter.Warnings().String())
+
+	// Every block must also decode on its own
+	index, err := reader.ReadIndex(ctx, reader.Handle())
+	require.NoError(t, err)
+	if !reader.Info().IndexPartitioned {
+		for i := 0; i < index.BlockMetaLength(); i++ {
+			_, err := sstable.ReadBlockRaw(reader.Info(), index, uint64(i), data)
+			require.NoError(t, err, "block '%d'", i)
+		}
+	}
+}
+
+func verifyGoldenManifest(t *testing.T, data []byte) {
+	m, err := manifest.FlatBufferManifestCodec{}.Decode(data)
+	require.NoError(t, err)
+
+	core := m.Core.Snapshot()
+	for _, sst := range append(core.L0, flatten(core.Compacted)...) {
+		assert.NotEmpty(t, sst.Info.FirstKey, "sst '%s' must have a first key", sst.Id.Value)
+	}
+}
+
+func flatten(sortedRuns []compaction.SortedRun) []sstable.Handle {
+	var handles []sstable.Handle
+	for _, sr := range sortedRuns {
+		handles = append(handles, sr.SSTList...)
+	}
+	return handles
+}
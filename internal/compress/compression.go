@@ -5,8 +5,10 @@ import (
 	"compress/zlib"
 	"errors"
 	"io"
+	"slices"
 
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/dict"
 	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 
@@ -156,3 +158,56 @@ func Decode(buf []byte, codec Codec) ([]byte, error) {
 		return nil, ErrInvalidCodec
 	}
 }
+
+// ErrDictUnsupported is returned when a dictionary is provided for a codec other than CodecZstd
+var ErrDictUnsupported = errors.New("compression dictionaries are only supported by zstd")
+
+// TrainDict returns a zstd dictionary of at most maxSize bytes trained on the
+// provided samples. Data compressed with the dictionary can only be decompressed
+// with the same dictionary.
+func TrainDict(samples [][]byte, maxSize int) ([]byte, error) {
+	// The trainer indexes 8 byte sequences, and cannot train on shorter samples
+	if !slices.ContainsFunc(samples, func(s []byte) bool { return len(s) >= 8 }) {
+		return nil, errors.New("samples are too small to train a dictionary")
+	}
+	return dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: maxSize,
+		HashBytes:   6,
+	})
+}
+
+// EncodeWithDict encodes the provided byte slice using the dictionary. Only
+// CodecZstd supports dictionaries, if dict is empty this is equivalent to Encode.
+func EncodeWithDict(buf []byte, codec Codec, dict []byte) ([]byte, error) {
+	if len(dict) == 0 {
+		return Encode(buf, codec)
+	}
+	if codec != CodecZstd {
+		return nil, ErrDictUnsupported
+	}
+
+	w, err := zstd.NewWriter(nil, zstd.WithEncoderDict(dict))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = w.Close() }()
+	return w.EncodeAll(buf, nil), nil
+}
+
+// DecodeWithDict decodes the provided byte slice which was encoded using the
+// dictionary. If dict is empty this is equivalent to Decode.
+func DecodeWithDict(buf []byte, codec Codec, dict []byte) ([]byte, error) {
+	if len(dict) == 0 {
+		return Decode(buf, codec)
+	}
+	if codec != CodecZstd {
+		return nil, ErrDictUnsupported
+	}
+
+	r, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dict))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return r.DecodeAll(buf, nil)
+}
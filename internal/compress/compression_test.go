@@ -2,6 +2,7 @@ package compress
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -83,3 +84,30 @@ func TestCompressDecompressLargeInput(t *testing.T) {
 		})
 	}
 }
+
+func TestCompressDecompressWithDict(t *testing.T) {
+	var samples [][]byte
+	for i := 0; i < 100; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(`{"tenant":"acme","user_id":%d,"status":"active","region":"us-east-1"}`, i)))
+	}
+	dict, err := TrainDict(samples, 1024)
+	require.NoError(t, err)
+
+	input := []byte(`{"tenant":"acme","user_id":1000,"status":"active","region":"us-east-1"}`)
+	compressed, err := EncodeWithDict(input, CodecZstd, dict)
+	require.NoError(t, err)
+
+	withoutDict, err := Encode(input, CodecZstd)
+	require.NoError(t, err)
+	assert.Less(t, len(compressed), len(withoutDict))
+
+	decompressed, err := DecodeWithDict(compressed, CodecZstd, dict)
+	require.NoError(t, err)
+	assert.Equal(t, input, decompressed)
+
+	_, err = EncodeWithDict(input, CodecSnappy, dict)
+	assert.ErrorIs(t, err, ErrDictUnsupported)
+
+	_, err = TrainDict([][]byte{[]byte("short")}, 1024)
+	assert.Error(t, err)
+}
@@ -145,6 +145,7 @@ func CompactedSstIdEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 type CompactedSsTableT struct {
 	Id   *CompactedSstIdT `json:"id"`
 	Info *SsTableInfoT    `json:"info"`
+	Data []byte           `json:"data"`
 }
 
 func (t *CompactedSsTableT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
@@ -153,15 +154,21 @@ func (t *CompactedSsTableT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffs
 	}
 	idOffset := t.Id.Pack(builder)
 	infoOffset := t.Info.Pack(builder)
+	dataOffset := flatbuffers.UOffsetT(0)
+	if t.Data != nil {
+		dataOffset = builder.CreateByteString(t.Data)
+	}
 	CompactedSsTableStart(builder)
 	CompactedSsTableAddId(builder, idOffset)
 	CompactedSsTableAddInfo(builder, infoOffset)
+	CompactedSsTableAddData(builder, dataOffset)
 	return CompactedSsTableEnd(builder)
 }
 
 func (rcv *CompactedSsTable) UnPackTo(t *CompactedSsTableT) {
 	t.Id = rcv.Id(nil).UnPack()
 	t.Info = rcv.Info(nil).UnPack()
+	t.Data = rcv.DataBytes()
 }
 
 func (rcv *CompactedSsTable) UnPack() *CompactedSsTableT {
@@ -234,8 +241,42 @@ func (rcv *CompactedSsTable) Info(obj *SsTableInfo) *SsTableInfo {
 	return nil
 }
 
+func (rcv *CompactedSsTable) Data(j int) byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(8))
+	if o != 0 {
+		a := rcv._tab.Vector(o)
+		return rcv._tab.GetByte(a + flatbuffers.UOffsetT(j*1))
+	}
+	return 0
+}
+
+func (rcv *CompactedSsTable) DataLength() int {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(8))
+	if o != 0 {
+		return rcv._tab.VectorLen(o)
+	}
+	return 0
+}
+
+func (rcv *CompactedSsTable) DataBytes() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(8))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *CompactedSsTable) MutateData(j int, n byte) bool {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(8))
+	if o != 0 {
+		a := rcv._tab.Vector(o)
+		return rcv._tab.MutateByte(a+flatbuffers.UOffsetT(j*1), n)
+	}
+	return false
+}
+
 func CompactedSsTableStart(builder *flatbuffers.Builder) {
-	builder.StartObject(2)
+	builder.StartObject(3)
 }
 func CompactedSsTableAddId(builder *flatbuffers.Builder, id flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(0, flatbuffers.UOffsetT(id), 0)
@@ -243,17 +284,38 @@ func CompactedSsTableAddId(builder *flatbuffers.Builder, id flatbuffers.UOffsetT
 func CompactedSsTableAddInfo(builder *flatbuffers.Builder, info flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(1, flatbuffers.UOffsetT(info), 0)
 }
+func CompactedSsTableAddData(builder *flatbuffers.Builder, data flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(2, flatbuffers.UOffsetT(data), 0)
+}
+func CompactedSsTableStartDataVector(builder *flatbuffers.Builder, numElems int) flatbuffers.UOffsetT {
+	return builder.StartVector(1, numElems, 1)
+}
 func CompactedSsTableEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	return builder.EndObject()
 }
 
 type SsTableInfoT struct {
-	FirstKey          []byte           `json:"first_key"`
-	IndexOffset       uint64           `json:"index_offset"`
-	IndexLen          uint64           `json:"index_len"`
-	FilterOffset      uint64           `json:"filter_offset"`
-	FilterLen         uint64           `json:"filter_len"`
-	CompressionFormat CompressionCodec `json:"compression_format"`
+	FirstKey              []byte           `json:"first_key"`
+	IndexOffset           uint64           `json:"index_offset"`
+	IndexLen              uint64           `json:"index_len"`
+	FilterOffset          uint64           `json:"filter_offset"`
+	FilterLen             uint64           `json:"filter_len"`
+	CompressionFormat     CompressionCodec `json:"compression_format"`
+	IndexPartitioned      bool             `json:"index_partitioned"`
+	LastKey               []byte           `json:"last_key"`
+	EntryCount            uint64           `json:"entry_count"`
+	TombstoneCount        uint64           `json:"tombstone_count"`
+	RawSize               uint64           `json:"raw_size"`
+	CompressedSize        uint64           `json:"compressed_size"`
+	FilterPolicy          string           `json:"filter_policy"`
+	CompressionDictOffset uint64           `json:"compression_dict_offset"`
+	CompressionDictLen    uint64           `json:"compression_dict_len"`
+	FilterBitsPerKey      uint32           `json:"filter_bits_per_key"`
+	BlockSize             uint64           `json:"block_size"`
+	RangeTombstoneOffset  uint64           `json:"range_tombstone_offset"`
+	RangeTombstoneLen     uint64           `json:"range_tombstone_len"`
+	WriterEpoch           uint64           `json:"writer_epoch"`
+	BlockFormatVersion    uint16           `json:"block_format_version"`
 }
 
 func (t *SsTableInfoT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
@@ -264,6 +326,14 @@ func (t *SsTableInfoT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	if t.FirstKey != nil {
 		firstKeyOffset = builder.CreateByteString(t.FirstKey)
 	}
+	lastKeyOffset := flatbuffers.UOffsetT(0)
+	if t.LastKey != nil {
+		lastKeyOffset = builder.CreateByteString(t.LastKey)
+	}
+	filterPolicyOffset := flatbuffers.UOffsetT(0)
+	if t.FilterPolicy != "" {
+		filterPolicyOffset = builder.CreateString(t.FilterPolicy)
+	}
 	SsTableInfoStart(builder)
 	SsTableInfoAddFirstKey(builder, firstKeyOffset)
 	SsTableInfoAddIndexOffset(builder, t.IndexOffset)
@@ -271,6 +341,21 @@ func (t *SsTableInfoT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	SsTableInfoAddFilterOffset(builder, t.FilterOffset)
 	SsTableInfoAddFilterLen(builder, t.FilterLen)
 	SsTableInfoAddCompressionFormat(builder, t.CompressionFormat)
+	SsTableInfoAddIndexPartitioned(builder, t.IndexPartitioned)
+	SsTableInfoAddLastKey(builder, lastKeyOffset)
+	SsTableInfoAddEntryCount(builder, t.EntryCount)
+	SsTableInfoAddTombstoneCount(builder, t.TombstoneCount)
+	SsTableInfoAddRawSize(builder, t.RawSize)
+	SsTableInfoAddCompressedSize(builder, t.CompressedSize)
+	SsTableInfoAddFilterPolicy(builder, filterPolicyOffset)
+	SsTableInfoAddCompressionDictOffset(builder, t.CompressionDictOffset)
+	SsTableInfoAddCompressionDictLen(builder, t.CompressionDictLen)
+	SsTableInfoAddFilterBitsPerKey(builder, t.FilterBitsPerKey)
+	SsTableInfoAddBlockSize(builder, t.BlockSize)
+	SsTableInfoAddRangeTombstoneOffset(builder, t.RangeTombstoneOffset)
+	SsTableInfoAddRangeTombstoneLen(builder, t.RangeTombstoneLen)
+	SsTableInfoAddWriterEpoch(builder, t.WriterEpoch)
+	SsTableInfoAddBlockFormatVersion(builder, t.BlockFormatVersion)
 	return SsTableInfoEnd(builder)
 }
 
@@ -281,6 +366,21 @@ func (rcv *SsTableInfo) UnPackTo(t *SsTableInfoT) {
 	t.FilterOffset = rcv.FilterOffset()
 	t.FilterLen = rcv.FilterLen()
 	t.CompressionFormat = rcv.CompressionFormat()
+	t.IndexPartitioned = rcv.IndexPartitioned()
+	t.LastKey = rcv.LastKeyBytes()
+	t.EntryCount = rcv.EntryCount()
+	t.TombstoneCount = rcv.TombstoneCount()
+	t.RawSize = rcv.RawSize()
+	t.CompressedSize = rcv.CompressedSize()
+	t.FilterPolicy = string(rcv.FilterPolicy())
+	t.CompressionDictOffset = rcv.CompressionDictOffset()
+	t.CompressionDictLen = rcv.CompressionDictLen()
+	t.FilterBitsPerKey = rcv.FilterBitsPerKey()
+	t.BlockSize = rcv.BlockSize()
+	t.RangeTombstoneOffset = rcv.RangeTombstoneOffset()
+	t.RangeTombstoneLen = rcv.RangeTombstoneLen()
+	t.WriterEpoch = rcv.WriterEpoch()
+	t.BlockFormatVersion = rcv.BlockFormatVersion()
 }
 
 func (rcv *SsTableInfo) UnPack() *SsTableInfoT {
@@ -421,8 +521,206 @@ func (rcv *SsTableInfo) MutateCompressionFormat(n CompressionCodec) bool {
 	return rcv._tab.MutateInt8Slot(14, int8(n))
 }
 
+func (rcv *SsTableInfo) IndexPartitioned() bool {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(16))
+	if o != 0 {
+		return rcv._tab.GetBool(o + rcv._tab.Pos)
+	}
+	return false
+}
+
+func (rcv *SsTableInfo) MutateIndexPartitioned(n bool) bool {
+	return rcv._tab.MutateBoolSlot(16, n)
+}
+
+func (rcv *SsTableInfo) LastKey(j int) byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(18))
+	if o != 0 {
+		a := rcv._tab.Vector(o)
+		return rcv._tab.GetByte(a + flatbuffers.UOffsetT(j*1))
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) LastKeyLength() int {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(18))
+	if o != 0 {
+		return rcv._tab.VectorLen(o)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) LastKeyBytes() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(18))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *SsTableInfo) MutateLastKey(j int, n byte) bool {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(18))
+	if o != 0 {
+		a := rcv._tab.Vector(o)
+		return rcv._tab.MutateByte(a+flatbuffers.UOffsetT(j*1), n)
+	}
+	return false
+}
+
+func (rcv *SsTableInfo) EntryCount() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(20))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateEntryCount(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(20, n)
+}
+
+func (rcv *SsTableInfo) TombstoneCount() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(22))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateTombstoneCount(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(22, n)
+}
+
+func (rcv *SsTableInfo) RawSize() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(24))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateRawSize(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(24, n)
+}
+
+func (rcv *SsTableInfo) CompressedSize() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(26))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateCompressedSize(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(26, n)
+}
+
+func (rcv *SsTableInfo) FilterPolicy() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(28))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *SsTableInfo) CompressionDictOffset() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(30))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateCompressionDictOffset(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(30, n)
+}
+
+func (rcv *SsTableInfo) CompressionDictLen() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(32))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateCompressionDictLen(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(32, n)
+}
+
+func (rcv *SsTableInfo) FilterBitsPerKey() uint32 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(34))
+	if o != 0 {
+		return rcv._tab.GetUint32(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateFilterBitsPerKey(n uint32) bool {
+	return rcv._tab.MutateUint32Slot(34, n)
+}
+
+func (rcv *SsTableInfo) BlockSize() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(36))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateBlockSize(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(36, n)
+}
+
+func (rcv *SsTableInfo) RangeTombstoneOffset() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(38))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateRangeTombstoneOffset(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(38, n)
+}
+
+func (rcv *SsTableInfo) RangeTombstoneLen() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(40))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateRangeTombstoneLen(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(40, n)
+}
+
+func (rcv *SsTableInfo) WriterEpoch() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(42))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateWriterEpoch(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(42, n)
+}
+
+func (rcv *SsTableInfo) BlockFormatVersion() uint16 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(44))
+	if o != 0 {
+		return rcv._tab.GetUint16(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableInfo) MutateBlockFormatVersion(n uint16) bool {
+	return rcv._tab.MutateUint16Slot(44, n)
+}
+
 func SsTableInfoStart(builder *flatbuffers.Builder) {
-	builder.StartObject(6)
+	builder.StartObject(21)
 }
 func SsTableInfoAddFirstKey(builder *flatbuffers.Builder, firstKey flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(0, flatbuffers.UOffsetT(firstKey), 0)
@@ -445,13 +743,62 @@ func SsTableInfoAddFilterLen(builder *flatbuffers.Builder, filterLen uint64) {
 func SsTableInfoAddCompressionFormat(builder *flatbuffers.Builder, compressionFormat CompressionCodec) {
 	builder.PrependInt8Slot(5, int8(compressionFormat), 0)
 }
+func SsTableInfoAddIndexPartitioned(builder *flatbuffers.Builder, indexPartitioned bool) {
+	builder.PrependBoolSlot(6, indexPartitioned, false)
+}
+func SsTableInfoAddLastKey(builder *flatbuffers.Builder, lastKey flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(7, flatbuffers.UOffsetT(lastKey), 0)
+}
+func SsTableInfoStartLastKeyVector(builder *flatbuffers.Builder, numElems int) flatbuffers.UOffsetT {
+	return builder.StartVector(1, numElems, 1)
+}
+func SsTableInfoAddEntryCount(builder *flatbuffers.Builder, entryCount uint64) {
+	builder.PrependUint64Slot(8, entryCount, 0)
+}
+func SsTableInfoAddTombstoneCount(builder *flatbuffers.Builder, tombstoneCount uint64) {
+	builder.PrependUint64Slot(9, tombstoneCount, 0)
+}
+func SsTableInfoAddRawSize(builder *flatbuffers.Builder, rawSize uint64) {
+	builder.PrependUint64Slot(10, rawSize, 0)
+}
+func SsTableInfoAddCompressedSize(builder *flatbuffers.Builder, compressedSize uint64) {
+	builder.PrependUint64Slot(11, compressedSize, 0)
+}
+func SsTableInfoAddFilterPolicy(builder *flatbuffers.Builder, filterPolicy flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(12, flatbuffers.UOffsetT(filterPolicy), 0)
+}
+func SsTableInfoAddCompressionDictOffset(builder *flatbuffers.Builder, compressionDictOffset uint64) {
+	builder.PrependUint64Slot(13, compressionDictOffset, 0)
+}
+func SsTableInfoAddCompressionDictLen(builder *flatbuffers.Builder, compressionDictLen uint64) {
+	builder.PrependUint64Slot(14, compressionDictLen, 0)
+}
+func SsTableInfoAddFilterBitsPerKey(builder *flatbuffers.Builder, filterBitsPerKey uint32) {
+	builder.PrependUint32Slot(15, filterBitsPerKey, 0)
+}
+func SsTableInfoAddBlockSize(builder *flatbuffers.Builder, blockSize uint64) {
+	builder.PrependUint64Slot(16, blockSize, 0)
+}
+func SsTableInfoAddRangeTombstoneOffset(builder *flatbuffers.Builder, rangeTombstoneOffset uint64) {
+	builder.PrependUint64Slot(17, rangeTombstoneOffset, 0)
+}
+func SsTableInfoAddRangeTombstoneLen(builder *flatbuffers.Builder, rangeTombstoneLen uint64) {
+	builder.PrependUint64Slot(18, rangeTombstoneLen, 0)
+}
+func SsTableInfoAddWriterEpoch(builder *flatbuffers.Builder, writerEpoch uint64) {
+	builder.PrependUint64Slot(19, writerEpoch, 0)
+}
+func SsTableInfoAddBlockFormatVersion(builder *flatbuffers.Builder, blockFormatVersion uint16) {
+	builder.PrependUint16Slot(20, blockFormatVersion, 0)
+}
 func SsTableInfoEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	return builder.EndObject()
 }
 
 type BlockMetaT struct {
-	Offset   uint64 `json:"offset"`
-	FirstKey []byte `json:"first_key"`
+	Offset     uint64 `json:"offset"`
+	FirstKey   []byte `json:"first_key"`
+	EntryCount uint64 `json:"entry_count"`
 }
 
 func (t *BlockMetaT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
@@ -465,12 +812,14 @@ func (t *BlockMetaT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	BlockMetaStart(builder)
 	BlockMetaAddOffset(builder, t.Offset)
 	BlockMetaAddFirstKey(builder, firstKeyOffset)
+	BlockMetaAddEntryCount(builder, t.EntryCount)
 	return BlockMetaEnd(builder)
 }
 
 func (rcv *BlockMeta) UnPackTo(t *BlockMetaT) {
 	t.Offset = rcv.Offset()
 	t.FirstKey = rcv.FirstKeyBytes()
+	t.EntryCount = rcv.EntryCount()
 }
 
 func (rcv *BlockMeta) UnPack() *BlockMetaT {
@@ -563,8 +912,20 @@ func (rcv *BlockMeta) MutateFirstKey(j int, n byte) bool {
 	return false
 }
 
+func (rcv *BlockMeta) EntryCount() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(8))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *BlockMeta) MutateEntryCount(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(8, n)
+}
+
 func BlockMetaStart(builder *flatbuffers.Builder) {
-	builder.StartObject(2)
+	builder.StartObject(3)
 }
 func BlockMetaAddOffset(builder *flatbuffers.Builder, offset uint64) {
 	builder.PrependUint64Slot(0, offset, 0)
@@ -575,12 +936,16 @@ func BlockMetaAddFirstKey(builder *flatbuffers.Builder, firstKey flatbuffers.UOf
 func BlockMetaStartFirstKeyVector(builder *flatbuffers.Builder, numElems int) flatbuffers.UOffsetT {
 	return builder.StartVector(1, numElems, 1)
 }
+func BlockMetaAddEntryCount(builder *flatbuffers.Builder, entryCount uint64) {
+	builder.PrependUint64Slot(2, entryCount, 0)
+}
 func BlockMetaEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	return builder.EndObject()
 }
 
 type SsTableIndexT struct {
 	BlockMeta []*BlockMetaT `json:"block_meta"`
+	EndOffset uint64        `json:"end_offset"`
 }
 
 func (t *SsTableIndexT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
@@ -602,6 +967,7 @@ func (t *SsTableIndexT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT
 	}
 	SsTableIndexStart(builder)
 	SsTableIndexAddBlockMeta(builder, blockMetaOffset)
+	SsTableIndexAddEndOffset(builder, t.EndOffset)
 	return SsTableIndexEnd(builder)
 }
 
@@ -613,6 +979,7 @@ func (rcv *SsTableIndex) UnPackTo(t *SsTableIndexT) {
 		rcv.BlockMeta(&x, j)
 		t.BlockMeta[j] = x.UnPack()
 	}
+	t.EndOffset = rcv.EndOffset()
 }
 
 func (rcv *SsTableIndex) UnPack() *SsTableIndexT {
@@ -679,8 +1046,20 @@ func (rcv *SsTableIndex) BlockMetaLength() int {
 	return 0
 }
 
+func (rcv *SsTableIndex) EndOffset() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(6))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *SsTableIndex) MutateEndOffset(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(6, n)
+}
+
 func SsTableIndexStart(builder *flatbuffers.Builder) {
-	builder.StartObject(1)
+	builder.StartObject(2)
 }
 func SsTableIndexAddBlockMeta(builder *flatbuffers.Builder, blockMeta flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(0, flatbuffers.UOffsetT(blockMeta), 0)
@@ -688,6 +1067,9 @@ func SsTableIndexAddBlockMeta(builder *flatbuffers.Builder, blockMeta flatbuffer
 func SsTableIndexStartBlockMetaVector(builder *flatbuffers.Builder, numElems int) flatbuffers.UOffsetT {
 	return builder.StartVector(4, numElems, 4)
 }
+func SsTableIndexAddEndOffset(builder *flatbuffers.Builder, endOffset uint64) {
+	builder.PrependUint64Slot(1, endOffset, 0)
+}
 func SsTableIndexEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	return builder.EndObject()
 }
@@ -702,6 +1084,9 @@ type ManifestV1T struct {
 	L0                 []*CompactedSsTableT `json:"l0"`
 	Compacted          []*SortedRunT        `json:"compacted"`
 	Snapshots          []*SnapshotT         `json:"snapshots"`
+	FormatOptions      *FormatOptionsT      `json:"format_options"`
+	LastL0Seq          uint64               `json:"last_l0_seq"`
+	Writer             *WriterInfoT         `json:"writer"`
 }
 
 func (t *ManifestV1T) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
@@ -748,6 +1133,8 @@ func (t *ManifestV1T) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 		}
 		snapshotsOffset = builder.EndVector(snapshotsLength)
 	}
+	formatOptionsOffset := t.FormatOptions.Pack(builder)
+	writerOffset := t.Writer.Pack(builder)
 	ManifestV1Start(builder)
 	ManifestV1AddManifestId(builder, t.ManifestId)
 	ManifestV1AddWriterEpoch(builder, t.WriterEpoch)
@@ -758,6 +1145,9 @@ func (t *ManifestV1T) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	ManifestV1AddL0(builder, l0Offset)
 	ManifestV1AddCompacted(builder, compactedOffset)
 	ManifestV1AddSnapshots(builder, snapshotsOffset)
+	ManifestV1AddFormatOptions(builder, formatOptionsOffset)
+	ManifestV1AddLastL0Seq(builder, t.LastL0Seq)
+	ManifestV1AddWriter(builder, writerOffset)
 	return ManifestV1End(builder)
 }
 
@@ -789,6 +1179,9 @@ func (rcv *ManifestV1) UnPackTo(t *ManifestV1T) {
 		rcv.Snapshots(&x, j)
 		t.Snapshots[j] = x.UnPack()
 	}
+	t.FormatOptions = rcv.FormatOptions(nil).UnPack()
+	t.LastL0Seq = rcv.LastL0Seq()
+	t.Writer = rcv.Writer(nil).UnPack()
 }
 
 func (rcv *ManifestV1) UnPack() *ManifestV1T {
@@ -968,8 +1361,46 @@ func (rcv *ManifestV1) SnapshotsLength() int {
 	return 0
 }
 
+func (rcv *ManifestV1) FormatOptions(obj *FormatOptions) *FormatOptions {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(22))
+	if o != 0 {
+		x := rcv._tab.Indirect(o + rcv._tab.Pos)
+		if obj == nil {
+			obj = new(FormatOptions)
+		}
+		obj.Init(rcv._tab.Bytes, x)
+		return obj
+	}
+	return nil
+}
+
+func (rcv *ManifestV1) LastL0Seq() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(24))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *ManifestV1) MutateLastL0Seq(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(24, n)
+}
+
+func (rcv *ManifestV1) Writer(obj *WriterInfo) *WriterInfo {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(26))
+	if o != 0 {
+		x := rcv._tab.Indirect(o + rcv._tab.Pos)
+		if obj == nil {
+			obj = new(WriterInfo)
+		}
+		obj.Init(rcv._tab.Bytes, x)
+		return obj
+	}
+	return nil
+}
+
 func ManifestV1Start(builder *flatbuffers.Builder) {
-	builder.StartObject(9)
+	builder.StartObject(12)
 }
 func ManifestV1AddManifestId(builder *flatbuffers.Builder, manifestId uint64) {
 	builder.PrependUint64Slot(0, manifestId, 0)
@@ -1007,10 +1438,275 @@ func ManifestV1AddSnapshots(builder *flatbuffers.Builder, snapshots flatbuffers.
 func ManifestV1StartSnapshotsVector(builder *flatbuffers.Builder, numElems int) flatbuffers.UOffsetT {
 	return builder.StartVector(4, numElems, 4)
 }
+func ManifestV1AddFormatOptions(builder *flatbuffers.Builder, formatOptions flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(9, flatbuffers.UOffsetT(formatOptions), 0)
+}
+func ManifestV1AddLastL0Seq(builder *flatbuffers.Builder, lastL0Seq uint64) {
+	builder.PrependUint64Slot(10, lastL0Seq, 0)
+}
+func ManifestV1AddWriter(builder *flatbuffers.Builder, writer flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(11, flatbuffers.UOffsetT(writer), 0)
+}
 func ManifestV1End(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	return builder.EndObject()
 }
 
+type FormatOptionsT struct {
+	Comparator         string           `json:"comparator"`
+	BlockFormatVersion uint16           `json:"block_format_version"`
+	CompressionFormat  CompressionCodec `json:"compression_format"`
+}
+
+func (t *FormatOptionsT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
+	if t == nil {
+		return 0
+	}
+	comparatorOffset := flatbuffers.UOffsetT(0)
+	if t.Comparator != "" {
+		comparatorOffset = builder.CreateString(t.Comparator)
+	}
+	FormatOptionsStart(builder)
+	FormatOptionsAddComparator(builder, comparatorOffset)
+	FormatOptionsAddBlockFormatVersion(builder, t.BlockFormatVersion)
+	FormatOptionsAddCompressionFormat(builder, t.CompressionFormat)
+	return FormatOptionsEnd(builder)
+}
+
+func (rcv *FormatOptions) UnPackTo(t *FormatOptionsT) {
+	t.Comparator = string(rcv.Comparator())
+	t.BlockFormatVersion = rcv.BlockFormatVersion()
+	t.CompressionFormat = rcv.CompressionFormat()
+}
+
+func (rcv *FormatOptions) UnPack() *FormatOptionsT {
+	if rcv == nil {
+		return nil
+	}
+	t := &FormatOptionsT{}
+	rcv.UnPackTo(t)
+	return t
+}
+
+type FormatOptions struct {
+	_tab flatbuffers.Table
+}
+
+func GetRootAsFormatOptions(buf []byte, offset flatbuffers.UOffsetT) *FormatOptions {
+	n := flatbuffers.GetUOffsetT(buf[offset:])
+	x := &FormatOptions{}
+	x.Init(buf, n+offset)
+	return x
+}
+
+func FinishFormatOptionsBuffer(builder *flatbuffers.Builder, offset flatbuffers.UOffsetT) {
+	builder.Finish(offset)
+}
+
+func GetSizePrefixedRootAsFormatOptions(buf []byte, offset flatbuffers.UOffsetT) *FormatOptions {
+	n := flatbuffers.GetUOffsetT(buf[offset+flatbuffers.SizeUint32:])
+	x := &FormatOptions{}
+	x.Init(buf, n+offset+flatbuffers.SizeUint32)
+	return x
+}
+
+func FinishSizePrefixedFormatOptionsBuffer(builder *flatbuffers.Builder, offset flatbuffers.UOffsetT) {
+	builder.FinishSizePrefixed(offset)
+}
+
+func (rcv *FormatOptions) Init(buf []byte, i flatbuffers.UOffsetT) {
+	rcv._tab.Bytes = buf
+	rcv._tab.Pos = i
+}
+
+func (rcv *FormatOptions) Table() flatbuffers.Table {
+	return rcv._tab
+}
+
+func (rcv *FormatOptions) Comparator() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(4))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *FormatOptions) BlockFormatVersion() uint16 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(6))
+	if o != 0 {
+		return rcv._tab.GetUint16(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *FormatOptions) MutateBlockFormatVersion(n uint16) bool {
+	return rcv._tab.MutateUint16Slot(6, n)
+}
+
+func (rcv *FormatOptions) CompressionFormat() CompressionCodec {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(8))
+	if o != 0 {
+		return CompressionCodec(rcv._tab.GetInt8(o + rcv._tab.Pos))
+	}
+	return 0
+}
+
+func (rcv *FormatOptions) MutateCompressionFormat(n CompressionCodec) bool {
+	return rcv._tab.MutateInt8Slot(8, int8(n))
+}
+
+func FormatOptionsStart(builder *flatbuffers.Builder) {
+	builder.StartObject(3)
+}
+func FormatOptionsAddComparator(builder *flatbuffers.Builder, comparator flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(0, flatbuffers.UOffsetT(comparator), 0)
+}
+func FormatOptionsAddBlockFormatVersion(builder *flatbuffers.Builder, blockFormatVersion uint16) {
+	builder.PrependUint16Slot(1, blockFormatVersion, 0)
+}
+func FormatOptionsAddCompressionFormat(builder *flatbuffers.Builder, compressionFormat CompressionCodec) {
+	builder.PrependInt8Slot(2, int8(compressionFormat), 0)
+}
+func FormatOptionsEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
+	return builder.EndObject()
+}
+
+type WriterInfoT struct {
+	Host        string `json:"host"`
+	Pid         uint64 `json:"pid"`
+	StartTimeMs int64  `json:"start_time_ms"`
+	InstanceId  string `json:"instance_id"`
+}
+
+func (t *WriterInfoT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
+	if t == nil {
+		return 0
+	}
+	hostOffset := flatbuffers.UOffsetT(0)
+	if t.Host != "" {
+		hostOffset = builder.CreateString(t.Host)
+	}
+	instanceIdOffset := flatbuffers.UOffsetT(0)
+	if t.InstanceId != "" {
+		instanceIdOffset = builder.CreateString(t.InstanceId)
+	}
+	WriterInfoStart(builder)
+	WriterInfoAddHost(builder, hostOffset)
+	WriterInfoAddPid(builder, t.Pid)
+	WriterInfoAddStartTimeMs(builder, t.StartTimeMs)
+	WriterInfoAddInstanceId(builder, instanceIdOffset)
+	return WriterInfoEnd(builder)
+}
+
+func (rcv *WriterInfo) UnPackTo(t *WriterInfoT) {
+	t.Host = string(rcv.Host())
+	t.Pid = rcv.Pid()
+	t.StartTimeMs = rcv.StartTimeMs()
+	t.InstanceId = string(rcv.InstanceId())
+}
+
+func (rcv *WriterInfo) UnPack() *WriterInfoT {
+	if rcv == nil {
+		return nil
+	}
+	t := &WriterInfoT{}
+	rcv.UnPackTo(t)
+	return t
+}
+
+type WriterInfo struct {
+	_tab flatbuffers.Table
+}
+
+func GetRootAsWriterInfo(buf []byte, offset flatbuffers.UOffsetT) *WriterInfo {
+	n := flatbuffers.GetUOffsetT(buf[offset:])
+	x := &WriterInfo{}
+	x.Init(buf, n+offset)
+	return x
+}
+
+func FinishWriterInfoBuffer(builder *flatbuffers.Builder, offset flatbuffers.UOffsetT) {
+	builder.Finish(offset)
+}
+
+func GetSizePrefixedRootAsWriterInfo(buf []byte, offset flatbuffers.UOffsetT) *WriterInfo {
+	n := flatbuffers.GetUOffsetT(buf[offset+flatbuffers.SizeUint32:])
+	x := &WriterInfo{}
+	x.Init(buf, n+offset+flatbuffers.SizeUint32)
+	return x
+}
+
+func FinishSizePrefixedWriterInfoBuffer(builder *flatbuffers.Builder, offset flatbuffers.UOffsetT) {
+	builder.FinishSizePrefixed(offset)
+}
+
+func (rcv *WriterInfo) Init(buf []byte, i flatbuffers.UOffsetT) {
+	rcv._tab.Bytes = buf
+	rcv._tab.Pos = i
+}
+
+func (rcv *WriterInfo) Table() flatbuffers.Table {
+	return rcv._tab
+}
+
+func (rcv *WriterInfo) Host() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(4))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *WriterInfo) Pid() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(6))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *WriterInfo) MutatePid(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(6, n)
+}
+
+func (rcv *WriterInfo) StartTimeMs() int64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(8))
+	if o != 0 {
+		return rcv._tab.GetInt64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *WriterInfo) MutateStartTimeMs(n int64) bool {
+	return rcv._tab.MutateInt64Slot(8, n)
+}
+
+func (rcv *WriterInfo) InstanceId() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(10))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func WriterInfoStart(builder *flatbuffers.Builder) {
+	builder.StartObject(4)
+}
+func WriterInfoAddHost(builder *flatbuffers.Builder, host flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(0, flatbuffers.UOffsetT(host), 0)
+}
+func WriterInfoAddPid(builder *flatbuffers.Builder, pid uint64) {
+	builder.PrependUint64Slot(1, pid, 0)
+}
+func WriterInfoAddStartTimeMs(builder *flatbuffers.Builder, startTimeMs int64) {
+	builder.PrependInt64Slot(2, startTimeMs, 0)
+}
+func WriterInfoAddInstanceId(builder *flatbuffers.Builder, instanceId flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(3, flatbuffers.UOffsetT(instanceId), 0)
+}
+func WriterInfoEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
+	return builder.EndObject()
+}
+
 type SortedRunT struct {
 	Id   uint32               `json:"id"`
 	Ssts []*CompactedSsTableT `json:"ssts"`
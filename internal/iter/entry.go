@@ -18,6 +18,19 @@ type KVIterator interface {
 	Warnings() *types.ErrWarn
 }
 
+// Closer is implemented by a KVIterator which holds resources, such as block
+// fetches in flight, that should be released once the iteration is abandoned.
+type Closer interface {
+	Close()
+}
+
+// Close closes the iterator if it implements Closer
+func Close(it KVIterator) {
+	if c, ok := it.(Closer); ok {
+		c.Close()
+	}
+}
+
 type EntryIterator struct {
 	entries []types.RowEntry
 	index   int
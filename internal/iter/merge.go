@@ -14,6 +14,19 @@ type MergeSort struct {
 	heap      minHeap
 	lastKey   []byte
 	warn      types.ErrWarn
+
+	// mergeOperator applies the merge operands of a key to its older entries,
+	// nil if merge operands are returned as is
+	mergeOperator types.MergeOperator
+
+	// rawMergeOperands returns the older entries of a key after a merge operand,
+	// see WithRawMergeOperands. lastMerge is true if the last entry returned was
+	// a merge operand.
+	rawMergeOperands bool
+	lastMerge        bool
+
+	// rangeTombstones delete the entries they cover as they are returned
+	rangeTombstones types.RangeTombstones
 }
 
 // NewMergeSort performs a merge sort on values of each iterator. Each iterator provided
@@ -27,6 +40,10 @@ type MergeSort struct {
 // Given an iterator in the list at index 0 which has key 'a'
 // and an iterator in the list at index 1 which also has key 'a'
 // the key value from the iterator at index 0 will be used.
+//
+// The iterators are merged with a min heap of their next entries, so each entry
+// costs O(log n) comparisons for n iterators, which keeps the merge of a wide L0
+// cheap.
 func NewMergeSort(ctx context.Context, iterators ...KVIterator) *MergeSort {
 	ms := &MergeSort{
 		iterators: iterators,
@@ -36,7 +53,7 @@ func NewMergeSort(ctx context.Context, iterators ...KVIterator) *MergeSort {
 	// Initialize the heap with the first element from each iterator
 	for i, iter := range iterators {
 		if kv, ok := iter.NextEntry(ctx); ok {
-			heap.Push(&ms.heap, heapItem{kv: kv, index: i})
+			ms.heap = append(ms.heap, heapItem{kv: kv, index: i})
 		}
 
 		if warn := iter.Warnings(); warn != nil {
@@ -48,12 +65,42 @@ func NewMergeSort(ctx context.Context, iterators ...KVIterator) *MergeSort {
 	return ms
 }
 
+// WithMergeOperator sets the MergeOperator which combines the merge operands of a
+// key with the older entries of the key, and returns the MergeSort. NextEntry
+// returns a merge operand only if no value or tombstone of the key is older than
+// the operand, which Next applies to an absent value.
+func (m *MergeSort) WithMergeOperator(op types.MergeOperator) *MergeSort {
+	m.mergeOperator = op
+	return m
+}
+
+// WithRawMergeOperands makes NextEntry return the merge operands of a key as they
+// are stored rather than only the newest entry of the key, and returns the
+// MergeSort. The operands of a key are returned from newest to oldest, followed
+// by the older value or tombstone they apply to, if any. The MergeSort must not
+// have a MergeOperator.
+func (m *MergeSort) WithRawMergeOperands() *MergeSort {
+	m.rawMergeOperands = true
+	return m
+}
+
+// WithRangeTombstones sets the range tombstones which apply to the entries of the
+// iterators, and returns the MergeSort. Entries covered by a range tombstone are
+// returned as tombstones.
+func (m *MergeSort) WithRangeTombstones(tombstones types.RangeTombstones) *MergeSort {
+	m.rangeTombstones = tombstones
+	return m
+}
+
 func (m *MergeSort) Next(ctx context.Context) (types.KeyValue, bool) {
 	for {
 		entry, ok := m.NextEntry(ctx)
 		if !ok {
 			return types.KeyValue{}, false
 		}
+		if m.mergeOperator != nil {
+			entry.Value = types.ResolveMerge(m.mergeOperator, entry.Key, entry.Value)
+		}
 		if !entry.Value.IsTombstone() {
 			return types.KeyValue{Key: entry.Key, Value: entry.Value.Value}, true
 		}
@@ -64,19 +111,18 @@ func (m *MergeSort) Next(ctx context.Context) (types.KeyValue, bool) {
 // a tombstone of a deleted key-value pair.
 func (m *MergeSort) NextEntry(ctx context.Context) (types.RowEntry, bool) {
 	for m.heap.Len() > 0 {
-		item := heap.Pop(&m.heap).(heapItem)
-		result := item.kv
-
-		// Push the next item from the same iterator
-		if nextKV, ok := m.iterators[item.index].NextEntry(ctx); ok {
-			heap.Push(&m.heap, heapItem{kv: nextKV, index: item.index})
-		} else {
-			m.warn.Merge(m.iterators[item.index].Warnings())
-		}
+		result := m.pop(ctx)
 
 		// Check if this key is different from the last one
 		if !bytes.Equal(result.Key, m.lastKey) {
 			m.lastKey = result.Key
+			m.lastMerge = result.Value.Kind == types.KindMerge
+			return m.merge(ctx, result), true
+		}
+
+		// The older entries of a key follow its merge operands if they are raw
+		if m.rawMergeOperands && m.lastMerge {
+			m.lastMerge = result.Value.Kind == types.KindMerge
 			return result, true
 		}
 
@@ -85,11 +131,46 @@ func (m *MergeSort) NextEntry(ctx context.Context) (types.RowEntry, bool) {
 	return types.RowEntry{}, false
 }
 
+// pop removes the entry at the top of the heap, and replaces it with the next
+// entry from the same iterator, which is sifted down in a single pass. The entry
+// is returned as a tombstone if a range tombstone covers it.
+func (m *MergeSort) pop(ctx context.Context) types.RowEntry {
+	item := m.heap[0]
+	if nextKV, ok := m.iterators[item.index].NextEntry(ctx); ok {
+		m.heap[0].kv = nextKV
+		heap.Fix(&m.heap, 0)
+	} else {
+		heap.Pop(&m.heap)
+		m.warn.Merge(m.iterators[item.index].Warnings())
+	}
+	return m.rangeTombstones.Apply(item.kv)
+}
+
+// merge applies the merge operand of the entry to the older entries of the same
+// key, until a value or tombstone of the key is reached. The older entries are
+// the duplicates of the key which follow the entry in the heap.
+func (m *MergeSort) merge(ctx context.Context, entry types.RowEntry) types.RowEntry {
+	for m.mergeOperator != nil && entry.Value.Kind == types.KindMerge &&
+		m.heap.Len() > 0 && bytes.Equal(m.heap[0].kv.Key, entry.Key) {
+		older := m.pop(ctx)
+		entry.Value = types.MergeValue(m.mergeOperator, entry.Key, older.Value, entry.Value.Value)
+	}
+	return entry
+}
+
 // Warnings returns types.ErrWarn if there was a warning during iteration.
 func (m *MergeSort) Warnings() *types.ErrWarn {
 	return &m.warn
 }
 
+// Close closes each of the merged iterators, see Closer
+func (m *MergeSort) Close() {
+	for _, it := range m.iterators {
+		Close(it)
+	}
+	m.heap = m.heap[:0]
+}
+
 // heapItem is used in the Sorted Heap
 type heapItem struct {
 	kv    types.RowEntry
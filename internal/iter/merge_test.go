@@ -1,13 +1,18 @@
 package iter_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"strconv"
 	"testing"
 
+	"github.com/samber/mo"
 	"github.com/stretchr/testify/assert"
 
 	assert2 "github.com/slatedb/slatedb-go/internal/assert"
 	"github.com/slatedb/slatedb-go/internal/iter"
+	"github.com/slatedb/slatedb-go/internal/types"
 )
 
 func TestMergeUniqueIteratorPrecedence(t *testing.T) {
@@ -109,3 +114,145 @@ func TestMergeSortTwoIteratorsPrecedence(t *testing.T) {
 	_, ok := mergeIter.Next(context.Background())
 	assert.False(t, ok, "Expected no more entries")
 }
+
+// appendOperator merges operands by appending them to the existing value
+type appendOperator struct{}
+
+func (appendOperator) Merge(_ []byte, existing mo.Option[[]byte], operand []byte) []byte {
+	return append(bytes.Clone(existing.OrEmpty()), operand...)
+}
+
+func TestMergeSortMergeOperator(t *testing.T) {
+	operand := func(key string, value string) types.RowEntry {
+		return types.RowEntry{Key: []byte(key), Value: types.Value{Kind: types.KindMerge, Value: []byte(value)}}
+	}
+	newest := iter.NewEntryIterator(operand("aaaa", "3"), operand("bbbb", "2"), operand("cccc", "1"))
+	middle := iter.NewEntryIterator(operand("aaaa", "2"), operand("bbbb", "1"))
+	oldest := iter.NewEntryIterator(
+		types.RowEntry{Key: []byte("aaaa"), Value: types.Value{Value: []byte("1")}},
+		types.RowEntry{Key: []byte("bbbb"), Value: types.Value{Kind: types.KindTombStone}},
+	)
+
+	mergeIter := iter.NewMergeSort(context.Background(), newest, middle, oldest).
+		WithMergeOperator(appendOperator{})
+
+	// The operands are applied to the oldest value of the key
+	entry, ok := mergeIter.NextEntry(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, types.Value{Value: []byte("123")}, entry.Value)
+
+	// Operands applied to a tombstone start from an absent value
+	entry, ok = mergeIter.NextEntry(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, types.Value{Value: []byte("12")}, entry.Value)
+
+	// An operand with no older value remains an operand, which Next resolves
+	kv, ok := mergeIter.Next(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, types.KeyValue{Key: []byte("cccc"), Value: []byte("1")}, kv)
+
+	_, ok = mergeIter.Next(context.Background())
+	assert.False(t, ok, "Expected no more entries")
+}
+
+func TestMergeSortRawMergeOperands(t *testing.T) {
+	operand := func(key string, value string) types.RowEntry {
+		return types.RowEntry{Key: []byte(key), Value: types.Value{Kind: types.KindMerge, Value: []byte(value)}}
+	}
+	value := func(key string, value string) types.RowEntry {
+		return types.RowEntry{Key: []byte(key), Value: types.Value{Value: []byte(value)}}
+	}
+	newest := iter.NewEntryIterator(operand("aaaa", "3"), operand("bbbb", "2"), value("cccc", "2"))
+	middle := iter.NewEntryIterator(operand("aaaa", "2"), operand("bbbb", "1"), value("cccc", "1"))
+	oldest := iter.NewEntryIterator(
+		value("aaaa", "1"),
+		types.RowEntry{Key: []byte("bbbb"), Value: types.Value{Kind: types.KindTombStone}},
+	)
+
+	mergeIter := iter.NewMergeSort(context.Background(), newest, middle, oldest).WithRawMergeOperands()
+
+	// The operands of a key are returned from newest to oldest, followed by the
+	// value they apply to, and the entries older than a value are skipped
+	var entries []types.RowEntry
+	for {
+		entry, ok := mergeIter.NextEntry(context.Background())
+		if !ok {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	assert.Equal(t, []types.RowEntry{
+		operand("aaaa", "3"), operand("aaaa", "2"), value("aaaa", "1"),
+		operand("bbbb", "2"), operand("bbbb", "1"),
+		{Key: []byte("bbbb"), Value: types.Value{Kind: types.KindTombStone}},
+		value("cccc", "2"),
+	}, entries)
+}
+
+func TestMergeSortRangeTombstones(t *testing.T) {
+	entry := func(key string, seq uint64) types.RowEntry {
+		return types.RowEntry{Key: []byte(key), Value: types.Value{Value: []byte(key)}, Seq: seq}
+	}
+	newer := iter.NewEntryIterator(entry("bbbb", 5))
+	older := iter.NewEntryIterator(entry("aaaa", 1), entry("bbbb", 2), entry("cccc", 3), entry("dddd", 4))
+
+	// The tombstone deletes the older keys in [aaaa, dddd), but not the newer bbbb
+	mergeIter := iter.NewMergeSort(context.Background(), newer, older).
+		WithRangeTombstones(types.RangeTombstones{{Start: []byte("aaaa"), End: []byte("dddd"), Seq: 4}})
+
+	var keys []string
+	for {
+		kv, ok := mergeIter.Next(context.Background())
+		if !ok {
+			break
+		}
+		keys = append(keys, string(kv.Key))
+	}
+	assert.Equal(t, []string{"bbbb", "dddd"}, keys)
+}
+
+func TestMergeSortManyIterators(t *testing.T) {
+	// Every iterator holds every key, and the first iterator which holds a key
+	// takes precedence
+	const width, keys = 50, 20
+	iters := make([]iter.KVIterator, 0, width)
+	for i := 0; i < width; i++ {
+		it := iter.NewEntryIterator()
+		for k := i % 3; k < keys; k++ {
+			it.Add([]byte(fmt.Sprintf("key%02d", k)), []byte(strconv.Itoa(i)))
+		}
+		iters = append(iters, it)
+	}
+
+	mergeIter := iter.NewMergeSort(context.Background(), iters...)
+	for k := 0; k < keys; k++ {
+		assert2.NextEntry(t, mergeIter, []byte(fmt.Sprintf("key%02d", k)), []byte("0"))
+	}
+	_, ok := mergeIter.Next(context.Background())
+	assert.False(t, ok, "Expected no more entries")
+}
+
+func BenchmarkMergeSort(b *testing.B) {
+	const width, keys = 64, 256
+	entries := make([][]types.RowEntry, width)
+	for i := range entries {
+		for k := 0; k < keys; k++ {
+			key := []byte(fmt.Sprintf("key%06d", k*width+i))
+			entries[i] = append(entries[i], types.RowEntry{Key: key, Value: types.Value{Value: key}})
+		}
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		iters := make([]iter.KVIterator, 0, width)
+		for i := range entries {
+			iters = append(iters, iter.NewEntryIterator(entries[i]...))
+		}
+		mergeIter := iter.NewMergeSort(context.Background(), iters...)
+		for {
+			if _, ok := mergeIter.NextEntry(context.Background()); !ok {
+				break
+			}
+		}
+	}
+}
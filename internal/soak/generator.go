@@ -0,0 +1,82 @@
+package soak
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+var ErrCorruptValue = errors.New("corrupt soak value")
+
+// valueHeaderSize is the size of the sequence number and checksum
+// which prefix every generated value
+const valueHeaderSize = common.SizeOfUint64 + common.SizeOfUint32
+
+// Generator produces the keys and values written by the soak workload. Values
+// embed the sequence number of the write which produced them along with a
+// checksum, so a reader can tell which write it observed and detect corruption.
+type Generator struct {
+	rnd       *rand.Rand
+	numKeys   int
+	valueSize int
+}
+
+// NewGenerator returns a Generator which is not safe for concurrent use,
+// each worker should use its own Generator.
+func NewGenerator(seed int64, numKeys int, valueSize int) *Generator {
+	return &Generator{
+		rnd:       rand.New(rand.NewSource(seed)),
+		numKeys:   numKeys,
+		valueSize: valueSize,
+	}
+}
+
+// Key returns the key at index i of the key space
+func Key(i int) []byte {
+	return []byte(fmt.Sprintf("key-%08d", i))
+}
+
+// KeyIndex returns a random index of the key space owned by the worker. Keys
+// are partitioned between workers, so writes to the same key are never
+// concurrent and the order of writes to a key is the order of their sequences.
+func (g *Generator) KeyIndex(worker int, workers int) int {
+	owned := (g.numKeys - worker + workers - 1) / workers
+	return g.rnd.Intn(owned)*workers + worker
+}
+
+// Intn returns a random number in [0, n)
+func (g *Generator) Intn(n int) int {
+	return g.rnd.Intn(n)
+}
+
+// Value returns a value for the key which records the sequence of the write
+func (g *Generator) Value(key []byte, seq uint64) []byte {
+	buf := make([]byte, valueHeaderSize+g.valueSize)
+	binary.BigEndian.PutUint64(buf, seq)
+	g.rnd.Read(buf[valueHeaderSize:])
+	binary.BigEndian.PutUint32(buf[common.SizeOfUint64:], valueChecksum(key, buf))
+	return buf
+}
+
+// DecodeValue returns the sequence of the write which produced the value
+func DecodeValue(key []byte, value []byte) (uint64, error) {
+	if len(value) < valueHeaderSize {
+		return 0, fmt.Errorf("%w: value of key '%s' is %d bytes", ErrCorruptValue, key, len(value))
+	}
+	checksum := binary.BigEndian.Uint32(value[common.SizeOfUint64:])
+	if checksum != valueChecksum(key, value) {
+		return 0, fmt.Errorf("%w: checksum mismatch for key '%s'", ErrCorruptValue, key)
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// valueChecksum covers the key, the sequence and the payload of the value
+func valueChecksum(key []byte, value []byte) uint32 {
+	crc := crc32.ChecksumIEEE(key)
+	crc = crc32.Update(crc, crc32.IEEETable, value[:common.SizeOfUint64])
+	return crc32.Update(crc, crc32.IEEETable, value[valueHeaderSize:])
+}
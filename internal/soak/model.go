@@ -0,0 +1,193 @@
+package soak
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samber/mo"
+)
+
+type Op byte
+
+const (
+	OpPut    Op = 'P'
+	OpDelete Op = 'D'
+)
+
+// maxHistory is the number of events kept for each key for diagnostics
+const maxHistory = 32
+
+// Event is reported by a worker for every write it issues, and again with
+// Acked set once the write is durable.
+type Event struct {
+	Op    Op
+	Key   string
+	Seq   uint64
+	Acked bool
+}
+
+// String encodes the Event as a single line understood by ParseEvent
+func (e Event) String() string {
+	status := "issue"
+	if e.Acked {
+		status = "ack"
+	}
+	return fmt.Sprintf("%s %c %s %d", status, e.Op, e.Key, e.Seq)
+}
+
+// ParseEvent decodes an Event encoded with Event.String
+func ParseEvent(line string) (Event, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 4 || len(fields[1]) != 1 || (fields[0] != "issue" && fields[0] != "ack") {
+		return Event{}, fmt.Errorf("malformed event '%s'", line)
+	}
+	seq, err := strconv.ParseUint(fields[3], 10, 64)
+	if err != nil {
+		return Event{}, fmt.Errorf("malformed sequence in event '%s': %w", line, err)
+	}
+	return Event{Op: Op(fields[1][0]), Key: fields[2], Seq: seq, Acked: fields[0] == "ack"}, nil
+}
+
+// Write is a single Put or Delete of a key
+type Write struct {
+	Op  Op     `json:"op"`
+	Seq uint64 `json:"seq"`
+}
+
+func (w Write) String() string {
+	if w.Seq == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%c@%d", w.Op, w.Seq)
+}
+
+type keyState struct {
+	// durable is the latest write known to be durable, a zero Seq means there is none
+	durable Write
+	// pending are the writes issued after durable which have not been acknowledged
+	pending []Write
+	history []string
+}
+
+// Violation describes an observation which breaks one of the invariants
+type Violation struct {
+	Key      string   `json:"key"`
+	Reason   string   `json:"reason"`
+	Observed string   `json:"observed"`
+	Durable  Write    `json:"durable"`
+	Pending  []Write  `json:"pending"`
+	History  []string `json:"history"`
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("key '%s': %s (observed %s, durable %s, pending %v)",
+		v.Key, v.Reason, v.Observed, v.Durable, v.Pending)
+}
+
+// Model is the oracle of the soak test. It tracks the writes issued and
+// acknowledged by the workers, and verifies the state of each key observed
+// after a restart against the invariants:
+//   - No lost writes, an acknowledged write is never replaced by an older write
+//   - No resurrections, a key is never present after an acknowledged delete
+//     unless a later Put was issued
+//   - Monotonic sequences, the observed sequence of a key never goes backwards
+type Model struct {
+	keys map[string]*keyState
+}
+
+func NewModel() *Model {
+	return &Model{keys: make(map[string]*keyState)}
+}
+
+// Apply records an Event reported by a worker
+func (m *Model) Apply(e Event) {
+	state := m.state(e.Key)
+	write := Write{Op: e.Op, Seq: e.Seq}
+	state.record(e.String())
+
+	if !e.Acked {
+		state.pending = append(state.pending, write)
+		return
+	}
+
+	// Writes to a key are issued in order, so every write issued before
+	// the acknowledged write has been replaced by it.
+	if write.Seq > state.durable.Seq {
+		state.durable = write
+	}
+	for len(state.pending) > 0 && state.pending[0].Seq <= write.Seq {
+		state.pending = state.pending[1:]
+	}
+}
+
+// Verify checks the observed sequence of the key, where None means the key was
+// not found. The observed state becomes the durable state of the key, as it
+// was read from the DB after a restart.
+func (m *Model) Verify(key string, observed mo.Option[uint64]) *Violation {
+	state := m.state(key)
+	defer func() { state.pending = nil }()
+
+	seq, present := observed.Get()
+	if present {
+		state.record(fmt.Sprintf("observed P@%d", seq))
+		if seq == state.durable.Seq && state.durable.Op == OpPut {
+			return nil
+		}
+		for _, w := range state.pending {
+			if w.Seq == seq && w.Op == OpPut {
+				state.durable = w
+				return nil
+			}
+		}
+
+		reason := "observed a value which was never written"
+		if seq < state.durable.Seq {
+			reason = "sequence went backwards, an acknowledged write was lost"
+			if state.durable.Op == OpDelete {
+				reason = "deleted key was resurrected"
+			}
+		}
+		return state.violation(key, reason, fmt.Sprintf("P@%d", seq))
+	}
+
+	state.record("observed absent")
+	if state.durable.Seq == 0 || state.durable.Op == OpDelete {
+		return nil
+	}
+	// Use the latest pending delete, any later pending put was lost before it was acknowledged
+	for i := len(state.pending) - 1; i >= 0; i-- {
+		if state.pending[i].Op == OpDelete {
+			state.durable = state.pending[i]
+			return nil
+		}
+	}
+	return state.violation(key, "acknowledged write was lost", "absent")
+}
+
+func (m *Model) state(key string) *keyState {
+	state, ok := m.keys[key]
+	if !ok {
+		state = &keyState{}
+		m.keys[key] = state
+	}
+	return state
+}
+
+func (s *keyState) record(event string) {
+	s.history = append(s.history, event)
+	if len(s.history) > maxHistory {
+		s.history = s.history[len(s.history)-maxHistory:]
+	}
+}
+
+func (s *keyState) violation(key string, reason string, observed string) *Violation {
+	return &Violation{
+		Key:      key,
+		Reason:   reason,
+		Observed: observed,
+		Durable:  s.durable,
+		Pending:  append([]Write(nil), s.pending...),
+		History:  append([]string(nil), s.history...),
+	}
+}
@@ -0,0 +1,145 @@
+package soak_test
+
+import (
+	"testing"
+
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slatedb/slatedb-go/internal/soak"
+)
+
+func apply(m *soak.Model, events ...soak.Event) {
+	for _, e := range events {
+		m.Apply(e)
+	}
+}
+
+func TestModel(t *testing.T) {
+	put := func(seq uint64, acked bool) soak.Event {
+		return soak.Event{Op: soak.OpPut, Key: "key", Seq: seq, Acked: acked}
+	}
+	del := func(seq uint64, acked bool) soak.Event {
+		return soak.Event{Op: soak.OpDelete, Key: "key", Seq: seq, Acked: acked}
+	}
+
+	for _, test := range []struct {
+		name     string
+		events   []soak.Event
+		observed mo.Option[uint64]
+		reason   string
+	}{
+		{
+			name:     "Acknowledged Put",
+			events:   []soak.Event{put(1, false), put(1, true)},
+			observed: mo.Some[uint64](1),
+		},
+		{
+			name:     "Pending Put Survived",
+			events:   []soak.Event{put(1, false), put(1, true), put(2, false)},
+			observed: mo.Some[uint64](2),
+		},
+		{
+			name:     "Pending Put Lost",
+			events:   []soak.Event{put(1, false), put(1, true), put(2, false)},
+			observed: mo.Some[uint64](1),
+		},
+		{
+			name:     "Pending Delete Survived",
+			events:   []soak.Event{put(1, false), put(1, true), del(2, false)},
+			observed: mo.None[uint64](),
+		},
+		{
+			name:     "Never Written",
+			observed: mo.None[uint64](),
+		},
+		{
+			name:     "Lost Write",
+			events:   []soak.Event{put(1, false), put(1, true)},
+			observed: mo.None[uint64](),
+			reason:   "acknowledged write was lost",
+		},
+		{
+			name:     "Sequence Went Backwards",
+			events:   []soak.Event{put(1, false), put(1, true), put(2, false), put(2, true)},
+			observed: mo.Some[uint64](1),
+			reason:   "sequence went backwards, an acknowledged write was lost",
+		},
+		{
+			name:     "Resurrection",
+			events:   []soak.Event{put(1, false), put(1, true), del(2, false), del(2, true)},
+			observed: mo.Some[uint64](1),
+			reason:   "deleted key was resurrected",
+		},
+		{
+			name:     "Phantom Value",
+			observed: mo.Some[uint64](7),
+			reason:   "observed a value which was never written",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m := soak.NewModel()
+			apply(m, test.events...)
+			v := m.Verify("key", test.observed)
+			if test.reason == "" {
+				assert.Nil(t, v)
+				return
+			}
+			require.NotNil(t, v)
+			assert.Equal(t, test.reason, v.Reason)
+			assert.NotEmpty(t, v.History)
+		})
+	}
+
+	t.Run("Observed State Becomes Durable", func(t *testing.T) {
+		m := soak.NewModel()
+		apply(m, put(1, false), put(1, true), put(2, false))
+		require.Nil(t, m.Verify("key", mo.Some[uint64](2)))
+
+		// The pending put survived the restart, so it may not be lost afterward
+		v := m.Verify("key", mo.Some[uint64](1))
+		require.NotNil(t, v)
+		assert.Equal(t, "sequence went backwards, an acknowledged write was lost", v.Reason)
+	})
+}
+
+func TestParseEvent(t *testing.T) {
+	for _, e := range []soak.Event{
+		{Op: soak.OpPut, Key: "key-00000001", Seq: 42},
+		{Op: soak.OpDelete, Key: "key-00000002", Seq: 43, Acked: true},
+	} {
+		parsed, err := soak.ParseEvent(e.String())
+		require.NoError(t, err)
+		assert.Equal(t, e, parsed)
+	}
+
+	_, err := soak.ParseEvent("issue P key")
+	assert.Error(t, err)
+	_, err = soak.ParseEvent("issue P key abc")
+	assert.Error(t, err)
+}
+
+func TestGenerator(t *testing.T) {
+	gen := soak.NewGenerator(1, 100, 32)
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, 2, gen.KeyIndex(2, 3)%3)
+		assert.Less(t, gen.KeyIndex(2, 3), 100)
+	}
+
+	key := soak.Key(1)
+	value := gen.Value(key, 42)
+	seq, err := soak.DecodeValue(key, value)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), seq)
+
+	_, err = soak.DecodeValue(soak.Key(2), value)
+	assert.ErrorIs(t, err, soak.ErrCorruptValue)
+
+	value[len(value)-1] ^= 0xff
+	_, err = soak.DecodeValue(key, value)
+	assert.ErrorIs(t, err, soak.ErrCorruptValue)
+
+	_, err = soak.DecodeValue(key, []byte("short"))
+	assert.ErrorIs(t, err, soak.ErrCorruptValue)
+}
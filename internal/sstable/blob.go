@@ -6,7 +6,8 @@ import (
 	"github.com/slatedb/slatedb-go/slatedb/common"
 )
 
-// NewBytesBlob creates a ReadOnlyBlob from a byte slice for testing purposes
+// NewBytesBlob creates a ReadOnlyBlob from a byte slice, such as an SSTable
+// which is stored inline in the manifest
 func NewBytesBlob(data []byte) common.ReadOnlyBlob {
 	return &bytesBlob{data: data}
 }
@@ -19,9 +19,14 @@ var (
 )
 
 type Block struct {
+	// FirstKey is the user key of the first row of the block
 	FirstKey []byte
 	Data     []byte
 	Offsets  []uint16
+
+	// FormatVersion is the version of the row encoding of the block, which is
+	// not part of the encoded block. See FormatVersion.
+	FormatVersion uint16
 }
 
 // Encode encodes the Block into a byte slice using the following format
@@ -56,6 +61,12 @@ type Block struct {
 // |  +-----------------------------------------+  |
 // +-----------------------------------------------+
 func Encode(b *Block, codec compress.Codec) ([]byte, error) {
+	return EncodeWithDict(b, codec, nil)
+}
+
+// EncodeWithDict encodes the Block like Encode, compressing it with the provided
+// compression dictionary. The same dictionary must be provided to DecodeWithDict.
+func EncodeWithDict(b *Block, codec compress.Codec, dict []byte) ([]byte, error) {
 	bufSize := len(b.Data) + len(b.Offsets)*common.SizeOfUint16 + common.SizeOfUint16
 
 	buf := make([]byte, 0, bufSize)
@@ -66,7 +77,7 @@ func Encode(b *Block, codec compress.Codec) ([]byte, error) {
 	}
 	buf = binary.BigEndian.AppendUint16(buf, uint16(len(b.Offsets)))
 
-	compressed, err := compress.Encode(buf, codec)
+	compressed, err := compress.EncodeWithDict(buf, codec, dict)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +91,22 @@ func Encode(b *Block, codec compress.Codec) ([]byte, error) {
 
 // Decode converts the encoded byte slice into the provided Block
 func Decode(b *Block, input []byte, codec compress.Codec) error {
+	return DecodeWithDict(b, input, codec, nil)
+}
+
+// DecodeWithDict converts the byte slice encoded by EncodeWithDict into the provided Block
+func DecodeWithDict(b *Block, input []byte, codec compress.Codec, dict []byte) error {
+	return DecodeVersion(b, input, codec, dict, FormatVersion)
+}
+
+// DecodeVersion converts the byte slice encoded by EncodeWithDict into the provided
+// Block, whose rows are encoded with the provided version of the row encoding,
+// such as the blocks of an SSTable written by a previous version
+func DecodeVersion(b *Block, input []byte, codec compress.Codec, dict []byte, version uint16) error {
+	rows, ok := rowCodecFor(version)
+	if !ok {
+		return fmt.Errorf("corrupt block: unsupported row format version '%d'", version)
+	}
 	if len(input) < 6 {
 		return errors.New("corrupt block: block is too small; must be at least 6 bytes")
 	}
@@ -87,11 +114,12 @@ func Decode(b *Block, input []byte, codec compress.Codec) error {
 	// last 4 bytes hold the checksum
 	checksumIndex := len(input) - common.SizeOfUint32
 	compressed := input[:checksumIndex]
-	if binary.BigEndian.Uint32(input[checksumIndex:]) != crc32.ChecksumIEEE(compressed) {
-		return common.ErrChecksumMismatch
+	expected, actual := binary.BigEndian.Uint32(input[checksumIndex:]), crc32.ChecksumIEEE(compressed)
+	if expected != actual {
+		return common.NewChecksumError(common.SectionBlock, expected, actual)
 	}
 
-	buf, err := compress.Decode(compressed, codec)
+	buf, err := compress.DecodeWithDict(compressed, codec, dict)
 	if err != nil {
 		return err
 	}
@@ -130,25 +158,36 @@ func Decode(b *Block, input []byte, codec compress.Codec) error {
 		return fmt.Errorf("corrupt block: Block.Offsets must be greater than 0")
 	}
 
-	// Extract the first key in the block
-	keyLen := binary.BigEndian.Uint16(b.Data[b.Offsets[0]:])
-	b.FirstKey = b.Data[b.Offsets[0]+2 : b.Offsets[0]+2+keyLen]
+	// Extract the first key in the block. A corrupt first key is left empty, as
+	// the Iterator skips corrupt rows and reports them as warnings.
+	b.FirstKey = nil
+	if first, err := rows.PeekAtKey(b.Data[b.Offsets[0]:], nil); err == nil {
+		b.FirstKey = rows.UserKey(first.keySuffix)
+	}
+	b.FormatVersion = version
 
 	return nil
 }
 
 type Builder struct {
-	offsets   []uint16
-	data      []byte
-	blockSize uint64
-	firstKey  []byte
+	offsets    []uint16
+	data       []byte
+	blockSize  uint64
+	maxEntries uint32
+	firstKey   []byte
+
+	// firstInternalKey is the internal key of the first row, which the keys of
+	// the rows share their prefix with
+	firstInternalKey []byte
+	// keyBuf holds the internal key of the row being added
+	keyBuf []byte
 }
 
-// NewBuilder builds a block of key values in the v0RowCodec
+// NewBuilder builds a block of key values in the v1RowCodec
 // format along with the Block.Offsets which point to the
 // beginning of each key/value.
 //
-// See v0RowCodec for on disk format of the key values.
+// See v1RowCodec for on disk format of the key values.
 func NewBuilder(blockSize uint64) *Builder {
 	return &Builder{
 		offsets:   make([]uint16, 0),
@@ -157,30 +196,50 @@ func NewBuilder(blockSize uint64) *Builder {
 	}
 }
 
+// NewBuilderWithMaxEntries returns a Builder like NewBuilder, which also limits
+// the number of key values in the block to maxEntries, so the binary search of a
+// block of many small keys stays shallow. Zero only limits the block size.
+func NewBuilderWithMaxEntries(blockSize uint64, maxEntries uint32) *Builder {
+	b := NewBuilder(blockSize)
+	b.maxEntries = maxEntries
+	return b
+}
+
 func (b *Builder) curBlockSize() int {
 	return common.SizeOfUint16 + // number of key-value pairs in the block
 		(len(b.offsets) * common.SizeOfUint16) + // offsets
 		len(b.data) // Row entries already in the block
 }
 
+// Add adds the row of the key, which is stored under the internal key of the
+// key, the sequence number and the kind of the row
 func (b *Builder) Add(key []byte, row Row) bool {
 	assert.True(len(key) > 0, "key must not be empty")
-	row.keyPrefixLen = computePrefixLen(b.firstKey, key)
-	row.keySuffix = key[row.keyPrefixLen:]
+	kind := row.Value.Kind
+	if row.Value.IsTombstone() {
+		kind = types.KindTombStone
+	}
+	b.keyBuf = types.AppendInternalKey(b.keyBuf[:0], key, row.Seq, kind)
+	row.keyPrefixLen = computePrefixLen(b.firstInternalKey, b.keyBuf)
+	row.keySuffix = b.keyBuf[row.keyPrefixLen:]
 
 	// If adding the key-value pair would exceed the block size limit, don't add it.
 	// (Unless the block is empty, in which case, allow the block to exceed the limit.)
 	// NOTE: This is the current block size, plus the size of a new offset in block.Offsets,
 	// plus the size of the new row to be added.
-	if uint64(b.curBlockSize()+common.SizeOfUint16+v0Size(row)) > b.blockSize && !b.IsEmpty() {
+	if uint64(b.curBlockSize()+common.SizeOfUint16+v1Size(row)) > b.blockSize && !b.IsEmpty() {
+		return false
+	}
+	if b.maxEntries > 0 && len(b.offsets) >= int(b.maxEntries) {
 		return false
 	}
 
 	b.offsets = append(b.offsets, uint16(len(b.data)))
-	b.data = append(b.data, v0RowCodec.Encode(row)...)
+	b.data = append(b.data, v1RowCodec.Encode(row)...)
 
 	if b.firstKey == nil {
 		b.firstKey = bytes.Clone(key)
+		b.firstInternalKey = bytes.Clone(b.keyBuf)
 	}
 	return true
 }
@@ -196,14 +255,25 @@ func (b *Builder) IsEmpty() bool {
 	return len(b.offsets) == 0
 }
 
+// Reset empties the Builder so it can build another block, reusing the buffers
+// of the previous block. A Block returned by Build shares these buffers, so it
+// must be encoded or copied before the Builder is Reset.
+func (b *Builder) Reset() {
+	b.offsets = b.offsets[:0]
+	b.data = b.data[:0]
+	b.firstKey = nil
+	b.firstInternalKey = nil
+}
+
 func (b *Builder) Build() (*Block, error) {
 	if b.IsEmpty() {
 		return nil, ErrEmptyBlock
 	}
 	return &Block{
-		FirstKey: b.firstKey,
-		Offsets:  b.offsets,
-		Data:     b.data,
+		FirstKey:      b.firstKey,
+		Offsets:       b.offsets,
+		Data:          b.data,
+		FormatVersion: FormatVersion,
 	}, nil
 }
 
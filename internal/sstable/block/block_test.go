@@ -37,6 +37,41 @@ func TestNewBuilder(t *testing.T) {
 	assert.Equal(t, b.Offsets, decoded.Offsets)
 }
 
+func TestBuilderReset(t *testing.T) {
+	bb := block.NewBuilder(4096)
+	assert.True(t, bb.AddValue([]byte("key1"), []byte("value1")))
+	assert.True(t, bb.AddValue([]byte("key2"), []byte("value2")))
+	b, err := bb.Build()
+	require.NoError(t, err)
+	first, err := block.Encode(b, compress.CodecNone)
+	require.NoError(t, err)
+
+	bb.Reset()
+	assert.True(t, bb.IsEmpty())
+	_, err = bb.Build()
+	assert.ErrorIs(t, err, block.ErrEmptyBlock)
+
+	// The reused builder builds the same block as a new builder
+	assert.True(t, bb.AddValue([]byte("other"), []byte("value3")))
+	b, err = bb.Build()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("other"), b.FirstKey)
+
+	expected := block.NewBuilder(4096)
+	assert.True(t, expected.AddValue([]byte("other"), []byte("value3")))
+	e, err := expected.Build()
+	require.NoError(t, err)
+	assert.Equal(t, e.Data, b.Data)
+	assert.Equal(t, e.Offsets, b.Offsets)
+
+	// The block encoded before the reset is unchanged
+	var decoded block.Block
+	require.NoError(t, block.Decode(&decoded, first, compress.CodecNone))
+	it := block.NewIterator(&decoded)
+	assert2.NextEntry(t, it, []byte("key1"), []byte("value1"))
+	assert2.NextEntry(t, it, []byte("key2"), []byte("value2"))
+}
+
 func TestBlockCompression(t *testing.T) {
 	bb := block.NewBuilder(4096)
 	assert.True(t, bb.IsEmpty())
@@ -15,15 +15,26 @@ type Iterator struct {
 	block       *Block
 	offsetIndex uint64
 	warn        types.ErrWarn
-	firstKey    []byte
+	// firstKey is the full key of the first row, which is the internal key of
+	// the row unless the block has v0 rows
+	firstKey []byte
+	// rows decodes the rows of the block, nil if the version of the row
+	// encoding of the block is not supported
+	rows rowCodec
 }
 
 // NewIterator constructs a block.Iterator that starts at the beginning of the block
 func NewIterator(block *Block) *Iterator {
-	return &Iterator{
+	iter := &Iterator{
 		block:       block,
 		offsetIndex: 0,
 	}
+	rows, ok := rowCodecFor(block.FormatVersion)
+	if !ok {
+		iter.warn.Add("unsupported row format version '%d'", block.FormatVersion)
+	}
+	iter.rows = rows
+	return iter
 }
 
 // NewIteratorAtKey Construct a block.Iterator that starts at the given key, or at the first
@@ -32,13 +43,17 @@ func NewIteratorAtKey(block *Block, key []byte) (*Iterator, error) {
 	if len(block.Offsets) <= 0 {
 		return nil, errors.New("number of block.Offsets must be greater than zero")
 	}
+	rows, ok := rowCodecFor(block.FormatVersion)
+	if !ok {
+		return nil, fmt.Errorf("unsupported row format version '%d'", block.FormatVersion)
+	}
 	var warn types.ErrWarn
 
 	// First key in the block should be a full key. -- the block.Builder ensures this is true --
 	// If it is corrupt we could lose all key values in the block IF they are all suffixes of the
 	// first key. As such, we search for the first full key in the block until we find one and begin
 	// iteration there. The fast path assumes the first block is valid and is a full key.
-	first, idx, ok := firstFullKey(block, &warn)
+	first, idx, ok := firstFullKey(block, rows, &warn)
 	if !ok {
 		// If we couldn't find a first full key, and there are no warnings
 		// we must assume the block is empty or not a block
@@ -49,28 +64,30 @@ func NewIteratorAtKey(block *Block, key []byte) (*Iterator, error) {
 	}
 
 	// If the first block is our key, then use that
-	if bytes.Equal(first.keySuffix, key) {
+	if bytes.Equal(rows.UserKey(first.keySuffix), key) {
 		return &Iterator{
 			firstKey:    bytes.Clone(first.keySuffix),
 			offsetIndex: uint64(0),
 			block:       block,
 			warn:        warn,
+			rows:        rows,
 		}, nil
 	}
 
 	// Start searching for keys at the first key found; which is idx=0 unless
-	// the first key was corrupt.
+	// the first key was corrupt. The newest version of a key comes first, so the
+	// first row whose user key is not less than the key is its newest version.
 	index := sort.Search(len(block.Offsets)-idx, func(i int) bool {
 		if block.Offsets[i+idx] > uint16(len(block.Data)) {
 			warn.Add("block.Offset[%d] = %d is out of bounds", i+idx, block.Offsets[i+idx])
 			return false
 		}
-		p, err := v0RowCodec.PeekAtKey(block.Data[block.Offsets[i+idx]:], first.keySuffix)
+		p, err := rows.PeekAtKey(block.Data[block.Offsets[i+idx]:], first.keySuffix)
 		if err != nil {
 			warn.Add("while peeking at block.Offset[%d]: %s", i+idx, err)
 			return false
 		}
-		return bytes.Compare(v0FullKey(p, first.keySuffix), key) >= 0
+		return bytes.Compare(rows.UserKey(fullKey(p, first.keySuffix)), key) >= 0
 	})
 
 	return &Iterator{
@@ -78,6 +95,7 @@ func NewIteratorAtKey(block *Block, key []byte) (*Iterator, error) {
 		offsetIndex: uint64(index + idx),
 		block:       block,
 		warn:        warn,
+		rows:        rows,
 	}, nil
 }
 
@@ -98,27 +116,29 @@ func (iter *Iterator) Next(ctx context.Context) (types.KeyValue, bool) {
 }
 
 func (iter *Iterator) NextEntry(ctx context.Context) (types.RowEntry, bool) {
-	if iter.offsetIndex >= uint64(len(iter.block.Offsets)) {
+	if iter.rows == nil || iter.offsetIndex >= uint64(len(iter.block.Offsets)) {
 		return types.RowEntry{}, false
 	}
 
 	data := iter.block.Data
 	offset := iter.block.Offsets[iter.offsetIndex]
 
-	r, err := v0RowCodec.Decode(data[offset:], iter.firstKey)
+	r, err := iter.rows.Decode(data[offset:], iter.firstKey)
 	if err != nil {
 		iter.warn.Add("while decoding block.Offset[%d]: %s", iter.offsetIndex, err)
 		return types.RowEntry{}, false
 	}
 
 	if iter.firstKey == nil {
-		iter.firstKey = v0FullKey(*r, nil)
+		iter.firstKey = fullKey(*r, nil)
 	}
 
 	iter.offsetIndex += 1
 	return types.RowEntry{
-		Key:   v0FullKey(*r, iter.firstKey),
-		Value: r.ToValue(),
+		Key:     iter.rows.UserKey(fullKey(*r, iter.firstKey)),
+		Value:   r.ToValue(),
+		Seq:     r.Seq,
+		Created: r.CreatedAt,
 	}, true
 }
 
@@ -130,9 +150,9 @@ func (iter *Iterator) Warnings() *types.ErrWarn {
 // firstFullKey finds the first full key -- which is a key with no keyPrefixLen set -- and
 // returns that key, and index found as the first key in the block. If we encounter a corrupted
 // key, we consider subsequent keys for the next full key in the block and return that instead.
-func firstFullKey(block *Block, warn *types.ErrWarn) (Row, int, bool) {
+func firstFullKey(block *Block, rows rowCodec, warn *types.ErrWarn) (Row, int, bool) {
 	for i, offset := range block.Offsets {
-		row, err := v0RowCodec.PeekAtKey(block.Data[offset:], nil)
+		row, err := rows.PeekAtKey(block.Data[offset:], nil)
 		if err != nil {
 			warn.Add("while peeking at key at offset %d: %v", offset, err)
 			continue
@@ -4,15 +4,58 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math"
 	"time"
 
+	"github.com/samber/mo"
+
 	"github.com/slatedb/slatedb-go/internal/assert"
 	"github.com/slatedb/slatedb-go/internal/types"
 	"github.com/slatedb/slatedb-go/slatedb/common"
 )
 
-var v0RowCodec v0Codec
+// FormatVersion is the version of the row encoding written by Builder. It is
+// persisted in the manifest so that clients can detect an incompatible format,
+// and in the SsTableInfo of each SSTable so that readers decode the blocks of
+// SSTables written by previous versions.
+//
+// Version 0 rows hold the user key, and the sequence number and kind of the row
+// separately. Version 1 rows hold the internal key of the row, see
+// types.InternalKey.
+const FormatVersion uint16 = 1
+
+// MinFormatVersion is the oldest version of the row encoding Iterator decodes
+const MinFormatVersion uint16 = 0
+
+var (
+	v0RowCodec v0Codec
+	v1RowCodec v1Codec
+)
+
+// rowCodec encodes and decodes the rows of a version of the row encoding. Every
+// version begins a row with the length of the prefix the key of the row shares
+// with the first key of the block, and the suffix of the key.
+type rowCodec interface {
+	Encode(r Row) []byte
+	Decode(data []byte, firstKey []byte) (*Row, error)
+	PeekAtKey(data []byte, firstKey []byte) (Row, error)
+
+	// UserKey returns the user key of the full key of a row
+	UserKey(key []byte) []byte
+}
+
+// rowCodecFor returns the rowCodec of the version of the row encoding, or false
+// if the version is not supported
+func rowCodecFor(version uint16) (rowCodec, bool) {
+	switch version {
+	case 0:
+		return v0RowCodec, true
+	case 1:
+		return v1RowCodec, true
+	}
+	return nil, false
+}
 
 type v0RowFlags uint8
 
@@ -20,8 +63,15 @@ const (
 	flagTombstone v0RowFlags = 1 << iota
 	flagHasExpire
 	flagHasCreate
+	flagHasChecksum
+	// flagMerge marks the value of the row as a merge operand, see types.KindMerge
+	flagMerge
 
 	v0ErrPrefix = "corrupt v0 row: "
+	v1ErrPrefix = "corrupt v1 row: "
+
+	// v1FlagsMask holds the flags of a v1 row, whose kind is held by its key
+	v1FlagsMask = flagHasExpire | flagHasCreate | flagHasChecksum
 )
 
 type Row struct {
@@ -30,8 +80,8 @@ type Row struct {
 	CreatedAt time.Time
 	Value     types.Value
 
-	// Used by v0Codec, might consider moving into a separate
-	// v0Row structure if future row versions are radically different
+	// The key of the row, stripped of the prefix it shares with the first key of
+	// the block. It is the user key in v0 rows, and the internal key in v1 rows.
 	keyPrefixLen uint16
 	keySuffix    []byte
 }
@@ -40,14 +90,18 @@ func (r Row) ToValue() types.Value {
 	if r.Value.IsTombstone() {
 		return types.Value{Kind: types.KindTombStone}
 	}
-	return types.Value{Kind: types.KindKeyValue, Value: r.Value.Value}
+	kind := types.KindKeyValue
+	if r.Value.Kind == types.KindMerge {
+		kind = types.KindMerge
+	}
+	return types.Value{Kind: kind, Value: r.Value.Value, Checksum: r.Value.Checksum}
 }
 
-// V0EstimateBlockSize estimates the block size that will result given the
+// EstimateBlockSize estimates the block size that will result given the
 // provided list of types.KeyValue. This estimate assumes no compression is used.
 // This function is useful in tests to calculate the block size needed to force
 // the creation of multiple blocks.
-func V0EstimateBlockSize(kv []types.KeyValue) uint64 {
+func EstimateBlockSize(kv []types.KeyValue) uint64 {
 	b := Builder{}
 
 	// The minimum block size includes all the required offset and length fields
@@ -56,20 +110,20 @@ func V0EstimateBlockSize(kv []types.KeyValue) uint64 {
 	for _, kv := range kv {
 		r := Row{
 			Value:     types.Value{Value: kv.Value},
-			keySuffix: kv.Key,
+			keySuffix: types.InternalKey(kv.Key, 0, types.KindKeyValue),
 		}
-		result += v0Size(r)
+		result += v1Size(r)
 		result += common.SizeOfUint16 // The size of a single uint16 offset
 	}
 	return uint64(result + common.SizeOfUint32) // The size of the checksum
 }
 
-// v0FullKey restores the full key by prepending the prefix to the key suffix.
-// Keys in a v0RowCodec are stored with prefix stripped off to reduce the storage size.
+// fullKey restores the full key by prepending the prefix to the key suffix.
+// Keys in a block are stored with prefix stripped off to reduce the storage size.
 //
 // NOTE: We don't store the full key in the Row to save space, it is up to the
 // caller to keep track of the first valid key in the block.
-func v0FullKey(r Row, prefix []byte) []byte {
+func fullKey(r Row, prefix []byte) []byte {
 	assert.True(r.keyPrefixLen <= uint16(len(prefix)),
 		"row key prefix length %d; exceeds prefix length '%d'", r.keyPrefixLen, len(prefix))
 	result := make([]byte, int(r.keyPrefixLen)+len(r.keySuffix))
@@ -83,25 +137,34 @@ func v0Flags(r Row) v0RowFlags {
 	if r.Value.IsTombstone() {
 		flags |= flagTombstone
 	}
-	if r.ExpireAt.Nanosecond() != 0 {
+	if !r.ExpireAt.IsZero() {
 		flags |= flagHasExpire
 	}
-	if r.CreatedAt.Nanosecond() != 0 {
+	if !r.CreatedAt.IsZero() {
 		flags |= flagHasCreate
 	}
+	if !r.Value.IsTombstone() && r.Value.Checksum.IsPresent() {
+		flags |= flagHasChecksum
+	}
+	if r.Value.Kind == types.KindMerge {
+		flags |= flagMerge
+	}
 	return flags
 }
 
 func v0Size(r Row) int {
 	size := 2 + 2 + len(r.keySuffix) + 8 + 1 // keyPrefixLen + keySuffixLen + keySuffix + Seq + Flags
-	if r.ExpireAt.Nanosecond() != 0 {
+	if !r.ExpireAt.IsZero() {
 		size += 8
 	}
-	if r.CreatedAt.Nanosecond() != 0 {
+	if !r.CreatedAt.IsZero() {
 		size += 8
 	}
 	if !r.Value.IsTombstone() {
 		size += 4 + len(r.Value.Value) // value_len + value
+		if r.Value.Checksum.IsPresent() {
+			size += 4
+		}
 	}
 	return size
 }
@@ -115,14 +178,16 @@ type v0Codec struct{}
 //
 // ```txt
 //
-//	|---------------------------------------------------------------------------------------------------------------------|
-//	|     uint16     |    uint16      |  []byte     | uint64  | uint8     | int64     | int64     | uint32    |  []byte   |
-//	|----------------|----------------|-------------|---------|-----------|-----------|-----------|-----------|-----------|
-//	| KeyPrefixLen   | KeySuffixLen   | KeySuffix   | seq     | flags     | expireAt  | createdAt | valueLen  | value     |
-//	|---------------------------------------------------------------------------------------------------------------------|
+//	|---------------------------------------------------------------------------------------------------------------------------------|
+//	|     uint16     |    uint16      |  []byte     | uint64  | uint8     | int64     | int64     | uint32    |  []byte   | uint32    |
+//	|----------------|----------------|-------------|---------|-----------|-----------|-----------|-----------|-----------|-----------|
+//	| KeyPrefixLen   | KeySuffixLen   | KeySuffix   | seq     | flags     | expireAt  | createdAt | valueLen  | value     | checksum  |
+//	|---------------------------------------------------------------------------------------------------------------------------------|
 //
 // ```
 //
+// Merge operands (flags & Merge != 0) are encoded like values.
+//
 // And for tombstones (flags & Tombstone == 1):
 //
 //	```txt
@@ -144,6 +209,7 @@ type v0Codec struct{}
 // | `createdAt`      | `int64`  | Optional, only has value when flags & FlagHasCreate    |
 // | `value_len`      | `uint32` | Length of the value                                    |
 // | `value`          | `[]byte` | Value bytes                                            |
+// | `checksum`       | `uint32` | Optional, only has value when flags & FlagHasChecksum  |
 //
 // NOTE: both expireAt and createdAt are epoch
 func (c v0Codec) Encode(r Row) []byte {
@@ -169,11 +235,11 @@ func (c v0Codec) Encode(r Row) []byte {
 	offset++
 
 	// Encode ExpireAt and CreatedAt if present
-	if r.ExpireAt.Nanosecond() != 0 {
+	if !r.ExpireAt.IsZero() {
 		binary.BigEndian.PutUint64(output[offset:], uint64(r.ExpireAt.UnixMilli()))
 		offset += 8
 	}
-	if r.CreatedAt.Nanosecond() != 0 {
+	if !r.CreatedAt.IsZero() {
 		binary.BigEndian.PutUint64(output[offset:], uint64(r.CreatedAt.UnixMilli()))
 		offset += 8
 	}
@@ -183,6 +249,10 @@ func (c v0Codec) Encode(r Row) []byte {
 		binary.BigEndian.PutUint32(output[offset:], uint32(len(r.Value.Value)))
 		offset += 4
 		copy(output[offset:], r.Value.Value)
+		offset += len(r.Value.Value)
+		if checksum, ok := r.Value.Checksum.Get(); ok {
+			binary.BigEndian.PutUint32(output[offset:], checksum)
+		}
 	}
 
 	return output
@@ -252,7 +322,18 @@ func (c v0Codec) Decode(data []byte, firstKey []byte) (*Row, error) {
 		}
 		value := make([]byte, valueLen)
 		copy(value, data[offset:offset+int(valueLen)])
+		offset += int(valueLen)
 		r.Value = types.Value{Value: value}
+		if flags&flagMerge != 0 {
+			r.Value.Kind = types.KindMerge
+		}
+
+		if flags&flagHasChecksum != 0 {
+			if len(data[offset:]) < 4 {
+				return nil, errors.New(v0ErrPrefix + "data length too short for checksum")
+			}
+			r.Value.Checksum = mo.Some(binary.BigEndian.Uint32(data[offset:]))
+		}
 	} else {
 		r.Value = types.Value{Kind: types.KindTombStone}
 	}
@@ -263,11 +344,21 @@ func (c v0Codec) Decode(data []byte, firstKey []byte) (*Row, error) {
 // PeekAtKey returns a Row with only the keyPrefixLen and keySuffix populated where
 // the keySuffix is a sub slice of the provided []byte.
 func (c v0Codec) PeekAtKey(data []byte, firstKey []byte) (Row, error) {
+	return peekAtKey(data, firstKey, v0ErrPrefix)
+}
+
+// UserKey returns the key, as v0 rows hold the user key
+func (c v0Codec) UserKey(key []byte) []byte {
+	return key
+}
+
+// peekAtKey reads the key of a row of any version, see rowCodec
+func peekAtKey(data []byte, firstKey []byte, errPrefix string) (Row, error) {
 	var offset int
 	var r Row
 
 	if len(data) < 4 { // Minimum size: keyPrefixLen + KeySuffixLen
-		return Row{}, errors.New(v0ErrPrefix + "data length too short to peek at row")
+		return Row{}, errors.New(errPrefix + "data length too short to peek at row")
 	}
 
 	// Decode keyPrefixLen and KeySuffixLen
@@ -277,16 +368,206 @@ func (c v0Codec) PeekAtKey(data []byte, firstKey []byte) (Row, error) {
 	offset += 2
 
 	if r.keyPrefixLen > uint16(len(firstKey)) {
-		return Row{}, errors.New(v0ErrPrefix + "key prefix length exceeds length of first key in block")
+		return Row{}, errors.New(errPrefix + "key prefix length exceeds length of first key in block")
 	}
 
 	if len(data[offset:]) < int(keySuffixLen) {
-		return Row{}, errors.New(v0ErrPrefix + "key suffix length exceeds length of block")
+		return Row{}, errors.New(errPrefix + "key suffix length exceeds length of block")
 	}
 	r.keySuffix = data[offset : offset+int(keySuffixLen)]
 	return r, nil
 }
 
+func v1Size(r Row) int {
+	size := 2 + 2 + len(r.keySuffix) + 1 // keyPrefixLen + keySuffixLen + keySuffix + Flags
+	if !r.ExpireAt.IsZero() {
+		size += 8
+	}
+	if !r.CreatedAt.IsZero() {
+		size += 8
+	}
+	if !r.Value.IsTombstone() {
+		size += 4 + len(r.Value.Value) // value_len + value
+		if r.Value.Checksum.IsPresent() {
+			size += 4
+		}
+	}
+	return size
+}
+
+type v1Codec struct{}
+
+// Encode key and value using the binary codec for SlateDB row representation
+// using the `v1` encoding scheme, whose key is the internal key of the row.
+// The sequence number and kind of the row are held by the trailer of the
+// internal key, see types.InternalKey, rather than by the row.
+//
+// The `v1` codec for the key is (for non-tombstones):
+//
+// ```txt
+//
+//	|---------------------------------------------------------------------------------------------------------------------|
+//	|     uint16     |    uint16      |  []byte     | uint8     | int64     | int64     | uint32    |  []byte   | uint32    |
+//	|----------------|----------------|-------------|-----------|-----------|-----------|-----------|-----------|-----------|
+//	| KeyPrefixLen   | KeySuffixLen   | KeySuffix   | flags     | expireAt  | createdAt | valueLen  | value     | checksum  |
+//	|---------------------------------------------------------------------------------------------------------------------|
+//
+// ```
+//
+// And for tombstones, whose internal key has the kind types.KindTombStone:
+//
+//	```txt
+//	|-----------------------------------------------|-----------|-----------|
+//	|     uint16     |    uint16      |  []byte     | uint8     | int64     |
+//	|----------------|----------------|-------------|-----------|-----------|
+//	| KeyPrefixLen   | KeySuffixLen   |  KeySuffix  | flags     | createdAt |
+//	|-----------------------------------------------------------------------|
+//	```
+//
+// The flags of a v1 row are the flags of a v0 row except flagTombstone and
+// flagMerge, and the optional fields are present under the same flags.
+func (c v1Codec) Encode(r Row) []byte {
+	output := make([]byte, v1Size(r))
+	var offset int
+
+	// Encode keyPrefixLen, KeySuffixLen and keySuffix
+	binary.BigEndian.PutUint16(output[offset:], r.keyPrefixLen)
+	offset += 2
+	binary.BigEndian.PutUint16(output[offset:], uint16(len(r.keySuffix)))
+	offset += 2
+	copy(output[offset:], r.keySuffix)
+	offset += len(r.keySuffix)
+
+	// Encode flags
+	output[offset] = uint8(v0Flags(r) & v1FlagsMask)
+	offset++
+
+	// Encode ExpireAt and CreatedAt if present
+	if !r.ExpireAt.IsZero() {
+		binary.BigEndian.PutUint64(output[offset:], uint64(r.ExpireAt.UnixMilli()))
+		offset += 8
+	}
+	if !r.CreatedAt.IsZero() {
+		binary.BigEndian.PutUint64(output[offset:], uint64(r.CreatedAt.UnixMilli()))
+		offset += 8
+	}
+
+	// Encode value for non-tombstones
+	if !r.Value.IsTombstone() {
+		binary.BigEndian.PutUint32(output[offset:], uint32(len(r.Value.Value)))
+		offset += 4
+		copy(output[offset:], r.Value.Value)
+		offset += len(r.Value.Value)
+		if checksum, ok := r.Value.Checksum.Get(); ok {
+			binary.BigEndian.PutUint32(output[offset:], checksum)
+		}
+	}
+
+	return output
+}
+
+func (c v1Codec) Decode(data []byte, firstKey []byte) (*Row, error) {
+	if len(data) < 5 { // Minimum size: keyPrefixLen + KeySuffixLen + Flags
+		return nil, errors.New(v1ErrPrefix + "data length too short to decode a row")
+	}
+
+	var offset int
+	var r Row
+
+	// Decode keyPrefixLen, KeySuffixLen and keySuffix
+	r.keyPrefixLen = binary.BigEndian.Uint16(data[offset:])
+	offset += 2
+	keySuffixLen := binary.BigEndian.Uint16(data[offset:])
+	offset += 2
+	if r.keyPrefixLen > uint16(len(firstKey)) {
+		return nil, errors.New(v1ErrPrefix + "key prefix length exceeds length of first key in block")
+	}
+	if len(data[offset:]) < int(keySuffixLen) {
+		return nil, errors.New(v1ErrPrefix + "key suffix length exceeds length of block")
+	}
+	r.keySuffix = make([]byte, keySuffixLen)
+	copy(r.keySuffix, data[offset:offset+int(keySuffixLen)])
+	offset += int(keySuffixLen)
+
+	// Decode Seq and kind from the trailer of the internal key
+	_, seq, kind, err := types.ParseInternalKey(fullKey(r, firstKey))
+	if err != nil {
+		return nil, errors.New(v1ErrPrefix + err.Error())
+	}
+	if kind != types.KindKeyValue && kind != types.KindTombStone && kind != types.KindMerge {
+		return nil, fmt.Errorf(v1ErrPrefix+"unknown kind '%d'", kind)
+	}
+	r.Seq = seq
+
+	// Decode flags
+	if len(data[offset:]) < 1 {
+		return nil, errors.New(v1ErrPrefix + "data length too short for flags")
+	}
+	flags := v0RowFlags(data[offset])
+	offset++
+	if flags&^v1FlagsMask != 0 {
+		return nil, fmt.Errorf(v1ErrPrefix+"unknown flags '%08b'", flags)
+	}
+
+	// Decode expire_ts and create_ts if present
+	if flags&flagHasExpire != 0 {
+		if len(data[offset:]) < 8 {
+			return nil, errors.New(v1ErrPrefix + "data length too short for expire")
+		}
+		r.ExpireAt = time.UnixMilli(int64(binary.BigEndian.Uint64(data[offset:])))
+		offset += 8
+	}
+	if flags&flagHasCreate != 0 {
+		if len(data[offset:]) < 8 {
+			return nil, errors.New(v1ErrPrefix + "data length too short for create")
+		}
+		r.CreatedAt = time.UnixMilli(int64(binary.BigEndian.Uint64(data[offset:])))
+		offset += 8
+	}
+
+	if kind == types.KindTombStone {
+		r.Value = types.Value{Kind: types.KindTombStone}
+		return &r, nil
+	}
+
+	// Decode value for non-tombstones
+	if len(data[offset:]) < 4 {
+		return nil, errors.New(v1ErrPrefix + "data length too short for value length")
+	}
+	valueLen := binary.BigEndian.Uint32(data[offset:])
+	offset += 4
+	if len(data[offset:]) < int(valueLen) {
+		return nil, errors.New(v1ErrPrefix + "data length too short for value")
+	}
+	value := make([]byte, valueLen)
+	copy(value, data[offset:offset+int(valueLen)])
+	offset += int(valueLen)
+	r.Value = types.Value{Value: value, Kind: kind}
+
+	if flags&flagHasChecksum != 0 {
+		if len(data[offset:]) < 4 {
+			return nil, errors.New(v1ErrPrefix + "data length too short for checksum")
+		}
+		r.Value.Checksum = mo.Some(binary.BigEndian.Uint32(data[offset:]))
+	}
+	return &r, nil
+}
+
+// PeekAtKey returns a Row with only the keyPrefixLen and keySuffix populated where
+// the keySuffix is a sub slice of the provided []byte.
+func (c v1Codec) PeekAtKey(data []byte, firstKey []byte) (Row, error) {
+	return peekAtKey(data, firstKey, v1ErrPrefix)
+}
+
+// UserKey returns the user key of the internal key. A corrupt key shorter than
+// the trailer of an internal key is returned as is, and fails to decode.
+func (c v1Codec) UserKey(key []byte) []byte {
+	if len(key) < types.InternalKeyTrailerLen {
+		return key
+	}
+	return types.UserKey(key)
+}
+
 // computePrefixLen calculates the length of the common prefix between two byte slices.
 // Source: https://users.rust-lang.org/t/how-to-find-common-prefix-of-two-byte-slices-effectively/25815/4
 func computePrefixLen(lhs, rhs []byte) uint16 {
@@ -2,10 +2,12 @@ package block
 
 import (
 	"bytes"
+	"context"
 	"testing"
 	"time"
 
 	"github.com/kapetan-io/tackle/random"
+	"github.com/samber/mo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -45,6 +47,20 @@ func TestRowFlags(t *testing.T) {
 			},
 			expected: flagHasCreate,
 		},
+		{
+			name: "WithChecksum",
+			row: Row{
+				Value: types.Value{Value: []byte("value"), Checksum: mo.Some(uint32(1))},
+			},
+			expected: flagHasChecksum,
+		},
+		{
+			name: "Merge",
+			row: Row{
+				Value: types.Value{Kind: types.KindMerge, Value: []byte("operand")},
+			},
+			expected: flagMerge,
+		},
 		{
 			name: "AllFlags",
 			row: Row{
@@ -201,6 +217,30 @@ func TestRowCodecV0EncodeAndDecode(t *testing.T) {
 			},
 			firstKeyPrefix: []byte("timecreate"),
 		},
+		{
+			name: "RowWithChecksum",
+			row: Row{
+				keyPrefixLen: 3,
+				keySuffix:    []byte("sum"),
+				Seq:          1,
+				Value:        types.Value{Value: []byte("value"), Checksum: mo.Some(types.ValueChecksum([]byte("value")))},
+				CreatedAt:    time.Time{},
+				ExpireAt:     time.UnixMilli(10),
+			},
+			firstKeyPrefix: []byte("checksum"),
+		},
+		{
+			name: "MergeRow",
+			row: Row{
+				keyPrefixLen: 3,
+				keySuffix:    []byte("merge"),
+				Seq:          3,
+				Value:        types.Value{Kind: types.KindMerge, Value: []byte("operand")},
+				CreatedAt:    time.UnixMilli(3),
+				ExpireAt:     time.Time{},
+			},
+			firstKeyPrefix: []byte("merged"),
+		},
 		{
 			name: "TombstoneRow",
 			row: Row{
@@ -237,6 +277,18 @@ func TestRowCodecV0EncodeAndDecode(t *testing.T) {
 			},
 			firstKeyPrefix: []byte("bigseq"),
 		},
+		{
+			name: "CreatedAtWholeSecond",
+			row: Row{
+				keyPrefixLen: 0,
+				keySuffix:    []byte("created"),
+				Seq:          7,
+				Value:        types.Value{Value: []byte("value")},
+				CreatedAt:    time.UnixMilli(1700000000000),
+				ExpireAt:     time.Time{},
+			},
+			firstKeyPrefix: []byte(""),
+		},
 		{
 			name: "LargeValue",
 			row: Row{
@@ -286,13 +338,159 @@ func TestRowCodecV0EncodeAndDecode(t *testing.T) {
 			assert.Equal(t, &tt.row, decoded)
 
 			// Test restoring full key
-			fullKey := v0FullKey(tt.row, tt.firstKeyPrefix)
+			fullKey := fullKey(tt.row, tt.firstKeyPrefix)
 			assert.Equal(t, append(tt.firstKeyPrefix[:tt.row.keyPrefixLen], decoded.keySuffix...), fullKey)
 			//t.Logf("FullKey: %s", fullKey)
 		})
 	}
 }
 
+func TestRowCodecV1EncodeAndDecode(t *testing.T) {
+	created := time.UnixMilli(1700000000000)
+	tests := []struct {
+		name  string
+		key   []byte
+		row   Row
+		first []byte
+	}{
+		{
+			name: "Value",
+			key:  []byte("key"),
+			row:  Row{Seq: 7, CreatedAt: created, Value: types.Value{Value: []byte("value")}},
+		},
+		{
+			name: "Tombstone",
+			key:  []byte("key"),
+			row:  Row{Seq: 8, ExpireAt: created, Value: types.Value{Kind: types.KindTombStone}},
+		},
+		{
+			name: "MergeWithChecksum",
+			key:  []byte("key"),
+			row: Row{Seq: 9, Value: types.Value{Kind: types.KindMerge, Value: []byte("operand"),
+				Checksum: mo.Some(uint32(42))}},
+		},
+		{
+			name:  "SharedPrefix",
+			key:   []byte("key2"),
+			row:   Row{Seq: types.MaxSeq, Value: types.Value{Value: []byte("value")}},
+			first: types.InternalKey([]byte("key1"), 1, types.KindKeyValue),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			internalKey := types.InternalKey(tt.key, tt.row.Seq, tt.row.Value.Kind)
+			tt.row.keyPrefixLen = computePrefixLen(tt.first, internalKey)
+			tt.row.keySuffix = internalKey[tt.row.keyPrefixLen:]
+
+			encoded := v1RowCodec.Encode(tt.row)
+			assert.Len(t, encoded, v1Size(tt.row))
+			decoded, err := v1RowCodec.Decode(encoded, tt.first)
+			require.NoError(t, err)
+			assert.Equal(t, &tt.row, decoded)
+			assert.Equal(t, tt.key, v1RowCodec.UserKey(fullKey(*decoded, tt.first)))
+		})
+	}
+}
+
+func TestV1RowCodecDecodeErrors(t *testing.T) {
+	key := types.InternalKey([]byte("k"), 1, types.KindKeyValue)
+	row := func(key []byte, rest ...byte) []byte {
+		data := []byte{0, 0, 0, byte(len(key))}
+		return append(append(data, key...), rest...)
+	}
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{
+			name:        "TooShort",
+			input:       []byte{0, 0, 0},
+			expectedErr: v1ErrPrefix + "data length too short to decode a row",
+		},
+		{
+			name:        "KeyShorterThanTrailer",
+			input:       row([]byte("k"), 0),
+			expectedErr: v1ErrPrefix + "internal key of 1 bytes is shorter than its trailer",
+		},
+		{
+			name:        "UnknownKind",
+			input:       row(types.InternalKey([]byte("k"), 1, types.Kind(9)), 0),
+			expectedErr: v1ErrPrefix + "unknown kind '9'",
+		},
+		{
+			name:        "MissingFlags",
+			input:       row(key),
+			expectedErr: v1ErrPrefix + "data length too short for flags",
+		},
+		{
+			name:        "V0Flags",
+			input:       row(key, byte(flagTombstone)),
+			expectedErr: v1ErrPrefix + "unknown flags",
+		},
+		{
+			name:        "InvalidValue",
+			input:       row(key, 0, 0, 0, 0, 5),
+			expectedErr: v1ErrPrefix + "data length too short for value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v1RowCodec.Decode(tt.input, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expectedErr)
+		})
+	}
+}
+
+// TestDecodeV0Block verifies blocks of v0 rows written by previous versions are
+// still decoded and searched
+func TestDecodeV0Block(t *testing.T) {
+	var blk Block
+	var first []byte
+	for i, key := range []string{"key1", "key2", "key3"} {
+		r := Row{Seq: uint64(i + 1), Value: types.Value{Value: []byte("value" + key[3:])}}
+		if key == "key2" {
+			r.Value = types.Value{Kind: types.KindTombStone}
+		}
+		r.keyPrefixLen = computePrefixLen(first, []byte(key))
+		r.keySuffix = []byte(key)[r.keyPrefixLen:]
+		blk.Offsets = append(blk.Offsets, uint16(len(blk.Data)))
+		blk.Data = append(blk.Data, v0RowCodec.Encode(r)...)
+		if first == nil {
+			first = []byte(key)
+		}
+	}
+	encoded, err := Encode(&blk, compress.CodecNone)
+	require.NoError(t, err)
+
+	// The version of the rows is recorded by the SSTable rather than by the block
+	var decoded Block
+	require.NoError(t, DecodeVersion(&decoded, encoded, compress.CodecNone, nil, 0))
+	assert.Equal(t, []byte("key1"), decoded.FirstKey)
+	assert.Equal(t, uint16(0), decoded.FormatVersion)
+
+	iter := NewIterator(&decoded)
+	for i, key := range []string{"key1", "key2", "key3"} {
+		entry, ok := iter.NextEntry(context.Background())
+		require.True(t, ok, iter.Warnings().String())
+		assert.Equal(t, []byte(key), entry.Key)
+		assert.Equal(t, uint64(i+1), entry.Seq)
+		assert.Equal(t, key == "key2", entry.Value.IsTombstone())
+	}
+
+	iter, err = NewIteratorAtKey(&decoded, []byte("key3"))
+	require.NoError(t, err)
+	kv, ok := iter.Next(context.Background())
+	require.True(t, ok)
+	assert.Equal(t, []byte("value3"), kv.Value)
+
+	err = DecodeVersion(&decoded, encoded, compress.CodecNone, nil, FormatVersion+1)
+	assert.ErrorContains(t, err, "unsupported row format version")
+}
+
 func TestComputePrefix(t *testing.T) {
 	prefix := random.String("", 200)
 	tests := []struct {
@@ -416,7 +614,7 @@ func BenchmarkComputePrefix(b *testing.B) {
 	}
 }
 
-func TestV0EstimateBlockSize(t *testing.T) {
+func TestEstimateBlockSize(t *testing.T) {
 	bb := NewBuilder(4096)
 	assert.True(t, bb.IsEmpty())
 	assert.True(t, bb.AddValue([]byte("k"), []byte("v")))
@@ -427,6 +625,6 @@ func TestV0EstimateBlockSize(t *testing.T) {
 	blk, err := Encode(b, compress.CodecNone)
 	assert.NoError(t, err)
 
-	estimatedSize := V0EstimateBlockSize([]types.KeyValue{{Key: []byte("k"), Value: []byte("v")}})
+	estimatedSize := EstimateBlockSize([]types.KeyValue{{Key: []byte("k"), Value: []byte("v")}})
 	assert.Equal(t, uint64(len(blk)), estimatedSize)
 }
@@ -50,17 +50,13 @@ func (f *Filter) HasKey(key []byte) bool {
 // |  +-----------------------------------------+  |
 // +-----------------------------------------------+
 func Encode(f Filter, codec compress.Codec) ([]byte, error) {
-	buf := make([]byte, 2+len(f.Data))
-	binary.BigEndian.PutUint16(buf[:2], f.NumProbes)
-	copy(buf[2:], f.Data)
-
-	compressed, err := compress.Encode(buf, codec)
+	compressed, err := compress.Encode(Marshal(f), codec)
 	if err != nil {
 		return nil, err
 	}
 
 	// Make a new buffer exactly the size of the compressed plus the checksum
-	buf = make([]byte, 0, len(compressed)+common.SizeOfUint32)
+	buf := make([]byte, 0, len(compressed)+common.SizeOfUint32)
 	buf = append(buf, compressed...)
 	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(compressed))
 	return buf, nil
@@ -74,18 +70,35 @@ func Decode(data []byte, codec compress.Codec) (Filter, error) {
 
 	checksumIndex := len(data) - common.SizeOfUint32
 	compressed := data[:checksumIndex]
-	if binary.BigEndian.Uint32(data[checksumIndex:]) != crc32.ChecksumIEEE(compressed) {
-		return Filter{}, common.ErrChecksumMismatch
+	expected, actual := binary.BigEndian.Uint32(data[checksumIndex:]), crc32.ChecksumIEEE(compressed)
+	if expected != actual {
+		return Filter{}, common.NewChecksumError(common.SectionFilter, expected, actual)
 	}
 
 	buf, err := compress.Decode(compressed, codec)
 	if err != nil {
 		return Filter{}, err
 	}
+	return Unmarshal(buf)
+}
 
-	numProbes := binary.BigEndian.Uint16(buf[:2])
+// Marshal returns the number of probes followed by the bit array of the
+// bloom filter, without compression or checksum
+func Marshal(f Filter) []byte {
+	buf := make([]byte, 2+len(f.Data))
+	binary.BigEndian.PutUint16(buf[:2], f.NumProbes)
+	copy(buf[2:], f.Data)
+	return buf
+}
+
+// Unmarshal returns the bloom filter contained in a buffer produced by Marshal.
+// The returned Filter references buf rather than copying it.
+func Unmarshal(buf []byte) (Filter, error) {
+	if len(buf) < 2 {
+		return Filter{}, errors.New("corrupt filter: filter is too small; must be at least 2 bytes")
+	}
 	return Filter{
-		NumProbes: numProbes,
+		NumProbes: binary.BigEndian.Uint16(buf[:2]),
 		Data:      buf[2:],
 	}, nil
 }
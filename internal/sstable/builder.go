@@ -2,7 +2,7 @@ package sstable
 
 import (
 	"bytes"
-	"encoding/binary"
+	"time"
 
 	"github.com/gammazero/deque"
 	"github.com/samber/mo"
@@ -11,15 +11,15 @@ import (
 	"github.com/slatedb/slatedb-go/internal/compress"
 	"github.com/slatedb/slatedb-go/internal/flatbuf"
 	"github.com/slatedb/slatedb-go/internal/sstable/block"
-	"github.com/slatedb/slatedb-go/internal/sstable/bloom"
 	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
 )
 
 // Table is the in memory representation of an SSTable
 type Table struct {
 	Info *Info
 
-	Bloom mo.Option[bloom.Filter]
+	Filter mo.Option[Filter]
 
 	// Blocks is a list of blocks contained in the Table
 	// NOTE: The final block added to the queue includes
@@ -27,6 +27,16 @@ type Table struct {
 	Blocks *deque.Deque[[]byte]
 }
 
+// EncodedSize returns the number of bytes of the encoded Table, including the
+// index, filter and Info
+func (t *Table) EncodedSize() uint64 {
+	var size uint64
+	for i := 0; i < t.Blocks.Len(); i++ {
+		size += uint64(len(t.Blocks.At(i)))
+	}
+	return size
+}
+
 // Builder builds the SSTable in the format outlined
 // in the diagram below.
 //
@@ -58,14 +68,32 @@ type Table struct {
 // |  +-----------------------------------------+  |
 // |                                               |
 // |  +-----------------------------------------+  |
-// |  |  bloom.Filter (if MinFilterKeys met)    |  |
+// |  |  Filter (if MinFilterKeys met)          |  |
+// |  +-----------------------------------------+  |
+// |  |  Checksum (4 bytes)                     |  |
+// |  +-----------------------------------------+  |
+// |                                               |
+// |  +-----------------------------------------+  |
+// |  |  Compression Dictionary (if trained)    |  |
 // |  +-----------------------------------------+  |
 // |  |  Checksum (4 bytes)                     |  |
 // |  +-----------------------------------------+  |
 // |                                               |
 // |  +-----------------------------------------+  |
+// |  |  Range Tombstones (if any)              |  |
+// |  +-----------------------------------------+  |
+// |  |  Checksum (4 bytes)                     |  |
+// |  +-----------------------------------------+  |
+// |                                               |
+// |  +-----------------------------------------+  |
+// |  |  Index Partitions (if partitioned)      |  |
+// |  |  flatbuf.SsTableIndexT + Checksum       |  |
+// |  |  ...                                    |  |
+// |  +-----------------------------------------+  |
+// |                                               |
+// |  +-----------------------------------------+  |
 // |  |  flatbuf.SsTableIndexT                  |  |
-// |  |  (List of Block Offsets)                |  |
+// |  |  (List of Block or Partition Offsets)   |  |
 // |  |  - Block Offset (Start of Block)        |  |
 // |  |  - FirstKey of this Block               |  |
 // |  |  ...                                    |  |
@@ -76,22 +104,34 @@ type Table struct {
 // |  +-----------------------------------------+  |
 // |  |  flatbuf.SsTableInfoT                   |  |
 // |  |  - FirstKey of the SSTable              |  |
-// |  |  - Offset of bloom.Filter               |  |
-// |  |  - Length of bloom.Filter               |  |
+// |  |  - LastKey of the SSTable               |  |
+// |  |  - Entry and Tombstone counts           |  |
+// |  |  - Raw and Compressed sizes             |  |
+// |  |  - Offset of Filter                     |  |
+// |  |  - Length of Filter                     |  |
+// |  |  - Offset of Compression Dictionary     |  |
+// |  |  - Length of Compression Dictionary     |  |
 // |  |  - Offset of flatbuf.SsTableIndexT      |  |
 // |  |  - Length of flatbuf.SsTableIndexT      |  |
 // |  |  - The Compression Codec                |  |
+// |  |  - If the Index is partitioned          |  |
 // |  +-----------------------------------------+  |
 // |  |  Checksum of SsTableInfoT (4 bytes)     |  |
 // |  +-----------------------------------------+  |
 // |                                               |
 // |  +-----------------------------------------+  |
-// |  |  Offset of SsTableInfoT (4 bytes)       |  |
+// |  |  Footer                                 |  |
+// |  |  - Offset of SsTableInfoT (4 bytes)     |  |
+// |  |  - Format Version (2 bytes)             |  |
+// |  |  - Magic Number (4 bytes)               |  |
 // |  +-----------------------------------------+  |
 // +-----------------------------------------------+
 type Builder struct {
-	blockBuilder  *block.Builder
-	filterBuilder *bloom.Builder
+	blockBuilder *block.Builder
+
+	// filterBuilder creates the filter from the keys added to the SSTable, and
+	// only holds their hashes. It is created by the first key added.
+	filterBuilder filter.Builder
 
 	// The metadata for each block held by the SSTableIndex
 	blockMetaList []*flatbuf.BlockMetaT
@@ -99,17 +139,47 @@ type Builder struct {
 	// firstKey is the first key of the first block in the SSTable
 	firstKey mo.Option[[]byte]
 
+	// lastKey is the most recent key added to the SSTable
+	lastKey []byte
+
+	// statistics about the entries added, which are recorded in the Info
+	tombstoneCount uint64
+	rawSize        uint64
+
+	// rangeTombstones are written to the range tombstone section of the SSTable
+	rangeTombstones types.RangeTombstones
+
 	// The encoded/serialized blocks that get added to the SSTable
 	blocks *deque.Deque[[]byte]
 
+	// pendingBlocks are blocks which are not yet encoded, as they await the
+	// compression dictionary which is trained on their contents
+	pendingBlocks []*block.Block
+	pendingSize   uint64
+
+	// dict is the compression dictionary used to compress the blocks, and
+	// dictTrained is true once no more blocks will be used for training.
+	dict        []byte
+	dictTrained bool
+
 	// currentLen is the total length of all existing blocks
 	currentLen uint64
 
-	// if numKeys >= minFilterKeys then we add a BloomFilter
-	// else we don't add BloomFilter since reading smaller set of keys
-	// is likely faster without BloomFilter
+	// if numKeys >= minFilterKeys then we add a Filter
+	// else we don't add Filter since reading smaller set of keys
+	// is likely faster without Filter
 	numKeys uint32
 
+	// writerEpoch is recorded in the Info of WAL SSTables, see SetWriterEpoch
+	writerEpoch uint64
+
+	// encoder encodes the blocks concurrently if Config.EncodeQueue is not zero.
+	// It is started by the first block of the SSTable, and stopped by Build.
+	encoder *blockEncoder
+
+	// encodeTime is the time spent compressing and checksumming blocks
+	encodeTime time.Duration
+
 	// config is the config options used to build the SSTable
 	conf Config
 }
@@ -119,24 +189,70 @@ type Config struct {
 	// BlockSize is the size of each block in the SSTable
 	BlockSize uint64
 
+	// BlockMaxEntries is the maximum number of entries in each block, in addition
+	// to the BlockSize. Blocks of many small keys are otherwise limited only by the
+	// 65535 offsets of a block, which makes the binary search of a seek deeper and
+	// the scan of a block costlier. Zero only limits the BlockSize.
+	BlockMaxEntries uint32
+
 	// MinFilterKeys is the minimum number of keys that must exist in the SSTable
-	// before a bloom filter is created. Reads on SSTables with a small number
-	// of items is faster than looking up in a bloom filter.
+	// before a filter is created. Reads on SSTables with a small number
+	// of items is faster than looking up in a filter.
 	MinFilterKeys uint32
 
+	// FilterBitsPerKey is the number of bits per key of the default bloom filter
 	FilterBitsPerKey uint32
 
+	// FilterPolicy creates the filter of new SSTables and reads the filter of
+	// existing SSTables created by a policy of the same name. If nil, a bloom
+	// filter of FilterBitsPerKey is used.
+	FilterPolicy filter.Policy
+
+	// IndexPartitionThreshold is the maximum number of blocks a flat index may
+	// reference. SSTables with more blocks are written with a partitioned index,
+	// where the top level index references index partitions of up to
+	// IndexPartitionThreshold blocks each. Partitions are loaded on demand, so
+	// reads don't need to load the index of every block. Zero disables partitioning.
+	IndexPartitionThreshold uint64
+
+	// BlockFetchParallelism is the maximum number of concurrent range reads used to
+	// fetch a range of several blocks. The range is split into consecutive parts
+	// which are fetched concurrently and reassembled in order. Zero or one fetches
+	// the range with a single range read.
+	BlockFetchParallelism int
+
+	// ValueChecksums records the types.ValueChecksum of every value added without a
+	// checksum, so readers can retrieve the checksum without hashing the value.
+	// Checksums of values added with a checksum are always recorded.
+	ValueChecksums bool
+
 	// The codec used to compress new SSTables. The compression codec used in
 	// existing SSTables already written disk is encoded into the SSTableInfo and
 	// will be used when decompressing the blocks in that SSTable.
 	Compression compress.Codec
+
+	// CompressionDictSize is the maximum size of the compression dictionary which is
+	// trained on the first blocks of each SSTable and shared by all of its blocks.
+	// A dictionary improves the compression ratio of small blocks. Only used with
+	// compress.CodecZstd, zero disables the dictionary.
+	CompressionDictSize uint64
+
+	// CompressionDictTrainingBytes is the number of bytes of blocks which are
+	// buffered to train the compression dictionary before the blocks are compressed.
+	// Defaults to 100 times the CompressionDictSize.
+	CompressionDictTrainingBytes uint64
+
+	// EncodeQueue is the number of built blocks queued to be compressed and
+	// checksummed by a separate goroutine while the next blocks are built, so
+	// building a large SSTable is not limited by the latency of both. Zero
+	// encodes each block as it is built.
+	EncodeQueue int
 }
 
 // NewBuilder create a builder
 func NewBuilder(conf Config) *Builder {
 	return &Builder{
-		filterBuilder: bloom.NewBuilder(conf.FilterBitsPerKey),
-		blockBuilder:  block.NewBuilder(conf.BlockSize),
+		blockBuilder:  newBlockBuilder(conf),
 		blocks:        deque.New[[]byte](0),
 		blockMetaList: []*flatbuf.BlockMetaT{},
 		firstKey:      mo.None[[]byte](),
@@ -146,6 +262,71 @@ func NewBuilder(conf Config) *Builder {
 	}
 }
 
+// newBlockBuilder returns a block.Builder limited by the BlockSize and BlockMaxEntries
+func newBlockBuilder(conf Config) *block.Builder {
+	return block.NewBuilderWithMaxEntries(conf.BlockSize, conf.BlockMaxEntries)
+}
+
+// Reset empties the Builder so it can build another SSTable with the same Config,
+// reusing the buffers of the previous SSTable where possible. The Table returned
+// by Build does not share any buffers with the Builder, so it remains valid after
+// the Builder is Reset.
+func (b *Builder) Reset() {
+	b.ResetWithConfig(b.conf)
+}
+
+// ResetWithConfig empties the Builder like Reset, and builds the next SSTable with
+// the provided Config.
+func (b *Builder) ResetWithConfig(conf Config) {
+	if b.encoder != nil {
+		// The SSTable was abandoned before it was built
+		_ = b.encoder.finish(func(encodedBlock) error { return nil })
+		b.encoder = nil
+	}
+	if conf.BlockSize != b.conf.BlockSize || conf.BlockMaxEntries != b.conf.BlockMaxEntries {
+		b.blockBuilder = newBlockBuilder(conf)
+	} else {
+		b.blockBuilder.Reset()
+	}
+
+	// Clear the references to the keys of the previous SSTable, so they can be
+	// collected while the Builder is not in use
+	b.filterBuilder = nil
+	clear(b.blockMetaList)
+	b.blockMetaList = b.blockMetaList[:0]
+
+	// The deque of blocks is owned by the Table returned by Build
+	b.blocks = deque.New[[]byte](0)
+	b.firstKey = mo.None[[]byte]()
+	b.lastKey = nil
+	b.tombstoneCount = 0
+	b.rawSize = 0
+	b.rangeTombstones = nil
+	b.pendingBlocks = nil
+	b.pendingSize = 0
+	b.dict = nil
+	b.dictTrained = false
+	b.currentLen = 0
+	b.numKeys = 0
+	b.writerEpoch = 0
+	b.encodeTime = 0
+	b.conf = conf
+}
+
+// SetWriterEpoch records the writer epoch of the client building a WAL SSTable in
+// its Info, so a WAL written by a writer which was fenced by a newer writer can be
+// detected. The epoch is cleared by Reset.
+func (b *Builder) SetWriterEpoch(epoch uint64) {
+	b.writerEpoch = epoch
+}
+
+// AddRangeTombstone adds a range tombstone to the SSTable. Range tombstones are
+// written to their own section rather than the blocks, and don't extend the
+// FirstKey and LastKey of the SSTable.
+func (b *Builder) AddRangeTombstone(tombstone types.RangeTombstone) {
+	b.rangeTombstones = append(b.rangeTombstones, tombstone)
+}
+
 func (b *Builder) AddValue(key []byte, value []byte) error {
 	// TODO(thrawn01): As of now, all of the code assumes if the value is missing it is
 	//  a tombstone. Once we implement transactions we should remove AddValue() method and
@@ -159,16 +340,16 @@ func (b *Builder) AddValue(key []byte, value []byte) error {
 
 func (b *Builder) Add(key []byte, entry types.RowEntry) error {
 	b.numKeys += 1
-	row := block.Row{Value: entry.Value}
+	if b.conf.ValueChecksums && !entry.Value.IsTombstone() && entry.Value.Checksum.IsAbsent() {
+		entry.Value.Checksum = mo.Some(types.ValueChecksum(entry.Value.Value))
+	}
+	row := block.Row{Seq: entry.Seq, CreatedAt: entry.Created, Value: entry.Value}
 
 	if !b.blockBuilder.Add(key, row) {
 		// Create a new block builder and append block data
-		buf, err := b.finishBlock()
-		if err != nil {
+		if err := b.finishBlock(); err != nil {
 			return err
 		}
-		b.currentLen += uint64(len(buf))
-		b.blocks.PushBack(buf)
 
 		addSuccess := b.blockBuilder.Add(key, row)
 		assert.True(addSuccess, "block.Builder.AddValue() failed")
@@ -177,11 +358,22 @@ func (b *Builder) Add(key []byte, entry types.RowEntry) error {
 	if b.firstKey.IsAbsent() {
 		b.firstKey = mo.Some(key)
 	}
+	b.lastKey = key
+	if entry.Value.IsTombstone() {
+		b.tombstoneCount++
+	}
+	b.rawSize += uint64(len(key) + len(entry.Value.Value))
 
+	if b.filterBuilder == nil {
+		b.filterBuilder = filter.NewBuilder(b.conf.filterPolicy())
+	}
 	b.filterBuilder.Add(key)
 	return nil
 }
 
+// NextBlock returns the next block which is built and encoded, if any. Blocks
+// queued for encoding are returned once the queue fills, or by Build, see
+// Config.EncodeQueue.
 func (b *Builder) NextBlock() mo.Option[[]byte] {
 	if b.blocks.Len() == 0 {
 		return mo.None[[]byte]()
@@ -189,52 +381,194 @@ func (b *Builder) NextBlock() mo.Option[[]byte] {
 	return mo.Some(b.blocks.PopFront())
 }
 
-func (b *Builder) finishBlock() ([]byte, error) {
+// finishBlock builds the current block, and appends it to the blocks unless it is
+// buffered for training of the compression dictionary
+func (b *Builder) finishBlock() error {
 	if b.blockBuilder.IsEmpty() {
-		return nil, nil
+		return nil
 	}
 
-	blockBuilder := b.blockBuilder
-	b.blockBuilder = block.NewBuilder(b.conf.BlockSize)
-	blk, err := blockBuilder.Build()
+	blk, err := b.blockBuilder.Build()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if !b.dictTrained && b.usesDict() {
+		// The pending block keeps the buffers of the block builder until it is
+		// encoded, so the next block is built by a new block builder
+		b.blockBuilder = newBlockBuilder(b.conf)
+		b.pendingBlocks = append(b.pendingBlocks, blk)
+		b.pendingSize += uint64(len(blk.Data))
+		if b.pendingSize < b.dictTrainingBytes() {
+			return nil
+		}
+		return b.trainDict()
+	}
+
+	if b.conf.EncodeQueue > 0 {
+		// The block keeps the buffers of the block builder until it is encoded
+		b.blockBuilder = newBlockBuilder(b.conf)
+		return b.appendBlock(blk)
+	}
+	// The block is copied as it is encoded, so the block builder can reuse its buffers
+	err = b.appendBlock(blk)
+	b.blockBuilder.Reset()
+	return err
+}
+
+// appendBlock encodes the block and appends it to the blocks, or queues it for
+// encoding if Config.EncodeQueue is not zero
+func (b *Builder) appendBlock(blk *block.Block) error {
+	if b.conf.EncodeQueue > 0 {
+		if b.encoder == nil {
+			// The dictionary is trained before the first block is encoded
+			b.encoder = startBlockEncoder(b.conf.EncodeQueue, b.conf.Compression, b.dict)
+		}
+		return b.encoder.send(blk, b.receiveBlock)
 	}
 
-	buf, err := block.Encode(blk, b.conf.Compression)
+	start := time.Now()
+	buf, err := block.EncodeWithDict(blk, b.conf.Compression, b.dict)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return b.receiveBlock(encodedBlock{blk: blk, buf: buf, elapsed: time.Since(start)})
+}
 
-	blockMeta := flatbuf.BlockMetaT{Offset: b.currentLen, FirstKey: blk.FirstKey}
+// receiveBlock appends the encoded block to the blocks
+func (b *Builder) receiveBlock(encoded encodedBlock) error {
+	if encoded.err != nil {
+		return encoded.err
+	}
+	blk, buf := encoded.blk, encoded.buf
+	b.encodeTime += encoded.elapsed
+	blockMeta := flatbuf.BlockMetaT{
+		Offset:     b.currentLen,
+		FirstKey:   blk.FirstKey,
+		EntryCount: uint64(len(blk.Offsets)),
+	}
 	b.blockMetaList = append(b.blockMetaList, &blockMeta)
+	b.currentLen += uint64(len(buf))
+	b.blocks.PushBack(buf)
+	return nil
+}
 
-	return buf, nil
+func (b *Builder) usesDict() bool {
+	return b.conf.CompressionDictSize > 0 && b.conf.Compression == compress.CodecZstd
+}
+
+func (b *Builder) dictTrainingBytes() uint64 {
+	if b.conf.CompressionDictTrainingBytes > 0 {
+		return b.conf.CompressionDictTrainingBytes
+	}
+	return b.conf.CompressionDictSize * 100
+}
+
+// trainDict trains the compression dictionary on the pending blocks, then appends
+// the pending blocks compressed using the dictionary. If the blocks don't provide
+// enough data to train a dictionary, the blocks are compressed without one.
+func (b *Builder) trainDict() error {
+	samples := make([][]byte, 0, len(b.pendingBlocks))
+	for _, blk := range b.pendingBlocks {
+		samples = append(samples, blk.Data)
+	}
+
+	dict, err := compress.TrainDict(samples, int(b.conf.CompressionDictSize))
+	if err == nil {
+		b.dict = dict
+	}
+	b.dictTrained = true
+
+	for _, blk := range b.pendingBlocks {
+		if err := b.appendBlock(blk); err != nil {
+			return err
+		}
+	}
+	b.pendingBlocks = nil
+	b.pendingSize = 0
+	return nil
 }
 
 func (b *Builder) Build() (*Table, error) {
-	buf, err := b.finishBlock()
-	if err != nil {
+	queued := b.blocks.Len()
+	if err := b.finishBlock(); err != nil {
 		return nil, err
 	}
+	if len(b.pendingBlocks) > 0 {
+		if err := b.trainDict(); err != nil {
+			return nil, err
+		}
+	}
+	if b.encoder != nil {
+		err := b.encoder.finish(b.receiveBlock)
+		b.encoder = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The final block is returned in the same buffer as the index and info
+	var buf []byte
+	var err error
+	if b.blocks.Len() > queued {
+		buf = b.blocks.PopBack()
+		b.currentLen -= uint64(len(buf))
+	}
 
 	// Write the filter if the total number of keys equals of exceeds minFilterKeys
-	maybeFilter := mo.None[bloom.Filter]()
+	maybeFilter := mo.None[Filter]()
 	filterLen := 0
+	filterBitsPerKey := uint32(0)
 	filterOffset := b.currentLen + uint64(len(buf))
+	policy := b.conf.filterPolicy()
 	if b.numKeys >= b.conf.MinFilterKeys {
-		filter := b.filterBuilder.Build()
-		encodedFilter, err := bloom.Encode(filter, b.conf.Compression)
+		data := policy.CreateFilter(nil)
+		if b.filterBuilder != nil {
+			data = b.filterBuilder.Build()
+		}
+		filtr := Filter{Policy: policy, Data: data}
+		encodedFilter, err := encodeFilter(filtr.Data, b.conf.Compression)
 		if err != nil {
 			return nil, err
 		}
 		filterLen = len(encodedFilter)
 		buf = append(buf, encodedFilter...)
-		maybeFilter = mo.Some(filter)
+		maybeFilter = mo.Some(filtr)
+		filterBitsPerKey = filter.BitsPerKey(policy)
+	}
+
+	// Write the compression dictionary the blocks were compressed with
+	dictOffset := b.currentLen + uint64(len(buf))
+	dictLen := 0
+	if len(b.dict) > 0 {
+		encodedDict := encodeDict(b.dict)
+		dictLen = len(encodedDict)
+		buf = append(buf, encodedDict...)
+	}
+
+	// Write the range tombstones. The offset is left zero without range tombstones,
+	// so the Info of SSTables without range tombstones is unchanged.
+	var rangeTombstoneOffset uint64
+	rangeTombstoneLen := 0
+	if len(b.rangeTombstones) > 0 {
+		encoded := encodeRangeTombstones(b.rangeTombstones)
+		rangeTombstoneOffset = b.currentLen + uint64(len(buf))
+		rangeTombstoneLen = len(encoded)
+		buf = append(buf, encoded...)
 	}
 
-	// Compress and Write the index block
+	// Compress and Write the index partitions if the SSTable has too many blocks
+	// for a flat index, then the top level index which references them.
 	sstIndex := flatbuf.SsTableIndexT{BlockMeta: b.blockMetaList}
+	partitioned := b.conf.IndexPartitionThreshold > 0 &&
+		uint64(len(b.blockMetaList)) > b.conf.IndexPartitionThreshold
+	if partitioned {
+		sstIndex.BlockMeta, buf, err = b.writeIndexPartitions(buf, filterOffset)
+		if err != nil {
+			return nil, err
+		}
+		sstIndex.EndOffset = b.currentLen + uint64(len(buf))
+	}
 	encodedIndex, err := encodeIndex(sstIndex, b.conf.Compression)
 	if err != nil {
 		return nil, err
@@ -253,16 +587,78 @@ func (b *Builder) Build() (*Table, error) {
 		FilterOffset:     filterOffset,
 		FilterLen:        uint64(filterLen),
 		CompressionCodec: b.conf.Compression,
+		IndexPartitioned: partitioned,
+		LastKey:          bytes.Clone(b.lastKey),
+		EntryCount:       uint64(b.numKeys),
+		TombstoneCount:   b.tombstoneCount,
+		RawSize:          b.rawSize,
+		CompressedSize:   filterOffset,
+		FilterPolicy:     policy.Name(),
+
+		CompressionDictOffset: dictOffset,
+		CompressionDictLen:    uint64(dictLen),
+		FilterBitsPerKey:      filterBitsPerKey,
+		BlockSize:             b.conf.BlockSize,
+		RangeTombstoneOffset:  rangeTombstoneOffset,
+		RangeTombstoneLen:     uint64(rangeTombstoneLen),
+		WriterEpoch:           b.writerEpoch,
+		BlockFormatVersion:    block.FormatVersion,
 	}
 	buf = append(buf, EncodeInfo(sstInfo)...)
 
-	// write the metadata offset at the end of the file.
-	buf = binary.BigEndian.AppendUint32(buf, uint32(metaOffset))
+	// write the footer with the index, filter and metadata offsets at the end of the file.
+	buf = appendFooter(buf, footer{
+		IndexOffset:  indexOffset,
+		FilterOffset: filterOffset,
+		InfoOffset:   metaOffset,
+	})
 	b.blocks.PushBack(buf)
 
 	return &Table{
 		Info:   sstInfo,
-		Bloom:  maybeFilter,
+		Filter: maybeFilter,
 		Blocks: b.blocks,
 	}, nil
 }
+
+// EncodeTime returns the time spent compressing and checksumming the blocks of the
+// SSTable, which is complete once the SSTable is built
+func (b *Builder) EncodeTime() time.Duration {
+	return b.encodeTime
+}
+
+// writeIndexPartitions appends index partitions of up to IndexPartitionThreshold blocks
+// each to buf and returns the metadata of each partition for the top level index.
+func (b *Builder) writeIndexPartitions(buf []byte, filterOffset uint64) ([]*flatbuf.BlockMetaT, []byte, error) {
+	var partitions []*flatbuf.BlockMetaT
+	size := int(b.conf.IndexPartitionThreshold)
+
+	for start := 0; start < len(b.blockMetaList); start += size {
+		end := min(start+size, len(b.blockMetaList))
+
+		// The last block of a partition ends where the first block of the
+		// next partition starts, or at the filter for the final partition.
+		endOffset := filterOffset
+		if end < len(b.blockMetaList) {
+			endOffset = b.blockMetaList[end].Offset
+		}
+
+		partition := flatbuf.SsTableIndexT{BlockMeta: b.blockMetaList[start:end], EndOffset: endOffset}
+		var entries uint64
+		for _, meta := range partition.BlockMeta {
+			entries += meta.EntryCount
+		}
+		encoded, err := encodeIndex(partition, b.conf.Compression)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		partitions = append(partitions, &flatbuf.BlockMetaT{
+			Offset:     b.currentLen + uint64(len(buf)),
+			FirstKey:   b.blockMetaList[start].FirstKey,
+			EntryCount: entries,
+		})
+		buf = append(buf, encoded...)
+	}
+	return partitions, buf, nil
+}
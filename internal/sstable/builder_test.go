@@ -1,8 +1,12 @@
 package sstable_test
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -36,8 +40,8 @@ func TestBuilder(t *testing.T) {
 
 		// Check table properties
 		assert.Equal(t, []byte("key1"), table.Info.FirstKey)
-		assert.True(t, table.Bloom.IsAbsent()) // Bloom filter should not be present (less than MinFilterKeys)
-		assert.Equal(t, 1, table.Blocks.Len()) // All keys should fit in one block
+		assert.True(t, table.Filter.IsAbsent()) // Filter should not be present (less than MinFilterKeys)
+		assert.Equal(t, 1, table.Blocks.Len())  // All keys should fit in one block
 
 		// Encode the table
 		encoded := sstable.EncodeTable(table)
@@ -54,7 +58,7 @@ func TestBuilder(t *testing.T) {
 		}
 
 		builder := sstable.NewBuilder(sstable.Config{
-			BlockSize:        block.V0EstimateBlockSize(blocks[0]), // Small block size to force multiple blocks
+			BlockSize:        block.EstimateBlockSize(blocks[0]), // Small block size to force multiple blocks
 			MinFilterKeys:    5,
 			FilterBitsPerKey: 10,
 			Compression:      compress.CodecNone,
@@ -69,7 +73,7 @@ func TestBuilder(t *testing.T) {
 		require.NotNil(t, table)
 
 		assert.True(t, table.Blocks.Len() > 1, "Expected multiple blocks")
-		assert.True(t, table.Bloom.IsPresent(), "Expected Bloom filter to be present")
+		assert.True(t, table.Filter.IsPresent(), "Expected Filter to be present")
 	})
 
 	t.Run("Compression", func(t *testing.T) {
@@ -92,6 +96,65 @@ func TestBuilder(t *testing.T) {
 
 		assert.Equal(t, compress.CodecSnappy, table.Info.CompressionCodec)
 	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		builder := sstable.NewBuilder(sstable.DefaultConfig())
+		require.NoError(t, builder.AddValue([]byte("key1"), []byte("value1")))
+		require.NoError(t, builder.AddValue([]byte("key2"), nil))
+		require.NoError(t, builder.AddValue([]byte("key3"), []byte("value3")))
+
+		table, err := builder.Build()
+		require.NoError(t, err)
+
+		assert.Equal(t, []byte("key1"), table.Info.FirstKey)
+		assert.Equal(t, []byte("key3"), table.Info.LastKey)
+		assert.Equal(t, uint64(3), table.Info.EntryCount)
+		assert.Equal(t, uint64(1), table.Info.TombstoneCount)
+		assert.Equal(t, uint64(24), table.Info.RawSize)
+		assert.Equal(t, table.Info.FilterOffset, table.Info.CompressedSize)
+
+		// The metadata is read back from the encoded SSTable
+		info, err := sstable.ReadInfo(sstable.NewBytesBlob(sstable.EncodeTable(table)))
+		require.NoError(t, err)
+		assert.Equal(t, table.Info, info)
+
+		handle := sstable.NewHandle(sstable.NewIDWal(1), info)
+		assert.True(t, handle.RangeCoversKey([]byte("key2")))
+		assert.True(t, handle.RangeCoversKey([]byte("key3")))
+		assert.False(t, handle.RangeCoversKey([]byte("key0")))
+		assert.False(t, handle.RangeCoversKey([]byte("key4")))
+	})
+
+	t.Run("Max Entries Per Block", func(t *testing.T) {
+		conf := sstable.DefaultConfig()
+		conf.BlockMaxEntries = 3
+		conf.IndexPartitionThreshold = 2
+		builder := sstable.NewBuilder(conf)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, builder.AddValue([]byte(fmt.Sprintf("key%d", i)), []byte("v")))
+		}
+
+		table, err := builder.Build()
+		require.NoError(t, err)
+		assert.Equal(t, 4, table.Blocks.Len())
+		require.True(t, table.Info.IndexPartitioned)
+
+		// The index records the entries of each block, and of each partition
+		encoded := sstable.EncodeTable(table)
+		index, err := sstable.ReadIndexRaw(table.Info, encoded)
+		require.NoError(t, err)
+		var partitionCounts, blockCounts []uint64
+		for i, p := range index.BlockMeta() {
+			partitionCounts = append(partitionCounts, p.EntryCount)
+			partition, err := sstable.ReadIndexPartition(table.Info, index, i, sstable.NewBytesBlob(encoded))
+			require.NoError(t, err)
+			for _, meta := range partition.BlockMeta() {
+				blockCounts = append(blockCounts, meta.EntryCount)
+			}
+		}
+		assert.Equal(t, []uint64{6, 4}, partitionCounts)
+		assert.Equal(t, []uint64{3, 3, 3, 1}, blockCounts)
+	})
 }
 
 func TestEncodeDecode(t *testing.T) {
@@ -103,7 +166,7 @@ func TestEncodeDecode(t *testing.T) {
 	}
 
 	builder := sstable.NewBuilder(sstable.Config{
-		BlockSize:        block.V0EstimateBlockSize(input[0]),
+		BlockSize:        block.EstimateBlockSize(input[0]),
 		MinFilterKeys:    0,
 		FilterBitsPerKey: 10,
 		Compression:      compress.CodecNone,
@@ -158,7 +221,217 @@ func TestEncodeDecode(t *testing.T) {
 	assert.True(t, filter.IsPresent())
 	f, ok := filter.Get()
 	assert.True(t, ok)
-	assert.True(t, f.HasKey([]byte("key1")))
-	assert.True(t, f.HasKey([]byte("key2")))
-	assert.True(t, f.HasKey([]byte("key3")))
+	assert.True(t, f.KeyMayMatch([]byte("key1")))
+	assert.True(t, f.KeyMayMatch([]byte("key2")))
+	assert.True(t, f.KeyMayMatch([]byte("key3")))
+}
+
+func TestBuilderReset(t *testing.T) {
+	conf := sstable.Config{
+		BlockSize:        128,
+		MinFilterKeys:    0,
+		FilterBitsPerKey: 10,
+		Compression:      compress.CodecNone,
+	}
+	build := func(builder *sstable.Builder, prefix string) []byte {
+		for i := 0; i < 50; i++ {
+			key := []byte(fmt.Sprintf("%s%04d", prefix, i))
+			require.NoError(t, builder.AddValue(key, []byte(fmt.Sprintf("value%04d", i))))
+		}
+		table, err := builder.Build()
+		require.NoError(t, err)
+		return sstable.EncodeTable(table)
+	}
+
+	builder := sstable.NewBuilder(conf)
+	first := build(builder, "a")
+	firstCopy := bytes.Clone(first)
+
+	builder.Reset()
+	second := build(builder, "b")
+
+	// The first table doesn't share buffers with the reused builder
+	assert.Equal(t, firstCopy, first)
+	// The reused builder builds the same table as a new builder
+	assert.Equal(t, build(sstable.NewBuilder(conf), "b"), second)
+
+	info, err := sstable.ReadInfo(sstable.NewBytesBlob(second))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b0000"), info.FirstKey)
+	assert.Equal(t, []byte("b0049"), info.LastKey)
+	assert.Equal(t, uint64(50), info.EntryCount)
+
+	// A builder reset with a new config builds the table with the new config
+	conf.BlockSize = 4096
+	builder.ResetWithConfig(conf)
+	assert.Equal(t, build(sstable.NewBuilder(conf), "c"), build(builder, "c"))
+}
+
+func TestFooter(t *testing.T) {
+	builder := sstable.NewBuilder(sstable.DefaultConfig())
+	require.NoError(t, builder.AddValue([]byte("key1"), []byte("value1")))
+	table, err := builder.Build()
+	require.NoError(t, err)
+
+	encoded := sstable.EncodeTable(table)
+	// The SSTable ends with the format version and magic number
+	assert.Equal(t, sstable.FormatVersion, binary.BigEndian.Uint16(encoded[len(encoded)-6:]))
+	assert.Equal(t, []byte("SLTB"), encoded[len(encoded)-4:])
+
+	t.Run("Bad Magic", func(t *testing.T) {
+		corrupt := bytes.Clone(encoded)
+		corrupt[len(corrupt)-1] = 'X'
+		_, err := sstable.ReadInfo(sstable.NewBytesBlob(corrupt))
+		assert.ErrorIs(t, err, common.ErrIncompleteSST)
+	})
+
+	t.Run("Unsupported Version", func(t *testing.T) {
+		corrupt := bytes.Clone(encoded)
+		binary.BigEndian.PutUint16(corrupt[len(corrupt)-6:], sstable.FormatVersion+1)
+		_, err := sstable.ReadInfo(sstable.NewBytesBlob(corrupt))
+		assert.ErrorIs(t, err, common.ErrUnsupportedSSTVersion)
+		assert.NotErrorIs(t, err, common.ErrIncompleteSST)
+		assert.ErrorContains(t, err, fmt.Sprintf("unsupported SSTable format version '%d'", sstable.FormatVersion+1))
+	})
+
+	// The offsets precede the checksum, format version and magic number
+	offsets := len(encoded) - 34
+
+	t.Run("Checksum Mismatch", func(t *testing.T) {
+		corrupt := bytes.Clone(encoded)
+		corrupt[offsets] ^= 0xff
+		_, err := sstable.ReadInfo(sstable.NewBytesBlob(corrupt))
+		assert.ErrorIs(t, err, common.ErrChecksumMismatch)
+
+		var cerr *common.CorruptionError
+		require.ErrorAs(t, err, &cerr)
+		assert.Equal(t, common.SectionFooter, cerr.Section)
+		assert.Equal(t, uint64(offsets), cerr.Offset)
+	})
+
+	t.Run("Offsets Differ From Info", func(t *testing.T) {
+		corrupt := bytes.Clone(encoded)
+		filterOffset := binary.BigEndian.Uint64(corrupt[offsets+8:])
+		binary.BigEndian.PutUint64(corrupt[offsets+8:], filterOffset+1)
+		binary.BigEndian.PutUint32(corrupt[offsets+24:], crc32.ChecksumIEEE(corrupt[offsets:offsets+24]))
+		_, err := sstable.ReadInfo(sstable.NewBytesBlob(corrupt))
+		assert.ErrorIs(t, err, common.ErrInvalidSSTFooter)
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		_, err := sstable.ReadInfo(sstable.NewBytesBlob(encoded[:len(encoded)-1]))
+		assert.ErrorIs(t, err, common.ErrIncompleteSST)
+	})
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	conf := sstable.DefaultConfig()
+	value := bytes.Repeat([]byte("v"), 100)
+	keys := make([][]byte, 10_000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key%08d", i))
+	}
+	build := func(b *testing.B, builder *sstable.Builder) {
+		for _, key := range keys {
+			if err := builder.AddValue(key, value); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if _, err := builder.Build(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("New", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			build(b, sstable.NewBuilder(conf))
+		}
+	})
+
+	b.Run("Reset", func(b *testing.B) {
+		builder := sstable.NewBuilder(conf)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			builder.Reset()
+			build(b, builder)
+		}
+	})
+}
+
+func TestBuilderRangeTombstones(t *testing.T) {
+	tombstones := types.RangeTombstones{
+		{Start: []byte("aaa"), End: []byte("ccc"), Seq: 3},
+		{Start: []byte("bbb"), End: []byte("zzz"), Seq: 7},
+	}
+	builder := sstable.NewBuilder(sstable.DefaultConfig())
+	for _, tombstone := range tombstones {
+		builder.AddRangeTombstone(tombstone)
+	}
+	require.NoError(t, builder.AddValue([]byte("key1"), []byte("value1")))
+	table, err := builder.Build()
+	require.NoError(t, err)
+	encoded := sstable.EncodeTable(table)
+
+	// The range tombstones don't extend the key range of the SSTable
+	info, err := sstable.ReadInfo(sstable.NewBytesBlob(encoded))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("key1"), info.FirstKey)
+	assert.NotZero(t, info.RangeTombstoneLen)
+
+	got, err := sstable.ReadRangeTombstones(info, sstable.NewBytesBlob(encoded))
+	require.NoError(t, err)
+	assert.Equal(t, tombstones, got)
+
+	corrupt := bytes.Clone(encoded)
+	corrupt[info.RangeTombstoneOffset] ^= 0xff
+	_, err = sstable.ReadRangeTombstones(info, sstable.NewBytesBlob(corrupt))
+	var cerr *common.CorruptionError
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, common.SectionRangeTombstones, cerr.Section)
+}
+
+func TestBuilderEncodeQueue(t *testing.T) {
+	build := func(conf sstable.Config, streamed bool) []byte {
+		builder := sstable.NewBuilder(conf)
+		var blocks []byte
+		for i := 0; i < 500; i++ {
+			key := []byte(fmt.Sprintf("key%04d", i))
+			require.NoError(t, builder.AddValue(key, []byte(fmt.Sprintf("value%04d", i%7))))
+			for streamed {
+				blk, ok := builder.NextBlock().Get()
+				if !ok {
+					break
+				}
+				blocks = append(blocks, blk...)
+			}
+		}
+		table, err := builder.Build()
+		require.NoError(t, err)
+		return append(blocks, sstable.EncodeTable(table)...)
+	}
+
+	for _, codec := range []compress.Codec{compress.CodecNone, compress.CodecSnappy, compress.CodecZstd} {
+		t.Run(codec.String(), func(t *testing.T) {
+			conf := sstable.Config{BlockSize: 128, FilterBitsPerKey: 10, Compression: codec}
+			expected := build(conf, false)
+
+			// Blocks encoded concurrently are laid out as if encoded sequentially
+			conf.EncodeQueue = 3
+			assert.Equal(t, expected, build(conf, false))
+			assert.Equal(t, expected, build(conf, true))
+		})
+	}
+
+	// A builder reset before the SSTable was built stops encoding its blocks
+	builder := sstable.NewBuilder(sstable.Config{BlockSize: 128, FilterBitsPerKey: 10, EncodeQueue: 2})
+	for i := 0; i < 100; i++ {
+		require.NoError(t, builder.AddValue([]byte(fmt.Sprintf("key%04d", i)), []byte("value")))
+	}
+	builder.Reset()
+	require.NoError(t, builder.AddValue([]byte("key"), []byte("value")))
+	table, err := builder.Build()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), table.Info.EntryCount)
+	assert.Greater(t, builder.EncodeTime(), time.Duration(0))
 }
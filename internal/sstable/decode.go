@@ -1,23 +1,26 @@
 package sstable
 
 import (
-	"encoding/binary"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/samber/mo"
 
 	"github.com/slatedb/slatedb-go/internal/compress"
 	"github.com/slatedb/slatedb-go/internal/sstable/block"
-	"github.com/slatedb/slatedb-go/internal/sstable/bloom"
 	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
 )
 
 func DefaultConfig() Config {
 	return Config{
-		BlockSize:        4096,
-		MinFilterKeys:    0,
-		FilterBitsPerKey: 10,
-		Compression:      compress.CodecNone,
+		BlockSize:               4096,
+		MinFilterKeys:           0,
+		FilterBitsPerKey:        10,
+		IndexPartitionThreshold: 8192,
+		BlockFetchParallelism:   4,
+		Compression:             compress.CodecNone,
 	}
 }
 
@@ -26,29 +29,84 @@ func ReadInfo(obj common.ReadOnlyBlob) (*Info, error) {
 	if err != nil {
 		return nil, err
 	}
-	if size <= 4 {
-		return nil, common.ErrEmptySSTable
+	if size <= footerSizeV1 {
+		return nil, fmt.Errorf("%w: %w", common.ErrIncompleteSST, common.ErrEmptySSTable)
 	}
 
-	// Get the metadata. Last 4 bytes are the metadata offset of SsTableInfo
-	offsetIndex := uint64(size - 4)
-	offsetBytes, err := obj.ReadRange(common.Range{Start: offsetIndex, End: uint64(size)})
+	// The fixed size footer holds the offset of SsTableInfo. Read enough bytes
+	// for the largest footer, as the size depends on the format version.
+	tailIndex := uint64(max(size-footerSize, 0))
+	tail, err := obj.ReadRange(common.Range{Start: tailIndex, End: uint64(size)})
 	if err != nil {
 		return nil, err
 	}
 
-	metadataOffset := binary.BigEndian.Uint32(offsetBytes)
-	metadataBytes, err := obj.ReadRange(common.Range{Start: uint64(metadataOffset), End: offsetIndex})
+	// Only an SSTable without the magic number is incomplete. The footer of an
+	// SSTable with the magic number was written in full, so any other failure
+	// to decode the SSTable is corruption.
+	f, err := decodeFooter(tail)
+	if errors.Is(err, common.ErrIncompleteSST) || errors.Is(err, common.ErrUnsupportedSSTVersion) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, corruptionAt(err, common.SectionFooter, uint64(size-f.size()), -1)
+	}
+	footerIndex := uint64(size - f.size())
+	metadataOffset := f.InfoOffset
+	if metadataOffset >= footerIndex {
+		return nil, corruptionAt(fmt.Errorf("%w: info offset '%d' is beyond footer", common.ErrInvalidSSTFooter, metadataOffset),
+			common.SectionFooter, footerIndex, -1)
+	}
+
+	metadataBytes, err := obj.ReadRange(common.Range{Start: metadataOffset, End: footerIndex})
 	if err != nil {
 		return nil, err
 	}
 
-	return DecodeInfo(metadataBytes)
+	info, err := DecodeInfo(metadataBytes)
+	if err != nil {
+		return nil, corruptionAt(err, common.SectionInfo, metadataOffset, -1)
+	}
+
+	if err := f.verifyInfo(info); err != nil {
+		return nil, corruptionAt(err, common.SectionFooter, footerIndex, -1)
+	}
+	if err := validateInfo(info, metadataOffset); err != nil {
+		return nil, corruptionAt(err, common.SectionInfo, metadataOffset, -1)
+	}
+	return info, nil
 }
 
-func ReadFilter(sstInfo *Info, obj common.ReadOnlyBlob) (mo.Option[bloom.Filter], error) {
+// validateInfo ensures the sections described by Info fit within the
+// data which precedes Info, such that readers never slice beyond the object.
+func validateInfo(info *Info, infoOffset uint64) error {
+	if info.IndexOffset+info.IndexLen > infoOffset {
+		return fmt.Errorf("index [%d:%d] overlaps info at '%d'",
+			info.IndexOffset, info.IndexOffset+info.IndexLen, infoOffset)
+	}
+	if info.CompressionDictLen > 0 && info.CompressionDictOffset+info.CompressionDictLen > info.IndexOffset {
+		return fmt.Errorf("compression dictionary [%d:%d] overlaps index at '%d'",
+			info.CompressionDictOffset, info.CompressionDictOffset+info.CompressionDictLen, info.IndexOffset)
+	}
+	if info.FilterOffset+info.FilterLen > info.IndexOffset {
+		return fmt.Errorf("filter [%d:%d] overlaps index at '%d'",
+			info.FilterOffset, info.FilterOffset+info.FilterLen, info.IndexOffset)
+	}
+	return nil
+}
+
+// ReadFilter reads the filter of the SSTable if present. The filter.Policy which
+// created the filter is chosen by name from the provided policies, or the bloom
+// policy. If none of the policies match, the filter is absent, as a filter which
+// cannot be read cannot exclude any keys.
+func ReadFilter(sstInfo *Info, obj common.ReadOnlyBlob, policies ...filter.Policy) (mo.Option[Filter], error) {
 	if sstInfo.FilterLen < 1 {
-		return mo.None[bloom.Filter](), nil
+		return mo.None[Filter](), nil
+	}
+
+	policy, ok := resolveFilterPolicy(sstInfo.FilterPolicy, policies)
+	if !ok {
+		return mo.None[Filter](), nil
 	}
 
 	filterOffsetRange := common.Range{
@@ -58,15 +116,15 @@ func ReadFilter(sstInfo *Info, obj common.ReadOnlyBlob) (mo.Option[bloom.Filter]
 
 	filterBytes, err := obj.ReadRange(filterOffsetRange)
 	if err != nil {
-		return mo.None[bloom.Filter](), fmt.Errorf("while reading filter offset: %w", err)
+		return mo.None[Filter](), fmt.Errorf("while reading filter offset: %w", err)
 	}
 
-	filterData, err := bloom.Decode(filterBytes, sstInfo.CompressionCodec)
+	data, err := decodeFilter(filterBytes, sstInfo.CompressionCodec)
 	if err != nil {
-		return mo.Option[bloom.Filter]{}, err
+		return mo.None[Filter](), corruptionAt(err, common.SectionFilter, sstInfo.FilterOffset, -1)
 	}
 
-	return mo.Some(filterData), nil
+	return mo.Some(Filter{Policy: policy, Data: data}), nil
 }
 
 func ReadIndex(info *Info, obj common.ReadOnlyBlob) (*Index, error) {
@@ -78,13 +136,48 @@ func ReadIndex(info *Info, obj common.ReadOnlyBlob) (*Index, error) {
 		return nil, err
 	}
 
-	return DecodeIndex(indexBytes, info.CompressionCodec)
+	index, err := DecodeIndex(indexBytes, info.CompressionCodec)
+	if err != nil {
+		return nil, corruptionAt(err, common.SectionIndex, info.IndexOffset, -1)
+	}
+	return index, nil
+}
+
+// ReadIndexPartition reads the partition of a partitioned index referenced by the
+// provided entry of the top level index.
+func ReadIndexPartition(info *Info, topLevel *Index, partition int, obj common.ReadOnlyBlob) (*Index, error) {
+	rng := getPartitionRange(topLevel, partition)
+	indexBytes, err := obj.ReadRange(rng)
+	if err != nil {
+		return nil, fmt.Errorf("while reading index partition '%d' [%d:%d]: %w", partition, rng.Start, rng.End, err)
+	}
+
+	index, err := DecodeIndex(indexBytes, info.CompressionCodec)
+	if err != nil {
+		return nil, corruptionAt(err, common.SectionIndex, rng.Start, -1)
+	}
+	return index, nil
+}
+
+// getPartitionRange returns the (startOffset, endOffset) of the index partition
+// referenced by the provided entry of the top level index.
+func getPartitionRange(topLevel *Index, partition int) common.Range {
+	partitions := topLevel.BlockMeta()
+	endOffset := topLevel.EndOffset()
+	if partition+1 < len(partitions) {
+		endOffset = partitions[partition+1].Offset
+	}
+	return common.Range{Start: partitions[partition].Offset, End: endOffset}
 }
 
 func ReadIndexRaw(info *Info, sstBytes []byte) (*Index, error) {
 	indexBytes := sstBytes[info.IndexOffset : info.IndexOffset+info.IndexLen]
 
-	return DecodeIndex(indexBytes, info.CompressionCodec)
+	index, err := DecodeIndex(indexBytes, info.CompressionCodec)
+	if err != nil {
+		return nil, corruptionAt(err, common.SectionIndex, info.IndexOffset, -1)
+	}
+	return index, nil
 }
 
 // getBlockRange returns the (startOffset, endOffset) of the data in ssTable that contains the
@@ -93,7 +186,11 @@ func getBlockRange(rng common.Range, sstInfo *Info, index *Index) common.Range {
 	blockMetaList := index.BlockMeta()
 	startOffset := blockMetaList[rng.Start].Offset
 
+	// The blocks of an index partition are not necessarily followed by the filter
 	endOffset := sstInfo.FilterOffset
+	if end := index.EndOffset(); end != 0 {
+		endOffset = end
+	}
 	if rng.End < uint64(len(blockMetaList)) {
 		endOffset = blockMetaList[rng.End].Offset
 	}
@@ -104,6 +201,35 @@ func getBlockRange(rng common.Range, sstInfo *Info, index *Index) common.Range {
 // ReadBlocks reads the complete data required into a byte slice (dataBytes)
 // and then breaks the data up into slice of Blocks (decodedBlocks) which is returned
 func ReadBlocks(info *Info, index *Index, r common.Range, obj common.ReadOnlyBlob) ([]block.Block, error) {
+	dict, err := ReadDict(info, obj)
+	if err != nil {
+		return nil, err
+	}
+	return ReadBlocksWithDict(info, index, r, obj, dict)
+}
+
+// ReadBlocksWithDict reads blocks like ReadBlocks, using the compression
+// dictionary of the SSTable previously read by ReadDict.
+func ReadBlocksWithDict(info *Info, index *Index, r common.Range, obj common.ReadOnlyBlob, dict []byte) ([]block.Block, error) {
+	encoded, err := ReadEncodedBlocks(info, index, r, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	decodedBlocks := make([]block.Block, 0, len(encoded))
+	for i, blockBytes := range encoded {
+		decodedBlock, err := DecodeBlock(info, index, r.Start+uint64(i), blockBytes, dict)
+		if err != nil {
+			return nil, err
+		}
+		decodedBlocks = append(decodedBlocks, decodedBlock)
+	}
+	return decodedBlocks, nil
+}
+
+// ReadEncodedBlocks reads the blocks in r with a single range read, and returns
+// the encoded bytes of each block without decoding or verifying them.
+func ReadEncodedBlocks(info *Info, index *Index, r common.Range, obj common.ReadOnlyBlob) ([][]byte, error) {
 	if r.Start >= r.End {
 		return nil, fmt.Errorf("block start '%d' range cannot be greater than end range '%d'", r.Start, r.End)
 	}
@@ -112,10 +238,6 @@ func ReadBlocks(info *Info, index *Index, r common.Range, obj common.ReadOnlyBlo
 			r.End, index.BlockMetaLength())
 	}
 
-	if r.Start == r.End {
-		return []block.Block{}, nil
-	}
-
 	rng := getBlockRange(r, info, index)
 	dataBytes, err := obj.ReadRange(rng)
 	if err != nil {
@@ -123,37 +245,106 @@ func ReadBlocks(info *Info, index *Index, r common.Range, obj common.ReadOnlyBlo
 	}
 
 	startOffset := rng.Start
-	var decodedBlocks []block.Block
+	encoded := make([][]byte, 0, r.End-r.Start)
 	blockMetaList := index.BlockMeta()
-	compressionCodec := info.CompressionCodec
-
 	for i := r.Start; i < r.End; i++ {
 		bytesStart := blockMetaList[i].Offset - startOffset
-		var blockBytes []byte
 		if i == uint64(index.BlockMetaLength())-1 {
-			blockBytes = dataBytes[bytesStart:]
+			encoded = append(encoded, dataBytes[bytesStart:])
 		} else {
 			bytesEnd := blockMetaList[i+1].Offset - startOffset
-			blockBytes = dataBytes[bytesStart:bytesEnd]
+			encoded = append(encoded, dataBytes[bytesStart:bytesEnd])
+		}
+	}
+	return encoded, nil
+}
+
+// DecodeBlock decodes the encoded bytes of the block at blockIndex read by
+// ReadEncodedBlocks, verifying the checksum of the block.
+func DecodeBlock(info *Info, index *Index, blockIndex uint64, encoded []byte, dict []byte) (block.Block, error) {
+	var decodedBlock block.Block
+	if err := block.DecodeVersion(&decodedBlock, encoded, info.CompressionCodec, dict, info.BlockFormatVersion); err != nil {
+		return block.Block{}, corruptionAt(err, common.SectionBlock, index.BlockMeta()[blockIndex].Offset, int(blockIndex))
+	}
+	return decodedBlock, nil
+}
+
+// ReadBlocksParallel reads blocks like ReadBlocksWithDict, splitting the range of
+// blocks into up to parallelism consecutive parts which are fetched concurrently
+// and returned in order. Concurrent range reads hide the latency of object stores
+// such as S3, where a single large range read is limited by the throughput of one
+// connection. A parallelism of one or less reads the range with a single request.
+func ReadBlocksParallel(
+	info *Info,
+	index *Index,
+	r common.Range,
+	obj common.ReadOnlyBlob,
+	dict []byte,
+	parallelism int,
+) ([]block.Block, error) {
+	if parallelism <= 1 || r.End <= r.Start+1 {
+		return ReadBlocksWithDict(info, index, r, obj, dict)
+	}
+
+	count := r.End - r.Start
+	parts := min(uint64(parallelism), count)
+	results := make([][]block.Block, parts)
+	errs := make([]error, parts)
+
+	var wg sync.WaitGroup
+	start := r.Start
+	for i := uint64(0); i < parts; i++ {
+		// Spread the remainder of the blocks across the first parts
+		size := count / parts
+		if i < count%parts {
+			size++
 		}
+		part := common.Range{Start: start, End: start + size}
+		start = part.End
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = ReadBlocksWithDict(info, index, part, obj, dict)
+		}()
+	}
+	wg.Wait()
 
-		var decodedBlock block.Block
-		if err := block.Decode(&decodedBlock, blockBytes, compressionCodec); err != nil {
-			return nil, fmt.Errorf("while decoding block '%d' data[%d:%d]: %w",
-				i, bytesStart, int(bytesStart)+len(blockBytes), err)
+	blocks := make([]block.Block, 0, count)
+	for i := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
 		}
-		decodedBlocks = append(decodedBlocks, decodedBlock)
+		blocks = append(blocks, results[i]...)
 	}
-	return decodedBlocks, nil
+	return blocks, nil
 }
 
 func ReadBlockRaw(info *Info, index *Index, blockIndex uint64, sstBytes []byte) (*block.Block, error) {
 	blockRange := getBlockRange(common.Range{Start: blockIndex, End: blockIndex + 1}, info, index)
 
+	dict, err := ReadDict(info, NewBytesBlob(sstBytes))
+	if err != nil {
+		return nil, err
+	}
+
 	var blk block.Block
-	if err := block.Decode(&blk, sstBytes[blockRange.Start:blockRange.End], info.CompressionCodec); err != nil {
-		return nil, fmt.Errorf("while decoding block '%d' data[%d:%d]: %w",
-			blockIndex, blockRange.Start, blockRange.End, err)
+	if err := block.DecodeVersion(&blk, sstBytes[blockRange.Start:blockRange.End], info.CompressionCodec, dict, info.BlockFormatVersion); err != nil {
+		return nil, corruptionAt(err, common.SectionBlock, blockRange.Start, int(blockIndex))
 	}
 	return &blk, nil
 }
+
+// corruptionAt records the location of a section of the SSTable which failed to
+// decode in the common.CorruptionError returned by the decoder, or wraps err in a
+// new common.CorruptionError if the decoder did not return one.
+func corruptionAt(err error, section string, offset uint64, blockIndex int) error {
+	var cerr *common.CorruptionError
+	if !errors.As(err, &cerr) {
+		cerr = common.NewCorruptionError(section, err)
+	}
+	cerr.Section = section
+	cerr.Offset = offset
+	cerr.Block = blockIndex
+	return cerr
+}
@@ -0,0 +1,53 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// encodeDict appends a checksum to the compression dictionary. The dictionary
+// is not compressed, as it is used to decompress the blocks.
+func encodeDict(dict []byte) []byte {
+	buf := make([]byte, 0, len(dict)+common.SizeOfUint32)
+	buf = append(buf, dict...)
+	return binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(dict))
+}
+
+// decodeDict verifies the checksum of the compression dictionary
+func decodeDict(buf []byte) ([]byte, error) {
+	if len(buf) <= common.SizeOfUint32 {
+		return nil, common.ErrChecksumMismatch
+	}
+
+	checksumIndex := len(buf) - common.SizeOfUint32
+	dict := buf[:checksumIndex]
+	expected, actual := binary.BigEndian.Uint32(buf[checksumIndex:]), crc32.ChecksumIEEE(dict)
+	if expected != actual {
+		return nil, common.NewChecksumError(common.SectionDict, expected, actual)
+	}
+	return dict, nil
+}
+
+// ReadDict reads the compression dictionary of the SSTable, or returns nil if
+// the blocks were compressed without a dictionary
+func ReadDict(info *Info, obj common.ReadOnlyBlob) ([]byte, error) {
+	if info.CompressionDictLen == 0 {
+		return nil, nil
+	}
+
+	buf, err := obj.ReadRange(common.Range{
+		Start: info.CompressionDictOffset,
+		End:   info.CompressionDictOffset + info.CompressionDictLen,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while reading compression dictionary: %w", err)
+	}
+	dict, err := decodeDict(buf)
+	if err != nil {
+		return nil, corruptionAt(err, common.SectionDict, info.CompressionDictOffset, -1)
+	}
+	return dict, nil
+}
@@ -0,0 +1,72 @@
+package sstable
+
+import (
+	"time"
+
+	"github.com/slatedb/slatedb-go/internal/compress"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+)
+
+// encodedBlock is a block encoded by a blockEncoder
+type encodedBlock struct {
+	blk     *block.Block
+	buf     []byte
+	elapsed time.Duration
+	err     error
+}
+
+// blockEncoder compresses and checksums the blocks of a Builder on a separate
+// goroutine, so the next blocks are built while the previous blocks are encoded.
+// Blocks are encoded one at a time, such that they are received in the order they
+// were sent, as the offset of every block depends on the size of the blocks before it.
+type blockEncoder struct {
+	in  chan *block.Block
+	out chan encodedBlock
+}
+
+// startBlockEncoder starts a blockEncoder which queues up to queue blocks, and
+// encodes them with the codec and dictionary
+func startBlockEncoder(queue int, codec compress.Codec, dict []byte) *blockEncoder {
+	e := &blockEncoder{
+		in:  make(chan *block.Block, queue),
+		out: make(chan encodedBlock, queue),
+	}
+	go func() {
+		defer close(e.out)
+		for blk := range e.in {
+			start := time.Now()
+			buf, err := block.EncodeWithDict(blk, codec, dict)
+			e.out <- encodedBlock{blk: blk, buf: buf, elapsed: time.Since(start), err: err}
+		}
+	}()
+	return e
+}
+
+// send queues the block for encoding. While the queue is full, the blocks already
+// encoded are passed to receive, and the first error returned by receive is returned.
+func (e *blockEncoder) send(blk *block.Block, receive func(encodedBlock) error) error {
+	for {
+		select {
+		case e.in <- blk:
+			return nil
+		case encoded := <-e.out:
+			if err := receive(encoded); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// finish waits for every queued block to be encoded and passes them to receive,
+// then stops the encoder. The first error returned by receive is returned, after
+// which the remaining blocks are discarded.
+func (e *blockEncoder) finish(receive func(encodedBlock) error) error {
+	close(e.in)
+	var err error
+	for encoded := range e.out {
+		if err == nil {
+			err = receive(encoded)
+		}
+	}
+	return err
+}
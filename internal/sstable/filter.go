@@ -0,0 +1,87 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/slatedb/slatedb-go/internal/compress"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
+)
+
+// Filter is the filter of an SSTable along with the filter.Policy which created it
+type Filter struct {
+	Policy filter.Policy
+	Data   []byte
+}
+
+// KeyMayMatch returns false if the key is definitely not in the SSTable
+func (f Filter) KeyMayMatch(key []byte) bool {
+	return f.Policy.KeyMayMatch(key, f.Data)
+}
+
+// PrefixMayMatch returns false if no key in the SSTable starts with the prefix
+func (f Filter) PrefixMayMatch(prefix []byte) bool {
+	return filter.PrefixMayMatch(f.Policy, prefix, f.Data)
+}
+
+// filterPolicy returns the policy used to create new filters, which is the bloom
+// filter unless Config.FilterPolicy is provided.
+func (c Config) filterPolicy() filter.Policy {
+	if c.FilterPolicy != nil {
+		return c.FilterPolicy
+	}
+	return filter.NewBloomPolicy(c.FilterBitsPerKey)
+}
+
+// resolveFilterPolicy returns the policy with the provided name, or false if none
+// of the policies match. The bloom policy is always available, as it is used by
+// SSTables which do not record a policy name.
+func resolveFilterPolicy(name string, policies []filter.Policy) (filter.Policy, bool) {
+	if name == "" {
+		name = filter.BloomPolicyName
+	}
+	for _, p := range policies {
+		if p != nil && p.Name() == name {
+			return p, true
+		}
+	}
+	if name == filter.BloomPolicyName {
+		return filter.NewBloomPolicy(0), true
+	}
+	return nil, false
+}
+
+// encodeFilter compresses the filter data and appends a checksum
+//
+// +-----------------------------------------------+
+// |  Filter Data (compressed)                     |
+// +-----------------------------------------------+
+// |  Checksum (4 bytes)                           |
+// +-----------------------------------------------+
+func encodeFilter(data []byte, codec compress.Codec) ([]byte, error) {
+	compressed, err := compress.Encode(data, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, len(compressed)+common.SizeOfUint32)
+	buf = append(buf, compressed...)
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(compressed))
+	return buf, nil
+}
+
+// decodeFilter verifies the checksum and decompresses the filter data
+func decodeFilter(buf []byte, codec compress.Codec) ([]byte, error) {
+	if len(buf) <= common.SizeOfUint32 {
+		return nil, common.ErrChecksumMismatch
+	}
+
+	checksumIndex := len(buf) - common.SizeOfUint32
+	compressed := buf[:checksumIndex]
+	expected, actual := binary.BigEndian.Uint32(buf[checksumIndex:]), crc32.ChecksumIEEE(compressed)
+	if expected != actual {
+		return nil, common.NewChecksumError(common.SectionFilter, expected, actual)
+	}
+	return compress.Decode(compressed, codec)
+}
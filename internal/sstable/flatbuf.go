@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"hash/crc32"
+	"sync"
 
 	flatbuffers "github.com/google/flatbuffers/go"
 
@@ -15,26 +16,37 @@ import (
 type Index struct {
 	Data []byte
 
-	blockMetaT   []*flatbuf.BlockMetaT
-	sstableIndex *flatbuf.SsTableIndex
+	// The index is decoded on first use. An Index is shared by concurrent
+	// readers, such as the range reads of ReadBlocksParallel.
+	rootOnce      sync.Once
+	sstableIndex  *flatbuf.SsTableIndex
+	blockMetaOnce sync.Once
+	blockMetaT    []*flatbuf.BlockMetaT
+}
+
+// root returns the SsTableIndex decoded from Data
+func (info *Index) root() *flatbuf.SsTableIndex {
+	info.rootOnce.Do(func() {
+		info.sstableIndex = flatbuf.GetRootAsSsTableIndex(info.Data, 0)
+	})
+	return info.sstableIndex
 }
 
 func (info *Index) BlockMeta() []*flatbuf.BlockMetaT {
-	// Return the cached result if available
-	if info.blockMetaT != nil {
-		return info.blockMetaT
-	}
-	info.sstableIndex = flatbuf.GetRootAsSsTableIndex(info.Data, 0)
-	info.blockMetaT = info.sstableIndex.UnPack().BlockMeta
+	info.blockMetaOnce.Do(func() {
+		info.blockMetaT = info.root().UnPack().BlockMeta
+	})
 	return info.blockMetaT
 }
 
 func (info *Index) BlockMetaLength() int {
-	if info.sstableIndex != nil {
-		return info.sstableIndex.BlockMetaLength()
-	}
-	info.sstableIndex = flatbuf.GetRootAsSsTableIndex(info.Data, 0)
-	return info.sstableIndex.BlockMetaLength()
+	return info.root().BlockMetaLength()
+}
+
+// EndOffset returns the offset of the end of the last entry in the index, or
+// zero if the last entry is a block which ends at the start of the bloom filter.
+func (info *Index) EndOffset() uint64 {
+	return info.root().EndOffset()
 }
 
 func (info *Index) Clone() *Index {
@@ -54,6 +66,22 @@ func SstInfoToFlatBuf(info *Info) *flatbuf.SsTableInfoT {
 		FilterOffset:      info.FilterOffset,
 		FilterLen:         info.FilterLen,
 		CompressionFormat: compress.CodecToFlatBuf(info.CompressionCodec),
+		IndexPartitioned:  info.IndexPartitioned,
+		LastKey:           bytes.Clone(info.LastKey),
+		EntryCount:        info.EntryCount,
+		TombstoneCount:    info.TombstoneCount,
+		RawSize:           info.RawSize,
+		CompressedSize:    info.CompressedSize,
+		FilterPolicy:      info.FilterPolicy,
+
+		CompressionDictOffset: info.CompressionDictOffset,
+		CompressionDictLen:    info.CompressionDictLen,
+		FilterBitsPerKey:      info.FilterBitsPerKey,
+		BlockSize:             info.BlockSize,
+		RangeTombstoneOffset:  info.RangeTombstoneOffset,
+		RangeTombstoneLen:     info.RangeTombstoneLen,
+		WriterEpoch:           info.WriterEpoch,
+		BlockFormatVersion:    info.BlockFormatVersion,
 	}
 }
 
@@ -63,6 +91,8 @@ func EncodeInfo(info *Info) []byte {
 	// Encode the Info struct as flatbuf.SsTableInfoT
 	builder := flatbuffers.NewBuilder(0)
 	firstKey := builder.CreateByteVector(info.FirstKey)
+	lastKey := builder.CreateByteVector(info.LastKey)
+	filterPolicy := builder.CreateString(info.FilterPolicy)
 
 	flatbuf.SsTableInfoStart(builder)
 	flatbuf.SsTableInfoAddFirstKey(builder, firstKey)
@@ -71,6 +101,21 @@ func EncodeInfo(info *Info) []byte {
 	flatbuf.SsTableInfoAddFilterOffset(builder, info.FilterOffset)
 	flatbuf.SsTableInfoAddFilterLen(builder, info.FilterLen)
 	flatbuf.SsTableInfoAddCompressionFormat(builder, flatbuf.CompressionCodec(info.CompressionCodec))
+	flatbuf.SsTableInfoAddIndexPartitioned(builder, info.IndexPartitioned)
+	flatbuf.SsTableInfoAddLastKey(builder, lastKey)
+	flatbuf.SsTableInfoAddEntryCount(builder, info.EntryCount)
+	flatbuf.SsTableInfoAddTombstoneCount(builder, info.TombstoneCount)
+	flatbuf.SsTableInfoAddRawSize(builder, info.RawSize)
+	flatbuf.SsTableInfoAddCompressedSize(builder, info.CompressedSize)
+	flatbuf.SsTableInfoAddFilterPolicy(builder, filterPolicy)
+	flatbuf.SsTableInfoAddCompressionDictOffset(builder, info.CompressionDictOffset)
+	flatbuf.SsTableInfoAddCompressionDictLen(builder, info.CompressionDictLen)
+	flatbuf.SsTableInfoAddFilterBitsPerKey(builder, info.FilterBitsPerKey)
+	flatbuf.SsTableInfoAddBlockSize(builder, info.BlockSize)
+	flatbuf.SsTableInfoAddRangeTombstoneOffset(builder, info.RangeTombstoneOffset)
+	flatbuf.SsTableInfoAddRangeTombstoneLen(builder, info.RangeTombstoneLen)
+	flatbuf.SsTableInfoAddWriterEpoch(builder, info.WriterEpoch)
+	flatbuf.SsTableInfoAddBlockFormatVersion(builder, info.BlockFormatVersion)
 	infoOffset := flatbuf.SsTableInfoEnd(builder)
 
 	builder.Finish(infoOffset)
@@ -87,8 +132,9 @@ func DecodeIndex(buf []byte, codec compress.Codec) (*Index, error) {
 
 	checksumIndex := len(buf) - common.SizeOfUint32
 	compressed := buf[:checksumIndex]
-	if binary.BigEndian.Uint32(buf[checksumIndex:]) != crc32.ChecksumIEEE(compressed) {
-		return nil, common.ErrChecksumMismatch
+	expected, actual := binary.BigEndian.Uint32(buf[checksumIndex:]), crc32.ChecksumIEEE(compressed)
+	if expected != actual {
+		return nil, common.NewChecksumError(common.SectionIndex, expected, actual)
 	}
 
 	buf, err := compress.Decode(compressed, codec)
@@ -106,9 +152,9 @@ func DecodeInfo(b []byte) (*Info, error) {
 
 	// last 4 bytes hold the checksum
 	checksumIndex := len(b) - common.SizeOfUint32
-	checksum := binary.BigEndian.Uint32(b[checksumIndex:])
-	if checksum != crc32.ChecksumIEEE(b[:checksumIndex]) {
-		return nil, common.ErrChecksumMismatch
+	expected, actual := binary.BigEndian.Uint32(b[checksumIndex:]), crc32.ChecksumIEEE(b[:checksumIndex])
+	if expected != actual {
+		return nil, common.NewChecksumError(common.SectionInfo, expected, actual)
 	}
 
 	fbInfo := flatbuf.GetRootAsSsTableInfo(b, 0)
@@ -119,6 +165,22 @@ func DecodeInfo(b []byte) (*Info, error) {
 		FilterOffset:     fbInfo.FilterOffset(),
 		FilterLen:        fbInfo.FilterLen(),
 		CompressionCodec: compress.Codec(fbInfo.CompressionFormat()),
+		IndexPartitioned: fbInfo.IndexPartitioned(),
+		LastKey:          bytes.Clone(fbInfo.LastKeyBytes()),
+		EntryCount:       fbInfo.EntryCount(),
+		TombstoneCount:   fbInfo.TombstoneCount(),
+		RawSize:          fbInfo.RawSize(),
+		CompressedSize:   fbInfo.CompressedSize(),
+		FilterPolicy:     string(fbInfo.FilterPolicy()),
+
+		CompressionDictOffset: fbInfo.CompressionDictOffset(),
+		CompressionDictLen:    fbInfo.CompressionDictLen(),
+		FilterBitsPerKey:      fbInfo.FilterBitsPerKey(),
+		BlockSize:             fbInfo.BlockSize(),
+		RangeTombstoneOffset:  fbInfo.RangeTombstoneOffset(),
+		RangeTombstoneLen:     fbInfo.RangeTombstoneLen(),
+		WriterEpoch:           fbInfo.WriterEpoch(),
+		BlockFormatVersion:    fbInfo.BlockFormatVersion(),
 	}
 	return info, nil
 }
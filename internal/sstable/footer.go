@@ -0,0 +1,142 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+const (
+	// FormatVersion is the version of the SSTable layout written by Builder.
+	// Version 3 SSTables have the layout of version 2 SSTables, and their blocks
+	// hold rows whose keys are internal keys, see Info.BlockFormatVersion. The
+	// version was raised so that previous releases, which can't decode those
+	// rows, refuse to read the SSTable rather than returning corrupt entries.
+	FormatVersion uint16 = 3
+
+	// formatVersionV1 footers record only the offset of the SsTableInfoT and
+	// have no checksum. They are still read, but no longer written.
+	formatVersionV1 uint16 = 1
+
+	// footerMagic identifies the end of a complete SSTable ("SLTB")
+	footerMagic uint32 = 0x534c5442
+
+	// footerTrailerSize is the size of the Version (2 bytes) + Magic (4 bytes)
+	// which end the footer of every format version
+	footerTrailerSize = common.SizeOfUint16 + common.SizeOfUint32
+
+	// footerSizeV1 is the size of a version 1 footer
+	// Offset of SsTableInfoT (4 bytes) + Version (2 bytes) + Magic (4 bytes)
+	footerSizeV1 = common.SizeOfUint32 + footerTrailerSize
+
+	// footerSize is the size of the footer written by Builder
+	footerSize = 3*common.SizeOfUint64 + common.SizeOfUint32 + footerTrailerSize
+)
+
+// footer is the fixed size footer at the end of every SSTable
+//
+// +-----------------------------------------------+
+// |  Index Offset (8 bytes)                       |
+// +-----------------------------------------------+
+// |  Filter Offset (8 bytes)                      |
+// +-----------------------------------------------+
+// |  SsTableInfoT Offset (8 bytes)                |
+// +-----------------------------------------------+
+// |  Checksum of the offsets (4 bytes)            |
+// +-----------------------------------------------+
+// |  Format Version (2 bytes)                     |
+// +-----------------------------------------------+
+// |  Magic Number (4 bytes)                       |
+// +-----------------------------------------------+
+//
+// The offsets of the index and filter duplicate those in SsTableInfoT, which
+// allows a reader to verify the SsTableInfoT it located using the footer.
+//
+// This footer, its magic number and its format versions are specific to this
+// implementation and differ from the footer written by the upstream Rust
+// implementation, so SSTables written by either can't be read by the other.
+type footer struct {
+	Version      uint16
+	IndexOffset  uint64
+	FilterOffset uint64
+	InfoOffset   uint64
+}
+
+// size returns the number of bytes occupied by the footer
+func (f footer) size() int {
+	if f.Version == formatVersionV1 {
+		return footerSizeV1
+	}
+	return footerSize
+}
+
+// verifyInfo ensures the offsets recorded in the footer match the Info it references
+func (f footer) verifyInfo(info *Info) error {
+	if f.Version == formatVersionV1 {
+		return nil
+	}
+	if f.IndexOffset != info.IndexOffset || f.FilterOffset != info.FilterOffset {
+		return fmt.Errorf("%w: footer offsets index '%d' filter '%d' do not match info offsets index '%d' filter '%d'",
+			common.ErrInvalidSSTFooter, f.IndexOffset, f.FilterOffset, info.IndexOffset, info.FilterOffset)
+	}
+	return nil
+}
+
+// appendFooter appends the fixed size footer which records where the index,
+// filter and SsTableInfoT begin along with the format version and magic number.
+func appendFooter(buf []byte, f footer) []byte {
+	start := len(buf)
+	buf = binary.BigEndian.AppendUint64(buf, f.IndexOffset)
+	buf = binary.BigEndian.AppendUint64(buf, f.FilterOffset)
+	buf = binary.BigEndian.AppendUint64(buf, f.InfoOffset)
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf[start:]))
+	buf = binary.BigEndian.AppendUint16(buf, FormatVersion)
+	return binary.BigEndian.AppendUint32(buf, footerMagic)
+}
+
+// decodeFooter validates the magic number, version and checksum of the footer
+// which ends the provided bytes of the SSTable. The provided bytes may include
+// data which precedes the footer. Returns common.ErrIncompleteSST if the magic
+// number is missing, and common.ErrUnsupportedSSTVersion if the SSTable is
+// complete but written in a format version this release does not read.
+func decodeFooter(buf []byte) (footer, error) {
+	if len(buf) < footerTrailerSize {
+		return footer{}, fmt.Errorf("%w: expected at least %d bytes got %d",
+			common.ErrIncompleteSST, footerTrailerSize, len(buf))
+	}
+
+	// A missing magic number means the object does not end where the
+	// writer intended, most likely because the upload did not complete.
+	magic := binary.BigEndian.Uint32(buf[len(buf)-common.SizeOfUint32:])
+	if magic != footerMagic {
+		return footer{}, fmt.Errorf("%w: bad magic number '%#x'", common.ErrIncompleteSST, magic)
+	}
+
+	f := footer{Version: binary.BigEndian.Uint16(buf[len(buf)-footerTrailerSize:])}
+	if f.Version < formatVersionV1 || f.Version > FormatVersion {
+		return footer{}, fmt.Errorf("%w '%d'; versions '%d' through '%d' are supported",
+			common.ErrUnsupportedSSTVersion, f.Version, formatVersionV1, FormatVersion)
+	}
+	if len(buf) < f.size() {
+		return footer{}, fmt.Errorf("%w: expected %d bytes for a version '%d' footer got %d",
+			common.ErrInvalidSSTFooter, f.size(), f.Version, len(buf))
+	}
+	buf = buf[len(buf)-f.size():]
+
+	if f.Version == formatVersionV1 {
+		f.InfoOffset = uint64(binary.BigEndian.Uint32(buf))
+		return f, nil
+	}
+
+	offsetsLen := 3 * common.SizeOfUint64
+	expected := binary.BigEndian.Uint32(buf[offsetsLen:])
+	if actual := crc32.ChecksumIEEE(buf[:offsetsLen]); actual != expected {
+		return footer{}, common.NewChecksumError(common.SectionFooter, expected, actual)
+	}
+	f.IndexOffset = binary.BigEndian.Uint64(buf)
+	f.FilterOffset = binary.BigEndian.Uint64(buf[common.SizeOfUint64:])
+	f.InfoOffset = binary.BigEndian.Uint64(buf[2*common.SizeOfUint64:])
+	return f, nil
+}
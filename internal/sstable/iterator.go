@@ -11,8 +11,26 @@ import (
 )
 
 type TableStore interface {
-	ReadIndex(*Handle) (*Index, error)
-	ReadBlocksUsingIndex(*Handle, common.Range, *Index) ([]block.Block, error)
+	ReadIndex(context.Context, *Handle) (*Index, error)
+	ReadIndexPartition(context.Context, *Handle, *Index, int) (*Index, error)
+	ReadBlocksUsingIndex(context.Context, *Handle, common.Range, *Index) ([]block.Block, error)
+}
+
+// IteratorOptions configures how an Iterator reads the SSTable
+type IteratorOptions struct {
+	// UpperBound is the exclusive upper bound of the keys returned by the
+	// Iterator. A nil UpperBound iterates to the end of the SSTable.
+	UpperBound []byte
+
+	// PrefetchBlocks is the number of blocks fetched asynchronously ahead of the
+	// block being consumed, so sequential scans overlap fetching blocks from
+	// object storage with decoding them. Zero disables prefetching.
+	PrefetchBlocks int
+
+	// Readahead bounds the number of blocks fetched ahead, which adapts to how the
+	// Iterator is consumed instead of fetching a fixed PrefetchBlocks blocks ahead.
+	// Ignored if Readahead.MaxBlocks is zero.
+	Readahead Readahead
 }
 
 // Iterator iterates through KeyValue pairs present in the SSTable.
@@ -22,38 +40,148 @@ type Iterator struct {
 	store     TableStore
 	handle    *Handle
 	index     *Index
+	opts      IteratorOptions
 	fromKey   []byte
 	nextBlock uint64
+
+	// partitions is the top level index of a partitioned index, in which case
+	// index holds the partition currently being iterated.
+	partitions *Index
+	partition  int
+
+	// prefetched holds the in flight fetches of the blocks from nextBlock, the
+	// first of which may also hold blocks which precede nextBlock
+	prefetched []*blockFetch
+
+	// readahead adapts the number of blocks prefetched, nil if
+	// IteratorOptions.Readahead is disabled
+	readahead *readahead
+
+	// exhausted is true once the UpperBound has been reached
+	exhausted bool
+
+	// err is the error which ended the iteration before the last block
+	err error
+}
+
+// blockFetch is a range of consecutive blocks being fetched in the background.
+// The blocks and error are only safe to read once done has been closed.
+type blockFetch struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	first  uint64
+	count  uint64
+	blks   []block.Block
+	err    error
+}
+
+// end returns the block which follows the blocks of the fetch
+func (f *blockFetch) end() uint64 {
+	return f.first + f.count
 }
 
-func NewIterator(handle *Handle, store TableStore) (*Iterator, error) {
-	index, err := store.ReadIndex(handle)
+// NewIterator returns an Iterator which starts at the first key of the SSTable.
+// The index of the SSTable is read with ctx, and the blocks are read with the
+// context passed to Iterator.NextEntry.
+func NewIterator(ctx context.Context, handle *Handle, store TableStore) (*Iterator, error) {
+	return newIterator(ctx, handle, store, IteratorOptions{}, nil)
+}
+
+func NewIteratorAtKey(ctx context.Context, handle *Handle, key []byte, store TableStore) (*Iterator, error) {
+	return newIterator(ctx, handle, store, IteratorOptions{}, key)
+}
+
+// NewIteratorWithOptions returns an Iterator which starts at the first key of the
+// SSTable and reads the SSTable according to the provided IteratorOptions.
+// Use Iterator.Seek to start at a different key.
+func NewIteratorWithOptions(ctx context.Context, handle *Handle, store TableStore, opts IteratorOptions) (*Iterator, error) {
+	return newIterator(ctx, handle, store, opts, nil)
+}
+
+func newIterator(ctx context.Context, handle *Handle, store TableStore, opts IteratorOptions, fromKey []byte) (*Iterator, error) {
+	index, err := store.ReadIndex(ctx, handle)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Iterator{
+	iter := &Iterator{
 		handle:    handle,
 		store:     store,
 		index:     index,
+		opts:      opts,
 		nextBlock: 0,
-	}, nil
+	}
+	if opts.Readahead.MaxBlocks > 0 {
+		iter.readahead = newReadahead(opts.Readahead)
+	}
+	if handle.Info.IndexPartitioned {
+		// The first partition is loaded by the first read, so a Seek
+		// only loads the partition which may contain the key
+		iter.partitions = index
+		iter.partition = -1
+	}
+
+	if fromKey != nil {
+		if err := iter.Seek(ctx, fromKey); err != nil {
+			return nil, err
+		}
+	}
+	return iter, nil
 }
 
-func NewIteratorAtKey(handle *Handle, key []byte, store TableStore) (*Iterator, error) {
-	index, err := store.ReadIndex(handle)
+// Seek positions the Iterator at the first key which is greater than or
+// equal to the provided key. Only the index partition which may contain the
+// key is loaded when the index is partitioned.
+func (iter *Iterator) Seek(ctx context.Context, key []byte) error {
+	iter.blockIter = nil
+	iter.exhausted = false
+	iter.err = nil
+	iter.fromKey = bytes.Clone(key)
+
+	samePartition := true
+	if iter.partitions != nil {
+		partition := int(iter.firstBlockIncludingOrAfterKey(iter.partitions, key))
+		if partition != iter.partition {
+			samePartition = false
+			if err := iter.loadPartition(ctx, partition); err != nil {
+				return err
+			}
+		}
+	}
+	target := iter.firstBlockIncludingOrAfterKey(iter.index, key)
+	if iter.readahead != nil {
+		iter.readahead.seeked(int64(target)-iter.readahead.last, samePartition)
+	}
+
+	// The blocks already fetched from the target onwards are kept, so a Seek
+	// which skips fewer blocks than were prefetched reuses the fetches
+	if !samePartition || target+1 < iter.nextBlock {
+		iter.cancelPrefetched()
+	}
+	for len(iter.prefetched) > 0 && iter.prefetched[0].end() <= target {
+		iter.prefetched[0].cancel()
+		iter.prefetched = iter.prefetched[1:]
+	}
+	iter.nextBlock = target
+	return nil
+}
+
+// loadPartition reads the requested partition of the top level index and
+// positions the iterator at the first block of the partition
+func (iter *Iterator) loadPartition(ctx context.Context, partition int) error {
+	index, err := iter.store.ReadIndexPartition(ctx, iter.handle, iter.partitions, partition)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("while reading index partition '%d': %w", partition, err)
 	}
 
-	iter := &Iterator{
-		fromKey: bytes.Clone(key),
-		handle:  handle,
-		store:   store,
-		index:   index,
+	iter.index = index
+	iter.partition = partition
+	iter.nextBlock = 0
+	iter.cancelPrefetched()
+	if iter.readahead != nil {
+		iter.readahead.last = -1
 	}
-	iter.nextBlock = iter.firstBlockIncludingOrAfterKey(index, key)
-	return iter, nil
+	return nil
 }
 
 func (iter *Iterator) Next(ctx context.Context) (types.KeyValue, bool) {
@@ -76,13 +204,18 @@ func (iter *Iterator) Next(ctx context.Context) (types.KeyValue, bool) {
 
 func (iter *Iterator) NextEntry(ctx context.Context) (types.RowEntry, bool) {
 	for {
+		if iter.exhausted {
+			return types.RowEntry{}, false
+		}
+
 		if iter.blockIter == nil {
-			it, err := iter.nextBlockIter()
+			it, err := iter.nextBlockIter(ctx)
 			if err != nil {
 				// TODO(thrawn01): This could be a transient error, or a corruption error
 				//  we need to handle each differently.
 				iter.warn.Add("while fetching blocks for SST '%s': %s",
 					iter.handle.Id.String(), err.Error())
+				iter.err = err
 				return types.RowEntry{}, false
 			}
 			if it == nil { // No more blocks
@@ -102,37 +235,188 @@ func (iter *Iterator) NextEntry(ctx context.Context) (types.RowEntry, bool) {
 			continue
 		}
 
+		if iter.beyondUpperBound(kv.Key) {
+			iter.exhausted = true
+			iter.blockIter = nil
+			iter.cancelPrefetched()
+			return types.RowEntry{}, false
+		}
 		return kv, true
 	}
 }
 
 // nextBlockIter fetches the next block and returns an iterator for that block
-func (iter *Iterator) nextBlockIter() (*block.Iterator, error) {
+func (iter *Iterator) nextBlockIter(ctx context.Context) (*block.Iterator, error) {
+	if iter.partitions != nil && iter.partition < 0 {
+		if err := iter.loadPartition(ctx, 0); err != nil {
+			return nil, err
+		}
+	}
+
 	if iter.nextBlock >= uint64(iter.index.BlockMetaLength()) {
-		return nil, nil // No more blocks to read
+		if iter.partitions == nil || iter.partition+1 >= iter.partitions.BlockMetaLength() {
+			return nil, nil // No more blocks to read
+		}
+		// Continue with the first block of the next index partition
+		if err := iter.loadPartition(ctx, iter.partition+1); err != nil {
+			return nil, err
+		}
 	}
 
-	// Fetch the next block
-	rng := common.Range{Start: iter.nextBlock, End: iter.nextBlock + 1}
-	blocks, err := iter.store.ReadBlocksUsingIndex(iter.handle, rng, iter.index)
-	if err != nil {
-		return nil, fmt.Errorf("while reading block range [%d:%d]: %w", rng.Start, rng.End, err)
+	// A block which starts at or beyond the UpperBound holds no keys we can return
+	if iter.beyondUpperBound(iter.index.BlockMeta()[iter.nextBlock].FirstKey) {
+		return nil, nil
 	}
-	if len(blocks) == 0 {
-		return nil, fmt.Errorf("block read range [%d:%d] returned zero blocks", rng.Start, rng.End)
+
+	var blk *block.Block
+	var err error
+	if iter.opts.PrefetchBlocks > 0 || iter.readahead != nil {
+		blk, err = iter.prefetchBlock(ctx)
+	} else {
+		blk, err = iter.readBlock(ctx, iter.index, iter.nextBlock)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// Increment the iter.nextBlock
 	iter.nextBlock++
 
-	// If iter.fromKey is present use NewIteratorAtKey() to find the key in the block
+	// If iter.fromKey is present use NewIteratorAtKey() to find the key in the block,
+	// every key in the blocks which follow is greater than iter.fromKey.
 	if iter.fromKey != nil {
+		fromKey := iter.fromKey
+		iter.fromKey = nil
 		// Will return an iterator nearest to where the key should be if it doesn't exist.
-		return block.NewIteratorAtKey(&blocks[0], iter.fromKey)
+		return block.NewIteratorAtKey(blk, fromKey)
 	}
 
 	// Iterate through all the blocks
-	return block.NewIterator(&blocks[0]), nil
+	return block.NewIterator(blk), nil
+}
+
+// prefetchBlock returns the block at iter.nextBlock and starts fetching the
+// blocks which follow it, up to IteratorOptions.PrefetchBlocks blocks or the
+// distance chosen by the readahead. Prefetching does not extend past the current
+// index partition or the UpperBound. The blocks are fetched with ctx, so a fetch
+// started by one call fails if ctx is done before a later call consumes it.
+func (iter *Iterator) prefetchBlock(ctx context.Context) (*block.Block, error) {
+	distance, request := uint64(iter.opts.PrefetchBlocks), uint64(1)
+	if iter.readahead != nil {
+		distance, request = uint64(iter.readahead.distance), uint64(iter.readahead.requestBlocks())
+	}
+
+	if len(iter.prefetched) == 0 || iter.prefetched[0].first > iter.nextBlock {
+		// The block is not being fetched, such as after a Seek to the block
+		// consumed last, so it is fetched ahead of the blocks in flight
+		fetch := iter.fetchBlocks(ctx, iter.index, iter.nextBlock, 1)
+		iter.prefetched = append([]*blockFetch{fetch}, iter.prefetched...)
+	}
+
+	// Blocks are fetched request blocks at a time, so a request which doesn't
+	// fit the window yet waits for the blocks before it to be consumed
+	next := iter.prefetched[len(iter.prefetched)-1].end()
+	end := uint64(iter.index.BlockMetaLength())
+	for next < end {
+		count := min(request, end-next)
+		if next+count > iter.nextBlock+distance+1 {
+			break
+		}
+		for i := uint64(0); i < count; i++ {
+			if iter.beyondUpperBound(iter.index.BlockMeta()[next+i].FirstKey) {
+				count = i
+				break
+			}
+		}
+		count = iter.limitRequestBytes(next, count)
+		if count == 0 {
+			break
+		}
+		iter.prefetched = append(iter.prefetched, iter.fetchBlocks(ctx, iter.index, next, count))
+		next += count
+	}
+
+	fetch := iter.prefetched[0]
+	waited := false
+	select {
+	case <-fetch.done:
+	default:
+		waited = true
+		select {
+		case <-fetch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if fetch.err != nil {
+		iter.cancelPrefetched()
+		return nil, fetch.err
+	}
+	if iter.readahead != nil {
+		iter.readahead.consumed(iter.nextBlock, waited)
+	}
+
+	blk := &fetch.blks[iter.nextBlock-fetch.first]
+	if iter.nextBlock+1 == fetch.end() {
+		fetch.cancel()
+		iter.prefetched = iter.prefetched[1:]
+	}
+	return blk, nil
+}
+
+// limitRequestBytes returns the number of the count blocks from first which fit
+// in Readahead.MaxRequestBytes, which is at least one
+func (iter *Iterator) limitRequestBytes(first uint64, count uint64) uint64 {
+	if iter.readahead == nil || iter.readahead.bounds.MaxRequestBytes == 0 || count <= 1 {
+		return count
+	}
+	for n := count; n > 1; n-- {
+		rng := getBlockRange(common.Range{Start: first, End: first + n}, iter.handle.Info, iter.index)
+		if rng.End-rng.Start <= iter.readahead.bounds.MaxRequestBytes {
+			return n
+		}
+	}
+	return 1
+}
+
+// fetchBlocks reads count blocks from the requested block in the background. The
+// fetch is canceled once its blocks are consumed or no longer needed.
+func (iter *Iterator) fetchBlocks(ctx context.Context, index *Index, first uint64, count uint64) *blockFetch {
+	ctx, cancel := context.WithCancel(ctx)
+	fetch := &blockFetch{done: make(chan struct{}), cancel: cancel, first: first, count: count}
+	go func() {
+		defer close(fetch.done)
+		fetch.blks, fetch.err = iter.readBlocks(ctx, index, first, count)
+	}()
+	return fetch
+}
+
+// readBlock reads a single block referenced by the provided index
+func (iter *Iterator) readBlock(ctx context.Context, index *Index, blockNum uint64) (*block.Block, error) {
+	blocks, err := iter.readBlocks(ctx, index, blockNum, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &blocks[0], nil
+}
+
+// readBlocks reads count consecutive blocks referenced by the provided index
+// with a single range read
+func (iter *Iterator) readBlocks(ctx context.Context, index *Index, first uint64, count uint64) ([]block.Block, error) {
+	rng := common.Range{Start: first, End: first + count}
+	blocks, err := iter.store.ReadBlocksUsingIndex(ctx, iter.handle, rng, index)
+	if err != nil {
+		return nil, fmt.Errorf("while reading block range [%d:%d]: %w", rng.Start, rng.End, err)
+	}
+	if uint64(len(blocks)) != count {
+		return nil, fmt.Errorf("block read range [%d:%d] returned %d blocks", rng.Start, rng.End, len(blocks))
+	}
+	return blocks, nil
+}
+
+// beyondUpperBound returns true if the key is greater than or equal to IteratorOptions.UpperBound
+func (iter *Iterator) beyondUpperBound(key []byte) bool {
+	return iter.opts.UpperBound != nil && bytes.Compare(key, iter.opts.UpperBound) >= 0
 }
 
 // firstBlockIncludingOrAfterKey performs a binary search on the SSTable index to find the first block
@@ -170,6 +454,29 @@ loop:
 	return uint64(foundBlockID)
 }
 
+// cancelPrefetched cancels the fetches in flight, whose blocks are no longer needed
+func (iter *Iterator) cancelPrefetched() {
+	for _, fetch := range iter.prefetched {
+		fetch.cancel()
+	}
+	iter.prefetched = nil
+}
+
+// Close cancels the fetches of the blocks prefetched but not yet consumed. The
+// Iterator returns no more entries once closed.
+func (iter *Iterator) Close() {
+	iter.cancelPrefetched()
+	iter.blockIter = nil
+	iter.exhausted = true
+}
+
+// Err returns the error which ended the iteration before the last block, such as
+// a common.CorruptionError for a corrupt block. Unlike Warnings, the error can be
+// matched with errors.Is and errors.As.
+func (iter *Iterator) Err() error {
+	return iter.err
+}
+
 // Warnings returns types.ErrWarn if there was a warning during iteration.
 func (iter *Iterator) Warnings() *types.ErrWarn {
 	return &iter.warn
@@ -13,14 +13,20 @@ import (
 // SSTable Info:
 //
 //	  First Key: key1
+//	  Last Key: key2
+//	  Entries: 2
+//	  Tombstones: 0
+//	  Raw Size: 18
+//	  Compressed Size: 140
 //	  Index Offset: 147
 //	  Index Length: 168
 //	  Filter Offset: 140
 //	  Filter Length: 7
 //	  Compression Codec: None
-//	Bloom Filter:
-//	  Number of Probes: 6
-//	  Data Length: 5
+//	  Compression Dictionary Length: 0
+//	Filter:
+//	  Policy: slatedb.BloomFilter
+//	  Data Length: 7
 //	Blocks:
 //	  First Block Offset: 0
 //	  End Offset: 140
@@ -58,20 +64,28 @@ func PrettyPrint(table *Table) string {
 	// Print SSTable Info
 	_, _ = fmt.Fprintf(&buf, "SSTable Info:\n")
 	_, _ = fmt.Fprintf(&buf, "  First Key: %s\n", string(table.Info.FirstKey))
+	_, _ = fmt.Fprintf(&buf, "  Last Key: %s\n", string(table.Info.LastKey))
+	_, _ = fmt.Fprintf(&buf, "  Entries: %d\n", table.Info.EntryCount)
+	_, _ = fmt.Fprintf(&buf, "  Tombstones: %d\n", table.Info.TombstoneCount)
+	_, _ = fmt.Fprintf(&buf, "  Raw Size: %d\n", table.Info.RawSize)
+	_, _ = fmt.Fprintf(&buf, "  Compressed Size: %d\n", table.Info.CompressedSize)
+	_, _ = fmt.Fprintf(&buf, "  Block Size: %d\n", table.Info.BlockSize)
 	_, _ = fmt.Fprintf(&buf, "  Index Offset: %d\n", table.Info.IndexOffset)
 	_, _ = fmt.Fprintf(&buf, "  Index Length: %d\n", table.Info.IndexLen)
 	_, _ = fmt.Fprintf(&buf, "  Filter Offset: %d\n", table.Info.FilterOffset)
 	_, _ = fmt.Fprintf(&buf, "  Filter Length: %d\n", table.Info.FilterLen)
+	_, _ = fmt.Fprintf(&buf, "  Filter Bits Per Key: %d\n", table.Info.FilterBitsPerKey)
 	_, _ = fmt.Fprintf(&buf, "  Compression Codec: %s\n", table.Info.CompressionCodec)
+	_, _ = fmt.Fprintf(&buf, "  Compression Dictionary Length: %d\n", table.Info.CompressionDictLen)
 
-	// Print Bloom Filter info if present
-	if filter, ok := table.Bloom.Get(); ok {
-		_, _ = fmt.Fprintf(&buf, "Bloom Filter:\n")
-		_, _ = fmt.Fprintf(&buf, "  Number of Probes: %d\n", filter.NumProbes)
+	// Print Filter info if present
+	if filter, ok := table.Filter.Get(); ok {
+		_, _ = fmt.Fprintf(&buf, "Filter:\n")
+		_, _ = fmt.Fprintf(&buf, "  Policy: %s\n", filter.Policy.Name())
 		_, _ = fmt.Fprintf(&buf, "  Data Length: %d\n", len(filter.Data))
 	} else {
-		_, _ = fmt.Fprintf(&buf, "Bloom Filter:\n")
-		_, _ = fmt.Fprintf(&buf, "  No Bloom Filter\n")
+		_, _ = fmt.Fprintf(&buf, "Filter:\n")
+		_, _ = fmt.Fprintf(&buf, "  No Filter\n")
 	}
 
 	encoded := EncodeTable(table)
@@ -83,24 +97,42 @@ func PrettyPrint(table *Table) string {
 		return buf.String()
 	}
 
-	blocksMeta := index.BlockMeta()
+	// A partitioned index is printed as the blocks of each partition in order
+	partitions := []*Index{index}
+	if table.Info.IndexPartitioned {
+		partitions = partitions[:0]
+		for i := 0; i < index.BlockMetaLength(); i++ {
+			partition, err := ReadIndexPartition(table.Info, index, i, NewBytesBlob(encoded))
+			if err != nil {
+				buf.WriteString(fmt.Sprintf("ERROR: while parsing index partition %d - %s\n", i, err.Error()))
+				return buf.String()
+			}
+			partitions = append(partitions, partition)
+		}
+	}
+
 	_, _ = fmt.Fprintf(&buf, "Blocks:\n")
-	_, _ = fmt.Fprintf(&buf, "  First Block Offset: %d\n", blocksMeta[0].Offset)
+	_, _ = fmt.Fprintf(&buf, "  First Block Offset: %d\n", partitions[0].BlockMeta()[0].Offset)
 	_, _ = fmt.Fprintf(&buf, "  End Offset: %d\n", table.Info.FilterOffset)
 
-	for i, meta := range blocksMeta {
-		_, _ = fmt.Fprintf(&buf, "  Block %d:\n", i)
-		_, _ = fmt.Fprintf(&buf, "    Offset: %d\n", meta.Offset)
-		_, _ = fmt.Fprintf(&buf, "    FirstKey: []byte(\"%s\")\n", meta.FirstKey)
-		_, _ = fmt.Fprintf(&buf, "    KeyValues:\n")
+	blockNum := 0
+	for _, partition := range partitions {
+		for i, meta := range partition.BlockMeta() {
+			_, _ = fmt.Fprintf(&buf, "  Block %d:\n", blockNum)
+			_, _ = fmt.Fprintf(&buf, "    Offset: %d\n", meta.Offset)
+			_, _ = fmt.Fprintf(&buf, "    FirstKey: []byte(\"%s\")\n", meta.FirstKey)
+			_, _ = fmt.Fprintf(&buf, "    Entries: %d\n", meta.EntryCount)
+			_, _ = fmt.Fprintf(&buf, "    KeyValues:\n")
 
-		blk, err := ReadBlockRaw(table.Info, index, uint64(i), encoded)
-		if err != nil {
-			buf.WriteString(fmt.Sprintf("ERROR: while parsing block at offset %d - %s\n",
-				meta.Offset, err.Error()))
-			return buf.String()
+			blk, err := ReadBlockRaw(table.Info, partition, uint64(i), encoded)
+			if err != nil {
+				buf.WriteString(fmt.Sprintf("ERROR: while parsing block at offset %d - %s\n",
+					meta.Offset, err.Error()))
+				return buf.String()
+			}
+			_, _ = fmt.Fprintf(&buf, "%s\n", indent(6, block.PrettyPrint(blk)))
+			blockNum++
 		}
-		_, _ = fmt.Fprintf(&buf, "%s\n", indent(6, block.PrettyPrint(blk)))
 	}
 	return strings.TrimRight(buf.String(), "\n")
 }
@@ -0,0 +1,93 @@
+package sstable
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// encodeRangeTombstones encodes the range tombstones of the SSTable followed by
+// a checksum. Each tombstone is encoded as the following, where the lengths and
+// the sequence number are uvarints.
+//
+//	| start len | start | end len | end | seq |
+func encodeRangeTombstones(tombstones types.RangeTombstones) []byte {
+	var buf []byte
+	for _, t := range tombstones {
+		buf = binary.AppendUvarint(buf, uint64(len(t.Start)))
+		buf = append(buf, t.Start...)
+		buf = binary.AppendUvarint(buf, uint64(len(t.End)))
+		buf = append(buf, t.End...)
+		buf = binary.AppendUvarint(buf, t.Seq)
+	}
+	return binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+}
+
+// decodeRangeTombstones verifies the checksum of the range tombstones and decodes them
+func decodeRangeTombstones(buf []byte) (types.RangeTombstones, error) {
+	if len(buf) <= common.SizeOfUint32 {
+		return nil, common.ErrChecksumMismatch
+	}
+
+	checksumIndex := len(buf) - common.SizeOfUint32
+	data := buf[:checksumIndex]
+	expected, actual := binary.BigEndian.Uint32(buf[checksumIndex:]), crc32.ChecksumIEEE(data)
+	if expected != actual {
+		return nil, common.NewChecksumError(common.SectionRangeTombstones, expected, actual)
+	}
+
+	errTruncated := errors.New("range tombstones are truncated")
+	bytesField := func() ([]byte, error) {
+		n, size := binary.Uvarint(data)
+		if size <= 0 || uint64(len(data)-size) < n {
+			return nil, errTruncated
+		}
+		field := data[size : size+int(n)]
+		data = data[size+int(n):]
+		return field, nil
+	}
+
+	var tombstones types.RangeTombstones
+	for len(data) > 0 {
+		start, err := bytesField()
+		if err != nil {
+			return nil, err
+		}
+		end, err := bytesField()
+		if err != nil {
+			return nil, err
+		}
+		seq, size := binary.Uvarint(data)
+		if size <= 0 {
+			return nil, errTruncated
+		}
+		data = data[size:]
+		tombstones = append(tombstones, types.RangeTombstone{Start: start, End: end, Seq: seq})
+	}
+	return tombstones, nil
+}
+
+// ReadRangeTombstones reads the range tombstones of the SSTable, or returns nil
+// if the SSTable has none
+func ReadRangeTombstones(info *Info, obj common.ReadOnlyBlob) (types.RangeTombstones, error) {
+	if info.RangeTombstoneLen == 0 {
+		return nil, nil
+	}
+
+	buf, err := obj.ReadRange(common.Range{
+		Start: info.RangeTombstoneOffset,
+		End:   info.RangeTombstoneOffset + info.RangeTombstoneLen,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while reading range tombstones: %w", err)
+	}
+	tombstones, err := decodeRangeTombstones(buf)
+	if err != nil {
+		return nil, corruptionAt(err, common.SectionRangeTombstones, info.RangeTombstoneOffset, -1)
+	}
+	return tombstones, nil
+}
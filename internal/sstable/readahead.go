@@ -0,0 +1,110 @@
+package sstable
+
+// Readahead bounds the adaptive prefetching of an Iterator. Rather than keeping a
+// fixed number of blocks in flight, the Iterator observes how it is consumed and
+// adapts how far ahead it fetches, and how many blocks each range read fetches.
+//
+//   - A dense scan which waits on a block that has not arrived yet doubles the
+//     prefetch distance, so fetches keep up with a fast consumer.
+//   - A consumer which is slower than the fetches shrinks the distance by one
+//     block each time the whole window arrives before it is needed, so fewer
+//     blocks are held in memory.
+//   - A Seek which skips blocks, or moves backwards, halves the distance, as
+//     the blocks fetched ahead of a skip-heavy consumer are mostly wasted.
+//
+// Each range read fetches half the prefetch distance, so dense scans issue fewer
+// and larger requests while skip-heavy scans fetch one block at a time. As the
+// distance grows so do the range reads, up to MaxRequestBlocks blocks and
+// MaxRequestBytes bytes.
+type Readahead struct {
+	// MinBlocks is the smallest number of blocks fetched ahead of the block
+	// being consumed, and the distance the Iterator starts with
+	MinBlocks int
+
+	// MaxBlocks is the largest number of blocks fetched ahead of the block being
+	// consumed. Zero disables adaptive readahead.
+	MaxBlocks int
+
+	// MaxRequestBlocks is the largest number of consecutive blocks fetched with a
+	// single range read. Zero fetches each block with a read of its own.
+	MaxRequestBlocks int
+
+	// MaxRequestBytes is the largest number of bytes fetched with a single range
+	// read, which bounds the reads of SSTables with large blocks. A range read
+	// fetches at least one block. Zero leaves the reads bounded by MaxRequestBlocks
+	// only.
+	MaxRequestBytes uint64
+}
+
+// readahead holds the prefetch distance of an Iterator as it adapts to the
+// stride and rate at which the Iterator is consumed
+type readahead struct {
+	bounds Readahead
+
+	// distance is the number of blocks fetched ahead of the block being consumed
+	distance int
+
+	// ready is the number of blocks consumed in sequence since the consumer last
+	// had to wait for a fetch
+	ready int
+
+	// last is the block consumed last, -1 if the next block does not follow it
+	// such as after a Seek
+	last int64
+}
+
+func newReadahead(bounds Readahead) *readahead {
+	bounds.MinBlocks = min(max(bounds.MinBlocks, 0), bounds.MaxBlocks)
+	return &readahead{bounds: bounds, distance: bounds.MinBlocks, last: -1}
+}
+
+// requestBlocks returns the number of blocks fetched by each range read
+func (r *readahead) requestBlocks() int {
+	return max(min(r.distance/2, r.bounds.MaxRequestBlocks), 1)
+}
+
+// sequential returns true if the block follows the block consumed last
+func (r *readahead) sequential(blockNum uint64) bool {
+	return r.last >= 0 && uint64(r.last)+1 == blockNum
+}
+
+// consumed records that the block was consumed, and whether the consumer had to
+// wait for it to be fetched. Only the blocks consumed in sequence adapt the
+// distance, as the first block after a Seek is never fetched ahead.
+func (r *readahead) consumed(blockNum uint64, waited bool) {
+	defer func() { r.last = int64(blockNum) }()
+	if !r.sequential(blockNum) {
+		return
+	}
+	if waited {
+		r.ready = 0
+		r.distance = min(max(r.distance*2, 1), r.bounds.MaxBlocks)
+		return
+	}
+	r.ready++
+	if r.ready > r.distance && r.distance > r.bounds.MinBlocks {
+		r.ready = 0
+		r.distance--
+	}
+}
+
+// seeked records a Seek to the block which is stride blocks after the block
+// consumed last, or to an unknown block if known is false, such as a block of
+// another index partition
+func (r *readahead) seeked(stride int64, known bool) {
+	if r.last < 0 {
+		// Nothing was consumed since the Iterator was created or last positioned
+		return
+	}
+	if known && stride == 1 {
+		// The Seek continues with the block which follows
+		return
+	}
+	r.last = -1
+	if known && stride == 0 {
+		// The Seek stays within the block consumed last, which is read again
+		return
+	}
+	r.ready = 0
+	r.distance = max(r.distance/2, r.bounds.MinBlocks)
+}
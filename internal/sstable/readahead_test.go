@@ -0,0 +1,53 @@
+package sstable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadahead(t *testing.T) {
+	r := newReadahead(Readahead{MinBlocks: 1, MaxBlocks: 8, MaxRequestBlocks: 2})
+	assert.Equal(t, 1, r.distance)
+	assert.Equal(t, 1, r.requestBlocks())
+
+	// The first block is never fetched ahead, so waiting for it doesn't adapt
+	r.consumed(0, true)
+	assert.Equal(t, 1, r.distance)
+
+	// A dense consumer which waits on the fetches doubles the distance
+	for blockNum := uint64(1); blockNum <= 4; blockNum++ {
+		r.consumed(blockNum, true)
+	}
+	assert.Equal(t, 8, r.distance)
+	assert.Equal(t, 2, r.requestBlocks())
+
+	// A consumer slower than the fetches shrinks the distance by a block each
+	// time the whole window arrives before it is needed
+	for blockNum := uint64(5); blockNum <= 13; blockNum++ {
+		r.consumed(blockNum, false)
+	}
+	assert.Equal(t, 7, r.distance)
+
+	// A Seek to the block which follows is dense
+	r.seeked(1, true)
+	r.consumed(14, true)
+	assert.Equal(t, 8, r.distance)
+
+	// Seeks which skip blocks, move backwards or to another partition halve the
+	// distance down to the minimum
+	r.seeked(10, true)
+	assert.Equal(t, 4, r.distance)
+	r.consumed(24, true)
+	assert.Equal(t, 4, r.distance)
+	r.seeked(-5, true)
+	assert.Equal(t, 2, r.distance)
+	r.consumed(19, false)
+	r.seeked(0, false)
+	assert.Equal(t, 1, r.distance)
+	assert.Equal(t, 1, r.requestBlocks())
+
+	// A Seek before anything was consumed since the last Seek doesn't adapt
+	r.seeked(10, true)
+	assert.Equal(t, 1, r.distance)
+}
@@ -0,0 +1,123 @@
+package sstable
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/samber/mo"
+
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
+)
+
+// Reader reads a single SSTable stored in a common.ReadOnlyBlob. The footer,
+// Info and Index are read once when the Reader is created, while blocks and
+// index partitions are fetched on demand using range reads, so the SSTable is
+// never fully loaded into memory.
+type Reader struct {
+	handle *Handle
+	obj    common.ReadOnlyBlob
+	index  *Index
+	filter mo.Option[Filter]
+	dict   []byte
+}
+
+// NewReader reads the footer, Info, Index, filter and compression dictionary (if present) of the
+// SSTable contained in obj. Filters created by a filter.Policy other than the
+// bloom filter are only used if the policy is provided.
+func NewReader(id ID, obj common.ReadOnlyBlob, policies ...filter.Policy) (*Reader, error) {
+	info, err := ReadInfo(obj)
+	if err != nil {
+		return nil, fmt.Errorf("while reading sst info: %w", err)
+	}
+
+	index, err := ReadIndex(info, obj)
+	if err != nil {
+		return nil, fmt.Errorf("while reading sst index: %w", err)
+	}
+
+	filtr, err := ReadFilter(info, obj, policies...)
+	if err != nil {
+		return nil, fmt.Errorf("while reading sst filter: %w", err)
+	}
+
+	dict, err := ReadDict(info, obj)
+	if err != nil {
+		return nil, fmt.Errorf("while reading sst compression dictionary: %w", err)
+	}
+
+	return &Reader{
+		handle: NewHandle(id, info),
+		obj:    obj,
+		index:  index,
+		filter: filtr,
+		dict:   dict,
+	}, nil
+}
+
+// Handle returns the Handle of the SSTable being read
+func (r *Reader) Handle() *Handle {
+	return r.handle
+}
+
+// Info returns the Info of the SSTable, which includes the key range and
+// statistics about the entries recorded when the SSTable was built
+func (r *Reader) Info() *Info {
+	return r.handle.Info
+}
+
+// RangeTombstones returns the range tombstones of the SSTable, or nil if it has none
+func (r *Reader) RangeTombstones() (types.RangeTombstones, error) {
+	return ReadRangeTombstones(r.handle.Info, r.obj)
+}
+
+// ReadIndex returns the Index read when the Reader was created
+func (r *Reader) ReadIndex(context.Context, *Handle) (*Index, error) {
+	return r.index, nil
+}
+
+// ReadIndexPartition reads the requested partition of a partitioned index. The
+// blob of the Reader is read without a context.
+func (r *Reader) ReadIndexPartition(_ context.Context, _ *Handle, topLevel *Index, partition int) (*Index, error) {
+	return ReadIndexPartition(r.handle.Info, topLevel, partition, r.obj)
+}
+
+// ReadBlocksUsingIndex fetches only the requested blocks from the object
+func (r *Reader) ReadBlocksUsingIndex(_ context.Context, _ *Handle, rng common.Range, index *Index) ([]block.Block, error) {
+	return ReadBlocksWithDict(r.handle.Info, index, rng, r.obj, r.dict)
+}
+
+// Get returns the value of the key if it exists in the SSTable. A tombstone
+// is returned as a types.Value of types.KindTombStone. The filter is
+// consulted first, and only the block which may contain the key is fetched.
+func (r *Reader) Get(ctx context.Context, key []byte) (mo.Option[types.Value], error) {
+	if !r.handle.RangeCoversKey(key) {
+		return mo.None[types.Value](), nil
+	}
+	if filtr, ok := r.filter.Get(); ok && !filtr.KeyMayMatch(key) {
+		return mo.None[types.Value](), nil
+	}
+
+	iter, err := NewIteratorAtKey(ctx, r.handle, key, r)
+	if err != nil {
+		return mo.None[types.Value](), err
+	}
+
+	entry, ok := iter.NextEntry(ctx)
+	if !ok {
+		return mo.None[types.Value](), iter.Warnings().If()
+	}
+	if !bytes.Equal(entry.Key, key) {
+		return mo.None[types.Value](), nil
+	}
+	return mo.Some(entry.Value), nil
+}
+
+// Iterator returns an Iterator over every entry in the SSTable
+func (r *Reader) Iterator() (*Iterator, error) {
+	// The index was read when the Reader was created
+	return NewIterator(context.Background(), r.handle, r)
+}
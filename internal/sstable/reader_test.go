@@ -0,0 +1,436 @@
+package sstable_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	assert2 "github.com/slatedb/slatedb-go/internal/assert"
+	"github.com/slatedb/slatedb-go/internal/compress"
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
+)
+
+// countingBlob records the number of bytes fetched by range reads
+type countingBlob struct {
+	common.ReadOnlyBlob
+	bytesRead int
+}
+
+func (c *countingBlob) ReadRange(r common.Range) ([]byte, error) {
+	c.bytesRead += int(r.End - r.Start)
+	return c.ReadOnlyBlob.ReadRange(r)
+}
+
+func buildTestTable(t *testing.T, count int) []byte {
+	return buildTestTableWithConfig(t, count, sstable.DefaultConfig())
+}
+
+func buildTestTableWithConfig(t *testing.T, count int, conf sstable.Config) []byte {
+	conf.BlockSize = block.EstimateBlockSize([]types.KeyValue{
+		{Key: []byte("key-000"), Value: []byte("value-000")},
+	})
+	builder := sstable.NewBuilder(conf)
+	for i := 0; i < count; i++ {
+		require.NoError(t, builder.AddValue([]byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i))))
+	}
+	require.NoError(t, builder.Add([]byte("key-999"), types.RowEntry{Value: types.Value{Kind: types.KindTombStone}}))
+
+	table, err := builder.Build()
+	require.NoError(t, err)
+	return sstable.EncodeTable(table)
+}
+
+func TestReader(t *testing.T) {
+	ctx := context.Background()
+	encoded := buildTestTable(t, 100)
+	blob := &countingBlob{ReadOnlyBlob: sstable.NewBytesBlob(encoded)}
+
+	reader, err := sstable.NewReader(sstable.NewIDWal(1), blob)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("key-000"), reader.Handle().Info.FirstKey)
+
+	t.Run("Get", func(t *testing.T) {
+		blob.bytesRead = 0
+		val, err := reader.Get(ctx, []byte("key-042"))
+		require.NoError(t, err)
+		v, ok := val.Get()
+		require.True(t, ok)
+		assert.Equal(t, []byte("value-042"), v.Value)
+
+		// Only a single block should be fetched from the object
+		assert.Less(t, blob.bytesRead, len(encoded)/10)
+	})
+
+	t.Run("Get Missing Key", func(t *testing.T) {
+		val, err := reader.Get(ctx, []byte("key-042a"))
+		require.NoError(t, err)
+		assert.True(t, val.IsAbsent())
+
+		val, err = reader.Get(ctx, []byte("a"))
+		require.NoError(t, err)
+		assert.True(t, val.IsAbsent())
+	})
+
+	t.Run("Get Filtered", func(t *testing.T) {
+		blob.bytesRead = 0
+		misses := 0
+		for i := 0; i < 100; i++ {
+			val, err := reader.Get(ctx, []byte(fmt.Sprintf("key-%03d-missing", i)))
+			require.NoError(t, err)
+			assert.True(t, val.IsAbsent())
+			if blob.bytesRead > 0 {
+				misses++
+				blob.bytesRead = 0
+			}
+		}
+		// The bloom filter should avoid fetching blocks for nearly every missing key
+		assert.Less(t, misses, 10)
+	})
+
+	t.Run("Get Tombstone", func(t *testing.T) {
+		val, err := reader.Get(ctx, []byte("key-999"))
+		require.NoError(t, err)
+		v, ok := val.Get()
+		require.True(t, ok)
+		assert.True(t, v.IsTombstone())
+	})
+
+	t.Run("Iterator", func(t *testing.T) {
+		iter, err := reader.Iterator()
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			assert2.NextEntry(t, iter, []byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i)))
+		}
+		assert2.NextEntry(t, iter, []byte("key-999"), nil)
+
+		_, ok := iter.NextEntry(ctx)
+		assert.False(t, ok)
+		assert.True(t, iter.Warnings().Empty())
+	})
+	t.Run("Approximate Size", func(t *testing.T) {
+		assertApproximateSize(t, reader)
+	})
+}
+
+func TestReaderPartitionedIndex(t *testing.T) {
+	ctx := context.Background()
+	conf := sstable.DefaultConfig()
+	conf.IndexPartitionThreshold = 4
+	encoded := buildTestTableWithConfig(t, 100, conf)
+	blob := &countingBlob{ReadOnlyBlob: sstable.NewBytesBlob(encoded)}
+
+	reader, err := sstable.NewReader(sstable.NewIDWal(1), blob)
+	require.NoError(t, err)
+	assert.True(t, reader.Handle().Info.IndexPartitioned)
+
+	t.Run("Get", func(t *testing.T) {
+		for _, i := range []int{0, 3, 4, 42, 99} {
+			blob.bytesRead = 0
+			key := []byte(fmt.Sprintf("key-%03d", i))
+			val, err := reader.Get(ctx, key)
+			require.NoError(t, err)
+			v, ok := val.Get()
+			require.True(t, ok, "key '%s' not found", key)
+			assert.Equal(t, []byte(fmt.Sprintf("value-%03d", i)), v.Value)
+
+			// Only a single partition and block should be fetched from the object
+			assert.Less(t, blob.bytesRead, len(encoded)/10)
+		}
+
+		val, err := reader.Get(ctx, []byte("key-999"))
+		require.NoError(t, err)
+		v, ok := val.Get()
+		require.True(t, ok)
+		assert.True(t, v.IsTombstone())
+	})
+
+	t.Run("Iterator", func(t *testing.T) {
+		iter, err := reader.Iterator()
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			assert2.NextEntry(t, iter, []byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i)))
+		}
+		assert2.NextEntry(t, iter, []byte("key-999"), nil)
+
+		_, ok := iter.NextEntry(ctx)
+		assert.False(t, ok)
+		assert.True(t, iter.Warnings().Empty())
+	})
+
+	t.Run("Iterator At Key", func(t *testing.T) {
+		iter, err := sstable.NewIteratorAtKey(ctx, reader.Handle(), []byte("key-041a"), reader)
+		require.NoError(t, err)
+		for i := 42; i < 100; i++ {
+			assert2.NextEntry(t, iter, []byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i)))
+		}
+		assert2.NextEntry(t, iter, []byte("key-999"), nil)
+
+		_, ok := iter.NextEntry(ctx)
+		assert.False(t, ok)
+	})
+
+	t.Run("Approximate Size", func(t *testing.T) {
+		assertApproximateSize(t, reader)
+	})
+}
+
+// assertApproximateSize asserts the approximate sizes of ranges of the table
+// built by buildTestTable, in which every key is written to its own block
+func assertApproximateSize(t *testing.T, reader *sstable.Reader) {
+	t.Helper()
+	total := reader.Info().FilterOffset
+	blockSize := float64(total) / 101
+
+	for _, test := range []struct {
+		start, end []byte
+		blocks     int
+	}{
+		{start: nil, end: nil, blocks: 101},
+		{start: []byte("key-010"), end: []byte("key-020"), blocks: 10},
+		{start: []byte("key-010a"), end: []byte("key-011a"), blocks: 2},
+		{start: []byte("key-050"), end: nil, blocks: 51},
+		{start: nil, end: []byte("key-000"), blocks: 0},
+		{start: []byte("zzz"), end: nil, blocks: 0},
+	} {
+		size, err := reader.ApproximateSize(test.start, test.end)
+		require.NoError(t, err)
+		assert.InDelta(t, blockSize*float64(test.blocks), float64(size), blockSize/2,
+			"range ['%s', '%s')", test.start, test.end)
+	}
+
+	size, err := reader.ApproximateSize(nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, total, size)
+}
+
+func TestReaderFilterPolicy(t *testing.T) {
+	ctx := context.Background()
+	conf := sstable.DefaultConfig()
+	conf.FilterPolicy = filter.NewPrefixPolicy(len("key-0"), filter.NewBloomPolicy(10))
+	encoded := buildTestTableWithConfig(t, 100, conf)
+
+	reader, err := sstable.NewReader(sstable.NewIDWal(1), sstable.NewBytesBlob(encoded), conf.FilterPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, "slatedb.FixedPrefix.5.slatedb.BloomFilter", reader.Info().FilterPolicy)
+
+	val, err := reader.Get(ctx, []byte("key-042"))
+	require.NoError(t, err)
+	v, ok := val.Get()
+	require.True(t, ok)
+	assert.Equal(t, []byte("value-042"), v.Value)
+
+	// A reader without the policy ignores the filter, but can still read the table
+	reader, err = sstable.NewReader(sstable.NewIDWal(1), sstable.NewBytesBlob(encoded))
+	require.NoError(t, err)
+	val, err = reader.Get(ctx, []byte("key-042"))
+	require.NoError(t, err)
+	assert.True(t, val.IsPresent())
+
+	f, err := sstable.ReadFilter(reader.Info(), sstable.NewBytesBlob(encoded))
+	require.NoError(t, err)
+	assert.True(t, f.IsAbsent())
+}
+
+func TestReaderCompressionDict(t *testing.T) {
+	ctx := context.Background()
+	build := func(dictSize uint64) *sstable.Table {
+		conf := sstable.DefaultConfig()
+		conf.BlockSize = 256
+		conf.Compression = compress.CodecZstd
+		conf.CompressionDictSize = dictSize
+		conf.CompressionDictTrainingBytes = 4096
+		builder := sstable.NewBuilder(conf)
+		for i := 0; i < 500; i++ {
+			value := fmt.Sprintf(`{"tenant":"acme","user_id":%d,"status":"active","region":"us-east-1"}`, i)
+			require.NoError(t, builder.AddValue([]byte(fmt.Sprintf("key-%03d", i)), []byte(value)))
+		}
+		table, err := builder.Build()
+		require.NoError(t, err)
+		return table
+	}
+
+	withoutDict := build(0)
+	assert.Equal(t, uint64(0), withoutDict.Info.CompressionDictLen)
+	table := build(1024)
+	require.NotZero(t, table.Info.CompressionDictLen)
+	assert.Less(t, table.Info.CompressedSize, withoutDict.Info.CompressedSize)
+
+	encoded := sstable.EncodeTable(table)
+	reader, err := sstable.NewReader(sstable.NewIDWal(1), sstable.NewBytesBlob(encoded))
+	require.NoError(t, err)
+
+	val, err := reader.Get(ctx, []byte("key-042"))
+	require.NoError(t, err)
+	v, ok := val.Get()
+	require.True(t, ok)
+	assert.Contains(t, string(v.Value), `"user_id":42,`)
+
+	iter, err := reader.Iterator()
+	require.NoError(t, err)
+	for i := 0; i < 500; i++ {
+		kv, ok := iter.NextEntry(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []byte(fmt.Sprintf("key-%03d", i)), kv.Key)
+	}
+	assert.True(t, iter.Warnings().Empty())
+}
+
+// concurrentBlob records the number of range reads and the maximum number of
+// range reads in flight at once
+type concurrentBlob struct {
+	common.ReadOnlyBlob
+	mu       sync.Mutex
+	reads    int
+	inFlight int
+	maxReads int
+}
+
+func (c *concurrentBlob) ReadRange(r common.Range) ([]byte, error) {
+	c.mu.Lock()
+	c.reads++
+	c.inFlight++
+	c.maxReads = max(c.maxReads, c.inFlight)
+	c.mu.Unlock()
+
+	// Give the other reads a chance to start
+	time.Sleep(10 * time.Millisecond)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return c.ReadOnlyBlob.ReadRange(r)
+}
+
+func TestReadBlocksParallel(t *testing.T) {
+	encoded := buildTestTable(t, 100)
+	bytesBlob := sstable.NewBytesBlob(encoded)
+	info, err := sstable.ReadInfo(bytesBlob)
+	require.NoError(t, err)
+	index, err := sstable.ReadIndex(info, bytesBlob)
+	require.NoError(t, err)
+
+	rng := common.Range{Start: 10, End: 60}
+	expected, err := sstable.ReadBlocksWithDict(info, index, rng, bytesBlob, nil)
+	require.NoError(t, err)
+
+	blob := &concurrentBlob{ReadOnlyBlob: bytesBlob}
+	blocks, err := sstable.ReadBlocksParallel(info, index, rng, blob, nil, 4)
+	require.NoError(t, err)
+	assert.Equal(t, expected, blocks)
+	assert.Equal(t, 4, blob.reads)
+	assert.Equal(t, 4, blob.maxReads)
+
+	// Never more parts than blocks
+	blob = &concurrentBlob{ReadOnlyBlob: bytesBlob}
+	blocks, err = sstable.ReadBlocksParallel(info, index, common.Range{Start: 10, End: 12}, blob, nil, 4)
+	require.NoError(t, err)
+	assert.Equal(t, expected[:2], blocks)
+	assert.Equal(t, 2, blob.reads)
+
+	// A parallelism of one reads the range with a single request
+	blob = &concurrentBlob{ReadOnlyBlob: bytesBlob}
+	blocks, err = sstable.ReadBlocksParallel(info, index, rng, blob, nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, expected, blocks)
+	assert.Equal(t, 1, blob.reads)
+}
+
+// slowStore records the size of the block reads of an Iterator, which each take
+// a millisecond so a consumer which doesn't wait between entries catches up
+type slowStore struct {
+	*sstable.Reader
+	mu    sync.Mutex
+	reads []int
+}
+
+func (s *slowStore) ReadBlocksUsingIndex(ctx context.Context, h *sstable.Handle, rng common.Range, index *sstable.Index) ([]block.Block, error) {
+	s.mu.Lock()
+	s.reads = append(s.reads, int(rng.End-rng.Start))
+	s.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	return s.Reader.ReadBlocksUsingIndex(ctx, h, rng, index)
+}
+
+// readSizes returns the sizes of the block reads so far, as prefetches may still
+// be in flight
+func (s *slowStore) readSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.reads)
+}
+
+func TestIteratorReadahead(t *testing.T) {
+	ctx := context.Background()
+	reader, err := sstable.NewReader(sstable.NewIDWal(1), sstable.NewBytesBlob(buildTestTable(t, 100)))
+	require.NoError(t, err)
+	opts := sstable.IteratorOptions{Readahead: sstable.Readahead{MinBlocks: 1, MaxBlocks: 8, MaxRequestBlocks: 4}}
+
+	t.Run("Dense", func(t *testing.T) {
+		store := &slowStore{Reader: reader}
+		iter, err := sstable.NewIteratorWithOptions(ctx, reader.Handle(), store, opts)
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			assert2.NextEntry(t, iter, []byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i)))
+		}
+		assert2.NextEntry(t, iter, []byte("key-999"), nil)
+		_, ok := iter.NextEntry(ctx)
+		assert.False(t, ok)
+		assert.True(t, iter.Warnings().Empty())
+
+		// The readahead grows to the largest requests, so the blocks are fetched
+		// with far fewer reads than blocks
+		reads := store.readSizes()
+		assert.Contains(t, reads, 4)
+		assert.Less(t, len(reads), 50)
+	})
+
+	t.Run("Skip Heavy", func(t *testing.T) {
+		store := &slowStore{Reader: reader}
+		iter, err := sstable.NewIteratorWithOptions(ctx, reader.Handle(), store, opts)
+		require.NoError(t, err)
+		// Read the first few blocks densely, so the readahead grows
+		for i := 0; i < 10; i++ {
+			assert2.NextEntry(t, iter, []byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i)))
+		}
+		for i := 20; i < 100; i += 20 {
+			require.NoError(t, iter.Seek(ctx, []byte(fmt.Sprintf("key-%03d", i))))
+			assert2.NextEntry(t, iter, []byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i)))
+		}
+		assert.True(t, iter.Warnings().Empty())
+
+		// Each skip halves the readahead, so the last seeks fetch one block at a time
+		reads := store.readSizes()
+		assert.Equal(t, []int{1, 1}, reads[len(reads)-2:])
+	})
+
+	t.Run("Request Bytes", func(t *testing.T) {
+		index, err := reader.ReadIndex(ctx, reader.Handle())
+		require.NoError(t, err)
+		opts := opts
+		opts.Readahead.MaxRequestBytes = index.BlockMeta()[2].Offset - index.BlockMeta()[0].Offset
+
+		store := &slowStore{Reader: reader}
+		iter, err := sstable.NewIteratorWithOptions(ctx, reader.Handle(), store, opts)
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			assert2.NextEntry(t, iter, []byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i)))
+		}
+		assert.True(t, iter.Warnings().Empty())
+
+		// The range reads grow up to the two blocks which fit MaxRequestBytes
+		reads := store.readSizes()
+		assert.Contains(t, reads, 2)
+		assert.NotContains(t, reads, 3)
+		assert.NotContains(t, reads, 4)
+	})
+}
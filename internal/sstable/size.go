@@ -0,0 +1,85 @@
+package sstable
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// ApproximateSize returns the approximate number of bytes of the blocks of the
+// SSTable which hold keys in the range [start, end). A nil start or end leaves the
+// range unbounded. The estimate is computed from the index, so no blocks are read,
+// and every block which may hold a key in the range is counted in full. Only the
+// index partitions which overlap the range are read if the index is partitioned.
+func ApproximateSize(ctx context.Context, handle *Handle, store TableStore, start []byte, end []byte) (uint64, error) {
+	if !handle.OverlapsRange(start, end) {
+		return 0, nil
+	}
+
+	index, err := store.ReadIndex(ctx, handle)
+	if err != nil {
+		return 0, fmt.Errorf("while reading index: %w", err)
+	}
+	if !handle.Info.IndexPartitioned {
+		return blocksSize(handle.Info, index, start, end), nil
+	}
+
+	var size uint64
+	partitions := index.BlockMeta()
+	for i, p := range partitions {
+		var next []byte
+		if i+1 < len(partitions) {
+			next = partitions[i+1].FirstKey
+		}
+		if !overlapsRange(p.FirstKey, next, start, end) {
+			continue
+		}
+		partition, err := store.ReadIndexPartition(ctx, handle, index, i)
+		if err != nil {
+			return 0, fmt.Errorf("while reading index partition '%d': %w", i, err)
+		}
+		size += blocksSize(handle.Info, partition, start, end)
+	}
+	return size, nil
+}
+
+// ApproximateSize returns the approximate number of bytes of the SSTable which
+// hold keys in the range [start, end). See ApproximateSize for details.
+func (r *Reader) ApproximateSize(start []byte, end []byte) (uint64, error) {
+	return ApproximateSize(context.Background(), r.handle, r, start, end)
+}
+
+// blocksSize returns the total size of the blocks referenced by the index
+// which may hold keys in the range [start, end)
+func blocksSize(info *Info, index *Index, start []byte, end []byte) uint64 {
+	// The last block ends at the end of the index partition, or at the filter
+	// if the index is not partitioned.
+	endOffset := index.EndOffset()
+	if endOffset == 0 {
+		endOffset = info.FilterOffset
+	}
+
+	var size uint64
+	blocks := index.BlockMeta()
+	for i, blk := range blocks {
+		var next []byte
+		blockEnd := endOffset
+		if i+1 < len(blocks) {
+			next = blocks[i+1].FirstKey
+			blockEnd = blocks[i+1].Offset
+		}
+		if overlapsRange(blk.FirstKey, next, start, end) {
+			size += blockEnd - blk.Offset
+		}
+	}
+	return size
+}
+
+// overlapsRange returns true if keys in the range [first, next) may also be in
+// the range [start, end). A nil next, start or end leaves the range unbounded.
+func overlapsRange(first []byte, next []byte, start []byte, end []byte) bool {
+	if end != nil && bytes.Compare(first, end) >= 0 {
+		return false
+	}
+	return next == nil || start == nil || bytes.Compare(next, start) > 0
+}
@@ -28,6 +28,67 @@ type Info struct {
 
 	// the codec used to compress/decompress SSTable before writing/reading from object storage
 	CompressionCodec compress.Codec
+
+	// true if the SSTableIndex at IndexOffset references index partitions rather than blocks
+	IndexPartitioned bool
+
+	// contains the LastKey of the SSTable. SSTables written before the LastKey
+	// was recorded have an empty LastKey and zero for each of the counts below.
+	LastKey []byte
+
+	// the number of entries in the SSTable, including tombstones
+	EntryCount uint64
+
+	// the number of tombstones in the SSTable
+	TombstoneCount uint64
+
+	// the total size of the keys and values before they were encoded into blocks
+	RawSize uint64
+
+	// the total size of the encoded and compressed blocks
+	CompressedSize uint64
+
+	// the name of the filter.Policy which created the filter. SSTables written
+	// before the policy was recorded have an empty name and use the bloom filter.
+	FilterPolicy string
+
+	// the offset at which the compression dictionary starts when SSTable is serialized
+	CompressionDictOffset uint64
+
+	// the length of the compression dictionary, zero if the blocks were
+	// compressed without a dictionary
+	CompressionDictLen uint64
+
+	// the bits per key of the filter, zero if the SSTable has no filter or the
+	// filter.Policy does not use a fixed number of bits per key
+	FilterBitsPerKey uint32
+
+	// the target size of the blocks, zero if the SSTable was written before the
+	// block size was recorded
+	BlockSize uint64
+
+	// the offset at which the range tombstones start when SSTable is serialized
+	RangeTombstoneOffset uint64
+
+	// the length of the range tombstones, zero if the SSTable has none
+	RangeTombstoneLen uint64
+
+	// the writer epoch of the client which wrote a WAL SSTable, zero for other
+	// SSTables and for WAL SSTables written before the epoch was recorded
+	WriterEpoch uint64
+
+	// the version of the row encoding of the blocks, see block.FormatVersion.
+	// SSTables written before the version was recorded have version 0 rows.
+	BlockFormatVersion uint16
+}
+
+// TombstoneDensity returns the fraction of the entries in the SSTable which are
+// tombstones. SSTables written before entries were counted return zero.
+func (info *Info) TombstoneDensity() float64 {
+	if info.EntryCount == 0 {
+		return 0
+	}
+	return float64(info.TombstoneCount) / float64(info.EntryCount)
 }
 
 func (info *Info) Clone() *Info {
@@ -38,5 +99,21 @@ func (info *Info) Clone() *Info {
 		FilterOffset:     info.FilterOffset,
 		FilterLen:        info.FilterLen,
 		CompressionCodec: info.CompressionCodec,
+		IndexPartitioned: info.IndexPartitioned,
+		LastKey:          bytes.Clone(info.LastKey),
+		EntryCount:       info.EntryCount,
+		TombstoneCount:   info.TombstoneCount,
+		RawSize:          info.RawSize,
+		CompressedSize:   info.CompressedSize,
+		FilterPolicy:     info.FilterPolicy,
+
+		CompressionDictOffset: info.CompressionDictOffset,
+		CompressionDictLen:    info.CompressionDictLen,
+		FilterBitsPerKey:      info.FilterBitsPerKey,
+		BlockSize:             info.BlockSize,
+		RangeTombstoneOffset:  info.RangeTombstoneOffset,
+		RangeTombstoneLen:     info.RangeTombstoneLen,
+		WriterEpoch:           info.WriterEpoch,
+		BlockFormatVersion:    info.BlockFormatVersion,
 	}
 }
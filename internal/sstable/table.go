@@ -80,22 +80,55 @@ func (s *ID) Clone() ID {
 type Handle struct {
 	Id   ID
 	Info *Info
+
+	// Inline holds the encoded SSTable if it is stored inline in the manifest
+	// rather than as an object, and is nil otherwise.
+	Inline []byte
 }
 
 func NewHandle(id ID, info *Info) *Handle {
-	return &Handle{id, info}
+	return &Handle{Id: id, Info: info}
+}
+
+// NewInlineHandle returns a Handle to an SSTable which is stored inline in the
+// manifest as the provided encoded bytes
+func NewInlineHandle(id ID, info *Info, data []byte) *Handle {
+	return &Handle{Id: id, Info: info, Inline: data}
+}
+
+// IsInline returns true if the SSTable is stored inline in the manifest
+func (h *Handle) IsInline() bool {
+	return h.Inline != nil
 }
 
 func (h *Handle) RangeCoversKey(key []byte) bool {
 	if len(h.Info.FirstKey) == 0 {
 		return false
 	}
+	if len(h.Info.LastKey) != 0 && bytes.Compare(key, h.Info.LastKey) > 0 {
+		return false
+	}
 	return bytes.Compare(key, h.Info.FirstKey) >= 0
 }
 
+// OverlapsRange returns true if the SSTable may contain keys in the range [start, end).
+// A nil start or end leaves the range unbounded. SSTables which do not record
+// a LastKey are assumed to extend to the end of the keyspace.
+func (h *Handle) OverlapsRange(start []byte, end []byte) bool {
+	if end != nil && bytes.Compare(h.Info.FirstKey, end) >= 0 {
+		return false
+	}
+	if start != nil && len(h.Info.LastKey) != 0 && bytes.Compare(h.Info.LastKey, start) < 0 {
+		return false
+	}
+	return true
+}
+
 func (h *Handle) Clone() *Handle {
 	return &Handle{
 		Id:   h.Id.Clone(),
 		Info: h.Info.Clone(),
+		// The encoded SSTable is never modified, so it is shared with the clone
+		Inline: h.Inline,
 	}
 }
@@ -0,0 +1,86 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// InternalKeyTrailerLen is the length of the trailer which an internal key
+// appends to the user key
+const InternalKeyTrailerLen = 8
+
+// MaxSeq is the highest sequence number an internal key can hold, as the
+// trailer packs the sequence number and the Kind of the entry into 8 bytes.
+// Higher sequence numbers are stored as MaxSeq.
+const MaxSeq uint64 = 1<<56 - 1
+
+// An internal key is the key of a version of an entry, which the memtable and the
+// blocks of SSTables order the entries by. It is the user key followed by a
+// trailer which packs the sequence number and the Kind of the entry.
+//
+//	| user key | seq << 8 | kind (8 bytes, big endian) |
+//
+// Internal keys are ordered by CompareInternalKeys, ascending by user key and
+// then descending by trailer, such that the newest version of a key comes first.
+
+// AppendInternalKey appends the internal key of the version of the user key
+// written with the sequence number and kind to dst
+func AppendInternalKey(dst []byte, userKey []byte, seq uint64, kind Kind) []byte {
+	dst = append(dst, userKey...)
+	return binary.BigEndian.AppendUint64(dst, packTrailer(seq, kind))
+}
+
+// InternalKey returns the internal key of the version of the user key written
+// with the sequence number and kind
+func InternalKey(userKey []byte, seq uint64, kind Kind) []byte {
+	return AppendInternalKey(make([]byte, 0, len(userKey)+InternalKeyTrailerLen), userKey, seq, kind)
+}
+
+// SeekInternalKey returns the internal key which sorts before every version of
+// the user key with a sequence number of at most seq, such that seeking to it
+// finds the newest version visible at seq. Use MaxSeq to find the newest version.
+func SeekInternalKey(userKey []byte, seq uint64) []byte {
+	// The highest kind sorts first among the versions of the same sequence number
+	return InternalKey(userKey, seq, Kind(0xff))
+}
+
+// ParseInternalKey splits the internal key into its user key, which shares the
+// memory of the internal key, its sequence number and its kind
+func ParseInternalKey(key []byte) ([]byte, uint64, Kind, error) {
+	if len(key) < InternalKeyTrailerLen {
+		return nil, 0, 0, fmt.Errorf("internal key of %d bytes is shorter than its trailer", len(key))
+	}
+	n := len(key) - InternalKeyTrailerLen
+	trailer := binary.BigEndian.Uint64(key[n:])
+	return key[:n:n], trailer >> 8, Kind(trailer), nil
+}
+
+// UserKey returns the user key of the internal key, which shares the memory of
+// the internal key. The internal key must be valid.
+func UserKey(key []byte) []byte {
+	n := len(key) - InternalKeyTrailerLen
+	return key[:n:n]
+}
+
+// CompareInternalKeys compares internal keys by user key in ascending order, and
+// then by sequence number and kind in descending order, such that the newest
+// version of a key comes first. Both internal keys must be valid.
+func CompareInternalKeys(a []byte, b []byte) int {
+	if cmp := bytes.Compare(UserKey(a), UserKey(b)); cmp != 0 {
+		return cmp
+	}
+	ta := binary.BigEndian.Uint64(a[len(a)-InternalKeyTrailerLen:])
+	tb := binary.BigEndian.Uint64(b[len(b)-InternalKeyTrailerLen:])
+	switch {
+	case ta > tb:
+		return -1
+	case ta < tb:
+		return 1
+	}
+	return 0
+}
+
+func packTrailer(seq uint64, kind Kind) uint64 {
+	return min(seq, MaxSeq)<<8 | uint64(kind)
+}
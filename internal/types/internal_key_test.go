@@ -0,0 +1,61 @@
+package types_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slatedb/slatedb-go/internal/types"
+)
+
+func TestInternalKey(t *testing.T) {
+	key := types.InternalKey([]byte("key"), 42, types.KindMerge)
+	assert.Len(t, key, 3+types.InternalKeyTrailerLen)
+	assert.Equal(t, []byte("key"), types.UserKey(key))
+
+	userKey, seq, kind, err := types.ParseInternalKey(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("key"), userKey)
+	assert.Equal(t, uint64(42), seq)
+	assert.Equal(t, types.KindMerge, kind)
+
+	// Appending to the parsed user key must not overwrite the trailer
+	_ = append(userKey, 'x')
+	assert.Equal(t, []byte("key"), types.UserKey(key))
+
+	_, _, _, err = types.ParseInternalKey([]byte("short"))
+	assert.Error(t, err)
+
+	_, seq, _, err = types.ParseInternalKey(types.InternalKey([]byte("key"), types.MaxSeq+1, types.KindKeyValue))
+	require.NoError(t, err)
+	assert.Equal(t, types.MaxSeq, seq)
+}
+
+func TestCompareInternalKeys(t *testing.T) {
+	// Ordered by user key, and then from the newest version to the oldest
+	expected := [][]byte{
+		types.InternalKey([]byte("a"), 3, types.KindTombStone),
+		types.InternalKey([]byte("a"), 2, types.KindKeyValue),
+		types.InternalKey([]byte("a"), 0, types.KindKeyValue),
+		// A user key which is a prefix of another user key sorts first
+		types.InternalKey([]byte("ab"), 1, types.KindKeyValue),
+		types.InternalKey([]byte("b"), 5, types.KindMerge),
+		types.InternalKey([]byte("b"), 5, types.KindKeyValue),
+	}
+	keys := slices.Clone(expected)
+	slices.Reverse(keys)
+	slices.SortFunc(keys, types.CompareInternalKeys)
+	assert.Equal(t, expected, keys)
+
+	assert.Equal(t, 0, types.CompareInternalKeys(expected[0], types.InternalKey([]byte("a"), 3, types.KindTombStone)))
+
+	// Seeking finds the newest version visible at the sequence number
+	seek := types.SeekInternalKey([]byte("a"), 2)
+	i, _ := slices.BinarySearchFunc(expected, seek, types.CompareInternalKeys)
+	assert.Equal(t, 1, i)
+	seek = types.SeekInternalKey([]byte("b"), types.MaxSeq)
+	i, _ = slices.BinarySearchFunc(expected, seek, types.CompareInternalKeys)
+	assert.Equal(t, 4, i)
+}
@@ -0,0 +1,37 @@
+package types
+
+import "github.com/samber/mo"
+
+// MergeOperator combines the merge operands written by DB.Merge with the older
+// value of a key, such as incrementing a counter or adding to a set. Operands are
+// applied lazily when the key is read or compacted, and may first be combined
+// with each other, so Merge must be associative.
+type MergeOperator interface {
+	// Merge returns the result of applying the operand to the existing value of
+	// the key. Existing is absent if the key has no older value or was deleted,
+	// and may be the result of merging earlier operands.
+	Merge(key []byte, existing mo.Option[[]byte], operand []byte) []byte
+}
+
+// MergeValue applies the merge operand to the older value of the key. The result
+// is a merge operand if older is a merge operand, as there may be values older
+// still which the operands have yet to be applied to.
+func MergeValue(op MergeOperator, key []byte, older Value, operand []byte) Value {
+	switch older.Kind {
+	case KindMerge:
+		return Value{Kind: KindMerge, Value: op.Merge(key, mo.Some(older.Value), operand)}
+	case KindTombStone:
+		return Value{Kind: KindKeyValue, Value: op.Merge(key, mo.None[[]byte](), operand)}
+	default:
+		return Value{Kind: KindKeyValue, Value: op.Merge(key, mo.Some(older.Value), operand)}
+	}
+}
+
+// ResolveMerge returns the value of a merge operand which has no older value to
+// be applied to. Values which are not merge operands are returned as is.
+func ResolveMerge(op MergeOperator, key []byte, v Value) Value {
+	if v.Kind != KindMerge {
+		return v
+	}
+	return MergeValue(op, key, Value{Kind: KindTombStone}, v.Value)
+}
@@ -0,0 +1,55 @@
+package types
+
+import "bytes"
+
+// RangeTombstone deletes every key in the range [Start, End) which was written
+// before the tombstone, that is every entry of the range with a lower sequence
+// number than the tombstone.
+type RangeTombstone struct {
+	Start []byte
+	End   []byte
+	Seq   uint64
+}
+
+// Contains returns true if the key is in the range of the tombstone
+func (t RangeTombstone) Contains(key []byte) bool {
+	return bytes.Compare(key, t.Start) >= 0 && bytes.Compare(key, t.End) < 0
+}
+
+// Covers returns true if the tombstone deletes the entry
+func (t RangeTombstone) Covers(entry RowEntry) bool {
+	return entry.Seq < t.Seq && t.Contains(entry.Key)
+}
+
+// RangeTombstones is a set of range tombstones, which may overlap
+type RangeTombstones []RangeTombstone
+
+// Covers returns true if any of the tombstones deletes the entry
+func (ts RangeTombstones) Covers(entry RowEntry) bool {
+	for _, t := range ts {
+		if t.Covers(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxSeq returns the highest sequence number of the tombstones which contain the
+// key, or zero if no tombstone contains the key
+func (ts RangeTombstones) MaxSeq(key []byte) uint64 {
+	var seq uint64
+	for _, t := range ts {
+		if t.Seq > seq && t.Contains(key) {
+			seq = t.Seq
+		}
+	}
+	return seq
+}
+
+// Apply returns the entry as a tombstone if any of the tombstones deletes it
+func (ts RangeTombstones) Apply(entry RowEntry) RowEntry {
+	if ts.Covers(entry) {
+		entry.Value = Value{Kind: KindTombStone}
+	}
+	return entry
+}
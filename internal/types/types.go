@@ -1,6 +1,9 @@
 package types
 
 import (
+	"hash/crc32"
+	"time"
+
 	"github.com/samber/mo"
 )
 
@@ -9,7 +12,9 @@ type Kind byte
 const (
 	KindKeyValue  Kind = 0x00
 	KindTombStone Kind = 0x01
-	// TODO(thrawn01): Future MergeOperator
+	// KindMerge is a merge operand, which the MergeOperator combines with the
+	// older value of the key when the key is read or compacted, unless the read
+	// returns the raw operands, see config.ReadOptions.RawMergeOperands.
 	KindMerge Kind = 0x02
 )
 
@@ -24,9 +29,15 @@ type RowEntry struct {
 	Key   []byte
 	Value Value
 
+	// Seq is the sequence number of the write, which increases monotonically with
+	// every write to the DB. Zero for entries written without a sequence number.
+	Seq uint64
+
+	// Created is the time of the write with millisecond precision, or the zero
+	// time if the time of the write was not recorded.
+	Created time.Time
+
 	// // Future Use
-	// Seq     uint64
-	// Created time.Time
 	// Expired time.Time
 }
 
@@ -35,8 +46,19 @@ type RowEntry struct {
 type Value struct {
 	Value []byte
 	Kind  Kind
+
+	// Checksum is the ValueChecksum of the Value recorded when the value was
+	// written to an SSTable, if value checksums were enabled.
+	Checksum mo.Option[uint32]
 }
 
+// ValueChecksum returns the CRC-32C checksum of the value
+func ValueChecksum(value []byte) uint32 {
+	return crc32.Checksum(value, castagnoli)
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
 func (v Value) IsTombstone() bool {
 	return v.Kind == KindTombStone
 }
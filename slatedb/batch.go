@@ -0,0 +1,131 @@
+package slatedb
+
+import (
+	"bytes"
+
+	"github.com/samber/mo"
+
+	"github.com/slatedb/slatedb-go/internal/assert"
+	"github.com/slatedb/slatedb-go/internal/types"
+)
+
+// WriteBatch collects Put, Delete and DeleteRange operations which are applied to
+// the DB together by DB.Write. When the same key is written more than once only
+// the last write is kept, so the batch never encodes more than one entry per key
+// into the WAL or the memtable.
+//
+// Every write of a batch is written to the same WAL while holding its lock, so
+// readers observe either none or all of the writes of the batch. The WAL is
+// uploaded with a single PUT, so a batch is atomic and costs one PUT regardless
+// of its size.
+// TODO: The DB has a single keyspace. If keyspaces are added, a batch which spans
+// keyspaces must still be encoded into a single WAL to keep it atomic, rather
+// than a WAL per keyspace, which would also multiply the PUTs of each batch.
+type WriteBatch struct {
+	entries []types.RowEntry
+
+	// positions maps each key in the batch to its position in entries
+	positions map[string]int
+
+	// ranges are the range tombstones of the batch, which are written before its
+	// entries, see DeleteRange
+	ranges types.RangeTombstones
+
+	// conditions are the values the keys must have for the batch to be applied,
+	// see DB.WriteIf
+	conditions []condition
+}
+
+// condition is the value a key must have for a conditional WriteBatch to be
+// applied, or None if the key must not exist
+type condition struct {
+	key   []byte
+	value mo.Option[[]byte]
+}
+
+func NewWriteBatch() *WriteBatch {
+	return &WriteBatch{
+		positions: make(map[string]int),
+	}
+}
+
+// Put adds the key and value to the batch, replacing any earlier
+// Put or Delete of the same key in the batch
+func (b *WriteBatch) Put(key []byte, value []byte) {
+	assert.True(len(key) > 0, "key cannot be empty")
+	b.add(types.RowEntry{
+		Key:   bytes.Clone(key),
+		Value: types.Value{Kind: types.KindKeyValue, Value: bytes.Clone(value)},
+	})
+}
+
+// Delete adds a tombstone for the key to the batch, replacing any earlier
+// Put or Delete of the same key in the batch
+func (b *WriteBatch) Delete(key []byte) {
+	assert.True(len(key) > 0, "key cannot be empty")
+	b.add(types.RowEntry{
+		Key:   bytes.Clone(key),
+		Value: types.Value{Kind: types.KindTombStone},
+	})
+}
+
+// DeleteRange adds a range tombstone for the keys in the range [start, end) to
+// the batch, see DB.DeleteRange. Any earlier Put or Delete of a key in the range
+// is removed from the batch, while a later one is kept, as the range tombstones
+// of a batch are written before its entries.
+func (b *WriteBatch) DeleteRange(start []byte, end []byte) {
+	assert.True(len(start) > 0, "start key cannot be empty")
+	assert.True(bytes.Compare(start, end) < 0, "end key must be greater than the start key")
+	tombstone := types.RangeTombstone{Start: bytes.Clone(start), End: bytes.Clone(end)}
+
+	entries := b.entries[:0]
+	for _, entry := range b.entries {
+		if tombstone.Contains(entry.Key) {
+			delete(b.positions, string(entry.Key))
+			continue
+		}
+		b.positions[string(entry.Key)] = len(entries)
+		entries = append(entries, entry)
+	}
+	clear(b.entries[len(entries):])
+	b.entries = entries
+	b.ranges = append(b.ranges, tombstone)
+}
+
+// Expect makes the batch conditional on the key having the value, see DB.WriteIf.
+// The key doesn't need to be written by the batch.
+func (b *WriteBatch) Expect(key []byte, value []byte) {
+	assert.True(len(key) > 0, "key cannot be empty")
+	b.conditions = append(b.conditions, condition{key: bytes.Clone(key), value: mo.Some(bytes.Clone(value))})
+}
+
+// ExpectAbsent makes the batch conditional on the key not existing, because it
+// was never written or was deleted, see DB.WriteIf
+func (b *WriteBatch) ExpectAbsent(key []byte) {
+	assert.True(len(key) > 0, "key cannot be empty")
+	b.conditions = append(b.conditions, condition{key: bytes.Clone(key), value: mo.None[[]byte]()})
+}
+
+// Len returns the number of distinct keys and ranges written by the batch
+func (b *WriteBatch) Len() int {
+	return len(b.entries) + len(b.ranges)
+}
+
+func (b *WriteBatch) add(entry types.RowEntry) {
+	if i, ok := b.positions[string(entry.Key)]; ok {
+		b.entries[i] = entry
+		return
+	}
+	b.positions[string(entry.Key)] = len(b.entries)
+	b.entries = append(b.entries, entry)
+}
+
+// batchSeq returns the sequence number of the last write of a batch whose range
+// tombstones and entries were assigned their sequence numbers, see
+// state.DBState.WriteEntriesToWAL
+func batchSeq(tombstones types.RangeTombstones, entries []types.RowEntry) uint64 {
+	if len(entries) > 0 {
+		return entries[len(entries)-1].Seq
+	}
+	return tombstones[len(tombstones)-1].Seq
+}
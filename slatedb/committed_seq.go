@@ -0,0 +1,64 @@
+package slatedb
+
+import (
+	"context"
+	"sync"
+)
+
+// committedSeqWatch wakes the callers watching the committed sequence number each
+// time a WAL is flushed to object storage, see DB.CommittedSeq
+type committedSeqWatch struct {
+	mu sync.Mutex
+	// advanced is closed and replaced each time the committed sequence number
+	// advances, which wakes the callers to check it again
+	advanced chan struct{}
+}
+
+func newCommittedSeqWatch() *committedSeqWatch {
+	return &committedSeqWatch{advanced: make(chan struct{})}
+}
+
+// notify wakes the callers watching the committed sequence number
+func (w *committedSeqWatch) notify() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	close(w.advanced)
+	w.advanced = make(chan struct{})
+}
+
+func (w *committedSeqWatch) changed() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.advanced
+}
+
+// CommittedSeqChanged returns a channel which is closed the next time the
+// committed sequence number advances, for applications which track the durable
+// watermark of the DB. A new channel must be taken after each change, and taken
+// before reading DB.CommittedSeq so that a change in between is not missed.
+//
+//	for {
+//		changed := db.CommittedSeqChanged()
+//		report(db.CommittedSeq())
+//		<-changed
+//	}
+func (db *DB) CommittedSeqChanged() <-chan struct{} {
+	return db.committedSeqWatch.changed()
+}
+
+// AwaitCommittedSeq blocks until the committed sequence number reaches seq, such
+// as the sequence number of a write returned by WriteFuture.Seq, or returns the
+// error of the context if it is done first.
+func (db *DB) AwaitCommittedSeq(ctx context.Context, seq uint64) error {
+	for {
+		changed := db.committedSeqWatch.changed()
+		if db.state.CommittedSeq() >= seq {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package slatedb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestAwaitCommittedSeq(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024*1024)
+	options.FlushInterval = time.Hour
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	changed := db.CommittedSeqChanged()
+	first, err := db.PutAsync(ctx, []byte("key1"), []byte("value1"))
+	require.NoError(t, err)
+	batch := NewWriteBatch()
+	batch.Put([]byte("key2"), []byte("value2"))
+	batch.Delete([]byte("key3"))
+	second, err := db.WriteAsync(ctx, batch)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), first.Seq())
+	assert.Equal(t, uint64(3), second.Seq())
+
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, db.AwaitCommittedSeq(waitCtx, second.Seq()), context.DeadlineExceeded)
+	select {
+	case <-changed:
+		t.Fatal("committed sequence number changed before the WAL was flushed")
+	default:
+	}
+
+	require.NoError(t, db.FlushWAL(ctx))
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("flushing the WAL did not notify the change")
+	}
+	require.NoError(t, db.AwaitCommittedSeq(ctx, second.Seq()))
+	assert.Equal(t, second.Seq(), db.CommittedSeq())
+
+	// the future of an empty batch is already durable
+	empty, err := db.WriteAsync(ctx, NewWriteBatch())
+	require.NoError(t, err)
+	assert.Equal(t, db.CommittedSeq(), empty.Seq())
+	require.NoError(t, db.AwaitCommittedSeq(ctx, empty.Seq()))
+}
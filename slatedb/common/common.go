@@ -1,12 +1,17 @@
 package common
 
-import "github.com/gammazero/deque"
+import (
+	"sync/atomic"
+
+	"github.com/gammazero/deque"
+)
 
 const (
 	// uint16 and uint32 sizes are constant as per https://go.dev/ref/spec#Size_and_alignment_guarantees
 
 	SizeOfUint16 = 2
 	SizeOfUint32 = 4
+	SizeOfUint64 = 8
 )
 
 type Range struct {
@@ -28,3 +33,13 @@ func CopyDeque[T Cloneable[T]](src *deque.Deque[T]) *deque.Deque[T] {
 	}
 	return dst
 }
+
+// StoreMax stores v in a if v is greater than the value of a
+func StoreMax(a *atomic.Uint64, v uint64) {
+	for {
+		current := a.Load()
+		if v <= current || a.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sections of an SSTable reported by CorruptionError
+const (
+	SectionFooter = "footer"
+	SectionInfo   = "info"
+	SectionIndex  = "index"
+	SectionFilter = "filter"
+	SectionDict   = "compression dictionary"
+	SectionBlock  = "block"
+
+	SectionRangeTombstones = "range tombstones"
+)
+
+// CorruptionError describes a decode or validation failure of a persisted object
+// along with the coordinates of the offending bytes, so the corrupt object can be
+// located without reproducing the failure. Use errors.As to retrieve it, while
+// errors.Is continues to match the underlying error such as ErrChecksumMismatch.
+type CorruptionError struct {
+	// The key of the object in object storage, empty if the object was not read
+	// from object storage.
+	Object string
+	// The section of the object which failed to decode, such as SectionBlock
+	Section string
+	// The byte offset of the section within the object
+	Offset uint64
+	// The index of the block which failed to decode, or -1 if the section is not a block
+	Block int
+	// The checksum recorded in the object and the checksum of the bytes read,
+	// both zero if the failure was not a checksum mismatch.
+	ExpectedChecksum uint32
+	ActualChecksum   uint32
+	// The underlying error
+	Err error
+}
+
+// NewCorruptionError returns a CorruptionError for the provided section
+func NewCorruptionError(section string, err error) *CorruptionError {
+	return &CorruptionError{Section: section, Block: -1, Err: err}
+}
+
+// NewChecksumError returns a CorruptionError wrapping ErrChecksumMismatch
+func NewChecksumError(section string, expected uint32, actual uint32) *CorruptionError {
+	return &CorruptionError{
+		Section:          section,
+		Block:            -1,
+		ExpectedChecksum: expected,
+		ActualChecksum:   actual,
+		Err:              ErrChecksumMismatch,
+	}
+}
+
+func (e *CorruptionError) Error() string {
+	var b strings.Builder
+	b.WriteString("corrupt ")
+	b.WriteString(e.Section)
+	if e.Block >= 0 {
+		fmt.Fprintf(&b, " '%d'", e.Block)
+	}
+	if e.Object != "" {
+		fmt.Fprintf(&b, " in object '%s'", e.Object)
+	}
+	fmt.Fprintf(&b, " at offset '%d'", e.Offset)
+	if e.ExpectedChecksum != e.ActualChecksum {
+		fmt.Fprintf(&b, " (expected checksum '%#08x' got '%#08x')", e.ExpectedChecksum, e.ActualChecksum)
+	}
+	fmt.Fprintf(&b, ": %s", e.Err)
+	return b.String()
+}
+
+func (e *CorruptionError) Unwrap() error {
+	return e.Err
+}
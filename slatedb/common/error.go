@@ -18,5 +18,24 @@ var (
 	ErrBlockCompression        = errors.New("error Compressing Block")
 	ErrReadBlocks              = errors.New("error Reading Blocks")
 	ErrObjectExists            = errors.New("error Object Exists")
+	ErrObjectNotFound          = errors.New("object not found")
 	ErrKeyNotFound             = errors.New("key not found")
+	ErrIncompleteSST           = errors.New("incomplete SSTable")
+	ErrInvalidSSTFooter        = errors.New("invalid SSTable footer")
+	ErrUnsupportedSSTVersion   = errors.New("unsupported SSTable format version")
+	ErrIncompatibleOptions     = errors.New("options are incompatible with the persisted format")
+	ErrManifestNotFound        = errors.New("manifest not found")
+	ErrInvalidResumeToken      = errors.New("invalid scan resume token")
+	ErrSnapshotExpired         = errors.New("snapshot of the scan resume token has expired")
+	ErrKeyOutsideSnapshot      = errors.New("key is outside the range of the snapshot")
+	ErrSnapshotClosed          = errors.New("snapshot is closed")
+	ErrInvalidOptions          = errors.New("invalid options")
+	ErrInvalidIngestSST        = errors.New("invalid SSTable for ingestion")
+	ErrInvalidExport           = errors.New("invalid export stream")
+	ErrManifestVerification    = errors.New("manifest read back does not match the manifest written")
+	ErrReadOnly                = errors.New("DB is read-only after an unrecoverable background error")
+	ErrConditionFailed         = errors.New("condition of the write is not met")
+	ErrAlreadyOpen             = errors.New("DB is already open by a live writer")
+	ErrIncompatibleLayout      = errors.New("path is claimed by an incompatible object store layout")
+	ErrThrottled               = errors.New("request throttled by the object store")
 )
@@ -32,14 +32,31 @@ func (s *SortedRun) indexOfSSTWithKey(key []byte) mo.Option[int] {
 	return mo.None[int]()
 }
 
+// SstWithKey returns the SSTable of the SortedRun which may contain the key. None
+// is returned if the key falls outside the key range recorded for the SSTable in
+// the manifest, so the index and filter of the SSTable don't need to be fetched.
 func (s *SortedRun) SstWithKey(key []byte) mo.Option[sstable.Handle] {
 	index, ok := s.indexOfSSTWithKey(key).Get()
-	if ok {
+	if ok && s.SSTList[index].RangeCoversKey(key) {
 		return mo.Some(s.SSTList[index])
 	}
 	return mo.None[sstable.Handle]()
 }
 
+// LiveDataFraction returns the fraction of the entries in the SortedRun which are
+// not tombstones. SSTables written before entries were counted are not included.
+func (s *SortedRun) LiveDataFraction() float64 {
+	var entries, tombstones uint64
+	for _, sst := range s.SSTList {
+		entries += sst.Info.EntryCount
+		tombstones += sst.Info.TombstoneCount
+	}
+	if entries == 0 {
+		return 1
+	}
+	return float64(entries-tombstones) / float64(entries)
+}
+
 func (s *SortedRun) Clone() *SortedRun {
 	sstList := make([]sstable.Handle, 0, len(s.SSTList))
 	for _, sst := range s.SSTList {
@@ -59,24 +76,54 @@ type SortedRunIterator struct {
 	currentKVIter mo.Option[*sstable.Iterator]
 	sstListIter   *SSTListIterator
 	tableStore    sstable.TableStore
+	opts          sstable.IteratorOptions
 	warn          types.ErrWarn
 }
 
-func NewSortedRunIterator(sr SortedRun, store sstable.TableStore) (*SortedRunIterator, error) {
-	return newSortedRunIter(sr.SSTList, store, mo.None[[]byte]())
+func NewSortedRunIterator(ctx context.Context, sr SortedRun, store sstable.TableStore) (*SortedRunIterator, error) {
+	return newSortedRunIter(ctx, sr.SSTList, store, mo.None[[]byte](), sstable.IteratorOptions{})
+}
+
+// NewSortedRunIteratorWithOptions returns an iterator over every SSTable in the
+// SortedRun, each of which is read according to the provided options.
+func NewSortedRunIteratorWithOptions(
+	ctx context.Context,
+	sr SortedRun,
+	store sstable.TableStore,
+	opts sstable.IteratorOptions,
+) (*SortedRunIterator, error) {
+	return newSortedRunIter(ctx, sr.SSTList, store, mo.None[[]byte](), opts)
 }
 
-func NewSortedRunIteratorFromKey(sr SortedRun, key []byte, store sstable.TableStore) (*SortedRunIterator, error) {
+func NewSortedRunIteratorFromKey(ctx context.Context, sr SortedRun, key []byte, store sstable.TableStore) (*SortedRunIterator, error) {
+	return NewSortedRunIteratorFromKeyWithOptions(ctx, sr, key, store, sstable.IteratorOptions{})
+}
+
+// NewSortedRunIteratorFromKeyWithOptions returns an iterator which starts at the key
+// of the SortedRun, each SSTable of which is read according to the provided options.
+func NewSortedRunIteratorFromKeyWithOptions(
+	ctx context.Context,
+	sr SortedRun,
+	key []byte,
+	store sstable.TableStore,
+	opts sstable.IteratorOptions,
+) (*SortedRunIterator, error) {
 	sstList := sr.SSTList
 	idx, ok := sr.indexOfSSTWithKey(key).Get()
 	if ok {
 		sstList = sr.SSTList[idx:]
 	}
 
-	return newSortedRunIter(sstList, store, mo.Some(key))
+	return newSortedRunIter(ctx, sstList, store, mo.Some(key), opts)
 }
 
-func newSortedRunIter(sstList []sstable.Handle, store sstable.TableStore, fromKey mo.Option[[]byte]) (*SortedRunIterator, error) {
+func newSortedRunIter(
+	ctx context.Context,
+	sstList []sstable.Handle,
+	store sstable.TableStore,
+	fromKey mo.Option[[]byte],
+	opts sstable.IteratorOptions,
+) (*SortedRunIterator, error) {
 
 	sstListIter := newSSTListIterator(sstList)
 	currentKVIter := mo.None[*sstable.Iterator]()
@@ -86,12 +133,15 @@ func newSortedRunIter(sstList []sstable.Handle, store sstable.TableStore, fromKe
 		var err error
 		if fromKey.IsPresent() {
 			key, _ := fromKey.Get()
-			iter, err = sstable.NewIteratorAtKey(&sst, key, store)
+			iter, err = sstable.NewIteratorWithOptions(ctx, &sst, store, opts)
 			if err != nil {
 				return nil, err
 			}
+			if err = iter.Seek(ctx, key); err != nil {
+				return nil, err
+			}
 		} else {
-			iter, err = sstable.NewIterator(&sst, store)
+			iter, err = sstable.NewIteratorWithOptions(ctx, &sst, store, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -104,6 +154,7 @@ func newSortedRunIter(sstList []sstable.Handle, store sstable.TableStore, fromKe
 		currentKVIter: currentKVIter,
 		sstListIter:   sstListIter,
 		tableStore:    store,
+		opts:          opts,
 	}, nil
 }
 
@@ -148,8 +199,10 @@ func (iter *SortedRunIterator) NextEntry(ctx context.Context) (types.RowEntry, b
 			return types.RowEntry{}, false
 		}
 
-		newKVIter, err := sstable.NewIterator(&sst, iter.tableStore)
+		kvIter.Close()
+		newKVIter, err := sstable.NewIteratorWithOptions(ctx, &sst, iter.tableStore, iter.opts)
 		if err != nil {
+			iter.currentKVIter = mo.None[*sstable.Iterator]()
 			iter.warn.Add("while creating SSTable iterator: %s", err.Error())
 			return types.RowEntry{}, false
 		}
@@ -163,6 +216,14 @@ func (iter *SortedRunIterator) Warnings() *types.ErrWarn {
 	return &iter.warn
 }
 
+// Close cancels the block prefetches of the current SSTable iterator
+func (iter *SortedRunIterator) Close() {
+	if kvIter, ok := iter.currentKVIter.Get(); ok {
+		kvIter.Close()
+	}
+	iter.currentKVIter = mo.None[*sstable.Iterator]()
+}
+
 // ------------------------------------------------
 // SSTListIterator
 // ------------------------------------------------
@@ -1,6 +1,7 @@
 package slatedb
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"log/slog"
@@ -17,6 +18,7 @@ import (
 	"github.com/slatedb/slatedb-go/slatedb/common"
 	compaction2 "github.com/slatedb/slatedb-go/slatedb/compaction"
 	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/state"
 	"github.com/slatedb/slatedb-go/slatedb/store"
 )
 
@@ -33,6 +35,8 @@ const (
 type CompactionResult struct {
 	SortedRun *compaction2.SortedRun
 	Error     error
+	// Destination is the ID of the sorted run written by the compaction
+	Destination uint32
 }
 
 // Compactor - The CompactionOrchestrator checks with the CompactionScheduler if Level0 needs to be compacted.
@@ -42,8 +46,14 @@ type Compactor struct {
 	orchestrator *CompactionOrchestrator
 }
 
-func newCompactor(manifestStore *store.ManifestStore, tableStore *store.TableStore, opts config.DBOptions) (*Compactor, error) {
-	orchestrator, err := spawnAndRunCompactionOrchestrator(manifestStore, tableStore, opts)
+func newCompactor(
+	manifestStore *store.ManifestStore,
+	tableStore *store.TableStore,
+	opts config.DBOptions,
+	background *backgroundErrors,
+	throttle *store.ThrottleController,
+) (*Compactor, error) {
+	orchestrator, err := spawnAndRunCompactionOrchestrator(manifestStore, tableStore, opts, background, throttle)
 	if err != nil {
 		return nil, err
 	}
@@ -65,13 +75,21 @@ func spawnAndRunCompactionOrchestrator(
 	manifestStore *store.ManifestStore,
 	tableStore *store.TableStore,
 	opts config.DBOptions,
+	background *backgroundErrors,
+	throttle *store.ThrottleController,
 ) (*CompactionOrchestrator, error) {
 	orchestrator, err := newCompactionOrchestrator(opts, manifestStore, tableStore)
 	if err != nil {
 		return nil, err
 	}
+	orchestrator.background = background
+	orchestrator.throttle = throttle
+	orchestrator.executor.throttle = throttle
 
-	orchestrator.spawnLoop(opts)
+	// Without background tasks compactions are run by DB.CompactOnce
+	if !opts.DisableBackgroundTasks {
+		orchestrator.spawnLoop(opts)
+	}
 	return orchestrator, nil
 }
 
@@ -87,6 +105,13 @@ type CompactionOrchestrator struct {
 	compactorMsgCh chan CompactorMainMsg
 	waitGroup      sync.WaitGroup
 	log            *slog.Logger
+
+	// background records the result of every compaction, may be nil
+	background *backgroundErrors
+
+	// throttle limits the compactions in flight to one while the object store
+	// throttles requests, may be nil
+	throttle *store.ThrottleController
 }
 
 func newCompactionOrchestrator(
@@ -113,8 +138,8 @@ func newCompactionOrchestrator(
 		return nil, err
 	}
 
-	scheduler := loadCompactionScheduler()
-	executor := newCompactorExecutor(opts.CompactorOptions, tableStore)
+	scheduler := loadCompactionScheduler(opts.CompactorOptions)
+	executor := newCompactorExecutor(opts.CompactorOptions, opts.MergeOperator, tableStore)
 
 	o := CompactionOrchestrator{
 		options:        opts.CompactorOptions,
@@ -136,8 +161,8 @@ func loadState(manifest *store.FenceableManifest) (*CompactorState, error) {
 	return newCompactorState(dbState.Clone(), nil), nil
 }
 
-func loadCompactionScheduler() CompactionScheduler {
-	return SizeTieredCompactionScheduler{}
+func loadCompactionScheduler(options *config.CompactorOptions) CompactionScheduler {
+	return SizeTieredCompactionScheduler{options: options}
 }
 
 func (o *CompactionOrchestrator) spawnLoop(opts config.DBOptions) {
@@ -156,7 +181,7 @@ func (o *CompactionOrchestrator) spawnLoop(opts config.DBOptions) {
 
 			select {
 			case <-ticker.C:
-				err := o.loadManifest()
+				err := o.loadManifest(context.Background())
 				assert.True(err == nil, "Failed to load manifest")
 			case <-o.compactorMsgCh:
 				// we receive Shutdown msg on compactorMsgCh. Stop the executor.
@@ -169,41 +194,73 @@ func (o *CompactionOrchestrator) spawnLoop(opts config.DBOptions) {
 	}()
 }
 
+// compactOnce schedules compactions of the latest manifest, and runs them along
+// with the compactions scheduled as they finish until none remain, see
+// DB.CompactOnce. A failed compaction is dropped, so it is scheduled again by
+// the next call. The compactions are canceled once ctx is done.
+func (o *CompactionOrchestrator) compactOnce(ctx context.Context) error {
+	if err := o.loadManifest(ctx); err != nil {
+		return err
+	}
+
+	var errs []error
+	for len(o.state.compactions) > 0 {
+		result := <-o.executor.resultCh
+		o.background.recordFor(ctx, taskCompaction, result.Error)
+		if result.Error != nil {
+			o.log.Error("Error executing compaction", "error", result.Error)
+			o.state.abortCompaction(result.Destination)
+			errs = append(errs, result.Error)
+		} else if result.SortedRun != nil {
+			if err := o.finishCompaction(ctx, result.SortedRun); err != nil {
+				return err
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func (o *CompactionOrchestrator) shutdown() {
 	o.compactorMsgCh <- CompactorShutdown
 	o.waitGroup.Wait()
 }
 
-func (o *CompactionOrchestrator) loadManifest() error {
+func (o *CompactionOrchestrator) loadManifest(ctx context.Context) error {
 	_, err := o.manifest.Refresh()
 	if err != nil {
 		return err
 	}
-	err = o.refreshDBState()
+	err = o.refreshDBState(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (o *CompactionOrchestrator) refreshDBState() error {
+func (o *CompactionOrchestrator) refreshDBState(ctx context.Context) error {
 	state, err := o.manifest.DbState()
 	if err != nil {
 		return err
 	}
 
 	o.state.refreshDBState(state)
-	err = o.maybeScheduleCompactions()
+	err = o.maybeScheduleCompactions(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (o *CompactionOrchestrator) maybeScheduleCompactions() error {
+func (o *CompactionOrchestrator) maybeScheduleCompactions(ctx context.Context) error {
 	compactions := o.scheduler.maybeScheduleCompaction(o.state)
 	for _, compaction := range compactions {
-		err := o.submitCompaction(compaction)
+		// The compaction is scheduled again once the compaction in flight finishes
+		if o.throttle.Level() < 1 && len(o.state.compactions) > 0 {
+			o.log.Debug("deferred compaction while object store is throttling requests",
+				"compaction", compaction)
+			break
+		}
+		err := o.submitCompaction(ctx, compaction)
 		if err != nil {
 			return err
 		}
@@ -211,7 +268,7 @@ func (o *CompactionOrchestrator) maybeScheduleCompactions() error {
 	return nil
 }
 
-func (o *CompactionOrchestrator) startCompaction(compaction Compaction) {
+func (o *CompactionOrchestrator) startCompaction(ctx context.Context, compaction Compaction) {
 	o.logCompactionState()
 	dbState := o.state.dbState
 
@@ -243,51 +300,64 @@ func (o *CompactionOrchestrator) startCompaction(compaction Compaction) {
 	}
 
 	sortedRuns := make([]compaction2.SortedRun, 0)
+	sourceSRs := make(map[uint32]bool)
 	for _, sID := range compaction.sources {
 		srID, ok := sID.sortedRunID().Get()
 		if ok {
 			sortedRuns = append(sortedRuns, srsByID[srID])
+			sourceSRs[srID] = true
+		}
+	}
+
+	// Tombstones can only be dropped if no older sorted run remains which may
+	// contain a value the tombstone deletes.
+	dropTombstones := true
+	for _, sr := range dbState.Compacted {
+		if sr.ID <= compaction.destination && !sourceSRs[sr.ID] {
+			dropTombstones = false
 		}
 	}
 
-	o.executor.startCompaction(CompactionJob{
-		destination: compaction.destination,
-		sstList:     ssts,
-		sortedRuns:  sortedRuns,
+	o.executor.startCompaction(ctx, CompactionJob{
+		destination:    compaction.destination,
+		sstList:        ssts,
+		sortedRuns:     sortedRuns,
+		dropTombstones: dropTombstones,
 	})
 }
 
 func (o *CompactionOrchestrator) processCompactionResult(log *slog.Logger) bool {
 	result, resultPresent := o.executor.nextCompactionResult()
 	if resultPresent {
+		o.background.record(taskCompaction, result.Error)
 		if result.Error != nil {
 			log.Error("Error executing compaction", "error", result.Error)
 		} else if result.SortedRun != nil {
-			err := o.finishCompaction(result.SortedRun)
+			err := o.finishCompaction(context.Background(), result.SortedRun)
 			assert.True(err == nil, "Failed to finish compaction")
 		}
 	}
 	return resultPresent
 }
 
-func (o *CompactionOrchestrator) finishCompaction(outputSR *compaction2.SortedRun) error {
+func (o *CompactionOrchestrator) finishCompaction(ctx context.Context, outputSR *compaction2.SortedRun) error {
 	o.state.finishCompaction(outputSR)
 	o.logCompactionState()
-	err := o.writeManifest()
+	err := o.writeManifest(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = o.maybeScheduleCompactions()
+	err = o.maybeScheduleCompactions(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (o *CompactionOrchestrator) writeManifest() error {
+func (o *CompactionOrchestrator) writeManifest(ctx context.Context) error {
 	for {
-		err := o.loadManifest()
+		err := o.loadManifest(ctx)
 		if err != nil {
 			return err
 		}
@@ -302,13 +372,13 @@ func (o *CompactionOrchestrator) writeManifest() error {
 	}
 }
 
-func (o *CompactionOrchestrator) submitCompaction(compaction Compaction) error {
+func (o *CompactionOrchestrator) submitCompaction(ctx context.Context, compaction Compaction) error {
 	err := o.state.submitCompaction(compaction)
 	if err != nil {
 		o.log.Warn("invalid compaction", "error", err)
 		return nil
 	}
-	o.startCompaction(compaction)
+	o.startCompaction(ctx, compaction)
 	return nil
 }
 
@@ -327,12 +397,23 @@ type CompactionJob struct {
 	destination uint32
 	sstList     []sstable.Handle
 	sortedRuns  []compaction2.SortedRun
+
+	// dropTombstones is true if the output is the oldest sorted run, such that
+	// tombstones no longer shadow any values and can be removed
+	dropTombstones bool
 }
 
 type CompactionExecutor struct {
 	options    *config.CompactorOptions
 	tableStore *store.TableStore
 
+	// mergeOperator applies merge operands to the older entries of their key
+	mergeOperator types.MergeOperator
+
+	// throttle scales down the blocks prefetched while the object store throttles
+	// requests, may be nil
+	throttle *store.ThrottleController
+
 	resultCh chan CompactionResult
 	tasksWG  sync.WaitGroup
 	stopped  atomic.Bool
@@ -340,12 +421,14 @@ type CompactionExecutor struct {
 
 func newCompactorExecutor(
 	options *config.CompactorOptions,
+	mergeOperator types.MergeOperator,
 	tableStore *store.TableStore,
 ) *CompactionExecutor {
 	return &CompactionExecutor{
-		options:    options,
-		tableStore: tableStore,
-		resultCh:   make(chan CompactionResult, 1),
+		options:       options,
+		tableStore:    tableStore,
+		mergeOperator: mergeOperator,
+		resultCh:      make(chan CompactionResult, 1),
 	}
 }
 
@@ -360,56 +443,102 @@ func (e *CompactionExecutor) nextCompactionResult() (CompactionResult, bool) {
 
 // create an iterator for CompactionJob.sstList and another iterator for CompactionJob.sortedRuns
 // Return the merged iterator for the above 2 iterators
-func (e *CompactionExecutor) loadIterators(compaction CompactionJob) (iter.KVIterator, error) {
+// rangeTombstones returns the range tombstones of the SSTables and sorted runs of the compaction
+func (e *CompactionExecutor) rangeTombstones(ctx context.Context, compaction CompactionJob) (types.RangeTombstones, error) {
+	core := &state.CoreStateSnapshot{L0: compaction.sstList, Compacted: compaction.sortedRuns}
+	return rangeTombstones(ctx, core, e.tableStore)
+}
+
+func (e *CompactionExecutor) loadIterators(
+	ctx context.Context,
+	compaction CompactionJob,
+	tombstones types.RangeTombstones,
+) (*iter.MergeSort, error) {
 	assert.True(
 		!(len(compaction.sstList) == 0 && len(compaction.sortedRuns) == 0),
 		"Compaction sources cannot be empty",
 	)
 
+	opts := sstable.IteratorOptions{PrefetchBlocks: e.throttle.Scale(e.options.PrefetchBlocks)}
 	l0Iters := make([]iter.KVIterator, 0)
+	srIters := make([]iter.KVIterator, 0)
+	// closeAll cancels the prefetches of the iterators created before an error
+	closeAll := func() {
+		for _, it := range append(l0Iters, srIters...) {
+			iter.Close(it)
+		}
+	}
 	for _, sst := range compaction.sstList {
-		sstIter, err := sstable.NewIterator(&sst, e.tableStore.Clone())
+		sstIter, err := sstable.NewIteratorWithOptions(ctx, &sst, e.tableStore.Clone(), opts)
 		if err != nil {
+			closeAll()
 			return nil, err
 		}
 		l0Iters = append(l0Iters, sstIter)
 	}
 
-	srIters := make([]iter.KVIterator, 0)
 	for _, sr := range compaction.sortedRuns {
-		srIter, err := compaction2.NewSortedRunIterator(sr, e.tableStore.Clone())
+		srIter, err := compaction2.NewSortedRunIteratorWithOptions(ctx, sr, e.tableStore.Clone(), opts)
 		if err != nil {
+			closeAll()
 			return nil, err
 		}
 		srIters = append(srIters, srIter)
 	}
 
-	ctx := context.TODO()
-	var l0MergeIter, srMergeIter iter.KVIterator
+	// The range tombstones are applied by each MergeSort, so a merge operand is
+	// never applied to an entry deleted by a range tombstone
+	newMergeSort := func(iters ...iter.KVIterator) *iter.MergeSort {
+		return iter.NewMergeSort(ctx, iters...).
+			WithMergeOperator(e.mergeOperator).
+			WithRangeTombstones(tombstones)
+	}
 	if len(compaction.sortedRuns) == 0 {
-		l0MergeIter = iter.NewMergeSort(ctx, l0Iters...)
-		return l0MergeIter, nil
+		return newMergeSort(l0Iters...), nil
 	} else if len(compaction.sstList) == 0 {
-		srMergeIter = iter.NewMergeSort(ctx, srIters...)
-		return srMergeIter, nil
+		return newMergeSort(srIters...), nil
 	}
-
-	it := iter.NewMergeSort(ctx, l0MergeIter, srMergeIter)
-	return it, nil
+	return newMergeSort(newMergeSort(l0Iters...), newMergeSort(srIters...)), nil
 }
 
-func (e *CompactionExecutor) executeCompaction(compaction CompactionJob) (*compaction2.SortedRun, error) {
-	allIter, err := e.loadIterators(compaction)
+func (e *CompactionExecutor) executeCompaction(ctx context.Context, compaction CompactionJob) (*compaction2.SortedRun, error) {
+	tombstones, err := e.rangeTombstones(ctx, compaction)
+	if err != nil {
+		return nil, err
+	}
+	allIter, err := e.loadIterators(ctx, compaction, tombstones)
 	if err != nil {
 		return nil, err
 	}
+	defer allIter.Close()
 	var warn types.ErrWarn
 
+	// The range tombstones still delete entries of older sorted runs, so every
+	// SSTable of the output carries them unless the output is the oldest sorted run
+	if compaction.dropTombstones {
+		tombstones = nil
+	}
+
 	outputSSTs := make([]sstable.Handle, 0)
-	currentWriter := e.tableStore.TableWriter(sstable.NewIDCompacted(ulid.Make()))
+	currentWriter := e.newTableWriter(ctx, tombstones)
 	currentSize := 0
+	// finishSST closes the current SSTable and starts the next one
+	finishSST := func() error {
+		currentSize = 0
+		finishedWriter := currentWriter
+		currentWriter = e.newTableWriter(ctx, tombstones)
+		sst, err := finishedWriter.Close()
+		if err != nil {
+			currentWriter.Abort()
+			return err
+		}
+		outputSSTs = append(outputSSTs, *sst)
+		return nil
+	}
+	// partition is the partition key of the last entry, see CompactorOptions.PartitionKey
+	var partition []byte
 	for {
-		kv, ok := allIter.NextEntry(context.TODO())
+		kv, ok := allIter.NextEntry(ctx)
 		if !ok {
 			if w := allIter.Warnings(); w != nil {
 				warn.Merge(w)
@@ -417,35 +546,51 @@ func (e *CompactionExecutor) executeCompaction(compaction CompactionJob) (*compa
 			break
 		}
 
-		value := kv.Value.GetValue()
-		err = currentWriter.Add(kv.Key, value)
+		if compaction.dropTombstones {
+			// No older entries remain which the merge operand could be applied to
+			if e.mergeOperator != nil {
+				kv.Value = types.ResolveMerge(e.mergeOperator, kv.Key, kv.Value)
+			}
+			if kv.Value.IsTombstone() {
+				continue
+			}
+		}
+
+		// Start a new SSTable where the partition changes, so the entries of a
+		// partition are not spread over the SSTables of other partitions
+		if e.options.PartitionKey != nil {
+			next := e.options.PartitionKey(kv.Key)
+			if currentSize > 0 && uint64(currentSize) >= e.options.PartitionMinSSTSize &&
+				!bytes.Equal(next, partition) {
+				if err := finishSST(); err != nil {
+					return nil, err
+				}
+			}
+			partition = bytes.Clone(next)
+		}
+
+		err = currentWriter.AddEntry(kv)
 		if err != nil {
+			currentWriter.Abort()
 			return nil, err
 		}
 
-		currentSize += len(kv.Key)
-		if value.IsPresent() {
-			val, _ := value.Get()
-			currentSize += len(val)
-		}
+		currentSize += len(kv.Key) + len(kv.Value.Value)
 
 		if uint64(currentSize) > e.options.MaxSSTSize {
-			currentSize = 0
-			finishedWriter := currentWriter
-			currentWriter = e.tableStore.TableWriter(sstable.NewIDCompacted(ulid.Make()))
-			sst, err := finishedWriter.Close()
-			if err != nil {
+			if err := finishSST(); err != nil {
 				return nil, err
 			}
-			outputSSTs = append(outputSSTs, *sst)
 		}
 	}
-	if currentSize > 0 {
+	if currentSize > 0 || (len(outputSSTs) == 0 && len(tombstones) > 0) {
 		sst, err := currentWriter.Close()
 		if err != nil {
 			return nil, err
 		}
 		outputSSTs = append(outputSSTs, *sst)
+	} else {
+		currentWriter.Abort()
 	}
 	return &compaction2.SortedRun{
 		ID:      compaction.destination,
@@ -453,7 +598,23 @@ func (e *CompactionExecutor) executeCompaction(compaction CompactionJob) (*compa
 	}, warn.If()
 }
 
-func (e *CompactionExecutor) startCompaction(compaction CompactionJob) {
+// newTableWriter returns a writer for a new SSTable of the output sorted run,
+// which holds the range tombstones
+func (e *CompactionExecutor) newTableWriter(ctx context.Context, tombstones types.RangeTombstones) *store.EncodedSSTableWriter {
+	opts := store.TableWriterOptions{
+		PartSize: e.options.UploadPartSize,
+		SSTable:  e.options.SSTableOptions,
+	}
+	writer := e.tableStore.TableWriterWithOptions(ctx, sstable.NewIDCompacted(ulid.Make()), opts)
+	for _, tombstone := range tombstones {
+		writer.AddRangeTombstone(tombstone)
+	}
+	return writer
+}
+
+// startCompaction runs the compaction in the background, whose requests to the
+// object store are canceled once ctx is done
+func (e *CompactionExecutor) startCompaction(ctx context.Context, compaction CompactionJob) {
 	if e.isStopped() {
 		return
 	}
@@ -466,13 +627,13 @@ func (e *CompactionExecutor) startCompaction(compaction CompactionJob) {
 			return
 		}
 
-		var result CompactionResult
-		sortedRun, err := e.executeCompaction(compaction)
+		result := CompactionResult{Destination: compaction.destination}
+		sortedRun, err := e.executeCompaction(ctx, compaction)
 		if err != nil {
 			// TODO(thrawn01): log the error somewhere.
-			result = CompactionResult{Error: err}
+			result.Error = err
 		} else if sortedRun != nil {
-			result = CompactionResult{SortedRun: sortedRun}
+			result.SortedRun = sortedRun
 		}
 		e.resultCh <- result
 	}()
@@ -41,6 +41,13 @@ func newSourceIDSST(id ulid.ULID) SourceID {
 	}
 }
 
+func newSourceIDSortedRun(id uint32) SourceID {
+	return SourceID{
+		typ:   SortedRunID,
+		value: strconv.Itoa(int(id)),
+	}
+}
+
 func (s SourceID) sortedRunID() mo.Option[uint32] {
 	if s.typ != SortedRunID {
 		return mo.None[uint32]()
@@ -130,6 +137,22 @@ func (c *CompactorState) submitCompaction(compaction Compaction) error {
 	return nil
 }
 
+// isCompacting returns true if the sorted run is the source or destination of
+// an in-flight compaction
+func (c *CompactorState) isCompacting(sortedRunID uint32) bool {
+	for _, compaction := range c.compactions {
+		if compaction.destination == sortedRunID {
+			return true
+		}
+		for _, src := range compaction.sources {
+			if id, ok := src.sortedRunID().Get(); ok && id == sortedRunID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (c *CompactorState) oneOfTheSourceSRMatchesDestination(compaction Compaction) bool {
 	for _, src := range compaction.sources {
 		if src.typ == SortedRunID {
@@ -161,6 +184,7 @@ func (c *CompactorState) refreshDBState(writerState *state.CoreStateSnapshot) {
 	merged := c.dbState.Clone()
 	merged.L0 = mergedL0s
 	merged.LastCompactedWalSSTID.Store(writerState.LastCompactedWalSSTID.Load())
+	merged.LastL0Seq.Store(writerState.LastL0Seq.Load())
 	merged.NextWalSstID.Store(writerState.NextWalSstID.Load())
 	c.dbState = merged
 }
@@ -229,6 +253,15 @@ func (c *CompactorState) finishCompaction(outputSR *compaction2.SortedRun) {
 	delete(c.compactions, outputSR.ID)
 }
 
+// abortCompaction drops the in-flight compaction writing the destination sorted
+// run, leaving its sources to be compacted again
+func (c *CompactorState) abortCompaction(destination uint32) {
+	if compaction, ok := c.compactions[destination]; ok {
+		c.log.Warn("aborted compaction", "compaction", compaction)
+		delete(c.compactions, destination)
+	}
+}
+
 // sortedRun list should have IDs in decreasing order
 func (c *CompactorState) assertCompactedSRsInIDOrder(compacted []compaction2.SortedRun) {
 	lastSortedRunID := uint32(math.MaxUint32)
@@ -70,14 +70,15 @@ func TestShouldRemoveCompactionWhenCompactionFinished(t *testing.T) {
 }
 
 func TestShouldRefreshDBStateCorrectlyWhenNeverCompacted(t *testing.T) {
+	ctx := context.Background()
 	bucket, sm, compactorState := buildTestState(t)
 	option := config.DefaultDBOptions()
 	option.L0SSTSizeBytes = 128
-	db, err := OpenWithOptions(context.Background(), testPath, bucket, option)
+	db, err := Open(context.Background(), testPath, bucket, option)
 	assert.NoError(t, err)
 	defer db.Close()
-	db.Put(repeatedChar('a', 16), repeatedChar('b', 48))
-	db.Put(repeatedChar('j', 16), repeatedChar('k', 48))
+	require.NoError(t, db.Put(ctx, repeatedChar('a', 16), repeatedChar('b', 48)))
+	require.NoError(t, db.Put(ctx, repeatedChar('j', 16), repeatedChar('k', 48)))
 
 	writerDBState := waitForManifestWithL0Len(sm, len(compactorState.dbState.L0)+1)
 
@@ -90,6 +91,7 @@ func TestShouldRefreshDBStateCorrectlyWhenNeverCompacted(t *testing.T) {
 }
 
 func TestShouldRefreshDBStateCorrectly(t *testing.T) {
+	ctx := context.Background()
 	bucket, sm, compactorState := buildTestState(t)
 	originalL0s := compactorState.dbState.Clone().L0
 	compactedID, ok := originalL0s[len(originalL0s)-1].Id.CompactedID().Get()
@@ -104,11 +106,11 @@ func TestShouldRefreshDBStateCorrectly(t *testing.T) {
 
 	option := config.DefaultDBOptions()
 	option.L0SSTSizeBytes = 128
-	db, err := OpenWithOptions(context.Background(), testPath, bucket, option)
+	db, err := Open(context.Background(), testPath, bucket, option)
 	assert.NoError(t, err)
 	defer db.Close()
-	db.Put(repeatedChar('a', 16), repeatedChar('b', 48))
-	db.Put(repeatedChar('j', 16), repeatedChar('k', 48))
+	require.NoError(t, db.Put(ctx, repeatedChar('a', 16), repeatedChar('b', 48)))
+	require.NoError(t, db.Put(ctx, repeatedChar('j', 16), repeatedChar('k', 48)))
 	writerDBState := waitForManifestWithL0Len(sm, len(originalL0s)+1)
 	dbStateBeforeMerge := compactorState.dbState.Clone()
 
@@ -117,7 +119,7 @@ func TestShouldRefreshDBStateCorrectly(t *testing.T) {
 	dbState := compactorState.dbState
 	// last sst was removed during compaction
 	expectedMergedL0s := originalL0s[:len(originalL0s)-1]
-	// new sst got added during db.Put() call above
+	// new sst got added during db.Put(ctx, ) call above
 	expectedMergedL0s = append([]sstable.Handle{writerDBState.L0[0]}, expectedMergedL0s...)
 	for i := 0; i < len(expectedMergedL0s); i++ {
 		expected, _ := expectedMergedL0s[i].Id.CompactedID().Get()
@@ -137,6 +139,7 @@ func TestShouldRefreshDBStateCorrectly(t *testing.T) {
 }
 
 func TestShouldRefreshDBStateCorrectlyWhenAllL0Compacted(t *testing.T) {
+	ctx := context.Background()
 	bucket, sm, compactorState := buildTestState(t)
 	originalL0s := compactorState.dbState.Clone().L0
 
@@ -157,11 +160,11 @@ func TestShouldRefreshDBStateCorrectlyWhenAllL0Compacted(t *testing.T) {
 
 	option := config.DefaultDBOptions()
 	option.L0SSTSizeBytes = 128
-	db, err := OpenWithOptions(context.Background(), testPath, bucket, option)
+	db, err := Open(context.Background(), testPath, bucket, option)
 	assert.NoError(t, err)
 	defer db.Close()
-	db.Put(repeatedChar('a', 16), repeatedChar('b', 48))
-	db.Put(repeatedChar('j', 16), repeatedChar('k', 48))
+	require.NoError(t, db.Put(ctx, repeatedChar('a', 16), repeatedChar('b', 48)))
+	require.NoError(t, db.Put(ctx, repeatedChar('j', 16), repeatedChar('k', 48)))
 	writerDBState := waitForManifestWithL0Len(sm, len(originalL0s)+1)
 
 	compactorState.refreshDBState(writerDBState)
@@ -198,16 +201,17 @@ func buildL0Compaction(sstList []sstable.Handle, destination uint32) Compaction
 
 func buildTestState(t *testing.T) (objstore.Bucket, store.StoredManifest, *CompactorState) {
 	t.Helper()
+	ctx := context.Background()
 
 	bucket := objstore.NewInMemBucket()
 	option := config.DefaultDBOptions()
 	option.L0SSTSizeBytes = 128
-	db, err := OpenWithOptions(context.Background(), testPath, bucket, option)
+	db, err := Open(context.Background(), testPath, bucket, option)
 	assert2.True(err == nil, "Could not open db")
 	l0Count := 5
 	for i := 0; i < l0Count; i++ {
-		db.Put(repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48))
-		db.Put(repeatedChar(rune('j'+i), 16), repeatedChar(rune('k'+i), 48))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48)))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('j'+i), 16), repeatedChar(rune('k'+i), 48)))
 	}
 	db.Close()
 
@@ -1,7 +1,9 @@
 package slatedb
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"log/slog"
 	"slices"
 	"testing"
@@ -11,6 +13,8 @@ import (
 	"github.com/slatedb/slatedb-go/internal/compress"
 	"github.com/slatedb/slatedb-go/internal/sstable"
 	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	compaction2 "github.com/slatedb/slatedb-go/slatedb/compaction"
 	"github.com/slatedb/slatedb-go/slatedb/config"
 	"github.com/slatedb/slatedb-go/slatedb/state"
 	"github.com/slatedb/slatedb-go/slatedb/store"
@@ -18,16 +22,18 @@ import (
 	"github.com/oklog/ulid/v2"
 	"github.com/samber/mo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/thanos-io/objstore"
 )
 
 func TestCompactorCompactsL0(t *testing.T) {
+	ctx := context.Background()
 	options := dbOptions(compactorOptions().CompactorOptions)
 	_, manifestStore, tableStore, db := buildTestDB(options)
 	defer db.Close()
 	for i := 0; i < 4; i++ {
-		db.Put(repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48))
-		db.Put(repeatedChar(rune('j'+i), 16), repeatedChar(rune('k'+i), 48))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48)))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('j'+i), 16), repeatedChar(rune('k'+i), 48)))
 	}
 
 	startTime := time.Now()
@@ -52,7 +58,7 @@ func TestCompactorCompactsL0(t *testing.T) {
 	assert.Equal(t, 1, len(compactedSSTList))
 
 	sst := compactedSSTList[0]
-	iter, err := sstable.NewIterator(&sst, tableStore)
+	iter, err := sstable.NewIterator(context.Background(), &sst, tableStore)
 	assert.NoError(t, err)
 	for i := 0; i < 4; i++ {
 		kv, ok := iter.Next(context.Background())
@@ -72,14 +78,245 @@ func TestCompactorCompactsL0(t *testing.T) {
 	assert.Equal(t, types.KeyValue{}, next)
 }
 
+func TestCompactorFoldsInlineL0(t *testing.T) {
+	ctx := context.Background()
+	options := dbOptions(compactorOptions().CompactorOptions)
+	options.InlineSSTMaxBytes = 64 * 1024
+	bucket, manifestStore, tableStore, db := buildTestDB(options)
+	defer db.Close()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48)))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('j'+i), 16), repeatedChar(rune('k'+i), 48)))
+	}
+
+	var dbState *state.CoreStateSnapshot
+	require.Eventually(t, func() bool {
+		sm, err := store.LoadStoredManifest(manifestStore)
+		require.NoError(t, err)
+		storedManifest, _ := sm.Get()
+		dbState = storedManifest.DbState().Clone()
+		return dbState.L0LastCompacted.IsPresent()
+	}, 10*time.Second, 50*time.Millisecond)
+
+	require.Equal(t, 1, len(dbState.Compacted))
+	require.Equal(t, 1, len(dbState.Compacted[0].SSTList))
+	sst := dbState.Compacted[0].SSTList[0]
+	assert.False(t, sst.IsInline())
+
+	// Only the SSTable written by the compactor is stored as an object
+	var objects []string
+	err := bucket.Iter(context.Background(), testPath+"/compacted", func(name string) error {
+		objects = append(objects, name)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{testPath + "/compacted/" + sst.Id.Value + ".sst"}, objects)
+
+	iter, err := sstable.NewIterator(context.Background(), &sst, tableStore)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		kv, ok := iter.Next(context.Background())
+		assert.True(t, ok)
+		assert.Equal(t, repeatedChar(rune('a'+i), 16), kv.Key)
+	}
+}
+
+func TestCompactorWritesSSTablesWithSSTableOptions(t *testing.T) {
+	ctx := context.Background()
+	compactorOpts := compactorOptions().CompactorOptions
+	compactorOpts.SSTableOptions = config.SSTableOptions{
+		BlockSize:        64,
+		CompressionCodec: compress.CodecSnappy,
+	}
+	options := dbOptions(compactorOpts)
+	options.BlockSize = 32
+	_, manifestStore, tableStore, db := buildTestDB(options)
+	defer db.Close()
+
+	require.NoError(t, db.Put(ctx, repeatedChar('a', 16), repeatedChar('b', 48)))
+	require.NoError(t, db.FlushMemtableToL0(context.Background()))
+	l0 := db.state.CoreStateSnapshot().L0
+	require.Len(t, l0, 1)
+	assert.Equal(t, uint64(32), l0[0].Info.BlockSize)
+	assert.Equal(t, compress.CodecNone, l0[0].Info.CompressionCodec)
+
+	for i := 1; i < 4; i++ {
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48)))
+		require.NoError(t, db.FlushMemtableToL0(context.Background()))
+	}
+	dbState := waitForCompactedState(t, manifestStore, func(s *state.CoreStateSnapshot) bool {
+		return len(s.Compacted) > 0
+	})
+
+	sst := dbState.Compacted[0].SSTList[0]
+	assert.Equal(t, uint64(64), sst.Info.BlockSize)
+	assert.Equal(t, compress.CodecSnappy, sst.Info.CompressionCodec)
+
+	iter, err := sstable.NewIterator(context.Background(), &sst, tableStore)
+	require.NoError(t, err)
+	kv, ok := iter.Next(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, repeatedChar('a', 16), kv.Key)
+	assert.Equal(t, repeatedChar('b', 48), kv.Value)
+}
+
+func TestCompactorSplitsSSTablesAtPartitionBoundaries(t *testing.T) {
+	ctx := context.Background()
+	compactorOpts := compactorOptions().CompactorOptions
+	// The partition of a key is the tenant before the first '/'
+	compactorOpts.PartitionKey = func(key []byte) []byte {
+		tenant, _, _ := bytes.Cut(key, []byte("/"))
+		return tenant
+	}
+	compactorOpts.PartitionMinSSTSize = 64
+	options := dbOptions(compactorOpts)
+	options.DisableBackgroundTasks = true
+	options.L0SSTSizeBytes = 1024 * 1024
+	_, manifestStore, tableStore, db := buildTestDB(options)
+	defer db.Close()
+
+	// Tenants 'a' and 'c' hold more than PartitionMinSSTSize bytes, tenants 'b1'
+	// and 'b2' less, so they share an SSTable
+	for _, tenant := range []struct {
+		name string
+		keys int
+	}{{"a", 4}, {"b1", 2}, {"b2", 1}, {"c", 4}} {
+		for i := 0; i < tenant.keys; i++ {
+			require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("%s/key%02d", tenant.name, i)), repeatedChar('v', 16)))
+		}
+		require.NoError(t, db.FlushMemtableToL0(ctx))
+	}
+	require.NoError(t, db.CompactOnce(ctx))
+
+	sm, err := store.LoadStoredManifest(manifestStore)
+	require.NoError(t, err)
+	storedManifest, ok := sm.Get()
+	require.True(t, ok)
+	dbState := storedManifest.DbState()
+	require.Len(t, dbState.Compacted, 1)
+	var partitions [][]string
+	for _, sst := range dbState.Compacted[0].SSTList {
+		iter, err := sstable.NewIterator(ctx, &sst, tableStore)
+		require.NoError(t, err)
+		var ssTenants []string
+		for {
+			kv, ok := iter.Next(ctx)
+			if !ok {
+				break
+			}
+			tenant := string(compactorOpts.PartitionKey(kv.Key))
+			if !slices.Contains(ssTenants, tenant) {
+				ssTenants = append(ssTenants, tenant)
+			}
+		}
+		partitions = append(partitions, ssTenants)
+	}
+	assert.Equal(t, [][]string{{"a"}, {"b1", "b2"}, {"c"}}, partitions)
+}
+
+func TestCompactorDropsTombstonesOfSortedRunWithLowLiveDataFraction(t *testing.T) {
+	ctx := context.Background()
+	compactorOpts := compactorOptions().CompactorOptions
+	compactorOpts.MinLiveDataFraction = 0.5
+	options := dbOptions(compactorOpts)
+	_, manifestStore, _, db := buildTestDB(options)
+	defer db.Close()
+
+	// Each write is flushed to its own L0 SSTable, such that 4 writes are
+	// compacted into a sorted run
+	flushToL0 := func() {
+		require.NoError(t, db.FlushWAL(context.Background()))
+		require.NoError(t, db.FlushMemtableToL0(context.Background()))
+	}
+	for i := 0; i < 4; i++ {
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48)))
+		flushToL0()
+	}
+	waitForCompactedState(t, manifestStore, func(dbState *state.CoreStateSnapshot) bool {
+		return len(dbState.L0) == 0 && len(dbState.Compacted) == 1
+	})
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, db.Delete(ctx, repeatedChar(rune('a'+i), 16)))
+		flushToL0()
+	}
+
+	// The sorted run of tombstones is compacted with the sorted run of values
+	// which leaves no entries behind
+	dbState := waitForCompactedState(t, manifestStore, func(dbState *state.CoreStateSnapshot) bool {
+		return len(dbState.L0) == 0 && len(dbState.Compacted) == 1 &&
+			len(dbState.Compacted[0].SSTList) == 0
+	})
+	assert.Equal(t, uint32(1), dbState.Compacted[0].ID)
+
+	for i := 0; i < 4; i++ {
+		_, err := db.Get(context.Background(), repeatedChar(rune('a'+i), 16))
+		assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	}
+}
+
+func TestShouldScheduleCompactionOfSortedRunWithLowLiveDataFraction(t *testing.T) {
+	sortedRun := func(id uint32, entries uint64, tombstones uint64) compaction2.SortedRun {
+		info := &sstable.Info{EntryCount: entries, TombstoneCount: tombstones}
+		return compaction2.SortedRun{
+			ID:      id,
+			SSTList: []sstable.Handle{*sstable.NewHandle(sstable.NewIDCompacted(ulid.Make()), info)},
+		}
+	}
+	compactorState := newCompactorState(&state.CoreStateSnapshot{
+		Compacted: []compaction2.SortedRun{
+			sortedRun(3, 100, 10),
+			sortedRun(2, 100, 80),
+			sortedRun(1, 100, 0),
+			sortedRun(0, 100, 0),
+		},
+	}, nil)
+
+	options := config.DefaultCompactorOptions()
+	scheduler := SizeTieredCompactionScheduler{options: options}
+	compactions := scheduler.maybeScheduleCompaction(compactorState)
+	assert.Equal(t, []Compaction{newCompaction([]SourceID{
+		newSourceIDSortedRun(2),
+		newSourceIDSortedRun(1),
+		newSourceIDSortedRun(0),
+	}, 2)}, compactions)
+
+	// Sorted runs which are already being compacted are left alone
+	assert.NoError(t, compactorState.submitCompaction(compactions[0]))
+	assert.Empty(t, scheduler.maybeScheduleCompaction(compactorState))
+
+	// Zero disables delete triggered compactions
+	options.MinLiveDataFraction = 0
+	assert.Empty(t, scheduler.maybeScheduleCompaction(newCompactorState(compactorState.dbState, nil)))
+}
+
+func waitForCompactedState(t *testing.T, manifestStore *store.ManifestStore,
+	cond func(*state.CoreStateSnapshot) bool) *state.CoreStateSnapshot {
+	t.Helper()
+	startTime := time.Now()
+	for time.Since(startTime) < time.Second*10 {
+		sm, err := store.LoadStoredManifest(manifestStore)
+		require.NoError(t, err)
+		storedManifest, ok := sm.Get()
+		require.True(t, ok)
+		if cond(storedManifest.DbState()) {
+			return storedManifest.DbState().Clone()
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	t.Fatal("timed out waiting for compacted state")
+	return nil
+}
+
 func TestShouldWriteManifestSafely(t *testing.T) {
+	ctx := context.Background()
 	options := dbOptions(nil)
 	bucket, manifestStore, tableStore, db := buildTestDB(options)
 	sm, err := store.LoadStoredManifest(manifestStore)
 	assert.NoError(t, err)
 	assert.True(t, sm.IsPresent())
 	storedManifest, _ := sm.Get()
-	db.Put(repeatedChar('a', 32), repeatedChar('b', 96))
+	require.NoError(t, db.Put(ctx, repeatedChar('a', 32), repeatedChar('b', 96)))
 	err = db.Close()
 	assert.NoError(t, err)
 
@@ -93,13 +330,13 @@ func TestShouldWriteManifestSafely(t *testing.T) {
 		l0IDsToCompact = append(l0IDsToCompact, newSourceIDSST(id))
 	}
 
-	db, err = OpenWithOptions(context.Background(), testPath, bucket, options)
+	db, err = Open(context.Background(), testPath, bucket, options)
 	assert.NoError(t, err)
-	db.Put(repeatedChar('j', 32), repeatedChar('k', 96))
+	require.NoError(t, db.Put(ctx, repeatedChar('j', 32), repeatedChar('k', 96)))
 	err = db.Close()
 	assert.NoError(t, err)
 
-	err = orchestrator.submitCompaction(newCompaction(l0IDsToCompact, 0))
+	err = orchestrator.submitCompaction(context.Background(), newCompaction(l0IDsToCompact, 0))
 	assert.NoError(t, err)
 	orchestrator.executor.waitForTasksToComplete()
 	msg, ok := orchestrator.executor.nextCompactionResult()
@@ -107,7 +344,7 @@ func TestShouldWriteManifestSafely(t *testing.T) {
 	assert.NotNil(t, msg.SortedRun)
 	sr := msg.SortedRun
 
-	err = orchestrator.finishCompaction(sr)
+	err = orchestrator.finishCompaction(context.Background(), sr)
 	assert.NoError(t, err)
 
 	// Key aaa... will be compacted and Key jjj... will be in Level0
@@ -130,7 +367,7 @@ func TestShouldWriteManifestSafely(t *testing.T) {
 
 func buildTestDB(options config.DBOptions) (objstore.Bucket, *store.ManifestStore, *store.TableStore, *DB) {
 	bucket := objstore.NewInMemBucket()
-	db, err := OpenWithOptions(context.Background(), testPath, bucket, options)
+	db, err := Open(context.Background(), testPath, bucket, options)
 	assert2.True(err == nil, "Failed to open test database")
 	conf := sstable.DefaultConfig()
 	conf.BlockSize = 32
@@ -0,0 +1,104 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/table"
+)
+
+// WriteIf applies the batch like Write, if every key the batch expects with
+// WriteBatch.Expect and WriteBatch.ExpectAbsent has its expected value. The
+// conditions are checked atomically with the write: no other write of the
+// expected keys is applied between the check and the batch, so a small invariant
+// spanning several keys can be maintained without transactions. Returns an error
+// wrapping common.ErrConditionFailed if a condition is not met, in which case
+// nothing is written.
+//
+// The conditions are evaluated against every write, including the writes which
+// are not yet durable, like a read with config.Uncommitted. Writes are durable in
+// order of sequence number, so the batch is never durable unless the writes it
+// was checked against are.
+//
+// The check is optimistic: if an expected key is written while the conditions are
+// evaluated, or an SSTable is added to L0, they are evaluated again.
+func (db *DB) WriteIf(ctx context.Context, batch *WriteBatch) error {
+	return db.WriteIfWithOptions(ctx, batch, config.DefaultWriteOptions())
+}
+
+// WriteIfWithOptions applies the batch like WriteIf, and waits for it to be
+// durable if options.AwaitDurable is set
+func (db *DB) WriteIfWithOptions(ctx context.Context, batch *WriteBatch, options config.WriteOptions) error {
+	if err := db.background.checkWritable(); err != nil {
+		return err
+	}
+	future, err := db.writeIf(ctx, batch)
+	if err != nil {
+		return err
+	}
+	if options.AwaitDurable {
+		return db.awaitDurable(ctx, future.Done())
+	}
+	return nil
+}
+
+func (db *DB) writeIf(ctx context.Context, batch *WriteBatch) (*WriteFuture, error) {
+	keys := make([][]byte, 0, len(batch.conditions))
+	for _, c := range batch.conditions {
+		keys = append(keys, c.key)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		version := db.state.ReadVersion()
+		if err := db.checkConditions(ctx, batch.conditions); err != nil {
+			return nil, err
+		}
+		if batch.Len() == 0 {
+			done := make(chan bool)
+			close(done)
+			return &WriteFuture{done: done, seq: db.CommittedSeq()}, nil
+		}
+
+		tombstones, entries := slices.Clone(batch.ranges), slices.Clone(batch.entries)
+		wal, err := db.writeEntriesWith(ctx, tombstones, entries, func(tombstones types.RangeTombstones, entries []types.RowEntry) *table.WAL {
+			wal, _ := db.state.WriteEntriesToWALIfUnchanged(tombstones, entries, keys, version)
+			return wal
+		})
+		if err != nil {
+			return nil, err
+		}
+		if wal != nil {
+			return newWriteFuture(wal, batchSeq(tombstones, entries)), nil
+		}
+	}
+}
+
+// checkConditions returns an error wrapping common.ErrConditionFailed for the
+// first condition whose key doesn't have its expected value
+func (db *DB) checkConditions(ctx context.Context, conditions []condition) error {
+	for _, c := range conditions {
+		value, err := db.GetWithOptions(ctx, c.key, config.ReadOptions{ReadLevel: config.Uncommitted})
+		if err != nil && !errors.Is(err, common.ErrKeyNotFound) {
+			return fmt.Errorf("while reading key '%s': %w", c.key, err)
+		}
+		expected, ok := c.value.Get()
+		switch {
+		case !ok && err == nil:
+			return fmt.Errorf("%w: key '%s' exists", common.ErrConditionFailed, c.key)
+		case ok && err != nil:
+			return fmt.Errorf("%w: key '%s' does not exist", common.ErrConditionFailed, c.key)
+		case ok && !bytes.Equal(expected, value):
+			return fmt.Errorf("%w: key '%s' has a different value", common.ErrConditionFailed, c.key)
+		}
+	}
+	return nil
+}
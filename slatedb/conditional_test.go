@@ -0,0 +1,97 @@
+package slatedb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestWriteIf(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	batch := NewWriteBatch()
+	batch.ExpectAbsent([]byte("key1"))
+	batch.Put([]byte("key1"), []byte("value1"))
+	require.NoError(t, db.WriteIf(ctx, batch))
+	assert.ErrorIs(t, db.WriteIf(ctx, batch), common.ErrConditionFailed)
+
+	// Every expected key must match for the batch to apply
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	batch = NewWriteBatch()
+	batch.Expect([]byte("key1"), []byte("value1"))
+	batch.Expect([]byte("key2"), []byte("other"))
+	batch.Delete([]byte("key1"))
+	batch.Put([]byte("key2"), []byte("value3"))
+	assert.ErrorIs(t, db.WriteIf(ctx, batch), common.ErrConditionFailed)
+	value, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+
+	batch = NewWriteBatch()
+	batch.Expect([]byte("key1"), []byte("value1"))
+	batch.Expect([]byte("key2"), []byte("value2"))
+	batch.Delete([]byte("key1"))
+	batch.Put([]byte("key2"), []byte("value3"))
+	require.NoError(t, db.WriteIf(ctx, batch))
+	_, err = db.Get(ctx, []byte("key1"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	value, err = db.Get(ctx, []byte("key2"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value3"), value)
+}
+
+func TestWriteIfIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Each writer moves units from one key to the other, the sum of both keys
+	// only holds if no move is applied against a stale value
+	require.NoError(t, db.Put(ctx, []byte("a"), []byte("1000")))
+	require.NoError(t, db.Put(ctx, []byte("b"), []byte("0")))
+	read := func(key string) []byte {
+		value, err := db.GetWithOptions(ctx, []byte(key), config.ReadOptions{ReadLevel: config.Uncommitted})
+		require.NoError(t, err)
+		return value
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for moved := 0; moved < 25; {
+				a, b := read("a"), read("b")
+				aNum, _ := strconv.Atoi(string(a))
+				bNum, _ := strconv.Atoi(string(b))
+				batch := NewWriteBatch()
+				batch.Expect([]byte("a"), a)
+				batch.Expect([]byte("b"), b)
+				batch.Put([]byte("a"), []byte(strconv.Itoa(aNum-1)))
+				batch.Put([]byte("b"), []byte(strconv.Itoa(bNum+1)))
+				err := db.WriteIfWithOptions(ctx, batch, config.WriteOptions{AwaitDurable: false})
+				if errors.Is(err, common.ErrConditionFailed) {
+					continue
+				}
+				assert.NoError(t, err)
+				moved++
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []byte("900"), read("a"))
+	assert.Equal(t, []byte("100"), read("b"))
+}
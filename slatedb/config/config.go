@@ -1,10 +1,16 @@
 package config
 
 import (
+	"io"
 	"log/slog"
 	"time"
 
 	"github.com/slatedb/slatedb-go/internal/compress"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
+	"github.com/slatedb/slatedb-go/slatedb/manifest"
+	"github.com/slatedb/slatedb-go/slatedb/writebuffer"
 )
 
 // DBOptions Configuration opts for the database. These opts are set on client startup.
@@ -29,16 +35,158 @@ type DBOptions struct {
 	// bytes to object storage.
 	FlushInterval time.Duration
 
+	// The size in bytes of the WAL which flushes it to object storage before the
+	// FlushInterval elapses, so bursts of writes are uploaded in WAL SSTables of
+	// a bounded size and await durability for less time. Zero flushes the WAL only
+	// every FlushInterval.
+	MaxWALBytes uint64
+
+	// Disable the WAL, for workloads which tolerate losing the most recent writes.
+	// Writes are still batched in memory, but instead of uploading a WAL SSTable,
+	// every FlushInterval (or MaxWALBytes) the memtable is flushed directly to L0.
+	// A write is only durable, and only resolves its WriteFuture and advances
+	// DB.CommittedSeq, once it is in L0, and the writes since the last flush are
+	// lost on a crash. Replicas, which tail the WAL, only see writes once they are
+	// in L0. Each flush writes a small L0 SSTable, so a longer FlushInterval is
+	// recommended to limit the work of compaction.
+	DisableWAL bool
+
 	// How frequently to poll for new manifest files. Refreshing the manifest file
 	// allows writers to detect fencing operations and allows readers to detect newly
 	// compacted data.
 	ManifestPollInterval time.Duration
 
+	// The number of most recent manifest versions to keep in object storage. Older
+	// versions are pruned every ManifestPollInterval. Each manifest holds the complete
+	// DB state, so only the latest version is needed to open the DB; older versions
+	// are kept to aid debugging and recovery. A value of zero disables pruning.
+	ManifestRetainVersions int
+
+	// The minimum time a manifest version is kept after it was written, see
+	// ManifestRetainVersions. A writer which was fenced but has not noticed yet
+	// writes its next manifest with the ID after the last manifest it read, so
+	// that ID must still exist for the conditional write to fail.
+	ManifestRetainDuration time.Duration
+
+	// Log a warning when the number of manifest versions in object storage exceeds
+	// this value, which usually means pruning is disabled or failing. A value of
+	// zero disables the warning.
+	ManifestVersionsWarnThreshold int
+
+	// The number of most recent WAL SSTables already flushed to L0 to keep in
+	// object storage. Older flushed WAL SSTables are deleted every
+	// ManifestPollInterval once they were uploaded more than WALRetainDuration
+	// ago. The DB doesn't replay them when it is opened, they are kept so that
+	// replicas tailing the WAL with TailWAL which lag behind can catch up. The WAL
+	// SSTables which a replica bootstrapped from a checkpoint pinned with
+	// store.ManifestStore.PinManifest tails are never deleted. A value of zero
+	// disables the deletion of WAL SSTables.
+	WALRetainCount int
+
+	// The minimum time a WAL SSTable flushed to L0 is kept in object storage
+	// after it was uploaded, see WALRetainCount.
+	WALRetainDuration time.Duration
+
+	// How frequently the writer publishes a heartbeat to object storage, which
+	// replicas read with slatedb.ReplicaStatsOf to detect a writer which died or
+	// lost access to object storage without closing the DB, and fail over or
+	// alert. The heartbeat is published by the WAL flush task, or by DB.Maintenance
+	// if DisableBackgroundTasks is set, and a writer stops publishing it once a
+	// background error was unrecoverable, such as when it was fenced by a newer
+	// writer. A value of zero disables the heartbeat.
+	HeartbeatInterval time.Duration
+
+	// Open fails with common.ErrAlreadyOpen if the writer of the DB published a
+	// heartbeat within LiveWriterTimeout and did not close the DB, rather than
+	// fencing a writer which is likely still alive. The error identifies the host,
+	// process and start time of that writer. A writer which died without closing
+	// the DB is fenced once its last heartbeat is older than the timeout, which
+	// should be several times the HeartbeatInterval of the writer. A value of zero
+	// disables the check, so Open fences any writer, which takes over a DB from a
+	// writer which is alive.
+	LiveWriterTimeout time.Duration
+
+	// Disable the background tasks of the DB, for deployments such as serverless
+	// functions where the process may be frozen at any time. FlushInterval,
+	// ManifestPollInterval and CompactorOptions.PollInterval are then ignored, and
+	// the application calls DB.Maintenance, or DB.FlushNow and DB.CompactOnce,
+	// instead. Writes with AwaitDurable flush the WAL themselves, and writes are not
+	// stalled by MaxImmutableMemtables, as no task would flush the memtables.
+	DisableBackgroundTasks bool
+
+	// The target size of the blocks of SSTables. Larger blocks compress better and
+	// need a smaller index, while smaller blocks reduce the bytes fetched by each
+	// point lookup. The block size is recorded in each SSTable. Defaults to 4096.
+	BlockSize uint64
+
+	// The maximum number of entries in each block of an SSTable, in addition to the
+	// BlockSize. Workloads with many tiny keys otherwise pack thousands of entries
+	// into a block, which makes each seek within the block and each scan of it
+	// costlier. The entry count of every block is recorded in the index. Zero only
+	// limits the BlockSize.
+	BlockMaxEntries uint32
+
 	// Write SSTables with a bloom filter if the number of keys in the SSTable
 	// is greater than or equal to this value. Reads on small SSTables might be
 	// faster without a bloom filter.
 	MinFilterKeys uint32
 
+	// The number of bits to use per key when building a bloom filter. More bits
+	// reduce the false positive rate, and so the number of blocks fetched for keys
+	// which do not exist, at the cost of a larger filter. 10 bits per key yields a
+	// false positive rate of roughly 1%.
+	FilterBitsPerKey uint32
+
+	// The policy used to create the filter of each SSTable, such as a filter of key
+	// prefixes from filter.NewPrefixPolicy, or a filter partitioned by prefix from
+	// filter.NewPartitionedPolicy which is also consulted by DB.ScanPrefix. The
+	// name of the policy is recorded in each SSTable, and filters created by a
+	// policy which is not configured are ignored when reading. If nil, a bloom
+	// filter of FilterBitsPerKey is used.
+	FilterPolicy filter.Policy
+
+	// SSTables with more blocks than this value are written with a partitioned
+	// index, so reads load only the index partition which covers the key instead
+	// of the index of every block in the SSTable.
+	IndexPartitionThreshold uint64
+
+	// The maximum number of concurrent range reads used to fetch several
+	// consecutive blocks of an SSTable. Concurrent reads hide the latency of
+	// object stores such as S3. Defaults to 4, or 16 for ProfileS3Express, a value
+	// of one fetches the blocks with a single range read.
+	BlockFetchParallelism int
+
+	// The maximum size in bytes of the decoded blocks cached in memory, so repeated
+	// reads of a block don't fetch it from object storage. The cache is split into
+	// 64 shards of equal size, and a block larger than a shard is not cached, so the
+	// capacity should be well over 64 times the BlockSize. Zero disables the cache.
+	BlockCacheBytes uint64
+
+	// When the checksum of a block held by the block cache is verified, see
+	// ChecksumVerification. Defaults to ChecksumVerifyOnCacheInsert.
+	BlockCacheChecksums ChecksumVerification
+
+	// The maximum size in bytes of the indexes and index partitions cached in
+	// memory, so reads don't fetch the index of an SSTable from object storage
+	// every time. When the indexes of a large DB don't fit, the partitions of
+	// partitioned indexes are paged in on demand and the least recently used
+	// evicted, so the memory held by indexes stays bounded. Stats.IndexCache
+	// reports the paging rate, see IndexPartitionThreshold to partition the
+	// indexes of large SSTables. Zero disables the cache.
+	IndexCacheBytes uint64
+
+	// Record a CRC-32C checksum of every value written to an SSTable, which
+	// DB.GetWithChecksum returns so applications which verify values end to end
+	// don't need to hash large values on every read. Costs 4 bytes per value.
+	ValueChecksums bool
+
+	// MergeOperator combines the merge operands written by DB.Merge with the
+	// older value of their key, such as incrementing a counter, so updates don't
+	// need to read the value before writing it. Operands are applied when the key
+	// is read or compacted, so the same MergeOperator must be configured every time
+	// the DB is opened. Nil disables DB.Merge.
+	MergeOperator types.MergeOperator
+
 	// The minimum size a memtable needs to be before it is frozen and flushed to
 	// L0 object storage. Writes will still be flushed to the object storage WAL
 	// (based on FlushInterval) regardless of this value. Memtable sizes are checked
@@ -67,26 +215,270 @@ type DBOptions struct {
 	//   secondary readers to see new data.
 	L0SSTSizeBytes uint64
 
+	// The size (in bytes) of each part of the multipart upload of an L0 SSTable.
+	// Memtables of at least this size are flushed through a pipeline, which
+	// compresses and checksums blocks while the next blocks are built, and uploads
+	// each part while the next part is built, so flushing a large memtable is
+	// limited by the bandwidth of the object store rather than the sum of the
+	// stages. The time spent in each stage is reported by DB.Stats. Memtables
+	// flushed through the pipeline are never stored inline, see InlineSSTMaxBytes.
+	// The object store must meet the requirements of CompactorOptions.UploadPartSize.
+	// Zero builds every L0 SSTable before it is uploaded.
+	L0UploadPartSize uint64
+
+	// The maximum size of the mutable memtable. The memtable is otherwise only
+	// frozen once a WAL has been applied to it in full, so a large WAL can grow
+	// the memtable well past L0SSTSizeBytes. When applying an entry pushes the
+	// memtable past this size, it is swapped for a new memtable and enqueued to
+	// be flushed to L0 immediately. Zero disables the limit.
+	MaxMemtableBytes uint64
+
+	// The maximum number of immutable memtables waiting to be flushed to L0. Once
+	// reached, writes stall until the memtable flush task uploads the oldest
+	// memtable, which bounds the memory of the DB when writes outpace object
+	// storage. The time writes spend stalled is reported by DB.Stats. Memtables
+	// frozen while applying WALs already written are still queued, so the queue can
+	// briefly exceed the limit. Zero disables the limit.
+	MaxImmutableMemtables int
+
+	// The number of skiplists the memtable is partitioned into by key hash. Each
+	// shard is locked separately, which reduces contention between goroutines
+	// writing and reading the memtable concurrently, at the cost of merging the
+	// shards when iterating over the memtable. Zero or one uses a single skiplist.
+	MemtableShards int
+
+	// The maximum encoded size of an L0 SSTable which is stored inline in the
+	// manifest rather than as a separate object. Storing tiny flushes inline saves
+	// a PUT for every flush, and a GET for every read of the SSTable, at the cost of
+	// a larger manifest. Compaction folds inline SSTables into SSTables which are
+	// stored as objects. Zero stores every SSTable as an object.
+	InlineSSTMaxBytes uint64
+
+	// WriteTimestamps records the time of every write with the entry, alongside
+	// the sequence number assigned to every write. The time of the write is
+	// preserved when the entry is flushed and compacted into SSTables, at the
+	// cost of 8 bytes per entry.
+	WriteTimestamps bool
+
+	// WriteBufferManager caps the memory used by the memtables of every DB which
+	// shares the same writebuffer.Manager. Once the memtables of all the DBs exceed
+	// the budget of the Manager, the largest memtable is flushed to L0, such that
+	// embedders running many DBs in one process can bound their memory. Nil leaves
+	// the memtables of the DB bounded only by L0SSTSizeBytes and MaxMemtableBytes.
+	WriteBufferManager *writebuffer.Manager
+
+	// Journal receives a record of every call to DB.Put, DB.Delete, DB.Merge and
+	// DB.Write as a line of JSON, with the time and sequence number of the call,
+	// which slatedb.ReplayJournal applies to another DB to reproduce a workload
+	// for debugging, or to migrate the data to a DB with different options. Every
+	// write is serialized while it is journaled. Nil disables the journal.
+	Journal io.Writer
+
 	// Log used to log database warnings
 	Log *slog.Logger
 
+	// Called whenever a read of an SSTable fails because the object is corrupt,
+	// with the key of the object and the location of the corrupt bytes. Every
+	// corruption is also logged to Log.
+	OnCorruption func(*common.CorruptionError)
+
+	// By default Open fails if a WAL which was not yet flushed to L0 is corrupt,
+	// and the WAL is left in place for inspection. If RecoverCorruptWAL is true the
+	// writes which can still be read from a corrupt WAL are replayed, and the WAL is
+	// moved aside to an object with the ".corrupt" extension in place of ".sst",
+	// such that the DB is opened without the writes which could not be read. The
+	// writes which are replayed need not be a prefix of the writes of the WAL, as a
+	// WAL holds its writes in order of key rather than sequence number.
+	RecoverCorruptWAL bool
+
 	// Configuration opts for the compactor.
 	CompactorOptions *CompactorOptions
 	CompressionCodec compress.Codec
+
+	// The codec used to compress the WAL SSTables, which are written every
+	// FlushInterval and read only by recovery and replication. compress.CodecNone
+	// uses the CompressionCodec. Every value in a WAL SSTable is written with a
+	// checksum, so recovery detects a corrupt WAL rather than replaying garbage.
+	WALCompressionCodec compress.Codec
+
+	// The maximum size of the compression dictionary trained on the first blocks
+	// of each SSTable and shared by all of its blocks. A dictionary gives much
+	// better compression ratios for small blocks. Only used with compress.CodecZstd,
+	// zero disables the dictionary.
+	CompressionDictSize uint64
+
+	// The number of bytes of blocks sampled to train the compression dictionary.
+	// Defaults to 100 times the CompressionDictSize.
+	CompressionDictTrainingBytes uint64
+
+	// When opening an existing DB whose persisted format options (comparator,
+	// compression codec, block format version) differ from the supplied options,
+	// Open fails with a report listing every mismatch. If ForceMigrate is true and
+	// every mismatch can be migrated safely, the supplied options are persisted
+	// instead and the DB is opened. Unsafe mismatches always fail.
+	ForceMigrate bool
+
+	// If true, every manifest written is immediately read back and compared with
+	// the bytes written before the writer or compactor proceeds. This detects
+	// object stores with weaker conditional write guarantees, which may allow a
+	// concurrent client to overwrite a manifest version. Every verification costs
+	// an additional GET, and the time spent is reported by DB.Stats.
+	ParanoidManifestWrites bool
+
+	// What the DB does once a background task, such as flushing the WAL, flushing
+	// memtables or compaction, fails BackgroundErrorLimit times in a row. Defaults
+	// to BackgroundErrorReport.
+	BackgroundErrorPolicy BackgroundErrorPolicy
+
+	// The number of consecutive failures of a background task after which the
+	// error is considered unrecoverable and BackgroundErrorPolicy is applied.
+	// Defaults to 3. A fenced DB is always considered unrecoverable.
+	BackgroundErrorLimit int
+
+	// Called with the error of every background task considered unrecoverable,
+	// before BackgroundErrorPolicy is applied. The error is also logged to Log
+	// and returned by DB.Health.
+	OnBackgroundError func(error)
+
+	// Called once the writer or compactor of the DB is fenced by a newer client,
+	// with the epochs and the writer identities of both clients, which tell a
+	// failover apart from two clients running against the same DB. The error is
+	// also logged to Log.
+	OnFenced func(*manifest.FencedError)
+
+	// Reports whether an error returned by the object store means it is throttling
+	// requests, such as a 503 SlowDown response from S3. While requests are
+	// throttled, memtable flushes upload one part at a time, one compaction runs
+	// at a time and compactions prefetch fewer blocks, until requests are no
+	// longer throttled. The WAL is still flushed every FlushInterval, so writes
+	// awaiting durability are not delayed. The state is reported by DB.Stats. If
+	// nil, store.IsThrottledErr recognizes errors which wrap common.ErrThrottled
+	// or carry an HTTP status of 429 or 503, such as the errors of the AWS SDK,
+	// along with the errors the IsThrottled method of the ObjectStore recognizes,
+	// if it has one. The errors of an objstore.Bucket are not recognized otherwise.
+	IsThrottled func(error) bool
+
+	// How failed object store requests are retried. The zero value makes every
+	// request once, without a timeout. The retries are reported by DB.Stats.
+	ObjectStoreRetry ObjectStoreRetryOptions
+
+	// Limits the rate of every object store request of the DB, including its WAL
+	// and manifest writes, so the DB stays within the request budget or the
+	// egress of the object store. Every attempt of a retried request counts. Set
+	// CompactorOptions.RateLimit to limit compaction alone. The zero value
+	// doesn't limit requests.
+	ObjectStoreRateLimit ObjectStoreRateLimits
+
+	// Layout names the prefixes of the WAL SSTables, compacted SSTables and
+	// manifests under the path of the DB, so several DBs can share a bucket, or a
+	// bucket with its own naming conventions can be adopted. The layout is
+	// recorded when the DB is created, and Open fails with
+	// common.ErrIncompatibleLayout if it differs from the recorded layout.
+	Layout ObjectStoreLayout
+
+	// The latency profile of the object store, which DefaultDBOptionsForProfile
+	// tunes the options for. Options tuned by the profile which are left zero,
+	// such as BlockFetchParallelism, default to the value of the profile.
+	StoreProfile ObjectStoreProfile
 }
 
 func DefaultDBOptions() DBOptions {
-	return DBOptions{
-		FlushInterval:        100 * time.Millisecond,
-		ManifestPollInterval: 1 * time.Second,
-		MinFilterKeys:        1000,
-		L0SSTSizeBytes:       64 * 1024 * 1024,
-		CompactorOptions:     DefaultCompactorOptions(),
-		CompressionCodec:     compress.CodecNone,
-		Log:                  slog.Default(),
+	return DefaultDBOptionsForProfile(ProfileStandard)
+}
+
+// ObjectStoreProfile describes the latency characteristics of the object store
+// backing the DB, and selects write pipeline defaults which suit it.
+//
+// Profiles only tune the DB; features specific to an object store such as S3
+// Express session authentication and directory buckets are provided by the
+// objstore.Bucket client passed to Open.
+type ObjectStoreProfile int
+
+const (
+	// ProfileStandard suits object stores with request latencies in the tens of
+	// milliseconds, such as S3 Standard, GCS and Azure Blob Storage.
+	ProfileStandard ObjectStoreProfile = iota
+
+	// ProfileS3Express suits object stores with single digit millisecond request
+	// latencies, such as S3 Express One Zone directory buckets or a MinIO server on
+	// the local network. As each PUT completes sooner, the WAL is flushed in smaller
+	// batches to reduce write latency, and the manifest and compactor are polled
+	// more frequently. Reads fetch more blocks in parallel, and failed requests are
+	// retried after shorter backoffs and time out sooner.
+	ProfileS3Express
+)
+
+// DefaultDBOptionsForProfile returns the default DBOptions tuned for the provided
+// ObjectStoreProfile.
+func DefaultDBOptionsForProfile(profile ObjectStoreProfile) DBOptions {
+	opts := DBOptions{
+		FlushInterval:                 100 * time.Millisecond,
+		ManifestPollInterval:          1 * time.Second,
+		ManifestRetainVersions:        100,
+		ManifestRetainDuration:        time.Hour,
+		ManifestVersionsWarnThreshold: 1000,
+		WALRetainCount:                100,
+		WALRetainDuration:             time.Hour,
+		HeartbeatInterval:             10 * time.Second,
+		LiveWriterTimeout:             30 * time.Second,
+		BlockSize:                     4096,
+		MinFilterKeys:                 1000,
+		FilterBitsPerKey:              10,
+		IndexPartitionThreshold:       8192,
+		BlockFetchParallelism:         4,
+		BackgroundErrorLimit:          3,
+		ObjectStoreRetry:              DefaultObjectStoreRetryOptions(),
+		L0SSTSizeBytes:                64 * 1024 * 1024,
+		CompactorOptions:              DefaultCompactorOptions(),
+		CompressionCodec:              compress.CodecNone,
+		Log:                           slog.Default(),
+		StoreProfile:                  profile,
+	}
+
+	if profile == ProfileS3Express {
+		opts.FlushInterval = 10 * time.Millisecond
+		opts.ManifestPollInterval = 200 * time.Millisecond
+		opts.CompactorOptions.PollInterval = 1 * time.Second
+		opts.BlockFetchParallelism = 16
+		opts.ObjectStoreRetry.InitialBackoff = 10 * time.Millisecond
+		opts.ObjectStoreRetry.MaxBackoff = 500 * time.Millisecond
+		opts.ObjectStoreRetry.OperationTimeout = 2 * time.Second
 	}
+	return opts
 }
 
+// BackgroundErrorPolicy decides the blast radius of unrecoverable background errors
+type BackgroundErrorPolicy int
+
+const (
+	// BackgroundErrorReport only surfaces the error via DB.Health and
+	// DBOptions.OnBackgroundError, and the DB continues to accept writes.
+	BackgroundErrorReport BackgroundErrorPolicy = iota
+
+	// BackgroundErrorReadOnly puts the DB into a read-only degraded state. Reads
+	// continue to be served, while writes, FlushWAL, FlushMemtableToL0 and
+	// IngestSST return common.ErrReadOnly.
+	BackgroundErrorReadOnly
+
+	// BackgroundErrorPanic panics with the error from the background task.
+	BackgroundErrorPanic
+)
+
+// ChecksumVerification decides when the checksum of a block in the block cache is verified
+type ChecksumVerification int
+
+const (
+	// ChecksumVerifyOnCacheInsert verifies the checksum of a block once when it is
+	// fetched and inserted into the block cache. Reads which hit the cache return
+	// the decoded block without verifying it again.
+	ChecksumVerifyOnCacheInsert ChecksumVerification = iota
+
+	// ChecksumVerifyOnRead caches the encoded block, which is decoded and its
+	// checksum verified on every read, so a block corrupted in memory is never
+	// returned. Costs the decompression of the block on every read.
+	ChecksumVerifyOnRead
+)
+
 type ReadLevel int
 
 // Whether reads see only writes that have been committed durably to the DB.  A
@@ -100,6 +492,11 @@ const (
 	// Uncommitted - Clients will see all writes, including those not yet durably committed to the
 	// DB.
 	Uncommitted
+
+	// Flushed - Clients will only see writes already flushed to L0, as of the latest manifest
+	// version, and not the writes still in the memtables. A scan which reads only flushed
+	// writes can be resumed with DB.ResumeScan.
+	Flushed
 )
 
 // ReadOptions Configuration for client read operations. `ReadOptions` is supplied for each
@@ -107,6 +504,13 @@ const (
 type ReadOptions struct {
 	// The read commit level for read operations.
 	ReadLevel ReadLevel
+
+	// RawMergeOperands returns the newest merge operand of the key as it is stored,
+	// rather than the result of applying DBOptions.MergeOperator to the operands
+	// and the older value of the key. Operands written to the same memtable, or
+	// compacted together, are already combined. Use a scan with
+	// ScanOptions.RawMergeOperands for the older operands of the key.
+	RawMergeOperands bool
 }
 
 func DefaultReadOptions() ReadOptions {
@@ -119,7 +523,9 @@ func DefaultReadOptions() ReadOptions {
 // write call and controls the behavior of the write.
 type WriteOptions struct {
 	// Whether `put` calls should block until the write has been durably committed
-	// to the DB.
+	// to the DB. Otherwise the write returns once it is added to the in-memory WAL,
+	// and is only read with ReadLevel Uncommitted until the WAL is flushed. The
+	// durability of such writes is tracked with DB.CommittedSeq.
 	AwaitDurable bool
 }
 
@@ -129,6 +535,158 @@ func DefaultWriteOptions() WriteOptions {
 	}
 }
 
+// ScanOptions Configuration for DB.Scan and DB.ResumeScan. `ScanOptions` is supplied
+// for each scan and controls how the scan reads the DB and reports its progress.
+type ScanOptions struct {
+	// The number of blocks fetched ahead of the block being read from each SSTable.
+	// Zero disables prefetching. Ignored if Readahead is enabled.
+	PrefetchBlocks int
+
+	// Readahead bounds the number of blocks fetched ahead of the block being read
+	// from each SSTable, which adapts to how the scan is consumed. Zero
+	// Readahead.MaxBlocks disables it in favour of a fixed PrefetchBlocks.
+	Readahead ReadaheadOptions
+
+	// The number of keys returned by the scan between each call to OnResumeToken.
+	// Zero disables periodic resume tokens.
+	ResumeTokenInterval int
+
+	// OnResumeToken is called with a resume token every ResumeTokenInterval keys.
+	// A scan which is interrupted can be continued after the last key returned
+	// before the token was issued by passing the token to DB.ResumeScan.
+	OnResumeToken func(token []byte)
+
+	// The writes read by DB.Scan, like the ReadLevel of ReadOptions. Zero, like
+	// Committed, merges the memtables with the SSTables, and Uncommitted the WALs
+	// as well. Flushed reads only the writes flushed to L0, as of the latest
+	// manifest version. Only a scan with Flushed can be resumed, so OnResumeToken
+	// requires Flushed.
+	ReadLevel ReadLevel
+
+	// RawMergeOperands returns the merge operands of each key as they are stored,
+	// rather than the result of applying DBOptions.MergeOperator to them, for
+	// callers which fold the operands themselves. The operands of a key are
+	// returned as separate entries of the key from newest to oldest, followed by
+	// the older value they apply to, if any. Operands written to the same
+	// memtable, or compacted together, are already combined. A scan of raw merge
+	// operands can't be resumed.
+	RawMergeOperands bool
+}
+
+func DefaultScanOptions() ScanOptions {
+	return ScanOptions{
+		Readahead: ReadaheadOptions{
+			MinBlocks:        1,
+			MaxBlocks:        16,
+			MaxRequestBlocks: 4,
+			MaxRequestBytes:  4 * 1024 * 1024,
+		},
+	}
+}
+
+// ReadaheadOptions bounds the adaptive readahead of each SSTable read by a scan.
+// Rather than fetching a fixed number of blocks ahead, the scan observes the rate
+// and stride at which it is consumed. A dense scan which catches up with the
+// blocks being fetched fetches further ahead with larger range reads, while a
+// scan which skips over blocks, such as a merge of SSTables whose keys rarely
+// interleave, or a consumer slower than the fetches, fetches fewer blocks ahead
+// so less is read and held in memory in vain.
+type ReadaheadOptions struct {
+	// The smallest number of blocks fetched ahead of the block being read, which
+	// is where the readahead of each SSTable starts
+	MinBlocks int
+
+	// The largest number of blocks fetched ahead of the block being read. Zero
+	// disables adaptive readahead.
+	MaxBlocks int
+
+	// The largest number of consecutive blocks fetched with a single range read,
+	// which trades fewer requests for a longer wait on the first block of each
+	// read. Zero fetches each block with a read of its own.
+	MaxRequestBlocks int
+
+	// The largest number of bytes fetched with a single range read, which caps
+	// the growth of the range reads of long scans, and bounds the reads of
+	// SSTables with large blocks. A range read fetches at least one block. Zero
+	// leaves the reads bounded by MaxRequestBlocks only.
+	MaxRequestBytes uint64
+}
+
+// ObjectStoreRetryOptions decides how failed object store requests are retried.
+// Each retry waits an exponential backoff with jitter, and a request is retried
+// only while the retry budget lasts, so a failing object store is not flooded
+// with retries.
+type ObjectStoreRetryOptions struct {
+	// The maximum number of attempts of a request, including the first. One or
+	// less disables retries.
+	MaxAttempts int
+
+	// The backoff before the first retry, which doubles after each retry up to
+	// MaxBackoff. Every backoff is randomized to between half and all of it, so
+	// clients which failed together don't retry together.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// The timeout of each attempt of a request. An attempt which times out is
+	// retried. For reads, it bounds the time until the object is read and closed.
+	// Zero disables the timeout.
+	OperationTimeout time.Duration
+
+	// The number of retries which can be made in a row. Every retry spends one
+	// from the budget, and every request which succeeds returns a tenth of one,
+	// so at most about one in ten requests is retried while the object store is
+	// failing. Zero leaves retries unbounded.
+	RetryBudget int
+
+	// Reports whether a failed request may succeed if retried. If nil, requests
+	// which failed because the object does or does not exist, were canceled, or
+	// were rejected as invalid or unauthorized are not retried, and other
+	// requests are. Conditional writes are only retried if the object store
+	// throttled them, see DBOptions.IsThrottled, as any other failed attempt may
+	// have written the object. Writes of a stream, such as the multipart upload
+	// of a large SSTable, are not retried, as the stream can't be written again.
+	IsRetryable func(error) bool
+}
+
+func DefaultObjectStoreRetryOptions() ObjectStoreRetryOptions {
+	return ObjectStoreRetryOptions{
+		MaxAttempts:    4,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		RetryBudget:    100,
+	}
+}
+
+// ObjectStoreRateLimits limits the rate of object store requests and bytes with
+// token buckets, separately for reads and writes. A request waits until its
+// bucket holds a token, and the bytes of a body wait as they are read. Each
+// bucket holds up to a second of its rate, so short bursts are not delayed. Zero
+// fields are not limited.
+type ObjectStoreRateLimits struct {
+	// The rate of Get, GetRange, Head and List requests, and of the bytes read
+	ReadRequestsPerSecond float64
+	ReadBytesPerSecond    float64
+
+	// The rate of Put, PutIfNotExists and Delete requests, and of the bytes written
+	WriteRequestsPerSecond float64
+	WriteBytesPerSecond    float64
+}
+
+// Limited returns true if any of the rates is limited
+func (l ObjectStoreRateLimits) Limited() bool {
+	return l.ReadRequestsPerSecond > 0 || l.ReadBytesPerSecond > 0 ||
+		l.WriteRequestsPerSecond > 0 || l.WriteBytesPerSecond > 0
+}
+
+// ObjectStoreLayout names the prefixes under the path of a DB which its objects
+// are written to. Empty prefixes default to "wal", "compacted" and "manifest".
+// The prefixes may be nested, such as "data/wal", but none may contain another.
+type ObjectStoreLayout struct {
+	WALPrefix       string
+	CompactedPrefix string
+	ManifestPrefix  string
+}
+
 type CompactorOptions struct {
 	// The interval at which the compactor checks for a new manifest and decides
 	// if a compaction must be scheduled
@@ -138,11 +696,84 @@ type CompactorOptions struct {
 	// written to a Sorted Run during a compaction, a new SSTable will be created
 	// in the Sorted Run when this size is exceeded.
 	MaxSSTSize uint64
+
+	// PartitionKey returns the partition of a key, such as the tenant of a key
+	// prefixed by its tenant ID. Compaction starts a new SSTable wherever the
+	// partition of consecutive keys differs, so the keys of a partition are held
+	// by few SSTables of their own, which makes scanning, exporting or deleting
+	// a partition cheaper. The partition of a key must never change. Nil doesn't
+	// partition the SSTables.
+	PartitionKey func(key []byte) []byte
+
+	// The size (in bytes) an SSTable must reach before compaction starts a new
+	// SSTable at the next partition boundary, see PartitionKey, so the keys of
+	// small partitions share SSTables rather than each being written to a tiny
+	// SSTable. Zero starts a new SSTable at every partition boundary.
+	PartitionMinSSTSize uint64
+
+	// The number of blocks fetched ahead of the block being merged while reading
+	// the SSTables being compacted. Zero disables prefetching.
+	PrefetchBlocks int
+
+	// A sorted run whose fraction of live (non tombstone) entries falls below
+	// this value is compacted along with every older sorted run, which drops the
+	// tombstones and the values they delete. This reclaims space promptly after
+	// mass deletions instead of waiting for size based compactions. Zero disables
+	// delete triggered compactions.
+	MinLiveDataFraction float64
+
+	// The size (in bytes) of each part of the multipart upload of a compacted
+	// SSTable. Blocks are streamed to object storage as each part fills, instead
+	// of buffering the entire SSTable in memory until it is complete. Memory is
+	// only bounded if the object store uploads the stream as it is written, such
+	// as with a multipart upload, rather than reading all of it first. The object
+	// store must not create the object if the stream ends with an error, so an
+	// aborted compaction leaves no partial SSTable behind. Zero buffers the
+	// entire SSTable.
+	UploadPartSize uint64
+
+	// The options of the SSTables written by compaction, which may differ from the
+	// options of the WAL and L0 SSTables written by the DB, such as larger blocks
+	// or a stronger compression codec for sorted runs. Zero fields use the value
+	// of DBOptions.
+	SSTableOptions SSTableOptions
+
+	// Limits the rate of the object store requests made by compaction, such as
+	// its reads of the SSTables being compacted and its uploads of the sorted
+	// runs, so compaction doesn't starve the requests of readers and writers or
+	// saturate the egress of the host. The limits apply on top of
+	// DBOptions.ObjectStoreRateLimit. The zero value doesn't limit compaction.
+	RateLimit ObjectStoreRateLimits
+}
+
+// SSTableOptions configures how SSTables are written. The options are recorded
+// in the metadata of each SSTable, so readers don't need to be configured with
+// the options an SSTable was written with.
+type SSTableOptions struct {
+	// The target size of each block
+	BlockSize uint64
+
+	// The maximum number of entries in each block
+	BlockMaxEntries uint32
+
+	// The codec used to compress the blocks, filter and index
+	CompressionCodec compress.Codec
+
+	// The number of bits per key of the bloom filter
+	FilterBitsPerKey uint32
+
+	// SSTables with more blocks than this value are written with a partitioned index
+	IndexPartitionThreshold uint64
+
+	// Record a checksum of every value, see DBOptions.ValueChecksums
+	ValueChecksums bool
 }
 
 func DefaultCompactorOptions() *CompactorOptions {
 	return &CompactorOptions{
-		PollInterval: 5 * time.Second,
-		MaxSSTSize:   1024 * 1024 * 1024,
+		PollInterval:        5 * time.Second,
+		MaxSSTSize:          1024 * 1024 * 1024,
+		PrefetchBlocks:      4,
+		MinLiveDataFraction: 0.5,
 	}
 }
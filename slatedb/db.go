@@ -8,16 +8,22 @@ import (
 	"log/slog"
 	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/kapetan-io/tackle/set"
+	"github.com/samber/mo"
 
 	"github.com/slatedb/slatedb-go/internal/assert"
 	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
 	"github.com/slatedb/slatedb-go/internal/types"
 	"github.com/slatedb/slatedb-go/slatedb/compaction"
 	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/manifest"
 	"github.com/slatedb/slatedb-go/slatedb/state"
 	"github.com/slatedb/slatedb-go/slatedb/store"
+	"github.com/slatedb/slatedb-go/slatedb/table"
 
 	"github.com/thanos-io/objstore"
 
@@ -27,19 +33,67 @@ import (
 const BlockSize = 4096
 
 type DB struct {
-	manifest   *store.FenceableManifest
-	tableStore *store.TableStore
-	compactor  *Compactor
-	opts       config.DBOptions
-	state      *state.DBState
+	manifest      *store.FenceableManifest
+	manifestStore *store.ManifestStore
+	tableStore    *store.TableStore
+	compactor     *Compactor
+	opts          config.DBOptions
+	state         *state.DBState
+	stats         dbStats
+	background    *backgroundErrors
+	writeStalls   *writeStalls
+
+	// committedSeqWatch wakes the callers waiting for the committed sequence
+	// number to advance, see DB.CommittedSeqChanged
+	committedSeqWatch *committedSeqWatch
+
+	// memtableFlushMu serializes the flushes of immutable memtables to L0, which
+	// are made by both the memtable flush task and callers of FlushMemtableToL0
+	memtableFlushMu sync.Mutex
+
+	// walFlushMu serializes the flushes of the WAL, which are made by both the WAL
+	// flush task and callers of FlushWAL
+	walFlushMu sync.Mutex
+
+	// unflushedWALs are the WALs applied to the memtable whose writes are not yet
+	// flushed to L0, when DBOptions.DisableWAL is set. Guarded by walFlushMu.
+	unflushedWALs []*table.ImmutableWAL
+
+	// maintenance runs the tasks of the memtable flush task when the application
+	// calls DB.Maintenance, nil unless DBOptions.DisableBackgroundTasks is set
+	maintenance *MemtableFlusher
+
+	// lastHeartbeat is the time in unix nanoseconds the writer last published its
+	// heartbeat, see DBOptions.HeartbeatInterval
+	lastHeartbeat atomic.Int64
+
+	// bucket delegates the requests of the DB to the bucket provided to Open, or
+	// to the bucket which last replaced it, see RotateBucket. It is nil if the DB
+	// was opened with OpenWithObjectStore.
+	bucket *store.RotatingBucket
+
+	// throttle backs off the background tasks while the object store throttles
+	// requests, see DBOptions.IsThrottled
+	throttle *store.ThrottleController
+	// retry retries the failed object store requests, see DBOptions.ObjectStoreRetry
+	retry *store.RetryObjectStore
+
+	// journal records every write when DBOptions.Journal is set, nil otherwise
+	journal *journal
+
+	// writeBuffer is registered with DBOptions.WriteBufferManager, nil if there is none
+	writeBuffer *writeBufferMember
 
 	// walFlushNotifierCh - When DB.Close is called, we send a notification to this channel
 	// and the goroutine running the walFlush task reads this channel and shuts down
 	walFlushNotifierCh chan bool
 
+	// walFullCh wakes the WAL flush task when the WAL reaches DBOptions.MaxWALBytes
+	walFullCh chan struct{}
+
 	// memtableFlushNotifierCh - When DB.Close is called, we send a Shutdown notification to this channel
 	// and the goroutine running the memtableFlush task reads this channel and shuts down
-	memtableFlushNotifierCh chan<- MemtableFlushThreadMsg
+	memtableFlushNotifierCh chan MemtableFlushThreadMsg
 
 	// walFlushTaskWG - When DB.Close is called, this is used to wait till the walFlush task goroutine is completed
 	walFlushTaskWG *sync.WaitGroup
@@ -48,21 +102,78 @@ type DB struct {
 	memtableFlushTaskWG *sync.WaitGroup
 }
 
-func Open(ctx context.Context, path string, bucket objstore.Bucket) (*DB, error) {
-	return OpenWithOptions(ctx, path, bucket, config.DefaultDBOptions())
+// Open opens the DB at the path of the bucket, creating it if it does not exist.
+// Use config.DefaultDBOptions for the default options.
+func Open(ctx context.Context, path string, bucket objstore.Bucket, options config.DBOptions) (*DB, error) {
+	rotating := store.NewRotatingBucket(bucket)
+	return open(ctx, path, store.NewBucketObjectStore(rotating), rotating, options)
 }
 
+// OpenWithOptions opens the DB at the path of the bucket.
+//
+// Deprecated: Use Open, which takes the options.
 func OpenWithOptions(ctx context.Context, path string, bucket objstore.Bucket, options config.DBOptions) (*DB, error) {
+	return Open(ctx, path, bucket, options)
+}
+
+// OpenWithObjectStore opens the DB at the path of the ObjectStore, for backends
+// which are not an objstore.Bucket. A DB opened this way cannot rotate its bucket.
+func OpenWithObjectStore(ctx context.Context, path string, objectStore store.ObjectStore, options config.DBOptions) (*DB, error) {
+	return open(ctx, path, objectStore, nil, options)
+}
+
+// open opens the DB through the ObjectStore. The rotating bucket is nil unless the
+// ObjectStore reads and writes it.
+func open(ctx context.Context, path string, objectStore store.ObjectStore, rotating *store.RotatingBucket,
+	options config.DBOptions) (*DB, error) {
 	conf := sstable.DefaultConfig()
-	conf.BlockSize = BlockSize
+	set.Default(&options.BlockSize, uint64(BlockSize))
+	conf.BlockSize = options.BlockSize
+	conf.BlockMaxEntries = options.BlockMaxEntries
 	conf.MinFilterKeys = options.MinFilterKeys
+	set.Default(&options.FilterBitsPerKey, conf.FilterBitsPerKey)
+	conf.FilterBitsPerKey = options.FilterBitsPerKey
+	conf.FilterPolicy = options.FilterPolicy
+	set.Default(&options.IndexPartitionThreshold, conf.IndexPartitionThreshold)
+	conf.IndexPartitionThreshold = options.IndexPartitionThreshold
+	set.Default(&options.BlockFetchParallelism, config.DefaultDBOptionsForProfile(options.StoreProfile).BlockFetchParallelism)
+	conf.BlockFetchParallelism = options.BlockFetchParallelism
+	conf.ValueChecksums = options.ValueChecksums
 	conf.Compression = options.CompressionCodec
+	conf.CompressionDictSize = options.CompressionDictSize
+	conf.CompressionDictTrainingBytes = options.CompressionDictTrainingBytes
 	set.Default(&options.Log, slog.Default())
+	set.Default(&options.BackgroundErrorLimit, 3)
+	layout, err := store.ResolveLayout(options.Layout)
+	if err != nil {
+		return nil, err
+	}
 
-	tableStore := store.NewTableStore(bucket, conf, path)
-	manifestStore := store.NewManifestStore(path, bucket)
-	manifest, err := getManifest(manifestStore)
-
+	isThrottled := store.ThrottleClassifier(objectStore, options.IsThrottled)
+	throttle := store.NewThrottleController(isThrottled)
+	// Every attempt of a retried request is rate limited and reported to the
+	// throttle controller
+	limited := store.NewRateLimitObjectStore(store.NewThrottleObjectStore(objectStore, throttle),
+		options.ObjectStoreRateLimit)
+	retry := store.NewRetryObjectStore(limited, options.ObjectStoreRetry, isThrottled)
+	if err := store.ClaimLayout(ctx, retry, path, layout); err != nil {
+		return nil, err
+	}
+	tableStore := store.NewTableStoreWithObjectStore(retry, conf, path)
+	tableStore.SetLayout(layout)
+	tableStore.OnCorruption(func(err *common.CorruptionError) {
+		options.Log.Error("detected corrupt object", "object", err.Object, "section", err.Section,
+			"offset", err.Offset, "block", err.Block, "error", err.Err)
+		if options.OnCorruption != nil {
+			options.OnCorruption(err)
+		}
+	})
+	tableStore.SetBlockCache(options.BlockCacheBytes, options.BlockCacheChecksums)
+	tableStore.SetIndexCache(options.IndexCacheBytes)
+	manifestStore := store.NewManifestStoreWithObjectStore(path, retry)
+	manifestStore.SetLayout(layout)
+	manifestStore.SetVerifyWrites(options.ParanoidManifestWrites)
+	manifest, err := getManifest(manifestStore, options)
 	if err != nil {
 		return nil, err
 	}
@@ -78,32 +189,77 @@ func OpenWithOptions(ctx context.Context, path string, bucket objstore.Bucket, o
 		return nil, fmt.Errorf("during db init: %w", err)
 	}
 	db.manifest = manifest
+	db.manifestStore = manifestStore
+	db.bucket = rotating
+	db.throttle = throttle
+	db.retry = retry
 
-	db.walFlushNotifierCh = make(chan bool, math.MaxUint8)
-	// we start 2 background threads
-	// one thread for flushing WAL to object store and then to memtable. Flushing happens every FlushInterval Duration
-	db.spawnWALFlushTask(db.walFlushNotifierCh, db.walFlushTaskWG)
-	// another thread for
-	// 1. flushing Immutable memtables to L0. Flushing happens when memtable size reaches L0SSTSizeBytes
-	// 2. loading manifest from object store and update current DBState. This happens every ManifestPollInterval milliseconds
-	db.spawnMemtableFlushTask(manifest, memtableFlushNotifierCh, db.memtableFlushTaskWG)
-
-	var compactor *Compactor
+	// The compactor is created before the background tasks are spawned, so a
+	// compactor which fails to start leaves no tasks behind
 	if db.opts.CompactorOptions != nil {
-		compactor, err = newCompactor(manifestStore, tableStore, db.opts)
+		compactionStore := tableStore.WithIOClass(store.IOClassCompaction).WithRateLimit(db.opts.CompactorOptions.RateLimit)
+		db.compactor, err = newCompactor(manifestStore, compactionStore, db.opts, db.background, throttle)
 		if err != nil {
 			return nil, fmt.Errorf("while creating compactor: %w", err)
 		}
 	}
-	db.compactor = compactor
+	if err := db.fenceWAL(ctx); err != nil {
+		if db.compactor != nil {
+			db.compactor.close()
+		}
+		return nil, fmt.Errorf("while fencing WAL: %w", err)
+	}
+
+	db.walFlushNotifierCh = make(chan bool, math.MaxUint8)
+	db.walFullCh = make(chan struct{}, 1)
+	if options.DisableBackgroundTasks {
+		// The tasks are run by the application, see DB.Maintenance
+		db.maintenance = &MemtableFlusher{db: db, manifest: manifest, log: options.Log}
+	} else {
+		// we start 2 background threads
+		// one thread for flushing WAL to object store and then to memtable. Flushing happens every FlushInterval Duration
+		db.spawnWALFlushTask(db.walFlushNotifierCh, db.walFlushTaskWG)
+		// another thread for
+		// 1. flushing Immutable memtables to L0. Flushing happens when memtable size reaches L0SSTSizeBytes
+		// 2. loading manifest from object store and update current DBState. This happens every ManifestPollInterval milliseconds
+		// 3. pruning manifest versions beyond ManifestRetainVersions. This also happens every ManifestPollInterval
+		db.spawnMemtableFlushTask(manifest, memtableFlushNotifierCh, db.memtableFlushTaskWG)
+	}
 
+	if m := db.opts.WriteBufferManager; m != nil {
+		db.writeBuffer = &writeBufferMember{db: db}
+		m.Register(db.writeBuffer)
+	}
 	return db, nil
 }
 
+// RotateBucket replaces the bucket which the DB reads and writes without closing
+// the DB, such as with a bucket created with new credentials before the static
+// credentials of the current bucket expire. Both buckets must refer to the same
+// storage. Requests in flight complete against the previous bucket, which is
+// returned once they completed for the caller to close, see store.RotatingBucket.
+//
+// Buckets created with credential providers which refresh themselves, such as
+// IRSA, instance profiles or workload identity, never need to be rotated. A DB
+// opened with OpenWithObjectStore has no bucket to rotate.
+func (db *DB) RotateBucket(ctx context.Context, bucket objstore.Bucket) (objstore.Bucket, error) {
+	if db.bucket == nil {
+		return nil, fmt.Errorf("%w: RotateBucket requires a DB opened with a bucket", common.ErrInvalidOptions)
+	}
+	db.opts.Log.Info("rotating object store bucket", "bucket", bucket.Name())
+	return db.bucket.Rotate(ctx, bucket)
+}
+
 func (db *DB) Close() error {
+	if m := db.opts.WriteBufferManager; m != nil {
+		m.Unregister(db.writeBuffer)
+	}
 	if db.compactor != nil {
 		db.compactor.close()
 	}
+	if db.maintenance != nil {
+		return db.closeWithoutBackgroundTasks()
+	}
 
 	// notify flush task goroutine to shutdown and wait for it to shutdown cleanly
 	db.walFlushNotifierCh <- true
@@ -113,23 +269,48 @@ func (db *DB) Close() error {
 	db.memtableFlushNotifierCh <- Shutdown
 	db.memtableFlushTaskWG.Wait()
 
+	db.publishHeartbeat(true)
 	return nil
 }
 
-func (db *DB) Put(key []byte, value []byte) {
-	db.PutWithOptions(key, value, config.DefaultWriteOptions())
+// SetFilterBitsPerKey changes the bits per key of the bloom filter of SSTables written
+// after the call, by both memtable flushes and compaction. Existing SSTables keep the
+// bits per key they were written with, which is recorded in sstable.Info, so the
+// rollout of the new setting can be tracked using FilterConfigs.
+//
+// Returns common.ErrInvalidOptions if bitsPerKey is zero or DBOptions.FilterPolicy
+// is set, as the bits per key of a custom filter.Policy are fixed by the policy.
+func (db *DB) SetFilterBitsPerKey(bitsPerKey uint32) error {
+	if bitsPerKey == 0 {
+		return fmt.Errorf("%w: filter bits per key must be greater than zero", common.ErrInvalidOptions)
+	}
+	if db.opts.FilterPolicy != nil {
+		return fmt.Errorf("%w: filter bits per key cannot be changed when a filter policy is set",
+			common.ErrInvalidOptions)
+	}
+	db.tableStore.SetFilterBitsPerKey(bitsPerKey)
+	return nil
 }
 
-func (db *DB) PutWithOptions(key []byte, value []byte, options config.WriteOptions) {
-	assert.True(len(key) > 0, "key cannot be empty")
+// Put writes the key and value, and waits for the write to be durable. Returns
+// common.ErrReadOnly if the DB is read-only, see config.BackgroundErrorReadOnly,
+// or the error of the context if it is done before the write is durable.
+func (db *DB) Put(ctx context.Context, key []byte, value []byte) error {
+	return db.PutWithOptions(ctx, key, value, config.DefaultWriteOptions())
+}
 
-	currentWAL := db.state.PutKVToWAL(key, value)
+func (db *DB) PutWithOptions(ctx context.Context, key []byte, value []byte, options config.WriteOptions) error {
+	future, err := db.PutAsync(ctx, key, value)
+	if err != nil {
+		return err
+	}
 	if options.AwaitDurable {
 		// we wait for WAL to be flushed to memtable and then we send a notification
 		// to goroutine to flush memtable to L0. we do not wait till its flushed to L0
 		// because client can read the key from memtable
-		currentWAL.Table().AwaitWALFlush()
+		return db.awaitDurable(ctx, future.Done())
 	}
+	return nil
 }
 
 func (db *DB) Get(ctx context.Context, key []byte) ([]byte, error) {
@@ -143,175 +324,444 @@ func (db *DB) Get(ctx context.Context, key []byte) ([]byte, error) {
 // if readlevel is Committed we start searching key in the following order
 // mutable memtable, immutable memtables, SSTs in L0, compacted Sorted runs
 func (db *DB) GetWithOptions(ctx context.Context, key []byte, options config.ReadOptions) ([]byte, error) {
-	snapshot := db.state.Snapshot()
+	val, err := db.getValue(ctx, key, options)
+	if err != nil {
+		return nil, err
+	}
+	return checkValue(val)
+}
+
+// GetWithChecksum returns the value of the key along with its types.ValueChecksum
+// (CRC-32C), for applications which verify the integrity of values end to end.
+// The checksum recorded when the value was written to an SSTable is returned if
+// DBOptions.ValueChecksums is enabled, otherwise the value is hashed, such as
+// for values which are not yet flushed to an SSTable.
+func (db *DB) GetWithChecksum(ctx context.Context, key []byte) ([]byte, uint32, error) {
+	val, err := db.getValue(ctx, key, config.DefaultReadOptions())
+	if err != nil {
+		return nil, 0, err
+	}
+	value, err := checkValue(val)
+	if err != nil {
+		return nil, 0, err
+	}
+	if checksum, ok := val.Checksum.Get(); ok {
+		return value, checksum, nil
+	}
+	return value, types.ValueChecksum(value), nil
+}
 
-	if options.ReadLevel == config.Uncommitted {
+// getValue returns the most recent value of the key, which is a tombstone if the
+// key was deleted, or common.ErrKeyNotFound if the key was never written. Merge
+// operands are applied to the older value of the key, and range tombstones delete
+// the older entries of the key.
+func (db *DB) getValue(ctx context.Context, key []byte, options config.ReadOptions) (types.Value, error) {
+	return db.getValueFrom(ctx, db.state.Snapshot(), key, options)
+}
+
+// getValueFrom returns the most recent value of the key in the tables of the
+// snapshot, see getValue. The WALs of the snapshot are only searched if the read
+// level is config.Uncommitted, and the memtables are not searched if it is
+// config.Flushed. Merge operands are returned as is with options.RawMergeOperands.
+func (db *DB) getValueFrom(ctx context.Context, snapshot *state.DBStateSnapshot, key []byte, options config.ReadOptions) (types.Value, error) {
+	level := options.ReadLevel
+	op := db.opts.MergeOperator
+	if options.RawMergeOperands {
+		op = nil
+	}
+	chain := newMergeChain(op, key)
+
+	if level == config.Uncommitted {
 		// search for key in mutable WAL
-		val, ok := snapshot.Wal.Get(key).Get()
-		if ok { // key is present or tombstoned
-			return checkValue(val)
+		if val, ok := chain.addTable(snapshot.Wal.GetEntry(key), snapshot.Wal.RangeTombstones()); ok {
+			return val, nil
 		}
 		// search for key in ImmutableWALs
 		immWALList := snapshot.ImmWALs
 		for i := 0; i < immWALList.Len(); i++ {
 			immWAL := immWALList.At(i)
-			val, ok := immWAL.Get(key).Get()
-			if ok { // key is present or tombstoned
-				return checkValue(val)
+			if val, ok := chain.addTable(immWAL.GetEntry(key), immWAL.RangeTombstones()); ok {
+				return val, nil
 			}
 		}
 	}
 
+	if level == config.Flushed {
+		return db.getChainFromSSTs(ctx, snapshot.Core, chain)
+	}
+
 	// search for key in mutable memtable
-	val, ok := snapshot.Memtable.Get(key).Get()
-	if ok { // key is present or tombstoned
-		return checkValue(val)
+	if val, ok := chain.addTable(snapshot.Memtable.GetEntry(key), snapshot.Memtable.RangeTombstones()); ok {
+		return val, nil
 	}
 	// search for key in Immutable memtables
 	immMemtables := snapshot.ImmMemtables
 	for i := 0; i < immMemtables.Len(); i++ {
 		immTable := immMemtables.At(i)
-		val, ok := immTable.Get(key).Get()
-		if ok {
-			return checkValue(val)
+		if val, ok := chain.addTable(immTable.GetEntry(key), immTable.RangeTombstones()); ok {
+			return val, nil
 		}
 	}
 
-	// search for key in SSTs in L0
-	for _, sst := range snapshot.Core.L0 {
-		if db.sstMayIncludeKey(sst, key) {
-			iter, err := sstable.NewIteratorAtKey(&sst, key, db.tableStore.Clone())
+	return db.getChainFromSSTs(ctx, snapshot.Core, chain)
+}
+
+// getChainFromSSTs searches the SSTs of core for the older values of the key of
+// the mergeChain, until the value of the key is resolved
+func (db *DB) getChainFromSSTs(ctx context.Context, core *state.CoreStateSnapshot, chain *mergeChain) (types.Value, error) {
+	key := chain.key
+	tableStore := db.tableStore.WithIOClass(store.IOClassGet)
+
+	// search for key in SSTs in L0. The range tombstones of an SSTable apply to
+	// keys outside the range of the keys of the SSTable.
+	for _, sst := range core.L0 {
+		tombstones, err := tableStore.ReadRangeTombstones(ctx, &sst)
+		if err != nil {
+			return types.Value{}, err
+		}
+
+		entry := mo.None[types.RowEntry]()
+		if db.sstMayIncludeKey(ctx, sst, key) {
+			iter, err := sstable.NewIteratorAtKey(ctx, &sst, key, tableStore)
 			if err != nil {
-				return nil, err
+				return types.Value{}, err
 			}
 
 			kv, ok := iter.NextEntry(ctx)
 			if ok && bytes.Equal(kv.Key, key) {
-				return checkValue(kv.Value)
+				entry = mo.Some(kv)
 			}
 		}
+		if val, ok := chain.addTable(entry, tombstones); ok {
+			return val, nil
+		}
 	}
 
-	// search for key in compacted Sorted runs
-	for _, sr := range snapshot.Core.Compacted {
-		if db.srMayIncludeKey(sr, key) {
-			iter, err := compaction.NewSortedRunIteratorFromKey(sr, key, db.tableStore.Clone())
+	// search for key in compacted Sorted runs. Every SSTable of a sorted run holds
+	// the range tombstones of the sorted run.
+	for _, sr := range core.Compacted {
+		var tombstones types.RangeTombstones
+		if len(sr.SSTList) > 0 {
+			var err error
+			tombstones, err = tableStore.ReadRangeTombstones(ctx, &sr.SSTList[0])
+			if err != nil {
+				return types.Value{}, err
+			}
+		}
+
+		entry := mo.None[types.RowEntry]()
+		if db.srMayIncludeKey(ctx, sr, key) {
+			iter, err := compaction.NewSortedRunIteratorFromKey(ctx, sr, key, tableStore)
 			if err != nil {
-				return nil, err
+				return types.Value{}, err
 			}
 
 			kv, ok := iter.NextEntry(ctx)
 			if ok && bytes.Equal(kv.Key, key) {
-				return checkValue(kv.Value)
+				entry = mo.Some(kv)
 			}
 		}
+		if val, ok := chain.addTable(entry, tombstones); ok {
+			return val, nil
+		}
 	}
 
-	return nil, common.ErrKeyNotFound
+	return chain.resolve()
 }
 
-func (db *DB) Delete(key []byte) {
-	db.DeleteWithOptions(key, config.DefaultWriteOptions())
+// Delete deletes the key like Put
+func (db *DB) Delete(ctx context.Context, key []byte) error {
+	return db.DeleteWithOptions(ctx, key, config.DefaultWriteOptions())
 }
 
-func (db *DB) DeleteWithOptions(key []byte, options config.WriteOptions) {
-	assert.True(len(key) > 0, "key cannot be empty")
+func (db *DB) DeleteWithOptions(ctx context.Context, key []byte, options config.WriteOptions) error {
+	future, err := db.DeleteAsync(ctx, key)
+	if err != nil {
+		return err
+	}
+	if options.AwaitDurable {
+		return db.awaitDurable(ctx, future.Done())
+	}
+	return nil
+}
 
-	currentWAL := db.state.DeleteKVFromWAL(key)
+// Write applies every Put, Delete and DeleteRange in the batch to the same WAL,
+// so the writes in the batch are flushed to object storage together, and waits
+// for the batch to be durable if options.AwaitDurable is set. Returns errors like
+// Put, in which case none of the writes in the batch is applied, unless the
+// context is done after the batch was applied and before it is durable.
+func (db *DB) Write(ctx context.Context, batch *WriteBatch, options config.WriteOptions) error {
+	future, err := db.WriteAsync(ctx, batch)
+	if err != nil {
+		return err
+	}
 	if options.AwaitDurable {
-		currentWAL.Table().AwaitWALFlush()
+		return db.awaitDurable(ctx, future.Done())
+	}
+	return nil
+}
+
+// WriteWithOptions applies the batch like Write.
+//
+// Deprecated: Use Write, which takes the options.
+func (db *DB) WriteWithOptions(ctx context.Context, batch *WriteBatch, options config.WriteOptions) error {
+	return db.Write(ctx, batch, options)
+}
+
+// writeEntries writes the entries to the current WAL, which assigns each entry
+// its sequence number. The write stalls while the immutable memtables waiting to
+// be flushed reach DBOptions.MaxImmutableMemtables. The time of the write is
+// recorded with each entry if DBOptions.WriteTimestamps is enabled. Returns
+// common.ErrReadOnly if the DB is read-only, or the error of the context if it is
+// done while the write is stalled, in which case nothing is written.
+func (db *DB) writeEntries(ctx context.Context, entries []types.RowEntry) (*table.WAL, error) {
+	return db.writeEntriesWith(ctx, nil, entries, db.state.WriteEntriesToWAL)
+}
+
+// writeEntriesWith writes the range tombstones and entries like writeEntries with
+// writeWAL, which returns nil if it didn't write them, see DB.WriteIf
+func (db *DB) writeEntriesWith(ctx context.Context, tombstones types.RangeTombstones, entries []types.RowEntry,
+	writeWAL func(types.RangeTombstones, []types.RowEntry) *table.WAL) (*table.WAL, error) {
+	if err := db.stallWrites(ctx); err != nil {
+		return nil, err
+	}
+	if db.opts.WriteTimestamps {
+		now := time.Now()
+		for i := range entries {
+			entries[i].Created = now
+		}
+	}
+	var wal *table.WAL
+	if db.journal != nil {
+		wal = db.journal.write(tombstones, entries, writeWAL)
+	} else {
+		wal = writeWAL(tombstones, entries)
+	}
+	if wal != nil {
+		db.notifyWALFull(wal)
+	}
+	return wal, nil
+}
+
+// notifyWALFull wakes the WAL flush task if the WAL reached DBOptions.MaxWALBytes.
+// A flush already requested is not requested again.
+func (db *DB) notifyWALFull(wal *table.WAL) {
+	if db.opts.MaxWALBytes == 0 || uint64(wal.Size()) < db.opts.MaxWALBytes {
+		return
+	}
+	select {
+	case db.walFullCh <- struct{}{}:
+	default:
 	}
 }
 
-func (db *DB) sstMayIncludeKey(sst sstable.Handle, key []byte) bool {
+func (db *DB) sstMayIncludeKey(ctx context.Context, sst sstable.Handle, key []byte) bool {
 	if !sst.RangeCoversKey(key) {
 		return false
 	}
-	filter, err := db.tableStore.ReadFilter(&sst)
+	filter, err := db.tableStore.WithIOClass(store.IOClassGet).ReadFilter(ctx, &sst)
 	if err == nil && filter.IsPresent() {
 		bFilter, _ := filter.Get()
-		return bFilter.HasKey(key)
+		return bFilter.KeyMayMatch(key)
 	}
 	return true
 }
 
-func (db *DB) srMayIncludeKey(sr compaction.SortedRun, key []byte) bool {
+func (db *DB) srMayIncludeKey(ctx context.Context, sr compaction.SortedRun, key []byte) bool {
 	sstOption := sr.SstWithKey(key)
 	if sstOption.IsAbsent() {
 		return false
 	}
 	sst, _ := sstOption.Get()
-	filter, err := db.tableStore.ReadFilter(&sst)
+	filter, err := db.tableStore.WithIOClass(store.IOClassGet).ReadFilter(ctx, &sst)
 	if err == nil && filter.IsPresent() {
 		bFilter, _ := filter.Get()
-		return bFilter.HasKey(key)
+		return bFilter.KeyMayMatch(key)
 	}
 	return true
 }
 
-// this is to recover from a crash. we read the WALs from object store (considered to be Uncommmitted)
-// and write the kv pairs to memtable
+// replayWAL recovers from a crash by replaying the WALs in object storage which
+// were not yet flushed to L0, in order of WAL ID, into the memtable. New WALs are
+// assigned IDs after the highest WAL found.
+//
+// A crash while uploading the last WAL leaves an incomplete WAL, none of whose
+// writes were acknowledged, so it is removed. Only a WAL whose footer lacks the
+// magic number is incomplete. An incomplete WAL followed by another WAL was not
+// left by a crash, and fails the replay, as does a complete WAL written in a
+// format version this release does not read.
+//
+// A corrupt WAL, whose blocks or values fail their checksums, holds writes which
+// were acknowledged, so it fails the replay and is left in place, unless
+// DBOptions.RecoverCorruptWAL is set. Corrupt blocks are also reported to
+// DBOptions.OnCorruption.
 func (db *DB) replayWAL(ctx context.Context) error {
+	tableStore := db.tableStore.WithIOClass(store.IOClassRecovery)
 	walIDLastCompacted := db.state.LastCompactedWALID()
-	walSSTList, err := db.tableStore.GetWalSSTList(walIDLastCompacted)
+	walSSTList, err := tableStore.GetWalSSTList(ctx, walIDLastCompacted)
 	if err != nil {
 		return err
 	}
 
-	lastSSTID := walIDLastCompacted
-	for _, sstID := range walSSTList {
-		lastSSTID = sstID
-		sst, err := db.tableStore.OpenSST(sstable.NewIDWal(sstID))
-		if err != nil {
-			return err
+	var epoch uint64
+	for i, sstID := range walSSTList {
+		walEpoch, err := db.replayWALSST(ctx, tableStore, sstID, epoch)
+		if errors.Is(err, common.ErrIncompleteSST) {
+			if i != len(walSSTList)-1 {
+				return fmt.Errorf("while replaying WAL '%d', which is followed by WAL '%d': %w",
+					sstID, walSSTList[len(walSSTList)-1], err)
+			}
+			db.opts.Log.Warn("removing incomplete WAL SST", "id", sstID, "error", err)
+			if err := tableStore.DeleteSST(ctx, sstable.NewIDWal(sstID)); err != nil {
+				return err
+			}
+			db.state.AdvanceNextWALID(sstID + 1)
+			continue
+		}
+		if isCorruptWAL(err) {
+			return fmt.Errorf("while replaying WAL '%d', which is corrupt; set "+
+				"DBOptions.RecoverCorruptWAL to open the DB without its unreadable writes: %w", sstID, err)
 		}
-		assert.True(sst.Id.WalID().IsPresent(), "Invalid WAL ID")
-
-		// iterate through kv pairs in sst and populate walReplayBuf
-		iter, err := sstable.NewIterator(sst, db.tableStore.Clone())
 		if err != nil {
-			return err
+			return fmt.Errorf("while replaying WAL '%d': %w", sstID, err)
 		}
+		epoch = max(epoch, walEpoch)
+	}
+	return nil
+}
 
-		walReplayBuf := make([]types.RowEntry, 0)
-		for {
-			kvDel, ok := iter.NextEntry(ctx)
-			if !ok {
-				break
-			}
-			walReplayBuf = append(walReplayBuf, kvDel)
+// replayWALSST replays the WAL with the provided ID into the memtable, and returns
+// the writer epoch the WAL was written with. A WAL written with an epoch lower than
+// minEpoch, the highest epoch of the WALs before it, was written by a writer which
+// was already fenced, so its writes were never acknowledged and it is skipped.
+//
+// Returns a common.CorruptionError if the WAL is corrupt, unless
+// DBOptions.RecoverCorruptWAL is set, in which case the writes which can still be
+// read are replayed and the WAL is quarantined.
+func (db *DB) replayWALSST(ctx context.Context, tableStore *store.TableStore, sstID uint64, minEpoch uint64) (uint64, error) {
+	sst, err := tableStore.OpenSST(ctx, sstable.NewIDWal(sstID))
+	if err != nil {
+		if !isCorruptWAL(err) || !db.opts.RecoverCorruptWAL {
+			return 0, err
 		}
-
-		// update memtable with kv pairs in walReplayBuf
-		for _, kvDel := range walReplayBuf {
-			if kvDel.Value.IsTombstone() {
-				db.state.DeleteKVFromMemtable(kvDel.Key)
-			} else {
-				db.state.PutKVToMemtable(kvDel.Key, kvDel.Value.Value)
-			}
+		if err := db.quarantineWAL(ctx, tableStore, sstID, err); err != nil {
+			return 0, err
+		}
+		return minEpoch, nil
+	}
+	assert.True(sst.Id.WalID().IsPresent(), "Invalid WAL ID")
+	// WALs written before the epoch was recorded have an epoch of zero
+	if epoch := sst.Info.WriterEpoch; epoch != 0 && epoch < minEpoch {
+		db.opts.Log.Warn("skipping WAL SST written by a fenced writer", "id", sstID,
+			"epoch", epoch, "fenced_by", minEpoch)
+		db.state.AdvanceNextWALID(sstID + 1)
+		return minEpoch, nil
+	}
+	entries, rangeTombstones, err := readWAL(ctx, tableStore, sst)
+	if err != nil && (!isCorruptWAL(err) || !db.opts.RecoverCorruptWAL) {
+		return 0, err
+	}
+	db.applyWALToMemtable(sstID, entries, rangeTombstones)
+	db.state.Memtable().SetLastWalID(sstID)
+	if err != nil {
+		if err := db.quarantineWAL(ctx, tableStore, sstID, err); err != nil {
+			return 0, err
 		}
+	}
+
+	db.maybeFreezeMemtable(db.state, sstID)
+	db.state.AdvanceNextWALID(sstID + 1)
+	return sst.Info.WriterEpoch, nil
+}
+
+// fenceWAL writes an empty WAL with the epoch of the writer at the next WAL ID.
+// A previous writer which is not yet aware that it was fenced fails to write its
+// next WAL, as the ID is taken, rather than writing WALs which interleave with the
+// WALs of this writer. WALs the previous writer wrote since the replay are
+// replayed before the next ID is tried.
+func (db *DB) fenceWAL(ctx context.Context) error {
+	tableStore := db.tableStore.WithIOClass(store.IOClassRecovery)
+	builder := tableStore.TableBuilderWithOptions(config.SSTableOptions{CompressionCodec: db.opts.WALCompressionCodec})
+	defer tableStore.ReleaseTableBuilder(builder)
+	builder.SetWriterEpoch(db.manifest.Epoch())
+	encodedSST, err := builder.Build()
+	if err != nil {
+		return err
+	}
 
-		db.maybeFreezeMemtable(db.state, sstID)
-		if db.state.NextWALID() == sstID {
-			db.state.IncrementNextWALID()
+	for {
+		walID := db.state.NextWALID()
+		_, err := tableStore.WriteSSTIfNotExists(ctx, sstable.NewIDWal(walID), encodedSST)
+		if err == nil {
+			db.state.AdvanceNextWALID(walID + 1)
+			return nil
+		}
+		if !errors.Is(err, common.ErrObjectExists) {
+			return fmt.Errorf("while writing fencing WAL '%d': %w", walID, err)
+		}
+		if _, err := db.replayWALSST(ctx, tableStore, walID, 0); err != nil {
+			return fmt.Errorf("while replaying WAL '%d': %w", walID, err)
 		}
 	}
+}
 
-	assert.True(lastSSTID+1 == db.state.NextWALID(), "")
+// quarantineWAL moves the corrupt WAL with the provided ID aside, once the writes
+// which could be read from it were replayed, such that it is neither replayed nor
+// removed by the garbage collector
+func (db *DB) quarantineWAL(ctx context.Context, tableStore *store.TableStore, sstID uint64, cause error) error {
+	quarantinePath, err := tableStore.QuarantineSST(ctx, sstable.NewIDWal(sstID))
+	if err != nil {
+		return fmt.Errorf("while quarantining corrupt WAL '%d': %w", sstID, err)
+	}
+	db.opts.Log.Warn("quarantined corrupt WAL SST, writes which could not be read are lost",
+		"id", sstID, "path", quarantinePath, "error", cause)
+	db.state.AdvanceNextWALID(sstID + 1)
 	return nil
 }
 
+// isCorruptWAL returns true if reading a complete WAL failed because the WAL is
+// corrupt, rather than because object storage failed
+func isCorruptWAL(err error) bool {
+	var cerr *common.CorruptionError
+	return errors.As(err, &cerr) && !errors.Is(err, common.ErrIncompleteSST)
+}
+
 func (db *DB) maybeFreezeMemtable(dbState *state.DBState, walID uint64) {
 	if dbState.Memtable().Size() < int64(db.opts.L0SSTSizeBytes) {
 		return
 	}
 	dbState.FreezeMemtable(walID)
+	db.notifyImmMemtableFlush()
+}
+
+// maybeFreezeFullMemtable freezes the memtable if applying an entry of the WAL with
+// the provided ID pushed its size past DBOptions.MaxMemtableBytes
+func (db *DB) maybeFreezeFullMemtable(walID uint64) {
+	if db.opts.MaxMemtableBytes == 0 || db.state.Memtable().Size() < int64(db.opts.MaxMemtableBytes) {
+		return
+	}
+	// The memtable holds only part of the WAL, so it is frozen as of the previous
+	// WAL. The WAL is then replayed in full on recovery, which is idempotent.
+	db.state.FreezeMemtable(walID - 1)
+	db.notifyImmMemtableFlush()
+}
+
+// notifyImmMemtableFlush wakes the memtable flush task to flush the frozen memtable.
+// Without background tasks the memtable is flushed by the next DB.Maintenance.
+func (db *DB) notifyImmMemtableFlush() {
+	if db.maintenance != nil {
+		return
+	}
 	db.memtableFlushNotifierCh <- FlushImmutableMemtables
 }
 
 // FlushMemtableToL0 - Normally Memtable is flushed to Level0 of object store when it reaches a size of DBOptions.L0SSTSizeBytes
 // This method allows the user to flush Memtable to Level0 irrespective of Memtable size.
-func (db *DB) FlushMemtableToL0() error {
+// The requests to the object store are canceled once ctx is done.
+func (db *DB) FlushMemtableToL0(ctx context.Context) error {
+	if err := db.background.checkWritable(); err != nil {
+		return err
+	}
 	lastWalID := db.state.Memtable().LastWalID()
 	if lastWalID.IsAbsent() {
 		return errors.New("WAL is not yet flushed to Memtable")
@@ -325,10 +775,10 @@ func (db *DB) FlushMemtableToL0() error {
 		manifest: db.manifest,
 		log:      db.opts.Log,
 	}
-	return flusher.flushImmMemtablesToL0()
+	return flusher.flushImmMemtablesToL0(ctx)
 }
 
-func getManifest(manifestStore *store.ManifestStore) (*store.FenceableManifest, error) {
+func getManifest(manifestStore *store.ManifestStore, options config.DBOptions) (*store.FenceableManifest, error) {
 	stored, err := store.LoadStoredManifest(manifestStore)
 	if err != nil {
 		return nil, err
@@ -345,23 +795,66 @@ func getManifest(manifestStore *store.ManifestStore) (*store.FenceableManifest,
 		}
 	}
 
+	// Check compatibility before fencing, so a client with
+	// incompatible options does not fence the current writer.
+	supplied := formatOptions(options)
+	persisted, ok := storedManifest.FormatOptions().Get()
+	if ok {
+		report := manifest.CheckCompatibility(persisted, supplied)
+		if report != nil {
+			if !options.ForceMigrate || !report.Safe() {
+				return nil, report
+			}
+			options.Log.Warn("migrating persisted format options", "report", report.Error())
+		}
+		if manifest.BlockFormatUpgrade(persisted, supplied) {
+			options.Log.Info("upgrading block format version; existing SSTs are rewritten as they are compacted",
+				"persisted", persisted.BlockFormatVersion, "supplied", supplied.BlockFormatVersion)
+		}
+	}
+	// Check for a live writer before fencing, so a client does not
+	// fence a writer which is still serving writes.
+	if stored.IsPresent() && options.LiveWriterTimeout > 0 {
+		if err := checkLiveWriter(manifestStore, storedManifest, options.LiveWriterTimeout); err != nil {
+			return nil, err
+		}
+	}
+	// Manifests written before format options were persisted adopt
+	// the supplied options. They are written when the writer is fenced.
+	storedManifest.SetFormatOptions(supplied)
+	storedManifest.SetWriter(newWriterInfo())
+
 	return store.NewWriterFenceableManifest(storedManifest)
 }
 
+// formatOptions returns the format affecting options which are persisted in the manifest
+func formatOptions(options config.DBOptions) manifest.FormatOptions {
+	return manifest.FormatOptions{
+		Comparator:         manifest.BytewiseComparator,
+		BlockFormatVersion: block.FormatVersion,
+		CompressionCodec:   options.CompressionCodec,
+	}
+}
+
 func newDB(
 	ctx context.Context,
 	options config.DBOptions,
 	tableStore *store.TableStore,
 	coreDBState *state.CoreDBState,
-	memtableFlushNotifierCh chan<- MemtableFlushThreadMsg,
+	memtableFlushNotifierCh chan MemtableFlushThreadMsg,
 ) (*DB, error) {
 
-	dbState := state.NewDBState(coreDBState)
+	dbState := state.NewDBStateWithMemtableShards(coreDBState, options.MemtableShards)
+	dbState.SetMergeOperator(options.MergeOperator)
 	db := &DB{
 		state:                   dbState,
 		opts:                    options,
 		tableStore:              tableStore,
 		memtableFlushNotifierCh: memtableFlushNotifierCh,
+		background:              newBackgroundErrors(options),
+		writeStalls:             newWriteStalls(),
+		committedSeqWatch:       newCommittedSeqWatch(),
+		journal:                 newJournal(options.Journal, options.Log),
 		walFlushTaskWG:          &sync.WaitGroup{},
 		memtableFlushTaskWG:     &sync.WaitGroup{},
 	}
@@ -369,6 +862,10 @@ func newDB(
 	if err != nil {
 		return nil, err
 	}
+	// The writes replayed from the WAL are already committed
+	if options.DisableWAL {
+		dbState.DisableWAL()
+	}
 	return db, nil
 }
 
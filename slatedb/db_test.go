@@ -3,21 +3,35 @@ package slatedb
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"math"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/samber/mo"
 	"github.com/stretchr/testify/require"
 
 	assert2 "github.com/slatedb/slatedb-go/internal/assert"
 	"github.com/slatedb/slatedb-go/internal/compress"
 	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
 	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/compaction"
 	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
+	"github.com/slatedb/slatedb-go/slatedb/manifest"
 	"github.com/slatedb/slatedb-go/slatedb/state"
 	"github.com/slatedb/slatedb-go/slatedb/store"
+	"github.com/slatedb/slatedb-go/slatedb/store/storetest"
+	"github.com/slatedb/slatedb-go/slatedb/writebuffer"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/thanos-io/objstore"
@@ -31,59 +45,93 @@ import (
 func TestPutGetDelete(t *testing.T) {
 	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
-	db, err := OpenWithOptions(context.Background(), "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
+	db, err := Open(context.Background(), "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
 	require.NoError(t, err)
 	defer db.Close()
 
 	key := []byte("key1")
 	value := []byte("value1")
-	db.Put(key, value)
+	require.NoError(t, db.Put(ctx, key, value))
 	val, err := db.Get(context.Background(), key)
 	require.NoError(t, err)
 	assert.Equal(t, value, val)
 
 	key = []byte("key2")
 	value = []byte("value2")
-	db.Put(key, value)
-	err = db.FlushWAL()
+	require.NoError(t, db.Put(ctx, key, value))
+	err = db.FlushWAL(ctx)
 	require.NoError(t, err)
 	val, err = db.Get(ctx, key)
 	require.NoError(t, err)
 	assert.Equal(t, value, val)
 
-	db.Delete(key)
+	require.NoError(t, db.Delete(ctx, key))
 	_, err = db.Get(ctx, key)
 	assert.ErrorIs(t, err, common.ErrKeyNotFound)
 }
 
+func TestWriteBatch(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Put(ctx, []byte("key3"), []byte("value3")))
+
+	batch := NewWriteBatch()
+	batch.Put([]byte("key1"), []byte("first"))
+	batch.Put([]byte("key2"), []byte("value2"))
+	batch.Delete([]byte("key1"))
+	batch.Put([]byte("key1"), []byte("value1"))
+	batch.Put([]byte("key2"), []byte("last"))
+	batch.Delete([]byte("key3"))
+
+	// Duplicate keys are collapsed to the last write
+	assert.Equal(t, 3, batch.Len())
+	require.NoError(t, db.Write(ctx, batch, config.WriteOptions{AwaitDurable: true}))
+
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+
+	val, err = db.Get(ctx, []byte("key2"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("last"), val)
+
+	_, err = db.Get(ctx, []byte("key3"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+}
+
 func TestGetNonExistingKey(t *testing.T) {
 	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
-	db, err := OpenWithOptions(ctx, "/tmp/test_kv_store", bucket, config.DefaultDBOptions())
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, config.DefaultDBOptions())
 	require.NoError(t, err)
 	defer db.Close()
 
-	db.Put([]byte("key1"), []byte("value1"))
-	require.NoError(t, db.FlushWAL())
-	require.NoError(t, db.FlushMemtableToL0())
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
 
 	_, err = db.Get(ctx, []byte("key2"))
 	assert.ErrorIs(t, err, common.ErrKeyNotFound)
 }
 
 func TestGetWithNonDurableWritesAndFlushToL0(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
-	db, err := OpenWithOptions(context.Background(), "/tmp/test_kv_store", bucket, config.DefaultDBOptions())
+	db, err := Open(context.Background(), "/tmp/test_kv_store", bucket, config.DefaultDBOptions())
 	require.NoError(t, err)
 	defer db.Close()
 
-	db.PutWithOptions([]byte("k1"), []byte("v1"), config.WriteOptions{AwaitDurable: false})
-	require.NoError(t, db.FlushWAL())
-	require.NoError(t, db.FlushMemtableToL0())
+	require.NoError(t, db.PutWithOptions(ctx, []byte("k1"), []byte("v1"), config.WriteOptions{AwaitDurable: false}))
+	require.NoError(t, db.FlushWAL(context.Background()))
+	require.NoError(t, db.FlushMemtableToL0(context.Background()))
 
-	db.PutWithOptions([]byte("k0"), []byte("v0"), config.WriteOptions{AwaitDurable: false})
-	require.NoError(t, db.FlushWAL())
-	require.NoError(t, db.FlushMemtableToL0())
+	require.NoError(t, db.PutWithOptions(ctx, []byte("k0"), []byte("v0"), config.WriteOptions{AwaitDurable: false}))
+	require.NoError(t, db.FlushWAL(context.Background()))
+	require.NoError(t, db.FlushMemtableToL0(context.Background()))
 
 	data, err := db.GetWithOptions(context.Background(), []byte("k1"), config.ReadOptions{ReadLevel: config.Committed})
 	assert.Equal(t, "v1", string(data))
@@ -103,9 +151,10 @@ func TestGetWithNonDurableWritesAndFlushToL0(t *testing.T) {
 }
 
 func TestPutFlushesMemtable(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, testDBOptions(0, 128))
+	db, err := Open(context.Background(), dbPath, bucket, testDBOptions(0, 128))
 	require.NoError(t, err)
 	defer db.Close()
 
@@ -123,27 +172,27 @@ func TestPutFlushesMemtable(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		key := repeatedChar(rune('a'+i), 16)
 		value := repeatedChar(rune('b'+i), 50)
-		db.Put(key, value)
+		require.NoError(t, db.Put(ctx, key, value))
 
 		key = repeatedChar(rune('j'+i), 16)
 		value = repeatedChar(rune('k'+i), 50)
-		db.Put(key, value)
+		require.NoError(t, db.Put(ctx, key, value))
 
 		dbState := waitForManifestCondition(storedManifest, time.Second*30, func(state *state.CoreStateSnapshot) bool {
 			return state.LastCompactedWalSSTID.Load() > lastCompacted
 		})
-		assert.Equal(t, uint64(i*2+2), dbState.LastCompactedWalSSTID.Load())
+		// WAL 1 is the fencing WAL written by Open
+		assert.Equal(t, uint64(i*2+3), dbState.LastCompactedWalSSTID.Load())
 		lastCompacted = dbState.LastCompactedWalSSTID.Load()
 	}
 
 	dbState, err := storedManifest.Refresh()
 	require.NoError(t, err)
 	l0 := dbState.L0
-	ctx := context.Background()
 	assert.Equal(t, 3, len(l0))
 	for i := 0; i < 3; i++ {
 		sst := l0[2-i]
-		iter, err := sstable.NewIterator(&sst, tableStore)
+		iter, err := sstable.NewIterator(ctx, &sst, tableStore)
 		require.NoError(t, err)
 
 		kv, ok := iter.Next(ctx)
@@ -166,16 +215,210 @@ func TestPutFlushesMemtable(t *testing.T) {
 	}
 }
 
+func TestInlineSSTs(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024*1024)
+	options.InlineSSTMaxBytes = 4096
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Put(ctx, []byte("small"), []byte("value")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Put(ctx, []byte("large"), repeatedChar('v', 8192)))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	l0 := db.state.L0()
+	require.Len(t, l0, 2)
+	assert.False(t, l0[0].IsInline())
+	assert.True(t, l0[1].IsInline())
+	exists, err := bucket.Exists(ctx, dbPath+"/compacted/"+l0[1].Id.Value+".sst")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, db.Close())
+
+	// The inline SSTable is read from the manifest when the DB is reopened
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Len(t, db.state.L0(), 2)
+	assert.True(t, db.state.L0()[1].IsInline())
+
+	val, err := db.Get(ctx, []byte("small"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), val)
+	val, err = db.Get(ctx, []byte("large"))
+	require.NoError(t, err)
+	assert.Equal(t, repeatedChar('v', 8192), val)
+}
+
+func TestWriteSeqAndTimestamps(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024*1024)
+	options.WriteTimestamps = true
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+
+	before := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	batch := NewWriteBatch()
+	batch.Put([]byte("key3"), []byte("value3"))
+	batch.Delete([]byte("key4"))
+	require.NoError(t, db.Write(ctx, batch, config.DefaultWriteOptions()))
+	assert.Equal(t, uint64(4), db.state.LastSeq())
+
+	// The sequence number and time of each write are preserved in the L0 SSTable
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	l0 := db.state.L0()
+	require.Len(t, l0, 1)
+	iter, err := sstable.NewIterator(ctx, &l0[0], db.tableStore)
+	require.NoError(t, err)
+	for i := 1; i <= 4; i++ {
+		entry, ok := iter.NextEntry(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []byte(fmt.Sprintf("key%d", i)), entry.Key)
+		assert.Equal(t, uint64(i), entry.Seq)
+		assert.False(t, entry.Created.Before(before))
+		assert.False(t, entry.Created.After(time.Now()))
+	}
+	require.NoError(t, db.Close())
+
+	// Sequence numbers continue from the last write when the DB is reopened
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), db.state.LastSeq())
+	require.NoError(t, db.Put(ctx, []byte("key5"), []byte("value5")))
+	assert.Equal(t, uint64(5), db.state.LastSeq())
+	require.NoError(t, db.Close())
+
+	// The last write is only in the WAL, which is replayed when the DB is reopened
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+	require.Len(t, db.state.L0(), 1)
+	assert.Equal(t, uint64(5), db.state.LastSeq())
+}
+
+func TestMemtableShards(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024*1024)
+	options.MemtableShards = 4
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				assert.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("key%d%02d", g, i)), []byte("value")))
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Equal(t, 4, db.state.Memtable().Shards())
+
+	// The L0 SSTable flushed from the memtable holds the keys of every shard in order
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	scan, err := db.Scan(ctx, nil, nil, config.DefaultScanOptions())
+	require.NoError(t, err)
+	var keys []string
+	for key := range scan.All(ctx) {
+		keys = append(keys, string(key))
+	}
+	require.NoError(t, scan.Close())
+	require.Len(t, keys, 100)
+	assert.True(t, slices.IsSorted(keys))
+}
+
+func TestWriteBufferManagerFlushesLargestMemtable(t *testing.T) {
+	ctx := context.Background()
+	manager := writebuffer.NewManager(4096)
+	options := testDBOptions(0, 1024*1024)
+	options.WriteBufferManager = manager
+
+	large, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer large.Close()
+	small, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+
+	// Neither memtable reaches L0SSTSizeBytes, but together they exceed the budget
+	require.NoError(t, large.Put(ctx, []byte("key1"), repeatedChar('v', 3000)))
+	assert.Empty(t, large.state.L0())
+	require.NoError(t, small.Put(ctx, []byte("key2"), repeatedChar('v', 2000)))
+
+	require.Eventually(t, func() bool {
+		return len(large.state.L0()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, small.state.L0())
+	assert.Less(t, manager.Usage(), manager.Budget())
+
+	val, err := large.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, repeatedChar('v', 3000), val)
+
+	// A closed DB no longer counts towards the budget
+	require.NoError(t, small.Close())
+	assert.Equal(t, large.state.Memtable().Size(), manager.Usage())
+}
+
+func TestMaxMemtableBytesFreezesMemtableWithinWAL(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024*1024)
+	options.MaxMemtableBytes = 256
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+
+	// Every write is in the same WAL, which is larger than MaxMemtableBytes
+	batch := NewWriteBatch()
+	for i := 0; i < 20; i++ {
+		batch.Put([]byte(fmt.Sprintf("key-%02d", i)), repeatedChar('v', 32))
+	}
+	require.NoError(t, db.Write(ctx, batch, config.WriteOptions{AwaitDurable: false}))
+	require.NoError(t, db.FlushWAL(ctx))
+
+	assert.Less(t, db.state.Memtable().Size(), int64(options.MaxMemtableBytes))
+	require.Eventually(t, func() bool {
+		return len(db.state.L0()) > 1
+	}, 5*time.Second, 10*time.Millisecond)
+	for i := 0; i < 20; i++ {
+		val, err := db.Get(ctx, []byte(fmt.Sprintf("key-%02d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, repeatedChar('v', 32), val)
+	}
+	require.NoError(t, db.Close())
+
+	// The WAL is replayed on recovery, as it was only partially flushed to L0
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+	for i := 0; i < 20; i++ {
+		val, err := db.Get(ctx, []byte(fmt.Sprintf("key-%02d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, repeatedChar('v', 32), val)
+	}
+}
+
 func TestPutEmptyValue(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
-	db, err := OpenWithOptions(context.Background(), "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
+	db, err := Open(context.Background(), "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
 	require.NoError(t, err)
 	defer db.Close()
 
 	key := []byte("key1")
 	value := []byte("")
-	db.Put(key, value)
-	err = db.FlushWAL()
+	require.NoError(t, db.Put(ctx, key, value))
+	err = db.FlushWAL(context.Background())
 	require.NoError(t, err)
 	val, err := db.Get(context.Background(), key)
 	require.NoError(t, err)
@@ -184,7 +427,7 @@ func TestPutEmptyValue(t *testing.T) {
 
 func TestFlushWhileIterating(t *testing.T) {
 	bucket := objstore.NewInMemBucket()
-	db, err := OpenWithOptions(context.Background(), "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
+	db, err := Open(context.Background(), "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
 	require.NoError(t, err)
 	defer db.Close()
 
@@ -201,7 +444,7 @@ func TestFlushWhileIterating(t *testing.T) {
 	assert.Equal(t, []byte("abc1111"), kv.Key)
 	assert.Equal(t, []byte("value1111"), kv.Value)
 
-	err = db.FlushWAL()
+	err = db.FlushWAL(context.Background())
 	require.NoError(t, err)
 
 	next, err = iter.Next()
@@ -218,9 +461,10 @@ func TestFlushWhileIterating(t *testing.T) {
 }
 
 func TestFlushMemtableToL0(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, config.DefaultDBOptions())
+	db, err := Open(context.Background(), dbPath, bucket, config.DefaultDBOptions())
 	require.NoError(t, err)
 	defer db.Close()
 
@@ -230,14 +474,14 @@ func TestFlushMemtableToL0(t *testing.T) {
 		{Key: []byte("abc3333"), Value: []byte("value3333")},
 	}
 
-	// write KeyValue pairs to DB and call db.FlushWAL()
+	// write KeyValue pairs to DB and call db.FlushWAL(context.Background())
 	for _, kv := range kvPairs {
-		db.Put(kv.Key, kv.Value)
+		require.NoError(t, db.Put(ctx, kv.Key, kv.Value))
 	}
-	err = db.FlushWAL()
+	err = db.FlushWAL(context.Background())
 	require.NoError(t, err)
 
-	// verify that WAL is empty after FlushWAL() is called
+	// verify that WAL is empty after FlushWAL(context.Background()) is called
 	assert.Equal(t, int64(0), db.state.WAL().Size())
 	assert.Equal(t, 0, db.state.ImmWALs().Len())
 
@@ -247,10 +491,10 @@ func TestFlushMemtableToL0(t *testing.T) {
 		assert.True(t, memtable.Get(kv.Key).IsPresent())
 	}
 
-	err = db.FlushMemtableToL0()
+	err = db.FlushMemtableToL0(context.Background())
 	require.NoError(t, err)
 
-	// verify that Memtable is empty after FlushMemtableToL0()
+	// verify that Memtable is empty after FlushMemtableToL0(context.Background())
 	assert.Equal(t, int64(0), db.state.Memtable().Size())
 
 	// verify that we can read keys from Level0
@@ -261,10 +505,185 @@ func TestFlushMemtableToL0(t *testing.T) {
 	}
 }
 
+// readRecordingBucket records the names of the objects read
+type readRecordingBucket struct {
+	objstore.Bucket
+	mu    sync.Mutex
+	reads []string
+}
+
+func (b *readRecordingBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	b.record(name)
+	return b.Bucket.Get(ctx, name)
+}
+
+func (b *readRecordingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	b.record(name)
+	return b.Bucket.GetRange(ctx, name, off, length)
+}
+
+func (b *readRecordingBucket) record(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reads = append(b.reads, name)
+}
+
+// sstReads returns the names of the SSTables read since the last call
+func (b *readRecordingBucket) sstReads() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var reads []string
+	for _, name := range b.reads {
+		if strings.HasSuffix(name, ".sst") {
+			reads = append(reads, name)
+		}
+	}
+	b.reads = nil
+	return reads
+}
+
+func TestGetSkipsSSTsOutsideKeyRange(t *testing.T) {
+	ctx := context.Background()
+	bucket := &readRecordingBucket{Bucket: objstore.NewInMemBucket()}
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Put(ctx, []byte("a1"), []byte("value")))
+	require.NoError(t, db.Put(ctx, []byte("a2"), []byte("value")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Put(ctx, []byte("z1"), []byte("value")))
+	require.NoError(t, db.Put(ctx, []byte("z2"), []byte("value")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	bucket.sstReads()
+
+	// Keys and ranges between the key ranges of the SSTs don't read any SST
+	_, err = db.Get(ctx, []byte("m"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	assert.Empty(t, bucket.sstReads())
+
+	scan, err := db.Scan(ctx, []byte("b"), []byte("y"), config.DefaultScanOptions())
+	require.NoError(t, err)
+	_, ok := scan.Next(ctx)
+	assert.False(t, ok)
+	require.NoError(t, scan.Close())
+	assert.Empty(t, bucket.sstReads())
+
+	val, err := db.Get(ctx, []byte("z1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), val)
+	assert.NotEmpty(t, bucket.sstReads())
+}
+
+func TestSetFilterBitsPerKey(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	flush := func(key string) {
+		require.NoError(t, db.Put(ctx, []byte(key), []byte("value")))
+		require.NoError(t, db.FlushWAL(ctx))
+		require.NoError(t, db.FlushMemtableToL0(ctx))
+	}
+
+	flush("key1")
+	require.ErrorIs(t, db.SetFilterBitsPerKey(0), common.ErrInvalidOptions)
+	require.NoError(t, db.SetFilterBitsPerKey(20))
+	flush("key2")
+
+	assert.Equal(t, map[FilterConfig]int{
+		{Policy: filter.BloomPolicyName, BitsPerKey: 10}: 1,
+		{Policy: filter.BloomPolicyName, BitsPerKey: 20}: 1,
+	}, db.FilterConfigs())
+
+	for _, key := range []string{"key1", "key2"} {
+		val, err := db.Get(ctx, []byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("value"), val)
+	}
+}
+
+func TestApproximateSize(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	batch := NewWriteBatch()
+	for i := 0; i < 100; i++ {
+		batch.Put([]byte(fmt.Sprintf("key-%03d", i)), bytes.Repeat([]byte("v"), 100))
+	}
+	require.NoError(t, db.Write(ctx, batch, config.DefaultWriteOptions()))
+
+	// The writes are in the memtable
+	all, err := db.ApproximateSize(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Greater(t, all, uint64(100*100))
+	half, err := db.ApproximateSize(ctx, []byte("key-050"), nil)
+	require.NoError(t, err)
+	assert.InDelta(t, all/2, half, float64(all)/10)
+	none, err := db.ApproximateSize(ctx, []byte("a"), []byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), none)
+
+	// The writes are in L0, where the size of whole blocks is counted
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	all, err = db.ApproximateSize(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Greater(t, all, uint64(100*100))
+	half, err = db.ApproximateSize(ctx, []byte("key-050"), nil)
+	require.NoError(t, err)
+	assert.Greater(t, half, all/4)
+	assert.Less(t, half, all)
+	none, err = db.ApproximateSize(ctx, []byte("a"), []byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), none)
+}
+
+func TestGetWithChecksum(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024*1024)
+	options.ValueChecksums = true
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	value := bytes.Repeat([]byte("v"), 1024)
+	require.NoError(t, db.Put(ctx, []byte("key"), value))
+	require.NoError(t, db.Put(ctx, []byte("deleted"), value))
+	require.NoError(t, db.Delete(ctx, []byte("deleted")))
+
+	// The checksum of a value in the memtable is computed
+	val, checksum, err := db.GetWithChecksum(ctx, []byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, value, val)
+	assert.Equal(t, types.ValueChecksum(value), checksum)
+
+	// The checksum of a value in an SSTable is recorded when the SSTable is written
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	l0 := db.state.L0()
+	require.Len(t, l0, 1)
+	iter, err := sstable.NewIteratorAtKey(ctx, &l0[0], []byte("key"), db.tableStore)
+	require.NoError(t, err)
+	entry, ok := iter.NextEntry(ctx)
+	require.True(t, ok)
+	assert.Equal(t, mo.Some(types.ValueChecksum(value)), entry.Value.Checksum)
+
+	val, checksum, err = db.GetWithChecksum(ctx, []byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, value, val)
+	assert.Equal(t, types.ValueChecksum(value), checksum)
+
+	_, _, err = db.GetWithChecksum(ctx, []byte("deleted"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+}
+
 func TestBasicRestore(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, testDBOptions(0, 128))
+	db, err := Open(context.Background(), dbPath, bucket, testDBOptions(0, 128))
 	require.NoError(t, err)
 
 	// do a few writes that will result in l0 flushes
@@ -272,23 +691,23 @@ func TestBasicRestore(t *testing.T) {
 	for i := 0; i < l0Count; i++ {
 		key := repeatedChar(rune('a'+i), 16)
 		value := repeatedChar(rune('b'+i), 48)
-		db.Put(key, value)
+		require.NoError(t, db.Put(ctx, key, value))
 		key = repeatedChar(rune('j'+i), 16)
 		value = repeatedChar(rune('k'+i), 48)
-		db.Put(key, value)
+		require.NoError(t, db.Put(ctx, key, value))
 	}
 
 	// write some smaller keys so that we populate wal without flushing to l0
 	sstCount := 5
 	for i := 0; i < sstCount; i++ {
-		db.Put([]byte(strconv.Itoa(i)), []byte(strconv.Itoa(i)))
-		err := db.FlushWAL()
+		require.NoError(t, db.Put(ctx, []byte(strconv.Itoa(i)), []byte(strconv.Itoa(i))))
+		err := db.FlushWAL(context.Background())
 		require.NoError(t, err)
 	}
 	db.Close()
 
 	// recover and validate that sst files are loaded on recovery.
-	dbRestored, err := OpenWithOptions(context.Background(), dbPath, bucket, testDBOptions(0, 128))
+	dbRestored, err := Open(context.Background(), dbPath, bucket, testDBOptions(0, 128))
 	require.NoError(t, err)
 	defer dbRestored.Close()
 
@@ -313,18 +732,592 @@ func TestBasicRestore(t *testing.T) {
 
 	storedManifest, _ := stored.Get()
 	dbState := storedManifest.DbState()
-	assert.Equal(t, uint64(sstCount+2*l0Count+1), dbState.NextWalSstID.Load())
+	// WAL 1 is the fencing WAL written by the first Open
+	assert.Equal(t, uint64(sstCount+2*l0Count+2), dbState.NextWalSstID.Load())
+}
+
+func TestRestoreSkipsIncompleteWAL(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	db, err := Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Close())
+
+	// simulate a writer which crashed while uploading the next WAL SST
+	nextWAL := fmt.Sprintf("%s/wal/%020d.sst", dbPath, db.state.NextWALID())
+	require.NoError(t, bucket.Upload(ctx, nextWAL, bytes.NewReader([]byte("partial upload"))))
+
+	db, err = Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+
+	exists, err := bucket.Exists(ctx, nextWAL)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// new writes must not collide with the removed WAL
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	val, err = db.Get(ctx, []byte("key2"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value2"), val)
+}
+
+func TestRestoreFailsOnWALOfUnsupportedVersion(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	db, err := Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Close())
+
+	walIDs, err := db.tableStore.GetWalSSTList(ctx, db.state.LastCompactedWALID())
+	require.NoError(t, err)
+	lastWAL := fmt.Sprintf("%s/wal/%020d.sst", dbPath, walIDs[len(walIDs)-1])
+
+	// a complete WAL written by a newer release is not removed as incomplete
+	r, err := bucket.Get(ctx, lastWAL)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	binary.BigEndian.PutUint16(data[len(data)-6:], sstable.FormatVersion+1)
+	require.NoError(t, bucket.Upload(ctx, lastWAL, bytes.NewReader(data)))
+
+	_, err = Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	assert.ErrorIs(t, err, common.ErrUnsupportedSSTVersion)
+
+	exists, err := bucket.Exists(ctx, lastWAL)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestRestoreResumesAfterHighestWAL(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	db, err := Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Close())
+
+	walIDs, err := db.tableStore.GetWalSSTList(ctx, db.state.LastCompactedWALID())
+	require.NoError(t, err)
+	require.NotEmpty(t, walIDs)
+
+	// leave a gap in the WAL IDs by moving the last WAL past the next WAL ID
+	lastWAL := walIDs[len(walIDs)-1]
+	movedWAL := lastWAL + 3
+	from := fmt.Sprintf("%s/wal/%020d.sst", dbPath, lastWAL)
+	r, err := bucket.Get(ctx, from)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.NoError(t, bucket.Upload(ctx, fmt.Sprintf("%s/wal/%020d.sst", dbPath, movedWAL), bytes.NewReader(data)))
+	require.NoError(t, bucket.Delete(ctx, from))
+
+	db, err = Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+	// The WAL after the highest WAL is the fencing WAL written by Open
+	assert.Equal(t, movedWAL+2, db.state.NextWALID())
+
+	lastWalID, ok := db.state.Memtable().LastWalID().Get()
+	require.True(t, ok)
+	assert.Equal(t, movedWAL, lastWalID)
+}
+
+func TestRestoreFailsOnIncompleteWALFollowedByWAL(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	db, err := Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	require.NoError(t, db.Close())
+
+	walIDs, err := db.tableStore.GetWalSSTList(ctx, db.state.LastCompactedWALID())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(walIDs), 2)
+
+	// an incomplete WAL which is not the last WAL was not left by a crash
+	torn := fmt.Sprintf("%s/wal/%020d.sst", dbPath, walIDs[0])
+	require.NoError(t, bucket.Upload(ctx, torn, bytes.NewReader([]byte("partial upload"))))
+
+	_, err = Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	assert.ErrorIs(t, err, common.ErrIncompleteSST)
+
+	exists, err := bucket.Exists(ctx, torn)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestRestoreCorruptLastWAL(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024)
+	options.WALCompressionCodec = compress.CodecSnappy
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	require.NoError(t, db.Close())
+
+	walIDs, err := db.tableStore.GetWalSSTList(ctx, db.state.LastCompactedWALID())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(walIDs), 2)
+	lastWAL := walIDs[len(walIDs)-1]
+
+	// WALs are compressed with their own codec and record the checksum of every value
+	sst, err := db.tableStore.OpenSST(ctx, sstable.NewIDWal(lastWAL))
+	require.NoError(t, err)
+	assert.Equal(t, compress.CodecSnappy, sst.Info.CompressionCodec)
+	iter, err := sstable.NewIterator(ctx, sst, db.tableStore)
+	require.NoError(t, err)
+	entry, ok := iter.NextEntry(ctx)
+	require.True(t, ok)
+	assert.Equal(t, []byte("key2"), entry.Key)
+	assert.Equal(t, mo.Some(types.ValueChecksum([]byte("value2"))), entry.Value.Checksum)
+
+	corrupt := fmt.Sprintf("%s/wal/%020d.sst", dbPath, lastWAL)
+	r, err := bucket.Get(ctx, corrupt)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	data[0] ^= 0xff
+	require.NoError(t, bucket.Upload(ctx, corrupt, bytes.NewReader(data)))
+
+	var corruptions []*common.CorruptionError
+	options.OnCorruption = func(err *common.CorruptionError) {
+		corruptions = append(corruptions, err)
+	}
+	// the corrupt WAL holds acknowledged writes, so Open fails and keeps the WAL
+	_, err = Open(ctx, dbPath, bucket, options)
+	var cerr *common.CorruptionError
+	require.ErrorAs(t, err, &cerr)
+	exists, err := bucket.Exists(ctx, corrupt)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	corruptions = nil
+
+	options.RecoverCorruptWAL = true
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the first block of the WAL is corrupt, so none of its writes can be read
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+	_, err = db.Get(ctx, []byte("key2"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+
+	require.Len(t, corruptions, 1)
+	assert.Equal(t, common.SectionBlock, corruptions[0].Section)
+	assert.Equal(t, corrupt, corruptions[0].Object)
+	exists, err = bucket.Exists(ctx, corrupt)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	quarantined := fmt.Sprintf("%s/wal/%020d.corrupt", dbPath, lastWAL)
+	exists, err = bucket.Exists(ctx, quarantined)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	// The fencing WAL written by Open takes the ID of the corrupt WAL
+	assert.Equal(t, lastWAL+2, db.state.NextWALID())
+}
+
+func TestWriterIsFencedByNewerWriter(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024)
+	options.FlushInterval = time.Hour
+	zombieOptions := options
+	var reported []*manifest.FencedError
+	zombieOptions.OnFenced = func(err *manifest.FencedError) {
+		reported = append(reported, err)
+	}
+	zombie, err := Open(ctx, dbPath, bucket, zombieOptions)
+	require.NoError(t, err)
+	defer zombie.Close()
+	require.NoError(t, zombie.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: false}))
+	require.NoError(t, zombie.FlushWAL(ctx))
+
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+
+	// The next WAL of the previous writer is taken by the fencing WAL
+	require.NoError(t, zombie.PutWithOptions(ctx, []byte("key2"), []byte("value2"), config.WriteOptions{AwaitDurable: false}))
+	err = zombie.FlushWAL(ctx)
+	assert.ErrorIs(t, err, common.ErrFenced)
+
+	// The error names the epochs and the distinct instances of both writers
+	var fenced *manifest.FencedError
+	require.True(t, errors.As(err, &fenced))
+	assert.Equal(t, "writer", fenced.Role)
+	assert.Equal(t, zombie.manifest.Epoch(), fenced.LocalEpoch)
+	assert.Equal(t, db.manifest.Epoch(), fenced.CurrentEpoch)
+	local, ok := fenced.LocalWriter.Get()
+	require.True(t, ok)
+	current, ok := fenced.CurrentWriter.Get()
+	require.True(t, ok)
+	assert.NotEmpty(t, local.InstanceID)
+	assert.NotEmpty(t, current.InstanceID)
+	assert.NotEqual(t, local.InstanceID, current.InstanceID)
+	assert.Contains(t, err.Error(), current.InstanceID)
+
+	// The fenced error is reported once
+	assert.ErrorIs(t, zombie.FlushWAL(ctx), common.ErrFenced)
+	require.Len(t, reported, 1)
+	assert.Equal(t, fenced, reported[0])
+
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+	_, err = db.Get(ctx, []byte("key2"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	require.NoError(t, db.Close())
+
+	// A WAL written with an older epoch after the WALs of the newer writer is
+	// skipped on recovery
+	builder := db.tableStore.TableBuilder()
+	builder.SetWriterEpoch(zombie.manifest.Epoch())
+	require.NoError(t, builder.AddValue([]byte("key3"), []byte("value3")))
+	sst, err := builder.Build()
+	require.NoError(t, err)
+	_, err = db.tableStore.WriteSST(ctx, sstable.NewIDWal(db.state.NextWALID()), sst)
+	require.NoError(t, err)
+
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Get(ctx, []byte("key3"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+}
+
+// lostResponseBucket stores the next lost uploads, but returns an error as if
+// the response to each of them was lost
+type lostResponseBucket struct {
+	objstore.Bucket
+	lost atomic.Int32
+}
+
+func (b *lostResponseBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if err := b.Bucket.Upload(ctx, name, r); err != nil {
+		return err
+	}
+	if b.lost.Add(-1) >= 0 {
+		return errors.New("connection reset by peer")
+	}
+	return nil
+}
+
+func TestWALWriteIsRetriedAfterLostResponse(t *testing.T) {
+	ctx := context.Background()
+	bucket := &lostResponseBucket{Bucket: objstore.NewInMemBucket()}
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024)
+	options.FlushInterval = time.Hour
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+
+	// The retry finds the WAL written by the attempt whose response was lost,
+	// which is not mistaken for the WAL of a newer writer
+	bucket.lost.Store(1)
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: false}))
+	require.NoError(t, db.FlushWAL(ctx))
+	assert.Equal(t, int32(0), bucket.lost.Load())
+	require.NoError(t, db.Health())
+	require.NoError(t, db.Close())
+
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+}
+
+func TestDisableWALFlushesMemtableToL0(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024*1024)
+	options.FlushInterval = time.Hour
+	options.DisableWAL = true
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	future, err := db.PutAsync(ctx, []byte("key1"), []byte("value1"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), db.CommittedSeq())
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, future.Wait(ctx))
+	assert.Equal(t, future.Seq(), db.CommittedSeq())
+	assert.Len(t, db.state.L0(), 1)
+
+	// Only the fencing WAL written by Open is in object storage
+	walIDs, err := db.tableStore.GetWalSSTList(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1}, walIDs)
+
+	// Writes which are not yet in L0 are lost when the writer goes away
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key2"), []byte("value2"), config.WriteOptions{AwaitDurable: false}))
+	restored, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer restored.Close()
+	val, err := restored.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+	_, err = restored.Get(ctx, []byte("key2"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+}
+
+func TestShouldPruneManifestVersions(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	options := testDBOptions(0, 1024)
+	options.ManifestRetainVersions = 2
+	db, err := Open(context.Background(), "/tmp/test_kv_store", bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Put(ctx, []byte(strconv.Itoa(i)), []byte(strconv.Itoa(i))))
+		require.NoError(t, db.FlushMemtableToL0(context.Background()))
+	}
+
+	require.Eventually(t, func() bool {
+		return db.Stats().ManifestVersions == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestStatsLevels(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, []LevelStats{{Level: "L0"}}, db.Stats().Levels)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("key%d", i)), []byte("value")))
+	}
+	require.NoError(t, db.Delete(ctx, []byte("key0")))
+	require.NoError(t, db.Delete(ctx, []byte("key9")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	levels := db.Stats().Levels
+	require.Len(t, levels, 1)
+	assert.Equal(t, LevelStats{Level: "L0", SSTables: 1, Entries: 5, Tombstones: 2}, levels[0])
+	assert.Equal(t, uint64(3), levels[0].LiveEntries())
+	assert.InDelta(t, 0.4, levels[0].DeleteRatio(), 0.001)
+
+	// Each sorted run is reported after L0
+	core := db.state.CoreStateSnapshot()
+	core.Compacted = []compaction.SortedRun{{ID: 3, SSTList: core.L0}}
+	levels = LevelStatsOf(core)
+	require.Len(t, levels, 2)
+	assert.Equal(t, LevelStats{Level: "SR 3", SSTables: 1, Entries: 5, Tombstones: 2}, levels[1])
+}
+
+func TestStatsObjectStore(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	options := testDBOptions(0, 1024)
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, options)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Put(ctx, []byte("key"), []byte("value")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	_, err = db.Get(ctx, []byte("key"))
+	require.NoError(t, err)
+
+	stats := db.Stats().ObjectStore
+	assert.Positive(t, stats[store.IOClassFlush].BytesWritten)
+	assert.Positive(t, stats[store.IOClassGet].BytesRead)
+	assert.Positive(t, stats[store.IOClassManifest].Requests)
+	assert.Zero(t, stats[store.IOClassScan].Requests)
+
+	// WALs written after the memtable was flushed are replayed when the DB is opened
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key2"), []byte("value2"), config.WriteOptions{AwaitDurable: false}))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.Close())
+
+	db, err = Open(ctx, "/tmp/test_kv_store", bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Positive(t, db.Stats().ObjectStore[store.IOClassRecovery].BytesRead)
+}
+
+func TestParanoidManifestWrites(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024)
+	options.ParanoidManifestWrites = true
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	before := db.Stats().ManifestVerifications
+	assert.Positive(t, before)
+
+	require.NoError(t, db.Put(ctx, []byte("key"), []byte("value")))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	stats := db.Stats()
+	assert.Greater(t, stats.ManifestVerifications, before)
+	assert.Positive(t, stats.ManifestVerificationLatency)
+}
+
+func TestOpenWithS3ExpressProfile(t *testing.T) {
+	ctx := context.Background()
+	options := config.DefaultDBOptionsForProfile(config.ProfileS3Express)
+	standard := config.DefaultDBOptions()
+	assert.Less(t, options.FlushInterval, standard.FlushInterval)
+	assert.Greater(t, options.BlockFetchParallelism, standard.BlockFetchParallelism)
+	assert.Less(t, options.ObjectStoreRetry.InitialBackoff, standard.ObjectStoreRetry.InitialBackoff)
+	assert.Less(t, options.ObjectStoreRetry.MaxBackoff, standard.ObjectStoreRetry.MaxBackoff)
+
+	// Options left zero default to the value of the profile
+	options.BlockFetchParallelism = 0
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, 16, db.opts.BlockFetchParallelism)
+
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+}
+
+func TestOpenWithIncompatibleOptions(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	db, err := Open(ctx, dbPath, bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Close())
+
+	options := testDBOptions(0, 1024)
+	options.CompressionCodec = compress.CodecSnappy
+	_, err = Open(ctx, dbPath, bucket, options)
+	assert.ErrorIs(t, err, common.ErrIncompatibleOptions)
+
+	var report *manifest.CompatibilityReport
+	require.True(t, errors.As(err, &report))
+	require.Len(t, report.Mismatches, 1)
+	assert.Equal(t, "compression_codec", report.Mismatches[0].Option)
+	assert.Equal(t, "None", report.Mismatches[0].Persisted)
+	assert.Equal(t, "Snappy", report.Mismatches[0].Supplied)
+	assert.True(t, report.Safe())
+
+	// compression is safe to migrate, SSTs written with the old codec remain readable
+	options.ForceMigrate = true
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+	require.NoError(t, db.Close())
+
+	stored, err := store.LoadStoredManifest(store.NewManifestStore(dbPath, bucket))
+	require.NoError(t, err)
+	storedManifest, _ := stored.Get()
+	persisted, ok := storedManifest.FormatOptions().Get()
+	require.True(t, ok)
+	assert.Equal(t, compress.CodecSnappy, persisted.CompressionCodec)
+}
+
+func TestOpenWithUnsafeFormatMismatch(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	manifestStore := store.NewManifestStore(dbPath, bucket)
+	sm, err := store.NewStoredManifest(manifestStore, state.NewCoreDBState())
+	require.NoError(t, err)
+	sm.SetFormatOptions(manifest.FormatOptions{
+		Comparator:         "reverse",
+		BlockFormatVersion: 7,
+		CompressionCodec:   compress.CodecZstd,
+	})
+	_, err = store.NewWriterFenceableManifest(sm)
+	require.NoError(t, err)
+
+	options := testDBOptions(0, 1024)
+	options.ForceMigrate = true
+	_, err = Open(context.Background(), dbPath, bucket, options)
+
+	var report *manifest.CompatibilityReport
+	require.True(t, errors.As(err, &report))
+	assert.False(t, report.Safe())
+	require.Len(t, report.Mismatches, 3)
+	assert.Equal(t, "comparator", report.Mismatches[0].Option)
+	assert.Equal(t, "block_format_version", report.Mismatches[1].Option)
+	assert.Equal(t, "compression_codec", report.Mismatches[2].Option)
+
+	// the persisted options are left untouched when open is rejected
+	stored, err := store.LoadStoredManifest(manifestStore)
+	require.NoError(t, err)
+	storedManifest, _ := stored.Get()
+	persisted, _ := storedManifest.FormatOptions().Get()
+	assert.Equal(t, "reverse", persisted.Comparator)
+}
+
+func TestOpenUpgradesBlockFormatVersion(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	manifestStore := store.NewManifestStore(dbPath, bucket)
+	sm, err := store.NewStoredManifest(manifestStore, state.NewCoreDBState())
+	require.NoError(t, err)
+	sm.SetFormatOptions(manifest.FormatOptions{
+		Comparator:         manifest.BytewiseComparator,
+		BlockFormatVersion: block.FormatVersion - 1,
+		CompressionCodec:   compress.CodecNone,
+	})
+	_, err = store.NewWriterFenceableManifest(sm)
+	require.NoError(t, err)
+
+	// Blocks of the previous version remain readable, so the DB is upgraded
+	// without ForceMigrate
+	db, err := Open(context.Background(), dbPath, bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	stored, err := store.LoadStoredManifest(manifestStore)
+	require.NoError(t, err)
+	storedManifest, _ := stored.Get()
+	persisted, _ := storedManifest.FormatOptions().Get()
+	assert.Equal(t, block.FormatVersion, persisted.BlockFormatVersion)
 }
 
 func TestShouldReadUncommittedIfReadLevelUncommitted(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, testDBOptions(0, 1024))
+	db, err := Open(context.Background(), dbPath, bucket, testDBOptions(0, 1024))
 	require.NoError(t, err)
 	defer db.Close()
 
 	// we do not wait till WAL is flushed to object store and memtable
-	db.PutWithOptions([]byte("foo"), []byte("bar"), config.WriteOptions{AwaitDurable: false})
+	require.NoError(t, db.PutWithOptions(ctx, []byte("foo"), []byte("bar"), config.WriteOptions{AwaitDurable: false}))
 
 	value, err := db.GetWithOptions(context.Background(), []byte("foo"), config.ReadOptions{ReadLevel: config.Uncommitted})
 	require.NoError(t, err)
@@ -332,14 +1325,15 @@ func TestShouldReadUncommittedIfReadLevelUncommitted(t *testing.T) {
 }
 
 func TestShouldReadOnlyCommittedData(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, testDBOptions(0, 1024))
+	db, err := Open(context.Background(), dbPath, bucket, testDBOptions(0, 1024))
 	require.NoError(t, err)
 	defer db.Close()
 
-	db.Put([]byte("foo"), []byte("bar"))
-	db.PutWithOptions([]byte("foo"), []byte("bla"), config.WriteOptions{AwaitDurable: false})
+	require.NoError(t, db.Put(ctx, []byte("foo"), []byte("bar")))
+	require.NoError(t, db.PutWithOptions(ctx, []byte("foo"), []byte("bla"), config.WriteOptions{AwaitDurable: false}))
 
 	value, err := db.Get(context.Background(), []byte("foo"))
 	require.NoError(t, err)
@@ -351,14 +1345,15 @@ func TestShouldReadOnlyCommittedData(t *testing.T) {
 }
 
 func TestShouldDeleteWithoutAwaitingFlush(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, testDBOptions(0, 1024))
+	db, err := Open(context.Background(), dbPath, bucket, testDBOptions(0, 1024))
 	require.NoError(t, err)
 	defer db.Close()
 
-	db.Put([]byte("foo"), []byte("bar"))
-	db.DeleteWithOptions([]byte("foo"), config.WriteOptions{AwaitDurable: false})
+	require.NoError(t, db.Put(ctx, []byte("foo"), []byte("bar")))
+	require.NoError(t, db.DeleteWithOptions(ctx, []byte("foo"), config.WriteOptions{AwaitDurable: false}))
 
 	value, err := db.Get(context.Background(), []byte("foo"))
 	require.NoError(t, err)
@@ -369,24 +1364,26 @@ func TestShouldDeleteWithoutAwaitingFlush(t *testing.T) {
 }
 
 func TestSnapshotState(t *testing.T) {
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, testDBOptions(0, 128))
+	db, err := Open(context.Background(), dbPath, bucket, testDBOptions(0, 128))
 	require.NoError(t, err)
 
 	// write a few keys that will result in memtable flushes
 	key1, value1 := repeatedChar('a', 32), repeatedChar('b', 96)
 	key2, value2 := repeatedChar('c', 32), repeatedChar('d', 96)
-	db.Put(key1, value1)
-	db.Put(key2, value2)
+	require.NoError(t, db.Put(ctx, key1, value1))
+	require.NoError(t, db.Put(ctx, key2, value2))
 	db.Close()
 
-	db, err = OpenWithOptions(context.Background(), dbPath, bucket, testDBOptions(0, 128))
+	db, err = Open(context.Background(), dbPath, bucket, testDBOptions(0, 128))
 	require.NoError(t, err)
 	defer db.Close()
 	snapshot := db.state.Snapshot()
-	assert.Equal(t, uint64(2), snapshot.Core.LastCompactedWalSSTID.Load())
-	assert.Equal(t, uint64(3), snapshot.Core.NextWalSstID.Load())
+	// WALs 1 and 4 are the fencing WALs written by each Open
+	assert.Equal(t, uint64(3), snapshot.Core.LastCompactedWalSSTID.Load())
+	assert.Equal(t, uint64(5), snapshot.Core.NextWalSstID.Load())
 	assert.Equal(t, 2, len(snapshot.Core.L0))
 
 	val1, err := db.Get(context.Background(), key1)
@@ -426,9 +1423,10 @@ func TestShouldReadFromCompactedDBNoFilters(t *testing.T) {
 
 func doTestShouldReadCompactedDB(t *testing.T, options config.DBOptions) {
 	t.Helper()
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, options)
+	db, err := Open(context.Background(), dbPath, bucket, options)
 	require.NoError(t, err)
 	defer db.Close()
 
@@ -440,8 +1438,8 @@ func doTestShouldReadCompactedDB(t *testing.T, options config.DBOptions) {
 
 	// write enough to fill up a few l0 SSTs
 	for i := 0; i < 4; i++ {
-		db.Put(repeatedChar(rune('a'+i), 32), bytes.Repeat([]byte{byte(1 + i)}, 32))
-		db.Put(repeatedChar(rune('m'+i), 32), bytes.Repeat([]byte{byte(13 + i)}, 32))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 32), bytes.Repeat([]byte{byte(1 + i)}, 32)))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('m'+i), 32), bytes.Repeat([]byte{byte(13 + i)}, 32)))
 	}
 	waitForManifestCondition(storedManifest, time.Second*10, func(state *state.CoreStateSnapshot) bool {
 		return state.L0LastCompacted.IsPresent() && len(state.L0) == 0
@@ -449,16 +1447,16 @@ func doTestShouldReadCompactedDB(t *testing.T, options config.DBOptions) {
 
 	// write more l0s and wait for compaction
 	for i := 0; i < 4; i++ {
-		db.Put(repeatedChar(rune('f'+i), 32), bytes.Repeat([]byte{byte(6 + i)}, 32))
-		db.Put(repeatedChar(rune('s'+i), 32), bytes.Repeat([]byte{byte(19 + i)}, 32))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('f'+i), 32), bytes.Repeat([]byte{byte(6 + i)}, 32)))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('s'+i), 32), bytes.Repeat([]byte{byte(19 + i)}, 32)))
 	}
 	waitForManifestCondition(storedManifest, time.Second*10, func(state *state.CoreStateSnapshot) bool {
 		return state.L0LastCompacted.IsPresent() && len(state.L0) == 0
 	})
 
 	// write another l0
-	db.Put(repeatedChar('a', 32), bytes.Repeat([]byte{128}, 32))
-	db.Put(repeatedChar('m', 32), bytes.Repeat([]byte{129}, 32))
+	require.NoError(t, db.Put(ctx, repeatedChar('a', 32), bytes.Repeat([]byte{128}, 32)))
+	require.NoError(t, db.Put(ctx, repeatedChar('m', 32), bytes.Repeat([]byte{129}, 32)))
 
 	val, err := db.Get(context.Background(), repeatedChar('a', 32))
 	require.NoError(t, err)
@@ -491,9 +1489,10 @@ func doTestShouldReadCompactedDB(t *testing.T, options config.DBOptions) {
 
 func doTestDeleteAndWaitForCompaction(t *testing.T, options config.DBOptions) {
 	t.Helper()
+	ctx := context.Background()
 	bucket := objstore.NewInMemBucket()
 	dbPath := "/tmp/test_kv_store"
-	db, err := OpenWithOptions(context.Background(), dbPath, bucket, options)
+	db, err := Open(context.Background(), dbPath, bucket, options)
 	require.NoError(t, err)
 	defer db.Close()
 
@@ -505,8 +1504,8 @@ func doTestDeleteAndWaitForCompaction(t *testing.T, options config.DBOptions) {
 
 	// write enough to fill up a few l0 SSTs
 	for i := 0; i < 4; i++ {
-		db.Put(repeatedChar(rune('a'+i), 32), bytes.Repeat([]byte{byte(1 + i)}, 32))
-		db.Put(repeatedChar(rune('m'+i), 32), bytes.Repeat([]byte{byte(13 + i)}, 32))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 32), bytes.Repeat([]byte{byte(1 + i)}, 32)))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('m'+i), 32), bytes.Repeat([]byte{byte(13 + i)}, 32)))
 	}
 	waitForManifestCondition(storedManifest, time.Second*10, func(state *state.CoreStateSnapshot) bool {
 		return state.L0LastCompacted.IsPresent() && len(state.L0) == 0
@@ -514,13 +1513,13 @@ func doTestDeleteAndWaitForCompaction(t *testing.T, options config.DBOptions) {
 
 	// Delete existing keys
 	for i := 0; i < 4; i++ {
-		db.Delete(repeatedChar(rune('a'+i), 32))
-		db.Delete(repeatedChar(rune('m'+i), 32))
+		require.NoError(t, db.Delete(ctx, repeatedChar(rune('a'+i), 32)))
+		require.NoError(t, db.Delete(ctx, repeatedChar(rune('m'+i), 32)))
 	}
 	//Add new keys
 	for i := 0; i < 2; i++ {
-		db.Put(repeatedChar(rune('f'+i), 32), bytes.Repeat([]byte{byte(6 + i)}, 32))
-		db.Put(repeatedChar(rune('s'+i), 32), bytes.Repeat([]byte{byte(19 + i)}, 32))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('f'+i), 32), bytes.Repeat([]byte{byte(6 + i)}, 32)))
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('s'+i), 32), bytes.Repeat([]byte{byte(19 + i)}, 32)))
 	}
 	waitForManifestCondition(storedManifest, time.Second*10, func(state *state.CoreStateSnapshot) bool {
 		return state.L0LastCompacted.IsPresent() && len(state.L0) == 0
@@ -562,6 +1561,180 @@ func waitForManifestCondition(
 	panic("manifest condition took longer than timeout")
 }
 
+func TestMaxWALBytesFlushesWAL(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024*1024)
+	options.FlushInterval = time.Hour
+	options.MaxWALBytes = 1024
+	db, err := Open(context.Background(), "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// A WAL below the threshold waits for the FlushInterval. WAL 1 is the fencing
+	// WAL written by Open
+	require.NoError(t, db.PutWithOptions(ctx, []byte("small"), []byte("value"), config.WriteOptions{AwaitDurable: false}))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, uint64(2), db.state.NextWALID())
+
+	// A WAL which reaches the threshold is flushed without waiting
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, db.Put(ctx, []byte("large"), repeatedChar('a', 2048)))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WAL was not flushed once it reached MaxWALBytes")
+	}
+	assert.Equal(t, uint64(3), db.state.NextWALID())
+}
+
+// expiringBucket fails every upload once its credentials expire
+type expiringBucket struct {
+	objstore.Bucket
+	expired atomic.Bool
+}
+
+func (b *expiringBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if b.expired.Load() {
+		return errors.New("credentials expired")
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+func TestRotateBucket(t *testing.T) {
+	ctx := context.Background()
+	storage := objstore.NewInMemBucket()
+	bucket := &expiringBucket{Bucket: storage}
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.FlushWAL(ctx))
+	bucket.expired.Store(true)
+
+	// Writes continue with new credentials for the same storage
+	previous, err := db.RotateBucket(ctx, &expiringBucket{Bucket: storage})
+	require.NoError(t, err)
+	assert.Same(t, bucket, previous)
+
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	for _, key := range []string{"key1", "key2"} {
+		_, err := db.Get(ctx, []byte(key))
+		require.NoError(t, err)
+	}
+}
+
+func TestOpenWithObjectStore(t *testing.T) {
+	ctx := context.Background()
+	objectStore := store.NewBucketObjectStore(objstore.NewInMemBucket())
+	db, err := OpenWithObjectStore(ctx, "/tmp/test_kv_store", objectStore, testDBOptions(0, 1024))
+	require.NoError(t, err)
+
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	// There is no bucket to rotate
+	_, err = db.RotateBucket(ctx, objstore.NewInMemBucket())
+	assert.ErrorIs(t, err, common.ErrInvalidOptions)
+	require.NoError(t, db.Close())
+
+	db, err = OpenWithObjectStore(ctx, "/tmp/test_kv_store", objectStore, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+}
+
+// failingManifestWrites fails the conditional manifest write with the index
+// failAt, counted from one, of the ObjectStore
+type failingManifestWrites struct {
+	store.ObjectStore
+	writes atomic.Int32
+	failAt int32
+}
+
+func (f *failingManifestWrites) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	if strings.Contains(path, "manifest/") && f.writes.Add(1) == f.failAt {
+		return errors.New("injected manifest write failure")
+	}
+	return f.ObjectStore.PutIfNotExists(ctx, path, r)
+}
+
+func TestOpenStopsTasksWhenCompactorFails(t *testing.T) {
+	ctx := context.Background()
+	memStore := storetest.NewMemObjectStore()
+	options := testDBOptionsCompactor(0, 1024, config.DefaultCompactorOptions())
+	options.FlushInterval = time.Millisecond
+	options.ManifestPollInterval = time.Millisecond
+
+	// The manifest is created and then fenced by the writer, before the compactor
+	// fences it with the third manifest write
+	objectStore := &failingManifestWrites{ObjectStore: memStore, failAt: 3}
+	_, err := OpenWithObjectStore(ctx, "/tmp/test_kv_store", objectStore, options)
+	require.ErrorContains(t, err, "while creating compactor")
+
+	// No background task of the DB which failed to open is left to write
+	requests := memStore.Requests(storetest.OpAny)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, requests, memStore.Requests(storetest.OpAny))
+}
+
+func TestOpenWithLayout(t *testing.T) {
+	ctx := context.Background()
+	objectStore := store.NewBucketObjectStore(objstore.NewInMemBucket())
+	options := testDBOptions(0, 1024)
+	options.Layout = config.ObjectStoreLayout{
+		WALPrefix:       "data/wal",
+		CompactedPrefix: "data/sst/",
+		ManifestPrefix:  "meta",
+	}
+	db, err := OpenWithObjectStore(ctx, "/tmp/test_kv_store", objectStore, options)
+	require.NoError(t, err)
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Close())
+
+	for _, prefix := range []string{"data/wal", "data/sst", "meta"} {
+		objects, err := objectStore.List(ctx, "/tmp/test_kv_store/"+prefix)
+		require.NoError(t, err)
+		assert.NotEmpty(t, objects, prefix)
+	}
+	for _, prefix := range []string{"wal", "compacted", "manifest"} {
+		objects, err := objectStore.List(ctx, "/tmp/test_kv_store/"+prefix)
+		require.NoError(t, err)
+		assert.Empty(t, objects, prefix)
+	}
+
+	// The DB can't be opened with another layout
+	_, err = OpenWithObjectStore(ctx, "/tmp/test_kv_store", objectStore, testDBOptions(0, 1024))
+	assert.ErrorIs(t, err, common.ErrIncompatibleLayout)
+
+	// Another DB in the same bucket is unaffected
+	other, err := OpenWithObjectStore(ctx, "/tmp/other_kv_store", objectStore, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+
+	db, err = OpenWithObjectStore(ctx, "/tmp/test_kv_store", objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+
+	// Overlapping prefixes are rejected
+	options.Layout.ManifestPrefix = "data"
+	_, err = OpenWithObjectStore(ctx, "/tmp/new_kv_store", objectStore, options)
+	assert.ErrorIs(t, err, common.ErrInvalidOptions)
+}
+
 func testDBOptions(minFilterKeys uint32, l0SSTSizeBytes uint64) config.DBOptions {
 	return config.DBOptions{
 		FlushInterval:        100 * time.Millisecond,
@@ -0,0 +1,323 @@
+package slatedb
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// TODO: Parquet exports need a Parquet encoder, which is not yet a
+//  dependency. It should be written as another encoding of the same streaming
+//  scan, flushing a row group every BufferBytes, so it keeps the same bound.
+
+// exportMagic starts every stream written by DB.Export. The last byte is the
+// version of the format.
+var exportMagic = []byte("SLATEEX\x01")
+
+const (
+	// defaultExportBufferBytes is the default of ExportOptions.BufferBytes and
+	// ImportOptions.BufferBytes
+	defaultExportBufferBytes = 1 << 20
+
+	// defaultImportBatchBytes is the default of ImportOptions.BatchBytes
+	defaultImportBatchBytes = 1 << 20
+
+	// maxExportRecordBytes bounds the key and value of a record read by
+	// DB.Import, so a corrupt length cannot allocate an unbounded buffer
+	maxExportRecordBytes = 1 << 30
+)
+
+// ExportOptions are the options of DB.Export
+type ExportOptions struct {
+	// The range [Start, End) of keys exported. A nil Start or End leaves the
+	// range unbounded.
+	Start []byte
+	End   []byte
+
+	// The size of the buffer between the scan and the writer. Defaults to 1 MiB.
+	BufferBytes int
+
+	// The number of blocks fetched ahead of the block being read from each
+	// SSTable, see config.ScanOptions
+	PrefetchBlocks int
+}
+
+// ImportOptions are the options of DB.Import
+type ImportOptions struct {
+	// The size of the buffer between the reader and the DB. Defaults to 1 MiB.
+	BufferBytes int
+
+	// The total size of the keys and values of each WriteBatch written to the
+	// DB. Defaults to 1 MiB.
+	BatchBytes int
+}
+
+// ExportStats are the number of keys and bytes of a stream written by
+// DB.Export or read by DB.Import
+type ExportStats struct {
+	Keys  int64
+	Bytes int64
+}
+
+// Export writes every key and value in the range of the options to w, as of the
+// latest manifest version once the memtable was flushed to L0, such that every
+// write completed before Export was called is exported. DB.Import writes the
+// stream to another DB.
+//
+// The keys are streamed from a pinned scan to w, so the size of the DB does not
+// affect the memory used. The peak memory of an export is about BufferBytes, plus
+// one block and PrefetchBlocks blocks for each SSTable in L0 and each sorted run,
+// plus the index of each of those SSTables. A slow w applies backpressure, as the
+// scan only advances when the buffer is written to w.
+func (db *DB) Export(ctx context.Context, w io.Writer, opts ExportOptions) (ExportStats, error) {
+	if opts.BufferBytes <= 0 {
+		opts.BufferBytes = defaultExportBufferBytes
+	}
+	if err := db.flushWritesToL0(ctx); err != nil {
+		return ExportStats{}, fmt.Errorf("while flushing memtable before export: %w", err)
+	}
+
+	scan, err := db.Scan(ctx, opts.Start, opts.End, config.ScanOptions{
+		ReadLevel:      config.Flushed,
+		PrefetchBlocks: opts.PrefetchBlocks,
+	})
+	if err != nil {
+		return ExportStats{}, fmt.Errorf("while starting scan: %w", err)
+	}
+	defer func() { _ = scan.Close() }()
+
+	enc := newExportEncoder(w, opts.BufferBytes)
+	enc.write(exportMagic)
+	for {
+		if err := ctx.Err(); err != nil {
+			return enc.stats, err
+		}
+		kv, ok := scan.Next(ctx)
+		if !ok {
+			break
+		}
+		enc.writeBytes(kv.Key)
+		enc.writeBytes(kv.Value)
+		enc.stats.Keys++
+		if enc.err != nil {
+			return enc.stats, fmt.Errorf("while writing export: %w", enc.err)
+		}
+	}
+	if err := scan.Close(); err != nil {
+		return enc.stats, fmt.Errorf("while scanning: %w", err)
+	}
+
+	// A zero length key ends the stream, followed by the number of keys and the
+	// checksum of the stream, which DB.Import verifies to detect truncation
+	enc.writeUvarint(0)
+	enc.writeUvarint(uint64(enc.stats.Keys))
+	enc.write(binary.BigEndian.AppendUint32(nil, enc.crc.Sum32()))
+	if err := enc.flush(); err != nil {
+		return enc.stats, fmt.Errorf("while writing export: %w", err)
+	}
+	return enc.stats, nil
+}
+
+// Import writes every key and value of a stream written by DB.Export to the DB.
+// The keys are written in batches of BatchBytes, each of which is awaited until
+// it is durable, so the import advances only as fast as the DB flushes. The peak
+// memory of an import is about BufferBytes plus twice BatchBytes, regardless of
+// the size of the stream, on top of the memtables of the DB, which are bounded by
+// DBOptions.MaxImmutableMemtables.
+//
+// Returns common.ErrInvalidExport if the stream is not an export, or if it is
+// truncated or corrupt. The batches imported before the error remain in the DB,
+// so a failed import may be repeated from the start of the stream.
+func (db *DB) Import(ctx context.Context, r io.Reader, opts ImportOptions) (ExportStats, error) {
+	if opts.BufferBytes <= 0 {
+		opts.BufferBytes = defaultExportBufferBytes
+	}
+	if opts.BatchBytes <= 0 {
+		opts.BatchBytes = defaultImportBatchBytes
+	}
+	if err := db.background.checkWritable(); err != nil {
+		return ExportStats{}, err
+	}
+
+	dec := newExportDecoder(r, opts.BufferBytes)
+	magic := dec.read(len(exportMagic))
+	if dec.err != nil || string(magic) != string(exportMagic) {
+		return dec.stats, fmt.Errorf("%w: missing export header", common.ErrInvalidExport)
+	}
+
+	durable := config.WriteOptions{AwaitDurable: true}
+	batch := NewWriteBatch()
+	var batchBytes int
+	for {
+		if err := ctx.Err(); err != nil {
+			return dec.stats, err
+		}
+		key := dec.readBytes()
+		if dec.err != nil {
+			return dec.stats, dec.error()
+		}
+		if len(key) == 0 {
+			break
+		}
+		value := dec.readBytes()
+		if dec.err != nil {
+			return dec.stats, dec.error()
+		}
+
+		batch.Put(key, value)
+		dec.stats.Keys++
+		batchBytes += len(key) + len(value)
+		if batchBytes >= opts.BatchBytes {
+			if err := db.Write(ctx, batch, durable); err != nil {
+				return dec.stats, err
+			}
+			batch, batchBytes = NewWriteBatch(), 0
+		}
+	}
+	if err := db.Write(ctx, batch, durable); err != nil {
+		return dec.stats, err
+	}
+
+	keys := dec.readUvarint()
+	sum := dec.crc.Sum32()
+	checksum := dec.read(4)
+	if dec.err != nil {
+		return dec.stats, dec.error()
+	}
+	if keys != uint64(dec.stats.Keys) {
+		return dec.stats, fmt.Errorf("%w: export holds %d keys, but %d were read",
+			common.ErrInvalidExport, keys, dec.stats.Keys)
+	}
+	if binary.BigEndian.Uint32(checksum) != sum {
+		return dec.stats, fmt.Errorf("%w: %w", common.ErrInvalidExport, common.ErrChecksumMismatch)
+	}
+	return dec.stats, nil
+}
+
+// flushWritesToL0 flushes the WAL and memtable to L0 if they contain writes, along
+// with the immutable memtables still waiting for the memtable flush task
+func (db *DB) flushWritesToL0(ctx context.Context) error {
+	if err := db.FlushWAL(ctx); err != nil {
+		return err
+	}
+	if db.state.Memtable().Size() > 0 {
+		return db.FlushMemtableToL0(ctx)
+	}
+	flusher := MemtableFlusher{
+		db:       db,
+		manifest: db.manifest,
+		log:      db.opts.Log,
+	}
+	return flusher.flushImmMemtablesToL0(ctx)
+}
+
+// exportEncoder writes the records of an export through a buffer of a fixed
+// size, keeping the first error
+type exportEncoder struct {
+	w     *bufio.Writer
+	crc   hash.Hash32
+	stats ExportStats
+	err   error
+}
+
+func newExportEncoder(w io.Writer, size int) *exportEncoder {
+	return &exportEncoder{w: bufio.NewWriterSize(w, size), crc: crc32.NewIEEE()}
+}
+
+func (e *exportEncoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+	_, _ = e.crc.Write(b)
+	e.stats.Bytes += int64(len(b))
+}
+
+func (e *exportEncoder) writeUvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	e.write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func (e *exportEncoder) writeBytes(b []byte) {
+	e.writeUvarint(uint64(len(b)))
+	e.write(b)
+}
+
+func (e *exportEncoder) flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// exportDecoder reads the records of an export, keeping the first error
+type exportDecoder struct {
+	r     *bufio.Reader
+	crc   hash.Hash32
+	stats ExportStats
+	err   error
+}
+
+func newExportDecoder(r io.Reader, size int) *exportDecoder {
+	return &exportDecoder{r: bufio.NewReaderSize(r, size), crc: crc32.NewIEEE()}
+}
+
+func (d *exportDecoder) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, b)
+	_, _ = d.crc.Write(b)
+	d.stats.Bytes += int64(n)
+	return b
+}
+
+func (d *exportDecoder) readUvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var buf []byte
+	for len(buf) < binary.MaxVarintLen64 {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			d.err = err
+			return 0
+		}
+		buf = append(buf, b)
+		if b < 0x80 {
+			break
+		}
+	}
+	_, _ = d.crc.Write(buf)
+	d.stats.Bytes += int64(len(buf))
+	v, n := binary.Uvarint(buf)
+	if n <= 0 {
+		d.err = errors.New("invalid length")
+	}
+	return v
+}
+
+func (d *exportDecoder) readBytes() []byte {
+	n := d.readUvarint()
+	if d.err == nil && n > maxExportRecordBytes {
+		d.err = fmt.Errorf("length %d exceeds the maximum of %d", n, maxExportRecordBytes)
+	}
+	return d.read(int(n))
+}
+
+// error returns the error of the decoder as common.ErrInvalidExport
+func (d *exportDecoder) error() error {
+	if errors.Is(d.err, io.EOF) || errors.Is(d.err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated after %d keys", common.ErrInvalidExport, d.stats.Keys)
+	}
+	return fmt.Errorf("%w: %w", common.ErrInvalidExport, d.err)
+}
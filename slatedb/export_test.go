@@ -0,0 +1,191 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"github.com/thanos-io/objstore/providers/filesystem"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer src.Close()
+
+	batch := NewWriteBatch()
+	for i := 0; i < 100; i++ {
+		batch.Put([]byte(fmt.Sprintf("key-%03d", i)), []byte(fmt.Sprintf("value-%03d", i)))
+	}
+	batch.Delete([]byte("key-050"))
+	require.NoError(t, src.Write(ctx, batch, config.DefaultWriteOptions()))
+
+	// Writes still in the memtable are exported
+	var buf bytes.Buffer
+	exported, err := src.Export(ctx, &buf, ExportOptions{Start: []byte("key-010"), End: []byte("key-090")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(79), exported.Keys)
+	assert.Equal(t, int64(buf.Len()), exported.Bytes)
+
+	dst, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer dst.Close()
+
+	imported, err := dst.Import(ctx, bytes.NewReader(buf.Bytes()), ImportOptions{BatchBytes: 100})
+	require.NoError(t, err)
+	assert.Equal(t, exported, imported)
+
+	val, err := dst.Get(ctx, []byte("key-010"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value-010"), val)
+	for _, key := range []string{"key-009", "key-050", "key-090"} {
+		_, err = dst.Get(ctx, []byte(key))
+		assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	}
+
+	t.Run("Truncated", func(t *testing.T) {
+		_, err := dst.Import(ctx, bytes.NewReader(buf.Bytes()[:buf.Len()-1]), ImportOptions{})
+		assert.ErrorIs(t, err, common.ErrInvalidExport)
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		corrupt := bytes.Clone(buf.Bytes())
+		corrupt[len(exportMagic)+2] ^= 0xff
+		_, err := dst.Import(ctx, bytes.NewReader(corrupt), ImportOptions{})
+		assert.ErrorIs(t, err, common.ErrInvalidExport)
+	})
+
+	t.Run("Not An Export", func(t *testing.T) {
+		_, err := dst.Import(ctx, bytes.NewReader([]byte("key,value\n")), ImportOptions{})
+		assert.ErrorIs(t, err, common.ErrInvalidExport)
+	})
+}
+
+// heapSampler records the peak growth of the live heap above the live heap when
+// the sampler was created, each time it is called. The live heap is the heap
+// marked by the last GC, so garbage allocated while the GC runs behind, as it
+// does on a loaded machine, isn't counted. The GC runs often enough that the
+// live heap follows the heap held by the export or import.
+type heapSampler struct {
+	baseline uint64
+	peak     uint64
+}
+
+func newHeapSampler(t *testing.T) *heapSampler {
+	gcPercent := debug.SetGCPercent(10)
+	t.Cleanup(func() { debug.SetGCPercent(gcPercent) })
+	runtime.GC()
+	return &heapSampler{baseline: liveHeap()}
+}
+
+// liveHeap returns the bytes of the heap marked live by the last GC
+func liveHeap() uint64 {
+	sample := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
+
+func (s *heapSampler) sample() {
+	if live := liveHeap(); live > s.baseline {
+		s.peak = max(s.peak, live-s.baseline)
+	}
+}
+
+type sampledWriter struct {
+	io.Writer
+	sampler *heapSampler
+}
+
+func (w sampledWriter) Write(p []byte) (int, error) {
+	w.sampler.sample()
+	return w.Writer.Write(p)
+}
+
+type sampledReader struct {
+	io.Reader
+	sampler *heapSampler
+}
+
+func (r sampledReader) Read(p []byte) (int, error) {
+	r.sampler.sample()
+	return r.Reader.Read(p)
+}
+
+// TestExportImportPeakMemory exports and imports a DB 4 times larger than the heap
+// growth each is allowed, which would fail if either held the stream in memory.
+// The DBs are stored on disk, so the heap only holds what the DBs keep in memory.
+func TestExportImportPeakMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes 64 MiB to disk")
+	}
+	const (
+		dataBytes  = 64 << 20
+		limitBytes = dataBytes / 4
+		valueBytes = 4 << 10
+	)
+	ctx := context.Background()
+	newDB := func() *DB {
+		bucket, err := filesystem.NewBucket(t.TempDir())
+		require.NoError(t, err)
+		options := testDBOptions(0, 1<<20)
+		options.FlushInterval = 10 * time.Millisecond
+		options.MaxImmutableMemtables = 2
+		db, err := Open(ctx, "/tmp/test_kv_store", bucket, options)
+		require.NoError(t, err)
+		return db
+	}
+
+	src := newDB()
+	value := bytes.Repeat([]byte("v"), valueBytes)
+	for i := 0; i < dataBytes/valueBytes; i += 64 {
+		batch := NewWriteBatch()
+		for j := i; j < i+64; j++ {
+			batch.Put([]byte(fmt.Sprintf("key-%06d", j)), value)
+		}
+		require.NoError(t, src.Write(ctx, batch, config.WriteOptions{AwaitDurable: true}))
+	}
+	// The memtables are released before the baseline of the export is taken
+	require.NoError(t, src.flushWritesToL0(ctx))
+
+	file, err := filesystem.NewBucket(t.TempDir())
+	require.NoError(t, err)
+	opts := ExportOptions{BufferBytes: 64 << 10}
+
+	sampler := newHeapSampler(t)
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := src.Export(ctx, sampledWriter{Writer: pw, sampler: sampler}, opts)
+		_ = pw.CloseWithError(err)
+	}()
+	require.NoError(t, file.Upload(ctx, "export", pr))
+	t.Logf("export peak heap growth: %d bytes", sampler.peak)
+	assert.Less(t, sampler.peak, uint64(limitBytes))
+	require.NoError(t, src.Close())
+
+	dst := newDB()
+	defer dst.Close()
+	r, err := file.Get(ctx, "export")
+	require.NoError(t, err)
+	defer r.Close()
+
+	sampler = newHeapSampler(t)
+	stats, err := dst.Import(ctx, sampledReader{Reader: r, sampler: sampler},
+		ImportOptions{BufferBytes: 64 << 10, BatchBytes: 256 << 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(dataBytes/valueBytes), stats.Keys)
+	t.Logf("import peak heap growth: %d bytes", sampler.peak)
+	assert.Less(t, sampler.peak, uint64(limitBytes))
+}
@@ -0,0 +1,194 @@
+package filter
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/slatedb/slatedb-go/internal/sstable/bloom"
+)
+
+// BloomPolicyName is the name of the Policy returned by NewBloomPolicy. SSTables
+// which do not record a filter policy were written with the bloom filter.
+const BloomPolicyName = "slatedb.BloomFilter"
+
+// Policy creates the filter stored in each SSTable and decides using that filter
+// whether a key may be present in the SSTable, such that reads of keys which are
+// not present can skip the SSTable without reading its blocks.
+//
+// The name of the Policy is recorded in each SSTable it creates a filter for. A filter
+// is only consulted by a Policy with the same name, so the name must change whenever
+// the format of the filter produced by CreateFilter changes.
+type Policy interface {
+	// Name returns the name which identifies the filters created by this Policy
+	Name() string
+
+	// CreateFilter returns a filter which contains the provided keys. Keys are
+	// provided in sorted order and the returned filter must not reference them.
+	CreateFilter(keys [][]byte) []byte
+
+	// KeyMayMatch returns false if the key is definitely not contained in the
+	// filter, or true if the key may be contained in the filter.
+	KeyMayMatch(key []byte, filter []byte) bool
+}
+
+// Builder creates a filter from the keys added to it in sorted order, the same
+// filter Policy.CreateFilter creates from the keys
+type Builder interface {
+	// Add adds the key to the filter. The Builder must not reference the key.
+	Add(key []byte)
+
+	// Build returns the filter of the keys added
+	Build() []byte
+}
+
+// NewBuilder returns a Builder of the filters created by the Policy. The builtin
+// policies only keep the hashes of the keys added until the filter is built, while
+// a Policy which does not implement NewBuilder is provided a copy of each key.
+func NewBuilder(p Policy) Builder {
+	if b, ok := p.(interface{ NewBuilder() Builder }); ok {
+		return b.NewBuilder()
+	}
+	return &keysBuilder{policy: p}
+}
+
+type keysBuilder struct {
+	policy Policy
+	keys   [][]byte
+}
+
+func (b *keysBuilder) Add(key []byte) {
+	b.keys = append(b.keys, bytes.Clone(key))
+}
+
+func (b *keysBuilder) Build() []byte {
+	return b.policy.CreateFilter(b.keys)
+}
+
+// NewBloomPolicy returns the default Policy which creates a bloom filter using
+// bitsPerKey bits for each key. The bitsPerKey is only used when creating filters,
+// so any bloom Policy can read filters created with a different bitsPerKey.
+func NewBloomPolicy(bitsPerKey uint32) Policy {
+	return bloomPolicy{bitsPerKey: bitsPerKey}
+}
+
+type bloomPolicy struct {
+	bitsPerKey uint32
+}
+
+func (p bloomPolicy) BitsPerKey() uint32 {
+	return p.bitsPerKey
+}
+
+func (p bloomPolicy) Name() string {
+	return BloomPolicyName
+}
+
+func (p bloomPolicy) CreateFilter(keys [][]byte) []byte {
+	return buildFilter(p.NewBuilder(), keys)
+}
+
+func (p bloomPolicy) NewBuilder() Builder {
+	return bloomBuilder{builder: bloom.NewBuilder(p.bitsPerKey)}
+}
+
+// bloomBuilder only keeps the hash of each key added
+type bloomBuilder struct {
+	builder *bloom.Builder
+}
+
+func (b bloomBuilder) Add(key []byte) {
+	b.builder.Add(key)
+}
+
+func (b bloomBuilder) Build() []byte {
+	return bloom.Marshal(b.builder.Build())
+}
+
+func (p bloomPolicy) KeyMayMatch(key []byte, filter []byte) bool {
+	f, err := bloom.Unmarshal(filter)
+	if err != nil {
+		// A filter we can't understand can't exclude the key
+		return true
+	}
+	return f.HasKey(key)
+}
+
+// buildFilter adds the keys to the builder and returns the filter built
+func buildFilter(builder Builder, keys [][]byte) []byte {
+	for _, key := range keys {
+		builder.Add(key)
+	}
+	return builder.Build()
+}
+
+// BitsPerKey returns the number of bits per key used by the filters the Policy
+// creates, or zero if the Policy does not use a fixed number of bits per key.
+func BitsPerKey(p Policy) uint32 {
+	if b, ok := p.(interface{ BitsPerKey() uint32 }); ok {
+		return b.BitsPerKey()
+	}
+	return 0
+}
+
+// NewPrefixPolicy returns a Policy which adds the first prefixLen bytes of each key to
+// the filter created by base, rather than the entire key. Keys shorter than prefixLen
+// are added in full. A prefix filter is smaller than a filter of entire keys when
+// many keys share a prefix, at the cost of only excluding keys whose prefix is absent.
+func NewPrefixPolicy(prefixLen int, base Policy) Policy {
+	return prefixPolicy{prefixLen: prefixLen, base: base}
+}
+
+type prefixPolicy struct {
+	prefixLen int
+	base      Policy
+}
+
+func (p prefixPolicy) Name() string {
+	return fmt.Sprintf("slatedb.FixedPrefix.%d.%s", p.prefixLen, p.base.Name())
+}
+
+func (p prefixPolicy) CreateFilter(keys [][]byte) []byte {
+	return buildFilter(p.NewBuilder(), keys)
+}
+
+func (p prefixPolicy) NewBuilder() Builder {
+	return &prefixBuilder{policy: p, base: NewBuilder(p.base)}
+}
+
+// prefixBuilder adds the prefix of each key to the Builder of the base Policy
+type prefixBuilder struct {
+	policy prefixPolicy
+	base   Builder
+	last   []byte
+	added  bool
+}
+
+func (b *prefixBuilder) Add(key []byte) {
+	prefix := b.policy.prefix(key)
+	// Keys are sorted, so duplicate prefixes are always adjacent
+	if b.added && bytes.Equal(b.last, prefix) {
+		return
+	}
+	b.last = append(b.last[:0], prefix...)
+	b.added = true
+	b.base.Add(prefix)
+}
+
+func (b *prefixBuilder) Build() []byte {
+	return b.base.Build()
+}
+
+func (p prefixPolicy) KeyMayMatch(key []byte, filter []byte) bool {
+	return p.base.KeyMayMatch(p.prefix(key), filter)
+}
+
+func (p prefixPolicy) BitsPerKey() uint32 {
+	return BitsPerKey(p.base)
+}
+
+func (p prefixPolicy) prefix(key []byte) []byte {
+	if len(key) <= p.prefixLen {
+		return key
+	}
+	return key[:p.prefixLen]
+}
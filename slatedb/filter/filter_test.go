@@ -0,0 +1,112 @@
+package filter_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slatedb/slatedb-go/slatedb/filter"
+)
+
+func TestBloomPolicy(t *testing.T) {
+	policy := filter.NewBloomPolicy(10)
+	var keys [][]byte
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("key-%04d", i)))
+	}
+	f := policy.CreateFilter(keys)
+	for _, key := range keys {
+		assert.True(t, policy.KeyMayMatch(key, f))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if policy.KeyMayMatch([]byte(fmt.Sprintf("absent-%04d", i)), f) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 50)
+
+	// Filters are readable regardless of the bits per key of the reader
+	assert.True(t, filter.NewBloomPolicy(0).KeyMayMatch(keys[0], f))
+	assert.Equal(t, filter.BloomPolicyName, policy.Name())
+}
+
+func TestPrefixPolicy(t *testing.T) {
+	policy := filter.NewPrefixPolicy(4, filter.NewBloomPolicy(10))
+	assert.Equal(t, "slatedb.FixedPrefix.4.slatedb.BloomFilter", policy.Name())
+
+	f := policy.CreateFilter([][]byte{
+		[]byte("aaa"),
+		[]byte("user-1"),
+		[]byte("user-2"),
+		[]byte("user-3"),
+	})
+
+	assert.True(t, policy.KeyMayMatch([]byte("aaa"), f))
+	assert.True(t, policy.KeyMayMatch([]byte("user-1"), f))
+	// Keys which share a prefix with a key in the filter always match
+	assert.True(t, policy.KeyMayMatch([]byte("user-42"), f))
+	assert.False(t, policy.KeyMayMatch([]byte("item-1"), f))
+}
+
+func TestPartitionedPolicy(t *testing.T) {
+	policy := filter.NewPartitionedPolicy(filter.NewFixedPrefixExtractor(5), filter.NewBloomPolicy(10))
+	assert.Equal(t, "slatedb.PrefixPartitioned.FixedPrefix.5.slatedb.BloomFilter", policy.Name())
+
+	keys := [][]byte{[]byte("aaa")}
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("user-%04d", i)))
+	}
+	keys = append(keys, []byte("zone-1"))
+	f := policy.CreateFilter(keys)
+	for _, key := range keys {
+		assert.True(t, policy.KeyMayMatch(key, f))
+	}
+
+	// Keys whose prefix has no partition never match, while keys in a partition
+	// are excluded by the filter of the partition
+	assert.False(t, policy.KeyMayMatch([]byte("item-1"), f))
+	falsePositives := 0
+	for i := 1000; i < 2000; i++ {
+		if policy.KeyMayMatch([]byte(fmt.Sprintf("user-%04d", i)), f) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 50)
+
+	assert.True(t, filter.PrefixMayMatch(policy, []byte("user-"), f))
+	assert.True(t, filter.PrefixMayMatch(policy, []byte("user-0042"), f))
+	assert.True(t, filter.PrefixMayMatch(policy, []byte("zo"), f))
+	assert.True(t, filter.PrefixMayMatch(policy, []byte("aaab"), f))
+	assert.False(t, filter.PrefixMayMatch(policy, []byte("item-"), f))
+	assert.False(t, filter.PrefixMayMatch(policy, []byte("b"), f))
+
+	// Policies which cannot exclude a prefix always match
+	bloom := filter.NewBloomPolicy(10)
+	assert.True(t, filter.PrefixMayMatch(bloom, []byte("item-"), bloom.CreateFilter(keys)))
+}
+
+func TestBuilderMatchesCreateFilter(t *testing.T) {
+	var keys [][]byte
+	for i := 0; i < 500; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("p%02d-key-%04d", i/50, i)))
+	}
+	policies := []filter.Policy{
+		filter.NewBloomPolicy(10),
+		filter.NewPrefixPolicy(3, filter.NewBloomPolicy(10)),
+		filter.NewPartitionedPolicy(filter.NewFixedPrefixExtractor(3), filter.NewBloomPolicy(10)),
+	}
+	for _, policy := range policies {
+		t.Run(policy.Name(), func(t *testing.T) {
+			builder := filter.NewBuilder(policy)
+			for _, key := range keys {
+				// The Builder must not reference the keys added
+				builder.Add(bytes.Clone(key))
+			}
+			assert.Equal(t, policy.CreateFilter(keys), builder.Build())
+		})
+	}
+}
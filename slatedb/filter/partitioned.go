@@ -0,0 +1,244 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// PrefixExtractor extracts the prefix of a key which selects the partition of the
+// filter created by NewPartitionedPolicy.
+type PrefixExtractor interface {
+	// Name returns the name which identifies the prefixes extracted, which must
+	// change whenever the prefix extracted from a key changes.
+	Name() string
+
+	// Prefix returns the prefix of the key, which must be a prefix of the key
+	Prefix(key []byte) []byte
+}
+
+// NewFixedPrefixExtractor returns a PrefixExtractor which extracts the first
+// prefixLen bytes of each key. Keys shorter than prefixLen are their own prefix.
+func NewFixedPrefixExtractor(prefixLen int) PrefixExtractor {
+	return fixedPrefixExtractor{prefixLen: prefixLen}
+}
+
+type fixedPrefixExtractor struct {
+	prefixLen int
+}
+
+func (e fixedPrefixExtractor) Name() string {
+	return fmt.Sprintf("FixedPrefix.%d", e.prefixLen)
+}
+
+func (e fixedPrefixExtractor) Prefix(key []byte) []byte {
+	if len(key) <= e.prefixLen {
+		return key
+	}
+	return key[:e.prefixLen]
+}
+
+// NewPartitionedPolicy returns a Policy which creates a separate filter with base
+// for the keys of each prefix extracted by extractor. A point read only consults
+// the filter of its prefix, and a prefix scan of a prefix which no key has is
+// excluded without consulting any filter. Each partition is sized for the keys of
+// its prefix, so a prefix with few keys does not pay for a prefix with many.
+func NewPartitionedPolicy(extractor PrefixExtractor, base Policy) Policy {
+	return partitionedPolicy{extractor: extractor, base: base}
+}
+
+type partitionedPolicy struct {
+	extractor PrefixExtractor
+	base      Policy
+}
+
+func (p partitionedPolicy) Name() string {
+	return fmt.Sprintf("slatedb.PrefixPartitioned.%s.%s", p.extractor.Name(), p.base.Name())
+}
+
+func (p partitionedPolicy) BitsPerKey() uint32 {
+	return BitsPerKey(p.base)
+}
+
+// CreateFilter encodes a filter for each prefix in the following format, where
+// the partitions are sorted by prefix.
+//
+// +-----------------------------------------------+
+// |  Num of Partitions (4 bytes)                  |
+// +-----------------------------------------------+
+// |  Partition Offsets (4 bytes * N)              |
+// +-----------------------------------------------+
+// |  Partitions                                   |
+// |  +-----------------------------------------+  |
+// |  |  Prefix Length (4 bytes)                |  |
+// |  |  Prefix                                 |  |
+// |  |  Filter Length (4 bytes)                |  |
+// |  |  Filter                                 |  |
+// |  +-----------------------------------------+  |
+// +-----------------------------------------------+
+func (p partitionedPolicy) CreateFilter(keys [][]byte) []byte {
+	return buildFilter(p.NewBuilder(), keys)
+}
+
+func (p partitionedPolicy) NewBuilder() Builder {
+	return &partitionedBuilder{policy: p}
+}
+
+// partitionedBuilder builds the filter of each prefix once the keys of the next
+// prefix are added, so only the Builder of the current prefix holds its keys
+type partitionedBuilder struct {
+	policy   partitionedPolicy
+	prefixes [][]byte
+	filters  [][]byte
+	current  Builder
+}
+
+func (b *partitionedBuilder) Add(key []byte) {
+	prefix := b.policy.extractor.Prefix(key)
+	// Keys are sorted, so the keys which share a prefix are always adjacent
+	if b.current == nil || !bytes.Equal(b.prefixes[len(b.prefixes)-1], prefix) {
+		b.finishPartition()
+		b.prefixes = append(b.prefixes, bytes.Clone(prefix))
+		b.current = NewBuilder(b.policy.base)
+	}
+	b.current.Add(key)
+}
+
+func (b *partitionedBuilder) finishPartition() {
+	if b.current != nil {
+		b.filters = append(b.filters, b.current.Build())
+		b.current = nil
+	}
+}
+
+func (b *partitionedBuilder) Build() []byte {
+	b.finishPartition()
+	headerLen := 4 + 4*len(b.prefixes)
+	buf := binary.BigEndian.AppendUint32(nil, uint32(len(b.prefixes)))
+	partitions := make([]byte, 0)
+	for i := range b.prefixes {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(headerLen+len(partitions)))
+		partitions = binary.BigEndian.AppendUint32(partitions, uint32(len(b.prefixes[i])))
+		partitions = append(partitions, b.prefixes[i]...)
+		partitions = binary.BigEndian.AppendUint32(partitions, uint32(len(b.filters[i])))
+		partitions = append(partitions, b.filters[i]...)
+	}
+	return append(buf, partitions...)
+}
+
+func (p partitionedPolicy) KeyMayMatch(key []byte, filter []byte) bool {
+	partitions, ok := decodePartitions(filter)
+	if !ok {
+		// A filter we can't understand can't exclude the key
+		return true
+	}
+	f, ok := partitions.find(p.extractor.Prefix(key))
+	if !ok {
+		return false
+	}
+	return p.base.KeyMayMatch(key, f)
+}
+
+// PrefixMayMatch returns false if no key in the filter starts with the prefix
+func (p partitionedPolicy) PrefixMayMatch(prefix []byte, filter []byte) bool {
+	partitions, ok := decodePartitions(filter)
+	if !ok {
+		return true
+	}
+
+	// Keys which start with the prefix either have an extracted prefix which
+	// starts with the prefix, or an extracted prefix which is shorter than it
+	i := partitions.search(prefix)
+	if i < partitions.len() && bytes.HasPrefix(partitions.prefix(i), prefix) {
+		return true
+	}
+	for n := 0; n < len(prefix); n++ {
+		if _, ok := partitions.find(prefix[:n]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// partitions reads the partitions of a filter created by partitionedPolicy
+// without decoding the partitions which are not consulted. The filter is
+// protected by the checksum of the SSTable, so a partition which is out of
+// bounds is treated as empty rather than as corruption.
+type partitions struct {
+	data  []byte
+	count int
+}
+
+func decodePartitions(filter []byte) (partitions, bool) {
+	if len(filter) < 4 {
+		return partitions{}, false
+	}
+	count := int(binary.BigEndian.Uint32(filter))
+	if len(filter) < 4+4*count {
+		return partitions{}, false
+	}
+	return partitions{data: filter, count: count}, true
+}
+
+func (ps partitions) len() int {
+	return ps.count
+}
+
+// at returns the prefix and filter of the partition i
+func (ps partitions) at(i int) ([]byte, []byte) {
+	offset := int(binary.BigEndian.Uint32(ps.data[4+4*i:]))
+	if offset+4 > len(ps.data) {
+		return nil, nil
+	}
+	prefixLen := int(binary.BigEndian.Uint32(ps.data[offset:]))
+	offset += 4
+	if offset+prefixLen+4 > len(ps.data) {
+		return nil, nil
+	}
+	prefix := ps.data[offset : offset+prefixLen]
+	offset += prefixLen
+	filterLen := int(binary.BigEndian.Uint32(ps.data[offset:]))
+	offset += 4
+	if offset+filterLen > len(ps.data) {
+		return nil, nil
+	}
+	return prefix, ps.data[offset : offset+filterLen]
+}
+
+func (ps partitions) prefix(i int) []byte {
+	prefix, _ := ps.at(i)
+	return prefix
+}
+
+// search returns the index of the first partition whose prefix is not less than prefix
+func (ps partitions) search(prefix []byte) int {
+	return sort.Search(ps.count, func(i int) bool {
+		return bytes.Compare(ps.prefix(i), prefix) >= 0
+	})
+}
+
+// find returns the filter of the partition of the prefix, if any
+func (ps partitions) find(prefix []byte) ([]byte, bool) {
+	i := ps.search(prefix)
+	if i == ps.count {
+		return nil, false
+	}
+	p, f := ps.at(i)
+	if !bytes.Equal(p, prefix) {
+		return nil, false
+	}
+	return f, true
+}
+
+// PrefixMayMatch returns false if the filter created by the Policy excludes
+// every key which starts with the prefix. Policies which cannot answer for a
+// prefix always return true.
+func PrefixMayMatch(p Policy, prefix []byte, filter []byte) bool {
+	if m, ok := p.(interface {
+		PrefixMayMatch(prefix []byte, filter []byte) bool
+	}); ok {
+		return m.PrefixMayMatch(prefix, filter)
+	}
+	return true
+}
@@ -1,12 +1,17 @@
 package slatedb
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/config"
 	"github.com/slatedb/slatedb-go/slatedb/store"
 	"github.com/slatedb/slatedb-go/slatedb/table"
 
@@ -15,6 +20,19 @@ import (
 	"github.com/slatedb/slatedb-go/slatedb/common"
 )
 
+const (
+	// walWriteAttempts is the number of times the write of a WAL SST is attempted
+	// before the flush of the WAL fails, see DB.writeWALSST
+	walWriteAttempts = 3
+	// walWriteRetryDelay is the delay before the first retry of a failed write of
+	// a WAL SST, which doubles after each retry
+	walWriteRetryDelay = 50 * time.Millisecond
+	// flushPipelineDepth is the number of blocks queued to be encoded while the
+	// next blocks are built, by flushes of memtables of at least
+	// DBOptions.L0UploadPartSize
+	flushPipelineDepth = 16
+)
+
 func (db *DB) spawnWALFlushTask(walFlushNotifierCh <-chan bool, walFlushTaskWG *sync.WaitGroup) {
 	walFlushTaskWG.Add(1)
 	go func() {
@@ -24,13 +42,26 @@ func (db *DB) spawnWALFlushTask(walFlushNotifierCh <-chan bool, walFlushTaskWG *
 		for {
 			select {
 			case <-ticker.C:
-				if err := db.FlushWAL(); err != nil {
+				db.publishHeartbeat(false)
+				// The WAL is flushed every FlushInterval even while the object store
+				// throttles requests, as writers awaiting durability wait on it
+				err := db.flushWAL(context.Background())
+				if err != nil {
 					db.opts.Log.Warn("Flush WAL failed", "error", err)
 				}
+				db.background.record(taskFlushWAL, err)
+			case <-db.walFullCh:
+				err := db.flushWAL(context.Background())
+				if err != nil {
+					db.opts.Log.Warn("Flush WAL failed", "error", err)
+				}
+				db.background.record(taskFlushWAL, err)
 			case <-walFlushNotifierCh:
-				if err := db.FlushWAL(); err != nil {
+				err := db.flushWAL(context.Background())
+				if err != nil {
 					db.opts.Log.Warn("Flush WAL failed", "error", err)
 				}
+				db.background.record(taskFlushWAL, err)
 				return
 			}
 		}
@@ -40,9 +71,27 @@ func (db *DB) spawnWALFlushTask(walFlushNotifierCh <-chan bool, walFlushTaskWG *
 // FlushWAL
 // 1. Convert mutable WAL to Immutable WAL
 // 2. Flush each Immutable WAL to object store and then to memtable
-func (db *DB) FlushWAL() error {
+//
+// Returns common.ErrReadOnly if the DB is read-only, see config.BackgroundErrorReadOnly.
+// The requests to the object store are canceled once ctx is done, in which case
+// the WAL is flushed again by the next flush.
+func (db *DB) FlushWAL(ctx context.Context) error {
+	if err := db.background.checkWritable(); err != nil {
+		return err
+	}
+	err := db.flushWAL(ctx)
+	db.background.reportFenced(err)
+	return err
+}
+
+// flushWAL flushes the WAL regardless of whether the DB is read-only, so the
+// background WAL flush task continues to persist writes accepted before the DB
+// became read-only.
+func (db *DB) flushWAL(ctx context.Context) error {
+	db.walFlushMu.Lock()
+	defer db.walFlushMu.Unlock()
 	db.state.FreezeWAL()
-	err := db.flushImmWALs()
+	err := db.flushImmWALs(ctx)
 	if err != nil {
 		return err
 	}
@@ -54,7 +103,10 @@ func (db *DB) FlushWAL() error {
 // Flush Immutable WAL to mutable Memtable
 // If memtable has reached size L0SSTBytes then convert memtable to Immutable memtable
 // Notify any client(with AwaitDurable set to true) that flush has happened
-func (db *DB) flushImmWALs() error {
+func (db *DB) flushImmWALs(ctx context.Context) error {
+	if db.opts.DisableWAL {
+		return db.flushImmWALsToL0(ctx)
+	}
 	for {
 		oldestWal := db.state.OldestImmWAL()
 		if oldestWal.IsAbsent() {
@@ -63,55 +115,176 @@ func (db *DB) flushImmWALs() error {
 
 		immWal := oldestWal.MustGet()
 		// Flush Immutable WAL to Object store
-		_, err := db.flushImmWAL(immWal)
+		_, err := db.flushImmWAL(ctx, immWal)
 		if err != nil {
 			return err
 		}
 		db.state.PopImmWAL()
 
 		// flush to the memtable before notifying so that data is available for reads
-		db.flushImmWALToMemtable(immWal, db.state.Memtable())
+		db.flushImmWALToMemtable(immWal)
 		db.maybeFreezeMemtable(db.state, immWal.ID())
+		if m := db.opts.WriteBufferManager; m != nil {
+			m.MaybeFlush()
+		}
+		immWal.Table().NotifyWALFlushed()
+		db.committedSeqWatch.notify()
+	}
+	return nil
+}
+
+// flushImmWALsToL0 applies the immutable WALs to the memtable without writing
+// them to object storage, then flushes the memtable to L0, for DBs opened with
+// DBOptions.DisableWAL. The writes of the WALs are durable once in L0, so clients
+// waiting on them are notified after the flush. If the flush fails, they are
+// notified by the next flush which succeeds.
+func (db *DB) flushImmWALsToL0(ctx context.Context) error {
+	for {
+		oldestWal := db.state.OldestImmWAL()
+		if oldestWal.IsAbsent() {
+			break
+		}
+		immWal := oldestWal.MustGet()
+		db.state.PopImmWAL()
+		db.flushImmWALToMemtable(immWal)
+		db.unflushedWALs = append(db.unflushedWALs, immWal)
+	}
+	if len(db.unflushedWALs) == 0 {
+		return nil
+	}
+
+	memtable := db.state.Memtable()
+	if walID, ok := memtable.LastWalID().Get(); ok && memtable.Size() > 0 {
+		db.state.FreezeMemtable(walID)
+	}
+	flusher := MemtableFlusher{db: db, manifest: db.manifest, log: db.opts.Log}
+	if err := flusher.flushImmMemtablesToL0(ctx); err != nil {
+		return err
+	}
+
+	for _, immWal := range db.unflushedWALs {
 		immWal.Table().NotifyWALFlushed()
 	}
+	db.unflushedWALs = nil
+	db.committedSeqWatch.notify()
 	return nil
 }
 
-func (db *DB) flushImmWAL(immWAL *table.ImmutableWAL) (*sstable.Handle, error) {
+func (db *DB) flushImmWAL(ctx context.Context, immWAL *table.ImmutableWAL) (*sstable.Handle, error) {
 	walID := sstable.NewIDWal(immWAL.ID())
-	return db.flushImmTable(walID, immWAL.Iter())
+	return db.flushImmTable(ctx, walID, immWAL.Iter(), immWAL.RangeTombstones())
 }
 
-func (db *DB) flushImmWALToMemtable(immWal *table.ImmutableWAL, memtable *table.Memtable) {
+func (db *DB) flushImmWALToMemtable(immWal *table.ImmutableWAL) {
+	var entries []types.RowEntry
 	iter := immWal.Iter()
 	for {
 		entry, err := iter.NextEntry()
 		if err != nil || entry.IsAbsent() {
 			break
 		}
-		kv, _ := entry.Get()
-		if kv.Value.IsTombstone() {
-			memtable.Delete(kv.Key)
+		entries = append(entries, entry.MustGet())
+	}
+	db.applyWALToMemtable(immWal.ID(), entries, immWal.RangeTombstones())
+	db.state.Memtable().SetLastWalID(immWal.ID())
+}
+
+// applyWALToMemtable writes the entries and range tombstones of the WAL with the
+// provided ID to the memtable in order of sequence number. When the memtable fills
+// part way through the WAL, the frozen memtable then holds every write of the WAL
+// up to a sequence number and none after it, so the LastL0Seq of the manifest
+// separates the writes in L0 from the writes only in the WAL, see ReplicationPosition.
+func (db *DB) applyWALToMemtable(walID uint64, entries []types.RowEntry, tombstones types.RangeTombstones) {
+	for _, w := range walWritesBySeq(entries, tombstones) {
+		if w.tombstone != nil {
+			db.state.WriteRangeTombstoneToMemtable(*w.tombstone)
 		} else {
-			memtable.Put(kv.Key, kv.Value.Value)
+			db.state.WriteEntryToMemtable(w.entry)
+		}
+		db.maybeFreezeFullMemtable(walID)
+	}
+}
+
+// flushImmMemtable flushes the immutable memtable to the L0 SSTable. Memtables of
+// at least DBOptions.L0UploadPartSize bytes are built, encoded and uploaded in a
+// pipeline, others are built before they are uploaded, see flushImmTable.
+func (db *DB) flushImmMemtable(ctx context.Context, id sstable.ID, imm *table.ImmutableMemtable) (*sstable.Handle, error) {
+	partSize := db.opts.L0UploadPartSize
+	if partSize == 0 || uint64(imm.Size()) < partSize {
+		return db.flushImmTable(ctx, id, imm.Iter(), imm.RangeTombstones())
+	}
+
+	// While the object store throttles requests, the parts are uploaded one at a
+	// time rather than while the next part is built
+	pipelineDepth := flushPipelineDepth
+	if db.throttle.Level() < 1 {
+		pipelineDepth = 0
+	}
+	tableStore := db.tableStore.WithIOClass(store.IOClassFlush)
+	writer := tableStore.TableWriterWithOptions(ctx, id, store.TableWriterOptions{
+		PartSize:      partSize,
+		PipelineDepth: pipelineDepth,
+	})
+	for _, tombstone := range imm.RangeTombstones() {
+		writer.AddRangeTombstone(tombstone)
+	}
+	iter := imm.Iter()
+	for {
+		entry, err := iter.NextEntry()
+		if err != nil || entry.IsAbsent() {
+			break
+		}
+		kv, _ := entry.Get()
+		// Empty values are written as tombstones, see sstable.Builder.AddValue
+		if kv.Value.Kind == types.KindKeyValue && len(kv.Value.Value) == 0 {
+			kv.Value = types.Value{Kind: types.KindTombStone}
+		}
+		if err := writer.AddEntry(kv); err != nil {
+			writer.Abort()
+			return nil, err
 		}
 	}
-	memtable.SetLastWalID(immWal.ID())
+
+	sst, err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	db.stats.recordFlush(writer.Stats())
+	return sst, nil
 }
 
-func (db *DB) flushImmTable(id sstable.ID, iter *table.KVTableIterator) (*sstable.Handle, error) {
-	sstBuilder := db.tableStore.TableBuilder()
+func (db *DB) flushImmTable(
+	ctx context.Context,
+	id sstable.ID,
+	iter *table.KVTableIterator,
+	rangeTombstones types.RangeTombstones,
+) (*sstable.Handle, error) {
+	start := time.Now()
+	var opts config.SSTableOptions
+	if id.Type == sstable.WAL {
+		// A checksum of every value lets recovery verify each write of the WAL
+		opts = config.SSTableOptions{CompressionCodec: db.opts.WALCompressionCodec, ValueChecksums: true}
+	}
+	sstBuilder := db.tableStore.TableBuilderWithOptions(opts)
+	defer db.tableStore.ReleaseTableBuilder(sstBuilder)
+	if id.Type == sstable.WAL {
+		sstBuilder.SetWriterEpoch(db.manifest.Epoch())
+	}
+	for _, tombstone := range rangeTombstones {
+		sstBuilder.AddRangeTombstone(tombstone)
+	}
 	for {
 		entry, err := iter.NextEntry()
 		if err != nil || entry.IsAbsent() {
 			break
 		}
 		kv, _ := entry.Get()
-		var val []byte
-		if !kv.Value.IsTombstone() {
-			val = kv.Value.Value
+		// Empty values are written as tombstones, see sstable.Builder.AddValue
+		if kv.Value.Kind == types.KindKeyValue && len(kv.Value.Value) == 0 {
+			kv.Value = types.Value{Kind: types.KindTombStone}
 		}
-		err = sstBuilder.AddValue(kv.Key, val)
+		// The sequence number and creation time of the entry are preserved
+		err = sstBuilder.Add(kv.Key, kv)
 		if err != nil {
 			return nil, err
 		}
@@ -121,15 +294,65 @@ func (db *DB) flushImmTable(id sstable.ID, iter *table.KVTableIterator) (*sstabl
 	if err != nil {
 		return nil, err
 	}
+	// The blocks were encoded as they were built
+	stats := store.TableWriterStats{Build: time.Since(start), Encode: sstBuilder.EncodeTime()}
+
+	// Only L0 SSTables are stored inline, as WAL SSTables are not referenced by the manifest
+	if id.Type == sstable.Compacted && encodedSST.EncodedSize() <= db.opts.InlineSSTMaxBytes {
+		stats.Total = time.Since(start)
+		db.stats.recordFlush(stats)
+		return db.tableStore.WriteSSTInline(id, encodedSST), nil
+	}
+
+	tableStore := db.tableStore.WithIOClass(store.IOClassFlush)
+	if id.Type == sstable.WAL {
+		return db.writeWALSST(ctx, tableStore, id, encodedSST)
+	}
 
-	sst, err := db.tableStore.WriteSST(id, encodedSST)
+	uploadStart := time.Now()
+	sst, err := tableStore.WriteSST(ctx, id, encodedSST)
 	if err != nil {
 		return nil, err
 	}
+	stats.Upload = time.Since(uploadStart)
+	stats.Total = time.Since(start)
+	db.stats.recordFlush(stats)
 
 	return sst, nil
 }
 
+// writeWALSST writes the WAL SST, retrying a failed write up to walWriteAttempts
+// times. Each attempt writes the WAL SST only if no object with its ID exists, and
+// an existing object with the same contents was written by an attempt whose
+// response was lost, so retries are idempotent. An existing object with other
+// contents was written by a newer writer which fenced this writer, see
+// DB.fenceWAL, so a manifest.FencedError naming both writers is returned rather
+// than retrying. The retries stop once ctx is done.
+func (db *DB) writeWALSST(ctx context.Context, tableStore *store.TableStore, id sstable.ID, encodedSST *sstable.Table) (*sstable.Handle, error) {
+	delay := walWriteRetryDelay
+	for attempt := 1; ; attempt++ {
+		sst, err := tableStore.WriteSSTIfNotExists(ctx, id, encodedSST)
+		if errors.Is(err, common.ErrObjectExists) {
+			// The writer which fenced this writer incremented the epoch in the
+			// manifest before writing its fencing WAL
+			if err := db.manifest.CheckFenced(); errors.Is(err, common.ErrFenced) {
+				return nil, fmt.Errorf("WAL '%d' was written by another writer: %w", id.WalID().OrEmpty(), err)
+			}
+			return nil, fmt.Errorf("%w: WAL '%d' was written by another writer", common.ErrFenced, id.WalID().OrEmpty())
+		}
+		if err == nil || attempt == walWriteAttempts {
+			return sst, err
+		}
+		db.opts.Log.Warn("retrying failed WAL write", "id", id.WalID().OrEmpty(), "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
 // ------------------------------------------------
 // MemtableFlusher
 // ------------------------------------------------
@@ -156,23 +379,52 @@ func (db *DB) spawnMemtableFlushTask(
 		for !(isShutdown && len(memtableFlushNotifierCh) == 0) {
 			select {
 			case <-ticker.C:
-				err := flusher.loadManifest()
+				err := flusher.refreshManifest()
 				if err != nil {
 					db.opts.Log.Error("error load manifest", "error", err)
 				}
+				err = flusher.pruneManifests()
+				if err != nil {
+					db.opts.Log.Warn("error pruning manifests", "error", err)
+				}
+				err = flusher.pruneWALs(context.Background())
+				if err != nil {
+					db.opts.Log.Warn("error pruning WALs", "error", err)
+				}
+				// Retry the flush of immutable memtables left behind by a failed
+				// flush, as writes stalled on them would otherwise wait for a
+				// memtable to be frozen which never happens
+				if db.opts.MaxImmutableMemtables > 0 && db.state.ImmMemtableCount() > 0 {
+					err = flusher.flushImmMemtablesToL0(context.Background())
+					if err != nil {
+						db.opts.Log.Error("error flushing memtable", "error", err)
+					}
+					db.background.record(taskFlushMemtable, err)
+					db.writeStalls.notify()
+				}
 			case val := <-memtableFlushNotifierCh:
 				if val == Shutdown {
 					isShutdown = true
 				} else if val == FlushImmutableMemtables {
-					err := flusher.flushImmMemtablesToL0()
+					err := flusher.flushImmMemtablesToL0(context.Background())
 					if err != nil {
 						db.opts.Log.Error("error flushing memtable", "error", err)
 					}
+					db.background.record(taskFlushMemtable, err)
+				} else if val == FlushMemtable {
+					err := db.flushWriteBuffer(context.Background(), &flusher)
+					if err != nil {
+						db.opts.Log.Error("error flushing memtable", "error", err)
+					}
+					db.background.record(taskFlushMemtable, err)
 				}
+				// Wake the stalled writes, which stop stalling if the failure of
+				// the flush put the DB into a read-only state
+				db.writeStalls.notify()
 			}
 		}
 
-		err := flusher.writeManifestSafely()
+		err := flusher.persistManifest()
 		if err != nil {
 			db.opts.Log.Error("error writing manifest on shutdown", "error", err)
 		}
@@ -184,12 +436,36 @@ type MemtableFlushThreadMsg int
 const (
 	Shutdown MemtableFlushThreadMsg = iota + 1
 	FlushImmutableMemtables
+	// FlushMemtable freezes and flushes the mutable memtable, sent when the
+	// writebuffer.Manager of DBOptions.WriteBufferManager exceeds its budget
+	FlushMemtable
 )
 
 type MemtableFlusher struct {
 	db       *DB
 	manifest *store.FenceableManifest
 	log      *slog.Logger
+
+	// warnedManifestVersions is true once the manifest versions warning has been
+	// logged, so it is logged once each time the threshold is crossed.
+	warnedManifestVersions bool
+}
+
+// refreshManifest loads the manifest like loadManifest while holding
+// memtableFlushMu. The manifest is not safe for concurrent use, and is written by
+// the flushes of the memtables while they hold memtableFlushMu.
+func (m *MemtableFlusher) refreshManifest() error {
+	m.db.memtableFlushMu.Lock()
+	defer m.db.memtableFlushMu.Unlock()
+	return m.loadManifest()
+}
+
+// persistManifest writes the manifest like writeManifestSafely while holding
+// memtableFlushMu, see refreshManifest
+func (m *MemtableFlusher) persistManifest() error {
+	m.db.memtableFlushMu.Lock()
+	defer m.db.memtableFlushMu.Unlock()
+	return m.writeManifestSafely()
 }
 
 func (m *MemtableFlusher) loadManifest() error {
@@ -201,6 +477,80 @@ func (m *MemtableFlusher) loadManifest() error {
 	return nil
 }
 
+// pruneManifests removes manifest versions beyond DBOptions.ManifestRetainVersions
+// which are older than DBOptions.ManifestRetainDuration, and records the number
+// of versions which remain in the DB stats.
+func (m *MemtableFlusher) pruneManifests() error {
+	retain := m.db.opts.ManifestRetainVersions
+	if retain <= 0 {
+		retain = math.MaxInt
+	}
+
+	remaining, err := m.db.manifestStore.PruneManifests(retain, m.db.opts.ManifestRetainDuration)
+	m.db.stats.manifestVersions.Store(int64(remaining))
+	if err != nil {
+		return err
+	}
+
+	threshold := m.db.opts.ManifestVersionsWarnThreshold
+	if threshold > 0 && remaining > threshold {
+		if !m.warnedManifestVersions {
+			m.log.Warn("number of manifest versions exceeds threshold",
+				"versions", remaining, "threshold", threshold)
+			m.warnedManifestVersions = true
+		}
+	} else {
+		m.warnedManifestVersions = false
+	}
+	return nil
+}
+
+// pruneWALs deletes the WAL SSTs flushed to L0 beyond DBOptions.WALRetainCount
+// which are older than DBOptions.WALRetainDuration. Only the WAL SSTs up to the
+// last compacted WAL of the manifest in object storage are flushed, so a crash
+// before the manifest is written never loses the writes of a deleted WAL SST.
+func (m *MemtableFlusher) pruneWALs(ctx context.Context) error {
+	retain := m.db.opts.WALRetainCount
+	if retain <= 0 {
+		return nil
+	}
+
+	m.db.memtableFlushMu.Lock()
+	core, err := m.manifest.DbState()
+	m.db.memtableFlushMu.Unlock()
+	if err != nil {
+		return err
+	}
+	lastCompacted := core.LastCompactedWalSSTID.Load()
+	pinned, ok, err := m.db.manifestStore.PinnedWALID()
+	if err != nil {
+		return fmt.Errorf("while reading pinned manifests: %w", err)
+	}
+	if ok && pinned <= lastCompacted {
+		lastCompacted = pinned - 1
+	}
+
+	tableStore := m.db.tableStore.WithIOClass(store.IOClassGC)
+	walList, err := tableStore.ListWALSSTs(ctx, lastCompacted)
+	if err != nil {
+		return err
+	}
+	if len(walList) <= retain {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-m.db.opts.WALRetainDuration)
+	for _, wal := range walList[:len(walList)-retain] {
+		if wal.LastModified.After(cutoff) {
+			continue
+		}
+		if err := tableStore.DeleteSST(ctx, sstable.NewIDWal(wal.ID)); err != nil {
+			return fmt.Errorf("while deleting WAL '%d': %w", wal.ID, err)
+		}
+	}
+	return nil
+}
+
 func (m *MemtableFlusher) writeManifest() error {
 	core := m.db.state.CoreStateSnapshot()
 	return m.manifest.UpdateDBState(core)
@@ -224,7 +574,9 @@ func (m *MemtableFlusher) writeManifestSafely() error {
 	}
 }
 
-func (m *MemtableFlusher) flushImmMemtablesToL0() error {
+func (m *MemtableFlusher) flushImmMemtablesToL0(ctx context.Context) error {
+	m.db.memtableFlushMu.Lock()
+	defer m.db.memtableFlushMu.Unlock()
 	for {
 		immMemtable := m.db.state.OldestImmMemtable()
 		if immMemtable.IsAbsent() {
@@ -232,12 +584,13 @@ func (m *MemtableFlusher) flushImmMemtablesToL0() error {
 		}
 
 		id := sstable.NewIDCompacted(ulid.Make())
-		sstHandle, err := m.db.flushImmTable(id, immMemtable.MustGet().Iter())
+		sstHandle, err := m.db.flushImmMemtable(ctx, id, immMemtable.MustGet())
 		if err != nil {
 			return err
 		}
 
 		m.db.state.MoveImmMemtableToL0(immMemtable.MustGet(), sstHandle)
+		m.db.writeStalls.notify()
 		err = m.writeManifestSafely()
 		if err != nil {
 			return err
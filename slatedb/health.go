@@ -0,0 +1,146 @@
+package slatedb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/manifest"
+)
+
+// Names of the background tasks reported to backgroundErrors
+const (
+	taskFlushWAL      = "flush WAL"
+	taskFlushMemtable = "flush memtable"
+	taskCompaction    = "compaction"
+)
+
+// backgroundErrors tracks the consecutive failures of each background task and
+// applies DBOptions.BackgroundErrorPolicy once a task fails
+// DBOptions.BackgroundErrorLimit times in a row.
+type backgroundErrors struct {
+	opts     config.DBOptions
+	mu       sync.Mutex
+	failures map[string]int
+	// The first unrecoverable error
+	err      error
+	readOnly atomic.Bool
+	// Whether a fenced error was reported, as every background task fails
+	// once the DB is fenced
+	fenced atomic.Bool
+}
+
+func newBackgroundErrors(opts config.DBOptions) *backgroundErrors {
+	return &backgroundErrors{
+		opts:     opts,
+		failures: make(map[string]int),
+	}
+}
+
+// record records the result of a run of the background task, where a nil err
+// resets the consecutive failures of the task.
+func (b *backgroundErrors) record(task string, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	if err == nil {
+		delete(b.failures, task)
+		b.mu.Unlock()
+		return
+	}
+	// A task which fails because the DB is read-only is a consequence of an
+	// earlier unrecoverable error, not a new one.
+	if errors.Is(err, common.ErrReadOnly) {
+		b.mu.Unlock()
+		return
+	}
+
+	b.failures[task]++
+	if b.failures[task] < b.opts.BackgroundErrorLimit && !errors.Is(err, common.ErrFenced) {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.failures, task)
+	err = fmt.Errorf("background task '%s' failed: %w", task, err)
+	if b.err == nil {
+		b.err = err
+	}
+	b.mu.Unlock()
+
+	b.reportFenced(err)
+	b.opts.Log.Error("unrecoverable background error", "task", task, "error", err)
+	if b.opts.OnBackgroundError != nil {
+		b.opts.OnBackgroundError(err)
+	}
+
+	switch b.opts.BackgroundErrorPolicy {
+	case config.BackgroundErrorReadOnly:
+		b.readOnly.Store(true)
+	case config.BackgroundErrorPanic:
+		panic(err)
+	}
+}
+
+// recordFor records the result of a run of the background task on behalf of a
+// caller whose ctx is passed, except if the run failed because ctx is done, as
+// the caller canceled it rather than the task failing.
+func (b *backgroundErrors) recordFor(ctx context.Context, task string, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	b.record(task, err)
+}
+
+// reportFenced logs the first manifest.FencedError wrapped by err, with the epochs
+// and writers of the fenced client and the client which fenced it, and passes it
+// to DBOptions.OnFenced
+func (b *backgroundErrors) reportFenced(err error) {
+	var fenced *manifest.FencedError
+	if !errors.As(err, &fenced) || !b.fenced.CompareAndSwap(false, true) {
+		return
+	}
+	args := []any{"role", fenced.Role, "local_epoch", fenced.LocalEpoch, "current_epoch", fenced.CurrentEpoch}
+	if writer, ok := fenced.LocalWriter.Get(); ok {
+		args = append(args, "local_writer", writer.String())
+	}
+	if writer, ok := fenced.CurrentWriter.Get(); ok {
+		args = append(args, "current_writer", writer.String())
+	}
+	b.opts.Log.Error("fenced by a newer client", args...)
+	if b.opts.OnFenced != nil {
+		b.opts.OnFenced(fenced)
+	}
+}
+
+// health returns the first unrecoverable error, wrapped by common.ErrReadOnly
+// if the DB is read-only
+func (b *backgroundErrors) health() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.readOnly.Load() {
+		return fmt.Errorf("%w: %w", common.ErrReadOnly, b.err)
+	}
+	return b.err
+}
+
+// checkWritable returns an error wrapping common.ErrReadOnly if the DB is read-only
+func (b *backgroundErrors) checkWritable() error {
+	if !b.readOnly.Load() {
+		return nil
+	}
+	return b.health()
+}
+
+// Health returns the first background error which was considered unrecoverable
+// according to DBOptions.BackgroundErrorLimit, or nil if there was none. If the
+// error put the DB into a read-only state, the returned error wraps
+// common.ErrReadOnly.
+func (db *DB) Health() error {
+	return db.background.health()
+}
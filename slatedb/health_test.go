@@ -0,0 +1,114 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// failingBucket fails every upload while fail is true
+type failingBucket struct {
+	objstore.Bucket
+	fail atomic.Bool
+}
+
+func (b *failingBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if b.fail.Load() {
+		return errors.New("upload failed")
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+func TestBackgroundErrorReadOnly(t *testing.T) {
+	ctx := context.Background()
+	bucket := &failingBucket{Bucket: objstore.NewInMemBucket()}
+	options := testDBOptions(0, 1024)
+	options.BackgroundErrorPolicy = config.BackgroundErrorReadOnly
+	options.BackgroundErrorLimit = 2
+	var reported atomic.Int32
+	options.OnBackgroundError = func(err error) {
+		reported.Add(1)
+	}
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Health())
+
+	bucket.fail.Store(true)
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key2"), []byte("value2"), config.WriteOptions{AwaitDurable: false}))
+	require.Eventually(t, func() bool {
+		return db.Health() != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.ErrorIs(t, db.Health(), common.ErrReadOnly)
+	assert.Contains(t, db.Health().Error(), taskFlushWAL)
+	assert.Equal(t, int32(1), reported.Load())
+
+	// Reads are still served, while writes are rejected
+	val, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+	noWait := config.WriteOptions{AwaitDurable: false}
+	assert.ErrorIs(t, db.PutWithOptions(ctx, []byte("key3"), []byte("value3"), noWait), common.ErrReadOnly)
+	assert.ErrorIs(t, db.DeleteWithOptions(ctx, []byte("key1"), noWait), common.ErrReadOnly)
+	assert.ErrorIs(t, db.DeleteRangeWithOptions(ctx, []byte("key1"), []byte("key2"), noWait), common.ErrReadOnly)
+	_, err = db.PutAsync(ctx, []byte("key3"), []byte("value3"))
+	assert.ErrorIs(t, err, common.ErrReadOnly)
+	batch := NewWriteBatch()
+	batch.Put([]byte("key3"), []byte("value3"))
+	assert.ErrorIs(t, db.Write(ctx, batch, noWait), common.ErrReadOnly)
+	assert.ErrorIs(t, db.WriteIfWithOptions(ctx, batch, noWait), common.ErrReadOnly)
+	_, err = db.Import(ctx, bytes.NewReader(nil), ImportOptions{})
+	assert.ErrorIs(t, err, common.ErrReadOnly)
+	_, err = db.Get(ctx, []byte("key3"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	assert.ErrorIs(t, db.FlushWAL(ctx), common.ErrReadOnly)
+	assert.ErrorIs(t, db.FlushMemtableToL0(ctx), common.ErrReadOnly)
+}
+
+func TestBackgroundErrorsResetOnSuccess(t *testing.T) {
+	var reported []error
+	options := testDBOptions(0, 1024)
+	options.BackgroundErrorLimit = 2
+	options.Log = config.DefaultDBOptions().Log
+	options.OnBackgroundError = func(err error) {
+		reported = append(reported, err)
+	}
+	b := newBackgroundErrors(options)
+
+	failure := errors.New("failure")
+	b.record(taskCompaction, failure)
+	b.record(taskCompaction, nil)
+	b.record(taskCompaction, failure)
+	assert.NoError(t, b.health())
+	assert.Empty(t, reported)
+
+	b.record(taskCompaction, failure)
+	require.Len(t, reported, 1)
+	assert.ErrorIs(t, b.health(), failure)
+	assert.NoError(t, b.checkWritable())
+
+	// A fenced DB is unrecoverable on the first failure
+	b.record(taskFlushMemtable, common.ErrFenced)
+	require.Len(t, reported, 2)
+	assert.ErrorIs(t, reported[1], common.ErrFenced)
+
+	options.BackgroundErrorPolicy = config.BackgroundErrorPanic
+	b = newBackgroundErrors(options)
+	assert.Panics(t, func() {
+		b.record(taskFlushMemtable, common.ErrFenced)
+	})
+}
@@ -0,0 +1,163 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/samber/mo"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/manifest"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// publishHeartbeat publishes the heartbeat of the writer if DBOptions.HeartbeatInterval
+// elapsed since the last one, or if the DB is closed. A writer which hit an
+// unrecoverable background error, such as being fenced by a newer writer, stops
+// publishing it, so replicas detect it as they would a dead writer.
+func (db *DB) publishHeartbeat(closed bool) {
+	interval := db.opts.HeartbeatInterval
+	if interval == 0 || db.background.health() != nil {
+		return
+	}
+	now := time.Now()
+	if !closed && now.Sub(time.Unix(0, db.lastHeartbeat.Load())) < interval {
+		return
+	}
+
+	heartbeat := store.Heartbeat{
+		Time:         now,
+		CommittedSeq: db.state.CommittedSeq(),
+		NextWALID:    db.state.NextWALID(),
+		Closed:       closed,
+		WriterEpoch:  db.manifest.Epoch(),
+	}
+	if err := db.manifestStore.WriteHeartbeat(heartbeat); err != nil {
+		db.opts.Log.Warn("error publishing heartbeat", "error", err)
+		return
+	}
+	db.lastHeartbeat.Store(now.UnixNano())
+}
+
+// ReplicaStats is a point in time view of the writer of a DB, as seen by a replica
+// which tails its WAL
+type ReplicaStats struct {
+	// Heartbeat is the last heartbeat published by the writer, see
+	// DBOptions.HeartbeatInterval. It is zero if the writer never published one.
+	Heartbeat store.Heartbeat
+
+	// SinceHeartbeat is the time elapsed since the last heartbeat was published,
+	// zero if the writer never published one
+	SinceHeartbeat time.Duration
+
+	// SeqLag is the number of sequence numbers the position of the replica is
+	// behind the CommittedSeq of the last heartbeat
+	SeqLag uint64
+}
+
+// WriterUnresponsive returns true if the writer published no heartbeat for longer
+// than timeout without closing the DB, which means it died or lost access to
+// object storage. The timeout should be several DBOptions.HeartbeatInterval, as
+// the heartbeat is published late while the writer is busy.
+func (s ReplicaStats) WriterUnresponsive(timeout time.Duration) bool {
+	return !s.Heartbeat.Time.IsZero() && !s.Heartbeat.Closed && s.SinceHeartbeat > timeout
+}
+
+// ReplicaStatsOf returns the stats of a replica of the DB at dbPath whose position
+// is pos, see TailWAL, without opening the DB
+func ReplicaStatsOf(bucket objstore.Bucket, dbPath string, pos ReplicationPosition) (ReplicaStats, error) {
+	layout, err := store.LoadLayout(context.Background(), store.NewBucketObjectStore(bucket), dbPath)
+	if err != nil {
+		return ReplicaStats{}, err
+	}
+	manifestStore := store.NewManifestStore(dbPath, bucket)
+	manifestStore.SetLayout(layout)
+	heartbeat, ok, err := manifestStore.ReadHeartbeat()
+	if err != nil {
+		return ReplicaStats{}, fmt.Errorf("while reading heartbeat: %w", err)
+	}
+	if !ok {
+		return ReplicaStats{}, nil
+	}
+
+	stats := ReplicaStats{
+		Heartbeat: heartbeat,
+		// The heartbeat is timed by the clock of the object store, which may be
+		// ahead of the clock of the replica
+		SinceHeartbeat: max(time.Since(heartbeat.Time), 0),
+	}
+	if heartbeat.CommittedSeq > pos.Seq {
+		stats.SeqLag = heartbeat.CommittedSeq - pos.Seq
+	}
+	return stats, nil
+}
+
+// AlreadyOpenError is returned by Open when the writer of the DB published a
+// heartbeat within DBOptions.LiveWriterTimeout and did not close the DB. It
+// wraps common.ErrAlreadyOpen.
+type AlreadyOpenError struct {
+	// Writer identifies the writer holding the DB. It is absent if the writer
+	// did not record its identity in the manifest.
+	Writer mo.Option[manifest.WriterInfo]
+
+	// Heartbeat is the last heartbeat published by the writer
+	Heartbeat store.Heartbeat
+
+	// SinceHeartbeat is the time elapsed since the heartbeat was published
+	SinceHeartbeat time.Duration
+}
+
+func (e *AlreadyOpenError) Error() string {
+	writer := "unknown writer"
+	if info, ok := e.Writer.Get(); ok {
+		writer = info.String()
+	}
+	return fmt.Sprintf("%s: %s, last heartbeat %s ago", common.ErrAlreadyOpen, writer,
+		e.SinceHeartbeat.Round(time.Millisecond))
+}
+
+func (e *AlreadyOpenError) Unwrap() error {
+	return common.ErrAlreadyOpen
+}
+
+// checkLiveWriter returns an AlreadyOpenError if the writer of the stored manifest
+// published a heartbeat within the timeout and did not close the DB. Heartbeats
+// of a writer which was since fenced are ignored, as it fails its next write.
+func checkLiveWriter(manifestStore *store.ManifestStore, stored *store.StoredManifest, timeout time.Duration) error {
+	heartbeat, ok, err := manifestStore.ReadHeartbeat()
+	if err != nil {
+		return err
+	}
+	if !ok || heartbeat.Closed {
+		return nil
+	}
+	if heartbeat.WriterEpoch != 0 && heartbeat.WriterEpoch != stored.WriterEpoch() {
+		return nil
+	}
+	// The heartbeat is timed by the clock of the object store, which may be
+	// ahead of the clock of this client
+	since := max(time.Since(heartbeat.Time), 0)
+	if since >= timeout {
+		return nil
+	}
+	return &AlreadyOpenError{Writer: stored.Writer(), Heartbeat: heartbeat, SinceHeartbeat: since}
+}
+
+// newWriterInfo returns the identity of this process as a writer of the DB, with
+// an instance ID which is unique to the DB opened
+func newWriterInfo() manifest.WriterInfo {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return manifest.WriterInfo{
+		Host:       host,
+		PID:        uint64(os.Getpid()),
+		StartTime:  time.Now(),
+		InstanceID: ulid.Make().String(),
+	}
+}
@@ -0,0 +1,105 @@
+package slatedb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024*1024)
+	options.DisableBackgroundTasks = true
+	options.HeartbeatInterval = time.Hour
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+
+	stats, err := ReplicaStatsOf(bucket, dbPath, ReplicationPosition{})
+	require.NoError(t, err)
+	assert.Equal(t, ReplicaStats{}, stats)
+	assert.False(t, stats.WriterUnresponsive(0))
+
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: true}))
+	require.NoError(t, db.Maintenance(ctx))
+	stats, err = ReplicaStatsOf(bucket, dbPath, ReplicationPosition{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stats.Heartbeat.CommittedSeq)
+	assert.Equal(t, db.state.NextWALID(), stats.Heartbeat.NextWALID)
+	assert.Equal(t, db.manifest.Epoch(), stats.Heartbeat.WriterEpoch)
+	assert.Equal(t, uint64(1), stats.SeqLag)
+	assert.False(t, stats.Heartbeat.Closed)
+	assert.False(t, stats.WriterUnresponsive(time.Minute))
+
+	// The next heartbeat is not due until the interval elapsed
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key2"), []byte("value2"), config.WriteOptions{AwaitDurable: true}))
+	require.NoError(t, db.Maintenance(ctx))
+	stats, err = ReplicaStatsOf(bucket, dbPath, ReplicationPosition{Seq: 1})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stats.Heartbeat.CommittedSeq)
+	assert.Equal(t, uint64(0), stats.SeqLag)
+
+	// A writer which closed the DB is never unresponsive
+	require.NoError(t, db.Close())
+	stats, err = ReplicaStatsOf(bucket, dbPath, ReplicationPosition{Seq: 1})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.Heartbeat.CommittedSeq)
+	assert.True(t, stats.Heartbeat.Closed)
+	assert.False(t, stats.WriterUnresponsive(0))
+}
+
+func TestOpenFailsWhileWriterIsLive(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024*1024)
+	options.DisableBackgroundTasks = true
+	options.HeartbeatInterval = time.Hour
+	options.LiveWriterTimeout = time.Minute
+	live, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer live.Close()
+	require.NoError(t, live.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: true}))
+	require.NoError(t, live.Maintenance(ctx))
+
+	_, err = Open(ctx, dbPath, bucket, options)
+	require.ErrorIs(t, err, common.ErrAlreadyOpen)
+	var alreadyOpen *AlreadyOpenError
+	require.True(t, errors.As(err, &alreadyOpen))
+	writer, ok := alreadyOpen.Writer.Get()
+	require.True(t, ok)
+	host, err := os.Hostname()
+	require.NoError(t, err)
+	assert.Equal(t, host, writer.Host)
+	assert.Equal(t, uint64(os.Getpid()), writer.PID)
+	assert.False(t, writer.StartTime.IsZero())
+	assert.Contains(t, alreadyOpen.Error(), host)
+
+	// The live writer was not fenced
+	require.NoError(t, live.PutWithOptions(ctx, []byte("key2"), []byte("value2"), config.WriteOptions{AwaitDurable: true}))
+	require.NoError(t, live.Maintenance(ctx))
+
+	// A client which disables the check takes over the DB
+	takeover := options
+	takeover.LiveWriterTimeout = 0
+	newer, err := Open(ctx, dbPath, bucket, takeover)
+	require.NoError(t, err)
+	require.NoError(t, live.PutWithOptions(ctx, []byte("key3"), []byte("value3"), config.WriteOptions{AwaitDurable: false}))
+	assert.ErrorIs(t, live.FlushWAL(ctx), common.ErrFenced)
+
+	// A writer which closed the DB is not live
+	require.NoError(t, newer.Close())
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
@@ -0,0 +1,207 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sort"
+
+	"github.com/oklog/ulid/v2"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// ingestFile is an SSTable file which has been read and validated for ingestion.
+// The contents of the file are not kept, only their checksum, which verifies the
+// file is unchanged when it is read again to be uploaded.
+type ingestFile struct {
+	path     string
+	checksum uint32
+	info     *sstable.Info
+	firstKey []byte
+	lastKey  []byte
+}
+
+// IngestSST adds the SSTables in the provided files to the DB without writing their
+// entries through the WAL and memtable, which makes bulk loading much cheaper.
+//
+// Every file is validated before any are uploaded: the footer, info, index, filter
+// and every block must pass their checksums, the keys must be sorted, and the key
+// ranges of the files must not overlap. The files are then rewritten with the
+// SSTable options of the DB, uploaded with new IDs and added to L0 as the newest
+// SSTables, so their entries shadow any existing values of the same keys. The
+// memtable is flushed first, such that writes which completed before IngestSST
+// was called are also shadowed. The entries of the files are rewritten with a
+// sequence number greater than that of every such write, so a range deletion
+// which completed before IngestSST was called does not delete them.
+//
+// Only one file is held in memory at a time, as each file is read once to be
+// validated and once more to be rewritten, so the peak memory of an ingest is
+// about twice the size of the largest file, regardless of the number of files.
+//
+// Returns common.ErrInvalidIngestSST if a file fails validation, or changed after
+// it was validated, in which case the DB is unchanged.
+func (db *DB) IngestSST(ctx context.Context, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	files := make([]ingestFile, 0, len(paths))
+	for _, path := range paths {
+		file, err := db.readIngestFile(ctx, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return bytes.Compare(files[i].firstKey, files[j].firstKey) < 0
+	})
+	for i := 1; i < len(files); i++ {
+		if bytes.Compare(files[i-1].lastKey, files[i].firstKey) >= 0 {
+			return fmt.Errorf("%w: key range of '%s' overlaps '%s'",
+				common.ErrInvalidIngestSST, files[i].path, files[i-1].path)
+		}
+	}
+
+	if err := db.flushWritesToL0(ctx); err != nil {
+		return fmt.Errorf("while flushing memtable before ingest: %w", err)
+	}
+
+	seq := db.state.ReserveSeq()
+	handles := make([]sstable.Handle, 0, len(files))
+	for _, file := range files {
+		handle, err := db.uploadIngestFile(ctx, file, seq)
+		if err != nil {
+			db.deleteIngested(handles)
+			return fmt.Errorf("while uploading '%s': %w", file.path, err)
+		}
+		handles = append(handles, *handle)
+	}
+
+	// The SSTables are added to L0 and written to the manifest without a memtable
+	// flush in between, which would write the manifest concurrently
+	db.memtableFlushMu.Lock()
+	defer db.memtableFlushMu.Unlock()
+	db.state.AddL0(handles, seq)
+	flusher := MemtableFlusher{
+		db:       db,
+		manifest: db.manifest,
+		log:      db.opts.Log,
+	}
+	if err := flusher.writeManifestSafely(); err != nil {
+		return fmt.Errorf("while writing manifest: %w", err)
+	}
+	return nil
+}
+
+// readIngestFile reads the SSTable file at path and verifies every block decodes
+func (db *DB) readIngestFile(ctx context.Context, path string) (ingestFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ingestFile{}, fmt.Errorf("while reading '%s': %w", path, err)
+	}
+
+	reader, err := sstable.NewReader(sstable.NewIDCompacted(ulid.ULID{}), sstable.NewBytesBlob(data),
+		db.opts.FilterPolicy)
+	if err != nil {
+		return ingestFile{}, fmt.Errorf("%w: '%s': %w", common.ErrInvalidIngestSST, path, err)
+	}
+
+	iter, err := reader.Iterator()
+	if err != nil {
+		return ingestFile{}, fmt.Errorf("%w: '%s': %w", common.ErrInvalidIngestSST, path, err)
+	}
+
+	file := ingestFile{path: path, checksum: crc32.ChecksumIEEE(data), info: reader.Info()}
+	for {
+		entry, ok := iter.NextEntry(ctx)
+		if !ok {
+			break
+		}
+		if file.lastKey != nil && bytes.Compare(file.lastKey, entry.Key) >= 0 {
+			return ingestFile{}, fmt.Errorf("%w: '%s': key '%s' is not greater than the previous key '%s'",
+				common.ErrInvalidIngestSST, path, entry.Key, file.lastKey)
+		}
+		if file.firstKey == nil {
+			file.firstKey = entry.Key
+		}
+		file.lastKey = entry.Key
+	}
+	if err := iter.Warnings().If(); err != nil {
+		return ingestFile{}, fmt.Errorf("%w: '%s': %w", common.ErrInvalidIngestSST, path, err)
+	}
+	if file.firstKey == nil {
+		return ingestFile{}, fmt.Errorf("%w: '%s': %w", common.ErrInvalidIngestSST, path, common.ErrEmptySSTable)
+	}
+	if !bytes.Equal(file.firstKey, file.info.FirstKey) {
+		return ingestFile{}, fmt.Errorf("%w: '%s': first key '%s' does not match info first key '%s'",
+			common.ErrInvalidIngestSST, path, file.firstKey, file.info.FirstKey)
+	}
+	return file, nil
+}
+
+// uploadIngestFile reads the file again, once it is verified to be unchanged since
+// it was validated, and uploads its entries and range tombstones with a new ID
+// and the provided sequence number
+func (db *DB) uploadIngestFile(ctx context.Context, file ingestFile, seq uint64) (*sstable.Handle, error) {
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(data) != file.checksum {
+		return nil, fmt.Errorf("%w: '%s' changed after it was validated", common.ErrInvalidIngestSST, file.path)
+	}
+
+	reader, err := sstable.NewReader(sstable.NewIDCompacted(ulid.ULID{}), sstable.NewBytesBlob(data),
+		db.opts.FilterPolicy)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := reader.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	tombstones, err := reader.RangeTombstones()
+	if err != nil {
+		return nil, err
+	}
+
+	writer := db.tableStore.TableWriterWithOptions(ctx, sstable.NewIDCompacted(ulid.Make()),
+		store.TableWriterOptions{PartSize: db.opts.L0UploadPartSize})
+	for _, tombstone := range tombstones {
+		tombstone.Seq = seq
+		writer.AddRangeTombstone(tombstone)
+	}
+	for {
+		entry, ok := iter.NextEntry(ctx)
+		if !ok {
+			break
+		}
+		entry.Seq = seq
+		if err := writer.AddEntry(entry); err != nil {
+			writer.Abort()
+			return nil, err
+		}
+	}
+	if err := iter.Warnings().If(); err != nil {
+		writer.Abort()
+		return nil, err
+	}
+	return writer.Close()
+}
+
+// deleteIngested removes SSTables uploaded by an ingest which failed, including
+// an ingest which failed because it was canceled
+func (db *DB) deleteIngested(handles []sstable.Handle) {
+	for _, handle := range handles {
+		if err := db.tableStore.DeleteSST(context.Background(), handle.Id); err != nil {
+			db.opts.Log.Warn("failed to delete ingested SST", "id", handle.Id.Value, "error", err)
+		}
+	}
+}
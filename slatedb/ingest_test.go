@@ -0,0 +1,149 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// writeIngestFile builds an SSTable of the keys prefix-000 through prefix-<count-1>
+// and writes it to a file in dir
+func writeIngestFile(t *testing.T, dir string, prefix string, count int) string {
+	builder := sstable.NewBuilder(sstable.DefaultConfig())
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("%s-%03d", prefix, i)
+		require.NoError(t, builder.AddValue([]byte(key), []byte("ingested-"+key)))
+	}
+	table, err := builder.Build()
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, prefix+".sst")
+	require.NoError(t, os.WriteFile(path, sstable.EncodeTable(table), 0o644))
+	return path
+}
+
+func TestIngestSST(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// An existing write of an ingested key is shadowed by the ingested value
+	require.NoError(t, db.Put(ctx, []byte("a-001"), []byte("written")))
+	require.NoError(t, db.Put(ctx, []byte("c-000"), []byte("written")))
+
+	dir := t.TempDir()
+	require.NoError(t, db.IngestSST(ctx, writeIngestFile(t, dir, "b", 10), writeIngestFile(t, dir, "a", 10)))
+
+	for _, key := range []string{"a-000", "a-001", "a-009", "b-005"} {
+		val, err := db.Get(ctx, []byte(key))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("ingested-"+key), val)
+	}
+	val, err := db.Get(ctx, []byte("c-000"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("written"), val)
+
+	// The ingested SSTs are recorded in the manifest
+	id, err := db.manifestStore.LatestManifestID()
+	require.NoError(t, err)
+	core, err := db.manifestStore.ReadManifest(id)
+	require.NoError(t, err)
+	assert.Len(t, core.L0, 3)
+}
+
+func TestIngestSSTAfterDeleteRange(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// A range deletion which completed before the ingest does not delete the
+	// ingested keys, whether they are read by Get or by Scan
+	require.NoError(t, db.Put(ctx, []byte("a-001"), []byte("written")))
+	require.NoError(t, db.DeleteRange(ctx, []byte("a"), []byte("b")))
+	require.NoError(t, db.IngestSST(ctx, writeIngestFile(t, t.TempDir(), "a", 10)))
+
+	val, err := db.Get(ctx, []byte("a-001"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ingested-a-001"), val)
+
+	scan, err := db.Scan(ctx, nil, nil, config.DefaultScanOptions())
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		kv, ok := scan.Next(ctx)
+		require.True(t, ok)
+		key := fmt.Sprintf("a-%03d", i)
+		assert.Equal(t, []byte(key), kv.Key)
+		assert.Equal(t, []byte("ingested-"+key), kv.Value)
+	}
+	_, ok := scan.Next(ctx)
+	assert.False(t, ok)
+	require.NoError(t, scan.Close())
+
+	// A range deletion after the ingest deletes the ingested keys
+	require.NoError(t, db.DeleteRange(ctx, []byte("a-005"), []byte("b")))
+	_, err = db.Get(ctx, []byte("a-007"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	scan, err = db.Scan(ctx, nil, nil, config.DefaultScanOptions())
+	require.NoError(t, err)
+	count := 0
+	for _, ok := scan.Next(ctx); ok; _, ok = scan.Next(ctx) {
+		count++
+	}
+	assert.Equal(t, 5, count)
+	require.NoError(t, scan.Close())
+}
+
+func TestIngestSSTInvalid(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := t.TempDir()
+	first := writeIngestFile(t, dir, "a", 10)
+
+	t.Run("Overlapping", func(t *testing.T) {
+		overlapping := writeIngestFile(t, t.TempDir(), "a", 5)
+		err := db.IngestSST(ctx, first, overlapping)
+		assert.ErrorIs(t, err, common.ErrInvalidIngestSST)
+	})
+
+	t.Run("Corrupt Block", func(t *testing.T) {
+		data, err := os.ReadFile(first)
+		require.NoError(t, err)
+		data[0] ^= 0xff
+		corrupt := filepath.Join(dir, "corrupt.sst")
+		require.NoError(t, os.WriteFile(corrupt, data, 0o644))
+
+		err = db.IngestSST(ctx, corrupt)
+		assert.ErrorIs(t, err, common.ErrInvalidIngestSST)
+		assert.ErrorContains(t, err, "checksum mismatch")
+	})
+
+	t.Run("Not An SST", func(t *testing.T) {
+		path := filepath.Join(dir, "garbage.sst")
+		require.NoError(t, os.WriteFile(path, []byte("not an sstable"), 0o644))
+		assert.ErrorIs(t, db.IngestSST(ctx, path), common.ErrInvalidIngestSST)
+	})
+
+	// Nothing was uploaded or added to the DB
+	assert.Empty(t, db.state.L0())
+	_, err = db.Get(ctx, []byte("a-000"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+}
@@ -0,0 +1,206 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/table"
+)
+
+// JournalOp is the kind of write of a JournalWrite
+type JournalOp string
+
+const (
+	JournalPut    JournalOp = "put"
+	JournalDelete JournalOp = "delete"
+	JournalMerge  JournalOp = "merge"
+
+	// JournalDeleteRange deletes the keys in the range [Key, Value)
+	JournalDeleteRange JournalOp = "delete_range"
+)
+
+// JournalWrite is a single Put, Delete or Merge of a JournalRecord
+type JournalWrite struct {
+	Op    JournalOp `json:"op"`
+	Key   []byte    `json:"key"`
+	Value []byte    `json:"value,omitempty"`
+}
+
+// JournalRecord is a call to DB.Put, DB.Delete, DB.Merge, DB.DeleteRange or DB.Write recorded in
+// the DBOptions.Journal. Unlike the WAL, which holds the internal representation
+// of the entries, the journal holds the calls made by the application, so it
+// can be replayed against a DB with different options by ReplayJournal.
+type JournalRecord struct {
+	// Time of the call
+	Time time.Time `json:"time"`
+	// Seq is the sequence number assigned to the first write of the call, which
+	// orders the records and identifies the writes of the call in the DB
+	Seq uint64 `json:"seq"`
+	// Writes holds a single write, or every write of a WriteBatch
+	Writes []JournalWrite `json:"writes"`
+}
+
+// journal encodes a JournalRecord for every write to the DB as a line of JSON
+type journal struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	log *slog.Logger
+}
+
+// newJournal returns a journal which writes to w, or nil if w is nil
+func newJournal(w io.Writer, log *slog.Logger) *journal {
+	if w == nil {
+		return nil
+	}
+	return &journal{enc: json.NewEncoder(w), log: log}
+}
+
+// write writes the range tombstones and entries to the WAL with writeWAL, and
+// records the call in the journal. The journal is locked while the writes are
+// applied, so records are journaled in the order of their sequence numbers. A
+// failure to write to the journal is logged, but doesn't fail the write to the DB.
+func (j *journal) write(tombstones types.RangeTombstones, entries []types.RowEntry,
+	writeWAL func(types.RangeTombstones, []types.RowEntry) *table.WAL) *table.WAL {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	wal := writeWAL(tombstones, entries)
+	if wal == nil {
+		// The conditions of a conditional write were not met, see DB.WriteIf
+		return nil
+	}
+	record := JournalRecord{
+		Time:   time.Now(),
+		Writes: make([]JournalWrite, 0, len(tombstones)+len(entries)),
+	}
+	for _, t := range tombstones {
+		record.Writes = append(record.Writes, JournalWrite{Op: JournalDeleteRange, Key: t.Start, Value: t.End})
+	}
+	if len(tombstones) > 0 {
+		record.Seq = tombstones[0].Seq
+	} else {
+		record.Seq = entries[0].Seq
+	}
+	if len(entries) > 0 && !entries[0].Created.IsZero() {
+		record.Time = entries[0].Created
+	}
+	for _, entry := range entries {
+		w := JournalWrite{Op: JournalPut, Key: entry.Key, Value: entry.Value.Value}
+		switch entry.Value.Kind {
+		case types.KindTombStone:
+			w = JournalWrite{Op: JournalDelete, Key: entry.Key}
+		case types.KindMerge:
+			w.Op = JournalMerge
+		}
+		record.Writes = append(record.Writes, w)
+	}
+
+	j.encode(record)
+	return wal
+}
+
+// writeRange writes a range tombstone to the WAL with writeWAL, and records the
+// call in the journal, see write
+func (j *journal) writeRange(start []byte, end []byte, lastSeq func() uint64, writeWAL func() *table.WAL) *table.WAL {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	wal := writeWAL()
+	j.encode(JournalRecord{
+		Time:   time.Now(),
+		Seq:    lastSeq(),
+		Writes: []JournalWrite{{Op: JournalDeleteRange, Key: start, Value: end}},
+	})
+	return wal
+}
+
+// encode writes the record to the journal, logging any failure
+func (j *journal) encode(record JournalRecord) {
+	if err := j.enc.Encode(record); err != nil {
+		j.log.Warn("failed to write to the journal", "seq", record.Seq, "error", err)
+	}
+}
+
+// ReplayJournal applies every JournalRecord read from r to the DB in the order
+// they were journaled, and returns the number of records replayed. A record with
+// several writes is applied as a single WriteBatch. Replaying merge operands
+// requires the DB to have a DBOptions.MergeOperator.
+func ReplayJournal(ctx context.Context, r io.Reader, db *DB) (int, error) {
+	dec := json.NewDecoder(r)
+	var replayed int
+	for {
+		if err := ctx.Err(); err != nil {
+			return replayed, err
+		}
+
+		var record JournalRecord
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return replayed, nil
+			}
+			return replayed, fmt.Errorf("while decoding journal record %d: %w", replayed+1, err)
+		}
+		if err := replayRecord(ctx, db, record); err != nil {
+			return replayed, fmt.Errorf("while replaying journal record with seq %d: %w", record.Seq, err)
+		}
+		replayed++
+	}
+}
+
+// replayRecord applies the writes of the record to the DB
+func replayRecord(ctx context.Context, db *DB, record JournalRecord) error {
+	for _, w := range record.Writes {
+		if len(w.Key) == 0 {
+			return errors.New("journal write has an empty key")
+		}
+		if w.Op == JournalMerge && db.opts.MergeOperator == nil {
+			return errors.New("journal holds merge operands but DBOptions.MergeOperator is not set")
+		}
+	}
+
+	if len(record.Writes) == 1 {
+		w := record.Writes[0]
+		switch w.Op {
+		case JournalPut:
+			return db.Put(ctx, w.Key, w.Value)
+		case JournalDelete:
+			return db.Delete(ctx, w.Key)
+		case JournalMerge:
+			return db.Merge(ctx, w.Key, w.Value)
+		case JournalDeleteRange:
+			if bytes.Compare(w.Key, w.Value) >= 0 {
+				return errors.New("journal holds an empty range")
+			}
+			return db.DeleteRange(ctx, w.Key, w.Value)
+		default:
+			return fmt.Errorf("unknown journal op '%s'", w.Op)
+		}
+	}
+
+	batch := NewWriteBatch()
+	for _, w := range record.Writes {
+		switch w.Op {
+		case JournalPut:
+			batch.Put(w.Key, w.Value)
+		case JournalDelete:
+			batch.Delete(w.Key)
+		case JournalDeleteRange:
+			if bytes.Compare(w.Key, w.Value) >= 0 {
+				return errors.New("journal holds an empty range")
+			}
+			batch.DeleteRange(w.Key, w.Value)
+		default:
+			return fmt.Errorf("journal op '%s' is not supported in a batch", w.Op)
+		}
+	}
+	return db.Write(ctx, batch, config.DefaultWriteOptions())
+}
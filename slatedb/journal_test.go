@@ -0,0 +1,67 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestJournalReplay(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	options := testDBOptions(0, 1024)
+	options.Journal = &buf
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	require.NoError(t, db.Delete(ctx, []byte("key1")))
+	batch := NewWriteBatch()
+	batch.Put([]byte("key3"), []byte("value3"))
+	batch.Delete([]byte("key2"))
+	batch.DeleteRange([]byte("key0"), []byte("key2"))
+	require.NoError(t, db.Write(ctx, batch, config.DefaultWriteOptions()))
+	require.NoError(t, db.Close())
+
+	// Every call is journaled in order with its sequence number
+	var records []JournalRecord
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for dec.More() {
+		var record JournalRecord
+		require.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 4)
+	assert.Equal(t, []JournalWrite{{Op: JournalDelete, Key: []byte("key1")}}, records[2].Writes)
+	require.Len(t, records[3].Writes, 3)
+	assert.Equal(t, JournalWrite{Op: JournalDeleteRange, Key: []byte("key0"), Value: []byte("key2")}, records[3].Writes[0])
+	for i := 1; i < len(records); i++ {
+		assert.Greater(t, records[i].Seq, records[i-1].Seq)
+	}
+
+	// Replaying the journal against a fresh DB reproduces the writes
+	replica, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer replica.Close()
+
+	n, err := ReplayJournal(ctx, bytes.NewReader(buf.Bytes()), replica)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	_, err = replica.Get(ctx, []byte("key1"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	_, err = replica.Get(ctx, []byte("key2"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+	val, err := replica.Get(ctx, []byte("key3"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value3"), val)
+}
@@ -0,0 +1,148 @@
+package slatedb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// Maintenance runs once each task otherwise run in the background, for DBs opened
+// with DBOptions.DisableBackgroundTasks. Writes are flushed to the WAL and to L0
+// by FlushNow, the manifest is refreshed, which detects a newer writer, manifest
+// versions beyond DBOptions.ManifestRetainVersions and WAL SSTables beyond
+// DBOptions.WALRetainCount are pruned, the heartbeat is published if
+// DBOptions.HeartbeatInterval elapsed since the last one, and if
+// DBOptions.CompactorOptions is set, compactions are run by CompactOnce.
+//
+// Call Maintenance before the process may be frozen, such as at the end of each
+// invocation of a serverless function. Returns common.ErrInvalidOptions if the DB
+// runs background tasks.
+func (db *DB) Maintenance(ctx context.Context) error {
+	if db.maintenance == nil {
+		return fmt.Errorf("%w: maintenance requires DBOptions.DisableBackgroundTasks", common.ErrInvalidOptions)
+	}
+
+	if err := db.FlushNow(ctx); err != nil {
+		return err
+	}
+	// Honour the flushes requested by DBOptions.WriteBufferManager meanwhile
+	for len(db.memtableFlushNotifierCh) > 0 {
+		if msg := <-db.memtableFlushNotifierCh; msg == FlushMemtable {
+			err := db.flushWriteBuffer(ctx, db.maintenance)
+			db.background.recordFor(ctx, taskFlushMemtable, err)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := db.maintenance.refreshManifest(); err != nil {
+		return fmt.Errorf("while loading manifest: %w", err)
+	}
+	if err := db.maintenance.pruneManifests(); err != nil {
+		db.opts.Log.Warn("error pruning manifests", "error", err)
+	}
+	if err := db.maintenance.pruneWALs(ctx); err != nil {
+		db.opts.Log.Warn("error pruning WALs", "error", err)
+	}
+	db.publishHeartbeat(false)
+
+	if db.compactor != nil {
+		return db.CompactOnce(ctx)
+	}
+	return nil
+}
+
+// FlushNow flushes the WAL to object storage, then flushes the memtables frozen
+// as the WAL was applied to them to L0, for DBs opened with
+// DBOptions.DisableBackgroundTasks. Unlike FlushMemtableToL0, the mutable memtable
+// is only flushed once it reaches DBOptions.L0SSTSizeBytes. The requests to the
+// object store are canceled once ctx is done, and a flush canceled by ctx doesn't
+// count towards DBOptions.BackgroundErrorLimit.
+//
+// Returns common.ErrReadOnly if the DB is read-only, see config.BackgroundErrorReadOnly,
+// and common.ErrInvalidOptions if the DB runs background tasks.
+func (db *DB) FlushNow(ctx context.Context) error {
+	if db.maintenance == nil {
+		return fmt.Errorf("%w: FlushNow requires DBOptions.DisableBackgroundTasks", common.ErrInvalidOptions)
+	}
+	if err := db.background.checkWritable(); err != nil {
+		return err
+	}
+
+	err := db.flushWAL(ctx)
+	db.background.recordFor(ctx, taskFlushWAL, err)
+	if err != nil {
+		return fmt.Errorf("while flushing WAL: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err = db.maintenance.flushImmMemtablesToL0(ctx)
+	db.background.recordFor(ctx, taskFlushMemtable, err)
+	if err != nil {
+		return fmt.Errorf("while flushing memtable: %w", err)
+	}
+	return nil
+}
+
+// CompactOnce schedules compactions of the latest manifest, and runs them along
+// with the compactions scheduled as they finish until none remain, for DBs opened
+// with DBOptions.DisableBackgroundTasks. Compactions which fail are scheduled
+// again by the next call.
+//
+// Returns common.ErrInvalidOptions if DBOptions.CompactorOptions is not set, or
+// the DB runs background tasks.
+func (db *DB) CompactOnce(ctx context.Context) error {
+	if db.maintenance == nil {
+		return fmt.Errorf("%w: CompactOnce requires DBOptions.DisableBackgroundTasks", common.ErrInvalidOptions)
+	}
+	if db.compactor == nil {
+		return fmt.Errorf("%w: CompactOnce requires DBOptions.CompactorOptions", common.ErrInvalidOptions)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return db.compactor.orchestrator.compactOnce(ctx)
+}
+
+// awaitDurable blocks until the WAL whose flush closes done is durable, or returns
+// the error of the context if it is done first. The write is still flushed when
+// the context is done. Without background tasks the WAL is flushed by the write
+// itself. If the flush fails, the write remains waiting for a later flush by
+// FlushNow or Maintenance.
+func (db *DB) awaitDurable(ctx context.Context, done <-chan bool) error {
+	if db.maintenance != nil {
+		err := db.flushWAL(ctx)
+		if err != nil {
+			db.opts.Log.Warn("Flush WAL failed", "error", err)
+		}
+		db.background.record(taskFlushWAL, err)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeWithoutBackgroundTasks flushes the writes and writes the manifest, as the
+// background tasks do when they shut down
+func (db *DB) closeWithoutBackgroundTasks() error {
+	var errs []error
+	if err := db.flushWAL(context.Background()); err != nil {
+		errs = append(errs, fmt.Errorf("while flushing WAL: %w", err))
+	}
+	if err := db.maintenance.flushImmMemtablesToL0(context.Background()); err != nil {
+		errs = append(errs, fmt.Errorf("while flushing memtable: %w", err))
+	}
+	if err := db.maintenance.persistManifest(); err != nil {
+		errs = append(errs, fmt.Errorf("while writing manifest: %w", err))
+	}
+	db.publishHeartbeat(true)
+	return errors.Join(errs...)
+}
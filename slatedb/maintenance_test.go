@@ -0,0 +1,298 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+	"github.com/slatedb/slatedb-go/slatedb/store/storetest"
+)
+
+func TestMaintenanceWithoutBackgroundTasks(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 128)
+	options.DisableBackgroundTasks = true
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+
+	// A write which awaits durability flushes the WAL itself
+	require.NoError(t, db.Put(ctx, []byte("key00"), []byte("value00")))
+	assert.Equal(t, uint64(1), db.CommittedSeq())
+
+	for i := 1; i < 10; i++ {
+		require.NoError(t, db.PutWithOptions(ctx, []byte(fmt.Sprintf("key%02d", i)), repeatedChar('v', 32), config.WriteOptions{AwaitDurable: false}))
+	}
+	assert.Equal(t, uint64(1), db.CommittedSeq())
+	assert.Empty(t, db.state.L0())
+
+	require.NoError(t, db.Maintenance(ctx))
+	assert.Equal(t, uint64(10), db.CommittedSeq())
+	assert.NotEmpty(t, db.state.L0())
+	require.NoError(t, db.Close())
+
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+	val, err := db.Get(ctx, []byte("key00"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value00"), val)
+	for i := 1; i < 10; i++ {
+		val, err := db.Get(ctx, []byte(fmt.Sprintf("key%02d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, repeatedChar('v', 32), val)
+	}
+}
+
+func TestCompactOnce(t *testing.T) {
+	ctx := context.Background()
+	options := dbOptions(compactorOptions().CompactorOptions)
+	options.DisableBackgroundTasks = true
+	_, manifestStore, _, db := buildTestDB(options)
+	defer db.Close()
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48)))
+		require.NoError(t, db.FlushMemtableToL0(ctx))
+	}
+	require.NoError(t, db.CompactOnce(ctx))
+
+	sm, err := store.LoadStoredManifest(manifestStore)
+	require.NoError(t, err)
+	storedManifest, ok := sm.Get()
+	require.True(t, ok)
+	dbState := storedManifest.DbState()
+	assert.True(t, dbState.L0LastCompacted.IsPresent())
+	assert.Empty(t, dbState.L0)
+	assert.Equal(t, 1, len(dbState.Compacted))
+
+	require.NoError(t, db.Maintenance(ctx))
+	for i := 0; i < 4; i++ {
+		val, err := db.Get(ctx, repeatedChar(rune('a'+i), 16))
+		require.NoError(t, err)
+		assert.Equal(t, repeatedChar(rune('b'+i), 48), val)
+	}
+}
+
+func TestMaintenanceRequiresDisabledBackgroundTasks(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.ErrorIs(t, db.Maintenance(ctx), common.ErrInvalidOptions)
+	assert.ErrorIs(t, db.FlushNow(ctx), common.ErrInvalidOptions)
+	assert.ErrorIs(t, db.CompactOnce(ctx), common.ErrInvalidOptions)
+}
+
+func TestMaintenancePrunesWALs(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024)
+	options.DisableBackgroundTasks = true
+	options.WALRetainCount = 2
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value%02d", i))))
+	}
+	walIDs, err := db.tableStore.GetWalSSTList(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, walIDs)
+
+	// A checkpoint pinned before the WALs were flushed to L0 retains them, as a
+	// replica bootstrapped from the checkpoint tails them
+	checkpointID, err := db.manifestStore.LatestManifestID()
+	require.NoError(t, err)
+	require.NoError(t, db.manifestStore.PinManifest(checkpointID))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Maintenance(ctx))
+	walIDs, err = db.tableStore.GetWalSSTList(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, walIDs)
+
+	// Without the checkpoint, only the most recent WALRetainCount WALs remain
+	require.NoError(t, db.manifestStore.UnpinManifest(checkpointID))
+	require.NoError(t, db.Maintenance(ctx))
+	walIDs, err = db.tableStore.GetWalSSTList(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5, 6}, walIDs)
+
+	// WALs uploaded more recently than WALRetainDuration are retained
+	require.NoError(t, db.Put(ctx, []byte("key05"), []byte("value05")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	db.opts.WALRetainDuration = time.Hour
+	require.NoError(t, db.Maintenance(ctx))
+	walIDs, err = db.tableStore.GetWalSSTList(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5, 6, 7}, walIDs)
+	require.NoError(t, db.Close())
+
+	db, err = Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+	for i := 0; i < 6; i++ {
+		val, err := db.Get(ctx, []byte(fmt.Sprintf("key%02d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, []byte(fmt.Sprintf("value%02d", i)), val)
+	}
+}
+
+func TestMaintenanceRecoversFromObjectStoreFaults(t *testing.T) {
+	ctx := context.Background()
+	objectStore := storetest.NewMemObjectStore()
+	dbPath := "/tmp/test_kv_store"
+	options := dbOptions(compactorOptions().CompactorOptions)
+	options.DisableBackgroundTasks = true
+	db, err := OpenWithObjectStore(ctx, dbPath, objectStore, options)
+	require.NoError(t, err)
+	// Every request is slow
+	objectStore.Inject(storetest.Fault{Kind: storetest.FaultDelay, Latency: time.Millisecond})
+
+	// The response of the upload of the first L0 SST is lost, and the manifest
+	// update of the second is throttled. The memtables are flushed again.
+	faults := []storetest.Fault{
+		{Kind: storetest.FaultLostResponse, Op: storetest.OpPut, Path: "compacted/", Count: 1},
+		{Kind: storetest.FaultFail, Op: storetest.OpPutIfNotExists, Path: "manifest/", Count: 1, Err: storetest.ErrThrottled},
+	}
+	for i := 0; i < 4; i++ {
+		require.NoError(t, db.Put(ctx, repeatedChar(rune('a'+i), 16), repeatedChar(rune('b'+i), 48)))
+		if i < len(faults) {
+			objectStore.Inject(faults[i])
+			require.Error(t, db.FlushMemtableToL0(ctx))
+			require.NoError(t, db.FlushNow(ctx))
+		} else {
+			require.NoError(t, db.FlushMemtableToL0(ctx))
+		}
+	}
+	assert.Len(t, db.state.L0(), 4)
+
+	// The compaction fails to read an L0 SST, and then to write its output. The
+	// compactions are scheduled again by the next call.
+	objectStore.Inject(storetest.Fault{Kind: storetest.FaultPartialRead, Path: "compacted/", Count: 1})
+	assert.Error(t, db.CompactOnce(ctx))
+	objectStore.Inject(storetest.Fault{Kind: storetest.FaultFail, Op: storetest.OpPut, Path: "compacted/", Count: 1})
+	assert.Error(t, db.CompactOnce(ctx))
+	require.NoError(t, db.CompactOnce(ctx))
+	require.NoError(t, db.Maintenance(ctx))
+	assert.Empty(t, db.state.L0())
+	require.NoError(t, db.Close())
+
+	db, err = OpenWithObjectStore(ctx, dbPath, objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+	for i := 0; i < 4; i++ {
+		val, err := db.Get(ctx, repeatedChar(rune('a'+i), 16))
+		require.NoError(t, err)
+		assert.Equal(t, repeatedChar(rune('b'+i), 48), val)
+	}
+}
+
+func TestObjectStoreRequestsAreRetried(t *testing.T) {
+	ctx := context.Background()
+	objectStore := storetest.NewMemObjectStore()
+	dbPath := "/tmp/test_kv_store"
+	options := dbOptions(compactorOptions().CompactorOptions)
+	options.DisableBackgroundTasks = true
+	options.ObjectStoreRetry = config.ObjectStoreRetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	db, err := OpenWithObjectStore(ctx, dbPath, objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The upload of the L0 SST and the throttled manifest update succeed when retried
+	objectStore.Inject(storetest.Fault{Kind: storetest.FaultFail, Op: storetest.OpPut, Path: "compacted/", Count: 2})
+	objectStore.Inject(storetest.Fault{Kind: storetest.FaultFail, Op: storetest.OpPutIfNotExists, Path: "manifest/",
+		Count: 1, Err: storetest.ErrThrottled})
+	require.NoError(t, db.Put(ctx, []byte("key"), []byte("value")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	assert.Len(t, db.state.L0(), 1)
+	assert.Equal(t, store.RetryStats{Retries: 3}, db.Stats().Retry)
+}
+
+func TestFlushPipelinesLargeMemtables(t *testing.T) {
+	ctx := context.Background()
+	objectStore := storetest.NewMemObjectStore()
+	dbPath := "/tmp/test_kv_store"
+	options := dbOptions(compactorOptions().CompactorOptions)
+	options.DisableBackgroundTasks = true
+	options.L0SSTSizeBytes = 1024 * 1024
+	options.L0UploadPartSize = 256
+	db, err := OpenWithObjectStore(ctx, dbPath, objectStore, options)
+	require.NoError(t, err)
+
+	// The memtable is built, encoded and uploaded in parts of 256 bytes
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.PutWithOptions(ctx, []byte(fmt.Sprintf("key%03d", i)), repeatedChar('v', 32), config.WriteOptions{AwaitDurable: false}))
+	}
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.Len(t, db.state.L0(), 1)
+	stats := db.Stats().Flush
+	assert.Equal(t, int64(1), stats.Flushes)
+	assert.NotZero(t, stats.Encode)
+	assert.NotZero(t, stats.Upload)
+	assert.GreaterOrEqual(t, stats.Total, stats.Upload)
+	require.NoError(t, db.Close())
+
+	db, err = OpenWithObjectStore(ctx, dbPath, objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		val, err := db.Get(ctx, []byte(fmt.Sprintf("key%03d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, repeatedChar('v', 32), val)
+	}
+}
+
+func TestOperationsAreCanceledByContext(t *testing.T) {
+	ctx := context.Background()
+	objectStore := storetest.NewMemObjectStore()
+	dbPath := "/tmp/test_kv_store"
+	options := dbOptions(compactorOptions().CompactorOptions)
+	options.DisableBackgroundTasks = true
+	options.BackgroundErrorPolicy = config.BackgroundErrorReadOnly
+	options.BackgroundErrorLimit = 1
+	db, err := OpenWithObjectStore(ctx, dbPath, objectStore, options)
+	require.NoError(t, err)
+
+	// The requests are slower than the deadline of the flush, which fails without
+	// counting as a background error
+	objectStore.Inject(storetest.Fault{Kind: storetest.FaultDelay, Latency: time.Second})
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key"), []byte("value"), config.WriteOptions{AwaitDurable: false}))
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, db.FlushNow(timeout), context.DeadlineExceeded)
+	require.NoError(t, db.Health())
+
+	// The writes are flushed by the next flush
+	objectStore.ClearFaults()
+	require.NoError(t, db.FlushNow(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Close())
+
+	// Reading the SSTable is canceled along with the read
+	db, err = OpenWithObjectStore(ctx, dbPath, objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+	objectStore.Inject(storetest.Fault{Kind: storetest.FaultDelay, Op: storetest.OpGetRange, Latency: time.Second})
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = db.Get(canceled, []byte("key"))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	objectStore.ClearFaults()
+	val, err := db.Get(ctx, []byte("key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), val)
+}
@@ -3,6 +3,7 @@ package manifest
 import (
 	"bytes"
 	"encoding/binary"
+	"time"
 
 	flatbuffers "github.com/google/flatbuffers/go"
 	"github.com/oklog/ulid/v2"
@@ -42,6 +43,7 @@ func (f FlatBufferManifestCodec) manifest(manifest *flatbuf.ManifestV1T) *Manife
 	}
 	core.NextWalSstID.Store(manifest.WalIdLastSeen + 1)
 	core.LastCompactedWalSSTID.Store(manifest.WalIdLastCompacted)
+	core.LastL0Seq.Store(manifest.LastL0Seq)
 
 	l0LastCompacted := f.parseFlatBufSSTId(manifest.L0LastCompacted)
 	if l0LastCompacted == ulid.Zero {
@@ -54,9 +56,28 @@ func (f FlatBufferManifestCodec) manifest(manifest *flatbuf.ManifestV1T) *Manife
 	m.Core = core.ToCoreState()
 	m.WriterEpoch.Store(manifest.WriterEpoch)
 	m.CompactorEpoch.Store(manifest.CompactorEpoch)
+	if manifest.FormatOptions != nil {
+		m.FormatOptions = mo.Some(f.parseFlatBufFormatOptions(manifest.FormatOptions))
+	}
+	if manifest.Writer != nil {
+		m.Writer = mo.Some(WriterInfo{
+			Host:       manifest.Writer.Host,
+			PID:        manifest.Writer.Pid,
+			StartTime:  time.UnixMilli(manifest.Writer.StartTimeMs),
+			InstanceID: manifest.Writer.InstanceId,
+		})
+	}
 	return m
 }
 
+func (f FlatBufferManifestCodec) parseFlatBufFormatOptions(opts *flatbuf.FormatOptionsT) FormatOptions {
+	return FormatOptions{
+		Comparator:         opts.Comparator,
+		BlockFormatVersion: opts.BlockFormatVersion,
+		CompressionCodec:   compress.CodecFromFlatBuf(opts.CompressionFormat),
+	}
+}
+
 func (f FlatBufferManifestCodec) parseFlatBufSSTId(sstID *flatbuf.CompactedSstIdT) ulid.ULID {
 	if sstID == nil || (sstID.High == 0 && sstID.Low == 0) {
 		return ulid.Zero
@@ -76,8 +97,9 @@ func (f FlatBufferManifestCodec) parseFlatBufSSTList(fbSSTList []*flatbuf.Compac
 	for _, sst := range fbSSTList {
 		id := f.parseFlatBufSSTId(sst.Id)
 		sstList = append(sstList, sstable.Handle{
-			Id:   sstable.NewIDCompacted(id),
-			Info: f.parseFlatBufSSTInfo(sst.Info),
+			Id:     sstable.NewIDCompacted(id),
+			Info:   f.parseFlatBufSSTInfo(sst.Info),
+			Inline: bytes.Clone(sst.Data),
 		})
 	}
 	return sstList
@@ -91,6 +113,22 @@ func (f FlatBufferManifestCodec) parseFlatBufSSTInfo(info *flatbuf.SsTableInfoT)
 		FilterOffset:     info.FilterOffset,
 		FilterLen:        info.FilterLen,
 		CompressionCodec: compress.CodecFromFlatBuf(info.CompressionFormat),
+		IndexPartitioned: info.IndexPartitioned,
+		LastKey:          bytes.Clone(info.LastKey),
+		EntryCount:       info.EntryCount,
+		TombstoneCount:   info.TombstoneCount,
+		RawSize:          info.RawSize,
+		CompressedSize:   info.CompressedSize,
+		FilterPolicy:     info.FilterPolicy,
+
+		CompressionDictOffset: info.CompressionDictOffset,
+		CompressionDictLen:    info.CompressionDictLen,
+		FilterBitsPerKey:      info.FilterBitsPerKey,
+		BlockSize:             info.BlockSize,
+		RangeTombstoneOffset:  info.RangeTombstoneOffset,
+		RangeTombstoneLen:     info.RangeTombstoneLen,
+		WriterEpoch:           info.WriterEpoch,
+		BlockFormatVersion:    info.BlockFormatVersion,
 	}
 }
 
@@ -126,6 +164,19 @@ func (fb *DBFlatBufferBuilder) createManifest(manifest *Manifest) []byte {
 		l0LastCompacted = fb.compactedSSTID(id)
 	}
 	compacted := fb.sortedRunsToFlatBuf(core.Compacted)
+	var formatOptions *flatbuf.FormatOptionsT
+	if opts, ok := manifest.FormatOptions.Get(); ok {
+		formatOptions = fb.formatOptions(opts)
+	}
+	var writer *flatbuf.WriterInfoT
+	if info, ok := manifest.Writer.Get(); ok {
+		writer = &flatbuf.WriterInfoT{
+			Host:        info.Host,
+			Pid:         info.PID,
+			StartTimeMs: info.StartTime.UnixMilli(),
+			InstanceId:  info.InstanceID,
+		}
+	}
 
 	manifestV1 := flatbuf.ManifestV1T{
 		ManifestId:         0,
@@ -137,30 +188,42 @@ func (fb *DBFlatBufferBuilder) createManifest(manifest *Manifest) []byte {
 		L0:                 l0,
 		Compacted:          compacted,
 		Snapshots:          nil,
+		FormatOptions:      formatOptions,
+		LastL0Seq:          core.LastL0Seq.Load(),
+		Writer:             writer,
 	}
 	manifestOffset := manifestV1.Pack(fb.builder)
 	fb.builder.Finish(manifestOffset)
 	return fb.builder.FinishedBytes()
 }
 
+func (fb *DBFlatBufferBuilder) formatOptions(opts FormatOptions) *flatbuf.FormatOptionsT {
+	return &flatbuf.FormatOptionsT{
+		Comparator:         opts.Comparator,
+		BlockFormatVersion: opts.BlockFormatVersion,
+		CompressionFormat:  compress.CodecToFlatBuf(opts.CompressionCodec),
+	}
+}
+
 func (fb *DBFlatBufferBuilder) sstListToFlatBuf(sstList []sstable.Handle) []*flatbuf.CompactedSsTableT {
 	compactedSSTs := make([]*flatbuf.CompactedSsTableT, 0)
 	for _, sst := range sstList {
-		compactedSSTs = append(compactedSSTs, fb.compactedSST(sst.Id, sst.Info))
+		compactedSSTs = append(compactedSSTs, fb.compactedSST(sst))
 	}
 	return compactedSSTs
 }
 
-func (fb *DBFlatBufferBuilder) compactedSST(sstID sstable.ID, sstInfo *sstable.Info) *flatbuf.CompactedSsTableT {
-	assert.True(sstID.Type == sstable.Compacted, "cannot pass WAL SST handle to create compacted sst")
-	id, err := ulid.Parse(sstID.Value)
+func (fb *DBFlatBufferBuilder) compactedSST(sst sstable.Handle) *flatbuf.CompactedSsTableT {
+	assert.True(sst.Id.Type == sstable.Compacted, "cannot pass WAL SST handle to create compacted sst")
+	id, err := ulid.Parse(sst.Id.Value)
 	if err != nil {
 		return nil
 	}
 
 	return &flatbuf.CompactedSsTableT{
 		Id:   fb.compactedSSTID(id),
-		Info: sstable.SstInfoToFlatBuf(sstInfo),
+		Info: sstable.SstInfoToFlatBuf(sst.Info),
+		Data: sst.Inline,
 	}
 }
 
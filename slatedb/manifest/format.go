@@ -0,0 +1,131 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/slatedb/slatedb-go/internal/compress"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// BytewiseComparator is the name of the comparator which orders keys by
+// comparing their bytes lexicographically. It is the only comparator supported.
+const BytewiseComparator = "bytewise"
+
+// FormatOptions are the options which affect how data is laid out on disk.
+// They are persisted in the manifest so that a client opening an existing DB
+// can detect when its options are incompatible with the data already written.
+type FormatOptions struct {
+	// Name of the comparator used to order keys
+	Comparator string
+
+	// Version of the block row encoding
+	BlockFormatVersion uint16
+
+	// Compression codec used when writing new SSTs
+	CompressionCodec compress.Codec
+}
+
+// FormatMismatch describes a single persisted format option which differs
+// from the option supplied by the client.
+type FormatMismatch struct {
+	// Option is the name of the format option which differs
+	Option string
+
+	// Persisted is the value recorded in the manifest
+	Persisted string
+
+	// Supplied is the value provided by the client
+	Supplied string
+
+	// Safe is true if the DB can be migrated to the supplied value without
+	// rewriting existing data.
+	Safe bool
+}
+
+// CompatibilityReport lists every format option which differs between the
+// manifest and the options supplied by the client. It is returned as an error
+// when a DB cannot be opened with the supplied options.
+type CompatibilityReport struct {
+	Mismatches []FormatMismatch
+}
+
+// CheckCompatibility compares the persisted format options against the supplied
+// ones and returns a CompatibilityReport if any of them differ, or nil if
+// they are compatible.
+func CheckCompatibility(persisted, supplied FormatOptions) *CompatibilityReport {
+	var mismatches []FormatMismatch
+	if persisted.Comparator != supplied.Comparator {
+		mismatches = append(mismatches, FormatMismatch{
+			Option:    "comparator",
+			Persisted: persisted.Comparator,
+			Supplied:  supplied.Comparator,
+			// Existing SSTs are ordered by the persisted comparator
+			Safe: false,
+		})
+	}
+	if persisted.BlockFormatVersion != supplied.BlockFormatVersion && !BlockFormatUpgrade(persisted, supplied) {
+		mismatches = append(mismatches, FormatMismatch{
+			Option:    "block_format_version",
+			Persisted: strconv.Itoa(int(persisted.BlockFormatVersion)),
+			Supplied:  strconv.Itoa(int(supplied.BlockFormatVersion)),
+			// Readers don't understand the block formats written after they
+			// were built
+			Safe: false,
+		})
+	}
+	if persisted.CompressionCodec != supplied.CompressionCodec {
+		mismatches = append(mismatches, FormatMismatch{
+			Option:    "compression_codec",
+			Persisted: persisted.CompressionCodec.String(),
+			Supplied:  supplied.CompressionCodec.String(),
+			// Every SST records the codec it was written with, so
+			// old and new SSTs can be read side by side.
+			Safe: true,
+		})
+	}
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+	return &CompatibilityReport{Mismatches: mismatches}
+}
+
+// BlockFormatUpgrade returns true if the supplied block format version is newer
+// than the persisted version, and the blocks of the persisted version can still
+// be read. Every SST records the version of its blocks, so SSTs of both versions
+// are read side by side, and the SSTs of the persisted version are rewritten as
+// they are compacted. The upgrade is not reported as a mismatch.
+func BlockFormatUpgrade(persisted, supplied FormatOptions) bool {
+	return persisted.BlockFormatVersion < supplied.BlockFormatVersion &&
+		persisted.BlockFormatVersion >= block.MinFormatVersion
+}
+
+// Safe returns true if every mismatch in the report can be migrated
+func (r *CompatibilityReport) Safe() bool {
+	for _, m := range r.Mismatches {
+		if !m.Safe {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *CompatibilityReport) Error() string {
+	parts := make([]string, 0, len(r.Mismatches))
+	for _, m := range r.Mismatches {
+		safety := "unsafe"
+		if m.Safe {
+			safety = "safe"
+		}
+		parts = append(parts, fmt.Sprintf("%s: persisted '%s', supplied '%s' (%s)",
+			m.Option, m.Persisted, m.Supplied, safety))
+	}
+	return fmt.Sprintf("%s: %s", common.ErrIncompatibleOptions, strings.Join(parts, "; "))
+}
+
+func (r *CompatibilityReport) Unwrap() error {
+	return common.ErrIncompatibleOptions
+}
@@ -1,8 +1,13 @@
 package manifest
 
 import (
+	"fmt"
 	"sync/atomic"
+	"time"
 
+	"github.com/samber/mo"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
 	"github.com/slatedb/slatedb-go/slatedb/state"
 )
 
@@ -10,6 +15,78 @@ type Manifest struct {
 	Core           *state.CoreDBState
 	WriterEpoch    atomic.Uint64
 	CompactorEpoch atomic.Uint64
+
+	// FormatOptions is absent for manifests written before
+	// format options were persisted.
+	FormatOptions mo.Option[FormatOptions]
+
+	// Writer is the writer which fenced the previous writer with WriterEpoch. It
+	// is absent for manifests written before writers recorded their identity.
+	Writer mo.Option[WriterInfo]
+}
+
+// WriterInfo identifies the process of a writer, so operators can tell which
+// process holds a DB which is already open.
+type WriterInfo struct {
+	// Hostname of the machine the writer runs on
+	Host string
+
+	// Process ID of the writer
+	PID uint64
+
+	// Time the writer opened the DB
+	StartTime time.Time
+
+	// InstanceID is unique to every DB opened as a writer, which tells apart
+	// writers in the same process, or in processes which reused a PID. It is
+	// empty for writers which did not record it.
+	InstanceID string
+}
+
+func (w WriterInfo) String() string {
+	s := fmt.Sprintf("host %s, pid %d, started at %s", w.Host, w.PID, w.StartTime.Format(time.RFC3339))
+	if w.InstanceID != "" {
+		s += ", instance " + w.InstanceID
+	}
+	return s
+}
+
+// FencedError is returned once a writer or compactor was fenced by a newer client,
+// which incremented the epoch in the manifest. It wraps common.ErrFenced, and
+// tells the fenced client apart from the client which fenced it, so operators can
+// distinguish a failover from two clients running against the same DB.
+type FencedError struct {
+	// Role is the role of the fenced client, "writer" or "compactor"
+	Role string
+
+	// LocalEpoch is the epoch of the fenced client
+	LocalEpoch uint64
+
+	// CurrentEpoch is the epoch of the client which fenced it
+	CurrentEpoch uint64
+
+	// LocalWriter identifies the fenced writer. It is absent for compactors.
+	LocalWriter mo.Option[WriterInfo]
+
+	// CurrentWriter identifies the writer which fenced it. It is absent for
+	// compactors, and for writers which did not record their identity.
+	CurrentWriter mo.Option[WriterInfo]
+}
+
+func (e *FencedError) Error() string {
+	return fmt.Sprintf("%s: %s epoch %d%s was fenced by epoch %d%s", common.ErrFenced, e.Role,
+		e.LocalEpoch, describeWriter(e.LocalWriter), e.CurrentEpoch, describeWriter(e.CurrentWriter))
+}
+
+func (e *FencedError) Unwrap() error {
+	return common.ErrFenced
+}
+
+func describeWriter(writer mo.Option[WriterInfo]) string {
+	if info, ok := writer.Get(); ok {
+		return " (" + info.String() + ")"
+	}
+	return ""
 }
 
 type Codec interface {
@@ -0,0 +1,97 @@
+package slatedb
+
+import (
+	"context"
+
+	"github.com/samber/mo"
+
+	"github.com/slatedb/slatedb-go/internal/assert"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// Merge writes a merge operand for the key, which the DBOptions.MergeOperator
+// applies to the value of the key when it is read or compacted, such that
+// updates like incrementing a counter don't read the value before writing it.
+// Returns errors like DB.Put.
+func (db *DB) Merge(ctx context.Context, key []byte, operand []byte) error {
+	return db.MergeWithOptions(ctx, key, operand, config.DefaultWriteOptions())
+}
+
+func (db *DB) MergeWithOptions(ctx context.Context, key []byte, operand []byte, options config.WriteOptions) error {
+	assert.True(len(key) > 0, "key cannot be empty")
+	assert.True(db.opts.MergeOperator != nil, "DBOptions.MergeOperator is not set")
+
+	currentWAL, err := db.writeEntries(ctx, []types.RowEntry{{Key: key, Value: types.Value{Kind: types.KindMerge, Value: operand}}})
+	if err != nil {
+		return err
+	}
+	if options.AwaitDurable {
+		return db.awaitDurable(ctx, currentWAL.Table().WALFlushed())
+	}
+	return nil
+}
+
+// mergeChain combines the merge operands of a key as the tables of the DB are
+// searched from newest to oldest, until a value or tombstone of the key is found
+// which the operands are applied to
+type mergeChain struct {
+	op      types.MergeOperator
+	key     []byte
+	operand mo.Option[types.Value]
+
+	// rangeSeq is the highest sequence number of the range tombstones which
+	// contain the key in the tables searched so far
+	rangeSeq uint64
+}
+
+func newMergeChain(op types.MergeOperator, key []byte) *mergeChain {
+	return &mergeChain{op: op, key: key}
+}
+
+// addTable adds the entry of the key found in the next older table, if any, along
+// with the range tombstones of the table, and returns the value of the key if it
+// no longer depends on older tables. Entries older than a range tombstone which
+// contains the key are deleted.
+func (c *mergeChain) addTable(entry mo.Option[types.RowEntry], tombstones types.RangeTombstones) (types.Value, bool) {
+	c.rangeSeq = max(c.rangeSeq, tombstones.MaxSeq(c.key))
+	e, ok := entry.Get()
+	if !ok {
+		return types.Value{}, false
+	}
+	if e.Seq < c.rangeSeq {
+		e.Value = types.Value{Kind: types.KindTombStone}
+	}
+	return c.add(e.Value)
+}
+
+// add adds the value of the key found in the next older table, and returns the
+// value of the key if it no longer depends on older tables
+func (c *mergeChain) add(v types.Value) (types.Value, bool) {
+	newer, ok := c.operand.Get()
+	if !ok {
+		if c.op == nil || v.Kind != types.KindMerge {
+			return v, true
+		}
+		c.operand = mo.Some(v)
+		return types.Value{}, false
+	}
+
+	merged := types.MergeValue(c.op, c.key, v, newer.Value)
+	if merged.Kind == types.KindMerge {
+		c.operand = mo.Some(merged)
+		return types.Value{}, false
+	}
+	return merged, true
+}
+
+// resolve returns the value of the key once every table was searched, which is
+// common.ErrKeyNotFound if the key was never written
+func (c *mergeChain) resolve() (types.Value, error) {
+	operand, ok := c.operand.Get()
+	if !ok {
+		return types.Value{}, common.ErrKeyNotFound
+	}
+	return types.ResolveMerge(c.op, c.key, operand), nil
+}
@@ -0,0 +1,126 @@
+package slatedb
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// counterOperator adds the uint64 operand to the existing uint64 value
+type counterOperator struct{}
+
+func (counterOperator) Merge(_ []byte, existing mo.Option[[]byte], operand []byte) []byte {
+	var sum uint64
+	if v, ok := existing.Get(); ok {
+		sum = binary.BigEndian.Uint64(v)
+	}
+	return binary.BigEndian.AppendUint64(nil, sum+binary.BigEndian.Uint64(operand))
+}
+
+func counter(n uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, n)
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024)
+	options.MergeOperator = counterOperator{}
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Operands without a value are applied to an absent value
+	require.NoError(t, db.Merge(ctx, []byte("hits"), counter(1)))
+	require.NoError(t, db.Merge(ctx, []byte("hits"), counter(2)))
+	val, err := db.Get(ctx, []byte("hits"))
+	require.NoError(t, err)
+	assert.Equal(t, counter(3), val)
+
+	// Operands in the memtable are applied to the value flushed to L0
+	require.NoError(t, db.Put(ctx, []byte("total"), counter(10)))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Merge(ctx, []byte("total"), counter(5)))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Merge(ctx, []byte("total"), counter(1)))
+
+	val, err = db.Get(ctx, []byte("total"))
+	require.NoError(t, err)
+	assert.Equal(t, counter(16), val)
+
+	// Operands after a delete start from an absent value
+	require.NoError(t, db.Delete(ctx, []byte("total")))
+	require.NoError(t, db.Merge(ctx, []byte("total"), counter(7)))
+	val, err = db.Get(ctx, []byte("total"))
+	require.NoError(t, err)
+	assert.Equal(t, counter(7), val)
+}
+
+func TestRawMergeOperands(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024)
+	options.MergeOperator = counterOperator{}
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The value and the first operand are each flushed to their own L0 SSTable,
+	// while the last operand remains in the memtable
+	require.NoError(t, db.Put(ctx, []byte("total"), counter(10)))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Merge(ctx, []byte("total"), counter(5)))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Merge(ctx, []byte("total"), counter(1)))
+	require.NoError(t, db.Put(ctx, []byte("other"), counter(2)))
+
+	// Get returns the newest operand rather than the merged value
+	val, err := db.Get(ctx, []byte("total"))
+	require.NoError(t, err)
+	assert.Equal(t, counter(16), val)
+	val, err = db.GetWithOptions(ctx, []byte("total"), config.ReadOptions{RawMergeOperands: true})
+	require.NoError(t, err)
+	assert.Equal(t, counter(1), val)
+
+	scanValues := func(opts config.ScanOptions) [][]byte {
+		scan, err := db.Scan(ctx, nil, nil, opts)
+		require.NoError(t, err)
+		defer scan.Close()
+		var values [][]byte
+		for {
+			kv, ok := scan.Next(ctx)
+			if !ok {
+				return values
+			}
+			if string(kv.Key) == "total" {
+				values = append(values, kv.Value)
+			}
+		}
+	}
+
+	// A scan returns every operand of the key from newest to oldest, followed by
+	// the value they apply to
+	assert.Equal(t, [][]byte{counter(16)}, scanValues(config.ScanOptions{}))
+	assert.Equal(t, [][]byte{counter(1), counter(5), counter(10)},
+		scanValues(config.ScanOptions{RawMergeOperands: true}))
+	assert.Equal(t, [][]byte{counter(5), counter(10)},
+		scanValues(config.ScanOptions{ReadLevel: config.Flushed, RawMergeOperands: true}))
+
+	// A scan of raw merge operands can't be resumed
+	_, err = db.Scan(ctx, nil, nil, config.ScanOptions{
+		ReadLevel:        config.Flushed,
+		RawMergeOperands: true,
+		OnResumeToken:    func([]byte) {},
+	})
+	assert.ErrorIs(t, err, common.ErrInvalidOptions)
+}
@@ -0,0 +1,67 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/slatedb/slatedb-go/internal/assert"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/state"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+	"github.com/slatedb/slatedb-go/slatedb/table"
+)
+
+// DeleteRange deletes every key in the range [start, end) with a single range
+// tombstone, which is much cheaper than deleting each key when dropping a large
+// prefix. Keys written to the range after the call are not deleted. Returns
+// errors like DB.Put.
+func (db *DB) DeleteRange(ctx context.Context, start []byte, end []byte) error {
+	return db.DeleteRangeWithOptions(ctx, start, end, config.DefaultWriteOptions())
+}
+
+func (db *DB) DeleteRangeWithOptions(ctx context.Context, start []byte, end []byte, options config.WriteOptions) error {
+	assert.True(len(start) > 0, "start key cannot be empty")
+	assert.True(bytes.Compare(start, end) < 0, "end key must be greater than the start key")
+	if err := db.stallWrites(ctx); err != nil {
+		return err
+	}
+
+	write := func() *table.WAL { return db.state.WriteRangeTombstoneToWAL(start, end) }
+	var currentWAL *table.WAL
+	if db.journal != nil {
+		currentWAL = db.journal.writeRange(start, end, db.state.LastSeq, write)
+	} else {
+		currentWAL = write()
+	}
+	db.notifyWALFull(currentWAL)
+	if options.AwaitDurable {
+		return db.awaitDurable(ctx, currentWAL.Table().WALFlushed())
+	}
+	return nil
+}
+
+// rangeTombstones returns the range tombstones of the SSTables of core. Every
+// SSTable of a sorted run holds the range tombstones of the sorted run, so only
+// the first SSTable of each sorted run is read.
+func rangeTombstones(ctx context.Context, core *state.CoreStateSnapshot, tableStore *store.TableStore) (types.RangeTombstones, error) {
+	var tombstones types.RangeTombstones
+	for _, sst := range core.L0 {
+		ts, err := tableStore.ReadRangeTombstones(ctx, &sst)
+		if err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, ts...)
+	}
+	for _, sr := range core.Compacted {
+		if len(sr.SSTList) == 0 {
+			continue
+		}
+		ts, err := tableStore.ReadRangeTombstones(ctx, &sr.SSTList[0])
+		if err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, ts...)
+	}
+	return tombstones, nil
+}
@@ -0,0 +1,113 @@
+package slatedb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestDeleteRange(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Keys flushed to L0 are deleted by a tombstone in the memtable
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	require.NoError(t, db.Put(ctx, []byte("key3"), []byte("value3")))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	require.NoError(t, db.DeleteRange(ctx, []byte("key1"), []byte("key3")))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("new")))
+
+	assertKeys := func(expected map[string]string) {
+		for _, key := range []string{"key1", "key2", "key3"} {
+			val, err := db.Get(ctx, []byte(key))
+			if want, ok := expected[key]; ok {
+				require.NoError(t, err)
+				assert.Equal(t, []byte(want), val)
+			} else {
+				assert.ErrorIs(t, err, common.ErrKeyNotFound)
+			}
+		}
+	}
+	assertKeys(map[string]string{"key2": "new", "key3": "value3"})
+
+	// The tombstone is flushed to L0 with the memtable, and applies to scans
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	assertKeys(map[string]string{"key2": "new", "key3": "value3"})
+
+	it, err := db.Scan(ctx, []byte("key1"), []byte("key4"), config.ScanOptions{})
+	require.NoError(t, err)
+	var kvs []types.KeyValue
+	for {
+		kv, ok := it.Next(ctx)
+		if !ok {
+			break
+		}
+		kvs = append(kvs, kv)
+	}
+	assert.Equal(t, []types.KeyValue{
+		{Key: []byte("key2"), Value: []byte("new")},
+		{Key: []byte("key3"), Value: []byte("value3")},
+	}, kvs)
+}
+
+func TestWriteBatchDeleteRange(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	require.NoError(t, db.Put(ctx, []byte("key3"), []byte("value3")))
+
+	// A write of the range before the DeleteRange is deleted, one after it is kept
+	batch := NewWriteBatch()
+	batch.Put([]byte("key1"), []byte("deleted"))
+	batch.Put([]byte("key4"), []byte("value4"))
+	batch.DeleteRange([]byte("key1"), []byte("key3"))
+	batch.Put([]byte("key2"), []byte("new"))
+	assert.Equal(t, 3, batch.Len())
+	future, err := db.WriteAsync(ctx, batch)
+	require.NoError(t, err)
+	require.NoError(t, future.Wait(ctx))
+	assert.Equal(t, db.CommittedSeq(), future.Seq())
+
+	assertKeys := func() {
+		expected := map[string]string{"key2": "new", "key3": "value3", "key4": "value4"}
+		for _, key := range []string{"key1", "key2", "key3", "key4"} {
+			val, err := db.Get(ctx, []byte(key))
+			if want, ok := expected[key]; ok {
+				require.NoError(t, err)
+				assert.Equal(t, []byte(want), val)
+			} else {
+				assert.ErrorIs(t, err, common.ErrKeyNotFound)
+			}
+		}
+	}
+	assertKeys()
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	assertKeys()
+
+	// A batch which only deletes a range resolves once the range is durable
+	batch = NewWriteBatch()
+	batch.DeleteRange([]byte("key3"), []byte("key5"))
+	future, err = db.WriteAsync(ctx, batch)
+	require.NoError(t, err)
+	require.NoError(t, future.Wait(ctx))
+	assert.Equal(t, db.CommittedSeq(), future.Seq())
+	_, err = db.Get(ctx, []byte("key4"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+}
@@ -0,0 +1,219 @@
+package slatedb
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/samber/mo"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// ReplicationPosition is a position in the sequence of writes to a DB, which an
+// external replication system bootstraps from and tails the WAL from. Every write
+// with a sequence number up to Seq is before the position, and every write after
+// it is in the WAL SSTables with an ID of WALID or higher.
+type ReplicationPosition struct {
+	Seq   uint64
+	WALID uint64
+}
+
+// CommittedSeq returns the sequence number of the most recent write which is
+// durable in the WAL in object storage. Writes are flushed in order of sequence
+// number, so every write with a lower sequence number is durable as well. See
+// CommittedSeqChanged to be notified as it advances.
+func (db *DB) CommittedSeq() uint64 {
+	return db.state.CommittedSeq()
+}
+
+// CheckpointPosition returns the position of the checkpoint stored in the manifest
+// version checkpointID of the DB at dbPath, without opening the DB. The checkpoint
+// holds exactly the writes before the position, so a replica bootstrapped from the
+// checkpoint is kept up to date by TailWAL from the position without missing or
+// repeating a write. The checkpoint should be pinned, see store.ManifestStore.PinManifest.
+//
+// SSTables added to L0 by DB.Ingest are not written to the WAL, so they are only
+// replicated by bootstrapping from a later checkpoint.
+func CheckpointPosition(bucket objstore.Bucket, dbPath string, checkpointID uint64) (ReplicationPosition, error) {
+	layout, err := store.LoadLayout(context.Background(), store.NewBucketObjectStore(bucket), dbPath)
+	if err != nil {
+		return ReplicationPosition{}, err
+	}
+	manifestStore := store.NewManifestStore(dbPath, bucket)
+	manifestStore.SetLayout(layout)
+	core, err := manifestStore.ReadManifest(checkpointID)
+	if err != nil {
+		return ReplicationPosition{}, fmt.Errorf("while reading checkpoint manifest '%d': %w", checkpointID, err)
+	}
+	return ReplicationPosition{
+		Seq:   core.LastL0Seq.Load(),
+		WALID: core.LastCompactedWalSSTID.Load() + 1,
+	}, nil
+}
+
+// TailWAL calls fn with a JournalRecord for each write after pos in the WAL of the
+// DB at dbPath, in order of sequence number, and returns the position after the
+// last WAL SSTable read. Each write of a WriteBatch is a record of its own, and
+// ReplayJournal applies the records to a replica. Calling TailWAL again with the
+// returned position continues with the writes flushed since.
+//
+// An incomplete WAL SSTable, left by a writer which crashed while uploading it,
+// holds no acknowledged writes and is skipped. If fn returns an error, TailWAL
+// returns it along with the position of the WAL SSTable being read, which is read
+// again in full by the next call, skipping the writes already passed to fn.
+func TailWAL(
+	ctx context.Context,
+	bucket objstore.Bucket,
+	dbPath string,
+	pos ReplicationPosition,
+	fn func(JournalRecord) error,
+) (ReplicationPosition, error) {
+	layout, err := store.LoadLayout(ctx, store.NewBucketObjectStore(bucket), dbPath)
+	if err != nil {
+		return pos, err
+	}
+	tableStore := store.NewTableStore(bucket, sstable.DefaultConfig(), dbPath)
+	tableStore.SetLayout(layout)
+	tableStore = tableStore.WithIOClass(store.IOClassRecovery)
+	walIDs, err := tableStore.GetWalSSTList(ctx, max(pos.WALID, 1)-1)
+	if err != nil {
+		return pos, err
+	}
+
+	for _, walID := range walIDs {
+		if err := ctx.Err(); err != nil {
+			return pos, err
+		}
+		sst, err := tableStore.OpenSST(ctx, sstable.NewIDWal(walID))
+		if errors.Is(err, common.ErrIncompleteSST) {
+			pos.WALID = walID + 1
+			continue
+		}
+		if err != nil {
+			return pos, fmt.Errorf("while opening WAL '%d': %w", walID, err)
+		}
+		entries, tombstones, err := readWAL(ctx, tableStore, sst)
+		if err != nil {
+			return pos, fmt.Errorf("while reading WAL '%d': %w", walID, err)
+		}
+
+		for _, w := range walWritesBySeq(entries, tombstones) {
+			if w.seq() <= pos.Seq {
+				continue
+			}
+			if err := fn(w.record()); err != nil {
+				return pos, err
+			}
+			pos.Seq = w.seq()
+		}
+		pos.WALID = walID + 1
+	}
+	return pos, nil
+}
+
+// readWAL reads every entry and range tombstone of the WAL SSTable, verifying the
+// checksum of each value. Returns a common.CorruptionError if the WAL is corrupt,
+// along with the entries which could still be read: every entry before the first
+// corrupt block, other than the entries whose values fail their checksums.
+func readWAL(
+	ctx context.Context,
+	tableStore *store.TableStore,
+	sst *sstable.Handle,
+) ([]types.RowEntry, types.RangeTombstones, error) {
+	tombstones, err := tableStore.ReadRangeTombstones(ctx, sst)
+	if err != nil {
+		return nil, nil, err
+	}
+	iter, err := sstable.NewIterator(ctx, sst, tableStore)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []types.RowEntry
+	var corruptValue error
+	for {
+		entry, ok := iter.NextEntry(ctx)
+		if !ok {
+			break
+		}
+		if checksum, ok := entry.Value.Checksum.Get(); ok {
+			if actual := types.ValueChecksum(entry.Value.Value); actual != checksum {
+				if corruptValue == nil {
+					corruptValue = fmt.Errorf("value of key '%s' with seq '%d': %w",
+						entry.Key, entry.Seq, common.NewChecksumError(common.SectionBlock, checksum, actual))
+				}
+				continue
+			}
+			// The checksum only protects the WAL, the memtable records its own
+			// checksum if DBOptions.ValueChecksums is set
+			entry.Value.Checksum = mo.None[uint32]()
+		}
+		entries = append(entries, entry)
+	}
+	if err := iter.Err(); err != nil {
+		return entries, tombstones, err
+	}
+	if err := iter.Warnings().If(); err != nil {
+		return entries, tombstones, err
+	}
+	return entries, tombstones, corruptValue
+}
+
+// walWrite is an entry or a range tombstone of a WAL
+type walWrite struct {
+	entry     types.RowEntry
+	tombstone *types.RangeTombstone
+}
+
+func (w walWrite) seq() uint64 {
+	if w.tombstone != nil {
+		return w.tombstone.Seq
+	}
+	return w.entry.Seq
+}
+
+// record returns the write as a JournalRecord
+func (w walWrite) record() JournalRecord {
+	if t := w.tombstone; t != nil {
+		return JournalRecord{
+			Time:   time.Now(),
+			Seq:    t.Seq,
+			Writes: []JournalWrite{{Op: JournalDeleteRange, Key: t.Start, Value: t.End}},
+		}
+	}
+	write := JournalWrite{Op: JournalPut, Key: w.entry.Key, Value: w.entry.Value.Value}
+	switch w.entry.Value.Kind {
+	case types.KindTombStone:
+		write = JournalWrite{Op: JournalDelete, Key: w.entry.Key}
+	case types.KindMerge:
+		write.Op = JournalMerge
+	}
+	record := JournalRecord{Time: w.entry.Created, Seq: w.entry.Seq, Writes: []JournalWrite{write}}
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	return record
+}
+
+// walWritesBySeq returns the entries and range tombstones of a WAL in order of
+// sequence number. A WAL holds its entries in order of key.
+func walWritesBySeq(entries []types.RowEntry, tombstones types.RangeTombstones) []walWrite {
+	writes := make([]walWrite, 0, len(entries)+len(tombstones))
+	for _, entry := range entries {
+		writes = append(writes, walWrite{entry: entry})
+	}
+	for i := range tombstones {
+		writes = append(writes, walWrite{tombstone: &tombstones[i]})
+	}
+	slices.SortFunc(writes, func(a, b walWrite) int {
+		return cmp.Compare(a.seq(), b.seq())
+	})
+	return writes
+}
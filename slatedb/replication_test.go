@@ -0,0 +1,163 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+func TestCommittedSeq(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(context.Background(), "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: false}))
+	assert.Equal(t, uint64(1), db.state.LastSeq())
+	assert.Equal(t, uint64(0), db.CommittedSeq())
+
+	require.NoError(t, db.FlushWAL(context.Background()))
+	assert.Equal(t, uint64(1), db.CommittedSeq())
+}
+
+func TestTailWALFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	db, err := Open(ctx, dbPath, bucket, testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	checkpointID, err := store.NewManifestStore(dbPath, bucket).LatestManifestID()
+	require.NoError(t, err)
+
+	pos, err := CheckpointPosition(bucket, dbPath, checkpointID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), pos.Seq)
+
+	require.NoError(t, db.Put(ctx, []byte("key3"), []byte("value3")))
+	require.NoError(t, db.Delete(ctx, []byte("key1")))
+	require.NoError(t, db.DeleteRange(ctx, []byte("key2"), []byte("key3")))
+
+	var records []JournalRecord
+	pos, err = TailWAL(ctx, bucket, dbPath, pos, func(r JournalRecord) error {
+		records = append(records, r)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, db.CommittedSeq(), pos.Seq)
+	assert.Equal(t, db.state.NextWALID(), pos.WALID)
+
+	require.Len(t, records, 3)
+	assert.Equal(t, uint64(3), records[0].Seq)
+	assert.Equal(t, []JournalWrite{{Op: JournalPut, Key: []byte("key3"), Value: []byte("value3")}}, records[0].Writes)
+	assert.Equal(t, []JournalWrite{{Op: JournalDelete, Key: []byte("key1")}}, records[1].Writes)
+	assert.Equal(t, []JournalWrite{{Op: JournalDeleteRange, Key: []byte("key2"), Value: []byte("key3")}}, records[2].Writes)
+
+	// Tailing from the returned position finds only the writes flushed since
+	require.NoError(t, db.Put(ctx, []byte("key4"), []byte("value4")))
+	records = nil
+	_, err = TailWAL(ctx, bucket, dbPath, pos, func(r JournalRecord) error {
+		records = append(records, r)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, []byte("key4"), records[0].Writes[0].Key)
+
+	// The records replayed against a DB bootstrapped from the checkpoint match the DB
+	replica, err := Open(ctx, "/tmp/test_replica", objstore.NewInMemBucket(), testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer replica.Close()
+	require.NoError(t, replica.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, replica.Put(ctx, []byte("key2"), []byte("value2")))
+	pos, err = CheckpointPosition(bucket, dbPath, checkpointID)
+	require.NoError(t, err)
+	_, err = TailWAL(ctx, bucket, dbPath, pos, func(r JournalRecord) error {
+		return replayRecord(ctx, replica, r)
+	})
+	require.NoError(t, err)
+	for _, key := range []string{"key1", "key2", "key3", "key4"} {
+		want, wantErr := db.Get(ctx, []byte(key))
+		got, gotErr := replica.Get(ctx, []byte(key))
+		assert.Equal(t, wantErr, gotErr, key)
+		assert.Equal(t, want, got, key)
+	}
+}
+
+// TestCheckpointPositionWithinWAL checks the writes of a WAL only partially
+// flushed to L0 are either in the checkpoint or tailed from the WAL, but not both.
+func TestCheckpointPositionWithinWAL(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	options := testDBOptions(0, 1024*1024)
+	options.MaxMemtableBytes = 256
+	db, err := Open(ctx, dbPath, bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The keys are written in the reverse of the order of their sequence numbers
+	batch := NewWriteBatch()
+	for i := 19; i >= 0; i-- {
+		batch.Put([]byte(fmt.Sprintf("key-%02d", i)), repeatedChar('v', 32))
+	}
+	require.NoError(t, db.Write(ctx, batch, config.WriteOptions{AwaitDurable: false}))
+	require.NoError(t, db.FlushWAL(ctx))
+
+	manifestStore := store.NewManifestStore(dbPath, bucket)
+	var checkpointID uint64
+	require.Eventually(t, func() bool {
+		checkpointID, err = manifestStore.LatestManifestID()
+		require.NoError(t, err)
+		core, err := manifestStore.ReadManifest(checkpointID)
+		require.NoError(t, err)
+		return len(core.L0) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, manifestStore.PinManifest(checkpointID))
+	defer func() { _ = manifestStore.UnpinManifest(checkpointID) }()
+
+	core, err := manifestStore.ReadManifest(checkpointID)
+	require.NoError(t, err)
+	tableStore := store.NewTableStore(bucket, sstable.DefaultConfig(), dbPath)
+	it, err := newCoreIterator(ctx, core, tableStore, nil, sstable.IteratorOptions{})
+	require.NoError(t, err)
+	seen := make(map[string]int)
+	for {
+		entry, ok := it.NextEntry(ctx)
+		if !ok {
+			break
+		}
+		seen[string(entry.Key)]++
+	}
+	require.NotEmpty(t, seen)
+
+	pos, err := CheckpointPosition(bucket, dbPath, checkpointID)
+	require.NoError(t, err)
+	expected := pos.Seq + 1
+	_, err = TailWAL(ctx, bucket, dbPath, pos, func(r JournalRecord) error {
+		assert.Equal(t, expected, r.Seq)
+		expected++
+		seen[string(r.Writes[0].Key)]++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, db.CommittedSeq()+1, expected)
+
+	assert.Len(t, seen, 20)
+	for key, count := range seen {
+		assert.Equal(t, 1, count, key)
+	}
+}
@@ -0,0 +1,585 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/samber/mo"
+
+	"github.com/slatedb/slatedb-go/internal/iter"
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/compaction"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/state"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+	"github.com/slatedb/slatedb-go/slatedb/table"
+)
+
+// resumeTokenVersion is the version of the encoded resume token. Tokens of
+// version 1 do not record the sequence number of the manifest version, and
+// tokens before version 3 do not record the ID of the pin of the scan.
+const resumeTokenVersion byte = 3
+
+// resumeToken records the progress of a scan. The manifest version the scan
+// reads from is pinned, so the same SSTables can be read when the scan resumes.
+type resumeToken struct {
+	manifestID uint64
+	// seq is the LastL0Seq of the manifest version, which identifies the view of
+	// the data the scan reads, as manifest IDs restart if the DB is recreated
+	seq   mo.Option[uint64]
+	start []byte
+	end   []byte
+	// lastKey is the last key returned by the scan, nil if no key was returned yet
+	lastKey []byte
+	// pinID is the ID of the pin of the manifest version held by the scan, which
+	// is reused when the scan resumes
+	pinID string
+}
+
+func (t resumeToken) encode() []byte {
+	buf := []byte{resumeTokenVersion}
+	buf = binary.BigEndian.AppendUint64(buf, t.manifestID)
+	buf = binary.BigEndian.AppendUint64(buf, t.seq.OrEmpty())
+	for _, b := range [][]byte{t.start, t.end, t.lastKey, []byte(t.pinID)} {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(b)))
+		buf = append(buf, b...)
+	}
+	return buf
+}
+
+func decodeResumeToken(buf []byte) (resumeToken, error) {
+	if len(buf) < 1+common.SizeOfUint64 || buf[0] < 1 || buf[0] > resumeTokenVersion {
+		return resumeToken{}, common.ErrInvalidResumeToken
+	}
+	version := buf[0]
+	t := resumeToken{manifestID: binary.BigEndian.Uint64(buf[1:])}
+	buf = buf[1+common.SizeOfUint64:]
+	if version >= 2 {
+		if len(buf) < common.SizeOfUint64 {
+			return resumeToken{}, common.ErrInvalidResumeToken
+		}
+		t.seq = mo.Some(binary.BigEndian.Uint64(buf))
+		buf = buf[common.SizeOfUint64:]
+	}
+
+	fields := make([][]byte, 3)
+	if version >= 3 {
+		fields = append(fields, nil)
+	}
+	for i := range fields {
+		if len(buf) < common.SizeOfUint32 {
+			return resumeToken{}, common.ErrInvalidResumeToken
+		}
+		size := int(binary.BigEndian.Uint32(buf))
+		buf = buf[common.SizeOfUint32:]
+		if len(buf) < size {
+			return resumeToken{}, common.ErrInvalidResumeToken
+		}
+		if size > 0 {
+			fields[i] = bytes.Clone(buf[:size])
+		}
+		buf = buf[size:]
+	}
+	t.start, t.end, t.lastKey = fields[0], fields[1], fields[2]
+	if version >= 3 {
+		t.pinID = string(fields[3])
+	}
+	return t, nil
+}
+
+// ScanIterator iterates over the keys of the DB in order as of the manifest
+// version the scan was started with, and of the memtables if the scan reads them.
+type ScanIterator struct {
+	db      *DB
+	iter    *iter.MergeSort
+	token   resumeToken
+	opts    config.ScanOptions
+	count   int
+	done    bool
+	closed  bool
+	skipKey []byte
+	// pin is the pin of the manifest version held by the scan, which is absent
+	// if the manifest version is pinned by a Snapshot instead
+	pin mo.Option[store.ManifestPin]
+	// unresumable is true if the scan reads the memtables, see config.ScanOptions.ReadLevel,
+	// or raw merge operands
+	unresumable bool
+}
+
+// Scan returns an iterator over the keys in the range [start, end) of the
+// memtables, and of the WALs for config.Uncommitted, merged with the SSTables of
+// the DB, like DB.GetWithOptions. A nil start or end scans from the first key or
+// to the last key. Newer writes of a key take precedence over older ones, and
+// deleted keys are skipped. Such a scan can't be resumed.
+//
+// If opts.ReadLevel is config.Flushed, the scan reads only the latest manifest
+// version, which only includes writes already flushed to L0. The manifest version
+// is pinned, so it is not pruned before ScanIterator.Close is called, which allows
+// the scan to be resumed with DB.ResumeScan even after the process restarts.
+func (db *DB) Scan(ctx context.Context, start []byte, end []byte, opts config.ScanOptions) (*ScanIterator, error) {
+	if opts.RawMergeOperands && opts.OnResumeToken != nil {
+		return nil, fmt.Errorf("%w: a scan of raw merge operands can't be resumed", common.ErrInvalidOptions)
+	}
+	if opts.ReadLevel != config.Flushed {
+		return db.scanTables(ctx, start, end, opts)
+	}
+	for {
+		manifestID, err := db.manifestStore.LatestManifestID()
+		if err != nil {
+			return nil, fmt.Errorf("while reading latest manifest: %w", err)
+		}
+
+		token := resumeToken{
+			manifestID: manifestID,
+			start:      bytes.Clone(start),
+			end:        bytes.Clone(end),
+		}
+		scan, err := db.scan(ctx, token, opts)
+		// The manifest version was pruned before it was pinned, as newer
+		// versions were written meanwhile, so the scan reads the latest version
+		if errors.Is(err, common.ErrManifestNotFound) {
+			continue
+		}
+		return scan, err
+	}
+}
+
+// ScanPrefix returns an iterator over the keys which start with the prefix, see
+// DB.Scan. SSTables whose filter excludes the prefix, such as the filter created
+// by filter.NewPartitionedPolicy, are skipped without reading their blocks.
+func (db *DB) ScanPrefix(ctx context.Context, prefix []byte, opts config.ScanOptions) (*ScanIterator, error) {
+	return db.Scan(ctx, prefix, prefixEnd(prefix), opts)
+}
+
+// ResumeScan continues the scan which issued the resume token, starting after
+// the last key returned before the token was issued, reading the same manifest
+// version as the scan which issued it. Like the scan which issued it, the resumed
+// scan reads only writes flushed to L0, so opts.ReadLevel must be zero or
+// config.Flushed, and opts.RawMergeOperands must not be set.
+//
+// The resumed scan takes over the pin of the manifest version held by the scan
+// which issued the token, so closing the resumed scan releases the pin of a scan
+// which was abandoned without being closed, such as by a process which crashed.
+// As closing either scan releases the pin, the scan which issued the token should
+// not be used once the token is resumed.
+//
+// Returns common.ErrSnapshotExpired if the manifest version of the token was
+// pruned, or is not the manifest version the token was issued from, such as when
+// the DB was recreated. The remainder of the scan cannot be read from the same
+// view of the data, so a new scan must be started with DB.Scan. To resume a scan
+// later, leave the ScanIterator open, which keeps the manifest version pinned.
+func (db *DB) ResumeScan(ctx context.Context, token []byte, opts config.ScanOptions) (*ScanIterator, error) {
+	if opts.ReadLevel != 0 && opts.ReadLevel != config.Flushed {
+		return nil, fmt.Errorf("%w: a scan which reads the memtables can't be resumed", common.ErrInvalidOptions)
+	}
+	if opts.RawMergeOperands {
+		return nil, fmt.Errorf("%w: a scan of raw merge operands can't be resumed", common.ErrInvalidOptions)
+	}
+	opts.ReadLevel = config.Flushed
+	t, err := decodeResumeToken(token)
+	if err != nil {
+		return nil, err
+	}
+	scan, err := db.scan(ctx, t, opts)
+	if errors.Is(err, common.ErrManifestNotFound) {
+		return nil, fmt.Errorf("%w: manifest '%d' was pruned after the scan was closed, start a new scan: %w",
+			common.ErrSnapshotExpired, t.manifestID, err)
+	}
+	return scan, err
+}
+
+func (db *DB) scan(ctx context.Context, token resumeToken, opts config.ScanOptions) (*ScanIterator, error) {
+	// Pin before reading, so the manifest cannot be pruned while the scan is open.
+	// A manifest pruned before the pin was created is not found, which the caller
+	// handles. A resumed scan reuses the pin of the scan which issued the token.
+	pin := store.ManifestPin{ManifestID: token.manifestID, ID: token.pinID}
+	var err error
+	if token.pinID != "" {
+		err = db.manifestStore.RepinManifestReader(pin)
+	} else {
+		pin, err = db.manifestStore.PinManifestReader(token.manifestID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	token.pinID = pin.ID
+
+	core, err := db.manifestStore.ReadManifest(token.manifestID)
+	if err != nil {
+		_ = db.manifestStore.UnpinManifestReader(pin)
+		return nil, err
+	}
+
+	seq := core.LastL0Seq.Load()
+	if expected, ok := token.seq.Get(); ok && expected != seq {
+		_ = db.manifestStore.UnpinManifestReader(pin)
+		return nil, fmt.Errorf("%w: manifest '%d' has sequence '%d' rather than '%d', as the DB was recreated, start a new scan",
+			common.ErrSnapshotExpired, token.manifestID, seq, expected)
+	}
+	token.seq = mo.Some(seq)
+
+	scan, err := db.newScanIterator(ctx, core, nil, token, opts)
+	if err != nil {
+		_ = db.manifestStore.UnpinManifestReader(pin)
+		return nil, err
+	}
+	scan.pin = mo.Some(pin)
+	return scan, nil
+}
+
+// scanTables returns an iterator over the keys in the range [start, end) of the
+// memtables, and of the WALs if opts.ReadLevel is config.Uncommitted, merged with
+// the SSTables of the DB state, like DB.GetWithOptions. The latest manifest version
+// is pinned until the scan is closed.
+func (db *DB) scanTables(ctx context.Context, start []byte, end []byte, opts config.ScanOptions) (*ScanIterator, error) {
+	if opts.OnResumeToken != nil {
+		return nil, fmt.Errorf("%w: a scan which reads the memtables can't be resumed", common.ErrInvalidOptions)
+	}
+	pin, _, err := db.pinLatestManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	// The memtables and the SSTables are read from the same snapshot of the DB
+	// state, so a memtable flushed to L0 meanwhile is read once
+	snapshot := db.state.Snapshot()
+	token := resumeToken{
+		manifestID: pin.ManifestID,
+		start:      bytes.Clone(start),
+		end:        bytes.Clone(end),
+	}
+	scan, err := db.newScanIterator(ctx, snapshot.Core, snapshot, token, opts)
+	if err != nil {
+		_ = db.manifestStore.UnpinManifestReader(pin)
+		return nil, err
+	}
+	scan.pin = mo.Some(pin)
+	scan.unresumable = true
+	return scan, nil
+}
+
+// pinLatestManifest pins the latest manifest version
+// and reads the version. A version pruned before it was pinned is unpinned in
+// favour of the version which replaced it.
+func (db *DB) pinLatestManifest() (store.ManifestPin, *state.CoreStateSnapshot, error) {
+	for {
+		manifestID, err := db.manifestStore.LatestManifestID()
+		if err != nil {
+			return store.ManifestPin{}, nil, fmt.Errorf("while reading latest manifest: %w", err)
+		}
+		pin, err := db.manifestStore.PinManifestReader(manifestID)
+		if err != nil {
+			return store.ManifestPin{}, nil, err
+		}
+
+		core, err := db.manifestStore.ReadManifest(manifestID)
+		if err != nil {
+			_ = db.manifestStore.UnpinManifestReader(pin)
+			if errors.Is(err, common.ErrManifestNotFound) {
+				continue
+			}
+			return store.ManifestPin{}, nil, err
+		}
+		return pin, core, nil
+	}
+}
+
+// newScanIterator returns an iterator over the SSTables of core in the range of
+// the token, merged with the tables of the snapshot if it is not nil, see
+// newTablesIterator
+func (db *DB) newScanIterator(
+	ctx context.Context,
+	core *state.CoreStateSnapshot,
+	snapshot *state.DBStateSnapshot,
+	token resumeToken,
+	opts config.ScanOptions,
+) (*ScanIterator, error) {
+	from := token.start
+	if token.lastKey != nil {
+		from = token.lastKey
+	}
+
+	// SSTables whose key range recorded in the manifest doesn't intersect the
+	// remainder of the scan are skipped without fetching their index or filter
+	core = core.WithinRange(from, token.end)
+
+	tableStore := db.tableStore.WithIOClass(store.IOClassScan)
+	if prefix := scanPrefix(token.start, token.end); prefix != nil {
+		core = db.withPrefix(ctx, core, prefix, tableStore)
+	}
+	sstOpts := sstable.IteratorOptions{
+		UpperBound:     token.end,
+		PrefetchBlocks: opts.PrefetchBlocks,
+		Readahead: sstable.Readahead{
+			MinBlocks:        opts.Readahead.MinBlocks,
+			MaxBlocks:        opts.Readahead.MaxBlocks,
+			MaxRequestBlocks: opts.Readahead.MaxRequestBlocks,
+			MaxRequestBytes:  opts.Readahead.MaxRequestBytes,
+		},
+	}
+	coreIter, err := newCoreIterator(ctx, core, tableStore, from, sstOpts)
+	if err != nil {
+		return nil, err
+	}
+	// The merge operands of a key are combined, or returned raw, both by the
+	// iterator over the SSTables and by the iterator which merges it with the
+	// memtables. The iterator over the SSTables is read as soon as it is merged.
+	withMerge := func(it *iter.MergeSort) *iter.MergeSort {
+		if opts.RawMergeOperands {
+			return it.WithRawMergeOperands()
+		}
+		return it.WithMergeOperator(db.opts.MergeOperator)
+	}
+	mergeIter := withMerge(coreIter)
+	if snapshot != nil {
+		mergeIter = withMerge(newTablesIterator(ctx, snapshot, opts.ReadLevel, from, token.end, mergeIter))
+	}
+
+	return &ScanIterator{
+		db:          db,
+		iter:        mergeIter,
+		token:       token,
+		opts:        opts,
+		skipKey:     token.lastKey,
+		unresumable: opts.RawMergeOperands,
+	}, nil
+}
+
+// newCoreIterator returns an iterator over the newest entry of each key in the
+// SSTables of core from start, including tombstones and the entries deleted by the
+// range tombstones of core. The iterator may return
+// keys past sstOpts.UpperBound, which the caller must stop at.
+func newCoreIterator(
+	ctx context.Context,
+	core *state.CoreStateSnapshot,
+	tableStore *store.TableStore,
+	start []byte,
+	sstOpts sstable.IteratorOptions,
+) (*iter.MergeSort, error) {
+	iters := make([]iter.KVIterator, 0, len(core.L0)+len(core.Compacted))
+	// fail cancels the prefetches of the iterators created before the error
+	fail := func(err error) (*iter.MergeSort, error) {
+		for _, it := range iters {
+			iter.Close(it)
+		}
+		return nil, err
+	}
+	for _, sst := range core.L0 {
+		sstIter, err := sstable.NewIteratorWithOptions(ctx, &sst, tableStore, sstOpts)
+		if err == nil && start != nil {
+			if err = sstIter.Seek(ctx, start); err != nil {
+				sstIter.Close()
+			}
+		}
+		if err != nil {
+			return fail(err)
+		}
+		iters = append(iters, sstIter)
+	}
+
+	for _, sr := range core.Compacted {
+		var srIter *compaction.SortedRunIterator
+		var err error
+		if start != nil {
+			srIter, err = compaction.NewSortedRunIteratorFromKeyWithOptions(ctx, sr, start, tableStore, sstOpts)
+		} else {
+			srIter, err = compaction.NewSortedRunIteratorWithOptions(ctx, sr, tableStore, sstOpts)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		iters = append(iters, srIter)
+	}
+	tombstones, err := rangeTombstones(ctx, core, tableStore)
+	if err != nil {
+		return fail(err)
+	}
+	return iter.NewMergeSort(ctx, iters...).WithRangeTombstones(tombstones), nil
+}
+
+// newTablesIterator returns an iterator over the newest entry of each key in the
+// range [start, end) of the memtables of the snapshot, and of its WALs if level is
+// config.Uncommitted, merged with the older entries of ssts. The range tombstones
+// of the tables delete the older entries of ssts.
+func newTablesIterator(
+	ctx context.Context,
+	snapshot *state.DBStateSnapshot,
+	level config.ReadLevel,
+	start []byte,
+	end []byte,
+	ssts iter.KVIterator,
+) *iter.MergeSort {
+	var iters []iter.KVIterator
+	var tombstones types.RangeTombstones
+	add := func(it *table.KVTableIterator, ts types.RangeTombstones) {
+		iters = append(iters, tableIterator{it})
+		tombstones = append(tombstones, ts...)
+	}
+
+	// The tables are ordered from the newest, which takes precedence
+	if level == config.Uncommitted {
+		add(snapshot.Wal.RangeBetween(start, end, table.HalfOpen), snapshot.Wal.RangeTombstones())
+		for i := 0; i < snapshot.ImmWALs.Len(); i++ {
+			immWAL := snapshot.ImmWALs.At(i)
+			add(immWAL.RangeBetween(start, end, table.HalfOpen), immWAL.RangeTombstones())
+		}
+	}
+	add(snapshot.Memtable.RangeBetween(start, end, table.HalfOpen), snapshot.Memtable.RangeTombstones())
+	for i := 0; i < snapshot.ImmMemtables.Len(); i++ {
+		imm := snapshot.ImmMemtables.At(i)
+		add(imm.RangeBetween(start, end, table.HalfOpen), imm.RangeTombstones())
+	}
+	iters = append(iters, ssts)
+	return iter.NewMergeSort(ctx, iters...).WithRangeTombstones(tombstones)
+}
+
+// tableIterator adapts a table.KVTableIterator to an iter.KVIterator
+type tableIterator struct {
+	iter *table.KVTableIterator
+}
+
+func (t tableIterator) Next(ctx context.Context) (types.KeyValue, bool) {
+	kv, err := t.iter.Next()
+	if err != nil {
+		return types.KeyValue{}, false
+	}
+	return kv.Get()
+}
+
+func (t tableIterator) NextEntry(ctx context.Context) (types.RowEntry, bool) {
+	entry, err := t.iter.NextEntry()
+	if err != nil {
+		return types.RowEntry{}, false
+	}
+	return entry.Get()
+}
+
+// Warnings returns nil, as reading a table never warns
+func (t tableIterator) Warnings() *types.ErrWarn {
+	return nil
+}
+
+// withPrefix returns core without the SSTables whose filter excludes every key
+// which starts with the prefix
+func (db *DB) withPrefix(ctx context.Context, core *state.CoreStateSnapshot, prefix []byte, tableStore *store.TableStore) *state.CoreStateSnapshot {
+	mayIncludePrefix := func(sst sstable.Handle) bool {
+		// The range tombstones of the SSTable may delete keys with the prefix
+		if sst.Info.RangeTombstoneLen > 0 {
+			return true
+		}
+		filter, err := tableStore.ReadFilter(ctx, &sst)
+		if err == nil && filter.IsPresent() {
+			return filter.MustGet().PrefixMayMatch(prefix)
+		}
+		return true
+	}
+
+	l0 := core.L0[:0]
+	for _, sst := range core.L0 {
+		if mayIncludePrefix(sst) {
+			l0 = append(l0, sst)
+		}
+	}
+	core.L0 = l0
+
+	compacted := core.Compacted[:0]
+	for _, sr := range core.Compacted {
+		sstList := sr.SSTList[:0]
+		for _, sst := range sr.SSTList {
+			if mayIncludePrefix(sst) {
+				sstList = append(sstList, sst)
+			}
+		}
+		if len(sstList) > 0 {
+			compacted = append(compacted, compaction.SortedRun{ID: sr.ID, SSTList: sstList})
+		}
+	}
+	core.Compacted = compacted
+	return core
+}
+
+// scanPrefix returns the prefix shared by every key in the range [start, end),
+// or nil if the range does not cover exactly the keys which start with a prefix
+func scanPrefix(start []byte, end []byte) []byte {
+	if len(start) == 0 || !bytes.Equal(prefixEnd(start), end) {
+		return nil
+	}
+	return start
+}
+
+// prefixEnd returns the first key after every key which starts with the prefix,
+// or nil if there is no such key
+func prefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for len(end) > 0 && end[len(end)-1] == 0xff {
+		end = end[:len(end)-1]
+	}
+	if len(end) == 0 {
+		return nil
+	}
+	end[len(end)-1]++
+	return end
+}
+
+// Next returns the next key in the scan, or false once the scan is complete
+func (s *ScanIterator) Next(ctx context.Context) (types.KeyValue, bool) {
+	for !s.done {
+		kv, ok := s.iter.Next(ctx)
+		if !ok || (s.token.end != nil && bytes.Compare(kv.Key, s.token.end) >= 0) {
+			s.done = true
+			break
+		}
+
+		// A resumed scan starts at the last key returned before the token was issued
+		if s.skipKey != nil && bytes.Compare(kv.Key, s.skipKey) <= 0 {
+			continue
+		}
+		s.skipKey = nil
+
+		s.token.lastKey = kv.Key
+		s.count++
+		if s.opts.OnResumeToken != nil && s.opts.ResumeTokenInterval > 0 &&
+			s.count%s.opts.ResumeTokenInterval == 0 {
+			s.opts.OnResumeToken(s.ResumeToken())
+		}
+		return kv, true
+	}
+	return types.KeyValue{}, false
+}
+
+// ResumeToken returns a token which DB.ResumeScan uses to continue the scan
+// after the last key returned by Next, or nil if the scan reads the memtables
+// and can't be resumed
+func (s *ScanIterator) ResumeToken() []byte {
+	if s.unresumable {
+		return nil
+	}
+	return s.token.encode()
+}
+
+// Warnings returns types.ErrWarn if there was a warning during iteration.
+func (s *ScanIterator) Warnings() *types.ErrWarn {
+	return s.iter.Warnings()
+}
+
+// Close unpins the manifest version read by the scan, and returns the Warnings
+// encountered during iteration as an error, if any. The scan can no longer be
+// resumed once it is closed.
+func (s *ScanIterator) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.iter.Close()
+	if pin, ok := s.pin.Get(); ok {
+		if err := s.db.manifestStore.UnpinManifestReader(pin); err != nil {
+			return err
+		}
+	}
+	return s.Warnings().If()
+}
@@ -0,0 +1,320 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
+)
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 30; i++ {
+		require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value%02d", i))))
+		if i%10 == 9 {
+			require.NoError(t, db.FlushMemtableToL0(ctx))
+		}
+	}
+	require.NoError(t, db.Delete(ctx, []byte("key05")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	scan, err := db.Scan(ctx, []byte("key03"), []byte("key25"), config.DefaultScanOptions())
+	require.NoError(t, err)
+	for i := 3; i < 25; i++ {
+		if i == 5 {
+			continue
+		}
+		kv, ok := scan.Next(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []byte(fmt.Sprintf("key%02d", i)), kv.Key)
+		assert.Equal(t, []byte(fmt.Sprintf("value%02d", i)), kv.Value)
+	}
+	_, ok := scan.Next(ctx)
+	assert.False(t, ok)
+	assert.True(t, scan.Warnings().Empty())
+	require.NoError(t, scan.Close())
+}
+
+func TestResumeScan(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	options := testDBOptions(0, 1024)
+	options.ManifestRetainVersions = 1
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("value%02d", i))))
+	}
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	var token []byte
+	opts := config.DefaultScanOptions()
+	opts.ReadLevel = config.Flushed
+	opts.ResumeTokenInterval = 5
+	opts.OnResumeToken = func(t []byte) { token = t }
+
+	scan, err := db.Scan(ctx, nil, nil, opts)
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		_, ok := scan.Next(ctx)
+		require.True(t, ok)
+	}
+	// Abandon the scan without closing it, as if the process crashed
+	require.NotNil(t, token)
+
+	// Writes after the scan started are not visible to the resumed scan,
+	// and the pinned manifest version is not pruned.
+	require.NoError(t, db.Put(ctx, []byte("key10"), []byte("updated")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	_, err = db.manifestStore.PruneManifests(1, 0)
+	require.NoError(t, err)
+
+	resumed, err := db.ResumeScan(ctx, token, config.DefaultScanOptions())
+	require.NoError(t, err)
+	for i := 5; i < 20; i++ {
+		kv, ok := resumed.Next(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []byte(fmt.Sprintf("key%02d", i)), kv.Key)
+		assert.Equal(t, []byte(fmt.Sprintf("value%02d", i)), kv.Value)
+	}
+	_, ok := resumed.Next(ctx)
+	assert.False(t, ok)
+
+	// The resumed scan took over the pin of the abandoned scan, so closing it
+	// leaves no pin behind, after which the scan can no longer be resumed
+	pins, err := db.manifestStore.ListPins()
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	require.NoError(t, resumed.Close())
+	pins, err = db.manifestStore.ListPins()
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+	require.NoError(t, scan.Close())
+
+	_, err = db.manifestStore.PruneManifests(1, 0)
+	require.NoError(t, err)
+	_, err = db.ResumeScan(ctx, token, config.DefaultScanOptions())
+	assert.ErrorIs(t, err, common.ErrSnapshotExpired)
+	assert.ErrorIs(t, err, common.ErrManifestNotFound)
+
+	_, err = db.ResumeScan(ctx, []byte("garbage"), config.DefaultScanOptions())
+	assert.ErrorIs(t, err, common.ErrInvalidResumeToken)
+}
+
+func TestResumeScanOfRecreatedDB(t *testing.T) {
+	ctx := context.Background()
+	dbPath := "/tmp/test_kv_store"
+	db, err := Open(ctx, dbPath, objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Put(ctx, []byte("key00"), []byte("value00")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	scan, err := db.Scan(ctx, nil, nil, config.ScanOptions{ReadLevel: config.Flushed})
+	require.NoError(t, err)
+	_, ok := scan.Next(ctx)
+	require.True(t, ok)
+	token := scan.ResumeToken()
+
+	// The recreated DB has a manifest version with the same ID, which holds
+	// different data than the manifest version the token was issued from
+	recreated, err := Open(ctx, dbPath, objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer recreated.Close()
+
+	require.NoError(t, recreated.Put(ctx, []byte("key00"), []byte("other")))
+	require.NoError(t, recreated.Put(ctx, []byte("key01"), []byte("other")))
+	require.NoError(t, recreated.FlushMemtableToL0(ctx))
+
+	decoded, err := decodeResumeToken(token)
+	require.NoError(t, err)
+	_, err = recreated.manifestStore.ReadManifest(decoded.manifestID)
+	require.NoError(t, err)
+
+	_, err = recreated.ResumeScan(ctx, token, config.DefaultScanOptions())
+	assert.ErrorIs(t, err, common.ErrSnapshotExpired)
+
+	resumed, err := db.ResumeScan(ctx, token, config.DefaultScanOptions())
+	require.NoError(t, err)
+	require.NoError(t, resumed.Close())
+	require.NoError(t, scan.Close())
+}
+
+func TestDecodeResumeTokenVersion1(t *testing.T) {
+	token := resumeToken{
+		manifestID: 3,
+		seq:        mo.Some(uint64(7)),
+		start:      []byte("a"),
+		lastKey:    []byte("b"),
+	}
+	buf := token.encode()
+
+	// Version 1 tokens have no sequence number
+	v1 := append([]byte{1}, buf[1:1+common.SizeOfUint64]...)
+	v1 = append(v1, buf[1+2*common.SizeOfUint64:]...)
+	decoded, err := decodeResumeToken(v1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), decoded.manifestID)
+	assert.True(t, decoded.seq.IsAbsent())
+	assert.Equal(t, []byte("a"), decoded.start)
+	assert.Nil(t, decoded.end)
+	assert.Equal(t, []byte("b"), decoded.lastKey)
+
+	decoded, err = decodeResumeToken(buf)
+	require.NoError(t, err)
+	assert.Equal(t, token, decoded)
+}
+
+func TestScanAll(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("key%02d", i)), []byte(fmt.Sprintf("%d", i))))
+	}
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	scan, err := db.Scan(ctx, nil, nil, config.DefaultScanOptions())
+	require.NoError(t, err)
+
+	// Breaking out of the loop leaves the remaining keys in the scan
+	var keys []string
+	for key := range scan.All(ctx) {
+		keys = append(keys, string(key))
+		if len(keys) == 5 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"key00", "key01", "key02", "key03", "key04"}, keys)
+
+	values := TypedSeq(scan.All(ctx),
+		func(key []byte) string { return string(key) },
+		func(value []byte) int {
+			v, err := strconv.Atoi(string(value))
+			require.NoError(t, err)
+			return v
+		})
+	i := 5
+	for key, value := range values {
+		assert.Equal(t, fmt.Sprintf("key%02d", i), key)
+		assert.Equal(t, i, value)
+		i++
+	}
+	assert.Equal(t, 20, i)
+	require.NoError(t, scan.Close())
+}
+
+func TestScanPrefixSkipsSSTsExcludedByFilter(t *testing.T) {
+	ctx := context.Background()
+	bucket := &readRecordingBucket{Bucket: objstore.NewInMemBucket()}
+	options := testDBOptions(0, 1024)
+	options.FilterPolicy = filter.NewPartitionedPolicy(filter.NewFixedPrefixExtractor(6), filter.NewBloomPolicy(10))
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The key range of both SSTables covers the prefix "user-b"
+	require.NoError(t, db.Put(ctx, []byte("user-a1"), []byte("value")))
+	require.NoError(t, db.Put(ctx, []byte("user-c1"), []byte("value")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Put(ctx, []byte("user-b1"), []byte("value")))
+	require.NoError(t, db.Put(ctx, []byte("user-b2"), []byte("value")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	bucket.sstReads()
+
+	scan, err := db.ScanPrefix(ctx, []byte("user-b"), config.DefaultScanOptions())
+	require.NoError(t, err)
+	var keys []string
+	for key := range scan.All(ctx) {
+		keys = append(keys, string(key))
+	}
+	require.NoError(t, scan.Close())
+	assert.Equal(t, []string{"user-b1", "user-b2"}, keys)
+
+	l0 := db.state.L0()
+	reads := bucket.sstReads()
+	require.NotEmpty(t, reads)
+	for _, read := range reads {
+		assert.Contains(t, read, l0[0].Id.Value)
+	}
+}
+
+func TestScanMemtables(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024*1024)
+	options.FlushInterval = time.Hour
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	noWait := config.WriteOptions{AwaitDurable: false}
+	for i := 0; i < 6; i++ {
+		require.NoError(t, db.PutWithOptions(ctx, []byte(fmt.Sprintf("key%d", i)), []byte("l0"), noWait))
+	}
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	// The committed writes are in the memtable, the uncommitted ones in the WAL
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key1"), []byte("memtable"), noWait))
+	require.NoError(t, db.DeleteWithOptions(ctx, []byte("key2"), noWait))
+	require.NoError(t, db.DeleteRangeWithOptions(ctx, []byte("key4"), []byte("key6"), noWait))
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key5"), []byte("memtable"), noWait))
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key0"), []byte("wal"), noWait))
+	require.NoError(t, db.DeleteWithOptions(ctx, []byte("key1"), noWait))
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key6"), []byte("wal"), noWait))
+
+	scanAll := func(opts config.ScanOptions) map[string]string {
+		scan, err := db.Scan(ctx, []byte("key0"), []byte("key9"), opts)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, scan.Close()) }()
+		kvs := make(map[string]string)
+		for key, value := range scan.All(ctx) {
+			kvs[string(key)] = string(value)
+		}
+		return kvs
+	}
+	assert.Equal(t, map[string]string{
+		"key0": "l0", "key1": "l0", "key2": "l0", "key3": "l0", "key4": "l0", "key5": "l0",
+	}, scanAll(config.ScanOptions{ReadLevel: config.Flushed}))
+	assert.Equal(t, map[string]string{
+		"key0": "l0", "key1": "memtable", "key3": "l0", "key5": "memtable",
+	}, scanAll(config.ScanOptions{ReadLevel: config.Committed}))
+	// Like a Get, a scan reads committed writes by default
+	assert.Equal(t, scanAll(config.ScanOptions{ReadLevel: config.Committed}), scanAll(config.ScanOptions{}))
+	assert.Equal(t, map[string]string{
+		"key0": "wal", "key3": "l0", "key5": "memtable", "key6": "wal",
+	}, scanAll(config.ScanOptions{ReadLevel: config.Uncommitted}))
+
+	// A scan of the memtables can't be resumed
+	scan, err := db.Scan(ctx, nil, nil, config.ScanOptions{ReadLevel: config.Committed})
+	require.NoError(t, err)
+	assert.Nil(t, scan.ResumeToken())
+	require.NoError(t, scan.Close())
+	_, err = db.Scan(ctx, nil, nil, config.ScanOptions{
+		ReadLevel:           config.Committed,
+		ResumeTokenInterval: 1,
+		OnResumeToken:       func([]byte) {},
+	})
+	assert.ErrorIs(t, err, common.ErrInvalidOptions)
+}
@@ -0,0 +1,49 @@
+package slatedb
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns an iter.Seq2 over the keys and values remaining in the scan, for
+// use with range over func. Breaking out of the loop stops the scan, which may be
+// continued by a later call to All or Next. Close returns any error encountered
+// during iteration.
+//
+//	scan, err := db.Scan(ctx, start, end, config.DefaultScanOptions())
+//	if err != nil {
+//		return err
+//	}
+//	for key, value := range scan.All(ctx) {
+//		...
+//	}
+//	if err := scan.Close(); err != nil {
+//		return err
+//	}
+func (s *ScanIterator) All(ctx context.Context) iter.Seq2[[]byte, []byte] {
+	return func(yield func([]byte, []byte) bool) {
+		for {
+			kv, ok := s.Next(ctx)
+			if !ok || !yield(kv.Key, kv.Value) {
+				return
+			}
+		}
+	}
+}
+
+// TypedSeq adapts an iter.Seq2 of encoded keys and values, such as the one
+// returned by ScanIterator.All, into an iter.Seq2 of the types the keys and values
+// decode to, for callers which store typed keys and values in the DB.
+func TypedSeq[K any, V any](
+	seq iter.Seq2[[]byte, []byte],
+	decodeKey func([]byte) K,
+	decodeValue func([]byte) V,
+) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for key, value := range seq {
+			if !yield(decodeKey(key), decodeValue(value)) {
+				return
+			}
+		}
+	}
+}
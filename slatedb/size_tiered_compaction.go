@@ -2,14 +2,19 @@ package slatedb
 
 import (
 	"github.com/slatedb/slatedb-go/internal/assert"
+	"github.com/slatedb/slatedb-go/slatedb/config"
 )
 
-type SizeTieredCompactionScheduler struct{}
+type SizeTieredCompactionScheduler struct {
+	options *config.CompactorOptions
+}
 
 func (s SizeTieredCompactionScheduler) maybeScheduleCompaction(state *CompactorState) []Compaction {
 	dbState := state.dbState
+	// compactions which reclaim the space of deleted entries are scheduled first
+	compactions := s.maybeScheduleTombstoneCompaction(state)
+
 	// for now, just compact l0 down to a new sorted run each time
-	compactions := make([]Compaction, 0)
 	if len(dbState.L0) >= 4 {
 		sources := make([]SourceID, 0)
 		for _, sst := range dbState.L0 {
@@ -27,3 +32,30 @@ func (s SizeTieredCompactionScheduler) maybeScheduleCompaction(state *CompactorS
 	}
 	return compactions
 }
+
+// maybeScheduleTombstoneCompaction compacts the newest sorted run whose live data
+// fraction is below CompactorOptions.MinLiveDataFraction along with every older
+// sorted run. No older sorted run remains once the output replaces the sources,
+// so the tombstones and the values they delete are dropped.
+func (s SizeTieredCompactionScheduler) maybeScheduleTombstoneCompaction(state *CompactorState) []Compaction {
+	if s.options == nil || s.options.MinLiveDataFraction <= 0 {
+		return nil
+	}
+
+	compacted := state.dbState.Compacted
+	for i, sr := range compacted {
+		if sr.LiveDataFraction() >= s.options.MinLiveDataFraction {
+			continue
+		}
+
+		sources := make([]SourceID, 0, len(compacted)-i)
+		for _, older := range compacted[i:] {
+			if state.isCompacting(older.ID) {
+				return nil
+			}
+			sources = append(sources, newSourceIDSortedRun(older.ID))
+		}
+		return []Compaction{newCompaction(sources, sr.ID)}
+	}
+	return nil
+}
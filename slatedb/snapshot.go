@@ -0,0 +1,160 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/state"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// Snapshot is a consistent, read-only view of the keys in the range [start, end)
+// as of the sequence number returned by Snapshot.Seq. Every write committed to
+// the memtable when the Snapshot was created is visible, and no later write is,
+// regardless of later flushes and compactions.
+//
+// The Snapshot holds the memtables of the DB as of its creation, and pins the
+// manifest version which holds the SSTables flushed before them until
+// Snapshot.Close is called, so the version is not pruned. The pin retains the
+// whole version, but only the SSTables of the version which overlap the range
+// of the Snapshot are held in memory, so a long-lived Snapshot of a single
+// tenant's prefix does not hold on to the metadata of the SSTables of the rest
+// of the DB.
+type Snapshot struct {
+	db     *DB
+	pin    store.ManifestPin
+	start  []byte
+	end    []byte
+	tables *state.DBStateSnapshot
+	seq    uint64
+	closed bool
+}
+
+// Snapshot returns a Snapshot of the keys in the range [start, end). A nil
+// start or end leaves the range unbounded.
+func (db *DB) Snapshot(ctx context.Context, start []byte, end []byte) (*Snapshot, error) {
+	// No write is applied to the memtable and no memtable is flushed to L0 while
+	// the Snapshot is created, so the latest manifest version holds every write
+	// which is not in the memtables of the snapshot of the DB state
+	db.walFlushMu.Lock()
+	defer db.walFlushMu.Unlock()
+	db.memtableFlushMu.Lock()
+	defer db.memtableFlushMu.Unlock()
+
+	pin, core, err := db.pinLatestManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	// The SSTables are read from the pinned manifest version rather than the DB
+	// state, which may not yet include the results of the latest compactions,
+	// so the Snapshot reads the same SSTables as a scan of the pinned version
+	snapshot := db.state.Snapshot()
+	tables := &state.DBStateSnapshot{
+		Memtable:     snapshot.Memtable,
+		ImmMemtables: snapshot.ImmMemtables,
+		Core:         core.WithinRange(start, end),
+	}
+	return &Snapshot{
+		db:     db,
+		pin:    pin,
+		start:  bytes.Clone(start),
+		end:    bytes.Clone(end),
+		tables: tables,
+		seq:    tablesSeq(tables, core),
+	}, nil
+}
+
+// tablesSeq returns the sequence number of the most recent write in the
+// memtables of tables or in the SSTables of core
+func tablesSeq(tables *state.DBStateSnapshot, core *state.CoreStateSnapshot) uint64 {
+	seq := max(core.LastL0Seq.Load(), tables.Memtable.LastSeq())
+	for i := 0; i < tables.ImmMemtables.Len(); i++ {
+		seq = max(seq, tables.ImmMemtables.At(i).LastSeq())
+	}
+	return seq
+}
+
+// Seq returns the sequence number of the most recent write visible through the
+// Snapshot. Writes with a greater sequence number are not visible.
+func (s *Snapshot) Seq() uint64 {
+	return s.seq
+}
+
+// Get returns the value of the key as of the Snapshot. Returns common.ErrKeyOutsideSnapshot
+// if the key is outside the range of the Snapshot, or common.ErrSnapshotClosed if
+// the Snapshot is closed.
+func (s *Snapshot) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if s.closed {
+		return nil, common.ErrSnapshotClosed
+	}
+	if !s.contains(key) {
+		return nil, common.ErrKeyOutsideSnapshot
+	}
+	val, err := s.db.getValueFrom(ctx, s.tables, key, config.ReadOptions{ReadLevel: config.Committed})
+	if err != nil {
+		return nil, err
+	}
+	return checkValue(val)
+}
+
+// Scan returns an iterator over the keys in the range [start, end) as of the
+// Snapshot. The range is limited to the range of the Snapshot. The memtables of
+// the Snapshot are merged with its SSTables, so the scan can't be resumed with
+// DB.ResumeScan. Returns common.ErrInvalidOptions if opts.ReadLevel is
+// config.Uncommitted, as the Snapshot holds no WALs, or config.Flushed, or
+// common.ErrSnapshotClosed if the Snapshot is closed.
+func (s *Snapshot) Scan(ctx context.Context, start []byte, end []byte, opts config.ScanOptions) (*ScanIterator, error) {
+	if s.closed {
+		return nil, common.ErrSnapshotClosed
+	}
+	if opts.ReadLevel == config.Uncommitted {
+		return nil, fmt.Errorf("%w: a Snapshot holds no WALs to scan", common.ErrInvalidOptions)
+	}
+	if opts.ReadLevel == config.Flushed {
+		return nil, fmt.Errorf("%w: a scan of a Snapshot merges its memtables", common.ErrInvalidOptions)
+	}
+	if opts.OnResumeToken != nil {
+		return nil, fmt.Errorf("%w: a scan of a Snapshot can't be resumed", common.ErrInvalidOptions)
+	}
+	if s.start != nil && (start == nil || bytes.Compare(start, s.start) < 0) {
+		start = s.start
+	}
+	if s.end != nil && (end == nil || bytes.Compare(end, s.end) > 0) {
+		end = s.end
+	}
+
+	token := resumeToken{
+		manifestID: s.pin.ManifestID,
+		start:      bytes.Clone(start),
+		end:        bytes.Clone(end),
+	}
+	opts.ReadLevel = config.Committed
+	scan, err := s.db.newScanIterator(ctx, s.tables.Core, s.tables, token, opts)
+	if err != nil {
+		return nil, err
+	}
+	scan.unresumable = true
+	return scan, nil
+}
+
+// Close releases the memtables held by the Snapshot and unpins the manifest
+// version read by the Snapshot, after which the version may be pruned.
+func (s *Snapshot) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.tables = nil
+	return s.db.manifestStore.UnpinManifestReader(s.pin)
+}
+
+func (s *Snapshot) contains(key []byte) bool {
+	if s.start != nil && bytes.Compare(key, s.start) < 0 {
+		return false
+	}
+	return s.end == nil || bytes.Compare(key, s.end) < 0
+}
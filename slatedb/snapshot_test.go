@@ -0,0 +1,143 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/state"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, testDBOptions(0, 1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Each tenant is flushed to a separate SST in L0
+	for _, tenant := range []string{"a", "b", "c"} {
+		for i := 0; i < 10; i++ {
+			require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("%s/key%02d", tenant, i)), []byte(fmt.Sprintf("%s/value%02d", tenant, i))))
+		}
+		require.NoError(t, db.FlushMemtableToL0(ctx))
+	}
+
+	snapshot, err := db.Snapshot(ctx, []byte("b/"), []byte("b0"))
+	require.NoError(t, err)
+
+	// Writes after the snapshot was created are not visible
+	require.NoError(t, db.Put(ctx, []byte("b/key03"), []byte("updated")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	val, err := snapshot.Get(ctx, []byte("b/key03"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b/value03"), val)
+	_, err = snapshot.Get(ctx, []byte("a/key03"))
+	assert.ErrorIs(t, err, common.ErrKeyOutsideSnapshot)
+
+	// The scan is limited to the range of the snapshot
+	scan, err := snapshot.Scan(ctx, nil, nil, config.DefaultScanOptions())
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		kv, ok := scan.Next(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []byte(fmt.Sprintf("b/key%02d", i)), kv.Key)
+		assert.Equal(t, []byte(fmt.Sprintf("b/value%02d", i)), kv.Value)
+	}
+	_, ok := scan.Next(ctx)
+	assert.False(t, ok)
+	require.NoError(t, scan.Close())
+
+	// Only the SST of the tenant in the range of the snapshot is read, and the
+	// manifest version is pinned by the snapshot
+	require.Len(t, snapshot.tables.Core.L0, 1)
+	assert.Equal(t, []byte("b/key00"), snapshot.tables.Core.L0[0].Info.FirstKey)
+	pins, err := db.manifestStore.ListPins()
+	require.NoError(t, err)
+	require.Len(t, pins, 1)
+	assert.Equal(t, snapshot.pin, pins[0])
+
+	require.NoError(t, snapshot.Close())
+	pins, err = db.manifestStore.ListPins()
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+}
+
+func TestSnapshotMemtable(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	options := testDBOptionsCompactor(0, 1024*1024, compactorOptions().CompactorOptions)
+	db, err := Open(ctx, "/tmp/test_kv_store", bucket, options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Writes in L0 and in the memtable when the snapshot is created are visible
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("value1")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Put(ctx, []byte("key2"), []byte("value2")))
+	seq := db.state.CommittedSeq()
+
+	snapshot, err := db.Snapshot(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, seq, snapshot.Seq())
+
+	// Writes after the snapshot was created are not visible, even once they are
+	// flushed and compacted with the writes the snapshot reads
+	require.NoError(t, db.Put(ctx, []byte("key1"), []byte("updated")))
+	require.NoError(t, db.Delete(ctx, []byte("key2")))
+	require.NoError(t, db.Put(ctx, []byte("key3"), []byte("value3")))
+	for i := 0; i < 4; i++ {
+		require.NoError(t, db.FlushMemtableToL0(ctx))
+		require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("key4/%d", i)), []byte("value4")))
+	}
+	waitForCompactedState(t, db.manifestStore, func(core *state.CoreStateSnapshot) bool {
+		return core.L0LastCompacted.IsPresent()
+	})
+	assert.Greater(t, db.state.LastSeq(), snapshot.Seq())
+
+	val, err := snapshot.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), val)
+	val, err = snapshot.Get(ctx, []byte("key2"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value2"), val)
+	_, err = snapshot.Get(ctx, []byte("key3"))
+	assert.ErrorIs(t, err, common.ErrKeyNotFound)
+
+	scan, err := snapshot.Scan(ctx, nil, nil, config.DefaultScanOptions())
+	require.NoError(t, err)
+	for _, key := range []string{"key1", "key2"} {
+		kv, ok := scan.Next(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []byte(key), kv.Key)
+	}
+	_, ok := scan.Next(ctx)
+	assert.False(t, ok)
+	assert.Nil(t, scan.ResumeToken())
+	require.NoError(t, scan.Close())
+
+	// The scan of a snapshot can't be resumed, and the snapshot holds no WALs
+	opts := config.DefaultScanOptions()
+	opts.ReadLevel = config.Uncommitted
+	_, err = snapshot.Scan(ctx, nil, nil, opts)
+	assert.ErrorIs(t, err, common.ErrInvalidOptions)
+	opts = config.DefaultScanOptions()
+	opts.OnResumeToken = func([]byte) {}
+	_, err = snapshot.Scan(ctx, nil, nil, opts)
+	assert.ErrorIs(t, err, common.ErrInvalidOptions)
+
+	// Closing the snapshot releases its pin
+	require.NoError(t, snapshot.Close())
+	pins, err := db.manifestStore.ListPins()
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+	_, err = snapshot.Get(ctx, []byte("key1"))
+	assert.ErrorIs(t, err, common.ErrSnapshotClosed)
+}
@@ -28,7 +28,7 @@ func buildSRWithSSTs(
 
 	sstList := make([]sstable.Handle, 0, n)
 	for i := uint64(0); i < n; i++ {
-		writer := tableStore.TableWriter(sstable.NewIDCompacted(ulid.Make()))
+		writer := tableStore.TableWriter(context.Background(), sstable.NewIDCompacted(ulid.Make()))
 		for j := uint64(0); j < keysPerSST; j++ {
 			if err := writer.Add(keyGen.Next(), mo.Some(valGen.Next())); err != nil {
 				return compaction.SortedRun{}, err
@@ -55,11 +55,11 @@ func TestOneSstSRIter(t *testing.T) {
 
 	encodedSST, err := builder.Build()
 	assert.NoError(t, err)
-	sstHandle, err := tableStore.WriteSST(sstable.NewIDCompacted(ulid.Make()), encodedSST)
+	sstHandle, err := tableStore.WriteSST(context.Background(), sstable.NewIDCompacted(ulid.Make()), encodedSST)
 	assert.NoError(t, err)
 
 	sr := compaction.SortedRun{ID: 0, SSTList: []sstable.Handle{*sstHandle}}
-	iterator, err := compaction.NewSortedRunIterator(sr, tableStore)
+	iterator, err := compaction.NewSortedRunIterator(context.Background(), sr, tableStore)
 	assert.NoError(t, err)
 	assert2.Next(t, iterator, []byte("key1"), []byte("value1"))
 	assert2.Next(t, iterator, []byte("key2"), []byte("value2"))
@@ -82,7 +82,7 @@ func TestManySstSRIter(t *testing.T) {
 
 	encodedSST, err := builder.Build()
 	assert.NoError(t, err)
-	sstHandle, err := tableStore.WriteSST(sstable.NewIDCompacted(ulid.Make()), encodedSST)
+	sstHandle, err := tableStore.WriteSST(context.Background(), sstable.NewIDCompacted(ulid.Make()), encodedSST)
 	require.NoError(t, err)
 
 	builder = tableStore.TableBuilder()
@@ -91,11 +91,11 @@ func TestManySstSRIter(t *testing.T) {
 
 	encodedSST, err = builder.Build()
 	require.NoError(t, err)
-	sstHandle2, err := tableStore.WriteSST(sstable.NewIDCompacted(ulid.Make()), encodedSST)
+	sstHandle2, err := tableStore.WriteSST(context.Background(), sstable.NewIDCompacted(ulid.Make()), encodedSST)
 	require.NoError(t, err)
 
 	sr := compaction.SortedRun{ID: 0, SSTList: []sstable.Handle{*sstHandle, *sstHandle2}}
-	iterator, err := compaction.NewSortedRunIterator(sr, tableStore)
+	iterator, err := compaction.NewSortedRunIterator(context.Background(), sr, tableStore)
 	assert.NoError(t, err)
 	assert2.Next(t, iterator, []byte("key1"), []byte("value1"))
 	assert2.Next(t, iterator, []byte("key2"), []byte("value2"))
@@ -106,6 +106,41 @@ func TestManySstSRIter(t *testing.T) {
 	assert.Equal(t, types.KeyValue{}, kv)
 }
 
+func TestSstWithKeySkipsKeysOutsideSSTRange(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	tableStore := store.NewTableStore(bucket, sstable.DefaultConfig(), "")
+
+	var sstList []sstable.Handle
+	for _, keys := range [][]string{{"key1", "key2"}, {"key5", "key6"}} {
+		writer := tableStore.TableWriter(context.Background(), sstable.NewIDCompacted(ulid.Make()))
+		for _, key := range keys {
+			require.NoError(t, writer.Add([]byte(key), mo.Some([]byte("value"))))
+		}
+		sst, err := writer.Close()
+		require.NoError(t, err)
+		sstList = append(sstList, *sst)
+	}
+	sr := compaction.SortedRun{ID: 0, SSTList: sstList}
+
+	for _, tc := range []struct {
+		key      string
+		expected mo.Option[sstable.Handle]
+	}{
+		{key: "key0", expected: mo.None[sstable.Handle]()},
+		{key: "key1", expected: mo.Some(sstList[0])},
+		{key: "key2", expected: mo.Some(sstList[0])},
+		{key: "key3", expected: mo.None[sstable.Handle]()},
+		{key: "key5", expected: mo.Some(sstList[1])},
+		{key: "key7", expected: mo.None[sstable.Handle]()},
+	} {
+		sst := sr.SstWithKey([]byte(tc.key))
+		assert.Equal(t, tc.expected.IsPresent(), sst.IsPresent(), tc.key)
+		if expected, ok := tc.expected.Get(); ok {
+			assert.Equal(t, expected.Id, sst.MustGet().Id, tc.key)
+		}
+	}
+}
+
 func TestSRIterFromKey(t *testing.T) {
 	bucket := objstore.NewInMemBucket()
 	conf := sstable.DefaultConfig()
@@ -129,7 +164,7 @@ func TestSRIterFromKey(t *testing.T) {
 		fromKey := testCaseKeyGen.Next()
 		testCaseValGen.Next()
 
-		kvIter, err := compaction.NewSortedRunIteratorFromKey(sr, fromKey, tableStore)
+		kvIter, err := compaction.NewSortedRunIteratorFromKey(context.Background(), sr, fromKey, tableStore)
 		assert.NoError(t, err)
 
 		for j := 0; j < 30-i; j++ {
@@ -158,7 +193,7 @@ func TestSRIterFromKeyLowerThanRange(t *testing.T) {
 	sr, err := buildSRWithSSTs(3, 10, tableStore, keyGen, valGen)
 	require.NoError(t, err)
 
-	kvIter, err := compaction.NewSortedRunIteratorFromKey(sr, []byte("aaaaaaaaaa"), tableStore)
+	kvIter, err := compaction.NewSortedRunIteratorFromKey(context.Background(), sr, []byte("aaaaaaaaaa"), tableStore)
 	assert.NoError(t, err)
 
 	for j := 0; j < 30; j++ {
@@ -184,7 +219,7 @@ func TestSRIterFromKeyHigherThanRange(t *testing.T) {
 	sr, err := buildSRWithSSTs(3, 10, tableStore, keyGen, valGen)
 	require.NoError(t, err)
 
-	kvIter, err := compaction.NewSortedRunIteratorFromKey(sr, []byte("zzzzzzzzzzzzzzzzzzzzzzzzzzzzzz"), tableStore)
+	kvIter, err := compaction.NewSortedRunIteratorFromKey(context.Background(), sr, []byte("zzzzzzzzzzzzzzzzzzzzzzzzzzzzzz"), tableStore)
 	assert.NoError(t, err)
 	next, ok := kvIter.Next(context.Background())
 	assert.False(t, ok)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/slatedb/slatedb-go/internal/assert"
 	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/types"
 	"github.com/slatedb/slatedb-go/slatedb/compaction"
 	"github.com/slatedb/slatedb-go/slatedb/table"
 
@@ -35,6 +36,11 @@ type CoreDBState struct {
 	// This value is updated when Memtable is flushed to Level0 of object store.
 	// It is later used during crash recovery to recover only those WALs that have not yet been flushed to Level0.
 	lastCompactedWalSSTID atomic.Uint64
+
+	// lastL0Seq is the highest sequence number of the entries flushed to Level0. Sequence
+	// numbers of new writes continue from the higher of this value and the entries of the
+	// WALs recovered during crash recovery.
+	lastL0Seq atomic.Uint64
 }
 
 type CoreStateSnapshot struct {
@@ -43,6 +49,7 @@ type CoreStateSnapshot struct {
 	Compacted             []compaction.SortedRun
 	NextWalSstID          atomic.Uint64
 	LastCompactedWalSSTID atomic.Uint64
+	LastL0Seq             atomic.Uint64
 }
 
 func (s *CoreStateSnapshot) ToCoreState() *CoreDBState {
@@ -54,6 +61,7 @@ func (s *CoreStateSnapshot) ToCoreState() *CoreDBState {
 	}
 	coreState.nextWalSstID.Store(s.NextWalSstID.Load())
 	coreState.lastCompactedWalSSTID.Store(s.LastCompactedWalSSTID.Load())
+	coreState.lastL0Seq.Store(s.LastL0Seq.Load())
 	return coreState
 }
 
@@ -73,6 +81,41 @@ func (s *CoreStateSnapshot) Clone() *CoreStateSnapshot {
 	}
 	snapshot.NextWalSstID.Store(s.NextWalSstID.Load())
 	snapshot.LastCompactedWalSSTID.Store(s.LastCompactedWalSSTID.Load())
+	snapshot.LastL0Seq.Store(s.LastL0Seq.Load())
+	return snapshot
+}
+
+// WithinRange returns a copy of the snapshot which only includes the SSTables which may
+// contain keys in the range [start, end). Sorted runs with no SSTables in the range
+// are omitted. A nil start or end leaves the range unbounded. SSTables holding range
+// tombstones are kept regardless of their key range, as the tombstones may delete keys
+// of the range held by other SSTables, see rangeTombstones of the DB.
+func (s *CoreStateSnapshot) WithinRange(start []byte, end []byte) *CoreStateSnapshot {
+	snapshot := s.Clone()
+	l0 := snapshot.L0[:0]
+	for _, sst := range snapshot.L0 {
+		if sst.OverlapsRange(start, end) || sst.Info.RangeTombstoneLen > 0 {
+			l0 = append(l0, sst)
+		}
+	}
+	snapshot.L0 = l0
+
+	compacted := snapshot.Compacted[:0]
+	for _, sr := range snapshot.Compacted {
+		sstList := sr.SSTList[:0]
+		for _, sst := range sr.SSTList {
+			// Every SSTable of a sorted run holds the range tombstones of the
+			// sorted run, so only the first needs to be kept
+			keep := len(sstList) == 0 && sst.Info.RangeTombstoneLen > 0
+			if keep || sst.OverlapsRange(start, end) {
+				sstList = append(sstList, sst)
+			}
+		}
+		if len(sstList) > 0 {
+			compacted = append(compacted, compaction.SortedRun{ID: sr.ID, SSTList: sstList})
+		}
+	}
+	snapshot.Compacted = compacted
 	return snapshot
 }
 
@@ -103,6 +146,7 @@ func (c *CoreDBState) Snapshot() *CoreStateSnapshot {
 	}
 	coreState.NextWalSstID.Store(c.nextWalSstID.Load())
 	coreState.LastCompactedWalSSTID.Store(c.lastCompactedWalSSTID.Load())
+	coreState.LastL0Seq.Store(c.lastL0Seq.Load())
 	return coreState
 }
 
@@ -130,16 +174,53 @@ type DBState struct {
 	immWALs      *deque.Deque[*table.ImmutableWAL]
 	immMemtables *deque.Deque[*table.ImmutableMemtable]
 	core         *CoreDBState
+
+	// memtableShards is the number of shards of each new memtable
+	memtableShards int
+
+	// mergeOperator is set on each new WAL and memtable
+	mergeOperator types.MergeOperator
+
+	// lastSeq is the sequence number of the most recent write
+	lastSeq atomic.Uint64
+
+	// committedSeq is the sequence number of the most recent write flushed to
+	// the WAL in object storage
+	committedSeq atomic.Uint64
+
+	// walDisabled is true if writes are committed once flushed to L0 rather than
+	// to the WAL, see DisableWAL
+	walDisabled bool
 }
 
 func NewDBState(coreDBState *CoreDBState) *DBState {
-	return &DBState{
-		wal:          table.NewWAL(),
-		memtable:     table.NewMemtable(),
-		immWALs:      deque.New[*table.ImmutableWAL](0),
-		immMemtables: deque.New[*table.ImmutableMemtable](0),
-		core:         coreDBState,
+	return NewDBStateWithMemtableShards(coreDBState, 1)
+}
+
+// NewDBStateWithMemtableShards returns a DBState whose memtables are partitioned
+// into the provided number of shards, see table.NewShardedMemtable
+func NewDBStateWithMemtableShards(coreDBState *CoreDBState, memtableShards int) *DBState {
+	s := &DBState{
+		wal:            table.NewWAL(),
+		memtable:       table.NewShardedMemtable(memtableShards),
+		immWALs:        deque.New[*table.ImmutableWAL](0),
+		immMemtables:   deque.New[*table.ImmutableMemtable](0),
+		core:           coreDBState,
+		memtableShards: memtableShards,
 	}
+	s.lastSeq.Store(coreDBState.lastL0Seq.Load())
+	s.committedSeq.Store(coreDBState.lastL0Seq.Load())
+	return s
+}
+
+// SetMergeOperator sets the MergeOperator of the current and every new WAL and
+// memtable. It must be called before the DBState is written to.
+func (s *DBState) SetMergeOperator(op types.MergeOperator) {
+	s.Lock()
+	defer s.Unlock()
+	s.mergeOperator = op
+	s.wal.WithMergeOperator(op)
+	s.memtable.WithMergeOperator(op)
 }
 
 func (s *DBState) WAL() *table.WAL {
@@ -180,30 +261,165 @@ func (s *DBState) LastCompactedWALID() uint64 {
 	return s.core.lastCompactedWalSSTID.Load()
 }
 
-func (s *DBState) PutKVToWAL(key []byte, value []byte) *table.WAL {
+// LastSeq returns the sequence number of the most recent write
+func (s *DBState) LastSeq() uint64 {
+	return s.lastSeq.Load()
+}
+
+// CommittedSeq returns the sequence number of the most recent write flushed to
+// the WAL in object storage. Only writes in the WAL in object storage are written
+// to the memtable, so the memtable advances the committed sequence number. With
+// the WAL disabled, flushing the memtable to L0 advances it instead.
+func (s *DBState) CommittedSeq() uint64 {
+	return s.committedSeq.Load()
+}
+
+// DisableWAL commits writes once their memtable is flushed to L0, rather than once
+// they are written to the memtable from the WAL in object storage. It must be
+// called before writes are applied to the memtable.
+func (s *DBState) DisableWAL() {
 	s.Lock()
 	defer s.Unlock()
-	s.wal.Put(key, value)
-	return s.wal
+	s.walDisabled = true
 }
 
-func (s *DBState) DeleteKVFromWAL(key []byte) *table.WAL {
+// WriteEntriesToWAL writes all the range tombstones and entries to the same WAL,
+// assigning each the next sequence number, the range tombstones first and then
+// the entries in their order. The Seq of the provided writes is overwritten.
+func (s *DBState) WriteEntriesToWAL(tombstones types.RangeTombstones, entries []types.RowEntry) *table.WAL {
 	s.Lock()
 	defer s.Unlock()
-	s.wal.Delete(key)
+	s.assignSeqs(tombstones, entries)
+	s.wal.Write(tombstones, entries)
 	return s.wal
 }
 
-func (s *DBState) PutKVToMemtable(key []byte, value []byte) {
+// ReserveSeq returns the next sequence number without assigning it to a write,
+// such as the sequence number of the entries of ingested SSTables, which is
+// greater than the sequence number of every write already made.
+func (s *DBState) ReserveSeq() uint64 {
 	s.Lock()
 	defer s.Unlock()
-	s.memtable.Put(key, value)
+	return s.lastSeq.Add(1)
 }
 
-func (s *DBState) DeleteKVFromMemtable(key []byte) {
+// assignSeqs assigns the next sequence numbers to the range tombstones and then
+// to the entries. Must be called while holding the lock.
+func (s *DBState) assignSeqs(tombstones types.RangeTombstones, entries []types.RowEntry) {
+	for i := range tombstones {
+		tombstones[i].Seq = s.lastSeq.Add(1)
+	}
+	for i := range entries {
+		entries[i].Seq = s.lastSeq.Add(1)
+	}
+}
+
+// ReadVersion identifies the writes visible to a read of the DBState, see
+// WriteEntriesToWALIfUnchanged
+type ReadVersion struct {
+	// Seq is the sequence number of the most recent write
+	Seq uint64
+	// L0Head is the newest SSTable in L0, if any
+	L0Head mo.Option[sstable.ID]
+}
+
+// ReadVersion returns the version of the DBState, which a read which starts after
+// the call observes every write of
+func (s *DBState) ReadVersion() ReadVersion {
+	s.RLock()
+	defer s.RUnlock()
+	version := ReadVersion{Seq: s.lastSeq.Load(), L0Head: mo.None[sstable.ID]()}
+	if len(s.core.l0) > 0 {
+		version.L0Head = mo.Some(s.core.l0[0].Id)
+	}
+	return version
+}
+
+// WriteEntriesToWALIfUnchanged writes the entries to the WAL like WriteEntriesToWAL,
+// unless a key of keys was written after the version, in which case it returns
+// false. A write after the version which was already flushed to L0 is only
+// detected by the change of L0, so any SSTable added to L0 or compacted since the
+// version returns false as well.
+func (s *DBState) WriteEntriesToWALIfUnchanged(tombstones types.RangeTombstones, entries []types.RowEntry,
+	keys [][]byte, version ReadVersion) (*table.WAL, bool) {
 	s.Lock()
 	defer s.Unlock()
-	s.memtable.Delete(key)
+
+	l0Head := mo.None[sstable.ID]()
+	if len(s.core.l0) > 0 {
+		l0Head = mo.Some(s.core.l0[0].Id)
+	}
+	if l0Head != version.L0Head {
+		return nil, false
+	}
+	if s.lastSeq.Load() != version.Seq {
+		for _, key := range keys {
+			if s.keySeq(key) > version.Seq {
+				return nil, false
+			}
+		}
+	}
+
+	s.assignSeqs(tombstones, entries)
+	s.wal.Write(tombstones, entries)
+	return s.wal, true
+}
+
+// keySeq returns the sequence number of the most recent write of the key, or of
+// a range tombstone which deletes it, in the WALs and memtables. Must be called
+// while holding the lock.
+func (s *DBState) keySeq(key []byte) uint64 {
+	var seq uint64
+	add := func(entry mo.Option[types.RowEntry], tombstones types.RangeTombstones) {
+		if e, ok := entry.Get(); ok {
+			seq = max(seq, e.Seq)
+		}
+		seq = max(seq, tombstones.MaxSeq(key))
+	}
+	add(s.wal.GetEntry(key), s.wal.RangeTombstones())
+	for i := 0; i < s.immWALs.Len(); i++ {
+		add(s.immWALs.At(i).GetEntry(key), s.immWALs.At(i).RangeTombstones())
+	}
+	add(s.memtable.GetEntry(key), s.memtable.RangeTombstones())
+	for i := 0; i < s.immMemtables.Len(); i++ {
+		add(s.immMemtables.At(i).GetEntry(key), s.immMemtables.At(i).RangeTombstones())
+	}
+	return seq
+}
+
+// WriteRangeTombstoneToWAL deletes the keys in the range [start, end) written
+// before the range tombstone, which is assigned the next sequence number
+func (s *DBState) WriteRangeTombstoneToWAL(start []byte, end []byte) *table.WAL {
+	s.Lock()
+	defer s.Unlock()
+	s.wal.DeleteRange(start, end, s.lastSeq.Add(1))
+	return s.wal
+}
+
+// WriteRangeTombstoneToMemtable adds the range tombstone to the memtable, see
+// WriteEntryToMemtable
+func (s *DBState) WriteRangeTombstoneToMemtable(tombstone types.RangeTombstone) {
+	s.RLock()
+	defer s.RUnlock()
+	common.StoreMax(&s.lastSeq, tombstone.Seq)
+	if !s.walDisabled {
+		common.StoreMax(&s.committedSeq, tombstone.Seq)
+	}
+	s.memtable.DeleteRange(tombstone.Start, tombstone.End, tombstone.Seq)
+}
+
+// WriteEntryToMemtable puts or deletes the key of the entry in the memtable. The
+// memtable locks the shard of the key, so only freezing the memtable is excluded
+// while writing. Entries replayed from the WAL during recovery advance the
+// sequence number of new writes past the sequence number of the entry.
+func (s *DBState) WriteEntryToMemtable(entry types.RowEntry) {
+	s.RLock()
+	defer s.RUnlock()
+	common.StoreMax(&s.lastSeq, entry.Seq)
+	if !s.walDisabled {
+		common.StoreMax(&s.committedSeq, entry.Seq)
+	}
+	s.memtable.PutEntry(entry)
 }
 
 func (s *DBState) CoreStateSnapshot() *CoreStateSnapshot {
@@ -232,7 +448,7 @@ func (s *DBState) FreezeMemtable(walID uint64) {
 	oldMemtable := s.memtable
 	immMemtable := table.NewImmutableMemtable(oldMemtable, walID)
 
-	s.memtable = table.NewMemtable()
+	s.memtable = table.NewShardedMemtable(s.memtableShards).WithMergeOperator(s.mergeOperator)
 	s.immMemtables.PushFront(immMemtable)
 }
 
@@ -246,7 +462,7 @@ func (s *DBState) FreezeWAL() mo.Option[uint64] {
 
 	oldWAL := s.wal
 	immWAL := table.NewImmutableWAL(oldWAL, s.core.nextWalSstID.Load())
-	s.wal = table.NewWAL()
+	s.wal = table.NewWAL().WithMergeOperator(s.mergeOperator)
 	s.immWALs.PushFront(immWAL)
 	s.core.nextWalSstID.Add(1)
 
@@ -270,6 +486,24 @@ func (s *DBState) OldestImmWAL() mo.Option[*table.ImmutableWAL] {
 	return mo.Some(s.immWALs.Back())
 }
 
+// ImmMemtableCount returns the number of immutable memtables waiting to be flushed to L0
+func (s *DBState) ImmMemtableCount() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.immMemtables.Len()
+}
+
+// ImmMemtableBytes returns the size of the immutable memtables waiting to be flushed to L0
+func (s *DBState) ImmMemtableBytes() int64 {
+	s.RLock()
+	defer s.RUnlock()
+	var size int64
+	for i := 0; i < s.immMemtables.Len(); i++ {
+		size += s.immMemtables.At(i).Size()
+	}
+	return size
+}
+
 func (s *DBState) OldestImmMemtable() mo.Option[*table.ImmutableMemtable] {
 	s.RLock()
 	defer s.RUnlock()
@@ -289,12 +523,35 @@ func (s *DBState) MoveImmMemtableToL0(immMemtable *table.ImmutableMemtable, sstH
 
 	s.core.l0 = append([]sstable.Handle{*sstHandle}, s.core.l0...)
 	s.core.lastCompactedWalSSTID.Store(immMemtable.LastWalID())
+	common.StoreMax(&s.core.lastL0Seq, immMemtable.LastSeq())
+	if s.walDisabled {
+		common.StoreMax(&s.committedSeq, immMemtable.LastSeq())
+	}
+}
+
+// AddL0 adds the provided SSTables to L0 as the newest SSTables, so their
+// entries shadow the entries of every SSTable already in the DB. The seq is the
+// sequence number of the entries of the SSTables, see ReserveSeq.
+func (s *DBState) AddL0(handles []sstable.Handle, seq uint64) {
+	s.Lock()
+	defer s.Unlock()
+
+	l0 := make([]sstable.Handle, 0, len(handles)+len(s.core.l0))
+	l0 = append(l0, handles...)
+	s.core.l0 = append(l0, s.core.l0...)
+	common.StoreMax(&s.core.lastL0Seq, seq)
 }
 
 func (s *DBState) IncrementNextWALID() {
 	s.core.nextWalSstID.Add(1)
 }
 
+// AdvanceNextWALID raises the ID of the next ImmutableWAL to at least id, so WAL
+// IDs resume after the highest WAL found in object storage
+func (s *DBState) AdvanceNextWALID(id uint64) {
+	common.StoreMax(&s.core.nextWalSstID, id)
+}
+
 func (s *DBState) RefreshDBState(compactorState *CoreStateSnapshot) {
 	s.Lock()
 	defer s.Unlock()
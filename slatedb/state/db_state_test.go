@@ -9,6 +9,7 @@ import (
 
 	"github.com/slatedb/slatedb-go/internal/compress"
 	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/types"
 )
 
 func addL0sToDBState(dbState *DBState, n uint32) {
@@ -75,3 +76,32 @@ func TestRefreshDBStateWithAllL0sIfNoneCompacted(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	}
 }
+
+func TestWriteEntriesToWALIfUnchanged(t *testing.T) {
+	dbState := NewDBState(NewCoreDBState())
+	entry := func(key string) []types.RowEntry {
+		return []types.RowEntry{{Key: []byte(key), Value: types.Value{Value: []byte("value")}}}
+	}
+	dbState.WriteEntriesToWAL(nil, entry("key1"))
+	version := dbState.ReadVersion()
+
+	// Writes of other keys since the version don't conflict
+	dbState.WriteEntriesToWAL(nil, entry("key2"))
+	_, ok := dbState.WriteEntriesToWALIfUnchanged(nil, entry("key3"), [][]byte{[]byte("key1")}, version)
+	assert.True(t, ok)
+
+	// A write of an expected key conflicts, as does a range tombstone deleting it
+	_, ok = dbState.WriteEntriesToWALIfUnchanged(nil, entry("key3"), [][]byte{[]byte("key2")}, version)
+	assert.False(t, ok)
+	version = dbState.ReadVersion()
+	dbState.WriteRangeTombstoneToWAL([]byte("key0"), []byte("key2"))
+	_, ok = dbState.WriteEntriesToWALIfUnchanged(nil, entry("key3"), [][]byte{[]byte("key1")}, version)
+	assert.False(t, ok)
+
+	// A write of an expected key may have been flushed to L0 since the version
+	version = dbState.ReadVersion()
+	addL0sToDBState(dbState, 1)
+	_, ok = dbState.WriteEntriesToWALIfUnchanged(nil, entry("key3"), [][]byte{[]byte("key4")}, version)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(4), dbState.LastSeq())
+}
@@ -0,0 +1,283 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/slatedb/filter"
+	"github.com/slatedb/slatedb-go/slatedb/state"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// Stats is a point in time view of statistics about the DB
+type Stats struct {
+	// ManifestVersions is the number of manifest versions in object storage
+	// as of the last time manifests were pruned. Every version is a complete
+	// copy of the DB state, so a growing count only costs storage, but it
+	// usually means pruning is disabled or failing.
+	ManifestVersions int64
+
+	// ManifestVerifications is the number of manifest writes which were read back
+	// and verified, when DBOptions.ParanoidManifestWrites is enabled.
+	ManifestVerifications int64
+
+	// ManifestVerificationLatency is the total time spent verifying manifest writes.
+	// The mean latency of a verification is ManifestVerificationLatency divided by
+	// ManifestVerifications.
+	ManifestVerificationLatency time.Duration
+
+	// ObjectStore is the number of object store requests and bytes read and
+	// written since the DB was opened, for each class of operation which made
+	// requests. It attributes the cost of object storage to each workload.
+	ObjectStore map[store.IOClass]store.IOClassStats
+
+	// Levels is the number of entries and tombstones in L0 and each sorted run,
+	// see LevelStatsOf
+	Levels []LevelStats
+
+	// ImmutableMemtables is the number of immutable memtables waiting to be
+	// flushed to L0, see DBOptions.MaxImmutableMemtables
+	ImmutableMemtables int
+
+	// WriteStalls is the number of writes which stalled because the immutable
+	// memtables reached DBOptions.MaxImmutableMemtables, and WriteStallDuration
+	// is the total time those writes spent stalled.
+	WriteStalls        int64
+	WriteStallDuration time.Duration
+
+	// Throttle is the state of the back off of the background tasks while the
+	// object store throttles requests, see DBOptions.IsThrottled
+	Throttle store.ThrottleStats
+
+	// Retry is the number of object store requests retried, timed out and failed
+	// despite retries, see DBOptions.ObjectStoreRetry
+	Retry store.RetryStats
+
+	// IndexCache is the number of reads of indexes served by the index cache and
+	// read from object storage, see DBOptions.IndexCacheBytes
+	IndexCache store.IndexCacheStats
+
+	// CoalescedBlockFetches is the number of reads which missed the block cache
+	// and shared the fetch of the same blocks by a concurrent read, rather than
+	// making a request of their own
+	CoalescedBlockFetches int64
+
+	// Flush is the time spent in each stage of flushing memtables to L0
+	Flush FlushStats
+}
+
+// FlushStats is the time spent in each stage of flushing memtables to L0, see
+// store.TableWriterStats. The stages of memtables of at least
+// DBOptions.L0UploadPartSize overlap, in which case their sum exceeds the Total.
+type FlushStats struct {
+	// Flushes is the number of memtables flushed to L0
+	Flushes int64
+	Build   time.Duration
+	Encode  time.Duration
+	Upload  time.Duration
+	Total   time.Duration
+}
+
+// dbStats holds the live counters which back Stats
+type dbStats struct {
+	manifestVersions atomic.Int64
+
+	flushes     atomic.Int64
+	flushBuild  atomic.Int64
+	flushEncode atomic.Int64
+	flushUpload atomic.Int64
+	flushTotal  atomic.Int64
+}
+
+// recordFlush adds the time spent flushing a memtable to L0 to the FlushStats
+func (s *dbStats) recordFlush(stats store.TableWriterStats) {
+	s.flushes.Add(1)
+	s.flushBuild.Add(int64(stats.Build))
+	s.flushEncode.Add(int64(stats.Encode))
+	s.flushUpload.Add(int64(stats.Upload))
+	s.flushTotal.Add(int64(stats.Total))
+}
+
+func (s *dbStats) flush() FlushStats {
+	return FlushStats{
+		Flushes: s.flushes.Load(),
+		Build:   time.Duration(s.flushBuild.Load()),
+		Encode:  time.Duration(s.flushEncode.Load()),
+		Upload:  time.Duration(s.flushUpload.Load()),
+		Total:   time.Duration(s.flushTotal.Load()),
+	}
+}
+
+// Stats returns the current statistics of the DB
+func (db *DB) Stats() Stats {
+	verifications, latency := db.manifestStore.VerifyStats()
+	return Stats{
+		ManifestVersions:            db.stats.manifestVersions.Load(),
+		ManifestVerifications:       verifications,
+		ManifestVerificationLatency: latency,
+		ObjectStore:                 db.objectStoreStats(),
+		Levels:                      LevelStatsOf(db.state.CoreStateSnapshot()),
+		ImmutableMemtables:          db.state.ImmMemtableCount(),
+		WriteStalls:                 db.writeStalls.count.Load(),
+		WriteStallDuration:          time.Duration(db.writeStalls.duration.Load()),
+		Throttle:                    db.throttle.Stats(),
+		Retry:                       db.retry.Stats(),
+		IndexCache:                  db.tableStore.IndexCacheStats(),
+		CoalescedBlockFetches:       db.tableStore.CoalescedBlockFetches(),
+		Flush:                       db.stats.flush(),
+	}
+}
+
+// objectStoreStats returns the object store stats of the table and manifest stores
+func (db *DB) objectStoreStats() map[store.IOClass]store.IOClassStats {
+	stats := db.tableStore.IOStats().Snapshot()
+	for class, s := range db.manifestStore.IOStats().Snapshot() {
+		stats[class] = stats[class].Add(s)
+	}
+	return stats
+}
+
+// FilterConfig is the filter configuration recorded in an SSTable
+type FilterConfig struct {
+	// The name of the filter.Policy which created the filter, empty if the
+	// SSTable has no filter
+	Policy string
+	// The bits per key of the filter, zero if the SSTable has no filter, the
+	// policy does not use a fixed number of bits per key, or the SSTable was
+	// written before the bits per key were recorded
+	BitsPerKey uint32
+}
+
+func (c FilterConfig) String() string {
+	if c.Policy == "" {
+		return "none"
+	}
+	if c.BitsPerKey == 0 {
+		return c.Policy
+	}
+	return fmt.Sprintf("%s (%d bits per key)", c.Policy, c.BitsPerKey)
+}
+
+// FilterConfigs returns the number of SSTables in L0 and the sorted runs of the
+// DB built with each FilterConfig
+func (db *DB) FilterConfigs() map[FilterConfig]int {
+	return FilterConfigs(db.state.CoreStateSnapshot())
+}
+
+// FilterConfigs returns the number of SSTables in L0 and the sorted runs of the
+// provided state built with each FilterConfig. SSTables built before a change of
+// the filter configuration are replaced as they are compacted, so the distribution
+// tracks the rollout of the new configuration.
+func FilterConfigs(core *state.CoreStateSnapshot) map[FilterConfig]int {
+	configs := make(map[FilterConfig]int)
+	add := func(info *sstable.Info) {
+		var conf FilterConfig
+		if info.FilterLen > 0 {
+			conf.Policy = info.FilterPolicy
+			if conf.Policy == "" {
+				conf.Policy = filter.BloomPolicyName
+			}
+			conf.BitsPerKey = info.FilterBitsPerKey
+		}
+		configs[conf]++
+	}
+
+	for _, sst := range core.L0 {
+		add(sst.Info)
+	}
+	for _, sr := range core.Compacted {
+		for _, sst := range sr.SSTList {
+			add(sst.Info)
+		}
+	}
+	return configs
+}
+
+// LevelStats is the number of entries and tombstones in the SSTables of a level,
+// as recorded in the SSTables when they were written. SSTables written before
+// entries were counted are counted as SSTables but add no entries.
+type LevelStats struct {
+	// Level is "L0" for the SSTables in L0, or "SR <id>" for a sorted run
+	Level string
+	// SSTables is the number of SSTables in the level
+	SSTables int
+	// Entries is the number of entries in the level, including tombstones
+	Entries uint64
+	// Tombstones is the number of tombstones in the level
+	Tombstones uint64
+}
+
+// LiveEntries returns the number of entries in the level which are not tombstones
+func (s LevelStats) LiveEntries() uint64 {
+	return s.Entries - s.Tombstones
+}
+
+// DeleteRatio returns the fraction of the entries in the level which are
+// tombstones, or zero if the level has no entries
+func (s LevelStats) DeleteRatio() float64 {
+	if s.Entries == 0 {
+		return 0
+	}
+	return float64(s.Tombstones) / float64(s.Entries)
+}
+
+// LevelStatsOf returns the LevelStats of L0 followed by each sorted run of the
+// provided state. Tombstones are only dropped once compaction merges them into
+// the oldest sorted run, so the delete ratio of each level tracks how much of the
+// space of deleted keys is yet to be reclaimed.
+func LevelStatsOf(core *state.CoreStateSnapshot) []LevelStats {
+	level := func(name string, ssts []sstable.Handle) LevelStats {
+		stats := LevelStats{Level: name, SSTables: len(ssts)}
+		for _, sst := range ssts {
+			stats.Entries += sst.Info.EntryCount
+			stats.Tombstones += sst.Info.TombstoneCount
+		}
+		return stats
+	}
+
+	levels := []LevelStats{level("L0", core.L0)}
+	for _, sr := range core.Compacted {
+		levels = append(levels, level(fmt.Sprintf("SR %d", sr.ID), sr.SSTList))
+	}
+	return levels
+}
+
+// ApproximateSize returns the approximate number of bytes of the DB which hold keys
+// in the range [start, end), which estimates how much data a scan or delete of the
+// range touches. A nil start or end leaves the range unbounded. The sizes of the
+// memtables, the SSTables in L0 and the sorted runs are added together, so keys
+// written to more than one of them, and their tombstones, are counted more than
+// once. SSTable sizes are estimated from their index, with every block which may
+// hold a key in the range counted in full. The indexes which are not cached are
+// read with ctx.
+func (db *DB) ApproximateSize(ctx context.Context, start []byte, end []byte) (uint64, error) {
+	snapshot := db.state.Snapshot()
+
+	size := uint64(snapshot.Memtable.RangeSize(start, end))
+	for i := 0; i < snapshot.ImmMemtables.Len(); i++ {
+		size += uint64(snapshot.ImmMemtables.At(i).RangeSize(start, end))
+	}
+
+	tableStore := db.tableStore.Clone()
+	for _, sst := range snapshot.Core.L0 {
+		sstSize, err := sstable.ApproximateSize(ctx, &sst, tableStore, start, end)
+		if err != nil {
+			return 0, fmt.Errorf("while estimating size of sst '%s': %w", sst.Id.Value, err)
+		}
+		size += sstSize
+	}
+
+	for _, sr := range snapshot.Core.Compacted {
+		for _, sst := range sr.SSTList {
+			sstSize, err := sstable.ApproximateSize(ctx, &sst, tableStore, start, end)
+			if err != nil {
+				return 0, fmt.Errorf("while estimating size of sst '%s': %w", sst.Id.Value, err)
+			}
+			size += sstSize
+		}
+	}
+	return size, nil
+}
@@ -0,0 +1,342 @@
+// Package azurestore implements store.ObjectStore against Azure Blob Storage with
+// the Azure SDK. Open a DB with the ObjectStore using slatedb.OpenWithObjectStore.
+//
+// Objects are block blobs. Blocks of SSTables are read with range GETs, blobs
+// larger than Options.BlockSize are uploaded by staging their blocks and
+// committing the block list, and PutIfNotExists is a conditional write with
+// If-None-Match: *, which makes the fencing of writers and the updates of the
+// manifest atomic.
+package azurestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+const (
+	// DefaultBlockSize is the default size of the blocks of a staged upload
+	DefaultBlockSize = 8 * 1024 * 1024
+
+	// MaxBlockSize is the largest block Azure accepts
+	MaxBlockSize = 4000 * 1024 * 1024
+
+	// DefaultUploadConcurrency is the default number of blocks of a blob staged
+	// at the same time
+	DefaultUploadConcurrency = 4
+)
+
+// Options configures the ObjectStore
+type Options struct {
+	// The size of the blocks of a staged upload. Blobs no larger than the block
+	// size are uploaded with a single PUT. Defaults to DefaultBlockSize, and must
+	// be at most MaxBlockSize.
+	BlockSize int64
+
+	// The number of blocks of a blob staged at the same time, each of which holds
+	// a buffer of BlockSize. Defaults to DefaultUploadConcurrency.
+	UploadConcurrency int
+}
+
+// ClientConfig configures the container client returned by NewClient
+type ClientConfig struct {
+	// ConnectionString holds the account, key and endpoint of the storage account,
+	// such as the connection string of Azurite. If set, the client is a client of
+	// the container named by Container, and the other fields are ignored.
+	ConnectionString string
+	Container        string
+
+	// ContainerURL is the URL of the container, e.g.
+	// "https://<account>.blob.core.windows.net/<container>"
+	ContainerURL string
+
+	// AccountName and AccountKey are a shared key of the storage account. If
+	// empty, the credentials are provided by the default chain of the Azure SDK,
+	// which covers workload identity, managed identities and the Azure CLI, and
+	// refreshes them as they expire.
+	AccountName string
+	AccountKey  string
+}
+
+// NewClient returns a client of the container configured by the config
+func NewClient(conf ClientConfig) (*container.Client, error) {
+	if conf.ConnectionString != "" {
+		return container.NewClientFromConnectionString(conf.ConnectionString, conf.Container, nil)
+	}
+	if conf.AccountName != "" {
+		cred, err := container.NewSharedKeyCredential(conf.AccountName, conf.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("while creating shared key credential: %w", err)
+		}
+		return container.NewClientWithSharedKeyCredential(conf.ContainerURL, cred, nil)
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("while loading Azure credentials: %w", err)
+	}
+	return container.NewClient(conf.ContainerURL, cred, nil)
+}
+
+// ObjectStore is a store.ObjectStore which reads and writes the block blobs of an
+// Azure container, at the names of the paths of the objects
+type ObjectStore struct {
+	client *container.Client
+	opts   Options
+}
+
+var _ store.ObjectStore = (*ObjectStore)(nil)
+
+// NewObjectStore returns an ObjectStore which reads and writes the blobs of the
+// container through the client
+func NewObjectStore(client *container.Client, opts Options) (*ObjectStore, error) {
+	if opts.BlockSize == 0 {
+		opts.BlockSize = DefaultBlockSize
+	}
+	if opts.BlockSize < 0 || opts.BlockSize > MaxBlockSize {
+		return nil, fmt.Errorf("%w: block size must be at most %d bytes", common.ErrInvalidOptions, MaxBlockSize)
+	}
+	if opts.UploadConcurrency <= 0 {
+		opts.UploadConcurrency = DefaultUploadConcurrency
+	}
+	return &ObjectStore{client: client, opts: opts}, nil
+}
+
+func (s *ObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	return s.put(ctx, path, r, nil)
+}
+
+func (s *ObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	return s.put(ctx, path, r, &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)},
+	})
+}
+
+func (s *ObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := s.client.NewBlockBlobClient(path).DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return resp.Body, nil
+}
+
+// GetRange returns length bytes of the object from the offset off, or the rest
+// of the object if length is negative
+func (s *ObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	// A count of zero reads to the end of the blob
+	rng := blob.HTTPRange{Offset: off, Count: max(length, 0)}
+	resp, err := s.client.NewBlockBlobClient(path).DownloadStream(ctx, &blob.DownloadStreamOptions{Range: rng})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return resp.Body, nil
+}
+
+func (s *ObjectStore) Head(ctx context.Context, path string) (store.ObjectMeta, error) {
+	resp, err := s.client.NewBlockBlobClient(path).GetProperties(ctx, nil)
+	if err != nil {
+		return store.ObjectMeta{}, wrapNotFound(err)
+	}
+	meta := store.ObjectMeta{Location: path}
+	if resp.LastModified != nil {
+		meta.LastModified = *resp.LastModified
+	}
+	if resp.ContentLength != nil {
+		meta.Size = *resp.ContentLength
+	}
+	return meta, nil
+}
+
+// List returns the metadata of every object under the prefix directory, along
+// with the time each object was last modified and its size
+func (s *ObjectStore) List(ctx context.Context, prefix string) ([]store.ObjectMeta, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	objMetaList := make([]store.ObjectMeta, 0)
+	pager := s.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: to.Ptr(prefix)})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Segment.BlobItems {
+			meta := store.ObjectMeta{Location: *item.Name}
+			if item.Properties != nil {
+				if item.Properties.LastModified != nil {
+					meta.LastModified = *item.Properties.LastModified
+				}
+				if item.Properties.ContentLength != nil {
+					meta.Size = *item.Properties.ContentLength
+				}
+			}
+			objMetaList = append(objMetaList, meta)
+		}
+	}
+	return objMetaList, nil
+}
+
+func (s *ObjectStore) Delete(ctx context.Context, path string) error {
+	_, err := s.client.NewBlockBlobClient(path).Delete(ctx, nil)
+	return err
+}
+
+// IsThrottled returns true if Azure rejected the request as the account is
+// busy, with a ServerBusy error or a 429 or 503 response. The DB uses it to
+// detect throttling unless DBOptions.IsThrottled is set.
+func (s *ObjectStore) IsThrottled(err error) bool {
+	if bloberror.HasCode(err, bloberror.ServerBusy) {
+		return true
+	}
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) &&
+		(respErr.StatusCode == http.StatusTooManyRequests || respErr.StatusCode == http.StatusServiceUnavailable)
+}
+
+// put uploads the blob with a single PUT if it fits in a block, and by staging
+// its blocks otherwise. If conditions is set, the blob is only written if the
+// conditions hold.
+func (s *ObjectStore) put(ctx context.Context, path string, r io.Reader, conditions *blob.AccessConditions) error {
+	part, last, err := readBlock(r, s.opts.BlockSize)
+	if err != nil {
+		return fmt.Errorf("while reading object: %w", err)
+	}
+	if !last {
+		return s.putStaged(ctx, path, part, r, conditions)
+	}
+
+	_, err = s.client.NewBlockBlobClient(path).Upload(ctx, streaming.NopCloser(bytes.NewReader(part)),
+		&blockblob.UploadOptions{AccessConditions: conditions})
+	return wrapConditionNotMet(err)
+}
+
+// putStaged stages the blocks of the blob, the first of which was already read,
+// and commits the block list. The blob is only created or replaced once the
+// block list is committed, so a blob which fails to upload is never visible.
+// The conditions are evaluated when the block list is committed. Azure discards
+// the blocks of a failed upload which are never committed after a week.
+func (s *ObjectStore) putStaged(ctx context.Context, path string, first []byte, r io.Reader,
+	conditions *blob.AccessConditions) error {
+	blobClient := s.client.NewBlockBlobClient(path)
+
+	// Uncommitted blocks are shared by every upload of the blob, so the IDs are
+	// unique to the upload, or concurrent uploads of the blob, such as writers
+	// racing for the same manifest, would commit each other's blocks.
+	// Every block ID of a blob must have the same length.
+	uploadID := make([]byte, 8)
+	if _, err := rand.Read(uploadID); err != nil {
+		return err
+	}
+	blockID := func(number int) string {
+		return base64.StdEncoding.EncodeToString(
+			[]byte(fmt.Sprintf("%s-%08d", hex.EncodeToString(uploadID), number)))
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		errs     []error
+		blockIDs []string
+		inFlight = make(chan struct{}, s.opts.UploadConcurrency)
+	)
+	stageBlock := func(id string, data []byte) {
+		defer func() {
+			<-inFlight
+			wg.Done()
+		}()
+		_, err := blobClient.StageBlock(ctx, id, streaming.NopCloser(bytes.NewReader(data)), nil)
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("while staging block: %w", err))
+			mu.Unlock()
+		}
+	}
+
+	part, last := first, false
+	for number := 0; ; number++ {
+		id := blockID(number)
+		blockIDs = append(blockIDs, id)
+		inFlight <- struct{}{}
+		wg.Add(1)
+		go stageBlock(id, part)
+		if last {
+			break
+		}
+		var err error
+		part, last, err = readBlock(r, s.opts.BlockSize)
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("while reading object: %w", err))
+			mu.Unlock()
+			break
+		}
+		if len(part) == 0 {
+			break
+		}
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	_, err := blobClient.CommitBlockList(ctx, blockIDs, &blockblob.CommitBlockListOptions{
+		AccessConditions: conditions,
+	})
+	return wrapConditionNotMet(err)
+}
+
+// readBlock reads up to size bytes from r, and returns true if r has no more
+// bytes after them
+func readBlock(r io.Reader, size int64) ([]byte, bool, error) {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return buf[:n], true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return buf, false, nil
+}
+
+// wrapNotFound wraps err with common.ErrObjectNotFound if Azure reports that the
+// blob does not exist
+func wrapNotFound(err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ResourceNotFound) {
+		return fmt.Errorf("%w: %w", common.ErrObjectNotFound, err)
+	}
+	return err
+}
+
+// wrapConditionNotMet returns common.ErrObjectExists if a conditional write
+// failed because a blob exists at the path. Azure reports a write with
+// If-None-Match: * to an existing blob as a conflict rather than a failed
+// precondition.
+func wrapConditionNotMet(err error) error {
+	if bloberror.HasCode(err, bloberror.BlobAlreadyExists, bloberror.ConditionNotMet) {
+		return fmt.Errorf("%w: %w", common.ErrObjectExists, err)
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package azurestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slatedb/slatedb-go/slatedb"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/store/storetest"
+)
+
+func TestReadBlock(t *testing.T) {
+	block, last, err := readBlock(strings.NewReader("abcdef"), 4)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("abcd"), block)
+	assert.False(t, last)
+
+	block, last, err = readBlock(strings.NewReader("ab"), 4)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ab"), block)
+	assert.True(t, last)
+
+	_, _, err = readBlock(io.MultiReader(strings.NewReader("ab"), errReader{}), 4)
+	assert.Error(t, err)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+// testObjectStore returns an ObjectStore of a container of the storage account
+// of SLATEDB_AZURE_CONNECTION_STRING, such as Azurite started by make test_azure,
+// and skips the test if it is not set. Each test writes under a prefix of its own.
+func testObjectStore(t *testing.T, opts Options) (*ObjectStore, string) {
+	connectionString := os.Getenv("SLATEDB_AZURE_CONNECTION_STRING")
+	if connectionString == "" {
+		t.Skip("SLATEDB_AZURE_CONNECTION_STRING is not set")
+	}
+	client, err := NewClient(ClientConfig{ConnectionString: connectionString, Container: "slatedb-test"})
+	require.NoError(t, err)
+	_, err = client.Create(context.Background(), nil)
+	if !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		require.NoError(t, err)
+	}
+	objectStore, err := NewObjectStore(client, opts)
+	require.NoError(t, err)
+	return objectStore, fmt.Sprintf("%s/%d", t.Name(), time.Now().UnixNano())
+}
+
+func TestObjectStore(t *testing.T) {
+	objectStore, prefix := testObjectStore(t, Options{})
+	storetest.TestObjectStore(t, objectStore, prefix)
+}
+
+func TestStagedPutIfNotExists(t *testing.T) {
+	objectStore, prefix := testObjectStore(t, Options{BlockSize: 1024})
+	ctx := context.Background()
+
+	// The condition of a staged upload is evaluated as the block list is committed
+	large := make([]byte, 10*1024+100)
+	_, err := rand.Read(large)
+	require.NoError(t, err)
+	path := prefix + "/large"
+	require.NoError(t, objectStore.PutIfNotExists(ctx, path, bytes.NewReader(large)))
+	err = objectStore.PutIfNotExists(ctx, path, bytes.NewReader(large))
+	assert.ErrorIs(t, err, common.ErrObjectExists)
+
+	r, err := objectStore.Get(ctx, path)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, large, data)
+	require.NoError(t, r.Close())
+}
+
+func TestOpenDB(t *testing.T) {
+	objectStore, prefix := testObjectStore(t, Options{})
+	ctx := context.Background()
+	options := config.DefaultDBOptions()
+	options.DisableBackgroundTasks = true
+
+	db, err := slatedb.OpenWithObjectStore(ctx, prefix, objectStore, options)
+	require.NoError(t, err)
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: true}))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Close())
+
+	db, err = slatedb.OpenWithObjectStore(ctx, prefix, objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+}
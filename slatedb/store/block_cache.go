@@ -0,0 +1,220 @@
+package store
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// blockKey identifies a block by the SSTable and the offset of the block within it
+type blockKey struct {
+	id     sstable.ID
+	offset uint64
+}
+
+// cachedBlock holds the decoded block, or the encoded block if the checksum of
+// the block is verified on every read
+type cachedBlock struct {
+	block   block.Block
+	encoded []byte
+}
+
+// size returns the number of bytes the cachedBlock is charged against the capacity of the cache
+func (b cachedBlock) size() uint32 {
+	size := len(b.encoded) + len(b.block.Data) + len(b.block.Offsets)*common.SizeOfUint16
+	return uint32(min(size, math.MaxUint32))
+}
+
+// blockCacheShards is the number of shards of a blockCache. Reads of blocks in
+// different shards never share a lock.
+const blockCacheShards = 64
+
+// blockCache caches the blocks read by the TableStore. A nil blockCache caches
+// nothing, every read fetches the blocks from object storage. Unlike the filter
+// and dictionary caches, hits don't take the lock of the TableStore. The blocks
+// are spread over shards, and a hit only takes the read lock of its shard and
+// marks the block as referenced, so cached reads don't contend with each other.
+// Inserts and evictions take the write lock of a single shard.
+type blockCache struct {
+	shards       [blockCacheShards]blockCacheShard
+	capacity     uint64
+	verification config.ChecksumVerification
+}
+
+// blockCacheEntry is a block in a shard of the blockCache
+type blockCacheEntry struct {
+	key   blockKey
+	block cachedBlock
+	size  uint64
+	// referenced is set by every hit and cleared as the eviction hand passes the
+	// entry, so a block read since the last pass survives another one
+	referenced atomic.Bool
+}
+
+// blockCacheShard holds the blocks of a blockCache whose keys hash to the shard.
+// Blocks are evicted with the CLOCK algorithm, the ring holds the entries in
+// insertion order and hand is the position of the next eviction candidate.
+type blockCacheShard struct {
+	mu       sync.RWMutex
+	entries  map[blockKey]*blockCacheEntry
+	ring     []*blockCacheEntry
+	hand     int
+	size     uint64
+	capacity uint64
+}
+
+// newBlockCache returns a blockCache which holds up to capacity bytes of blocks,
+// or nil if capacity is zero
+func newBlockCache(capacity uint64, verification config.ChecksumVerification) *blockCache {
+	if capacity == 0 {
+		return nil
+	}
+	c := &blockCache{capacity: capacity, verification: verification}
+	for i := range c.shards {
+		c.shards[i].entries = make(map[blockKey]*blockCacheEntry)
+		c.shards[i].capacity = max(capacity/blockCacheShards, 1)
+	}
+	return c
+}
+
+// shard returns the shard which holds the block identified by key. The key is
+// hashed with FNV-1a inline, so a lookup doesn't allocate.
+func (c *blockCache) shard(key blockKey) *blockCacheShard {
+	const prime = 1099511628211
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key.id.Value); i++ {
+		h = (h ^ uint64(key.id.Value[i])) * prime
+	}
+	for i := 0; i < 8; i++ {
+		h = (h ^ (key.offset >> (8 * i) & 0xff)) * prime
+	}
+	return &c.shards[h%blockCacheShards]
+}
+
+// lookup returns the cached block identified by key and marks it as referenced
+func (c *blockCache) lookup(key blockKey) (cachedBlock, bool) {
+	shard := c.shard(key)
+	shard.mu.RLock()
+	entry, ok := shard.entries[key]
+	shard.mu.RUnlock()
+	if !ok {
+		return cachedBlock{}, false
+	}
+	if !entry.referenced.Load() {
+		entry.referenced.Store(true)
+	}
+	return entry.block, true
+}
+
+// insert adds the block identified by key, evicting blocks of the shard until it fits.
+// A block larger than the capacity of its shard is not cached.
+func (c *blockCache) insert(key blockKey, cached cachedBlock) {
+	entry := &blockCacheEntry{key: key, block: cached, size: uint64(cached.size())}
+	shard := c.shard(key)
+	if entry.size > shard.capacity {
+		return
+	}
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, ok := shard.entries[key]; ok {
+		return
+	}
+	for shard.size+entry.size > shard.capacity {
+		shard.evict()
+	}
+	shard.entries[key] = entry
+	shard.ring = append(shard.ring, entry)
+	shard.size += entry.size
+}
+
+// evict removes the first unreferenced entry from the hand of the shard, clearing
+// the referenced flag of the entries it passes. The caller must hold the write lock.
+func (s *blockCacheShard) evict() {
+	for {
+		if s.hand >= len(s.ring) {
+			s.hand = 0
+		}
+		entry := s.ring[s.hand]
+		if entry.referenced.Swap(false) {
+			s.hand++
+			continue
+		}
+		s.remove(s.hand)
+		return
+	}
+}
+
+// remove removes the entry at position i of the ring, keeping the hand on the
+// entry which followed it. The caller must hold the write lock.
+func (s *blockCacheShard) remove(i int) {
+	entry := s.ring[i]
+	delete(s.entries, entry.key)
+	s.size -= entry.size
+	s.ring = slices.Delete(s.ring, i, i+1)
+	if s.hand > i {
+		s.hand--
+	}
+}
+
+// clone returns an empty blockCache with the same capacity and verification
+func (c *blockCache) clone() *blockCache {
+	if c == nil {
+		return nil
+	}
+	return newBlockCache(c.capacity, c.verification)
+}
+
+// get returns the block at blockIndex of the SSTable. With ChecksumVerifyOnRead the
+// cached block is decoded, which verifies its checksum.
+func (c *blockCache) get(sst *sstable.Handle, index *sstable.Index, blockIndex uint64, dict []byte) (block.Block, bool, error) {
+	if c == nil {
+		return block.Block{}, false, nil
+	}
+	cached, ok := c.lookup(blockKey{id: sst.Id, offset: index.BlockMeta()[blockIndex].Offset})
+	if !ok {
+		return block.Block{}, false, nil
+	}
+	if c.verification != config.ChecksumVerifyOnRead {
+		return cached.block, true, nil
+	}
+	blk, err := sstable.DecodeBlock(sst.Info, index, blockIndex, cached.encoded, dict)
+	if err != nil {
+		return block.Block{}, false, err
+	}
+	return blk, true, nil
+}
+
+// set inserts the block at blockIndex of the SSTable, which was decoded from encoded
+func (c *blockCache) set(sst *sstable.Handle, index *sstable.Index, blockIndex uint64, blk block.Block, encoded []byte) {
+	if c == nil {
+		return
+	}
+	cached := cachedBlock{block: blk}
+	if c.verification == config.ChecksumVerifyOnRead {
+		cached = cachedBlock{encoded: encoded}
+	}
+	c.insert(blockKey{id: sst.Id, offset: index.BlockMeta()[blockIndex].Offset}, cached)
+}
+
+// deleteSST removes the blocks of the SSTable from the cache
+func (c *blockCache) deleteSST(id sstable.ID) {
+	if c == nil {
+		return
+	}
+	for i := range c.shards {
+		shard := &c.shards[i]
+		shard.mu.Lock()
+		for j := len(shard.ring) - 1; j >= 0; j-- {
+			if shard.ring[j].key.id == id {
+				shard.remove(j)
+			}
+		}
+		shard.mu.Unlock()
+	}
+}
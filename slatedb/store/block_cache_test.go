@@ -0,0 +1,183 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// writeBlockCacheSST writes an SSTable of several blocks and returns its handle
+func writeBlockCacheSST(t testing.TB, tableStore *TableStore) *sstable.Handle {
+	builder := tableStore.TableBuilder()
+	require.NoError(t, builder.AddValue([]byte("aa"), []byte("11")))
+	require.NoError(t, builder.AddValue([]byte("bb"), []byte("22")))
+	require.NoError(t, builder.AddValue([]byte("cccccccccccccccccccc"), []byte("33333333333333333333")))
+	require.NoError(t, builder.AddValue([]byte("dddddddddddddddddddd"), []byte("44444444444444444444")))
+	table, err := builder.Build()
+	require.NoError(t, err)
+
+	handle, err := tableStore.WriteSST(context.Background(), sstable.NewIDCompacted(ulid.Make()), table)
+	require.NoError(t, err)
+	return handle
+}
+
+// corruptBlock flips a byte of the first block of the SSTable in the bucket
+func corruptBlock(t testing.TB, bucket objstore.Bucket, tableStore *TableStore, handle *sstable.Handle) {
+	sstPath := tableStore.sstPath(handle.Id)
+	reader, err := bucket.Get(context.Background(), sstPath)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	data[0] ^= 0xff
+	require.NoError(t, bucket.Upload(context.Background(), sstPath, bytes.NewReader(data)))
+}
+
+func TestBlockCacheVerifyOnCacheInsert(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = 52
+	tableStore := NewTableStore(bucket, conf, "")
+	tableStore.SetBlockCache(1024*1024, config.ChecksumVerifyOnCacheInsert)
+	handle := writeBlockCacheSST(t, tableStore)
+
+	blocks, err := tableStore.ReadBlocks(context.Background(), handle, common.Range{Start: 0, End: 2})
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+
+	// Cached blocks are served without fetching them, so the corruption in the
+	// object store is not observed. Only the index is fetched.
+	corruptBlock(t, bucket, tableStore, handle)
+	cached, err := tableStore.WithIOClass(IOClassGet).ReadBlocks(context.Background(), handle, common.Range{Start: 0, End: 2})
+	require.NoError(t, err)
+	assert.Equal(t, blocks, cached)
+	assert.Equal(t, int64(1), tableStore.IOStats().Snapshot()[IOClassGet].Requests)
+
+	// Deleting the SSTable evicts its blocks
+	index, err := tableStore.ReadIndex(context.Background(), handle)
+	require.NoError(t, err)
+	require.NoError(t, tableStore.DeleteSST(context.Background(), handle.Id))
+	_, ok := tableStore.blockCache.lookup(blockKey{id: handle.Id, offset: index.BlockMeta()[0].Offset})
+	assert.False(t, ok)
+}
+
+func TestBlockCacheVerifyOnRead(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = 52
+	tableStore := NewTableStore(bucket, conf, "")
+	tableStore.SetBlockCache(1024*1024, config.ChecksumVerifyOnRead)
+	handle := writeBlockCacheSST(t, tableStore)
+
+	blocks, err := tableStore.ReadBlocks(context.Background(), handle, common.Range{Start: 0, End: 2})
+	require.NoError(t, err)
+	cached, err := tableStore.ReadBlocks(context.Background(), handle, common.Range{Start: 0, End: 2})
+	require.NoError(t, err)
+	assert.Equal(t, blocks, cached)
+
+	// A cached block corrupted in memory fails verification on the next read
+	index, err := tableStore.ReadIndex(context.Background(), handle)
+	require.NoError(t, err)
+	entry, ok := tableStore.blockCache.lookup(blockKey{id: handle.Id, offset: index.BlockMeta()[1].Offset})
+	require.True(t, ok)
+	require.NotEmpty(t, entry.encoded)
+	entry.encoded[0] ^= 0xff
+
+	_, err = tableStore.ReadBlocks(context.Background(), handle, common.Range{Start: 0, End: 2})
+	assert.ErrorIs(t, err, common.ErrChecksumMismatch)
+}
+
+func TestBlockCacheEvictsUnreferencedBlocks(t *testing.T) {
+	cache := newBlockCache(blockCacheShards*100, config.ChecksumVerifyOnCacheInsert)
+	id := sstable.NewIDCompacted(ulid.Make())
+	cached := cachedBlock{block: block.Block{Data: make([]byte, 40)}}
+
+	// Find three blocks which land in the same shard, only two of them fit
+	var keys []blockKey
+	shard := cache.shard(blockKey{id: id})
+	for offset := uint64(0); len(keys) < 3; offset++ {
+		if key := (blockKey{id: id, offset: offset}); cache.shard(key) == shard {
+			keys = append(keys, key)
+		}
+	}
+	cache.insert(keys[0], cached)
+	cache.insert(keys[1], cached)
+
+	// The block read since it was inserted survives the eviction
+	_, ok := cache.lookup(keys[0])
+	require.True(t, ok)
+	cache.insert(keys[2], cached)
+
+	_, ok = cache.lookup(keys[0])
+	assert.True(t, ok)
+	_, ok = cache.lookup(keys[1])
+	assert.False(t, ok)
+	_, ok = cache.lookup(keys[2])
+	assert.True(t, ok)
+	assert.Equal(t, uint64(80), shard.size)
+
+	// A block larger than a shard is not cached
+	large := blockKey{id: id, offset: 1 << 20}
+	cache.insert(large, cachedBlock{block: block.Block{Data: make([]byte, 101)}})
+	_, ok = cache.lookup(large)
+	assert.False(t, ok)
+
+	cache.deleteSST(id)
+	assert.Zero(t, shard.size)
+	assert.Empty(t, shard.ring)
+}
+
+func BenchmarkBlockCacheHit(b *testing.B) {
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = 52
+	tableStore := NewTableStore(objstore.NewInMemBucket(), conf, "")
+	tableStore.SetBlockCache(1024*1024, config.ChecksumVerifyOnCacheInsert)
+	handle := writeBlockCacheSST(b, tableStore)
+	index, err := tableStore.ReadIndex(context.Background(), handle)
+	require.NoError(b, err)
+	_, err = tableStore.ReadBlocksUsingIndex(context.Background(), handle, common.Range{Start: 0, End: 2}, index)
+	require.NoError(b, err)
+
+	// Run with -cpu 1,8,64 to compare the contention of cached reads
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := tableStore.ReadBlocksUsingIndex(context.Background(), handle, common.Range{Start: 0, End: 2}, index); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkBlockCacheLookup(b *testing.B) {
+	cache := newBlockCache(64*1024*1024, config.ChecksumVerifyOnCacheInsert)
+	id := sstable.NewIDCompacted(ulid.Make())
+	const blocks = 4096
+	for i := uint64(0); i < blocks; i++ {
+		cache.insert(blockKey{id: id, offset: i * 4096}, cachedBlock{block: block.Block{Data: make([]byte, 4096)}})
+	}
+
+	// Run with -cpu 1,8,64, the hits are spread over all the blocks
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i uint64
+		for pb.Next() {
+			if _, ok := cache.lookup(blockKey{id: id, offset: (i % blocks) * 4096}); !ok {
+				b.Fatal("block not cached")
+			}
+			i += 7
+		}
+	})
+}
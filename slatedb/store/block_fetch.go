@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// blockFetchKey identifies a fetch of a range of blocks of an SSTable
+type blockFetchKey struct {
+	id     sstable.ID
+	blocks common.Range
+}
+
+// blockFetch is a fetch of blocks in flight, whose result is shared by every
+// reader waiting on it once done is closed
+type blockFetch struct {
+	done   chan struct{}
+	blocks []block.Block
+	err    error
+}
+
+// blockFetches coalesces concurrent fetches of the same blocks of an SSTable, so
+// readers which miss the block cache for the same blocks at the same time, such as
+// Gets of keys in the same block, share a single request to the object store.
+// Only fetches of the same range of blocks are coalesced. Shared with the clones
+// of the TableStore.
+type blockFetches struct {
+	mu      sync.Mutex
+	flights map[blockFetchKey]*blockFetch
+	// coalesced is the number of fetches which waited on a fetch in flight
+	// rather than making their own request
+	coalesced atomic.Int64
+}
+
+func newBlockFetches() *blockFetches {
+	return &blockFetches{flights: make(map[blockFetchKey]*blockFetch)}
+}
+
+// do calls fetch, unless a fetch of the same blocks is in flight, in which case
+// it waits for that fetch and returns its result. A reader waiting on a fetch which
+// was canceled by the context of the reader which made it fetches the blocks again,
+// unless ctx is done as well.
+func (f *blockFetches) do(ctx context.Context, key blockFetchKey, fetch func() ([]block.Block, error)) ([]block.Block, error) {
+	for {
+		f.mu.Lock()
+		flight, ok := f.flights[key]
+		if !ok {
+			flight = &blockFetch{done: make(chan struct{})}
+			f.flights[key] = flight
+			f.mu.Unlock()
+
+			flight.blocks, flight.err = fetch()
+			f.mu.Lock()
+			delete(f.flights, key)
+			f.mu.Unlock()
+			close(flight.done)
+			return flight.blocks, flight.err
+		}
+		f.mu.Unlock()
+
+		f.coalesced.Add(1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-flight.done:
+		}
+		canceled := errors.Is(flight.err, context.Canceled) || errors.Is(flight.err, context.DeadlineExceeded)
+		if !canceled || ctx.Err() != nil {
+			return flight.blocks, flight.err
+		}
+	}
+}
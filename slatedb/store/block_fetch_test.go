@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// gatedObjectStore counts the range reads, which wait until release is closed
+// once it is set
+type gatedObjectStore struct {
+	ObjectStore
+	requests atomic.Int64
+	release  chan struct{}
+}
+
+func (s *gatedObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	s.requests.Add(1)
+	if s.release != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.release:
+		}
+	}
+	return s.ObjectStore.GetRange(ctx, path, off, length)
+}
+
+func TestConcurrentReadsOfBlocksShareFetch(t *testing.T) {
+	ctx := context.Background()
+	gated := &gatedObjectStore{ObjectStore: NewBucketObjectStore(objstore.NewInMemBucket())}
+	tableStore := NewTableStoreWithObjectStore(gated, sstable.DefaultConfig(), "")
+	builder := tableStore.TableBuilder()
+	require.NoError(t, builder.AddValue([]byte("key1"), []byte("value1")))
+	table, err := builder.Build()
+	require.NoError(t, err)
+	handle, err := tableStore.WriteSST(ctx, sstable.NewIDWal(1), table)
+	require.NoError(t, err)
+	index, err := tableStore.ReadIndex(ctx, handle)
+	require.NoError(t, err)
+
+	read := func(ctx context.Context) ([]block.Block, error) {
+		return tableStore.ReadBlocksUsingIndex(ctx, handle, common.Range{Start: 0, End: 1}, index)
+	}
+
+	// The reads which arrive while the first fetch is in flight wait for it
+	gated.requests.Store(0)
+	gated.release = make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([][]block.Block, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			blocks, err := read(ctx)
+			assert.NoError(t, err)
+			results[i] = blocks
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return tableStore.CoalescedBlockFetches() == 7
+	}, 5*time.Second, time.Millisecond)
+	close(gated.release)
+	wg.Wait()
+	assert.Equal(t, int64(1), gated.requests.Load())
+	for _, blocks := range results {
+		assert.Equal(t, results[0], blocks)
+	}
+
+	// A read waiting on a fetch canceled by the reader which made it fetches the
+	// blocks itself
+	gated.requests.Store(0)
+	gated.release = make(chan struct{})
+	canceled, cancel := context.WithCancel(ctx)
+	leaderErr := make(chan error)
+	go func() {
+		_, err := read(canceled)
+		leaderErr <- err
+	}()
+	require.Eventually(t, func() bool { return gated.requests.Load() == 1 }, 5*time.Second, time.Millisecond)
+	done := make(chan []block.Block)
+	go func() {
+		blocks, err := read(ctx)
+		assert.NoError(t, err)
+		done <- blocks
+	}()
+	require.Eventually(t, func() bool {
+		return tableStore.CoalescedBlockFetches() == 8
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	close(gated.release)
+	assert.Equal(t, results[0], <-done)
+	assert.Equal(t, int64(2), gated.requests.Load())
+}
@@ -0,0 +1,37 @@
+package fsstore
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	directIOSupported = true
+
+	// directIOAlignment is the alignment of the offsets, sizes and buffers of
+	// reads with O_DIRECT, which covers the logical block size of every device
+	directIOAlignment = 4096
+)
+
+// openDirect opens the file for reading with O_DIRECT, or through the page cache
+// if the filesystem doesn't support O_DIRECT
+func openDirect(file string) (*os.File, error) {
+	f, err := os.OpenFile(file, os.O_RDONLY|syscall.O_DIRECT, 0)
+	if errors.Is(err, syscall.EINVAL) {
+		return os.Open(file)
+	}
+	return f, err
+}
+
+// alignedBuffer returns a buffer of the size whose address is aligned to
+// directIOAlignment
+func alignedBuffer(size int) []byte {
+	buf := make([]byte, size+directIOAlignment)
+	shift := 0
+	if rem := int(uintptr(unsafe.Pointer(&buf[0])) & (directIOAlignment - 1)); rem != 0 {
+		shift = directIOAlignment - rem
+	}
+	return buf[shift : shift+size : shift+size]
+}
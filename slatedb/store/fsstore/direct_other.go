@@ -0,0 +1,18 @@
+//go:build !linux
+
+package fsstore
+
+import "os"
+
+const (
+	directIOSupported = false
+	directIOAlignment = 4096
+)
+
+func openDirect(file string) (*os.File, error) {
+	return os.Open(file)
+}
+
+func alignedBuffer(size int) []byte {
+	return make([]byte, size)
+}
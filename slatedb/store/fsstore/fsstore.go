@@ -0,0 +1,284 @@
+// Package fsstore implements store.ObjectStore with the files of a directory of
+// the local filesystem, for development, embedded use and testing without any
+// cloud dependency. Open a DB with the ObjectStore using
+// slatedb.OpenWithObjectStore.
+//
+// Every object is written to a temporary file which is synced and then moved to
+// the path of the object, so readers never see a partially written object. Put
+// renames the temporary file over the object, while PutIfNotExists links it at
+// the path of the object, which unlike a rename fails if the path exists, so the
+// fencing of writers and the updates of the manifest are atomic, even between
+// processes sharing the directory.
+package fsstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// tmpDir is the directory, relative to the root of the ObjectStore, which holds
+// the files being written. It is within the root so the files are moved within
+// the same filesystem, and is excluded from List.
+const tmpDir = ".tmp"
+
+// Options configures the ObjectStore
+type Options struct {
+	// NoSync skips syncing the files and directories of written objects, which
+	// makes writes faster at the cost of losing objects if the machine crashes.
+	// Use it for tests only.
+	NoSync bool
+
+	// DirectIO reads the objects with O_DIRECT, which bypasses the page cache
+	// so the block cache of the DB is not duplicated by the page cache of the
+	// kernel. Filesystems which don't support O_DIRECT, such as tmpfs, are read
+	// through the page cache. Only supported on Linux.
+	DirectIO bool
+}
+
+// ObjectStore is a store.ObjectStore which reads and writes the files of a
+// directory, at the paths of the objects relative to the directory
+type ObjectStore struct {
+	dir  string
+	opts Options
+}
+
+var _ store.ObjectStore = (*ObjectStore)(nil)
+
+// NewObjectStore returns an ObjectStore which reads and writes the files of the
+// directory, which is created if it does not exist
+func NewObjectStore(dir string, opts Options) (*ObjectStore, error) {
+	if opts.DirectIO && !directIOSupported {
+		return nil, fmt.Errorf("%w: direct I/O is not supported on this platform", common.ErrInvalidOptions)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, tmpDir), 0o755); err != nil {
+		return nil, fmt.Errorf("while creating directory: %w", err)
+	}
+	return &ObjectStore{dir: dir, opts: opts}, nil
+}
+
+func (s *ObjectStore) Put(_ context.Context, objPath string, r io.Reader) error {
+	return s.put(objPath, r, os.Rename)
+}
+
+func (s *ObjectStore) PutIfNotExists(_ context.Context, objPath string, r io.Reader) error {
+	return s.put(objPath, r, func(tmp string, file string) error {
+		err := os.Link(tmp, file)
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%w: %w", common.ErrObjectExists, err)
+		}
+		return err
+	})
+}
+
+func (s *ObjectStore) Get(_ context.Context, objPath string) (io.ReadCloser, error) {
+	if s.opts.DirectIO {
+		return s.readDirect(objPath, 0, -1)
+	}
+	f, err := os.Open(s.file(objPath))
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return f, nil
+}
+
+// GetRange returns length bytes of the object from the offset off, or the rest
+// of the object if length is negative
+func (s *ObjectStore) GetRange(_ context.Context, objPath string, off, length int64) (io.ReadCloser, error) {
+	if s.opts.DirectIO {
+		return s.readDirect(objPath, off, length)
+	}
+	f, err := os.Open(s.file(objPath))
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	if length < 0 {
+		info, err := f.Stat()
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		length = max(info.Size()-off, 0)
+	}
+	return sectionReadCloser{Reader: io.NewSectionReader(f, off, length), Closer: f}, nil
+}
+
+func (s *ObjectStore) Head(_ context.Context, objPath string) (store.ObjectMeta, error) {
+	info, err := os.Stat(s.file(objPath))
+	if err != nil {
+		return store.ObjectMeta{}, wrapNotFound(err)
+	}
+	if info.IsDir() {
+		return store.ObjectMeta{}, fmt.Errorf("%w: '%s' is a directory", common.ErrObjectNotFound, objPath)
+	}
+	return store.ObjectMeta{LastModified: info.ModTime(), Location: objPath, Size: info.Size()}, nil
+}
+
+// List returns the metadata of every object under the prefix directory, along
+// with the time each object was last modified and its size
+func (s *ObjectStore) List(_ context.Context, prefix string) ([]store.ObjectMeta, error) {
+	objMetaList := make([]store.ObjectMeta, 0)
+	root := s.file(prefix)
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if file == filepath.Join(s.dir, tmpDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// The object was deleted while listing
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		objMetaList = append(objMetaList, store.ObjectMeta{
+			LastModified: info.ModTime(),
+			Location:     path.Join(prefix, filepath.ToSlash(rel)),
+			Size:         info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objMetaList, nil
+}
+
+func (s *ObjectStore) Delete(_ context.Context, objPath string) error {
+	return wrapNotFound(os.Remove(s.file(objPath)))
+}
+
+// file returns the file of the object. Paths are cleaned as if they were rooted,
+// so no path resolves to a file outside the directory.
+func (s *ObjectStore) file(objPath string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+objPath)))
+}
+
+// put writes the object to a temporary file, which is synced and then moved to
+// the file of the object with publish
+func (s *ObjectStore) put(objPath string, r io.Reader, publish func(tmp string, file string) error) error {
+	file := s.file(objPath)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("while creating directory: %w", err)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, tmpDir, filepath.Base(file)+"."+hex.EncodeToString(suffix))
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("while creating temporary file: %w", err)
+	}
+	// The temporary file is removed once published, or if the write failed
+	defer func() { _ = os.Remove(tmp) }()
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("while writing object: %w", err)
+	}
+	if !s.opts.NoSync {
+		if err := f.Sync(); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("while syncing object: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("while writing object: %w", err)
+	}
+
+	if err := publish(tmp, file); err != nil {
+		return err
+	}
+	if !s.opts.NoSync {
+		return syncDir(filepath.Dir(file))
+	}
+	return nil
+}
+
+// syncDir syncs the directory, which makes the files moved into it durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("while syncing directory: %w", err)
+	}
+	return nil
+}
+
+// readDirect reads length bytes of the object from the offset off with O_DIRECT,
+// or the rest of the object if length is negative. O_DIRECT requires reads to be
+// aligned, so the aligned range which covers the requested range is read into
+// memory.
+func (s *ObjectStore) readDirect(objPath string, off, length int64) (io.ReadCloser, error) {
+	f, err := openDirect(s.file(objPath))
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if length < 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		length = max(info.Size()-off, 0)
+	}
+	if length == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	start := off &^ (directIOAlignment - 1)
+	end := (off + length + directIOAlignment - 1) &^ (directIOAlignment - 1)
+	buf := alignedBuffer(int(end - start))
+	n, err := f.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	from := min(int(off-start), n)
+	to := min(from+int(length), n)
+	return io.NopCloser(bytes.NewReader(buf[from:to])), nil
+}
+
+// sectionReadCloser reads a section of a file, and closes the file when closed
+type sectionReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// wrapNotFound wraps err with common.ErrObjectNotFound if the file of the object
+// does not exist
+func wrapNotFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", common.ErrObjectNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package fsstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slatedb/slatedb-go/slatedb"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/store/storetest"
+)
+
+func TestObjectStore(t *testing.T) {
+	objectStore, err := NewObjectStore(t.TempDir(), Options{})
+	require.NoError(t, err)
+	storetest.TestObjectStore(t, objectStore, "test")
+}
+
+func TestObjectStoreDirectIO(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		_, err := NewObjectStore(t.TempDir(), Options{DirectIO: true})
+		assert.ErrorIs(t, err, common.ErrInvalidOptions)
+		return
+	}
+	objectStore, err := NewObjectStore(t.TempDir(), Options{DirectIO: true, NoSync: true})
+	require.NoError(t, err)
+	storetest.TestObjectStore(t, objectStore, "test")
+
+	// Ranges which are not aligned span several aligned blocks
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	require.NoError(t, objectStore.Put(ctx, "obj", bytes.NewReader(data)))
+	for _, rng := range [][2]int64{{0, 1}, {4095, 2}, {4000, 5000}, {9999, -1}, {9000, 5000}} {
+		r, err := objectStore.GetRange(ctx, "obj", rng[0], rng[1])
+		require.NoError(t, err)
+		read, err := io.ReadAll(r)
+		require.NoError(t, err)
+		end := int64(len(data))
+		if rng[1] >= 0 {
+			end = min(rng[0]+rng[1], end)
+		}
+		assert.Equal(t, data[rng[0]:end], read)
+	}
+}
+
+func TestPutIfNotExistsIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	objectStore, err := NewObjectStore(dir, Options{NoSync: true})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Exactly one of the writers racing for the path creates the object
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := objectStore.PutIfNotExists(ctx, "manifest/00000000000000000001.manifest", strings.NewReader("manifest"))
+			if errors.Is(err, common.ErrObjectExists) {
+				return
+			}
+			assert.NoError(t, err)
+			mu.Lock()
+			created++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, created)
+
+	// No temporary file is left behind, or listed
+	tmp, err := os.ReadDir(filepath.Join(dir, tmpDir))
+	require.NoError(t, err)
+	assert.Empty(t, tmp)
+	list, err := objectStore.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "manifest/00000000000000000001.manifest", list[0].Location)
+}
+
+func TestPathsStayWithinDir(t *testing.T) {
+	dir := t.TempDir()
+	objectStore, err := NewObjectStore(filepath.Join(dir, "store"), Options{NoSync: true})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, objectStore.Put(ctx, "../../escaped", strings.NewReader("data")))
+	_, err = os.Stat(filepath.Join(dir, "escaped"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dir, "store", "escaped"))
+	assert.NoError(t, err)
+}
+
+func TestOpenDB(t *testing.T) {
+	dir := t.TempDir()
+	objectStore, err := NewObjectStore(dir, Options{})
+	require.NoError(t, err)
+	ctx := context.Background()
+	options := config.DefaultDBOptions()
+	options.DisableBackgroundTasks = true
+
+	db, err := slatedb.OpenWithObjectStore(ctx, "/tmp/test_kv_store", objectStore, options)
+	require.NoError(t, err)
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: true}))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Close())
+
+	// A second ObjectStore of the directory, as another process would open it
+	objectStore, err = NewObjectStore(dir, Options{DirectIO: runtime.GOOS == "linux"})
+	require.NoError(t, err)
+	db, err = slatedb.OpenWithObjectStore(ctx, "/tmp/test_kv_store", objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+}
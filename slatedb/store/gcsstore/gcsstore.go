@@ -0,0 +1,169 @@
+// Package gcsstore implements store.ObjectStore against Google Cloud Storage with
+// the Cloud Storage client library. Open a DB with the ObjectStore using
+// slatedb.OpenWithObjectStore.
+//
+// Blocks are read with range reads, objects are uploaded in chunks of
+// Options.ChunkSize with resumable uploads, and PutIfNotExists is a write with
+// the precondition that the generation of the object is 0, which GCS evaluates
+// atomically, so the fencing of writers and the updates of the manifest are
+// compare-and-set operations.
+package gcsstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// Options configures the ObjectStore
+type Options struct {
+	// The size of the chunks of a resumable upload, each of which is buffered in
+	// memory. Objects smaller than the chunk size are uploaded with a single
+	// request. Zero uses the default of the client library, 16 MiB.
+	ChunkSize int
+}
+
+// ObjectStore is a store.ObjectStore which reads and writes the objects of a GCS
+// bucket, at the names of the paths of the objects
+type ObjectStore struct {
+	bucket *storage.BucketHandle
+	opts   Options
+}
+
+var _ store.ObjectStore = (*ObjectStore)(nil)
+
+// NewObjectStore returns an ObjectStore which reads and writes the objects of
+// the bucket through the client. The client authenticates with Application
+// Default Credentials unless it was created with other options, and honours
+// STORAGE_EMULATOR_HOST.
+func NewObjectStore(client *storage.Client, bucket string, opts Options) *ObjectStore {
+	return &ObjectStore{bucket: client.Bucket(bucket), opts: opts}
+}
+
+func (s *ObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	return s.put(ctx, s.bucket.Object(path), r)
+}
+
+func (s *ObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	obj := s.bucket.Object(path).If(storage.Conditions{DoesNotExist: true})
+	err := s.put(ctx, obj, r)
+	if statusCode(err) == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: %w", common.ErrObjectExists, err)
+	}
+	return err
+}
+
+func (s *ObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	r, err := s.bucket.Object(path).NewReader(ctx)
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return r, nil
+}
+
+// GetRange returns length bytes of the object from the offset off, or the rest
+// of the object if length is negative
+func (s *ObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	r, err := s.bucket.Object(path).NewRangeReader(ctx, off, length)
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return r, nil
+}
+
+func (s *ObjectStore) Head(ctx context.Context, path string) (store.ObjectMeta, error) {
+	attrs, err := s.bucket.Object(path).Attrs(ctx)
+	if err != nil {
+		return store.ObjectMeta{}, wrapNotFound(err)
+	}
+	return store.ObjectMeta{LastModified: attrs.Updated, Location: path, Size: attrs.Size}, nil
+}
+
+// List returns the metadata of every object under the prefix directory, along
+// with the time each object was last modified and its size
+func (s *ObjectStore) List(ctx context.Context, prefix string) ([]store.ObjectMeta, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	query := &storage.Query{Prefix: prefix}
+	if err := query.SetAttrSelection([]string{"Name", "Updated", "Size"}); err != nil {
+		return nil, err
+	}
+
+	objMetaList := make([]store.ObjectMeta, 0)
+	it := s.bucket.Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		objMetaList = append(objMetaList, store.ObjectMeta{
+			LastModified: attrs.Updated,
+			Location:     attrs.Name,
+			Size:         attrs.Size,
+		})
+	}
+	return objMetaList, nil
+}
+
+func (s *ObjectStore) Delete(ctx context.Context, path string) error {
+	return s.bucket.Object(path).Delete(ctx)
+}
+
+// IsThrottled returns true if GCS rejected the request with a 429 or 503
+// response, as it does when the request rate exceeds the rate of the bucket.
+// The DB uses it to detect throttling unless DBOptions.IsThrottled is set.
+func (s *ObjectStore) IsThrottled(err error) bool {
+	code := statusCode(err)
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// put uploads the object. The object is only created once the upload completes,
+// an upload which fails is canceled so no partial object is ever visible.
+func (s *ObjectStore) put(ctx context.Context, obj *storage.ObjectHandle, r io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := obj.NewWriter(ctx)
+	if s.opts.ChunkSize > 0 {
+		w.ChunkSize = s.opts.ChunkSize
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		// Canceling the context aborts the upload
+		cancel()
+		_ = w.Close()
+		return fmt.Errorf("while uploading object: %w", err)
+	}
+	return w.Close()
+}
+
+// wrapNotFound wraps err with common.ErrObjectNotFound if the object does not exist
+func wrapNotFound(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %w", common.ErrObjectNotFound, err)
+	}
+	return err
+}
+
+// statusCode returns the HTTP status code of the response which failed with err,
+// or zero if there was none
+func statusCode(err error) int {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
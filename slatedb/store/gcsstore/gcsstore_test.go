@@ -0,0 +1,74 @@
+package gcsstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	"github.com/slatedb/slatedb-go/slatedb"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/store/storetest"
+)
+
+// testObjectStore returns an ObjectStore of a bucket of the GCS emulator at
+// STORAGE_EMULATOR_HOST, such as fake-gcs-server started by make test_gcs, and
+// skips the test if it is not set. Each test writes under a prefix of its own.
+func testObjectStore(t *testing.T) (*ObjectStore, string) {
+	if os.Getenv("STORAGE_EMULATOR_HOST") == "" {
+		t.Skip("STORAGE_EMULATOR_HOST is not set")
+	}
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx, option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	bucket := "slatedb-test"
+	if v := os.Getenv("SLATEDB_GCS_BUCKET"); v != "" {
+		bucket = v
+	}
+	err = client.Bucket(bucket).Create(ctx, "test-project", nil)
+	if statusCode(err) != http.StatusConflict {
+		require.NoError(t, err)
+	}
+	return NewObjectStore(client, bucket, Options{}), fmt.Sprintf("%s/%d", t.Name(), time.Now().UnixNano())
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, http.StatusPreconditionFailed, statusCode(fmt.Errorf("wrapped: %w", &googleapi.Error{Code: http.StatusPreconditionFailed})))
+	assert.Equal(t, 0, statusCode(errors.New("no response")))
+}
+
+func TestObjectStore(t *testing.T) {
+	objectStore, prefix := testObjectStore(t)
+	storetest.TestObjectStore(t, objectStore, prefix)
+}
+
+func TestOpenDB(t *testing.T) {
+	objectStore, prefix := testObjectStore(t)
+	ctx := context.Background()
+	options := config.DefaultDBOptions()
+	options.DisableBackgroundTasks = true
+
+	db, err := slatedb.OpenWithObjectStore(ctx, prefix, objectStore, options)
+	require.NoError(t, err)
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: true}))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Close())
+
+	db, err = slatedb.OpenWithObjectStore(ctx, prefix, objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// heartbeatPath is the path of the heartbeat object relative to the root of the DB
+const heartbeatPath = "heartbeat"
+
+// heartbeatSize is the size of an encoded Heartbeat. Heartbeats published before
+// the writer epoch was recorded are common.SizeOfUint64 bytes smaller.
+const heartbeatSize = 4*common.SizeOfUint64 + 1
+
+// Heartbeat is published periodically by the writer of a DB, so that replicas and
+// operators can detect a writer which died or was partitioned from object storage
+// before it closed the DB. See DBOptions.HeartbeatInterval.
+type Heartbeat struct {
+	// Time is the time the heartbeat was published. It is the time the object
+	// store last modified the heartbeat, so it is not affected by the clock of the
+	// writer, unless the object store doesn't record it.
+	Time time.Time
+
+	// CommittedSeq is the sequence number of the most recent write which was
+	// durable in the WAL when the heartbeat was published
+	CommittedSeq uint64
+
+	// NextWALID is the ID of the next WAL SSTable the writer writes
+	NextWALID uint64
+
+	// Closed is true if the writer closed the DB, after which it publishes no
+	// further heartbeats
+	Closed bool
+
+	// WriterEpoch is the epoch of the writer which published the heartbeat, or
+	// zero if it was published by a writer which did not record it
+	WriterEpoch uint64
+}
+
+func (h Heartbeat) encode() []byte {
+	buf := make([]byte, 0, heartbeatSize)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(h.Time.UnixNano()))
+	buf = binary.BigEndian.AppendUint64(buf, h.CommittedSeq)
+	buf = binary.BigEndian.AppendUint64(buf, h.NextWALID)
+	closed := byte(0)
+	if h.Closed {
+		closed = 1
+	}
+	buf = append(buf, closed)
+	return binary.BigEndian.AppendUint64(buf, h.WriterEpoch)
+}
+
+func (h *Heartbeat) decode(buf []byte) error {
+	if len(buf) < heartbeatSize-common.SizeOfUint64 {
+		return fmt.Errorf("invalid heartbeat of %d bytes", len(buf))
+	}
+	h.Time = time.Unix(0, int64(binary.BigEndian.Uint64(buf)))
+	h.CommittedSeq = binary.BigEndian.Uint64(buf[common.SizeOfUint64:])
+	h.NextWALID = binary.BigEndian.Uint64(buf[2*common.SizeOfUint64:])
+	h.Closed = buf[3*common.SizeOfUint64] == 1
+	if len(buf) >= heartbeatSize {
+		h.WriterEpoch = binary.BigEndian.Uint64(buf[3*common.SizeOfUint64+1:])
+	}
+	return nil
+}
+
+// WriteHeartbeat publishes the heartbeat, replacing the previous heartbeat
+func (s *ManifestStore) WriteHeartbeat(heartbeat Heartbeat) error {
+	if err := s.objectStore.put(heartbeatPath, heartbeat.encode()); err != nil {
+		return fmt.Errorf("while writing heartbeat: %w", err)
+	}
+	return nil
+}
+
+// ReadHeartbeat returns the last heartbeat published by the writer of the DB, and
+// false if the writer never published one
+func (s *ManifestStore) ReadHeartbeat() (Heartbeat, bool, error) {
+	meta, err := s.objectStore.head(heartbeatPath)
+	if errors.Is(err, common.ErrObjectNotFound) {
+		return Heartbeat{}, false, nil
+	}
+	if err != nil {
+		return Heartbeat{}, false, fmt.Errorf("while reading heartbeat: %w", err)
+	}
+	data, err := s.objectStore.get(heartbeatPath)
+	if err != nil {
+		return Heartbeat{}, false, fmt.Errorf("while reading heartbeat: %w", err)
+	}
+
+	var heartbeat Heartbeat
+	if err := heartbeat.decode(data); err != nil {
+		return Heartbeat{}, false, err
+	}
+	if !meta.LastModified.IsZero() {
+		heartbeat.Time = meta.LastModified
+	}
+	return heartbeat, true, nil
+}
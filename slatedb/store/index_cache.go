@@ -0,0 +1,139 @@
+package store
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/maypok86/otter"
+
+	"github.com/slatedb/slatedb-go/internal/assert"
+	"github.com/slatedb/slatedb-go/internal/sstable"
+)
+
+// topLevelIndex is the partition of the indexKey of the index read from the
+// SSTable info, which is the flat index or the top level of a partitioned index
+const topLevelIndex = -1
+
+// blockMetaOverhead is the approximate size of the decoded metadata of a block
+// held by an sstable.Index in addition to its first key
+const blockMetaOverhead = 48
+
+// indexKey identifies an index by the SSTable and the partition of the index,
+// topLevelIndex for the index read from the SSTable info
+type indexKey struct {
+	id        sstable.ID
+	partition int
+}
+
+// indexSize returns the number of bytes the index is charged against the capacity
+// of the cache, which includes the decoded block metadata
+func indexSize(index *sstable.Index) uint32 {
+	size := len(index.Data)
+	for _, meta := range index.BlockMeta() {
+		size += len(meta.FirstKey) + blockMetaOverhead
+	}
+	return uint32(min(size, math.MaxUint32))
+}
+
+// IndexCacheStats are the counters of the index cache, see DBOptions.IndexCacheBytes
+type IndexCacheStats struct {
+	// Hits is the number of indexes and index partitions served from memory
+	Hits int64
+	// Misses is the number of indexes and index partitions read from object
+	// storage, including PartitionsPaged
+	Misses int64
+	// PartitionsPaged is the number of index partitions read from object storage.
+	// A steadily growing count means the partitions read by the workload don't
+	// fit in the cache and are paged in and evicted again.
+	PartitionsPaged int64
+}
+
+// indexCacheCounters holds the live counters which back IndexCacheStats, and is
+// shared with the clones of the TableStore
+type indexCacheCounters struct {
+	hits            atomic.Int64
+	misses          atomic.Int64
+	partitionsPaged atomic.Int64
+}
+
+// indexCache caches the indexes and index partitions read by the TableStore, so
+// the memory held by indexes is bounded by its capacity however many SSTables
+// the DB has. When the indexes don't fit, the partitions of partitioned indexes
+// are paged in on demand and the least recently used indexes are evicted. A nil
+// indexCache caches nothing, every read fetches the index from object storage.
+type indexCache struct {
+	cache    otter.Cache[indexKey, *sstable.Index]
+	capacity uint64
+	counters *indexCacheCounters
+}
+
+// newIndexCache returns an indexCache which holds up to capacity bytes of
+// indexes, or nil if capacity is zero
+func newIndexCache(capacity uint64, counters *indexCacheCounters) *indexCache {
+	if capacity == 0 {
+		return nil
+	}
+	cache, err := otter.MustBuilder[indexKey, *sstable.Index](int(min(capacity, math.MaxInt))).
+		Cost(func(_ indexKey, index *sstable.Index) uint32 { return indexSize(index) }).
+		Build()
+	assert.True(err == nil, "")
+	return &indexCache{cache: cache, capacity: capacity, counters: counters}
+}
+
+// clone returns an empty indexCache with the same capacity, which shares the counters
+func (c *indexCache) clone() *indexCache {
+	if c == nil {
+		return nil
+	}
+	return newIndexCache(c.capacity, c.counters)
+}
+
+// get returns the cached partition of the index of the SSTable
+func (c *indexCache) get(id sstable.ID, partition int) (*sstable.Index, bool) {
+	if c == nil {
+		return nil, false
+	}
+	index, ok := c.cache.Get(indexKey{id: id, partition: partition})
+	if ok {
+		c.counters.hits.Add(1)
+	}
+	return index, ok
+}
+
+// set inserts the partition of the index of the SSTable which was read from
+// object storage. An index larger than the capacity is not cached.
+func (c *indexCache) set(id sstable.ID, partition int, index *sstable.Index) {
+	if c == nil {
+		return
+	}
+	c.counters.misses.Add(1)
+	if partition != topLevelIndex {
+		c.counters.partitionsPaged.Add(1)
+	}
+	// The block metadata is decoded before the index is shared, as it is decoded
+	// lazily by the first reader otherwise
+	index.BlockMeta()
+	c.cache.Set(indexKey{id: id, partition: partition}, index)
+}
+
+// deleteSST removes the indexes of the SSTable from the cache
+func (c *indexCache) deleteSST(id sstable.ID) {
+	if c == nil {
+		return
+	}
+	c.cache.DeleteByFunc(func(key indexKey, _ *sstable.Index) bool {
+		return key.id == id
+	})
+}
+
+// stats returns the counters of the cache, which are zero if the cache is disabled
+func (c *indexCache) stats() IndexCacheStats {
+	if c == nil {
+		return IndexCacheStats{}
+	}
+	return IndexCacheStats{
+		Hits:            c.counters.hits.Load(),
+		Misses:          c.counters.misses.Load(),
+		PartitionsPaged: c.counters.partitionsPaged.Load(),
+	}
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/sstable/block"
+	"github.com/slatedb/slatedb-go/internal/types"
+)
+
+// writePartitionedSST writes an SSTable of one key per block, whose index is
+// partitioned into partitions of 4 blocks
+func writePartitionedSST(t testing.TB, tableStore *TableStore) *sstable.Handle {
+	builder := tableStore.TableBuilder()
+	for i := 0; i < 40; i++ {
+		require.NoError(t, builder.AddValue([]byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i))))
+	}
+	table, err := builder.Build()
+	require.NoError(t, err)
+	handle, err := tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), table)
+	require.NoError(t, err)
+	require.True(t, handle.Info.IndexPartitioned)
+	return handle
+}
+
+func partitionedIndexConfig() sstable.Config {
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = block.EstimateBlockSize([]types.KeyValue{
+		{Key: []byte("key000"), Value: []byte("value000")},
+	})
+	conf.IndexPartitionThreshold = 4
+	return conf
+}
+
+// readAll reads every entry of the SSTable, which reads every index partition
+func readAll(t testing.TB, handle *sstable.Handle, tableStore *TableStore) {
+	iter, err := sstable.NewIterator(context.Background(), handle, tableStore)
+	require.NoError(t, err)
+	count := 0
+	for {
+		_, ok := iter.NextEntry(context.Background())
+		if !ok {
+			break
+		}
+		count++
+	}
+	assert.Equal(t, 40, count)
+}
+
+func TestIndexCache(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	tableStore := NewTableStore(bucket, partitionedIndexConfig(), "")
+	tableStore.SetIndexCache(1024 * 1024)
+	handle := writePartitionedSST(t, tableStore)
+
+	readAll(t, handle, tableStore)
+	stats := tableStore.IndexCacheStats()
+	assert.Equal(t, int64(0), stats.Hits)
+	assert.Equal(t, int64(10), stats.PartitionsPaged)
+	assert.Equal(t, int64(11), stats.Misses)
+
+	// Every index partition fits in the cache, so only the blocks are fetched
+	readAll(t, handle, tableStore.WithIOClass(IOClassGet))
+	stats = tableStore.IndexCacheStats()
+	assert.Equal(t, int64(11), stats.Hits)
+	assert.Equal(t, int64(11), stats.Misses)
+	assert.Equal(t, int64(40), tableStore.IOStats().Snapshot()[IOClassGet].Requests)
+
+	// Deleting the SSTable evicts its indexes
+	require.NoError(t, tableStore.DeleteSST(context.Background(), handle.Id))
+	_, ok := tableStore.indexCache.get(handle.Id, topLevelIndex)
+	assert.False(t, ok)
+}
+
+func TestIndexCachePagesPartitions(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	tableStore := NewTableStore(bucket, partitionedIndexConfig(), "")
+	handle := writePartitionedSST(t, tableStore)
+
+	// The cache holds the top level index and two partitions, far less than the
+	// index of the SSTable
+	topLevel, err := tableStore.ReadIndex(context.Background(), handle)
+	require.NoError(t, err)
+	partition, err := tableStore.ReadIndexPartition(context.Background(), handle, topLevel, 0)
+	require.NoError(t, err)
+	tableStore.SetIndexCache(uint64(indexSize(topLevel) + 2*indexSize(partition)))
+
+	readAll(t, handle, tableStore)
+	readAll(t, handle, tableStore)
+	// The partitions are paged in again by the second read, the top level index
+	// is read twice whether or not it remained in the cache
+	stats := tableStore.IndexCacheStats()
+	assert.Equal(t, int64(20), stats.PartitionsPaged)
+	assert.Equal(t, int64(2), stats.Hits+stats.Misses-stats.PartitionsPaged)
+}
+
+func TestIndexCacheDisabled(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	tableStore := NewTableStore(bucket, partitionedIndexConfig(), "")
+	tableStore.SetIndexCache(0)
+	handle := writePartitionedSST(t, tableStore)
+
+	readAll(t, handle, tableStore)
+	assert.Nil(t, tableStore.indexCache)
+	assert.Equal(t, IndexCacheStats{}, tableStore.IndexCacheStats())
+}
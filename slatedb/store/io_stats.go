@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// IOClass is the class of operation which an object store request is attributed to
+type IOClass int
+
+const (
+	// IOClassOther is every request which is not attributed to one of the classes
+	// below, such as opening the DB or ingesting SSTables
+	IOClassOther IOClass = iota
+	// IOClassGet is reading SSTables to serve DB.Get
+	IOClassGet
+	// IOClassScan is reading SSTables to serve DB.Scan
+	IOClassScan
+	// IOClassFlush is writing WAL and L0 SSTables
+	IOClassFlush
+	// IOClassCompaction is reading and writing SSTables during compaction
+	IOClassCompaction
+	// IOClassGC is deleting objects which are no longer needed, such as pruned
+	// manifest versions
+	IOClassGC
+	// IOClassRecovery is reading WAL SSTables to replay them when the DB is opened
+	IOClassRecovery
+	// IOClassManifest is reading and writing manifest versions and pins
+	IOClassManifest
+
+	ioClassCount
+)
+
+func (c IOClass) String() string {
+	switch c {
+	case IOClassGet:
+		return "get"
+	case IOClassScan:
+		return "scan"
+	case IOClassFlush:
+		return "flush"
+	case IOClassCompaction:
+		return "compaction"
+	case IOClassGC:
+		return "gc"
+	case IOClassRecovery:
+		return "recovery"
+	case IOClassManifest:
+		return "manifest"
+	default:
+		return "other"
+	}
+}
+
+// IOClassStats is the number of object store requests and bytes of an IOClass
+type IOClassStats struct {
+	Requests     int64
+	BytesRead    int64
+	BytesWritten int64
+}
+
+// Add returns the sum of both stats
+func (s IOClassStats) Add(rhs IOClassStats) IOClassStats {
+	return IOClassStats{
+		Requests:     s.Requests + rhs.Requests,
+		BytesRead:    s.BytesRead + rhs.BytesRead,
+		BytesWritten: s.BytesWritten + rhs.BytesWritten,
+	}
+}
+
+// IOStats counts the object store requests and bytes of each IOClass
+type IOStats struct {
+	classes [ioClassCount]ioCounters
+}
+
+type ioCounters struct {
+	requests     atomic.Int64
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+}
+
+// Snapshot returns the stats of every IOClass which made at least one request
+func (s *IOStats) Snapshot() map[IOClass]IOClassStats {
+	stats := make(map[IOClass]IOClassStats)
+	for class := range s.classes {
+		c := &s.classes[class]
+		if c.requests.Load() == 0 {
+			continue
+		}
+		stats[IOClass(class)] = IOClassStats{
+			Requests:     c.requests.Load(),
+			BytesRead:    c.bytesRead.Load(),
+			BytesWritten: c.bytesWritten.Load(),
+		}
+	}
+	return stats
+}
+
+// ioObjectStore attributes every request made through the ObjectStore to an IOClass
+type ioObjectStore struct {
+	ObjectStore
+	counters *ioCounters
+}
+
+// newIOObjectStore returns an ObjectStore which attributes its requests to the
+// IOClass. An ObjectStore which is already attributed to an IOClass is attributed
+// to the new one.
+func newIOObjectStore(store ObjectStore, stats *IOStats, class IOClass) ObjectStore {
+	if s, ok := store.(*ioObjectStore); ok {
+		store = s.ObjectStore
+	}
+	return &ioObjectStore{ObjectStore: store, counters: &stats.classes[class]}
+}
+
+func (s *ioObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	s.counters.requests.Add(1)
+	return s.ObjectStore.Put(ctx, path, countWritten(r, &s.counters.bytesWritten))
+}
+
+func (s *ioObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	s.counters.requests.Add(1)
+	return s.ObjectStore.PutIfNotExists(ctx, path, countWritten(r, &s.counters.bytesWritten))
+}
+
+func (s *ioObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	s.counters.requests.Add(1)
+	r, err := s.ObjectStore.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return countingReadCloser{ReadCloser: r, count: &s.counters.bytesRead}, nil
+}
+
+func (s *ioObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	s.counters.requests.Add(1)
+	r, err := s.ObjectStore.GetRange(ctx, path, off, length)
+	if err != nil {
+		return nil, err
+	}
+	return countingReadCloser{ReadCloser: r, count: &s.counters.bytesRead}, nil
+}
+
+func (s *ioObjectStore) Head(ctx context.Context, path string) (ObjectMeta, error) {
+	s.counters.requests.Add(1)
+	return s.ObjectStore.Head(ctx, path)
+}
+
+func (s *ioObjectStore) List(ctx context.Context, prefix string) ([]ObjectMeta, error) {
+	s.counters.requests.Add(1)
+	return s.ObjectStore.List(ctx, prefix)
+}
+
+func (s *ioObjectStore) Delete(ctx context.Context, path string) error {
+	s.counters.requests.Add(1)
+	return s.ObjectStore.Delete(ctx, path)
+}
+
+// countingReader adds the number of bytes read to count
+type countingReader struct {
+	io.Reader
+	count *atomic.Int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
+
+// countingReadSeeker is a countingReader of a body which can be rewound, such
+// that writes of the body which fail can be retried
+type countingReadSeeker struct {
+	countingReader
+	seeker io.Seeker
+}
+
+func (r countingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
+
+// countWritten returns the body of a write which adds the number of bytes read
+// to count, and which can be rewound if the body can
+func countWritten(r io.Reader, count *atomic.Int64) io.Reader {
+	counting := countingReader{Reader: r, count: count}
+	if seeker, ok := r.(io.Seeker); ok {
+		return countingReadSeeker{countingReader: counting, seeker: seeker}
+	}
+	return counting
+}
+
+// countingReadCloser adds the number of bytes read to count
+type countingReadCloser struct {
+	io.ReadCloser
+	count *atomic.Int64
+}
+
+func (r countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.count.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/kapetan-io/tackle/set"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// LayoutVersion is the version of the layout of the objects of a DB, which is
+// recorded in the layout object along with the prefixes of the layout
+const LayoutVersion = 1
+
+// layoutPath is the path of the layout object relative to the root of the DB
+const layoutPath = "layout"
+
+// layoutObject is the layout recorded in the layout object of a DB
+type layoutObject struct {
+	Version   int    `json:"version"`
+	WAL       string `json:"wal"`
+	Compacted string `json:"compacted"`
+	Manifest  string `json:"manifest"`
+}
+
+func newLayoutObject(layout config.ObjectStoreLayout) layoutObject {
+	return layoutObject{
+		Version:   LayoutVersion,
+		WAL:       layout.WALPrefix,
+		Compacted: layout.CompactedPrefix,
+		Manifest:  layout.ManifestPrefix,
+	}
+}
+
+// DefaultLayout returns the layout of a DB whose prefixes are not configured,
+// which is also the layout of the DBs created before layouts were recorded
+func DefaultLayout() config.ObjectStoreLayout {
+	return config.ObjectStoreLayout{
+		WALPrefix:       "wal",
+		CompactedPrefix: "compacted",
+		ManifestPrefix:  "manifest",
+	}
+}
+
+// ResolveLayout returns the layout with its empty prefixes defaulted and every
+// prefix cleaned, or common.ErrInvalidOptions if a prefix leaves the path of the
+// DB or contains another prefix
+func ResolveLayout(layout config.ObjectStoreLayout) (config.ObjectStoreLayout, error) {
+	defaults := DefaultLayout()
+	set.Default(&layout.WALPrefix, defaults.WALPrefix)
+	set.Default(&layout.CompactedPrefix, defaults.CompactedPrefix)
+	set.Default(&layout.ManifestPrefix, defaults.ManifestPrefix)
+
+	prefixes := []*string{&layout.WALPrefix, &layout.CompactedPrefix, &layout.ManifestPrefix}
+	for _, prefix := range prefixes {
+		cleaned := strings.Trim(path.Clean("/"+*prefix), "/")
+		if cleaned == "" || cleaned == layoutPath || cleaned == heartbeatPath {
+			return layout, fmt.Errorf("%w: invalid object store layout prefix '%s'", common.ErrInvalidOptions, *prefix)
+		}
+		*prefix = cleaned
+	}
+	for i, a := range prefixes {
+		for _, b := range prefixes[i+1:] {
+			if *a == *b || strings.HasPrefix(*a, *b+"/") || strings.HasPrefix(*b, *a+"/") {
+				return layout, fmt.Errorf("%w: object store layout prefixes '%s' and '%s' overlap",
+					common.ErrInvalidOptions, *a, *b)
+			}
+		}
+	}
+	return layout, nil
+}
+
+// ClaimLayout validates that the objects of the DB at rootPath are laid out with
+// the resolved layout, and records the layout if the DB has none recorded. A DB
+// with no recorded layout but with manifests under the default prefix was created
+// before layouts were recorded, and has the default layout. Returns
+// common.ErrIncompatibleLayout if the DB is laid out differently.
+func ClaimLayout(ctx context.Context, objectStore ObjectStore, rootPath string, layout config.ObjectStoreLayout) error {
+	want := newLayoutObject(layout)
+	encoded, err := json.Marshal(want)
+	if err != nil {
+		return err
+	}
+
+	for {
+		got, ok, err := readLayout(ctx, objectStore, rootPath)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return checkLayout(rootPath, got, want)
+		}
+
+		existing, err := objectStore.List(ctx, path.Join(rootPath, DefaultLayout().ManifestPrefix))
+		if err != nil {
+			return fmt.Errorf("while listing manifests: %w", err)
+		}
+		if len(existing) > 0 {
+			if err := checkLayout(rootPath, newLayoutObject(DefaultLayout()), want); err != nil {
+				return err
+			}
+		}
+
+		err = objectStore.PutIfNotExists(ctx, path.Join(rootPath, layoutPath), bytes.NewReader(encoded))
+		if errors.Is(err, common.ErrObjectExists) {
+			// Another client recorded a layout meanwhile, which is validated
+			continue
+		}
+		return err
+	}
+}
+
+// LoadLayout returns the layout recorded for the DB at rootPath, or the default
+// layout if the DB has none recorded
+func LoadLayout(ctx context.Context, objectStore ObjectStore, rootPath string) (config.ObjectStoreLayout, error) {
+	got, ok, err := readLayout(ctx, objectStore, rootPath)
+	if err != nil || !ok {
+		return DefaultLayout(), err
+	}
+	if got.Version != LayoutVersion {
+		return config.ObjectStoreLayout{}, fmt.Errorf("%w: '%s' has layout version %d, expected %d",
+			common.ErrIncompatibleLayout, rootPath, got.Version, LayoutVersion)
+	}
+	return config.ObjectStoreLayout{WALPrefix: got.WAL, CompactedPrefix: got.Compacted, ManifestPrefix: got.Manifest}, nil
+}
+
+// readLayout reads the layout object of the DB at rootPath, and returns false if
+// there is none
+func readLayout(ctx context.Context, objectStore ObjectStore, rootPath string) (layoutObject, bool, error) {
+	r, err := objectStore.Get(ctx, path.Join(rootPath, layoutPath))
+	if errors.Is(err, common.ErrObjectNotFound) {
+		return layoutObject{}, false, nil
+	}
+	if err != nil {
+		return layoutObject{}, false, fmt.Errorf("while reading layout: %w", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return layoutObject{}, false, fmt.Errorf("while reading layout: %w", err)
+	}
+
+	var layout layoutObject
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return layoutObject{}, false, fmt.Errorf("%w: invalid layout of '%s': %w", common.ErrIncompatibleLayout, rootPath, err)
+	}
+	return layout, true, nil
+}
+
+// checkLayout returns common.ErrIncompatibleLayout unless the DB at rootPath laid
+// out as got can be opened with the layout want
+func checkLayout(rootPath string, got layoutObject, want layoutObject) error {
+	if got.Version != want.Version {
+		return fmt.Errorf("%w: '%s' has layout version %d, expected %d",
+			common.ErrIncompatibleLayout, rootPath, got.Version, want.Version)
+	}
+	if got != want {
+		return fmt.Errorf("%w: '%s' is laid out with prefixes wal '%s', compacted '%s' and manifest '%s'",
+			common.ErrIncompatibleLayout, rootPath, got.WAL, got.Compacted, got.Manifest)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestResolveLayout(t *testing.T) {
+	layout, err := ResolveLayout(config.ObjectStoreLayout{WALPrefix: "/db/wal/", ManifestPrefix: "meta//data"})
+	require.NoError(t, err)
+	assert.Equal(t, config.ObjectStoreLayout{
+		WALPrefix:       "db/wal",
+		CompactedPrefix: "compacted",
+		ManifestPrefix:  "meta/data",
+	}, layout)
+
+	for _, layout := range []config.ObjectStoreLayout{
+		{WALPrefix: "/"},
+		{WALPrefix: "layout"},
+		{WALPrefix: "data", CompactedPrefix: "data"},
+		{WALPrefix: "data", CompactedPrefix: "data/compacted"},
+		{ManifestPrefix: "wal/manifest"},
+	} {
+		_, err := ResolveLayout(layout)
+		assert.ErrorIs(t, err, common.ErrInvalidOptions, layout)
+	}
+}
+
+func TestClaimLayout(t *testing.T) {
+	ctx := context.Background()
+	objectStore := NewBucketObjectStore(objstore.NewInMemBucket())
+	custom, err := ResolveLayout(config.ObjectStoreLayout{WALPrefix: "data/wal", CompactedPrefix: "data/compacted"})
+	require.NoError(t, err)
+
+	// The first claim records the layout, which later claims must match
+	require.NoError(t, ClaimLayout(ctx, objectStore, "db", custom))
+	require.NoError(t, ClaimLayout(ctx, objectStore, "db", custom))
+	assert.ErrorIs(t, ClaimLayout(ctx, objectStore, "db", DefaultLayout()), common.ErrIncompatibleLayout)
+	loaded, err := LoadLayout(ctx, objectStore, "db")
+	require.NoError(t, err)
+	assert.Equal(t, custom, loaded)
+
+	// A DB without a recorded layout has the default layout if it has manifests
+	require.NoError(t, objectStore.Put(ctx, "legacy/manifest/00000000000000000001.manifest", strings.NewReader("")))
+	loaded, err = LoadLayout(ctx, objectStore, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLayout(), loaded)
+	assert.ErrorIs(t, ClaimLayout(ctx, objectStore, "legacy", custom), common.ErrIncompatibleLayout)
+	require.NoError(t, ClaimLayout(ctx, objectStore, "legacy", DefaultLayout()))
+
+	// A layout of another version is incompatible
+	require.NoError(t, objectStore.Put(ctx, "future/layout",
+		strings.NewReader(`{"version":2,"wal":"wal","compacted":"compacted","manifest":"manifest"}`)))
+	assert.ErrorIs(t, ClaimLayout(ctx, objectStore, "future", DefaultLayout()), common.ErrIncompatibleLayout)
+	_, err = LoadLayout(ctx, objectStore, "future")
+	assert.ErrorIs(t, err, common.ErrIncompatibleLayout)
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"bytes"
 	"cmp"
 	"errors"
 	"fmt"
@@ -11,16 +12,16 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/oklog/ulid/v2"
 	"github.com/samber/mo"
 	"github.com/thanos-io/objstore"
 
 	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
 	"github.com/slatedb/slatedb-go/slatedb/manifest"
 	"github.com/slatedb/slatedb-go/slatedb/state"
 )
 
-const manifestDir = "manifest"
-
 type EpochType int
 
 const (
@@ -34,11 +35,13 @@ const (
 
 // FenceableManifest wraps StoredManifest, and fences other conflicting writers by incrementing
 // the relevant epoch when initialized. It also detects when the current writer has been
-// fenced and fails all operations with error ErrFenced.
+// fenced and fails all operations with a manifest.FencedError, which wraps ErrFenced.
 type FenceableManifest struct {
 	storedManifest *StoredManifest
 	localEpoch     atomic.Uint64
 	epochType      EpochType
+	// The identity of the writer, absent for compactors
+	localWriter mo.Option[manifest.WriterInfo]
 }
 
 func NewWriterFenceableManifest(storedManifest *StoredManifest) (*FenceableManifest, error) {
@@ -52,6 +55,7 @@ func NewWriterFenceableManifest(storedManifest *StoredManifest) (*FenceableManif
 	fm := &FenceableManifest{
 		storedManifest: storedManifest,
 		epochType:      WriterEpoch,
+		localWriter:    manifest.Writer,
 	}
 	fm.localEpoch.Store(manifest.WriterEpoch.Load())
 	return fm, nil
@@ -73,6 +77,11 @@ func NewCompactorFenceableManifest(storedManifest *StoredManifest) (*FenceableMa
 	return fm, nil
 }
 
+// Epoch returns the epoch this client fenced the previous writer or compactor with
+func (f *FenceableManifest) Epoch() uint64 {
+	return f.localEpoch.Load()
+}
+
 func (f *FenceableManifest) DbState() (*state.CoreStateSnapshot, error) {
 	err := f.checkEpoch()
 	if err != nil {
@@ -97,19 +106,48 @@ func (f *FenceableManifest) Refresh() (*state.CoreStateSnapshot, error) {
 	return f.DbState()
 }
 
-func (f *FenceableManifest) storedEpoch() uint64 {
+// CheckFenced reads the latest manifest and returns a manifest.FencedError if a
+// newer client fenced this one. Unlike Refresh, the local manifest is not updated.
+func (f *FenceableManifest) CheckFenced() error {
+	stored, err := f.storedManifest.manifestStore.readLatestManifest()
+	if err != nil {
+		return err
+	}
+	storedInfo, ok := stored.Get()
+	if !ok {
+		return common.ErrInvalidDBState
+	}
+	return f.checkEpochOf(storedInfo.manifest)
+}
+
+func (f *FenceableManifest) storedEpoch(m *manifest.Manifest) uint64 {
 	if f.epochType == WriterEpoch {
-		return f.storedManifest.manifest.WriterEpoch.Load()
+		return m.WriterEpoch.Load()
 	} else {
-		return f.storedManifest.manifest.CompactorEpoch.Load()
+		return m.CompactorEpoch.Load()
 	}
 }
 
 func (f *FenceableManifest) checkEpoch() error {
-	if f.localEpoch.Load() < f.storedEpoch() {
-		return common.ErrFenced
+	return f.checkEpochOf(f.storedManifest.manifest)
+}
+
+func (f *FenceableManifest) checkEpochOf(m *manifest.Manifest) error {
+	storedEpoch := f.storedEpoch(m)
+	if f.localEpoch.Load() < storedEpoch {
+		err := &manifest.FencedError{
+			Role:         "compactor",
+			LocalEpoch:   f.localEpoch.Load(),
+			CurrentEpoch: storedEpoch,
+		}
+		if f.epochType == WriterEpoch {
+			err.Role = "writer"
+			err.LocalWriter = f.localWriter
+			err.CurrentWriter = m.Writer
+		}
+		return err
 	}
-	if f.localEpoch.Load() > f.storedEpoch() {
+	if f.localEpoch.Load() > storedEpoch {
 		panic("the stored epoch is lower than the local epoch")
 	}
 	return nil
@@ -169,6 +207,34 @@ func (s *StoredManifest) DbState() *state.CoreStateSnapshot {
 	return s.manifest.Core.Snapshot()
 }
 
+// FormatOptions returns the format options persisted in the manifest, if any.
+func (s *StoredManifest) FormatOptions() mo.Option[manifest.FormatOptions] {
+	return s.manifest.FormatOptions
+}
+
+// SetFormatOptions replaces the format options of the local manifest. The new
+// options are persisted with the next manifest update.
+func (s *StoredManifest) SetFormatOptions(opts manifest.FormatOptions) {
+	s.manifest.FormatOptions = mo.Some(opts)
+}
+
+// WriterEpoch returns the epoch of the writer of the manifest
+func (s *StoredManifest) WriterEpoch() uint64 {
+	return s.manifest.WriterEpoch.Load()
+}
+
+// Writer returns the identity of the writer recorded in the manifest, if any.
+func (s *StoredManifest) Writer() mo.Option[manifest.WriterInfo] {
+	return s.manifest.Writer
+}
+
+// SetWriter replaces the identity of the writer in the local manifest. The new
+// identity is persisted with the next manifest update, which for a writer is
+// the update fencing the previous writer.
+func (s *StoredManifest) SetWriter(info manifest.WriterInfo) {
+	s.manifest.Writer = mo.Some(info)
+}
+
 // write Manifest with updated DB state to object store and update StoredManifest with the new manifest
 func (s *StoredManifest) updateDBState(coreSnapshot *state.CoreStateSnapshot) error {
 	manifest := &manifest.Manifest{
@@ -176,13 +242,29 @@ func (s *StoredManifest) updateDBState(coreSnapshot *state.CoreStateSnapshot) er
 	}
 	manifest.WriterEpoch.Store(s.manifest.WriterEpoch.Load())
 	manifest.CompactorEpoch.Store(s.manifest.CompactorEpoch.Load())
+	manifest.FormatOptions = s.manifest.FormatOptions
+	manifest.Writer = s.manifest.Writer
 	return s.updateManifest(manifest)
 }
 
-// write given manifest to object store and update StoredManifest with given manifest
+// write given manifest to object store and update StoredManifest with given manifest.
+// The manifest is written with the ID after the manifest read last, which fails if
+// another client wrote that ID. If the manifest read last was pruned, newer
+// manifests exist, and the ID may have been pruned as well, in which case the
+// write would succeed. Returns common.ErrManifestVersionExists instead, so the
+// caller refreshes the manifest and detects if it was fenced.
 func (s *StoredManifest) updateManifest(manifest *manifest.Manifest) error {
+	_, err := s.manifestStore.objectStore.head(s.manifestStore.manifestPath(
+		fmt.Sprintf("%020d.%s", s.id, s.manifestStore.manifestSuffix)))
+	if errors.Is(err, common.ErrObjectNotFound) {
+		return fmt.Errorf("%w: manifest '%d' was pruned, as newer manifests exist", common.ErrManifestVersionExists, s.id)
+	}
+	if err != nil {
+		return fmt.Errorf("while reading attributes of manifest '%d': %w", s.id, err)
+	}
+
 	newID := s.id + 1
-	err := s.manifestStore.writeManifest(newID, manifest)
+	err = s.manifestStore.writeManifest(newID, manifest)
 	if err != nil {
 		return err
 	}
@@ -228,37 +310,131 @@ type manifestInfo struct {
 
 // ManifestStore has helper methods to read and write manifest to object store
 type ManifestStore struct {
-	objectStore    ObjectStore
+	objectStore    rootedObjectStore
+	gcObjectStore  rootedObjectStore
+	ioStats        *IOStats
 	codec          manifest.Codec
 	manifestSuffix string
+	pinSuffix      string
+	// manifestDir is the prefix of the manifests relative to the root of the DB
+	manifestDir string
+
+	// verifyWrites is true if every manifest written is read back and verified
+	verifyWrites  bool
+	verifications atomic.Int64
+	verifyNanos   atomic.Int64
 }
 
 func NewManifestStore(rootPath string, bucket objstore.Bucket) *ManifestStore {
+	return NewManifestStoreWithObjectStore(rootPath, NewBucketObjectStore(bucket))
+}
+
+// NewManifestStoreWithObjectStore returns a ManifestStore which reads and writes
+// the manifests under rootPath through the ObjectStore
+func NewManifestStoreWithObjectStore(rootPath string, objectStore ObjectStore) *ManifestStore {
+	ioStats := &IOStats{}
 	return &ManifestStore{
-		objectStore:    newDelegatingObjectStore(rootPath, bucket),
+		objectStore:    newDelegatingObjectStore(rootPath, newIOObjectStore(objectStore, ioStats, IOClassManifest)),
+		gcObjectStore:  newDelegatingObjectStore(rootPath, newIOObjectStore(objectStore, ioStats, IOClassGC)),
+		ioStats:        ioStats,
 		codec:          manifest.FlatBufferManifestCodec{},
 		manifestSuffix: "manifest",
+		pinSuffix:      "pin",
+		manifestDir:    DefaultLayout().ManifestPrefix,
 	}
 }
 
+// IOStats returns the object store requests and bytes of the ManifestStore. Reads
+// and writes of manifests are attributed to IOClassManifest, while deletes of
+// pruned manifests are attributed to IOClassGC.
+func (s *ManifestStore) IOStats() *IOStats {
+	return s.ioStats
+}
+
 func (s *ManifestStore) manifestPath(filename string) string {
-	return path.Join(manifestDir, filename)
+	return path.Join(s.manifestDir, filename)
+}
+
+// SetLayout reads and writes the manifests under the manifest prefix of the
+// layout, which must be resolved by ResolveLayout. Must be called before the
+// ManifestStore is used.
+func (s *ManifestStore) SetLayout(layout config.ObjectStoreLayout) {
+	s.manifestDir = layout.ManifestPrefix
+}
+
+// SetVerifyWrites enables reading back every manifest immediately after it is
+// written, which detects object stores whose conditional writes allowed a
+// concurrent writer to overwrite the manifest.
+func (s *ManifestStore) SetVerifyWrites(verify bool) {
+	s.verifyWrites = verify
+}
+
+// VerifyStats returns the number of manifest writes which have been verified,
+// and the total time spent verifying them.
+func (s *ManifestStore) VerifyStats() (int64, time.Duration) {
+	return s.verifications.Load(), time.Duration(s.verifyNanos.Load())
 }
 
 func (s *ManifestStore) writeManifest(id uint64, manifest *manifest.Manifest) error {
 	filepath := s.manifestPath(fmt.Sprintf("%020d.%s", id, s.manifestSuffix))
-	err := s.objectStore.putIfNotExists(filepath, s.codec.Encode(manifest))
+	data := s.codec.Encode(manifest)
+	err := s.objectStore.putIfNotExists(filepath, data)
 	if err != nil {
 		if errors.Is(err, common.ErrObjectExists) {
 			return common.ErrManifestVersionExists
 		}
 		return common.ErrObjectStore
 	}
+
+	if s.verifyWrites {
+		start := time.Now()
+		err = s.verifyManifest(id, filepath, data, manifest)
+		s.verifications.Add(1)
+		s.verifyNanos.Add(int64(time.Since(start)))
+		return err
+	}
 	return nil
 }
 
+// verifyManifest reads back the manifest written to filepath and ensures it is
+// identical to the data written. If another writer replaced the manifest, the
+// epochs of the stored manifest determine if this writer has been fenced.
+func (s *ManifestStore) verifyManifest(id uint64, filepath string, data []byte, written *manifest.Manifest) error {
+	stored, err := s.objectStore.get(filepath)
+	if err != nil {
+		return fmt.Errorf("while reading back manifest '%d': %w", id, err)
+	}
+	if bytes.Equal(stored, data) {
+		return nil
+	}
+
+	storedManifest, err := s.codec.Decode(stored)
+	if err != nil {
+		return fmt.Errorf("%w: manifest '%d' could not be decoded: %w", common.ErrManifestVerification, id, err)
+	}
+	if storedManifest.WriterEpoch.Load() > written.WriterEpoch.Load() {
+		return fmt.Errorf("%w: manifest '%d' was written by a newer client: %w",
+			common.ErrManifestVerification, id, &manifest.FencedError{
+				Role:          "writer",
+				LocalEpoch:    written.WriterEpoch.Load(),
+				CurrentEpoch:  storedManifest.WriterEpoch.Load(),
+				LocalWriter:   written.Writer,
+				CurrentWriter: storedManifest.Writer,
+			})
+	}
+	if storedManifest.CompactorEpoch.Load() > written.CompactorEpoch.Load() {
+		return fmt.Errorf("%w: manifest '%d' was written by a newer client: %w",
+			common.ErrManifestVerification, id, &manifest.FencedError{
+				Role:         "compactor",
+				LocalEpoch:   written.CompactorEpoch.Load(),
+				CurrentEpoch: storedManifest.CompactorEpoch.Load(),
+			})
+	}
+	return fmt.Errorf("%w: manifest '%d' differs from the manifest written", common.ErrManifestVerification, id)
+}
+
 func (s *ManifestStore) listManifests() ([]ManifestFileMetadata, error) {
-	objMetaList, err := s.objectStore.list(mo.Some(manifestDir))
+	objMetaList, err := s.objectStore.list(mo.Some(s.manifestDir))
 	if err != nil {
 		return nil, common.ErrObjectStore
 	}
@@ -283,6 +459,240 @@ func (s *ManifestStore) listManifests() ([]ManifestFileMetadata, error) {
 	return manifests, nil
 }
 
+// PruneManifests deletes all but the newest retain manifest versions from the object
+// store and returns the number of manifest versions which remain. Every manifest
+// holds the complete DB state, so only the latest version is required to open the DB.
+//
+// Manifest versions written less than retainDuration ago are kept. A fenced writer
+// which has not noticed yet writes the manifest after the last one it read, and
+// the conditional write only fails while that manifest exists, see
+// StoredManifest.updateManifest.
+func (s *ManifestStore) PruneManifests(retain int, retainDuration time.Duration) (int, error) {
+	manifestList, err := s.listManifests()
+	if err != nil {
+		return 0, err
+	}
+
+	// Always keep the latest manifest
+	retain = max(retain, 1)
+	if len(manifestList) <= retain {
+		return len(manifestList), nil
+	}
+
+	pinned, err := s.listPinnedManifests()
+	if err != nil {
+		return len(manifestList), err
+	}
+
+	remaining := len(manifestList)
+	cutoff := time.Now().Add(-retainDuration)
+	for _, m := range manifestList[:len(manifestList)-retain] {
+		if pinned[m.ID] {
+			continue
+		}
+		lastModified := m.LastModified
+		if lastModified.IsZero() {
+			head, err := s.gcObjectStore.head(s.manifestPath(path.Base(m.Location)))
+			if err != nil {
+				return remaining, fmt.Errorf("while reading attributes of manifest '%d': %w", m.ID, err)
+			}
+			lastModified = head.LastModified
+		}
+		if lastModified.After(cutoff) {
+			continue
+		}
+		if err := s.gcObjectStore.delete(s.manifestPath(path.Base(m.Location))); err != nil {
+			return remaining, fmt.Errorf("while deleting manifest '%d': %w", m.ID, err)
+		}
+		remaining--
+	}
+	return remaining, nil
+}
+
+// PinManifest prevents PruneManifests from deleting the manifest version until
+// UnpinManifest is called. Pins are stored in the object store, so they are
+// honoured by every client and survive restarts.
+func (s *ManifestStore) PinManifest(id uint64) error {
+	filepath := s.manifestPath(fmt.Sprintf("%020d.%s", id, s.pinSuffix))
+	err := s.objectStore.putIfNotExists(filepath, []byte{})
+	if err != nil && !errors.Is(err, common.ErrObjectExists) {
+		return fmt.Errorf("while pinning manifest '%d': %w", id, err)
+	}
+	return nil
+}
+
+// UnpinManifest allows PruneManifests to delete the previously pinned manifest version
+func (s *ManifestStore) UnpinManifest(id uint64) error {
+	filepath := s.manifestPath(fmt.Sprintf("%020d.%s", id, s.pinSuffix))
+	if err := s.objectStore.delete(filepath); err != nil {
+		return fmt.Errorf("while unpinning manifest '%d': %w", id, err)
+	}
+	return nil
+}
+
+// ManifestPin is a pin on a manifest version. A pin retains the whole version,
+// that is the manifest and every SSTable it references, whatever the keys read
+// through it, since SSTables are only deleted with the version which drops them.
+type ManifestPin struct {
+	ManifestID uint64
+	// ID distinguishes pins of the same manifest version. It is empty for the
+	// pin created by PinManifest.
+	ID string
+}
+
+// PinManifestReader prevents PruneManifests from deleting the manifest version until
+// UnpinManifestReader is called with the returned pin. Unlike PinManifest, the pin
+// does not retain the WAL SSTs of the version, see PinnedWALID. Every call creates
+// a new pin, so pins of the same version by different readers are independent.
+func (s *ManifestStore) PinManifestReader(id uint64) (ManifestPin, error) {
+	pin := ManifestPin{
+		ManifestID: id,
+		ID:         ulid.Make().String(),
+	}
+	if err := s.objectStore.putIfNotExists(s.pinPath(pin), []byte{}); err != nil {
+		return ManifestPin{}, fmt.Errorf("while pinning manifest '%d': %w", id, err)
+	}
+	return pin, nil
+}
+
+// RepinManifestReader pins the manifest version with a pin created by PinManifestReader,
+// such as the pin of a reader which did not unpin it before exiting. The pin is
+// reused if it still exists, or created again otherwise, so a reader which takes
+// over the pin of another does not leave a second pin behind.
+func (s *ManifestStore) RepinManifestReader(pin ManifestPin) error {
+	err := s.objectStore.putIfNotExists(s.pinPath(pin), []byte{})
+	if err != nil && !errors.Is(err, common.ErrObjectExists) {
+		return fmt.Errorf("while pinning manifest '%d': %w", pin.ManifestID, err)
+	}
+	return nil
+}
+
+// UnpinManifestReader removes a pin created by PinManifestReader. A pin which was
+// already removed, such as by a reader which took over the pin, is ignored.
+func (s *ManifestStore) UnpinManifestReader(pin ManifestPin) error {
+	err := s.objectStore.delete(s.pinPath(pin))
+	if err != nil && !errors.Is(err, common.ErrObjectNotFound) {
+		return fmt.Errorf("while unpinning manifest '%d': %w", pin.ManifestID, err)
+	}
+	return nil
+}
+
+// ListPins returns every pin of every manifest version
+func (s *ManifestStore) ListPins() ([]ManifestPin, error) {
+	objMetaList, err := s.objectStore.list(mo.Some(s.manifestDir))
+	if err != nil {
+		return nil, common.ErrObjectStore
+	}
+
+	var pins []ManifestPin
+	for _, objMeta := range objMetaList {
+		base := path.Base(objMeta.Location)
+		if path.Ext(base) != "."+s.pinSuffix {
+			continue
+		}
+
+		// Pins are named '<manifest id>.pin' or '<manifest id>.<pin id>.pin'
+		parts := strings.Split(strings.TrimSuffix(base, "."+s.pinSuffix), ".")
+		id, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil || len(parts) > 2 {
+			continue
+		}
+		pin := ManifestPin{ManifestID: id}
+		if len(parts) == 2 {
+			pin.ID = parts[1]
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+// PinnedWALID returns the lowest ID of the WAL SSTs which a replica bootstrapped
+// from a manifest version pinned by PinManifest tails the WAL from, and false if
+// no manifest version is pinned by PinManifest. Pins of a reader, such as those of
+// a Snapshot, only retain SSTables, as reads of a Snapshot don't read the WAL.
+func (s *ManifestStore) PinnedWALID() (uint64, bool, error) {
+	pins, err := s.ListPins()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var walID uint64
+	found := false
+	for _, pin := range pins {
+		if pin.ID != "" {
+			continue
+		}
+		core, err := s.ReadManifest(pin.ManifestID)
+		if err != nil {
+			return 0, false, err
+		}
+		id := core.LastCompactedWalSSTID.Load() + 1
+		if !found || id < walID {
+			walID = id
+			found = true
+		}
+	}
+	return walID, found, nil
+}
+
+func (s *ManifestStore) listPinnedManifests() (map[uint64]bool, error) {
+	pins, err := s.ListPins()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := make(map[uint64]bool)
+	for _, pin := range pins {
+		pinned[pin.ManifestID] = true
+	}
+	return pinned, nil
+}
+
+func (s *ManifestStore) pinPath(pin ManifestPin) string {
+	if pin.ID == "" {
+		return s.manifestPath(fmt.Sprintf("%020d.%s", pin.ManifestID, s.pinSuffix))
+	}
+	return s.manifestPath(fmt.Sprintf("%020d.%s.%s", pin.ManifestID, pin.ID, s.pinSuffix))
+}
+
+// LatestManifestID returns the id of the newest manifest version in the object store
+func (s *ManifestStore) LatestManifestID() (uint64, error) {
+	manifestList, err := s.listManifests()
+	if err != nil {
+		return 0, err
+	}
+	if len(manifestList) == 0 {
+		return 0, common.ErrManifestNotFound
+	}
+	return manifestList[len(manifestList)-1].ID, nil
+}
+
+// ReadManifest returns the DB state stored in the requested manifest version
+func (s *ManifestStore) ReadManifest(id uint64) (*state.CoreStateSnapshot, error) {
+	manifestList, err := s.listManifests()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(manifestList, func(m ManifestFileMetadata) bool { return m.ID == id }) {
+		return nil, fmt.Errorf("%w: '%d'", common.ErrManifestNotFound, id)
+	}
+
+	manifestBytes, err := s.objectStore.get(s.manifestPath(fmt.Sprintf("%020d.%s", id, s.manifestSuffix)))
+	if errors.Is(err, common.ErrObjectNotFound) {
+		// The manifest was pruned after it was listed
+		return nil, fmt.Errorf("%w: '%d'", common.ErrManifestNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("while reading manifest '%d': %w", id, err)
+	}
+
+	manifest, err := s.codec.Decode(manifestBytes)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Core.Snapshot(), nil
+}
+
 func (s *ManifestStore) readLatestManifest() (mo.Option[manifestInfo], error) {
 	manifestList, err := s.listManifests()
 	if err != nil || len(manifestList) == 0 {
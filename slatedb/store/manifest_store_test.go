@@ -1,12 +1,19 @@
 package store
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/thanos-io/objstore"
 
 	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/manifest"
 	"github.com/slatedb/slatedb-go/slatedb/state"
 )
 
@@ -132,6 +139,11 @@ func TestShouldFailOnWriterFenced(t *testing.T) {
 
 	_, err = writer1.Refresh()
 	assert.ErrorIs(t, err, common.ErrFenced)
+	var fenced *manifest.FencedError
+	require.True(t, errors.As(err, &fenced))
+	assert.Equal(t, "writer", fenced.Role)
+	assert.Equal(t, writer1.Epoch(), fenced.LocalEpoch)
+	assert.Equal(t, writer2.Epoch(), fenced.CurrentEpoch)
 	core := coreState.Snapshot()
 	core.NextWalSstID.Store(123)
 	err = writer1.UpdateDBState(core)
@@ -195,3 +207,202 @@ func TestShouldFailOnCompactorFenced(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(1), refreshed.NextWalSstID.Load())
 }
+
+func TestShouldPruneOldManifests(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	manifestStore := NewManifestStore(rootPath, bucket)
+	coreState := state.NewCoreDBState()
+
+	sm, err := NewStoredManifest(manifestStore, coreState)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sm.updateDBState(coreState.Snapshot()))
+	}
+
+	remaining, err := manifestStore.PruneManifests(2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, remaining)
+
+	manifests, err := manifestStore.listManifests()
+	assert.NoError(t, err)
+	assert.Len(t, manifests, 2)
+	assert.Equal(t, uint64(5), manifests[0].ID)
+	assert.Equal(t, uint64(6), manifests[1].ID)
+
+	// the latest manifest is always kept
+	remaining, err = manifestStore.PruneManifests(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, remaining)
+
+	_, err = sm.Refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), sm.id)
+}
+
+func TestShouldNotPrunePinnedManifests(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	manifestStore := NewManifestStore(rootPath, bucket)
+	coreState := state.NewCoreDBState()
+
+	sm, err := NewStoredManifest(manifestStore, coreState)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sm.updateDBState(coreState.Snapshot()))
+	}
+
+	assert.NoError(t, manifestStore.PinManifest(2))
+	// pinning an already pinned manifest is not an error
+	assert.NoError(t, manifestStore.PinManifest(2))
+
+	remaining, err := manifestStore.PruneManifests(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, remaining)
+
+	_, err = manifestStore.ReadManifest(2)
+	assert.NoError(t, err)
+	_, err = manifestStore.ReadManifest(3)
+	assert.ErrorIs(t, err, common.ErrManifestNotFound)
+
+	latest, err := manifestStore.LatestManifestID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), latest)
+
+	assert.NoError(t, manifestStore.UnpinManifest(2))
+	remaining, err = manifestStore.PruneManifests(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, remaining)
+}
+
+func TestShouldFailZombieWriterAfterPruning(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	manifestStore := NewManifestStore(rootPath, bucket)
+	coreState := state.NewCoreDBState()
+
+	sm, err := NewStoredManifest(manifestStore, coreState)
+	require.NoError(t, err)
+	zombie, err := NewWriterFenceableManifest(sm)
+	require.NoError(t, err)
+
+	// A newer writer fences the zombie, which has not noticed yet, and writes
+	// more manifests after the manifest the zombie read last
+	storedManifest, err := LoadStoredManifest(manifestStore)
+	require.NoError(t, err)
+	sm2, ok := storedManifest.Get()
+	require.True(t, ok)
+	writer, err := NewWriterFenceableManifest(&sm2)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, writer.UpdateDBState(coreState.Snapshot()))
+	}
+
+	// Manifests written recently are kept
+	remaining, err := manifestStore.PruneManifests(1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 6, remaining)
+	remaining, err = manifestStore.PruneManifests(1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, remaining)
+
+	// The ID the zombie writes next was pruned, yet the write fails, and the
+	// refresh which follows detects that the zombie was fenced
+	err = zombie.UpdateDBState(coreState.Snapshot())
+	assert.ErrorIs(t, err, common.ErrManifestVersionExists)
+	_, err = manifestStore.ReadManifest(3)
+	assert.ErrorIs(t, err, common.ErrManifestNotFound)
+	_, err = zombie.Refresh()
+	assert.ErrorIs(t, err, common.ErrFenced)
+}
+
+func TestPinManifestReader(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	manifestStore := NewManifestStore(rootPath, bucket)
+	coreState := state.NewCoreDBState()
+
+	sm, err := NewStoredManifest(manifestStore, coreState)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, sm.updateDBState(coreState.Snapshot()))
+	}
+
+	// Pins of the same manifest version are independent of each other
+	first, err := manifestStore.PinManifestReader(2)
+	assert.NoError(t, err)
+	second, err := manifestStore.PinManifestReader(2)
+	assert.NoError(t, err)
+	assert.NoError(t, manifestStore.PinManifest(3))
+
+	pins, err := manifestStore.ListPins()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []ManifestPin{first, second, {ManifestID: 3}}, pins)
+
+	assert.NoError(t, manifestStore.UnpinManifestReader(first))
+	remaining, err := manifestStore.PruneManifests(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, remaining)
+
+	assert.NoError(t, manifestStore.UnpinManifestReader(second))
+	assert.NoError(t, manifestStore.UnpinManifest(3))
+	remaining, err = manifestStore.PruneManifests(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, remaining)
+}
+
+// lostWriteObjectStore simulates an object store with weak conditional writes,
+// where a concurrent writer replaces each object after it is written
+type lostWriteObjectStore struct {
+	*DelegatingObjectStore
+	replacement []byte
+}
+
+func (o *lostWriteObjectStore) putIfNotExists(objPath string, data []byte) error {
+	if err := o.DelegatingObjectStore.putIfNotExists(objPath, data); err != nil {
+		return err
+	}
+	if o.replacement == nil {
+		return nil
+	}
+	fullPath := path.Join(o.rootPath, objPath)
+	return o.store.Put(context.Background(), fullPath, bytes.NewReader(o.replacement))
+}
+
+func TestShouldVerifyManifestWrites(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	manifestStore := NewManifestStore(rootPath, bucket)
+	objectStore := &lostWriteObjectStore{DelegatingObjectStore: newDelegatingObjectStore(rootPath, NewBucketObjectStore(bucket))}
+	manifestStore.objectStore = objectStore
+	manifestStore.SetVerifyWrites(true)
+	coreState := state.NewCoreDBState()
+
+	sm, err := NewStoredManifest(manifestStore, coreState)
+	require.NoError(t, err)
+	count, _ := manifestStore.VerifyStats()
+	assert.Equal(t, int64(1), count)
+
+	replace := func(writerEpoch uint64) {
+		core := state.NewCoreDBState().Snapshot()
+		core.NextWalSstID.Store(100)
+		m := &manifest.Manifest{Core: core.ToCoreState()}
+		m.WriterEpoch.Store(writerEpoch)
+		objectStore.replacement = manifestStore.codec.Encode(m)
+	}
+
+	t.Run("Replaced By Newer Writer", func(t *testing.T) {
+		replace(sm.manifest.WriterEpoch.Load() + 1)
+		err := sm.updateDBState(coreState.Snapshot())
+		assert.ErrorIs(t, err, common.ErrManifestVerification)
+		assert.ErrorIs(t, err, common.ErrFenced)
+	})
+
+	t.Run("Replaced By Same Epoch", func(t *testing.T) {
+		_, err := sm.Refresh()
+		require.NoError(t, err)
+		replace(sm.manifest.WriterEpoch.Load())
+		err = sm.updateDBState(coreState.Snapshot())
+		assert.ErrorIs(t, err, common.ErrManifestVerification)
+		assert.NotErrorIs(t, err, common.ErrFenced)
+	})
+
+	count, latency := manifestStore.VerifyStats()
+	assert.Equal(t, int64(3), count)
+	assert.Greater(t, latency, time.Duration(0))
+}
@@ -3,10 +3,12 @@ package store
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"path"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/samber/mo"
@@ -16,42 +18,180 @@ import (
 )
 
 type ObjectMeta struct {
-	// LastModified is the time the object was last modified.
+	// LastModified is the time the object was last modified. It is zero if the
+	// ObjectStore does not return it while listing, see ObjectStore.Head.
 	LastModified time.Time
 
 	// Location is the path of the object
 	Location string
+
+	// Size is the size of the object in bytes. Like LastModified, it is zero if
+	// the ObjectStore does not return it while listing.
+	Size int64
 }
 
+// ObjectStore is the object storage which every read and write of SSTables, WAL
+// SSTables and manifests goes through, so the storage backend can be swapped.
+// NewBucketObjectStore adapts the providers of objstore.Bucket, other backends
+// implement ObjectStore themselves. Get, GetRange and Head return an error which
+// wraps common.ErrObjectNotFound if there is no object at the path.
 type ObjectStore interface {
+	// Put writes the object, replacing the object at the path if any
+	Put(ctx context.Context, path string, r io.Reader) error
+
+	// PutIfNotExists writes the object unless an object exists at the path, in
+	// which case common.ErrObjectExists is returned. Fencing of writers and
+	// manifest updates rely on it, so backends which support conditional writes,
+	// such as S3 with If-None-Match, should use them.
+	PutIfNotExists(ctx context.Context, path string, r io.Reader) error
+
+	// Get returns a reader of the object
+	Get(ctx context.Context, path string) (io.ReadCloser, error)
+
+	// GetRange returns a reader of length bytes of the object from the offset off
+	GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error)
+
+	// Head returns the metadata of the object
+	Head(ctx context.Context, path string) (ObjectMeta, error)
+
+	// List returns the metadata of every object whose path is under the prefix
+	// directory, including the objects of nested directories
+	List(ctx context.Context, prefix string) ([]ObjectMeta, error)
+
+	// Delete removes the object
+	Delete(ctx context.Context, path string) error
+}
+
+// bucketObjectStore is an ObjectStore backed by an objstore.Bucket
+type bucketObjectStore struct {
+	bucket objstore.Bucket
+}
+
+// NewBucketObjectStore returns an ObjectStore which reads and writes the objects
+// of the bucket
+func NewBucketObjectStore(bucket objstore.Bucket) ObjectStore {
+	return bucketObjectStore{bucket: bucket}
+}
+
+func (b bucketObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	return b.bucket.Upload(ctx, path, r)
+}
+
+// TODO: We should make this atomic once objstore.Bucket supports preconditions
+func (b bucketObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	exists, err := b.bucket.Exists(ctx, path)
+	if err != nil {
+		return fmt.Errorf("during object exists: %w", err)
+	}
+	if exists {
+		return common.ErrObjectExists
+	}
+	return b.bucket.Upload(ctx, path, r)
+}
+
+func (b bucketObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	r, err := b.bucket.Get(ctx, path)
+	return r, b.wrapNotFound(err)
+}
+
+func (b bucketObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	r, err := b.bucket.GetRange(ctx, path, off, length)
+	return r, b.wrapNotFound(err)
+}
+
+func (b bucketObjectStore) Head(ctx context.Context, path string) (ObjectMeta, error) {
+	attrs, err := b.bucket.Attributes(ctx, path)
+	if err != nil {
+		return ObjectMeta{}, b.wrapNotFound(err)
+	}
+	return ObjectMeta{LastModified: attrs.LastModified, Location: path, Size: attrs.Size}, nil
+}
+
+// List returns the metadata of every object under the prefix directory. The
+// prefix is matched as a directory, so "wal" doesn't match "wal2/a".
+func (b bucketObjectStore) List(ctx context.Context, prefix string) ([]ObjectMeta, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	objMetaList := make([]ObjectMeta, 0)
+	iterFn := func(attrs objstore.IterObjectAttributes) error {
+		lastModified, _ := attrs.LastModified()
+		objMetaList = append(objMetaList, ObjectMeta{LastModified: lastModified, Location: attrs.Name})
+		return nil
+	}
+	err := b.bucket.IterWithAttributes(ctx, prefix, iterFn, objStoreIterOptions(b.bucket)...)
+	if err != nil {
+		return nil, err
+	}
+	return objMetaList, nil
+}
+
+func (b bucketObjectStore) Delete(ctx context.Context, path string) error {
+	return b.wrapNotFound(b.bucket.Delete(ctx, path))
+}
+
+// wrapNotFound wraps err with common.ErrObjectNotFound if the bucket reports that
+// the object does not exist
+func (b bucketObjectStore) wrapNotFound(err error) error {
+	if err != nil && b.bucket.IsObjNotFoundErr(err) {
+		return fmt.Errorf("%w: %w", common.ErrObjectNotFound, err)
+	}
+	return err
+}
+
+// objStoreIterOptions gets IterOptions supported by the storage provider
+func objStoreIterOptions(bucket objstore.Bucket) []objstore.IterOption {
+	iterOptions := make([]objstore.IterOption, 0)
+	requiredOptions := []objstore.IterOption{objstore.WithRecursiveIter(), objstore.WithUpdatedAt()}
+
+	for _, required := range requiredOptions {
+		if slices.Contains(bucket.SupportedIterOptions(), required.Type) {
+			iterOptions = append(iterOptions, required)
+		}
+	}
+	return iterOptions
+}
+
+// rootedObjectStore reads and writes the objects of an ObjectStore at paths
+// relative to a root path, as the ManifestStore does
+type rootedObjectStore interface {
+	put(path string, data []byte) error
+
 	putIfNotExists(path string, data []byte) error
 
 	get(path string) ([]byte, error)
 
+	head(path string) (ObjectMeta, error)
+
 	list(path mo.Option[string]) ([]ObjectMeta, error)
+
+	delete(path string) error
 }
 
 type DelegatingObjectStore struct {
 	rootPath string
-	bucket   objstore.Bucket
+	store    ObjectStore
 }
 
-func newDelegatingObjectStore(rootPath string, bucket objstore.Bucket) *DelegatingObjectStore {
-	return &DelegatingObjectStore{rootPath, bucket}
+func newDelegatingObjectStore(rootPath string, store ObjectStore) *DelegatingObjectStore {
+	return &DelegatingObjectStore{rootPath, store}
 }
 
-// TODO: We should make this atomic
-func (d *DelegatingObjectStore) putIfNotExists(objPath string, data []byte) error {
+func (d *DelegatingObjectStore) put(objPath string, data []byte) error {
 	fullPath := path.Join(d.rootPath, objPath)
-	exists, err := d.bucket.Exists(context.Background(), fullPath)
+	err := d.store.Put(context.Background(), fullPath, bytes.NewReader(data))
 	if err != nil {
 		return common.ErrObjectStore
 	}
-	if exists {
+	return nil
+}
+
+func (d *DelegatingObjectStore) putIfNotExists(objPath string, data []byte) error {
+	fullPath := path.Join(d.rootPath, objPath)
+	err := d.store.PutIfNotExists(context.Background(), fullPath, bytes.NewReader(data))
+	if errors.Is(err, common.ErrObjectExists) {
 		return common.ErrObjectExists
 	}
-
-	err = d.bucket.Upload(context.Background(), fullPath, bytes.NewReader(data))
 	if err != nil {
 		return common.ErrObjectStore
 	}
@@ -60,10 +200,11 @@ func (d *DelegatingObjectStore) putIfNotExists(objPath string, data []byte) erro
 
 func (d *DelegatingObjectStore) get(objPath string) ([]byte, error) {
 	fullPath := path.Join(d.rootPath, objPath)
-	reader, err := d.bucket.Get(context.Background(), fullPath)
+	reader, err := d.store.Get(context.Background(), fullPath)
 	if err != nil {
 		return nil, common.ErrObjectStore
 	}
+	defer func() { _ = reader.Close() }()
 
 	data, err := io.ReadAll(reader)
 	if err != nil {
@@ -72,6 +213,18 @@ func (d *DelegatingObjectStore) get(objPath string) ([]byte, error) {
 	return data, nil
 }
 
+func (d *DelegatingObjectStore) head(objPath string) (ObjectMeta, error) {
+	fullPath := path.Join(d.rootPath, objPath)
+	meta, err := d.store.Head(context.Background(), fullPath)
+	if errors.Is(err, common.ErrObjectNotFound) {
+		return ObjectMeta{}, common.ErrObjectNotFound
+	}
+	if err != nil {
+		return ObjectMeta{}, common.ErrObjectStore
+	}
+	return meta, nil
+}
+
 func (d *DelegatingObjectStore) list(objPath mo.Option[string]) ([]ObjectMeta, error) {
 	fullPath := d.rootPath
 	if objPath.IsPresent() {
@@ -79,29 +232,21 @@ func (d *DelegatingObjectStore) list(objPath mo.Option[string]) ([]ObjectMeta, e
 		fullPath = path.Join(d.rootPath, p)
 	}
 
-	objMetaList := make([]ObjectMeta, 0)
-	iterFn := func(attrs objstore.IterObjectAttributes) error {
-		lastModified, _ := attrs.LastModified()
-		objMetaList = append(objMetaList, ObjectMeta{lastModified, attrs.Name})
-		return nil
-	}
-	err := d.bucket.IterWithAttributes(context.Background(), fullPath, iterFn, objStoreIterOptions(d.bucket)...)
+	objMetaList, err := d.store.List(context.Background(), fullPath)
 	if err != nil {
 		return nil, common.ErrObjectStore
 	}
-
 	return objMetaList, nil
 }
 
-// objStoreIterOptions gets IterOptions supported by the storage provider
-func objStoreIterOptions(bucket objstore.Bucket) []objstore.IterOption {
-	iterOptions := make([]objstore.IterOption, 0)
-	requiredOptions := []objstore.IterOption{objstore.WithRecursiveIter(), objstore.WithUpdatedAt()}
-
-	for _, required := range requiredOptions {
-		if slices.Contains(bucket.SupportedIterOptions(), required.Type) {
-			iterOptions = append(iterOptions, required)
-		}
+func (d *DelegatingObjectStore) delete(objPath string) error {
+	fullPath := path.Join(d.rootPath, objPath)
+	err := d.store.Delete(context.Background(), fullPath)
+	if errors.Is(err, common.ErrObjectNotFound) {
+		return common.ErrObjectNotFound
 	}
-	return iterOptions
+	if err != nil {
+		return common.ErrObjectStore
+	}
+	return nil
 }
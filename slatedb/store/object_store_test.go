@@ -19,7 +19,7 @@ var rootPath = "/root/path"
 
 func TestDelegatingShouldFailPutIfExists(t *testing.T) {
 	bucket := objstore.NewInMemBucket()
-	store := newDelegatingObjectStore(rootPath, bucket)
+	store := newDelegatingObjectStore(rootPath, NewBucketObjectStore(bucket))
 
 	err := store.putIfNotExists("obj", []byte("data1"))
 	assert.NoError(t, err)
@@ -34,7 +34,7 @@ func TestDelegatingShouldFailPutIfExists(t *testing.T) {
 
 func TestDelegatingShouldGetPut(t *testing.T) {
 	bucket := objstore.NewInMemBucket()
-	store := newDelegatingObjectStore(rootPath, bucket)
+	store := newDelegatingObjectStore(rootPath, NewBucketObjectStore(bucket))
 
 	err := store.putIfNotExists("obj", []byte("data1"))
 	assert.NoError(t, err)
@@ -46,7 +46,7 @@ func TestDelegatingShouldGetPut(t *testing.T) {
 
 func TestDelegatingShouldList(t *testing.T) {
 	bucket := objstore.NewInMemBucket()
-	store := newDelegatingObjectStore(rootPath, bucket)
+	store := newDelegatingObjectStore(rootPath, NewBucketObjectStore(bucket))
 
 	err := store.putIfNotExists("obj", []byte("data1"))
 	assert.NoError(t, err)
@@ -71,7 +71,7 @@ func TestDelegatingShouldList(t *testing.T) {
 
 func TestDelegatingShouldPutWithPrefix(t *testing.T) {
 	bucket := objstore.NewInMemBucket()
-	store := newDelegatingObjectStore(rootPath, bucket)
+	store := newDelegatingObjectStore(rootPath, NewBucketObjectStore(bucket))
 
 	err := store.putIfNotExists("obj", []byte("data1"))
 	assert.NoError(t, err)
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// RateLimiter is a token bucket which refills at a rate of tokens per second, up
+// to its burst. Callers take tokens with Wait, which may leave the bucket in debt,
+// so a request for more tokens than the burst waits rather than failing.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter which starts with a full bucket, or nil if
+// rate is not positive. A nil RateLimiter never waits.
+func NewRateLimiter(rate float64, burst float64) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	burst = max(burst, 1)
+	return &RateLimiter{rate: rate, burst: burst, tokens: burst, now: time.Now}
+}
+
+// Wait takes n tokens from the bucket, and waits until the bucket has refilled
+// the tokens it is short of. The tokens are returned if ctx is done first.
+func (l *RateLimiter) Wait(ctx context.Context, n float64) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	delay := l.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.refund(n)
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes n tokens from the bucket, and returns the time until the bucket
+// is out of debt
+func (l *RateLimiter) reserve(n float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens -= n
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// refund returns n tokens which were reserved but not used
+func (l *RateLimiter) refund(n float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.burst, l.tokens+n)
+}
+
+// rateLimitObjectStore limits the rate of the requests and bytes of an ObjectStore
+// according to config.ObjectStoreRateLimits
+type rateLimitObjectStore struct {
+	ObjectStore
+	readRequests  *RateLimiter
+	readBytes     *RateLimiter
+	writeRequests *RateLimiter
+	writeBytes    *RateLimiter
+}
+
+// NewRateLimitObjectStore returns an ObjectStore which limits the rate of the
+// requests and bytes of the store, or the store itself if nothing is limited
+func NewRateLimitObjectStore(store ObjectStore, limits config.ObjectStoreRateLimits) ObjectStore {
+	if !limits.Limited() {
+		return store
+	}
+	// Each bucket holds up to a second of its rate
+	return &rateLimitObjectStore{
+		ObjectStore:   store,
+		readRequests:  NewRateLimiter(limits.ReadRequestsPerSecond, limits.ReadRequestsPerSecond),
+		readBytes:     NewRateLimiter(limits.ReadBytesPerSecond, limits.ReadBytesPerSecond),
+		writeRequests: NewRateLimiter(limits.WriteRequestsPerSecond, limits.WriteRequestsPerSecond),
+		writeBytes:    NewRateLimiter(limits.WriteBytesPerSecond, limits.WriteBytesPerSecond),
+	}
+}
+
+func (s *rateLimitObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	if err := s.writeRequests.Wait(ctx, 1); err != nil {
+		return err
+	}
+	return s.ObjectStore.Put(ctx, path, limitBody(ctx, r, s.writeBytes))
+}
+
+func (s *rateLimitObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	if err := s.writeRequests.Wait(ctx, 1); err != nil {
+		return err
+	}
+	return s.ObjectStore.PutIfNotExists(ctx, path, limitBody(ctx, r, s.writeBytes))
+}
+
+func (s *rateLimitObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	if err := s.readRequests.Wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	r, err := s.ObjectStore.Get(ctx, path)
+	if err != nil || s.readBytes == nil {
+		return r, err
+	}
+	return rateLimitedReadCloser{ReadCloser: r, ctx: ctx, limiter: s.readBytes}, nil
+}
+
+func (s *rateLimitObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	if err := s.readRequests.Wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	r, err := s.ObjectStore.GetRange(ctx, path, off, length)
+	if err != nil || s.readBytes == nil {
+		return r, err
+	}
+	return rateLimitedReadCloser{ReadCloser: r, ctx: ctx, limiter: s.readBytes}, nil
+}
+
+func (s *rateLimitObjectStore) Head(ctx context.Context, path string) (ObjectMeta, error) {
+	if err := s.readRequests.Wait(ctx, 1); err != nil {
+		return ObjectMeta{}, err
+	}
+	return s.ObjectStore.Head(ctx, path)
+}
+
+func (s *rateLimitObjectStore) List(ctx context.Context, prefix string) ([]ObjectMeta, error) {
+	if err := s.readRequests.Wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return s.ObjectStore.List(ctx, prefix)
+}
+
+func (s *rateLimitObjectStore) Delete(ctx context.Context, path string) error {
+	if err := s.writeRequests.Wait(ctx, 1); err != nil {
+		return err
+	}
+	return s.ObjectStore.Delete(ctx, path)
+}
+
+// limitBody returns the body of a write whose bytes wait for the limiter as they
+// are read, and which can be rewound if the body can
+func limitBody(ctx context.Context, r io.Reader, limiter *RateLimiter) io.Reader {
+	if limiter == nil {
+		return r
+	}
+	limited := rateLimitedReader{Reader: r, ctx: ctx, limiter: limiter}
+	if seeker, ok := r.(io.Seeker); ok {
+		return rateLimitedReadSeeker{rateLimitedReader: limited, seeker: seeker}
+	}
+	return limited
+}
+
+// rateLimitedReader waits for the limiter after each read, for the bytes read
+type rateLimitedReader struct {
+	io.Reader
+	ctx     context.Context
+	limiter *RateLimiter
+}
+
+func (r rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if waitErr := r.limiter.Wait(r.ctx, float64(n)); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
+
+// rateLimitedReadSeeker is a rateLimitedReader of a body which can be rewound
+type rateLimitedReadSeeker struct {
+	rateLimitedReader
+	seeker io.Seeker
+}
+
+func (r rateLimitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
+
+// rateLimitedReadCloser waits for the limiter after each read of an object, for
+// the bytes read
+type rateLimitedReadCloser struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *RateLimiter
+}
+
+func (r rateLimitedReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if waitErr := r.limiter.Wait(r.ctx, float64(n)); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
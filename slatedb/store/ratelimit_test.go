@@ -0,0 +1,94 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(10, 5)
+	l.now = func() time.Time { return now }
+
+	// The bucket starts full, and goes into debt once it is empty
+	assert.Zero(t, l.reserve(5))
+	assert.Equal(t, 100*time.Millisecond, l.reserve(1))
+	assert.Equal(t, time.Second, l.reserve(9))
+
+	// The bucket refills at the rate, up to the burst
+	now = now.Add(2 * time.Second)
+	assert.Zero(t, l.reserve(5))
+	now = now.Add(time.Hour)
+	assert.Zero(t, l.reserve(5))
+	assert.Equal(t, 100*time.Millisecond, l.reserve(1))
+
+	// Tokens reserved by a canceled wait are returned
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.Wait(ctx, 10), context.Canceled)
+	assert.Equal(t, 100*time.Millisecond, l.reserve(0))
+
+	assert.Nil(t, NewRateLimiter(0, 5))
+	assert.NoError(t, (*RateLimiter)(nil).Wait(ctx, 1))
+}
+
+func TestRateLimitObjectStore(t *testing.T) {
+	ctx := context.Background()
+	inner := NewBucketObjectStore(objstore.NewInMemBucket())
+	assert.Equal(t, inner, NewRateLimitObjectStore(inner, config.ObjectStoreRateLimits{}))
+
+	s := NewRateLimitObjectStore(inner, config.ObjectStoreRateLimits{
+		ReadRequestsPerSecond: 20,
+		WriteBytesPerSecond:   1000,
+	})
+
+	// A write of a second and a half of bytes waits for half a second, once the
+	// burst of a second is spent
+	start := time.Now()
+	require.NoError(t, s.Put(ctx, "a", bytes.NewReader(make([]byte, 1500))))
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	// The body of a limited write can be rewound to retry it
+	_, ok := limitBody(ctx, bytes.NewReader(nil), NewRateLimiter(1, 1)).(io.Seeker)
+	assert.True(t, ok)
+
+	// Reads beyond the burst of requests wait, writes are not limited
+	start = time.Now()
+	for i := 0; i < 25; i++ {
+		_, err := s.Head(ctx, "a")
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	start = time.Now()
+	for i := 0; i < 25; i++ {
+		require.NoError(t, s.Put(ctx, "b", bytes.NewReader(nil)))
+	}
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+
+	// A request canceled while waiting fails
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err := s.Get(canceled, "a")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestTableStoreWithRateLimit(t *testing.T) {
+	tableStore := NewTableStore(objstore.NewInMemBucket(), sstable.DefaultConfig(), "")
+	assert.Same(t, tableStore, tableStore.WithRateLimit(config.ObjectStoreRateLimits{}))
+
+	// The requests of the limited TableStore are attributed to its IOClass
+	limited := tableStore.WithIOClass(IOClassCompaction).WithRateLimit(config.ObjectStoreRateLimits{ReadRequestsPerSecond: 100})
+	_, err := limited.objectStore.List(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), tableStore.IOStats().Snapshot()[IOClassCompaction].Requests)
+}
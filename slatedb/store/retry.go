@@ -0,0 +1,331 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// retryBudgetRefill is the part of a retry returned to the retry budget by every
+// request which succeeds
+const retryBudgetRefill = 0.1
+
+// fatalMessages are the substrings of the errors which object stores return when
+// a request is invalid or unauthorized, which fails again if retried
+var fatalMessages = []string{
+	"access denied",
+	"accessdenied",
+	"forbidden",
+	"unauthorized",
+	"invalid",
+	"no such bucket",
+	"nosuchbucket",
+	"not implemented",
+}
+
+// IsRetryableErr returns true if a failed object store request may succeed if
+// retried. Requests which failed because the object does or does not exist, were
+// canceled, or were rejected as invalid or unauthorized are not retryable.
+func IsRetryableErr(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) ||
+		errors.Is(err, common.ErrObjectNotFound) || errors.Is(err, common.ErrObjectExists) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range fatalMessages {
+		if strings.Contains(msg, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// RetryStats counts the retries of a RetryObjectStore
+type RetryStats struct {
+	// Retries is the number of attempts made after a failed attempt
+	Retries int64
+	// Timeouts is the number of attempts which exceeded the operation timeout
+	Timeouts int64
+	// GaveUp is the number of requests which failed on their last attempt
+	GaveUp int64
+	// BudgetExhausted is the number of failed requests which were not retried as
+	// the retry budget was spent
+	BudgetExhausted int64
+}
+
+// RetryObjectStore retries the failed requests of an ObjectStore according to
+// config.ObjectStoreRetryOptions. Only the request is retried, a read of an
+// object which fails once its reader was returned is not.
+type RetryObjectStore struct {
+	ObjectStore
+	opts        config.ObjectStoreRetryOptions
+	isThrottled func(error) bool
+
+	mu sync.Mutex
+	// tokens is the remaining retry budget
+	tokens float64
+
+	retries         atomic.Int64
+	timeouts        atomic.Int64
+	gaveUp          atomic.Int64
+	budgetExhausted atomic.Int64
+}
+
+// NewRetryObjectStore returns an ObjectStore which retries the failed requests of
+// the store. Conditional writes are retried only if isThrottled, or IsThrottledErr
+// if nil, reports that the object store throttled them.
+func NewRetryObjectStore(store ObjectStore, opts config.ObjectStoreRetryOptions, isThrottled func(error) bool) *RetryObjectStore {
+	if opts.IsRetryable == nil {
+		opts.IsRetryable = IsRetryableErr
+	}
+	if isThrottled == nil {
+		isThrottled = IsThrottledErr
+	}
+	return &RetryObjectStore{
+		ObjectStore: store,
+		opts:        opts,
+		isThrottled: isThrottled,
+		tokens:      float64(opts.RetryBudget),
+	}
+}
+
+// Stats returns the retries made since the store was created
+func (s *RetryObjectStore) Stats() RetryStats {
+	return RetryStats{
+		Retries:         s.retries.Load(),
+		Timeouts:        s.timeouts.Load(),
+		GaveUp:          s.gaveUp.Load(),
+		BudgetExhausted: s.budgetExhausted.Load(),
+	}
+}
+
+func (s *RetryObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	body, err := s.rewindable(r)
+	if err != nil {
+		return err
+	}
+	return s.retry(ctx, false, body.attempts(s.opts.MaxAttempts), func(ctx context.Context) error {
+		r, err := body.rewind()
+		if err != nil {
+			return err
+		}
+		return s.ObjectStore.Put(ctx, path, r)
+	})
+}
+
+func (s *RetryObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	body, err := s.rewindable(r)
+	if err != nil {
+		return err
+	}
+	return s.retry(ctx, true, body.attempts(s.opts.MaxAttempts), func(ctx context.Context) error {
+		r, err := body.rewind()
+		if err != nil {
+			return err
+		}
+		return s.ObjectStore.PutIfNotExists(ctx, path, r)
+	})
+}
+
+func (s *RetryObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	return s.read(ctx, func(ctx context.Context) (io.ReadCloser, error) {
+		return s.ObjectStore.Get(ctx, path)
+	})
+}
+
+func (s *RetryObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	return s.read(ctx, func(ctx context.Context) (io.ReadCloser, error) {
+		return s.ObjectStore.GetRange(ctx, path, off, length)
+	})
+}
+
+func (s *RetryObjectStore) Head(ctx context.Context, path string) (ObjectMeta, error) {
+	var meta ObjectMeta
+	err := s.retry(ctx, false, s.opts.MaxAttempts, func(ctx context.Context) error {
+		var err error
+		meta, err = s.ObjectStore.Head(ctx, path)
+		return err
+	})
+	return meta, err
+}
+
+func (s *RetryObjectStore) List(ctx context.Context, prefix string) ([]ObjectMeta, error) {
+	var list []ObjectMeta
+	err := s.retry(ctx, false, s.opts.MaxAttempts, func(ctx context.Context) error {
+		var err error
+		list, err = s.ObjectStore.List(ctx, prefix)
+		return err
+	})
+	return list, err
+}
+
+func (s *RetryObjectStore) Delete(ctx context.Context, path string) error {
+	return s.retry(ctx, false, s.opts.MaxAttempts, func(ctx context.Context) error {
+		return s.ObjectStore.Delete(ctx, path)
+	})
+}
+
+// read retries a request which returns the reader of an object. The timeout of
+// the attempt is canceled once the reader is closed.
+func (s *RetryObjectStore) read(ctx context.Context, get func(ctx context.Context) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	err := s.retryAttempts(ctx, false, s.opts.MaxAttempts, func(ctx context.Context, cancel context.CancelFunc) error {
+		r, err := get(ctx)
+		if err != nil {
+			cancel()
+			return err
+		}
+		reader = cancelOnClose{ReadCloser: r, cancel: cancel}
+		return nil
+	})
+	return reader, err
+}
+
+// retry makes up to maxAttempts attempts of the request until one succeeds or the
+// error is not retried
+func (s *RetryObjectStore) retry(ctx context.Context, conditional bool, maxAttempts int,
+	attempt func(ctx context.Context) error) error {
+	return s.retryAttempts(ctx, conditional, maxAttempts, func(ctx context.Context, cancel context.CancelFunc) error {
+		defer cancel()
+		return attempt(ctx)
+	})
+}
+
+// retryAttempts makes up to maxAttempts attempts of the request until one succeeds
+// or the error is not retried. Each attempt is passed a context which times out after
+// config.ObjectStoreRetryOptions.OperationTimeout, and the function which cancels it.
+func (s *RetryObjectStore) retryAttempts(ctx context.Context, conditional bool, maxAttempts int,
+	attempt func(ctx context.Context, cancel context.CancelFunc) error) error {
+	backoff := s.opts.InitialBackoff
+	for n := 1; ; n++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if s.opts.OperationTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, s.opts.OperationTimeout)
+		}
+		err := attempt(attemptCtx, cancel)
+		if err == nil {
+			s.refill()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		timedOut := errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		if timedOut {
+			s.timeouts.Add(1)
+		}
+		if conditional && !s.isThrottled(err) {
+			return err
+		}
+		if !timedOut && !s.opts.IsRetryable(err) {
+			return err
+		}
+		if n >= maxAttempts {
+			if maxAttempts > 1 {
+				s.gaveUp.Add(1)
+			}
+			return err
+		}
+		if !s.spend() {
+			s.budgetExhausted.Add(1)
+			return err
+		}
+
+		s.retries.Add(1)
+		// Equal jitter, between half and all of the backoff
+		delay := backoff/2 + rand.N(backoff/2+1)
+		backoff = min(2*backoff, max(s.opts.MaxBackoff, s.opts.InitialBackoff))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// spend takes a retry from the retry budget, and returns false if it was spent
+func (s *RetryObjectStore) spend() bool {
+	if s.opts.RetryBudget <= 0 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// refill returns part of a retry to the retry budget after a request succeeded
+func (s *RetryObjectStore) refill() {
+	if s.opts.RetryBudget <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = min(float64(s.opts.RetryBudget), s.tokens+retryBudgetRefill)
+}
+
+// rewindable returns a reader of the body of a write which can be rewound to
+// write it again if it is an io.ReadSeeker. Other bodies are streams, such as the
+// multipart upload of a large SSTable, which are written once rather than
+// buffered, so the upload starts while the stream is written.
+func (s *RetryObjectStore) rewindable(r io.Reader) (*rewindReader, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok || s.opts.MaxAttempts <= 1 {
+		return &rewindReader{r: r}, nil
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	return &rewindReader{r: rs, seeker: rs, start: start}, nil
+}
+
+// rewindReader is the body of a write, which is rewound to its start before
+// every attempt
+type rewindReader struct {
+	r io.Reader
+	// seeker is nil if the body can't be rewound
+	seeker io.Seeker
+	start  int64
+}
+
+// attempts returns the number of attempts of the write, which is one if the body
+// can't be rewound
+func (r *rewindReader) attempts(maxAttempts int) int {
+	if r.seeker == nil {
+		return 1
+	}
+	return maxAttempts
+}
+
+// rewind returns the body rewound to its start
+func (r *rewindReader) rewind() (io.Reader, error) {
+	if r.seeker == nil {
+		return r.r, nil
+	}
+	_, err := r.seeker.Seek(r.start, io.SeekStart)
+	return r.r, err
+}
+
+// cancelOnClose cancels the context of a read once its reader is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r cancelOnClose) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
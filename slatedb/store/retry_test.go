@@ -0,0 +1,162 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+// failingObjectStore fails the next requests with the queued errors, where a
+// context.DeadlineExceeded error blocks the request until its context is done
+type failingObjectStore struct {
+	ObjectStore
+	errs     []error
+	requests int
+}
+
+func (s *failingObjectStore) fail(ctx context.Context) error {
+	s.requests++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	if errors.Is(err, context.DeadlineExceeded) {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return err
+}
+
+func (s *failingObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	if err := s.fail(ctx); err != nil {
+		// Consume part of the body, as a failed upload does
+		_, _ = r.Read(make([]byte, 2))
+		return err
+	}
+	return s.ObjectStore.Put(ctx, path, r)
+}
+
+func (s *failingObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	if err := s.fail(ctx); err != nil {
+		return err
+	}
+	return s.ObjectStore.PutIfNotExists(ctx, path, r)
+}
+
+func (s *failingObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	if err := s.fail(ctx); err != nil {
+		return nil, err
+	}
+	return s.ObjectStore.Get(ctx, path)
+}
+
+func retryOptions() config.ObjectStoreRetryOptions {
+	return config.ObjectStoreRetryOptions{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+	}
+}
+
+func TestIsRetryableErr(t *testing.T) {
+	assert.True(t, IsRetryableErr(errors.New("connection reset by peer")))
+	assert.True(t, IsRetryableErr(errors.New("SlowDown: Please reduce your request rate.")))
+	assert.False(t, IsRetryableErr(nil))
+	assert.False(t, IsRetryableErr(context.Canceled))
+	assert.False(t, IsRetryableErr(fmt.Errorf("%w: key", common.ErrObjectNotFound)))
+	assert.False(t, IsRetryableErr(common.ErrObjectExists))
+	assert.False(t, IsRetryableErr(errors.New("AccessDenied: Access Denied")))
+}
+
+func TestRetryObjectStoreRetriesTransientErrors(t *testing.T) {
+	ctx := context.Background()
+	failing := &failingObjectStore{ObjectStore: NewBucketObjectStore(objstore.NewInMemBucket())}
+	s := NewRetryObjectStore(failing, retryOptions(), nil)
+
+	// The body is written in full by the attempt which succeeds
+	reset := errors.New("connection reset by peer")
+	failing.errs = []error{reset, reset}
+	require.NoError(t, s.Put(ctx, "a", strings.NewReader("value")))
+	r, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "value", string(data))
+	assert.Equal(t, RetryStats{Retries: 2}, s.Stats())
+
+	// A stream which can't be rewound is written once
+	failing.errs = []error{reset}
+	assert.ErrorIs(t, s.Put(ctx, "b", io.MultiReader(strings.NewReader("other"))), reset)
+	assert.Equal(t, RetryStats{Retries: 2}, s.Stats())
+
+	// Fatal errors are not retried
+	failing.requests = 0
+	_, err = s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, common.ErrObjectNotFound)
+	assert.Equal(t, 1, failing.requests)
+
+	// A request fails once it made MaxAttempts attempts
+	failing.errs = []error{reset, reset, reset}
+	assert.ErrorIs(t, s.Put(ctx, "a", strings.NewReader("value")), reset)
+	assert.Equal(t, RetryStats{Retries: 4, GaveUp: 1}, s.Stats())
+}
+
+func TestRetryObjectStoreConditionalWrites(t *testing.T) {
+	ctx := context.Background()
+	failing := &failingObjectStore{ObjectStore: NewBucketObjectStore(objstore.NewInMemBucket())}
+	s := NewRetryObjectStore(failing, retryOptions(), nil)
+
+	// A failed conditional write may have written the object, so it is retried
+	// only if the object store throttled it
+	reset := errors.New("connection reset by peer")
+	failing.errs = []error{reset}
+	assert.ErrorIs(t, s.PutIfNotExists(ctx, "a", bytes.NewReader([]byte("value"))), reset)
+	assert.Equal(t, int64(0), s.Stats().Retries)
+
+	failing.errs = []error{responseError{status: 503, code: "SlowDown"}}
+	require.NoError(t, s.PutIfNotExists(ctx, "a", bytes.NewReader([]byte("value"))))
+	assert.Equal(t, int64(1), s.Stats().Retries)
+	assert.ErrorIs(t, s.PutIfNotExists(ctx, "a", bytes.NewReader([]byte("value"))), common.ErrObjectExists)
+	assert.Equal(t, int64(1), s.Stats().Retries)
+}
+
+func TestRetryObjectStoreTimeoutAndBudget(t *testing.T) {
+	ctx := context.Background()
+	failing := &failingObjectStore{ObjectStore: NewBucketObjectStore(objstore.NewInMemBucket())}
+	opts := retryOptions()
+	opts.OperationTimeout = 10 * time.Millisecond
+	opts.RetryBudget = 2
+	s := NewRetryObjectStore(failing, opts, nil)
+
+	// An attempt which times out is retried
+	failing.errs = []error{context.DeadlineExceeded}
+	require.NoError(t, s.Put(ctx, "a", strings.NewReader("value")))
+	assert.Equal(t, RetryStats{Retries: 1, Timeouts: 1}, s.Stats())
+
+	// The budget allows one more retry, as the success returned only part of one
+	reset := errors.New("connection reset by peer")
+	failing.errs = []error{reset, reset}
+	assert.ErrorIs(t, s.Put(ctx, "a", strings.NewReader("value")), reset)
+	assert.Equal(t, RetryStats{Retries: 2, Timeouts: 1, BudgetExhausted: 1}, s.Stats())
+
+	// A canceled request is not retried
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	failing.errs = []error{context.DeadlineExceeded}
+	assert.Error(t, s.Put(canceled, "a", strings.NewReader("value")))
+	assert.Equal(t, int64(2), s.Stats().Retries)
+}
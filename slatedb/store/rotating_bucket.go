@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/thanos-io/objstore"
+)
+
+// RotatingBucket is an objstore.Bucket which delegates every request to a bucket
+// which can be replaced while in use, such as a bucket created with new credentials
+// once the previous credentials expire. Requests in flight complete against the
+// bucket they started with.
+type RotatingBucket struct {
+	mu      sync.RWMutex
+	current *rotatingBucketTarget
+}
+
+// rotatingBucketTarget is a bucket of the RotatingBucket and its requests in flight
+type rotatingBucketTarget struct {
+	objstore.Bucket
+	inFlight sync.WaitGroup
+}
+
+// NewRotatingBucket returns a RotatingBucket which delegates to the bucket. A
+// RotatingBucket is returned as is.
+func NewRotatingBucket(bucket objstore.Bucket) *RotatingBucket {
+	if b, ok := bucket.(*RotatingBucket); ok {
+		return b
+	}
+	return &RotatingBucket{current: &rotatingBucketTarget{Bucket: bucket}}
+}
+
+// Rotate replaces the bucket which new requests are made to, and waits for the
+// requests in flight to the previous bucket to complete, which includes closing
+// the readers returned by Get and GetRange. The previous bucket is returned, for
+// the caller to close. If ctx is done before the requests complete, the bucket is
+// still replaced and ctx.Err() is returned along with the previous bucket.
+func (b *RotatingBucket) Rotate(ctx context.Context, bucket objstore.Bucket) (objstore.Bucket, error) {
+	b.mu.Lock()
+	previous := b.current
+	b.current = &rotatingBucketTarget{Bucket: bucket}
+	b.mu.Unlock()
+
+	// No request is added to the previous target once it is replaced
+	done := make(chan struct{})
+	go func() {
+		previous.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return previous.Bucket, nil
+	case <-ctx.Done():
+		return previous.Bucket, ctx.Err()
+	}
+}
+
+// acquire returns the current bucket, which the caller must release once the
+// request completes
+func (b *RotatingBucket) acquire() *rotatingBucketTarget {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.current.inFlight.Add(1)
+	return b.current
+}
+
+func (b *RotatingBucket) Close() error {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.Close()
+}
+
+func (b *RotatingBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.Iter(ctx, dir, f, options...)
+}
+
+func (b *RotatingBucket) IterWithAttributes(ctx context.Context, dir string,
+	f func(objstore.IterObjectAttributes) error, options ...objstore.IterOption) error {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.IterWithAttributes(ctx, dir, f, options...)
+}
+
+func (b *RotatingBucket) SupportedIterOptions() []objstore.IterOptionType {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.SupportedIterOptions()
+}
+
+func (b *RotatingBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	t := b.acquire()
+	r, err := t.Get(ctx, name)
+	if err != nil {
+		t.inFlight.Done()
+		return nil, err
+	}
+	return &releasingReadCloser{ReadCloser: r, target: t}, nil
+}
+
+func (b *RotatingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	t := b.acquire()
+	r, err := t.GetRange(ctx, name, off, length)
+	if err != nil {
+		t.inFlight.Done()
+		return nil, err
+	}
+	return &releasingReadCloser{ReadCloser: r, target: t}, nil
+}
+
+func (b *RotatingBucket) Exists(ctx context.Context, name string) (bool, error) {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.Exists(ctx, name)
+}
+
+func (b *RotatingBucket) IsObjNotFoundErr(err error) bool {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.IsObjNotFoundErr(err)
+}
+
+func (b *RotatingBucket) IsAccessDeniedErr(err error) bool {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.IsAccessDeniedErr(err)
+}
+
+func (b *RotatingBucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.Attributes(ctx, name)
+}
+
+func (b *RotatingBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.Upload(ctx, name, r)
+}
+
+func (b *RotatingBucket) Delete(ctx context.Context, name string) error {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.Delete(ctx, name)
+}
+
+func (b *RotatingBucket) Name() string {
+	t := b.acquire()
+	defer t.inFlight.Done()
+	return t.Name()
+}
+
+// releasingReadCloser completes the request to the target once the reader is closed
+type releasingReadCloser struct {
+	io.ReadCloser
+	target *rotatingBucketTarget
+	once   sync.Once
+}
+
+func (r *releasingReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.target.inFlight.Done)
+	return err
+}
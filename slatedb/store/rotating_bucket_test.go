@@ -0,0 +1,47 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestRotatingBucket(t *testing.T) {
+	ctx := context.Background()
+	previous := objstore.NewInMemBucket()
+	bucket := NewRotatingBucket(previous)
+	assert.Same(t, bucket, NewRotatingBucket(bucket))
+	require.NoError(t, bucket.Upload(ctx, "obj", bytes.NewReader([]byte("data"))))
+
+	// A reader of the previous bucket holds the rotation until it is closed
+	reader, err := bucket.Get(ctx, "obj")
+	require.NoError(t, err)
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	replacement := objstore.NewInMemBucket()
+	got, err := bucket.Rotate(timeout, replacement)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Same(t, previous, got)
+
+	// New requests are made to the replacement
+	exists, err := bucket.Exists(ctx, "obj")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+	require.NoError(t, reader.Close())
+	require.NoError(t, reader.Close())
+
+	got, err = bucket.Rotate(ctx, objstore.NewInMemBucket())
+	require.NoError(t, err)
+	assert.Same(t, replacement, got)
+}
@@ -0,0 +1,374 @@
+// Package s3store implements store.ObjectStore against Amazon S3 and the object
+// stores compatible with it, such as MinIO, with the AWS SDK. Open a DB with the
+// ObjectStore using slatedb.OpenWithObjectStore.
+//
+// Blocks are read with range GETs, objects larger than Options.PartSize are
+// uploaded in parts, and PutIfNotExists is a conditional PUT with If-None-Match,
+// which makes the fencing of writers and the updates of the manifest atomic.
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+const (
+	// DefaultPartSize is the default size of the parts of a multipart upload
+	DefaultPartSize = 8 * 1024 * 1024
+
+	// MinPartSize is the smallest part S3 accepts, except for the last part
+	MinPartSize = 5 * 1024 * 1024
+
+	// DefaultUploadConcurrency is the default number of parts of an object
+	// uploaded at the same time
+	DefaultUploadConcurrency = 4
+)
+
+// Options configures the ObjectStore
+type Options struct {
+	// The size of the parts of a multipart upload. Objects no larger than the
+	// part size are uploaded with a single PUT. Defaults to DefaultPartSize, and
+	// must be at least MinPartSize.
+	PartSize int64
+
+	// The number of parts of an object uploaded at the same time, each of which
+	// holds a buffer of PartSize. Defaults to DefaultUploadConcurrency.
+	UploadConcurrency int
+}
+
+// ClientConfig configures the S3 client returned by NewClient
+type ClientConfig struct {
+	// Region is the region of the bucket. If empty, the region of the AWS
+	// configuration of the environment is used.
+	Region string
+
+	// Endpoint replaces the endpoint of S3, for S3 compatible object stores such
+	// as MinIO, e.g. "http://localhost:9000"
+	Endpoint string
+
+	// UsePathStyle addresses buckets by path rather than by host name, which
+	// most S3 compatible object stores require
+	UsePathStyle bool
+
+	// AccessKeyID and SecretAccessKey are static credentials. If empty, the
+	// credentials are provided by the default chain of the AWS SDK, which covers
+	// IRSA web identity tokens, ECS task roles and EC2 instance profiles, and
+	// refreshes them as they expire.
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// NewClient returns an S3 client configured from the environment and the config
+func NewClient(ctx context.Context, conf ClientConfig) (*s3.Client, error) {
+	var loadOptions []func(*config.LoadOptions) error
+	if conf.Region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(conf.Region))
+	}
+	if conf.AccessKeyID != "" {
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(conf.AccessKeyID, conf.SecretAccessKey, "")))
+	}
+	awsConf, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("while loading AWS config: %w", err)
+	}
+	return s3.NewFromConfig(awsConf, func(o *s3.Options) {
+		if conf.Endpoint != "" {
+			o.BaseEndpoint = aws.String(conf.Endpoint)
+		}
+		o.UsePathStyle = conf.UsePathStyle
+	}), nil
+}
+
+// ObjectStore is a store.ObjectStore which reads and writes the objects of an S3
+// bucket, at the keys of the paths of the objects
+type ObjectStore struct {
+	client *s3.Client
+	bucket string
+	opts   Options
+}
+
+var _ store.ObjectStore = (*ObjectStore)(nil)
+
+// NewObjectStore returns an ObjectStore which reads and writes the objects of
+// the bucket through the client
+func NewObjectStore(client *s3.Client, bucket string, opts Options) (*ObjectStore, error) {
+	if opts.PartSize == 0 {
+		opts.PartSize = DefaultPartSize
+	}
+	if opts.PartSize < MinPartSize {
+		return nil, fmt.Errorf("%w: part size must be at least %d bytes", common.ErrInvalidOptions, MinPartSize)
+	}
+	if opts.UploadConcurrency <= 0 {
+		opts.UploadConcurrency = DefaultUploadConcurrency
+	}
+	return &ObjectStore{client: client, bucket: bucket, opts: opts}, nil
+}
+
+func (s *ObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	return s.put(ctx, path, r, nil)
+}
+
+func (s *ObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	return s.put(ctx, path, r, aws.String("*"))
+}
+
+func (s *ObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return out.Body, nil
+}
+
+// GetRange returns length bytes of the object from the offset off, or the rest
+// of the object if length is negative
+func (s *ObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	rng := fmt.Sprintf("bytes=%d-", off)
+	if length > 0 {
+		rng = fmt.Sprintf("bytes=%d-%d", off, off+length-1)
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+		Range:  aws.String(rng),
+	})
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+	return out.Body, nil
+}
+
+func (s *ObjectStore) Head(ctx context.Context, path string) (store.ObjectMeta, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return store.ObjectMeta{}, wrapNotFound(err)
+	}
+	return store.ObjectMeta{
+		LastModified: aws.ToTime(out.LastModified),
+		Location:     path,
+		Size:         aws.ToInt64(out.ContentLength),
+	}, nil
+}
+
+// List returns the metadata of every object under the prefix directory, along
+// with the time each object was last modified and its size
+func (s *ObjectStore) List(ctx context.Context, prefix string) ([]store.ObjectMeta, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	objMetaList := make([]store.ObjectMeta, 0)
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			objMetaList = append(objMetaList, store.ObjectMeta{
+				LastModified: aws.ToTime(obj.LastModified),
+				Location:     aws.ToString(obj.Key),
+				Size:         aws.ToInt64(obj.Size),
+			})
+		}
+	}
+	return objMetaList, nil
+}
+
+func (s *ObjectStore) Delete(ctx context.Context, path string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	return err
+}
+
+// put uploads the object with a single PUT if it fits in a part, and in parts
+// otherwise. If ifNoneMatch is set, the object is only written if no object
+// exists at the path.
+func (s *ObjectStore) put(ctx context.Context, path string, r io.Reader, ifNoneMatch *string) error {
+	part, last, err := readPart(r, s.opts.PartSize)
+	if err != nil {
+		return fmt.Errorf("while reading object: %w", err)
+	}
+	if !last {
+		return s.putMultipart(ctx, path, part, r, ifNoneMatch)
+	}
+
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(path),
+		Body:          bytes.NewReader(part),
+		ContentLength: aws.Int64(int64(len(part))),
+		IfNoneMatch:   ifNoneMatch,
+	})
+	return wrapPreconditionFailed(err)
+}
+
+// putMultipart uploads the object in parts, the first of which was already read.
+// The object is only created once every part was uploaded, so an object which
+// fails to upload is never visible. The precondition is evaluated when the
+// upload completes.
+func (s *ObjectStore) putMultipart(ctx context.Context, path string, first []byte, r io.Reader, ifNoneMatch *string) error {
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return err
+	}
+	uploadID := created.UploadId
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		parts    []types.CompletedPart
+		errs     []error
+		inFlight = make(chan struct{}, s.opts.UploadConcurrency)
+	)
+	uploadPart := func(number int32, data []byte) {
+		defer func() {
+			<-inFlight
+			wg.Done()
+		}()
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(s.bucket),
+			Key:           aws.String(path),
+			UploadId:      uploadID,
+			PartNumber:    aws.Int32(number),
+			Body:          bytes.NewReader(data),
+			ContentLength: aws.Int64(int64(len(data))),
+		})
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("while uploading part %d: %w", number, err))
+			return
+		}
+		parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(number)})
+	}
+
+	part, last := first, false
+	for number := int32(1); ; number++ {
+		inFlight <- struct{}{}
+		wg.Add(1)
+		go uploadPart(number, part)
+		if last {
+			break
+		}
+		part, last, err = readPart(r, s.opts.PartSize)
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("while reading object: %w", err))
+			mu.Unlock()
+			break
+		}
+		if len(part) == 0 {
+			break
+		}
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return errors.Join(err, s.abortMultipart(ctx, path, uploadID))
+	}
+	slices.SortFunc(parts, func(a, b types.CompletedPart) int {
+		return int(aws.ToInt32(a.PartNumber) - aws.ToInt32(b.PartNumber))
+	})
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(path),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		IfNoneMatch:     ifNoneMatch,
+	})
+	if err != nil {
+		return errors.Join(wrapPreconditionFailed(err), s.abortMultipart(ctx, path, uploadID))
+	}
+	return nil
+}
+
+// abortMultipart aborts the upload so that the parts already uploaded are not
+// billed, even if the context of the upload was canceled
+func (s *ObjectStore) abortMultipart(ctx context.Context, path string, uploadID *string) error {
+	_, err := s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(path),
+		UploadId: uploadID,
+	})
+	if err != nil {
+		return fmt.Errorf("while aborting multipart upload: %w", err)
+	}
+	return nil
+}
+
+// readPart reads up to size bytes from r, and returns true if r has no more bytes
+// after them
+func readPart(r io.Reader, size int64) ([]byte, bool, error) {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return buf[:n], true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return buf, false, nil
+}
+
+// wrapNotFound wraps err with common.ErrObjectNotFound if S3 reports that the
+// object does not exist, which HEAD requests report by status code alone
+func wrapNotFound(err error) error {
+	if statusCode(err) == http.StatusNotFound {
+		return fmt.Errorf("%w: %w", common.ErrObjectNotFound, err)
+	}
+	return err
+}
+
+// wrapPreconditionFailed returns common.ErrObjectExists if a conditional write
+// failed because an object exists at the path
+func wrapPreconditionFailed(err error) error {
+	if statusCode(err) == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: %w", common.ErrObjectExists, err)
+	}
+	return err
+}
+
+// statusCode returns the HTTP status code of the response which failed with err,
+// or zero if there was none
+func statusCode(err error) int {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
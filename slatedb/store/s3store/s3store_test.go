@@ -0,0 +1,131 @@
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slatedb/slatedb-go/slatedb"
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
+	"github.com/slatedb/slatedb-go/slatedb/store/storetest"
+)
+
+func TestReadPart(t *testing.T) {
+	part, last, err := readPart(strings.NewReader("abcdef"), 4)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("abcd"), part)
+	assert.False(t, last)
+
+	part, last, err = readPart(strings.NewReader("ab"), 4)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ab"), part)
+	assert.True(t, last)
+
+	part, last, err = readPart(strings.NewReader(""), 4)
+	require.NoError(t, err)
+	assert.Empty(t, part)
+	assert.True(t, last)
+
+	_, _, err = readPart(io.MultiReader(strings.NewReader("ab"), errReader{}), 4)
+	assert.Error(t, err)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+// testObjectStore returns an ObjectStore of a bucket of the S3 compatible object
+// store at SLATEDB_S3_ENDPOINT, such as MinIO started by make test_s3, and skips
+// the test if it is not set. Each test writes under a prefix of its own.
+func testObjectStore(t *testing.T) (*ObjectStore, string) {
+	endpoint := os.Getenv("SLATEDB_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("SLATEDB_S3_ENDPOINT is not set")
+	}
+	ctx := context.Background()
+	client, err := NewClient(ctx, ClientConfig{
+		Region:          "us-east-1",
+		Endpoint:        endpoint,
+		UsePathStyle:    true,
+		AccessKeyID:     envOr("SLATEDB_S3_ACCESS_KEY_ID", "minioadmin"),
+		SecretAccessKey: envOr("SLATEDB_S3_SECRET_ACCESS_KEY", "minioadmin"),
+	})
+	require.NoError(t, err)
+
+	bucket := envOr("SLATEDB_S3_BUCKET", "slatedb-test")
+	_, err = client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(bucket)})
+	if err != nil && !strings.Contains(err.Error(), "BucketAlreadyOwnedByYou") {
+		require.NoError(t, err)
+	}
+	objectStore, err := NewObjectStore(client, bucket, Options{PartSize: MinPartSize})
+	require.NoError(t, err)
+	return objectStore, fmt.Sprintf("%s/%d", t.Name(), time.Now().UnixNano())
+}
+
+func envOr(key, value string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return value
+}
+
+func TestObjectStore(t *testing.T) {
+	objectStore, prefix := testObjectStore(t)
+	storetest.TestObjectStore(t, objectStore, prefix)
+}
+
+func TestMultipartPutIfNotExists(t *testing.T) {
+	objectStore, prefix := testObjectStore(t)
+	ctx := context.Background()
+
+	// The precondition of a multipart upload is evaluated as it completes
+	large := make([]byte, 2*MinPartSize+1024)
+	_, err := rand.Read(large)
+	require.NoError(t, err)
+	path := prefix + "/large"
+	require.NoError(t, objectStore.PutIfNotExists(ctx, path, bytes.NewReader(large)))
+	err = objectStore.PutIfNotExists(ctx, path, bytes.NewReader(large))
+	assert.ErrorIs(t, err, common.ErrObjectExists)
+
+	r, err := objectStore.Get(ctx, path)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, large, data)
+	require.NoError(t, r.Close())
+}
+
+func TestOpenDB(t *testing.T) {
+	objectStore, prefix := testObjectStore(t)
+	ctx := context.Background()
+	options := config.DefaultDBOptions()
+	options.DisableBackgroundTasks = true
+
+	db, err := slatedb.OpenWithObjectStore(ctx, prefix, objectStore, options)
+	require.NoError(t, err)
+	require.NoError(t, db.PutWithOptions(ctx, []byte("key1"), []byte("value1"), config.WriteOptions{AwaitDurable: true}))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	require.NoError(t, db.Close())
+
+	db, err = slatedb.OpenWithObjectStore(ctx, prefix, objectStore, options)
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+}
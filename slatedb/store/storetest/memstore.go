@@ -0,0 +1,403 @@
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+var (
+	// ErrInjected is the error of an injected fault whose Err is not set
+	ErrInjected = errors.New("injected fault")
+
+	// ErrThrottled is an error which store.IsThrottledErr detects as a request
+	// throttled by the object store
+	ErrThrottled = fmt.Errorf("SlowDown: Please reduce your request rate: %w", common.ErrThrottled)
+)
+
+// Op is a request of an ObjectStore
+type Op int
+
+const (
+	// OpAny matches every request
+	OpAny Op = iota
+	OpPut
+	OpPutIfNotExists
+	OpGet
+	OpGetRange
+	OpHead
+	OpList
+	OpDelete
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpAny:
+		return "any"
+	case OpPut:
+		return "put"
+	case OpPutIfNotExists:
+		return "put_if_not_exists"
+	case OpGet:
+		return "get"
+	case OpGetRange:
+		return "get_range"
+	case OpHead:
+		return "head"
+	case OpList:
+		return "list"
+	case OpDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("Op(%d)", int(o))
+}
+
+// isRead returns true if the request returns the contents of an object
+func (o Op) isRead() bool {
+	return o == OpGet || o == OpGetRange
+}
+
+// FaultKind is how a fault affects the requests it is injected into
+type FaultKind int
+
+const (
+	// FaultDelay only delays the request by Fault.Latency
+	FaultDelay FaultKind = iota
+
+	// FaultFail fails the request with Fault.Err before it is applied
+	FaultFail
+
+	// FaultLostResponse applies a write and then fails it with Fault.Err, as if
+	// the response of the object store was lost. Other requests are failed like
+	// FaultFail.
+	FaultLostResponse
+
+	// FaultPartialRead returns a reader of a read which fails with Fault.Err once
+	// Fault.ReadBytes bytes of the object were read, as if the connection was
+	// reset while streaming the object. Other requests are not affected.
+	FaultPartialRead
+)
+
+// Fault describes a fault injected into the requests of a MemObjectStore
+type Fault struct {
+	// Kind is how the fault affects the requests it is injected into
+	Kind FaultKind
+
+	// Op restricts the fault to the requests of the Op, every request if OpAny
+	Op Op
+
+	// Path restricts the fault to the requests whose path contains Path, such as
+	// "manifest/" or ".sst", every request if empty. The prefix of List requests
+	// is matched as their path.
+	Path string
+
+	// Count is the number of requests the fault is injected into, after which it
+	// is removed. Zero injects the fault into every matching request until the
+	// faults are cleared.
+	Count int
+
+	// Latency delays each request the fault is injected into, unless the context
+	// of the request is canceled first. Any kind of fault may have a latency.
+	Latency time.Duration
+
+	// Err is the error returned by the requests the fault fails, ErrInjected if
+	// nil. Use ErrThrottled to simulate a throttling object store.
+	Err error
+
+	// ReadBytes is the number of bytes a FaultPartialRead returns before it fails
+	ReadBytes int64
+}
+
+// matches returns true if the fault applies to the request
+func (f *Fault) matches(op Op, path string) bool {
+	if f.Op != OpAny && f.Op != op {
+		return false
+	}
+	if f.Kind == FaultPartialRead && !op.isRead() {
+		return false
+	}
+	return strings.Contains(path, f.Path)
+}
+
+func (f *Fault) err(op Op, path string) error {
+	err := f.Err
+	if err == nil {
+		err = ErrInjected
+	}
+	return fmt.Errorf("%s '%s': %w", op, path, err)
+}
+
+type memObject struct {
+	data         []byte
+	lastModified time.Time
+}
+
+// MemObjectStore is a store.ObjectStore which keeps the objects in memory, and
+// injects faults into its requests, so tests can deterministically exercise how
+// the DB recovers from a slow, throttling or unreliable object store.
+// PutIfNotExists is atomic. It is safe for concurrent use.
+type MemObjectStore struct {
+	mu       sync.Mutex
+	objects  map[string]memObject
+	faults   []*Fault
+	requests map[Op]int64
+	injected int64
+}
+
+var _ store.ObjectStore = (*MemObjectStore)(nil)
+
+// NewMemObjectStore returns an empty MemObjectStore without faults
+func NewMemObjectStore() *MemObjectStore {
+	return &MemObjectStore{
+		objects:  make(map[string]memObject),
+		requests: make(map[Op]int64),
+	}
+}
+
+// Inject adds the fault, which is injected into the matching requests after the
+// faults already injected. Every matching fault is injected into a request, their
+// latencies add up, and the first fault which fails the request determines its
+// error.
+func (s *MemObjectStore) Inject(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes every fault
+func (s *MemObjectStore) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the number of requests of the Op, or of every request if OpAny
+func (s *MemObjectStore) Requests(op Op) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if op == OpAny {
+		var total int64
+		for _, n := range s.requests {
+			total += n
+		}
+		return total
+	}
+	return s.requests[op]
+}
+
+// Injected returns the number of faults injected into requests, which counts
+// each fault injected into a request once
+func (s *MemObjectStore) Injected() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.injected
+}
+
+func (s *MemObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	return s.put(ctx, OpPut, path, r)
+}
+
+func (s *MemObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	return s.put(ctx, OpPutIfNotExists, path, r)
+}
+
+func (s *MemObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	return s.read(ctx, OpGet, path, 0, -1)
+}
+
+// GetRange returns length bytes of the object from the offset off, or the rest
+// of the object if length is negative
+func (s *MemObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	return s.read(ctx, OpGetRange, path, off, length)
+}
+
+func (s *MemObjectStore) Head(ctx context.Context, path string) (store.ObjectMeta, error) {
+	fault, err := s.request(ctx, OpHead, path)
+	if err != nil {
+		return store.ObjectMeta{}, err
+	}
+	if fault != nil {
+		return store.ObjectMeta{}, fault.err(OpHead, path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[path]
+	if !ok {
+		return store.ObjectMeta{}, fmt.Errorf("%w: %s", common.ErrObjectNotFound, path)
+	}
+	return store.ObjectMeta{LastModified: obj.lastModified, Location: path, Size: int64(len(obj.data))}, nil
+}
+
+// List returns the metadata of every object under the prefix directory, along
+// with the time each object was last modified and its size, ordered by path
+func (s *MemObjectStore) List(ctx context.Context, prefix string) ([]store.ObjectMeta, error) {
+	fault, err := s.request(ctx, OpList, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if fault != nil {
+		return nil, fault.err(OpList, prefix)
+	}
+
+	dir := prefix
+	if dir != "" && !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objMetaList := make([]store.ObjectMeta, 0)
+	for path, obj := range s.objects {
+		if strings.HasPrefix(path, dir) {
+			objMetaList = append(objMetaList, store.ObjectMeta{
+				LastModified: obj.lastModified,
+				Location:     path,
+				Size:         int64(len(obj.data)),
+			})
+		}
+	}
+	sort.Slice(objMetaList, func(i, j int) bool {
+		return objMetaList[i].Location < objMetaList[j].Location
+	})
+	return objMetaList, nil
+}
+
+func (s *MemObjectStore) Delete(ctx context.Context, path string) error {
+	fault, err := s.request(ctx, OpDelete, path)
+	if err != nil {
+		return err
+	}
+	if fault != nil && fault.Kind == FaultFail {
+		return fault.err(OpDelete, path)
+	}
+
+	s.mu.Lock()
+	delete(s.objects, path)
+	s.mu.Unlock()
+	if fault != nil {
+		return fault.err(OpDelete, path)
+	}
+	return nil
+}
+
+func (s *MemObjectStore) put(ctx context.Context, op Op, path string, r io.Reader) error {
+	// The object is read before the request, as a client uploads it
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("while reading object: %w", err)
+	}
+	fault, err := s.request(ctx, op, path)
+	if err != nil {
+		return err
+	}
+	if fault != nil && fault.Kind == FaultFail {
+		return fault.err(op, path)
+	}
+
+	s.mu.Lock()
+	_, exists := s.objects[path]
+	if op == OpPutIfNotExists && exists {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", common.ErrObjectExists, path)
+	}
+	s.objects[path] = memObject{data: data, lastModified: time.Now()}
+	s.mu.Unlock()
+
+	if fault != nil {
+		return fault.err(op, path)
+	}
+	return nil
+}
+
+func (s *MemObjectStore) read(ctx context.Context, op Op, path string, off, length int64) (io.ReadCloser, error) {
+	fault, err := s.request(ctx, op, path)
+	if err != nil {
+		return nil, err
+	}
+	if fault != nil && fault.Kind != FaultPartialRead {
+		return nil, fault.err(op, path)
+	}
+
+	s.mu.Lock()
+	obj, ok := s.objects[path]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", common.ErrObjectNotFound, path)
+	}
+	size := int64(len(obj.data))
+	off = min(off, size)
+	end := size
+	if length >= 0 {
+		end = min(off+length, size)
+	}
+	// Objects are never modified, as each write replaces the slice of the object
+	data := obj.data[off:end]
+
+	if fault != nil {
+		return io.NopCloser(io.MultiReader(
+			bytes.NewReader(data[:min(max(fault.ReadBytes, 0), int64(len(data)))]),
+			errorReader{err: fault.err(op, path)},
+		)), nil
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// request counts the request and injects the matching faults into it. It returns
+// the first fault which affects the outcome of the request, if any, or the error
+// of the context if it was canceled while the request was delayed.
+func (s *MemObjectStore) request(ctx context.Context, op Op, path string) (*Fault, error) {
+	s.mu.Lock()
+	s.requests[op]++
+	var (
+		latency time.Duration
+		failure *Fault
+	)
+	faults := s.faults[:0]
+	for _, fault := range s.faults {
+		if fault.matches(op, path) {
+			s.injected++
+			latency += fault.Latency
+			if failure == nil && fault.Kind != FaultDelay {
+				failure = fault
+			}
+			if fault.Count > 0 {
+				fault.Count--
+				if fault.Count == 0 {
+					continue
+				}
+			}
+		}
+		faults = append(faults, fault)
+	}
+	s.faults = faults
+	s.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return failure, nil
+}
+
+// errorReader fails every read with err
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,114 @@
+// Package storetest verifies that an implementation of store.ObjectStore has the
+// semantics the DB relies on, so every backend behaves the same. It also provides
+// MemObjectStore, an in-memory ObjectStore which injects faults into its requests
+// for tests of how the DB recovers from them.
+package storetest
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// TestObjectStore runs the conformance tests of the ObjectStore, which write
+// under the prefix. The prefix must hold no objects.
+func TestObjectStore(t *testing.T, objectStore store.ObjectStore, prefix string) {
+	t.Run("NotFound", func(t *testing.T) {
+		testNotFound(t, objectStore, prefix+"/not_found")
+	})
+	t.Run("ReadWrite", func(t *testing.T) {
+		testReadWrite(t, objectStore, prefix+"/read_write")
+	})
+	t.Run("List", func(t *testing.T) {
+		testList(t, objectStore, prefix+"/list")
+	})
+	t.Run("PutIfNotExists", func(t *testing.T) {
+		testPutIfNotExists(t, objectStore, prefix+"/put_if_not_exists")
+	})
+}
+
+func testNotFound(t *testing.T, objectStore store.ObjectStore, prefix string) {
+	ctx := context.Background()
+	_, err := objectStore.Head(ctx, prefix+"/obj")
+	assert.ErrorIs(t, err, common.ErrObjectNotFound)
+	_, err = objectStore.Get(ctx, prefix+"/obj")
+	assert.ErrorIs(t, err, common.ErrObjectNotFound)
+	_, err = objectStore.GetRange(ctx, prefix+"/obj", 0, 1)
+	assert.ErrorIs(t, err, common.ErrObjectNotFound)
+}
+
+func testReadWrite(t *testing.T, objectStore store.ObjectStore, prefix string) {
+	ctx := context.Background()
+	read := reader(t)
+	path := prefix + "/obj"
+	require.NoError(t, objectStore.Put(ctx, path, strings.NewReader("0123456789")))
+
+	meta, err := objectStore.Head(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), meta.Size)
+	assert.False(t, meta.LastModified.IsZero())
+
+	assert.Equal(t, "0123456789", read(objectStore.Get(ctx, path)))
+	assert.Equal(t, "23456", read(objectStore.GetRange(ctx, path, 2, 5)))
+	// A negative length reads the rest of the object
+	assert.Equal(t, "89", read(objectStore.GetRange(ctx, path, 8, -1)))
+
+	// Put replaces the object
+	require.NoError(t, objectStore.Put(ctx, path, strings.NewReader("abc")))
+	assert.Equal(t, "abc", read(objectStore.Get(ctx, path)))
+
+	require.NoError(t, objectStore.Delete(ctx, path))
+	_, err = objectStore.Head(ctx, path)
+	assert.ErrorIs(t, err, common.ErrObjectNotFound)
+}
+
+func testList(t *testing.T, objectStore store.ObjectStore, prefix string) {
+	ctx := context.Background()
+	// The prefix is a directory, which excludes the objects of a sibling whose
+	// name starts with the name of the directory
+	paths := []string{prefix + "/dir/a", prefix + "/dir/nested/b", prefix + "/dir2/c"}
+	for _, path := range paths {
+		require.NoError(t, objectStore.Put(ctx, path, strings.NewReader(path)))
+	}
+
+	list, err := objectStore.List(ctx, prefix+"/dir")
+	require.NoError(t, err)
+	var locations []string
+	for _, meta := range list {
+		locations = append(locations, meta.Location)
+	}
+	assert.ElementsMatch(t, paths[:2], locations)
+
+	list, err = objectStore.List(ctx, prefix+"/empty")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testPutIfNotExists(t *testing.T, objectStore store.ObjectStore, prefix string) {
+	ctx := context.Background()
+	read := reader(t)
+	path := prefix + "/obj"
+	require.NoError(t, objectStore.PutIfNotExists(ctx, path, strings.NewReader("first")))
+	err := objectStore.PutIfNotExists(ctx, path, strings.NewReader("second"))
+	assert.ErrorIs(t, err, common.ErrObjectExists)
+	assert.Equal(t, "first", read(objectStore.Get(ctx, path)))
+}
+
+// reader returns a function which returns the contents of the reader returned
+// by a read of the ObjectStore, which it closes
+func reader(t *testing.T) func(io.ReadCloser, error) string {
+	return func(r io.ReadCloser, err error) string {
+		require.NoError(t, err)
+		defer func() { _ = r.Close() }()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+}
@@ -0,0 +1,96 @@
+package storetest
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+func TestBucketObjectStore(t *testing.T) {
+	TestObjectStore(t, store.NewBucketObjectStore(objstore.NewInMemBucket()), "test")
+}
+
+func TestMemObjectStore(t *testing.T) {
+	TestObjectStore(t, NewMemObjectStore(), "test")
+}
+
+func TestMemObjectStoreFaults(t *testing.T) {
+	ctx := context.Background()
+	read := reader(t)
+	objectStore := NewMemObjectStore()
+	require.NoError(t, objectStore.Put(ctx, "dir/obj", strings.NewReader("0123456789")))
+
+	t.Run("Fail", func(t *testing.T) {
+		objectStore.Inject(Fault{Kind: FaultFail, Op: OpPut, Path: "dir/", Count: 1})
+		err := objectStore.Put(ctx, "dir/obj", strings.NewReader("replaced"))
+		assert.ErrorIs(t, err, ErrInjected)
+		assert.Equal(t, "0123456789", read(objectStore.Get(ctx, "dir/obj")))
+
+		// The fault was removed after Count requests
+		require.NoError(t, objectStore.Put(ctx, "other", strings.NewReader("data")))
+		require.NoError(t, objectStore.Delete(ctx, "other"))
+	})
+
+	t.Run("Throttled", func(t *testing.T) {
+		objectStore.Inject(Fault{Kind: FaultFail, Op: OpHead, Err: ErrThrottled})
+		_, err := objectStore.Head(ctx, "dir/obj")
+		assert.True(t, store.IsThrottledErr(err))
+		_, err = objectStore.Head(ctx, "dir/obj")
+		assert.True(t, store.IsThrottledErr(err))
+		objectStore.ClearFaults()
+		_, err = objectStore.Head(ctx, "dir/obj")
+		assert.NoError(t, err)
+	})
+
+	t.Run("LostResponse", func(t *testing.T) {
+		objectStore.Inject(Fault{Kind: FaultLostResponse, Count: 1})
+		err := objectStore.PutIfNotExists(ctx, "dir/lost", strings.NewReader("written"))
+		assert.ErrorIs(t, err, ErrInjected)
+		assert.Equal(t, "written", read(objectStore.Get(ctx, "dir/lost")))
+
+		// A retry finds the object written by the lost request
+		err = objectStore.PutIfNotExists(ctx, "dir/lost", strings.NewReader("written"))
+		assert.ErrorIs(t, err, common.ErrObjectExists)
+	})
+
+	t.Run("PartialRead", func(t *testing.T) {
+		objectStore.Inject(Fault{Kind: FaultPartialRead, Path: "dir/obj", Count: 1, ReadBytes: 3})
+		// Requests which don't read the object are not affected
+		_, err := objectStore.Head(ctx, "dir/obj")
+		require.NoError(t, err)
+
+		r, err := objectStore.GetRange(ctx, "dir/obj", 2, 5)
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		assert.ErrorIs(t, err, ErrInjected)
+		assert.Equal(t, "234", string(data))
+		assert.Equal(t, "23456", read(objectStore.GetRange(ctx, "dir/obj", 2, 5)))
+	})
+
+	t.Run("Latency", func(t *testing.T) {
+		objectStore.Inject(Fault{Kind: FaultDelay, Op: OpList, Latency: 20 * time.Millisecond, Count: 1})
+		start := time.Now()
+		_, err := objectStore.List(ctx, "dir")
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+		// A request whose context is canceled while it is delayed fails
+		objectStore.Inject(Fault{Kind: FaultDelay, Latency: time.Hour, Count: 1})
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = objectStore.List(canceled, "dir")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	assert.Equal(t, int64(7), objectStore.Injected())
+	assert.Equal(t, int64(4), objectStore.Requests(OpHead))
+}
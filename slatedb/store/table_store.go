@@ -2,7 +2,9 @@ package store
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"path"
@@ -10,17 +12,21 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/slatedb/slatedb-go/internal/assert"
 
+	"github.com/kapetan-io/tackle/set"
 	"github.com/maypok86/otter"
 	"github.com/samber/mo"
 	"github.com/thanos-io/objstore"
 
 	"github.com/slatedb/slatedb-go/internal/sstable"
 	"github.com/slatedb/slatedb-go/internal/sstable/block"
-	"github.com/slatedb/slatedb-go/internal/sstable/bloom"
+	"github.com/slatedb/slatedb-go/internal/types"
 	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
 )
 
 // ------------------------------------------------
@@ -30,115 +36,604 @@ import (
 
 type TableStore struct {
 	mu            sync.RWMutex
-	bucket        objstore.Bucket
+	objectStore   ObjectStore
 	sstConfig     sstable.Config
 	rootPath      string
 	walPath       string
 	compactedPath string
-	filterCache   otter.Cache[sstable.ID, mo.Option[bloom.Filter]]
+	filterCache   otter.Cache[sstable.ID, mo.Option[sstable.Filter]]
+	dictCache     otter.Cache[sstable.ID, []byte]
+	rangeCache    otter.Cache[sstable.ID, types.RangeTombstones]
+	onCorruption  func(*common.CorruptionError)
+
+	// blockCache holds the blocks read from SSTables, nil if blocks are not cached
+	blockCache *blockCache
+
+	// indexCache holds the indexes and index partitions read from SSTables, nil
+	// if indexes are not cached
+	indexCache *indexCache
+
+	// blockFetches coalesces concurrent fetches of the same blocks, and is shared
+	// with clones
+	blockFetches *blockFetches
+
+	// filterBitsPerKey overrides sstConfig.FilterBitsPerKey, and is shared with
+	// clones so it can be changed while the DB is running.
+	filterBitsPerKey *atomic.Uint32
+
+	// ioStats counts the requests made through objectStore, and is shared with clones
+	ioStats *IOStats
+
+	// builders holds the sstable.Builders released after an SSTable is built, so
+	// their buffers are reused by the next SSTable. Shared with clones.
+	builders *sync.Pool
 }
 
 func NewTableStore(bucket objstore.Bucket, sstConfig sstable.Config, rootPath string) *TableStore {
-	cache, err := otter.MustBuilder[sstable.ID, mo.Option[bloom.Filter]](1000).Build()
+	return NewTableStoreWithObjectStore(NewBucketObjectStore(bucket), sstConfig, rootPath)
+}
+
+// NewTableStoreWithObjectStore returns a TableStore which reads and writes the
+// SSTables under rootPath through the ObjectStore
+func NewTableStoreWithObjectStore(objectStore ObjectStore, sstConfig sstable.Config, rootPath string) *TableStore {
+	cache, err := otter.MustBuilder[sstable.ID, mo.Option[sstable.Filter]](1000).Build()
 	assert.True(err == nil, "")
+	dictCache, err := otter.MustBuilder[sstable.ID, []byte](1000).Build()
+	assert.True(err == nil, "")
+	rangeCache, err := otter.MustBuilder[sstable.ID, types.RangeTombstones](1000).Build()
+	assert.True(err == nil, "")
+	filterBitsPerKey := &atomic.Uint32{}
+	filterBitsPerKey.Store(sstConfig.FilterBitsPerKey)
+	ioStats := &IOStats{}
 	return &TableStore{
-		bucket:        bucket,
-		sstConfig:     sstConfig,
-		rootPath:      rootPath,
-		walPath:       "wal",
-		compactedPath: "compacted",
-		filterCache:   cache,
+		objectStore:      newIOObjectStore(objectStore, ioStats, IOClassOther),
+		sstConfig:        sstConfig,
+		rootPath:         rootPath,
+		walPath:          DefaultLayout().WALPrefix,
+		compactedPath:    DefaultLayout().CompactedPrefix,
+		filterCache:      cache,
+		dictCache:        dictCache,
+		rangeCache:       rangeCache,
+		blockFetches:     newBlockFetches(),
+		filterBitsPerKey: filterBitsPerKey,
+		ioStats:          ioStats,
+		builders:         &sync.Pool{},
+	}
+}
+
+// WithIOClass returns a TableStore which shares the caches of this TableStore, and
+// attributes the object store requests it makes to the IOClass
+func (ts *TableStore) WithIOClass(class IOClass) *TableStore {
+	return ts.withObjectStore(newIOObjectStore(ts.objectStore, ts.ioStats, class))
+}
+
+// WithRateLimit returns a TableStore which shares the caches of this TableStore,
+// and limits the rate of the object store requests it makes, see
+// NewRateLimitObjectStore. The requests are attributed to the IOClass of this
+// TableStore.
+func (ts *TableStore) WithRateLimit(limits config.ObjectStoreRateLimits) *TableStore {
+	if !limits.Limited() {
+		return ts
+	}
+	classed, ok := ts.objectStore.(*ioObjectStore)
+	if !ok {
+		return ts.withObjectStore(NewRateLimitObjectStore(ts.objectStore, limits))
 	}
+	return ts.withObjectStore(&ioObjectStore{
+		ObjectStore: NewRateLimitObjectStore(classed.ObjectStore, limits),
+		counters:    classed.counters,
+	})
+}
+
+// withObjectStore returns a TableStore which shares the caches of this TableStore,
+// and makes its requests through the ObjectStore
+func (ts *TableStore) withObjectStore(objectStore ObjectStore) *TableStore {
+	return &TableStore{
+		objectStore:      objectStore,
+		sstConfig:        ts.sstConfig,
+		rootPath:         ts.rootPath,
+		walPath:          ts.walPath,
+		compactedPath:    ts.compactedPath,
+		filterCache:      ts.filterCache,
+		dictCache:        ts.dictCache,
+		rangeCache:       ts.rangeCache,
+		onCorruption:     ts.onCorruption,
+		blockCache:       ts.blockCache,
+		indexCache:       ts.indexCache,
+		blockFetches:     ts.blockFetches,
+		filterBitsPerKey: ts.filterBitsPerKey,
+		ioStats:          ts.ioStats,
+		builders:         ts.builders,
+	}
+}
+
+// IOStats returns the object store requests and bytes of the TableStore and its
+// clones for each IOClass
+func (ts *TableStore) IOStats() *IOStats {
+	return ts.ioStats
 }
 
 // Get list of WALs from object store that are not compacted (walID greater than walIDLastCompacted)
-func (ts *TableStore) GetWalSSTList(walIDLastCompacted uint64) ([]uint64, error) {
+func (ts *TableStore) GetWalSSTList(ctx context.Context, walIDLastCompacted uint64) ([]uint64, error) {
 	walList := make([]uint64, 0)
 	walPath := path.Join(ts.rootPath, ts.walPath)
 
-	err := ts.bucket.Iter(context.Background(), walPath, func(filepath string) error {
-		if strings.Contains(filepath, ".sst") {
-			walID, err := ts.parseID(filepath, ".sst")
+	objMetaList, err := ts.objectStore.List(ctx, walPath)
+	if err != nil {
+		return nil, fmt.Errorf("while iterating over the table list: %w", err)
+	}
+	for _, objMeta := range objMetaList {
+		if strings.Contains(objMeta.Location, ".sst") {
+			walID, err := ts.parseID(objMeta.Location, ".sst")
 			if err == nil && walID > walIDLastCompacted {
 				walList = append(walList, walID)
 			}
 		}
-		return nil
-	}, objstore.WithRecursiveIter())
-	if err != nil {
-		return nil, fmt.Errorf("while iterating over the table list: %w", err)
 	}
 
 	slices.Sort(walList)
 	return walList, nil
 }
 
-func (ts *TableStore) TableWriter(sstID sstable.ID) *EncodedSSTableWriter {
+// WALSSTMeta is the ID and the time of upload of a WAL SST in object storage
+type WALSSTMeta struct {
+	ID           uint64
+	LastModified time.Time
+}
+
+// ListWALSSTs returns the WAL SSTs in object storage with an ID up to maxID, in order
+// of ID. The time of upload is read from the attributes of each WAL SST if the object
+// store doesn't return it while listing.
+func (ts *TableStore) ListWALSSTs(ctx context.Context, maxID uint64) ([]WALSSTMeta, error) {
+	var walList []WALSSTMeta
+	walPath := path.Join(ts.rootPath, ts.walPath)
+
+	objMetaList, err := ts.objectStore.List(ctx, walPath)
+	if err != nil {
+		return nil, fmt.Errorf("while iterating over the WAL list: %w", err)
+	}
+	for _, objMeta := range objMetaList {
+		if !strings.Contains(objMeta.Location, ".sst") {
+			continue
+		}
+		walID, err := ts.parseID(objMeta.Location, ".sst")
+		if err != nil || walID > maxID {
+			continue
+		}
+		lastModified := objMeta.LastModified
+		if lastModified.IsZero() {
+			head, err := ts.objectStore.Head(ctx, objMeta.Location)
+			if err != nil {
+				return nil, fmt.Errorf("while reading attributes of WAL '%d': %w", walID, err)
+			}
+			lastModified = head.LastModified
+		}
+		walList = append(walList, WALSSTMeta{ID: walID, LastModified: lastModified})
+	}
+
+	slices.SortFunc(walList, func(a, b WALSSTMeta) int { return cmp.Compare(a.ID, b.ID) })
+	return walList, nil
+}
+
+func (ts *TableStore) TableWriter(ctx context.Context, sstID sstable.ID) *EncodedSSTableWriter {
+	return ts.TableWriterWithOptions(ctx, sstID, TableWriterOptions{})
+}
+
+// TableWriterOptions configures how an EncodedSSTableWriter uploads the SSTable
+type TableWriterOptions struct {
+	// PartSize is the number of bytes of encoded blocks buffered before they are
+	// streamed to object storage as a part of a multipart upload, which bounds the
+	// memory used to write large SSTables. Zero buffers the entire SSTable, which
+	// is uploaded when the writer is closed.
+	PartSize uint64
+
+	// SSTable overrides the options of the TableStore for the SSTable, such as
+	// the block size. Zero fields use the options of the TableStore.
+	SSTable config.SSTableOptions
+
+	// PipelineDepth is the number of blocks queued to be compressed and
+	// checksummed while the next blocks are built, see sstable.Config.EncodeQueue.
+	// If not zero, a part is also uploaded while the next part is built, so
+	// writing a large SSTable is limited by the slowest of the stages rather than
+	// their sum. Zero builds, encodes and uploads the SSTable sequentially.
+	PipelineDepth int
+}
+
+// TableWriterStats is the time an EncodedSSTableWriter spent in each stage of
+// writing an SSTable. The stages overlap if TableWriterOptions.PipelineDepth is
+// not zero, in which case their sum exceeds the Total time.
+type TableWriterStats struct {
+	// Build is the time spent adding entries, including the time blocked on
+	// later stages
+	Build time.Duration
+	// Encode is the time spent compressing and checksumming blocks
+	Encode time.Duration
+	// Upload is the time spent writing to object storage
+	Upload time.Duration
+	// Total is the time from the creation of the writer until it was closed
+	Total time.Duration
+}
+
+func (ts *TableStore) TableWriterWithOptions(ctx context.Context, sstID sstable.ID, opts TableWriterOptions) *EncodedSSTableWriter {
+	conf := ts.configWith(opts.SSTable)
+	conf.EncodeQueue = opts.PipelineDepth
 	return &EncodedSSTableWriter{
-		builder:       sstable.NewBuilder(ts.sstConfig),
+		ctx:           ctx,
+		builder:       ts.tableBuilder(conf),
 		sstID:         sstID,
 		tableStore:    ts,
+		partSize:      opts.PartSize,
+		pipelined:     opts.PipelineDepth > 0,
 		blocksWritten: 0,
+		start:         time.Now(),
 	}
 }
 
+// TableBuilder returns a builder for a new SSTable. The builder may be reused from
+// a previous SSTable, and should be returned with ReleaseTableBuilder once the
+// SSTable is built.
 func (ts *TableStore) TableBuilder() *sstable.Builder {
-	return sstable.NewBuilder(ts.sstConfig)
+	return ts.tableBuilder(ts.config())
+}
+
+// TableBuilderWithOptions returns a builder like TableBuilder, with the non-zero
+// fields of opts applied to the options of the TableStore
+func (ts *TableStore) TableBuilderWithOptions(opts config.SSTableOptions) *sstable.Builder {
+	return ts.tableBuilder(ts.configWith(opts))
+}
+
+// ReleaseTableBuilder returns a builder obtained from TableBuilder, so its buffers
+// can be reused by the next SSTable. The builder must not be used after it is released.
+func (ts *TableStore) ReleaseTableBuilder(builder *sstable.Builder) {
+	ts.builders.Put(builder)
+}
+
+func (ts *TableStore) tableBuilder(conf sstable.Config) *sstable.Builder {
+	if builder, ok := ts.builders.Get().(*sstable.Builder); ok {
+		builder.ResetWithConfig(conf)
+		return builder
+	}
+	return sstable.NewBuilder(conf)
+}
+
+// SetFilterBitsPerKey sets the bits per key of the bloom filter of SSTables built
+// after the call. SSTables already built keep the bits per key they were built with.
+func (ts *TableStore) SetFilterBitsPerKey(bitsPerKey uint32) {
+	ts.filterBitsPerKey.Store(bitsPerKey)
+}
+
+// config returns the sstable.Config used to build new SSTables
+func (ts *TableStore) config() sstable.Config {
+	conf := ts.sstConfig
+	conf.FilterBitsPerKey = ts.filterBitsPerKey.Load()
+	return conf
 }
 
-func (ts *TableStore) WriteSST(id sstable.ID, encodedSST *sstable.Table) (*sstable.Handle, error) {
+// configWith returns the sstable.Config used to build new SSTables, with the
+// non-zero fields of opts applied
+func (ts *TableStore) configWith(opts config.SSTableOptions) sstable.Config {
+	conf := ts.config()
+	set.Override(&conf.BlockSize, opts.BlockSize)
+	set.Override(&conf.BlockMaxEntries, opts.BlockMaxEntries)
+	set.Override(&conf.Compression, opts.CompressionCodec)
+	set.Override(&conf.FilterBitsPerKey, opts.FilterBitsPerKey)
+	set.Override(&conf.IndexPartitionThreshold, opts.IndexPartitionThreshold)
+	set.Override(&conf.ValueChecksums, opts.ValueChecksums)
+	return conf
+}
+
+func (ts *TableStore) WriteSST(ctx context.Context, id sstable.ID, encodedSST *sstable.Table) (*sstable.Handle, error) {
+	err := ts.objectStore.Put(ctx, ts.sstPath(id), bytes.NewReader(encodeBlocks(encodedSST)))
+	if err != nil {
+		return nil, fmt.Errorf("during object write: %w", err)
+	}
+
+	ts.cacheFilter(id, encodedSST.Filter)
+	return sstable.NewHandle(id, encodedSST.Info), nil
+}
+
+// WriteSSTIfNotExists writes the SSTable unless an object with its ID already
+// exists, in which case common.ErrObjectExists is returned. WAL SSTables are
+// written this way, so a writer which was fenced cannot overwrite the WAL written
+// with the same ID by the writer which fenced it.
+//
+// An existing object which holds the same bytes as the SSTable was written by an
+// earlier call whose response was lost, so the write succeeds, which makes
+// retrying a failed write idempotent.
+func (ts *TableStore) WriteSSTIfNotExists(ctx context.Context, id sstable.ID, encodedSST *sstable.Table) (*sstable.Handle, error) {
 	sstPath := ts.sstPath(id)
+	data := encodeBlocks(encodedSST)
+	err := ts.objectStore.PutIfNotExists(ctx, sstPath, bytes.NewReader(data))
+	if err == nil {
+		ts.cacheFilter(id, encodedSST.Filter)
+		return sstable.NewHandle(id, encodedSST.Info), nil
+	}
+	if !errors.Is(err, common.ErrObjectExists) {
+		return nil, fmt.Errorf("during object write: %w", err)
+	}
 
+	reader, err := ts.objectStore.Get(ctx, sstPath)
+	if err != nil {
+		return nil, fmt.Errorf("during object read: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+	existing, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("during object read: %w", err)
+	}
+	if !bytes.Equal(existing, data) {
+		return nil, common.ErrObjectExists
+	}
+	ts.cacheFilter(id, encodedSST.Filter)
+	return sstable.NewHandle(id, encodedSST.Info), nil
+}
+
+// WriteSSTInline returns a handle to the SSTable which holds the encoded SSTable,
+// such that it is stored inline in the manifest rather than uploaded as an object.
+func (ts *TableStore) WriteSSTInline(id sstable.ID, encodedSST *sstable.Table) *sstable.Handle {
+	ts.cacheFilter(id, encodedSST.Filter)
+	return sstable.NewInlineHandle(id, encodedSST.Info, encodeBlocks(encodedSST))
+}
+
+// encodeBlocks returns the blocks of the SSTable as a single slice
+func encodeBlocks(encodedSST *sstable.Table) []byte {
 	blocksData := make([]byte, 0)
 	for i := 0; i < encodedSST.Blocks.Len(); i++ {
 		blocksData = append(blocksData, encodedSST.Blocks.At(i)...)
 	}
+	return blocksData
+}
 
-	err := ts.bucket.Upload(context.Background(), sstPath, bytes.NewReader(blocksData))
+// WriteEncodedSST uploads an SSTable which has already been encoded, such as an
+// SSTable built outside the DB, along with the Info decoded from it.
+func (ts *TableStore) WriteEncodedSST(ctx context.Context, id sstable.ID, data []byte, info *sstable.Info) (*sstable.Handle, error) {
+	err := ts.objectStore.Put(ctx, ts.sstPath(id), bytes.NewReader(data))
 	if err != nil {
 		return nil, fmt.Errorf("during object write: %w", err)
 	}
-
-	ts.cacheFilter(id, encodedSST.Bloom)
-	return sstable.NewHandle(id, encodedSST.Info), nil
+	return sstable.NewHandle(id, info), nil
 }
 
-func (ts *TableStore) OpenSST(id sstable.ID) (*sstable.Handle, error) {
-	obj := ReadOnlyObject{ts.bucket, ts.sstPath(id)}
+func (ts *TableStore) OpenSST(ctx context.Context, id sstable.ID) (*sstable.Handle, error) {
+	obj := ReadOnlyObject{objectStore: ts.objectStore, path: ts.sstPath(id), ctx: ctx}
 	sstInfo, err := sstable.ReadInfo(obj)
 	if err != nil {
-		return nil, fmt.Errorf("while reading sst info: %w", err)
+		return nil, fmt.Errorf("while reading sst info: %w", ts.reportCorruption(id, err))
 	}
 
 	return sstable.NewHandle(id, sstInfo), nil
 }
 
-func (ts *TableStore) ReadBlocks(sstHandle *sstable.Handle, blocksRange common.Range) ([]block.Block, error) {
-	obj := ReadOnlyObject{ts.bucket, ts.sstPath(sstHandle.Id)}
-	index, err := sstable.ReadIndex(sstHandle.Info, obj)
+// DeleteSST removes the SSTable from object storage
+func (ts *TableStore) DeleteSST(ctx context.Context, id sstable.ID) error {
+	err := ts.objectStore.Delete(ctx, ts.sstPath(id))
+	if err != nil {
+		return fmt.Errorf("while deleting sst '%s': %w", id.Value, err)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.filterCache.Delete(id)
+	ts.dictCache.Delete(id)
+	ts.rangeCache.Delete(id)
+	ts.blockCache.deleteSST(id)
+	ts.indexCache.deleteSST(id)
+	return nil
+}
+
+// QuarantineSST moves a corrupt SSTable aside, to an object with the ".corrupt"
+// extension in place of ".sst", which is kept for inspection but no longer listed
+// as an SSTable. Returns the path of the quarantined object.
+func (ts *TableStore) QuarantineSST(ctx context.Context, id sstable.ID) (string, error) {
+	sstPath := ts.sstPath(id)
+	reader, err := ts.objectStore.Get(ctx, sstPath)
+	if err != nil {
+		return "", fmt.Errorf("while reading sst '%s': %w", id.Value, err)
+	}
+	data, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil {
+		return "", fmt.Errorf("while reading sst '%s': %w", id.Value, err)
+	}
+
+	quarantinePath := strings.TrimSuffix(sstPath, ".sst") + ".corrupt"
+	if err := ts.objectStore.Put(ctx, quarantinePath, bytes.NewReader(data)); err != nil {
+		return "", fmt.Errorf("while writing '%s': %w", quarantinePath, err)
+	}
+	if err := ts.DeleteSST(ctx, id); err != nil {
+		return "", err
+	}
+	return quarantinePath, nil
+}
+
+// ReadBlocks reads the blocks in blocksRange from an SSTable with a flat index. Blocks of
+// an SSTable with a partitioned index must be read using ReadBlocksUsingIndex.
+func (ts *TableStore) ReadBlocks(ctx context.Context, sstHandle *sstable.Handle, blocksRange common.Range) ([]block.Block, error) {
+	if sstHandle.Info.IndexPartitioned {
+		return nil, fmt.Errorf("cannot read blocks of sst '%s' without its index partition", sstHandle.Id.Value)
+	}
+	index, err := ts.ReadIndex(ctx, sstHandle)
 	if err != nil {
 		return nil, err
 	}
-	return sstable.ReadBlocks(sstHandle.Info, index, blocksRange, obj)
+	return ts.ReadBlocksUsingIndex(ctx, sstHandle, blocksRange, index)
 }
 
-// Reads specified blocks from an SSTable using the provided index.
+// Reads specified blocks from an SSTable using the provided index. Blocks held
+// by the block cache are served from memory, the remaining blocks are fetched
+// from object storage and inserted into the cache. Concurrent reads of the same
+// remaining blocks share a single fetch.
 func (ts *TableStore) ReadBlocksUsingIndex(
+	ctx context.Context,
 	sstHandle *sstable.Handle,
 	blocksRange common.Range,
 	index *sstable.Index,
 ) ([]block.Block, error) {
-	obj := ReadOnlyObject{ts.bucket, ts.sstPath(sstHandle.Id)}
-	return sstable.ReadBlocks(sstHandle.Info, index, blocksRange, obj)
+	obj := ts.object(ctx, sstHandle)
+	dict, err := ts.readDict(ctx, sstHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serve the leading blocks of the range held by the cache
+	blocks := make([]block.Block, 0, blocksRange.End-blocksRange.Start)
+	for i := blocksRange.Start; i < blocksRange.End; i++ {
+		blk, ok, err := ts.blockCache.get(sstHandle, index, i, dict)
+		if err != nil {
+			return nil, ts.reportCorruption(sstHandle.Id, err)
+		}
+		if !ok {
+			break
+		}
+		blocks = append(blocks, blk)
+	}
+	remaining := common.Range{Start: blocksRange.Start + uint64(len(blocks)), End: blocksRange.End}
+	if remaining.Start == remaining.End {
+		return blocks, nil
+	}
+
+	key := blockFetchKey{id: sstHandle.Id, blocks: remaining}
+	fetched, err := ts.blockFetches.do(ctx, key, func() ([]block.Block, error) {
+		return ts.fetchBlocks(sstHandle, index, remaining, obj, dict)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(blocks, fetched...), nil
 }
 
-func (ts *TableStore) cacheFilter(sstID sstable.ID, filter mo.Option[bloom.Filter]) {
+// fetchBlocks fetches the blocks in blocksRange from object storage and inserts
+// them into the block cache
+func (ts *TableStore) fetchBlocks(
+	sstHandle *sstable.Handle,
+	index *sstable.Index,
+	blocksRange common.Range,
+	obj common.ReadOnlyBlob,
+	dict []byte,
+) ([]block.Block, error) {
+	// The encoded blocks are only needed when the cache verifies blocks on every read
+	if ts.blockCache != nil && ts.blockCache.verification == config.ChecksumVerifyOnRead {
+		encoded, err := sstable.ReadEncodedBlocks(sstHandle.Info, index, blocksRange, obj)
+		if err != nil {
+			return nil, err
+		}
+		blocks := make([]block.Block, 0, len(encoded))
+		for i, enc := range encoded {
+			blockIndex := blocksRange.Start + uint64(i)
+			blk, err := sstable.DecodeBlock(sstHandle.Info, index, blockIndex, enc, dict)
+			if err != nil {
+				return nil, ts.reportCorruption(sstHandle.Id, err)
+			}
+			ts.blockCache.set(sstHandle, index, blockIndex, blk, enc)
+			blocks = append(blocks, blk)
+		}
+		return blocks, nil
+	}
+
+	blocks, err := sstable.ReadBlocksParallel(sstHandle.Info, index, blocksRange, obj, dict,
+		ts.sstConfig.BlockFetchParallelism)
+	if err != nil {
+		return nil, ts.reportCorruption(sstHandle.Id, err)
+	}
+	for i, blk := range blocks {
+		ts.blockCache.set(sstHandle, index, blocksRange.Start+uint64(i), blk, nil)
+	}
+	return blocks, nil
+}
+
+// CoalescedBlockFetches returns the number of reads of blocks of the TableStore
+// and its clones which shared the fetch of another read rather than fetching the
+// blocks themselves
+func (ts *TableStore) CoalescedBlockFetches() int64 {
+	return ts.blockFetches.coalesced.Load()
+}
+
+// SetLayout writes and reads the SSTables under the WAL and compacted prefixes of
+// the layout, which must be resolved by ResolveLayout. Must be called before the
+// TableStore is used.
+func (ts *TableStore) SetLayout(layout config.ObjectStoreLayout) {
+	ts.walPath = layout.WALPrefix
+	ts.compactedPath = layout.CompactedPrefix
+}
+
+// SetBlockCache caches up to capacity bytes of the blocks read from SSTables,
+// verifying the checksum of cached blocks as decided by verification. A capacity
+// of zero disables the cache. Must be called before the TableStore is used, the
+// cache is shared with the clones returned by WithIOClass.
+func (ts *TableStore) SetBlockCache(capacity uint64, verification config.ChecksumVerification) {
+	ts.blockCache = newBlockCache(capacity, verification)
+}
+
+// SetIndexCache caches up to capacity bytes of the indexes and index partitions
+// read from SSTables. A capacity of zero disables the cache. Must be called before
+// the TableStore is used, the cache is shared with the clones returned by WithIOClass.
+func (ts *TableStore) SetIndexCache(capacity uint64) {
+	ts.indexCache = newIndexCache(capacity, &indexCacheCounters{})
+}
+
+// IndexCacheStats returns the counters of the index cache of the TableStore and
+// its clones
+func (ts *TableStore) IndexCacheStats() IndexCacheStats {
+	return ts.indexCache.stats()
+}
+
+// readDict returns the compression dictionary of the SSTable, which is cached
+// so it is only read once rather than for every read of blocks.
+func (ts *TableStore) readDict(ctx context.Context, sstHandle *sstable.Handle) ([]byte, error) {
+	if sstHandle.Info.CompressionDictLen == 0 {
+		return nil, nil
+	}
+
+	ts.mu.RLock()
+	dict, ok := ts.dictCache.Get(sstHandle.Id)
+	ts.mu.RUnlock()
+	if ok {
+		return dict, nil
+	}
+
+	dict, err := sstable.ReadDict(sstHandle.Info, ts.object(ctx, sstHandle))
+	if err != nil {
+		return nil, ts.reportCorruption(sstHandle.Id, err)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.dictCache.Set(sstHandle.Id, dict)
+	return dict, nil
+}
+
+// ReadRangeTombstones returns the range tombstones of the SSTable, which are
+// cached so they are only read once rather than for every read of the SSTable
+func (ts *TableStore) ReadRangeTombstones(ctx context.Context, sstHandle *sstable.Handle) (types.RangeTombstones, error) {
+	if sstHandle.Info.RangeTombstoneLen == 0 {
+		return nil, nil
+	}
+
+	ts.mu.RLock()
+	tombstones, ok := ts.rangeCache.Get(sstHandle.Id)
+	ts.mu.RUnlock()
+	if ok {
+		return tombstones, nil
+	}
+
+	tombstones, err := sstable.ReadRangeTombstones(sstHandle.Info, ts.object(ctx, sstHandle))
+	if err != nil {
+		return nil, ts.reportCorruption(sstHandle.Id, err)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.rangeCache.Set(sstHandle.Id, tombstones)
+	return tombstones, nil
+}
+
+func (ts *TableStore) cacheFilter(sstID sstable.ID, filter mo.Option[sstable.Filter]) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 	ts.filterCache.Set(sstID, filter)
 }
 
-func (ts *TableStore) ReadFilter(sstHandle *sstable.Handle) (mo.Option[bloom.Filter], error) {
+func (ts *TableStore) ReadFilter(ctx context.Context, sstHandle *sstable.Handle) (mo.Option[sstable.Filter], error) {
 	ts.mu.RLock()
 	val, ok := ts.filterCache.Get(sstHandle.Id)
 	ts.mu.RUnlock()
@@ -146,25 +641,82 @@ func (ts *TableStore) ReadFilter(sstHandle *sstable.Handle) (mo.Option[bloom.Fil
 		return val, nil
 	}
 
-	obj := ReadOnlyObject{ts.bucket, ts.sstPath(sstHandle.Id)}
-	filtr, err := sstable.ReadFilter(sstHandle.Info, obj)
+	obj := ts.object(ctx, sstHandle)
+	filtr, err := sstable.ReadFilter(sstHandle.Info, obj, ts.sstConfig.FilterPolicy)
 	if err != nil {
-		return mo.None[bloom.Filter](), err
+		return mo.None[sstable.Filter](), ts.reportCorruption(sstHandle.Id, err)
 	}
 
 	ts.cacheFilter(sstHandle.Id, filtr)
 	return filtr, nil
 }
 
-func (ts *TableStore) ReadIndex(sstHandle *sstable.Handle) (*sstable.Index, error) {
-	obj := ReadOnlyObject{ts.bucket, ts.sstPath(sstHandle.Id)}
+// ReadIndex reads the index of the SSTable, which is the top level index if the
+// index is partitioned. The index is served from the index cache if held by it.
+func (ts *TableStore) ReadIndex(ctx context.Context, sstHandle *sstable.Handle) (*sstable.Index, error) {
+	if index, ok := ts.indexCache.get(sstHandle.Id, topLevelIndex); ok {
+		return index, nil
+	}
+	obj := ts.object(ctx, sstHandle)
 	index, err := sstable.ReadIndex(sstHandle.Info, obj)
 	if err != nil {
-		return nil, err
+		return nil, ts.reportCorruption(sstHandle.Id, err)
 	}
+	ts.indexCache.set(sstHandle.Id, topLevelIndex, index)
 	return index, nil
 }
 
+// ReadIndexPartition reads the requested partition of a partitioned index. The
+// partition is served from the index cache if held by it, otherwise it is paged
+// in from object storage.
+func (ts *TableStore) ReadIndexPartition(
+	ctx context.Context,
+	sstHandle *sstable.Handle,
+	topLevel *sstable.Index,
+	partition int,
+) (*sstable.Index, error) {
+	if index, ok := ts.indexCache.get(sstHandle.Id, partition); ok {
+		return index, nil
+	}
+	obj := ts.object(ctx, sstHandle)
+	index, err := sstable.ReadIndexPartition(sstHandle.Info, topLevel, partition, obj)
+	if err != nil {
+		return nil, ts.reportCorruption(sstHandle.Id, err)
+	}
+	ts.indexCache.set(sstHandle.Id, partition, index)
+	return index, nil
+}
+
+// OnCorruption sets the function called with every common.CorruptionError
+// returned while reading an SSTable
+func (ts *TableStore) OnCorruption(fn func(*common.CorruptionError)) {
+	ts.onCorruption = fn
+}
+
+// reportCorruption records the key of the SSTable object in the common.CorruptionError
+// wrapped by err, if any, and passes it to the function set by OnCorruption.
+func (ts *TableStore) reportCorruption(id sstable.ID, err error) error {
+	var cerr *common.CorruptionError
+	if !errors.As(err, &cerr) {
+		return err
+	}
+	cerr.Object = ts.sstPath(id)
+	if ts.onCorruption != nil {
+		ts.onCorruption(cerr)
+	}
+	return err
+}
+
+// object returns the blob to read the SSTable from, which is the bytes of the
+// SSTable if it is stored inline in the manifest. Reads of the object are
+// canceled once ctx is done.
+func (ts *TableStore) object(ctx context.Context, sstHandle *sstable.Handle) common.ReadOnlyBlob {
+	if sstHandle.IsInline() {
+		return sstable.NewBytesBlob(sstHandle.Inline)
+	}
+	return ReadOnlyObject{objectStore: ts.objectStore, path: ts.sstPath(sstHandle.Id), ctx: ctx}
+}
+
 func (ts *TableStore) sstPath(id sstable.ID) string {
 	if id.Type == sstable.WAL {
 		return path.Join(ts.rootPath, ts.walPath, id.Value+".sst")
@@ -188,16 +740,29 @@ func (ts *TableStore) parseID(filepath string, expectedExt string) (uint64, erro
 }
 
 func (ts *TableStore) Clone() *TableStore {
-	cache, err := otter.MustBuilder[sstable.ID, mo.Option[bloom.Filter]](1000).Build()
+	cache, err := otter.MustBuilder[sstable.ID, mo.Option[sstable.Filter]](1000).Build()
+	assert.True(err == nil, "")
+	dictCache, err := otter.MustBuilder[sstable.ID, []byte](1000).Build()
+	assert.True(err == nil, "")
+	rangeCache, err := otter.MustBuilder[sstable.ID, types.RangeTombstones](1000).Build()
 	assert.True(err == nil, "")
 	return &TableStore{
-		mu:            sync.RWMutex{},
-		bucket:        ts.bucket,
-		sstConfig:     ts.sstConfig,
-		rootPath:      ts.rootPath,
-		walPath:       ts.walPath,
-		compactedPath: ts.compactedPath,
-		filterCache:   cache,
+		mu:               sync.RWMutex{},
+		objectStore:      ts.objectStore,
+		sstConfig:        ts.sstConfig,
+		rootPath:         ts.rootPath,
+		walPath:          ts.walPath,
+		compactedPath:    ts.compactedPath,
+		filterCache:      cache,
+		dictCache:        dictCache,
+		rangeCache:       rangeCache,
+		onCorruption:     ts.onCorruption,
+		blockCache:       ts.blockCache.clone(),
+		indexCache:       ts.indexCache.clone(),
+		blockFetches:     ts.blockFetches,
+		filterBitsPerKey: ts.filterBitsPerKey,
+		ioStats:          ts.ioStats,
+		builders:         ts.builders,
 	}
 }
 
@@ -207,24 +772,52 @@ func (ts *TableStore) Clone() *TableStore {
 // ------------------------------------------------
 
 type EncodedSSTableWriter struct {
+	// ctx cancels the upload of the SSTable once it is done
+	ctx        context.Context
 	sstID      sstable.ID
 	builder    *sstable.Builder
 	tableStore *TableStore
 
-	// TODO: we are using an unbounded slice of byte as buffer.
-	//  Add a capacity for buffer and when buffer reaches the capacity
-	//  it should be written to object storage
-	buffer        []byte
+	// buffer holds the encoded blocks which are not yet uploaded. If partSize is
+	// not zero, the buffer is streamed to the upload each time it reaches partSize.
+	buffer   []byte
+	partSize uint64
+	// pipelined uploads each part while the next part is built
+	pipelined     bool
+	upload        *streamingUpload
 	blocksWritten uint64
+
+	start time.Time
+	stats TableWriterStats
 }
 
 func (w *EncodedSSTableWriter) Add(key []byte, value mo.Option[[]byte]) error {
+	defer w.timeBuild(time.Now())
 	v, _ := value.Get()
 	err := w.builder.AddValue(key, v)
 	if err != nil {
 		return fmt.Errorf("builder failed to add key value: %w", err)
 	}
+	return w.bufferBlocks()
+}
+
+// AddEntry adds the entry as is, which preserves the checksum of its value
+func (w *EncodedSSTableWriter) AddEntry(entry types.RowEntry) error {
+	defer w.timeBuild(time.Now())
+	if err := w.builder.Add(entry.Key, entry); err != nil {
+		return fmt.Errorf("builder failed to add key value: %w", err)
+	}
+	return w.bufferBlocks()
+}
 
+// bufferBlocks buffers the blocks completed by the builder, and streams the
+// buffer to object storage once it fills a part
+// AddRangeTombstone adds a range tombstone to the SSTable, see sstable.Builder.AddRangeTombstone
+func (w *EncodedSSTableWriter) AddRangeTombstone(tombstone types.RangeTombstone) {
+	w.builder.AddRangeTombstone(tombstone)
+}
+
+func (w *EncodedSSTableWriter) bufferBlocks() error {
 	for {
 		blk, ok := w.builder.NextBlock().Get()
 		if !ok {
@@ -234,6 +827,11 @@ func (w *EncodedSSTableWriter) Add(key []byte, value mo.Option[[]byte]) error {
 		w.blocksWritten += 1
 	}
 
+	if w.partSize > 0 && uint64(len(w.buffer)) >= w.partSize {
+		if err := w.writePart(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -241,52 +839,215 @@ func (w *EncodedSSTableWriter) Written() uint64 {
 	return w.blocksWritten
 }
 
+// Stats returns the time spent in each stage of writing the SSTable, which is
+// complete once the writer is closed
+func (w *EncodedSSTableWriter) Stats() TableWriterStats {
+	return w.stats
+}
+
+func (w *EncodedSSTableWriter) timeBuild(start time.Time) {
+	w.stats.Build += time.Since(start)
+}
+
+// writePart streams the buffered blocks to object storage, starting the upload
+// if this is the first part.
+func (w *EncodedSSTableWriter) writePart() error {
+	if w.upload == nil {
+		w.upload = startStreamingUpload(w.ctx, w.tableStore.objectStore, w.tableStore.sstPath(w.sstID), w.pipelined)
+	}
+	if err := w.upload.write(w.buffer); err != nil {
+		return fmt.Errorf("%w: while uploading part of sst '%s': %w", common.ErrObjectStore, w.sstID.Value, err)
+	}
+	if w.pipelined {
+		// The part is owned by the upload until it is written
+		w.buffer = nil
+	} else {
+		w.buffer = w.buffer[:0]
+	}
+	return nil
+}
+
 func (w *EncodedSSTableWriter) Close() (*sstable.Handle, error) {
+	start := time.Now()
 	encodedSST, err := w.builder.Build()
+	w.stats.Build += time.Since(start)
+	w.stats.Encode = w.builder.EncodeTime()
+	// The Table doesn't share buffers with the builder, so it is released once built
+	w.tableStore.ReleaseTableBuilder(w.builder)
+	w.builder = nil
 	if err != nil {
+		w.Abort()
 		return nil, fmt.Errorf("SST build failed: %w", err)
 	}
 
-	blocksData := w.buffer
 	for {
 		if encodedSST.Blocks.Len() == 0 {
 			break
 		}
-		blocksData = append(blocksData, encodedSST.Blocks.PopFront()...)
+		w.buffer = append(w.buffer, encodedSST.Blocks.PopFront()...)
 	}
 
-	sstPath := w.tableStore.sstPath(w.sstID)
-	err = w.tableStore.bucket.Upload(context.Background(), sstPath, bytes.NewReader(blocksData))
-	if err != nil {
-		return nil, common.ErrObjectStore
+	if w.upload != nil {
+		// The footer is written with the final part, after which the upload completes
+		if err := w.writePart(); err != nil {
+			w.Abort()
+			return nil, err
+		}
+		err := w.upload.finish()
+		w.stats.Upload = w.upload.elapsed
+		if err != nil {
+			return nil, fmt.Errorf("%w: while completing upload of sst '%s': %w", common.ErrObjectStore, w.sstID.Value, err)
+		}
+	} else {
+		start := time.Now()
+		sstPath := w.tableStore.sstPath(w.sstID)
+		err = w.tableStore.objectStore.Put(w.ctx, sstPath, bytes.NewReader(w.buffer))
+		w.stats.Upload = time.Since(start)
+		if err != nil {
+			return nil, fmt.Errorf("%w: while uploading sst '%s': %w", common.ErrObjectStore, w.sstID.Value, err)
+		}
 	}
+	w.stats.Total = time.Since(w.start)
 
-	w.tableStore.cacheFilter(w.sstID, encodedSST.Bloom)
+	w.tableStore.cacheFilter(w.sstID, encodedSST.Filter)
 	return sstable.NewHandle(w.sstID, encodedSST.Info), nil
 }
 
+// Abort cancels the upload of the SSTable if any parts have been streamed, such
+// that the object storage provider discards the parts already uploaded.
+func (w *EncodedSSTableWriter) Abort() {
+	if w.upload == nil {
+		return
+	}
+	w.upload.abort()
+	w.upload = nil
+}
+
+// streamingUpload streams the bytes written to writer to an object. The object
+// storage provider uploads the stream as a multipart upload, and aborts the
+// upload if the stream is closed with an error.
+type streamingUpload struct {
+	writer *io.PipeWriter
+	done   chan error
+
+	// parts queues the parts which are written to the stream by a separate
+	// goroutine, so the next part is built while a part is uploaded. It is nil
+	// if parts are written to the stream by write.
+	parts  chan []byte
+	pumped chan error
+	// failed holds the error of the first part which could not be written
+	failed atomic.Pointer[error]
+
+	// elapsed is the time spent writing parts to the stream and completing the
+	// upload, which is complete once the upload is finished or aborted
+	elapsed time.Duration
+}
+
+func startStreamingUpload(ctx context.Context, objectStore ObjectStore, objPath string, pipelined bool) *streamingUpload {
+	reader, writer := io.Pipe()
+	u := &streamingUpload{writer: writer, done: make(chan error, 1)}
+	go func() {
+		err := objectStore.Put(ctx, objPath, reader)
+		// Unblock any writes still in progress if the upload failed
+		_ = reader.CloseWithError(err)
+		u.done <- err
+	}()
+	if pipelined {
+		u.parts = make(chan []byte, 1)
+		u.pumped = make(chan error, 1)
+		go u.pump()
+	}
+	return u
+}
+
+// pump writes the queued parts to the stream. Once a part fails, the remaining
+// parts are discarded.
+func (u *streamingUpload) pump() {
+	var err error
+	for part := range u.parts {
+		if err != nil {
+			continue
+		}
+		start := time.Now()
+		_, err = u.writer.Write(part)
+		u.elapsed += time.Since(start)
+		if err != nil {
+			u.failed.Store(&err)
+		}
+	}
+	u.pumped <- err
+}
+
+// write writes the part to the stream, or queues it if the upload is pipelined.
+// The error of a queued part is returned by a later call.
+func (u *streamingUpload) write(part []byte) error {
+	if u.parts == nil {
+		start := time.Now()
+		_, err := u.writer.Write(part)
+		u.elapsed += time.Since(start)
+		return err
+	}
+	if err := u.failed.Load(); err != nil {
+		return *err
+	}
+	u.parts <- part
+	return nil
+}
+
+// finish ends the stream once the queued parts are written, and waits for the
+// upload to complete
+func (u *streamingUpload) finish() error {
+	var pumpErr error
+	if u.parts != nil {
+		close(u.parts)
+		pumpErr = <-u.pumped
+	}
+	start := time.Now()
+	_ = u.writer.Close()
+	err := <-u.done
+	u.elapsed += time.Since(start)
+	if err == nil {
+		err = pumpErr
+	}
+	return err
+}
+
+// abort ends the stream with an error, which aborts the upload
+func (u *streamingUpload) abort() {
+	_ = u.writer.CloseWithError(errors.New("upload aborted"))
+	if u.parts != nil {
+		close(u.parts)
+		<-u.pumped
+	}
+	<-u.done
+}
+
 // ------------------------------------------------
 // ReadOnlyObject
 // ------------------------------------------------
 
+// ReadOnlyObject reads an object through the ObjectStore. Its reads are canceled
+// once ctx is done.
 type ReadOnlyObject struct {
-	bucket objstore.Bucket
-	path   string
+	objectStore ObjectStore
+	path        string
+	ctx         context.Context
 }
 
 func (r ReadOnlyObject) Len() (int, error) {
-	attr, err := r.bucket.Attributes(context.Background(), r.path)
+	meta, err := r.objectStore.Head(r.ctx, r.path)
 	if err != nil {
 		return 0, fmt.Errorf("while fetching object attributes: %w", err)
 	}
-	return int(attr.Size), nil
+	return int(meta.Size), nil
 }
 
 func (r ReadOnlyObject) ReadRange(rng common.Range) ([]byte, error) {
-	read, err := r.bucket.GetRange(context.Background(), r.path, int64(rng.Start), int64(rng.End-rng.Start))
+	read, err := r.objectStore.GetRange(r.ctx, r.path, int64(rng.Start), int64(rng.End-rng.Start))
 	if err != nil {
 		return nil, fmt.Errorf("while fetching object range [%d:%d]: %w", rng.Start, rng.End-rng.Start, err)
 	}
+	defer func() { _ = read.Close() }()
 
 	data, err := io.ReadAll(read)
 	if err != nil {
@@ -297,10 +1058,11 @@ func (r ReadOnlyObject) ReadRange(rng common.Range) ([]byte, error) {
 }
 
 func (r ReadOnlyObject) Read() ([]byte, error) {
-	read, err := r.bucket.Get(context.Background(), r.path)
+	read, err := r.objectStore.Get(r.ctx, r.path)
 	if err != nil {
 		return nil, fmt.Errorf("while fetching object '%s': %w", r.path, err)
 	}
+	defer func() { _ = read.Close() }()
 
 	data, err := io.ReadAll(read)
 	if err != nil {
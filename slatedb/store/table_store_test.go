@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/oklog/ulid/v2"
 	"github.com/samber/mo"
@@ -18,9 +21,9 @@ import (
 	"github.com/slatedb/slatedb-go/internal/compress"
 	"github.com/slatedb/slatedb-go/internal/sstable"
 	"github.com/slatedb/slatedb-go/internal/sstable/block"
-	"github.com/slatedb/slatedb-go/internal/sstable/bloom"
 	"github.com/slatedb/slatedb-go/internal/types"
 	"github.com/slatedb/slatedb-go/slatedb/common"
+	"github.com/slatedb/slatedb-go/slatedb/config"
 )
 
 type BytesBlob struct {
@@ -54,7 +57,7 @@ func buildSSTWithNBlocks(
 	keyGen common.OrderedBytesGenerator,
 	valGen common.OrderedBytesGenerator,
 ) (*sstable.Handle, int, error) {
-	writer := tableStore.TableWriter(sstable.NewIDWal(0))
+	writer := tableStore.TableWriter(context.Background(), sstable.NewIDWal(0))
 	nKeys := 0
 	for writer.blocksWritten < n {
 		if err := writer.Add(keyGen.Next(), mo.Some(valGen.Next())); err != nil {
@@ -152,7 +155,7 @@ func TestSSTable(t *testing.T) {
 	encodedInfo := encodedSST.Info
 
 	// write sst and validate that the handle returned has the correct content.
-	sstHandle, err := tableStore.WriteSST(sstable.NewIDWal(0), encodedSST)
+	sstHandle, err := tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), encodedSST)
 	assert.NoError(t, err)
 	assert.Equal(t, encodedInfo, sstHandle.Info)
 	firstKey := sstHandle.Info.FirstKey
@@ -160,12 +163,12 @@ func TestSSTable(t *testing.T) {
 	assert.True(t, bytes.Equal(firstKey, []byte("key1")))
 
 	// construct sst info from the raw bytes and validate that it matches the original info.
-	sstHandleFromStore, err := tableStore.OpenSST(sstable.NewIDWal(0))
+	sstHandleFromStore, err := tableStore.OpenSST(context.Background(), sstable.NewIDWal(0))
 	assert.NoError(t, err)
 	assert.Equal(t, encodedInfo, sstHandleFromStore.Info)
 
 	sstInfoFromStore := sstHandleFromStore.Info
-	index, err := tableStore.ReadIndex(sstHandleFromStore)
+	index, err := tableStore.ReadIndex(context.Background(), sstHandleFromStore)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, index.BlockMetaLength())
 	firstKey = sstInfoFromStore.FirstKey
@@ -189,9 +192,9 @@ func TestSSTableNoFilter(t *testing.T) {
 	assert.NoError(t, err)
 	encodedInfo := encodedSST.Info
 
-	_, err = tableStore.WriteSST(sstable.NewIDWal(0), encodedSST)
+	_, err = tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), encodedSST)
 	assert.NoError(t, err)
-	sstHandle, err := tableStore.OpenSST(sstable.NewIDWal(0))
+	sstHandle, err := tableStore.OpenSST(context.Background(), sstable.NewIDWal(0))
 	assert.NoError(t, err)
 	assert.Equal(t, encodedInfo, sstHandle.Info)
 	assert.Equal(t, uint64(0), sstHandle.Info.FilterLen)
@@ -210,12 +213,11 @@ func TestSSTableBuildsFilterWithCorrectBitsPerKey(t *testing.T) {
 		}
 		encodedSST, err := builder.Build()
 		assert.NoError(t, err)
-		filter, _ := encodedSST.Bloom.Get()
+		filter, _ := encodedSST.Filter.Get()
 		// filters are encoded as a 2 byte number of probes followed by the filter + 4 byte checksum
 		// Since we have added 8 keys, the filter will have (8 * FilterBitsPerKey) bits or FilterBitsPerKey bytes
-		f, err := bloom.Encode(filter, compress.CodecNone)
-		assert.NoError(t, err)
-		assert.Equal(t, 2+int(filterBitsPerKey)+4, len(f))
+		assert.Equal(t, 2+int(filterBitsPerKey), len(filter.Data))
+		assert.Equal(t, uint64(2+filterBitsPerKey+4), encodedSST.Info.FilterLen)
 	}
 }
 
@@ -235,11 +237,11 @@ func TestSSTableWithCompression(t *testing.T) {
 		assert.NoError(t, err)
 		encodedInfo := encodedSST.Info
 
-		_, err = tableStore.WriteSST(sstable.NewIDWal(0), encodedSST)
+		_, err = tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), encodedSST)
 		assert.NoError(t, err)
-		sstHandle, err := tableStore.OpenSST(sstable.NewIDWal(0))
+		sstHandle, err := tableStore.OpenSST(context.Background(), sstable.NewIDWal(0))
 		assert.NoError(t, err)
-		index, err := tableStore.ReadIndex(sstHandle)
+		index, err := tableStore.ReadIndex(context.Background(), sstHandle)
 		assert.NoError(t, err)
 
 		assert.Equal(t, encodedInfo, sstHandle.Info)
@@ -250,6 +252,45 @@ func TestSSTableWithCompression(t *testing.T) {
 	}
 }
 
+func TestSSTableWithCompressionDict(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = 128
+	conf.Compression = compress.CodecZstd
+	conf.CompressionDictSize = 512
+	conf.CompressionDictTrainingBytes = 1024
+	tableStore := NewTableStore(bucket, conf, "")
+
+	// The blocks are written by the compactor's writer before the dictionary is stored
+	writer := tableStore.TableWriter(context.Background(), sstable.NewIDCompacted(ulid.Make()))
+	for i := 0; i < 100; i++ {
+		value := fmt.Sprintf("value-%03d-shared-by-every-value-of-the-table", i)
+		require.NoError(t, writer.Add([]byte(fmt.Sprintf("key-%03d", i)), mo.Some([]byte(value))))
+	}
+	sstHandle, err := writer.Close()
+	require.NoError(t, err)
+	require.NotZero(t, sstHandle.Info.CompressionDictLen)
+
+	index, err := tableStore.ReadIndex(context.Background(), sstHandle)
+	require.NoError(t, err)
+	blocks, err := tableStore.ReadBlocksUsingIndex(context.Background(), sstHandle, common.Range{Start: 0, End: uint64(index.BlockMetaLength())}, index)
+	require.NoError(t, err)
+
+	i := 0
+	for _, blk := range blocks {
+		it := block.NewIterator(&blk)
+		for {
+			kv, ok := it.Next(context.Background())
+			if !ok {
+				break
+			}
+			assert.Equal(t, []byte(fmt.Sprintf("key-%03d", i)), kv.Key)
+			i++
+		}
+	}
+	assert.Equal(t, 100, i)
+}
+
 func TestReadBlocks(t *testing.T) {
 	bucket := objstore.NewInMemBucket()
 	conf := sstable.DefaultConfig()
@@ -291,9 +332,69 @@ func TestReadBlocks(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestReadBlocksReportsCorruption(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = 52
+	tableStore := NewTableStore(bucket, conf, "")
+	var reported []*common.CorruptionError
+	tableStore.OnCorruption(func(err *common.CorruptionError) {
+		reported = append(reported, err)
+	})
+
+	builder := tableStore.TableBuilder()
+	require.NoError(t, builder.AddValue([]byte("aa"), []byte("11")))
+	require.NoError(t, builder.AddValue([]byte("bb"), []byte("22")))
+	require.NoError(t, builder.AddValue([]byte("cccccccccccccccccccc"), []byte("33333333333333333333")))
+	require.NoError(t, builder.AddValue([]byte("dddddddddddddddddddd"), []byte("44444444444444444444")))
+	table, err := builder.Build()
+	require.NoError(t, err)
+
+	id := sstable.NewIDCompacted(ulid.Make())
+	handle, err := tableStore.WriteSST(context.Background(), id, table)
+	require.NoError(t, err)
+	index, err := tableStore.ReadIndex(context.Background(), handle)
+	require.NoError(t, err)
+	require.Greater(t, index.BlockMetaLength(), 1)
+
+	// Flip a byte within the second block
+	sstPath := tableStore.sstPath(id)
+	reader, err := bucket.Get(context.Background(), sstPath)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	blockOffset := index.BlockMeta()[1].Offset
+	data[blockOffset] ^= 0xff
+	require.NoError(t, bucket.Upload(context.Background(), sstPath, bytes.NewReader(data)))
+
+	_, err = tableStore.ReadBlocks(context.Background(), handle, common.Range{Start: 0, End: 2})
+	require.ErrorIs(t, err, common.ErrChecksumMismatch)
+
+	var cerr *common.CorruptionError
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, sstPath, cerr.Object)
+	assert.Equal(t, common.SectionBlock, cerr.Section)
+	assert.Equal(t, 1, cerr.Block)
+	assert.Equal(t, blockOffset, cerr.Offset)
+	assert.NotEqual(t, cerr.ExpectedChecksum, cerr.ActualChecksum)
+	require.Len(t, reported, 1)
+	assert.Same(t, cerr, reported[0])
+
+	// Corruption of the info is reported when the SSTable is opened
+	data[handle.Info.IndexOffset+handle.Info.IndexLen] ^= 0xff
+	require.NoError(t, bucket.Upload(context.Background(), sstPath, bytes.NewReader(data)))
+
+	_, err = tableStore.OpenSST(context.Background(), id)
+	require.ErrorAs(t, err, &cerr)
+	assert.Equal(t, common.SectionInfo, cerr.Section)
+	assert.Equal(t, -1, cerr.Block)
+	assert.Equal(t, sstPath, cerr.Object)
+	assert.Len(t, reported, 2)
+}
+
 func TestReadAllBlocks(t *testing.T) {
 	// Force the creation of multiple blocks
-	blockSize := block.V0EstimateBlockSize([]types.KeyValue{
+	blockSize := block.EstimateBlockSize([]types.KeyValue{
 		{Key: []byte("aa"), Value: []byte("11")},
 		{Key: []byte("bb"), Value: []byte("22")},
 	})
@@ -359,15 +460,15 @@ func TestOneBlockSSTIter(t *testing.T) {
 
 	encodedSST, err := builder.Build()
 	assert.NoError(t, err)
-	_, err = tableStore.WriteSST(sstable.NewIDWal(0), encodedSST)
+	_, err = tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), encodedSST)
 	require.NoError(t, err)
-	sstHandle, err := tableStore.OpenSST(sstable.NewIDWal(0))
+	sstHandle, err := tableStore.OpenSST(context.Background(), sstable.NewIDWal(0))
 	assert.NoError(t, err)
-	index, err := tableStore.ReadIndex(sstHandle)
+	index, err := tableStore.ReadIndex(context.Background(), sstHandle)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, index.BlockMetaLength())
 
-	iterator, err := sstable.NewIterator(sstHandle, tableStore)
+	iterator, err := sstable.NewIterator(context.Background(), sstHandle, tableStore)
 	assert.NoError(t, err)
 	assert2.Next(t, iterator, []byte("key1"), []byte("value1"))
 	assert2.Next(t, iterator, []byte("key2"), []byte("value2"))
@@ -393,15 +494,15 @@ func TestManyBlockSSTIter(t *testing.T) {
 
 	encodedSST, err := builder.Build()
 	assert.NoError(t, err)
-	_, err = tableStore.WriteSST(sstable.NewIDWal(0), encodedSST)
+	_, err = tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), encodedSST)
 	require.NoError(t, err)
-	sstHandle, err := tableStore.OpenSST(sstable.NewIDWal(0))
+	sstHandle, err := tableStore.OpenSST(context.Background(), sstable.NewIDWal(0))
 	assert.NoError(t, err)
-	index, err := tableStore.ReadIndex(sstHandle)
+	index, err := tableStore.ReadIndex(context.Background(), sstHandle)
 	require.NoError(t, err)
 	require.NotNil(t, index)
 
-	iterator, err := sstable.NewIterator(sstHandle, tableStore)
+	iterator, err := sstable.NewIterator(context.Background(), sstHandle, tableStore)
 	assert.NoError(t, err)
 	for i := 0; i < 1000; i++ {
 		key := []byte(fmt.Sprintf("key%d", i))
@@ -415,6 +516,141 @@ func TestManyBlockSSTIter(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestPartitionedIndexSSTWriter(t *testing.T) {
+	// Force key values into separate blocks
+	blockSize := block.EstimateBlockSize([]types.KeyValue{
+		{Key: []byte("key000"), Value: []byte("value000")},
+	})
+
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = blockSize
+	conf.IndexPartitionThreshold = 3
+	tableStore := NewTableStore(bucket, conf, "")
+	sstID := sstable.NewIDCompacted(ulid.Make())
+
+	writer := tableStore.TableWriter(context.Background(), sstID)
+	for i := 0; i < 20; i++ {
+		key := []byte(fmt.Sprintf("key%03d", i))
+		require.NoError(t, writer.Add(key, mo.Some([]byte(fmt.Sprintf("value%03d", i)))))
+	}
+	_, err := writer.Close()
+	require.NoError(t, err)
+
+	sstHandle, err := tableStore.OpenSST(context.Background(), sstID)
+	require.NoError(t, err)
+	assert.True(t, sstHandle.Info.IndexPartitioned)
+
+	_, err = tableStore.ReadBlocks(context.Background(), sstHandle, common.Range{Start: 0, End: 1})
+	assert.Error(t, err)
+
+	iterator, err := sstable.NewIterator(context.Background(), sstHandle, tableStore)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		assert2.NextEntry(t, iterator, []byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i)))
+	}
+	_, ok := iterator.NextEntry(context.Background())
+	assert.False(t, ok)
+
+	iterator, err = sstable.NewIteratorAtKey(context.Background(), sstHandle, []byte("key010"), tableStore)
+	require.NoError(t, err)
+	for i := 10; i < 20; i++ {
+		assert2.NextEntry(t, iterator, []byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i)))
+	}
+	_, ok = iterator.NextEntry(context.Background())
+	assert.False(t, ok)
+}
+
+func TestIterWithOptions(t *testing.T) {
+	// Force key values into separate blocks
+	blockSize := block.EstimateBlockSize([]types.KeyValue{
+		{Key: []byte("key000"), Value: []byte("value000")},
+	})
+
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = blockSize
+	tableStore := NewTableStore(bucket, conf, "")
+	builder := tableStore.TableBuilder()
+	for i := 0; i < 50; i++ {
+		require.NoError(t, builder.AddValue([]byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i))))
+	}
+	encodedSST, err := builder.Build()
+	require.NoError(t, err)
+	sst, err := tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), encodedSST)
+	require.NoError(t, err)
+
+	cases := map[string]sstable.IteratorOptions{"Readahead": {
+		UpperBound: []byte("key030"),
+		Readahead:  sstable.Readahead{MinBlocks: 0, MaxBlocks: 8, MaxRequestBlocks: 3},
+	}}
+	for _, prefetch := range []int{0, 1, 4, 100} {
+		cases[fmt.Sprintf("Prefetch %d", prefetch)] = sstable.IteratorOptions{
+			UpperBound:     []byte("key030"),
+			PrefetchBlocks: prefetch,
+		}
+	}
+	for name, opts := range cases {
+		t.Run(name, func(t *testing.T) {
+			iterator, err := sstable.NewIteratorWithOptions(context.Background(), sst, tableStore, opts)
+			require.NoError(t, err)
+			for i := 0; i < 30; i++ {
+				assert2.Next(t, iterator, []byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i)))
+			}
+			_, ok := iterator.Next(context.Background())
+			assert.False(t, ok)
+
+			// Seek repositions the iterator, even after the UpperBound was reached
+			require.NoError(t, iterator.Seek(context.Background(), []byte("key010a")))
+			for i := 11; i < 30; i++ {
+				assert2.Next(t, iterator, []byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i)))
+			}
+			_, ok = iterator.Next(context.Background())
+			assert.False(t, ok)
+
+			require.NoError(t, iterator.Seek(context.Background(), []byte("key040")))
+			_, ok = iterator.Next(context.Background())
+			assert.False(t, ok)
+			assert.True(t, iterator.Warnings().Empty())
+		})
+	}
+}
+
+func TestIterWithOptionsPartitionedIndex(t *testing.T) {
+	// Force key values into separate blocks
+	blockSize := block.EstimateBlockSize([]types.KeyValue{
+		{Key: []byte("key000"), Value: []byte("value000")},
+	})
+
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = blockSize
+	conf.IndexPartitionThreshold = 4
+	tableStore := NewTableStore(bucket, conf, "")
+	builder := tableStore.TableBuilder()
+	for i := 0; i < 50; i++ {
+		require.NoError(t, builder.AddValue([]byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i))))
+	}
+	encodedSST, err := builder.Build()
+	require.NoError(t, err)
+	sst, err := tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), encodedSST)
+	require.NoError(t, err)
+	require.True(t, sst.Info.IndexPartitioned)
+
+	iterator, err := sstable.NewIteratorWithOptions(context.Background(), sst, tableStore, sstable.IteratorOptions{
+		UpperBound:     []byte("key045"),
+		PrefetchBlocks: 3,
+	})
+	require.NoError(t, err)
+	require.NoError(t, iterator.Seek(context.Background(), []byte("key002")))
+	for i := 2; i < 45; i++ {
+		assert2.Next(t, iterator, []byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i)))
+	}
+	_, ok := iterator.Next(context.Background())
+	assert.False(t, ok)
+	assert.True(t, iterator.Warnings().Empty())
+}
+
 func TestIterFromKey(t *testing.T) {
 	bucket := objstore.NewInMemBucket()
 	conf := sstable.DefaultConfig()
@@ -437,7 +673,7 @@ func TestIterFromKey(t *testing.T) {
 		expectedValGen := testCaseValGen.Clone()
 		fromKey := testCaseKeyGen.Next()
 		testCaseValGen.Next()
-		kvIter, err := sstable.NewIteratorAtKey(sst, fromKey, tableStore)
+		kvIter, err := sstable.NewIteratorAtKey(context.Background(), sst, fromKey, tableStore)
 		assert.NoError(t, err)
 
 		for j := 0; j < nKeys-i; j++ {
@@ -467,7 +703,7 @@ func TestIterFromKeySmallerThanFirst(t *testing.T) {
 	sst, nKeys, err := buildSSTWithNBlocks(2, tableStore, keyGen, valGen)
 	require.NoError(t, err)
 
-	kvIter, err := sstable.NewIteratorAtKey(sst, []byte("aaaaaaaaaaaaaaaa"), tableStore)
+	kvIter, err := sstable.NewIteratorAtKey(context.Background(), sst, []byte("aaaaaaaaaaaaaaaa"), tableStore)
 	assert.NoError(t, err)
 
 	for i := 0; i < nKeys; i++ {
@@ -491,7 +727,7 @@ func TestIterFromKeyLargerThanLast(t *testing.T) {
 
 	sst, _, err := buildSSTWithNBlocks(2, tableStore, keyGen, valGen)
 	require.NoError(t, err)
-	kvIter, err := sstable.NewIteratorAtKey(sst, []byte("zzzzzzzzzzzzzzzz"), tableStore)
+	kvIter, err := sstable.NewIteratorAtKey(context.Background(), sst, []byte("zzzzzzzzzzzzzzzz"), tableStore)
 	assert.NoError(t, err)
 
 	_, ok := kvIter.Next(context.Background())
@@ -539,7 +775,7 @@ func TestShouldGenerateOrderedBytes(t *testing.T) {
 
 func TestSSTWriter(t *testing.T) {
 	// Force key values into separate blocks
-	blockSize := block.V0EstimateBlockSize([]types.KeyValue{
+	blockSize := block.EstimateBlockSize([]types.KeyValue{
 		{Key: []byte("aaaaaaaaaaaaaaaa"), Value: []byte("1111111111111111")},
 	})
 
@@ -550,7 +786,7 @@ func TestSSTWriter(t *testing.T) {
 	tableStore := NewTableStore(bucket, conf, "")
 	sstID := sstable.NewIDCompacted(ulid.Make())
 
-	writer := tableStore.TableWriter(sstID)
+	writer := tableStore.TableWriter(context.Background(), sstID)
 	require.NoError(t, writer.Add([]byte("aaaaaaaaaaaaaaaa"), mo.Some([]byte("1111111111111111"))))
 	require.NoError(t, writer.Add([]byte("bbbbbbbbbbbbbbbb"), mo.Some([]byte("2222222222222222"))))
 	require.NoError(t, writer.Add([]byte("cccccccccccccccc"), mo.None[[]byte]()))
@@ -558,7 +794,7 @@ func TestSSTWriter(t *testing.T) {
 	sst, err := writer.Close()
 	assert.NoError(t, err)
 
-	iterator, err := sstable.NewIterator(sst, tableStore)
+	iterator, err := sstable.NewIterator(context.Background(), sst, tableStore)
 	assert.NoError(t, err)
 	assert2.NextEntry(t, iterator, []byte("aaaaaaaaaaaaaaaa"), []byte("1111111111111111"))
 	assert2.NextEntry(t, iterator, []byte("bbbbbbbbbbbbbbbb"), []byte("2222222222222222"))
@@ -567,3 +803,255 @@ func TestSSTWriter(t *testing.T) {
 	_, ok := iterator.NextEntry(context.Background())
 	assert.False(t, ok)
 }
+
+func TestSSTWriterWithSSTableOptions(t *testing.T) {
+	// Force key values into separate blocks
+	blockSize := block.EstimateBlockSize([]types.KeyValue{
+		{Key: []byte("aaaaaaaaaaaaaaaa"), Value: []byte("1111111111111111")},
+	})
+
+	bucket := objstore.NewInMemBucket()
+	tableStore := NewTableStore(bucket, sstable.DefaultConfig(), "")
+	sstID := sstable.NewIDCompacted(ulid.Make())
+
+	writer := tableStore.TableWriterWithOptions(context.Background(), sstID, TableWriterOptions{
+		SSTable: config.SSTableOptions{
+			BlockSize:        blockSize,
+			CompressionCodec: compress.CodecSnappy,
+		},
+	})
+	require.NoError(t, writer.Add([]byte("aaaaaaaaaaaaaaaa"), mo.Some([]byte("1111111111111111"))))
+	require.NoError(t, writer.Add([]byte("bbbbbbbbbbbbbbbb"), mo.Some([]byte("2222222222222222"))))
+	require.NoError(t, writer.Add([]byte("cccccccccccccccc"), mo.Some([]byte("3333333333333333"))))
+	sst, err := writer.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, blockSize, sst.Info.BlockSize)
+	assert.Equal(t, compress.CodecSnappy, sst.Info.CompressionCodec)
+	assert.Equal(t, sstable.DefaultConfig().FilterBitsPerKey, sst.Info.FilterBitsPerKey)
+
+	// The options are read from the SSTable, not the config of the TableStore
+	sst, err = tableStore.OpenSST(context.Background(), sstID)
+	require.NoError(t, err)
+	assert.Equal(t, blockSize, sst.Info.BlockSize)
+	index, err := tableStore.ReadIndex(context.Background(), sst)
+	require.NoError(t, err)
+	assert.Len(t, index.BlockMeta(), 3)
+
+	iterator, err := sstable.NewIterator(context.Background(), sst, tableStore)
+	require.NoError(t, err)
+	assert2.NextEntry(t, iterator, []byte("aaaaaaaaaaaaaaaa"), []byte("1111111111111111"))
+	assert2.NextEntry(t, iterator, []byte("bbbbbbbbbbbbbbbb"), []byte("2222222222222222"))
+	assert2.NextEntry(t, iterator, []byte("cccccccccccccccc"), []byte("3333333333333333"))
+	_, ok := iterator.NextEntry(context.Background())
+	assert.False(t, ok)
+}
+
+func TestTableStoreIOClass(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	tableStore := NewTableStore(bucket, sstable.DefaultConfig(), "")
+	sstID := sstable.NewIDCompacted(ulid.Make())
+
+	builder := tableStore.TableBuilder()
+	require.NoError(t, builder.AddValue([]byte("key1"), []byte("value1")))
+	table, err := builder.Build()
+	require.NoError(t, err)
+	sst, err := tableStore.WithIOClass(IOClassFlush).WriteSST(context.Background(), sstID, table)
+	require.NoError(t, err)
+
+	getStore := tableStore.WithIOClass(IOClassGet)
+	iter, err := sstable.NewIterator(context.Background(), sst, getStore.Clone())
+	require.NoError(t, err)
+	assert2.NextEntry(t, iter, []byte("key1"), []byte("value1"))
+
+	attrs, err := bucket.Attributes(context.Background(), tableStore.sstPath(sstID))
+	require.NoError(t, err)
+
+	stats := tableStore.IOStats().Snapshot()
+	assert.Len(t, stats, 2)
+	assert.Equal(t, IOClassStats{Requests: 1, BytesWritten: attrs.Size}, stats[IOClassFlush])
+	assert.Positive(t, stats[IOClassGet].Requests)
+	assert.Positive(t, stats[IOClassGet].BytesRead)
+	assert.Zero(t, stats[IOClassGet].BytesWritten)
+}
+
+// streamedBucket counts the bytes read by uploads before they complete
+type streamedBucket struct {
+	objstore.Bucket
+	streamed atomic.Int64
+}
+
+func (b *streamedBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	return b.Bucket.Upload(ctx, name, io.TeeReader(r, writerFunc(func(p []byte) (int, error) {
+		b.streamed.Add(int64(len(p)))
+		return len(p), nil
+	})))
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestSSTWriterStreamsParts(t *testing.T) {
+	bucket := &streamedBucket{Bucket: objstore.NewInMemBucket()}
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = 64
+	tableStore := NewTableStore(bucket, conf, "")
+	sstID := sstable.NewIDCompacted(ulid.Make())
+
+	writer := tableStore.TableWriterWithOptions(context.Background(), sstID, TableWriterOptions{PartSize: 256})
+	keyGen := common.NewOrderedBytesGeneratorWithByteRange([]byte("aaaaaaaaaaaaaaaa"), byte('a'), byte('z'))
+	for i := 0; i < 100; i++ {
+		require.NoError(t, writer.Add(keyGen.Next(), mo.Some([]byte("1111111111111111"))))
+	}
+
+	// Completed parts are uploaded before the SSTable is complete
+	assert.Greater(t, bucket.streamed.Load(), int64(256))
+
+	sst, err := writer.Close()
+	require.NoError(t, err)
+
+	iterator, err := sstable.NewIterator(context.Background(), sst, tableStore)
+	require.NoError(t, err)
+	keyGen = common.NewOrderedBytesGeneratorWithByteRange([]byte("aaaaaaaaaaaaaaaa"), byte('a'), byte('z'))
+	for i := 0; i < 100; i++ {
+		assert2.NextEntry(t, iterator, keyGen.Next(), []byte("1111111111111111"))
+	}
+	_, ok := iterator.NextEntry(context.Background())
+	assert.False(t, ok)
+
+	// An aborted upload leaves no object behind
+	sstID = sstable.NewIDCompacted(ulid.Make())
+	writer = tableStore.TableWriterWithOptions(context.Background(), sstID, TableWriterOptions{PartSize: 256})
+	for i := 0; i < 100; i++ {
+		require.NoError(t, writer.Add(keyGen.Next(), mo.Some([]byte("1111111111111111"))))
+	}
+	writer.Abort()
+	exists, err := bucket.Exists(context.Background(), tableStore.sstPath(sstID))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestSSTWriterPipeline(t *testing.T) {
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = 64
+	conf.Compression = compress.CodecSnappy
+	tableStore := NewTableStore(bucket, conf, "")
+
+	write := func(opts TableWriterOptions) (sstable.ID, TableWriterStats) {
+		sstID := sstable.NewIDCompacted(ulid.Make())
+		writer := tableStore.TableWriterWithOptions(context.Background(), sstID, opts)
+		keyGen := common.NewOrderedBytesGeneratorWithByteRange([]byte("aaaaaaaaaaaaaaaa"), byte('a'), byte('z'))
+		for i := 0; i < 200; i++ {
+			require.NoError(t, writer.Add(keyGen.Next(), mo.Some([]byte("1111111111111111"))))
+		}
+		_, err := writer.Close()
+		require.NoError(t, err)
+		return sstID, writer.Stats()
+	}
+	read := func(sstID sstable.ID) []byte {
+		r, err := bucket.Get(context.Background(), tableStore.sstPath(sstID))
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return data
+	}
+
+	// Encoding blocks and uploading parts while the next are built writes the
+	// same SSTable as writing it sequentially
+	sequential, _ := write(TableWriterOptions{PartSize: 256})
+	pipelined, stats := write(TableWriterOptions{PartSize: 256, PipelineDepth: 4})
+	assert.Equal(t, read(sequential), read(pipelined))
+	assert.NotZero(t, stats.Encode)
+	assert.NotZero(t, stats.Upload)
+	assert.GreaterOrEqual(t, stats.Total, stats.Build)
+}
+
+func TestWriteSSTIfNotExists(t *testing.T) {
+	tableStore := NewTableStore(objstore.NewInMemBucket(), sstable.DefaultConfig(), "")
+	build := func(value string) *sstable.Table {
+		builder := tableStore.TableBuilder()
+		require.NoError(t, builder.AddValue([]byte("key1"), []byte(value)))
+		table, err := builder.Build()
+		require.NoError(t, err)
+		return table
+	}
+
+	id := sstable.NewIDWal(1)
+	_, err := tableStore.WriteSSTIfNotExists(context.Background(), id, build("value1"))
+	require.NoError(t, err)
+
+	// Writing the same SSTable again succeeds, as when retrying a write whose
+	// response was lost
+	handle, err := tableStore.WriteSSTIfNotExists(context.Background(), id, build("value1"))
+	require.NoError(t, err)
+	assert.Equal(t, id, handle.Id)
+
+	_, err = tableStore.WriteSSTIfNotExists(context.Background(), id, build("value2"))
+	assert.ErrorIs(t, err, common.ErrObjectExists)
+}
+
+// blockingTableStore reads the first block, and blocks the reads of the blocks
+// which follow it until their context is canceled
+type blockingTableStore struct {
+	*TableStore
+	canceled atomic.Int64
+}
+
+func (b *blockingTableStore) ReadBlocksUsingIndex(
+	ctx context.Context, handle *sstable.Handle, r common.Range, index *sstable.Index,
+) ([]block.Block, error) {
+	if r.Start == 0 {
+		return b.TableStore.ReadBlocksUsingIndex(ctx, handle, r, index)
+	}
+	<-ctx.Done()
+	b.canceled.Add(1)
+	return nil, ctx.Err()
+}
+
+func TestIterCancelsPrefetchOnSeekAndClose(t *testing.T) {
+	// Force key values into separate blocks
+	blockSize := block.EstimateBlockSize([]types.KeyValue{
+		{Key: []byte("key000"), Value: []byte("value000")},
+	})
+
+	bucket := objstore.NewInMemBucket()
+	conf := sstable.DefaultConfig()
+	conf.BlockSize = blockSize
+	tableStore := NewTableStore(bucket, conf, "")
+	builder := tableStore.TableBuilder()
+	for i := 0; i < 50; i++ {
+		require.NoError(t, builder.AddValue([]byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%03d", i))))
+	}
+	encodedSST, err := builder.Build()
+	require.NoError(t, err)
+	sst, err := tableStore.WriteSST(context.Background(), sstable.NewIDWal(0), encodedSST)
+	require.NoError(t, err)
+
+	blocking := &blockingTableStore{TableStore: tableStore}
+	opts := sstable.IteratorOptions{PrefetchBlocks: 4}
+
+	// A Seek past the blocks in flight cancels their fetches
+	iterator, err := sstable.NewIteratorWithOptions(context.Background(), sst, blocking, opts)
+	require.NoError(t, err)
+	assert2.Next(t, iterator, []byte("key000"), []byte("value000"))
+	require.NoError(t, iterator.Seek(context.Background(), []byte("key040")))
+	require.Eventually(t, func() bool {
+		return blocking.canceled.Load() == 4
+	}, 5*time.Second, time.Millisecond)
+
+	// Close cancels the fetches in flight
+	iterator, err = sstable.NewIteratorWithOptions(context.Background(), sst, blocking, opts)
+	require.NoError(t, err)
+	assert2.Next(t, iterator, []byte("key000"), []byte("value000"))
+	iterator.Close()
+	require.Eventually(t, func() bool {
+		return blocking.canceled.Load() == 8
+	}, 5*time.Second, time.Millisecond)
+	_, ok := iterator.Next(context.Background())
+	assert.False(t, ok)
+}
@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+const (
+	// minThrottleLevel is the lowest level the ThrottleController backs off to
+	minThrottleLevel = 1.0 / 16
+	// throttleRecoveryStep is the level restored after each throttleRecoveryInterval
+	// without a throttled request
+	throttleRecoveryStep = 0.125
+	// throttleBackoffInterval is the minimum time between halvings of the level, so
+	// a burst of concurrent requests rejected together halve it only once
+	throttleBackoffInterval = time.Second
+	// throttleRecoveryInterval is the time without a throttled request after which
+	// the level is raised by throttleRecoveryStep
+	throttleRecoveryInterval = 5 * time.Second
+)
+
+// throttleCodes are the error codes with which object stores reject requests
+// they throttle, such as S3 'SlowDown' and Azure 'ServerBusy'
+var throttleCodes = map[string]bool{
+	"SlowDown":                 true,
+	"Throttling":               true,
+	"ThrottlingException":      true,
+	"RequestLimitExceeded":     true,
+	"TooManyRequests":          true,
+	"TooManyRequestsException": true,
+	"ServerBusy":               true,
+	"rateLimitExceeded":        true,
+}
+
+// IsThrottledErr returns true if the error was returned by an object store which
+// is throttling requests: an error which wraps common.ErrThrottled, or an error
+// of the response of the object store, such as the errors of the AWS SDK, whose
+// HTTP status is 429 or 503, or whose error code is a throttling code such as
+// 'SlowDown'. The message of the error is not inspected, as it may contain the
+// name of an object or a message which merely mentions throttling.
+func IsThrottledErr(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, common.ErrThrottled) {
+		return true
+	}
+	var status interface{ HTTPStatusCode() int }
+	if errors.As(err, &status) {
+		if code := status.HTTPStatusCode(); code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable {
+			return true
+		}
+	}
+	var coded interface{ ErrorCode() string }
+	return errors.As(err, &coded) && throttleCodes[coded.ErrorCode()]
+}
+
+// ThrottleClassifier returns the function which detects the requests of the
+// ObjectStore which were throttled: isThrottled if not nil, or IsThrottledErr
+// along with the IsThrottled method of an ObjectStore which has one, such as a
+// backend which recognizes the throttling errors of its client library.
+func ThrottleClassifier(objectStore ObjectStore, isThrottled func(error) bool) func(error) bool {
+	if isThrottled != nil {
+		return isThrottled
+	}
+	if c, ok := objectStore.(interface{ IsThrottled(error) bool }); ok {
+		return func(err error) bool {
+			return IsThrottledErr(err) || (err != nil && c.IsThrottled(err))
+		}
+	}
+	return IsThrottledErr
+}
+
+// ThrottleStats is the state of a ThrottleController
+type ThrottleStats struct {
+	// Level is the fraction of the configured background concurrency and rate in
+	// use, which is 1 unless the object store recently throttled requests
+	Level float64
+	// ThrottledRequests is the number of requests the object store throttled
+	ThrottledRequests int64
+	// LastThrottled is the time of the last throttled request, zero if none was
+	LastThrottled time.Time
+}
+
+// Throttled returns true if the background work is currently backed off
+func (s ThrottleStats) Throttled() bool {
+	return s.Level < 1
+}
+
+// ThrottleController adapts background work to the throttling signals of the
+// object store. The level is halved each time the object store throttles a
+// request, down to 1/16, and raised again in steps once requests are no longer
+// throttled. Background tasks scale their concurrency and rate by the level.
+type ThrottleController struct {
+	mu sync.Mutex
+	// level is the current level, held as the bits of a float64 so it can be read
+	// without taking mu
+	level         atomic.Uint64
+	lastThrottled time.Time
+	lastChange    time.Time
+	throttled     atomic.Int64
+	isThrottled   func(error) bool
+	now           func() time.Time
+}
+
+// NewThrottleController returns a ThrottleController which detects throttled
+// requests using isThrottled, or IsThrottledErr if nil
+func NewThrottleController(isThrottled func(error) bool) *ThrottleController {
+	if isThrottled == nil {
+		isThrottled = IsThrottledErr
+	}
+	c := &ThrottleController{isThrottled: isThrottled, now: time.Now}
+	c.level.Store(math.Float64bits(1))
+	return c
+}
+
+// Level returns the fraction of the configured background concurrency and rate
+// which background tasks should use
+func (c *ThrottleController) Level() float64 {
+	if c == nil {
+		return 1
+	}
+	c.restore()
+	return math.Float64frombits(c.level.Load())
+}
+
+// Scale scales n by the level, to no less than 1 if n is positive
+func (c *ThrottleController) Scale(n int) int {
+	if n <= 0 {
+		return n
+	}
+	return max(1, int(float64(n)*c.Level()))
+}
+
+// Stats returns the current state of the controller
+func (c *ThrottleController) Stats() ThrottleStats {
+	level := c.Level()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ThrottleStats{
+		Level:             level,
+		ThrottledRequests: c.throttled.Load(),
+		LastThrottled:     c.lastThrottled,
+	}
+}
+
+// Observe records the result of an object store request, which backs off if the
+// object store throttled the request
+func (c *ThrottleController) Observe(err error) {
+	if err == nil || !c.isThrottled(err) {
+		return
+	}
+	c.throttled.Add(1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.lastThrottled = now
+	if now.Sub(c.lastChange) < throttleBackoffInterval {
+		return
+	}
+	level := math.Float64frombits(c.level.Load())
+	c.level.Store(math.Float64bits(max(minThrottleLevel, level/2)))
+	c.lastChange = now
+}
+
+// restore raises the level by a step for each throttleRecoveryInterval which
+// passed without a throttled request
+func (c *ThrottleController) restore() {
+	if math.Float64frombits(c.level.Load()) >= 1 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	last := c.lastChange
+	if c.lastThrottled.After(last) {
+		last = c.lastThrottled
+	}
+	since := now.Sub(last)
+	if since < throttleRecoveryInterval {
+		return
+	}
+	steps := float64(since / throttleRecoveryInterval)
+	level := math.Float64frombits(c.level.Load())
+	c.level.Store(math.Float64bits(min(1, level+steps*throttleRecoveryStep)))
+	c.lastChange = now
+}
+
+// throttleObjectStore reports the result of every request made through the
+// ObjectStore to a ThrottleController
+type throttleObjectStore struct {
+	ObjectStore
+	controller *ThrottleController
+}
+
+// NewThrottleObjectStore returns an ObjectStore which reports the result of every
+// request to the controller
+func NewThrottleObjectStore(store ObjectStore, controller *ThrottleController) ObjectStore {
+	return &throttleObjectStore{ObjectStore: store, controller: controller}
+}
+
+func (s *throttleObjectStore) Put(ctx context.Context, path string, r io.Reader) error {
+	err := s.ObjectStore.Put(ctx, path, r)
+	s.controller.Observe(err)
+	return err
+}
+
+func (s *throttleObjectStore) PutIfNotExists(ctx context.Context, path string, r io.Reader) error {
+	err := s.ObjectStore.PutIfNotExists(ctx, path, r)
+	s.controller.Observe(err)
+	return err
+}
+
+func (s *throttleObjectStore) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	r, err := s.ObjectStore.Get(ctx, path)
+	s.controller.Observe(err)
+	return r, err
+}
+
+func (s *throttleObjectStore) GetRange(ctx context.Context, path string, off, length int64) (io.ReadCloser, error) {
+	r, err := s.ObjectStore.GetRange(ctx, path, off, length)
+	s.controller.Observe(err)
+	return r, err
+}
+
+func (s *throttleObjectStore) Head(ctx context.Context, path string) (ObjectMeta, error) {
+	meta, err := s.ObjectStore.Head(ctx, path)
+	s.controller.Observe(err)
+	return meta, err
+}
+
+func (s *throttleObjectStore) List(ctx context.Context, prefix string) ([]ObjectMeta, error) {
+	list, err := s.ObjectStore.List(ctx, prefix)
+	s.controller.Observe(err)
+	return list, err
+}
+
+func (s *throttleObjectStore) Delete(ctx context.Context, path string) error {
+	err := s.ObjectStore.Delete(ctx, path)
+	s.controller.Observe(err)
+	return err
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/common"
+)
+
+// responseError is an error of an object store response, with the methods of
+// the response errors of the AWS SDK
+type responseError struct {
+	status int
+	code   string
+}
+
+func (e responseError) Error() string       { return fmt.Sprintf("%d %s", e.status, e.code) }
+func (e responseError) HTTPStatusCode() int { return e.status }
+func (e responseError) ErrorCode() string   { return e.code }
+
+func TestIsThrottledErr(t *testing.T) {
+	assert.True(t, IsThrottledErr(fmt.Errorf("while reading: %w", common.ErrThrottled)))
+	assert.True(t, IsThrottledErr(fmt.Errorf("while reading: %w", responseError{status: 503, code: "SlowDown"})))
+	assert.True(t, IsThrottledErr(responseError{status: 429}))
+	assert.True(t, IsThrottledErr(responseError{status: 400, code: "RequestLimitExceeded"}))
+	assert.False(t, IsThrottledErr(responseError{status: 404, code: "NoSuchKey"}))
+	assert.False(t, IsThrottledErr(nil))
+	assert.False(t, IsThrottledErr(context.Canceled))
+
+	// The message is not inspected
+	assert.False(t, IsThrottledErr(errors.New("SlowDown: Please reduce your request rate.")))
+	assert.False(t, IsThrottledErr(errors.New("object throttled/00000000000000000503.sst not found")))
+}
+
+// throttlingStore is an ObjectStore which recognizes the errors of its client
+type throttlingStore struct {
+	ObjectStore
+}
+
+func (throttlingStore) IsThrottled(err error) bool {
+	return err.Error() == "busy"
+}
+
+func TestThrottleClassifier(t *testing.T) {
+	busy := errors.New("busy")
+	objectStore := throttlingStore{}
+	assert.True(t, ThrottleClassifier(objectStore, nil)(busy))
+	assert.True(t, ThrottleClassifier(objectStore, nil)(common.ErrThrottled))
+	assert.False(t, ThrottleClassifier(objectStore, nil)(nil))
+	assert.False(t, ThrottleClassifier(NewBucketObjectStore(objstore.NewInMemBucket()), nil)(busy))
+	assert.False(t, ThrottleClassifier(objectStore, func(error) bool { return false })(busy))
+}
+
+func TestThrottleControllerBacksOffAndRecovers(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewThrottleController(nil)
+	c.now = func() time.Time { return now }
+	assert.Equal(t, 1.0, c.Level())
+	assert.Equal(t, 8, c.Scale(8))
+
+	slowDown := responseError{status: 503, code: "SlowDown"}
+	c.Observe(slowDown)
+	assert.Equal(t, 0.5, c.Level())
+	assert.Equal(t, 4, c.Scale(8))
+
+	// Requests throttled together back off once
+	c.Observe(slowDown)
+	assert.Equal(t, 0.5, c.Level())
+
+	for i := 0; i < 10; i++ {
+		now = now.Add(throttleBackoffInterval)
+		c.Observe(slowDown)
+	}
+	assert.Equal(t, minThrottleLevel, c.Level())
+	assert.Equal(t, 1, c.Scale(8))
+	assert.Equal(t, 0, c.Scale(0))
+
+	stats := c.Stats()
+	assert.True(t, stats.Throttled())
+	assert.Equal(t, int64(12), stats.ThrottledRequests)
+	assert.Equal(t, now, stats.LastThrottled)
+
+	// Errors which are not throttling don't back off
+	c.Observe(errors.New("access denied"))
+	assert.Equal(t, int64(12), c.Stats().ThrottledRequests)
+
+	now = now.Add(throttleRecoveryInterval)
+	assert.Equal(t, minThrottleLevel+throttleRecoveryStep, c.Level())
+	now = now.Add(10 * throttleRecoveryInterval)
+	assert.Equal(t, 1.0, c.Level())
+	assert.False(t, c.Stats().Throttled())
+}
+
+// slowDownBucket rejects every upload as throttled
+type slowDownBucket struct {
+	objstore.Bucket
+}
+
+func (b slowDownBucket) Upload(context.Context, string, io.Reader) error {
+	return responseError{status: 503, code: "SlowDown"}
+}
+
+func TestThrottleObjectStore(t *testing.T) {
+	ctx := context.Background()
+	c := NewThrottleController(nil)
+	objectStore := NewThrottleObjectStore(NewBucketObjectStore(slowDownBucket{Bucket: objstore.NewInMemBucket()}), c)
+
+	_, err := objectStore.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, c.Level())
+
+	err = objectStore.Put(ctx, "obj", bytes.NewReader([]byte("data")))
+	require.Error(t, err)
+	assert.Equal(t, 0.5, c.Level())
+	assert.Equal(t, int64(1), c.Stats().ThrottledRequests)
+}
@@ -0,0 +1,33 @@
+package table
+
+// arenaBlockSize is the size of the blocks an arena allocates from
+const arenaBlockSize = 64 * 1024
+
+// arena allocates the entries of a KVTable from large blocks, so the heap holds a
+// few large allocations per KVTable rather than an allocation for every key and
+// value. Entries are never freed individually, the blocks are released together
+// once the KVTable is no longer referenced, such as when an ImmutableMemtable is
+// dropped after it is flushed to L0. An arena is not safe for concurrent use, and
+// is guarded by the lock of the KVTable which owns it.
+type arena struct {
+	block []byte
+}
+
+func newArena() *arena {
+	return &arena{}
+}
+
+// alloc returns a slice of n bytes. Entries larger than a quarter of a block are
+// allocated separately, so they don't waste the remainder of the current block.
+func (a *arena) alloc(n int) []byte {
+	if n > arenaBlockSize/4 {
+		return make([]byte, n)
+	}
+	if len(a.block)+n > cap(a.block) {
+		a.block = make([]byte, 0, arenaBlockSize)
+	}
+	start := len(a.block)
+	a.block = a.block[:start+n]
+	// Limit the capacity, so appending to the entry can't overwrite the next one
+	return a.block[start : start+n : start+n]
+}
@@ -1,7 +1,11 @@
 package table
 
 import (
+	"bytes"
+	"encoding/binary"
+	"slices"
 	"sync/atomic"
+	"time"
 
 	"github.com/huandu/skiplist"
 	"github.com/samber/mo"
@@ -14,76 +18,244 @@ import (
 // ------------------------------------------------
 
 type KVTable struct {
-	// skl skipList stores key ([]byte), value (Value) pairs
+	// skl maps the internal key ([]byte) of the newest version of an entry to the
+	// creation time and value of the version, see types.InternalKey.
 	skl *skiplist.SkipList
 
 	// size of KVTable changes when we put/delete a key
 	size atomic.Int64
 
+	// arena holds the keys and values of the entries in skl
+	arena *arena
+
 	// Initially this KVTable is part of a WAL and clients wait on isDurableCh channel to know if the WAL is durably
 	// committed to object store
 	// The WALFlushTask goroutine converts the WAL to ImmutableWAL(backed by this same KVTable),
 	// then flushes the ImmutableWAL to object store and
 	// then closes this channel to notify clients waiting on isDurableCh channel
 	isDurableCh chan bool
+
+	// mergeOperator combines a merge operand with the existing entry of its key,
+	// nil if the DB has no MergeOperator
+	mergeOperator types.MergeOperator
+
+	// rangeTombstones delete the entries of their range with a lower sequence
+	// number, both in this KVTable and in older tables
+	rangeTombstones types.RangeTombstones
 }
 
 func newKVTable() *KVTable {
 	return &KVTable{
-		skl:         skiplist.New(skiplist.Bytes),
+		skl:         newSkipList(),
+		arena:       newArena(),
 		isDurableCh: make(chan bool),
 	}
 }
 
+// newSkipList returns a skiplist ordered by internal key
+func newSkipList() *skiplist.SkipList {
+	return skiplist.New(internalKeyComparable{})
+}
+
+// internalKeyComparable orders the internal keys of a skiplist with
+// types.CompareInternalKeys
+type internalKeyComparable struct{}
+
+func (internalKeyComparable) Compare(lhs, rhs interface{}) int {
+	return types.CompareInternalKeys(lhs.([]byte), rhs.([]byte))
+}
+
+// CalcScore scores the key by its user key, as the skiplist orders keys by
+// score before comparing them, and the score of a user key never decreases as
+// the user key increases
+func (internalKeyComparable) CalcScore(key interface{}) float64 {
+	return skiplist.Bytes.CalcScore(types.UserKey(key.([]byte)))
+}
+
 func (t *KVTable) get(key []byte) mo.Option[types.Value] {
-	elem := t.skl.Get(key)
-	if elem == nil {
+	entry, ok := t.getEntry(key).Get()
+	if !ok {
 		return mo.None[types.Value]()
 	}
+	return mo.Some(entry.Value)
+}
 
-	val := elem.Value.([]byte)
-	return mo.Some(types.ValueFromBytes(val))
+// getEntry returns the newest version of the key, including its sequence number
+// and creation time
+func (t *KVTable) getEntry(key []byte) mo.Option[types.RowEntry] {
+	elem := t.skl.Find(types.SeekInternalKey(key, types.MaxSeq))
+	if elem == nil || !bytes.Equal(types.UserKey(elem.Key().([]byte)), key) {
+		return mo.None[types.RowEntry]()
+	}
+	return mo.Some(t.rangeTombstones.Apply(decodeEntry(elem.Key().([]byte), elem.Value.([]byte))))
+}
+
+// deleteRange adds the range tombstone, and returns the size in bytes of the
+// tombstone, which the caller adds to the size of the table
+func (t *KVTable) deleteRange(tombstone types.RangeTombstone) int64 {
+	tombstone.Start = bytes.Clone(tombstone.Start)
+	tombstone.End = bytes.Clone(tombstone.End)
+	t.rangeTombstones = append(t.rangeTombstones, tombstone)
+	return int64(len(tombstone.Start) + len(tombstone.End) + 8)
 }
 
 func (t *KVTable) put(key []byte, value []byte) int64 {
-	oldSize := t.existingKVSize(key)
-	valueDel := types.Value{
-		Kind:  types.KindKeyValue,
-		Value: value,
+	return t.set(types.RowEntry{Key: key, Value: types.Value{Value: value}})
+}
+
+func (t *KVTable) delete(key []byte) {
+	t.set(types.RowEntry{Key: key, Value: types.Value{Kind: types.KindTombStone}})
+}
+
+// entryHeaderLen is the length of the creation time which precedes the value of
+// an entry
+const entryHeaderLen = 8
+
+// set copies the internal key and the entry into the arena, and returns the size
+// in bytes of the entry. The kind and sequence number of the entry are held by
+// its internal key, and the rest of the entry is stored as the following, where
+// createdAt is the unix time in milliseconds or zero if the entry has no
+// creation time.
+//
+//	| createdAt (8 bytes) | value |
+//
+// Only the newest version of a key is kept, as reads only return the newest
+// version, and a snapshot of the DB reads a clone of the table. A version replaces
+// the versions of its key with the same or a lower sequence number, whatever their
+// kinds, and a version older than the newest version of its key is discarded, in
+// which case the returned size is zero. A merge operand is stored as the result of
+// applying the operand to the newest version of its key.
+func (t *KVTable) set(entry types.RowEntry) int64 {
+	key := entry.Key
+	existing, exists := t.getEntry(key).Get()
+	if exists && existing.Seq > entry.Seq {
+		return 0
+	}
+	if exists && entry.Value.Kind == types.KindMerge && t.mergeOperator != nil {
+		entry.Value = types.MergeValue(t.mergeOperator, key, existing.Value, entry.Value.Value)
+	}
+	var value []byte
+	if !entry.Value.IsTombstone() {
+		value = entry.Value.Value
 	}
-	valueBytes := valueDel.ToBytes()
-	t.skl.Set(key, valueBytes)
 
-	newSize := int64(len(key) + len(valueBytes))
+	oldSize := t.removeVersions(key, entry.Seq)
+	keyLen := len(key) + types.InternalKeyTrailerLen
+	buf := t.arena.alloc(keyLen + entryHeaderLen + len(value))
+	types.AppendInternalKey(buf[:0], key, entry.Seq, entry.Value.Kind)
+	header := buf[keyLen:]
+	var createdAt int64
+	if !entry.Created.IsZero() {
+		createdAt = entry.Created.UnixMilli()
+	}
+	binary.BigEndian.PutUint64(header, uint64(createdAt))
+	copy(header[entryHeaderLen:], value)
+	t.skl.Set(buf[:keyLen:keyLen], header)
+
+	newSize := int64(len(buf))
 	t.size.Add(newSize - oldSize)
 	return newSize
 }
 
-func (t *KVTable) delete(key []byte) {
-	oldSize := t.existingKVSize(key)
-	valueDel := types.Value{Kind: types.KindTombStone}
-	valueBytes := valueDel.ToBytes()
-	t.skl.Set(key, valueBytes)
+// removeVersions removes the versions of the key with a sequence number of at
+// most seq, and returns their size in bytes
+func (t *KVTable) removeVersions(key []byte, seq uint64) int64 {
+	var size int64
+	elem := t.skl.Find(types.SeekInternalKey(key, seq))
+	for elem != nil {
+		internalKey := elem.Key().([]byte)
+		if !bytes.Equal(types.UserKey(internalKey), key) {
+			break
+		}
+		next := elem.Next()
+		t.skl.RemoveElement(elem)
+		size += int64(len(internalKey) + len(elem.Value.([]byte)))
+		elem = next
+	}
+	return size
+}
 
-	newSize := int64(len(key) + len(valueBytes))
-	t.size.Add(newSize - oldSize)
+// decodeEntry decodes the entry of the internal key stored by set. The key of
+// the entry shares the memory of the internal key.
+func decodeEntry(internalKey []byte, b []byte) types.RowEntry {
+	key, seq, kind, _ := types.ParseInternalKey(internalKey)
+	entry := types.RowEntry{
+		Key: key,
+		Seq: seq,
+	}
+	if createdAt := int64(binary.BigEndian.Uint64(b)); createdAt != 0 {
+		entry.Created = time.UnixMilli(createdAt)
+	}
+	switch kind {
+	case types.KindTombStone:
+		entry.Value = types.Value{Kind: types.KindTombStone}
+	case types.KindMerge:
+		entry.Value = types.Value{Kind: types.KindMerge, Value: b[entryHeaderLen:]}
+	default:
+		entry.Value = types.Value{Kind: types.KindKeyValue, Value: b[entryHeaderLen:]}
+	}
+	return entry
 }
 
 func (t *KVTable) iter() *KVTableIterator {
-	return newKVTableIterator(t.skl.Front())
+	iter := newKVTableIterator(t.skl.Front())
+	iter.rangeTombstones = t.rangeTombstones
+	return iter
 }
 
 func (t *KVTable) rangeFrom(start []byte) *KVTableIterator {
-	elem := t.skl.Find(start)
-	return newKVTableIterator(elem)
+	elem := t.skl.Find(types.SeekInternalKey(start, types.MaxSeq))
+	iter := newKVTableIterator(elem)
+	iter.rangeTombstones = t.rangeTombstones
+	return iter
+}
+
+func (t *KVTable) rangeBetween(start []byte, end []byte, inclusivity Inclusivity) *KVTableIterator {
+	elem := t.skl.Front()
+	if start != nil {
+		elem = t.skl.Find(types.SeekInternalKey(start, types.MaxSeq))
+	}
+	return &KVTableIterator{
+		element:         elem,
+		end:             end,
+		includeEnd:      inclusivity == Closed,
+		rangeTombstones: t.rangeTombstones,
+	}
+}
+
+// reverseRangeBetween returns an iterator over the same keys as rangeBetween,
+// which iterates from the end of the range to its start
+func (t *KVTable) reverseRangeBetween(start []byte, end []byte, inclusivity Inclusivity) *KVTableIterator {
+	elem := t.skl.Back()
+	if end != nil {
+		// Find returns the newest version of the first key which is not less than
+		// end, such that the iterator starts at the key before it unless it is the
+		// included end key
+		elem = t.skl.Find(types.SeekInternalKey(end, types.MaxSeq))
+		if elem == nil {
+			elem = t.skl.Back()
+		} else if inclusivity == HalfOpen || !bytes.Equal(types.UserKey(elem.Key().([]byte)), end) {
+			elem = elem.Prev()
+		}
+	}
+	return &KVTableIterator{
+		element:         elem,
+		start:           start,
+		reverse:         true,
+		rangeTombstones: t.rangeTombstones,
+	}
 }
 
-func (t *KVTable) existingKVSize(key []byte) int64 {
-	value := t.get(key)
-	if value.IsPresent() {
-		return int64(len(key) + len(value.MustGet().ToBytes()))
+// rangeSize returns the size of every version of the key value pairs in the
+// range [start, end). A nil start or end leaves the range unbounded.
+func (t *KVTable) rangeSize(start []byte, end []byte) int64 {
+	iter := t.rangeBetween(start, end, HalfOpen)
+	var size int64
+	for elem := iter.peekElement(); elem != nil; elem = iter.peekElement() {
+		size += int64(len(elem.Key().([]byte)) + len(elem.Value.([]byte)))
+		iter.element = elem.Next()
 	}
-	return 0
+	return size
 }
 
 // AwaitWALFlush - This is called during DB.Put/DB.Delete to wait till the WAL is
@@ -92,6 +264,12 @@ func (t *KVTable) AwaitWALFlush() {
 	<-t.isDurableCh
 }
 
+// WALFlushed returns a channel which is closed once the WAL is durably committed
+// to object store, for clients which wait on the flush asynchronously
+func (t *KVTable) WALFlushed() <-chan bool {
+	return t.isDurableCh
+}
+
 // NotifyWALFlushed - This is called by WALFlushTask goroutine to notify any client waiting
 // on AwaitWALFlush that the WAL contents have been durably committed to object store
 func (t *KVTable) NotifyWALFlushed() {
@@ -111,7 +289,7 @@ func (t *KVTable) toBytes() []byte {
 }
 
 func (t *KVTable) clone() *KVTable {
-	skl := skiplist.New(skiplist.Bytes)
+	skl := newSkipList()
 	current := t.skl.Front()
 	for current != nil {
 		key := current.Key().([]byte)
@@ -121,8 +299,11 @@ func (t *KVTable) clone() *KVTable {
 	}
 
 	return &KVTable{
-		isDurableCh: make(chan bool),
-		skl:         skl,
+		isDurableCh:     make(chan bool),
+		skl:             skl,
+		arena:           newArena(),
+		mergeOperator:   t.mergeOperator,
+		rangeTombstones: slices.Clone(t.rangeTombstones),
 	}
 }
 
@@ -130,8 +311,35 @@ func (t *KVTable) clone() *KVTable {
 // KVTableIterator
 // ------------------------------------------------
 
+// Inclusivity decides whether the end key of a range is part of the range
+type Inclusivity int
+
+const (
+	// HalfOpen is the range [start, end), which excludes the end key
+	HalfOpen Inclusivity = iota
+	// Closed is the range [start, end], which includes the end key
+	Closed
+)
+
 type KVTableIterator struct {
 	element *skiplist.Element
+
+	// end is the upper bound of the iterator, nil if unbounded
+	end        []byte
+	includeEnd bool
+
+	// reverse iterates in descending order, where start is the inclusive lower
+	// bound of the iterator, nil if unbounded
+	reverse bool
+	start   []byte
+
+	// children are the iterators of the shards of a sharded memtable, which
+	// are merged in the order of the iterator
+	children []*KVTableIterator
+
+	// rangeTombstones of the table, whose covered entries NextEntry returns as
+	// tombstones
+	rangeTombstones types.RangeTombstones
 }
 
 func newKVTableIterator(element *skiplist.Element) *KVTableIterator {
@@ -140,6 +348,20 @@ func newKVTableIterator(element *skiplist.Element) *KVTableIterator {
 	}
 }
 
+// mergeKVTableIterators returns an iterator over the keys of every iterator, which
+// must hold disjoint sets of keys and iterate in the same direction
+func mergeKVTableIterators(iters []*KVTableIterator) *KVTableIterator {
+	if len(iters) == 1 {
+		return iters[0]
+	}
+	// Every shard holds the range tombstones of the memtable
+	return &KVTableIterator{
+		reverse:         iters[0].reverse,
+		children:        iters,
+		rangeTombstones: iters[0].rangeTombstones,
+	}
+}
+
 func (iter *KVTableIterator) Next() (mo.Option[types.KeyValue], error) {
 	for {
 		entry, err := iter.NextEntry()
@@ -162,17 +384,90 @@ func (iter *KVTableIterator) Next() (mo.Option[types.KeyValue], error) {
 	}
 }
 
+// NextEntry returns the newest version of the next key
 func (iter *KVTableIterator) NextEntry() (mo.Option[types.RowEntry], error) {
-	elem := iter.element
+	elem := iter.nextElement()
 	if elem == nil {
 		return mo.None[types.RowEntry](), nil
 	}
 
-	iter.element = iter.element.Next()
+	entry := decodeEntry(elem.Key().([]byte), elem.Value.([]byte))
+	return mo.Some(iter.rangeTombstones.Apply(entry)), nil
+}
 
-	valueBytes := elem.Value.([]byte)
-	return mo.Some(types.RowEntry{
-		Key:   elem.Key().([]byte),
-		Value: types.ValueFromBytes(valueBytes),
-	}), nil
+// nextElement returns the element of the newest version of the next key of the
+// skiplist, and skips the older versions of the key. It returns nil once the
+// iterator has passed its upper bound, or its lower bound if it is reversed.
+func (iter *KVTableIterator) nextElement() *skiplist.Element {
+	if iter.children != nil {
+		child := iter.nextChild()
+		if child == nil {
+			return nil
+		}
+		return child.nextElement()
+	}
+
+	elem := iter.peekElement()
+	if elem == nil {
+		return nil
+	}
+	key := types.UserKey(elem.Key().([]byte))
+	if iter.reverse {
+		// Moving backwards, the iterator is at the oldest version of the key, and
+		// the newest version is the first element of the key
+		next := elem.Prev()
+		for next != nil && bytes.Equal(types.UserKey(next.Key().([]byte)), key) {
+			elem, next = next, next.Prev()
+		}
+		iter.element = next
+	} else {
+		next := elem.Next()
+		for next != nil && bytes.Equal(types.UserKey(next.Key().([]byte)), key) {
+			next = next.Next()
+		}
+		iter.element = next
+	}
+	return elem
+}
+
+// peekElement returns the element of a version of the key which nextElement
+// returns next without advancing the iterator
+func (iter *KVTableIterator) peekElement() *skiplist.Element {
+	elem := iter.element
+	if elem == nil {
+		return nil
+	}
+	key := types.UserKey(elem.Key().([]byte))
+	if iter.reverse {
+		if iter.start != nil && bytes.Compare(key, iter.start) < 0 {
+			iter.element = nil
+			return nil
+		}
+	} else if iter.end != nil {
+		cmp := bytes.Compare(key, iter.end)
+		if cmp > 0 || (cmp == 0 && !iter.includeEnd) {
+			iter.element = nil
+			return nil
+		}
+	}
+	return elem
+}
+
+// nextChild returns the child whose next element comes first in the order of
+// the iterator, or nil if every child is exhausted
+func (iter *KVTableIterator) nextChild() *KVTableIterator {
+	var next *KVTableIterator
+	var nextKey []byte
+	for _, child := range iter.children {
+		elem := child.peekElement()
+		if elem == nil {
+			continue
+		}
+		key := types.UserKey(elem.Key().([]byte))
+		// Shards hold disjoint keys, so keys of different children are never equal
+		if next == nil || (bytes.Compare(key, nextKey) < 0) != iter.reverse {
+			next, nextKey = child, key
+		}
+	}
+	return next
 }
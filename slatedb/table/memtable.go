@@ -1,11 +1,14 @@
 package table
 
 import (
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/samber/mo"
 
 	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/common"
 )
 
 // ------------------------------------------------
@@ -14,42 +17,159 @@ import (
 
 type Memtable struct {
 	sync.RWMutex
-	table *KVTable
+
+	// shards partition the keys of the Memtable by key hash. Each shard has its
+	// own lock, so writes of keys in different shards do not contend.
+	shards []*memtableShard
 
 	// As WALs get written to Memtable, this value holds the ID of the last WAL that was written to Memtable
 	lastWalID mo.Option[uint64]
+
+	// lastSeq is the highest sequence number of the entries written to the Memtable
+	lastSeq atomic.Uint64
+}
+
+type memtableShard struct {
+	sync.RWMutex
+	table *KVTable
 }
 
 func NewMemtable() *Memtable {
-	return &Memtable{
-		table:     newKVTable(),
+	return NewShardedMemtable(1)
+}
+
+// NewShardedMemtable returns a Memtable whose keys are partitioned by key hash
+// into the provided number of skiplists. Iterators over the Memtable merge the
+// shards, so keys are returned in order regardless of the number of shards.
+func NewShardedMemtable(shards int) *Memtable {
+	if shards < 1 {
+		shards = 1
+	}
+	m := &Memtable{
+		shards:    make([]*memtableShard, shards),
 		lastWalID: mo.None[uint64](),
 	}
+	for i := range m.shards {
+		m.shards[i] = &memtableShard{table: newKVTable()}
+	}
+	return m
+}
+
+// WithMergeOperator sets the MergeOperator which combines merge operands with the
+// existing entry of their key, and returns the Memtable. It must be called before
+// the Memtable is written to.
+func (m *Memtable) WithMergeOperator(op types.MergeOperator) *Memtable {
+	for _, shard := range m.shards {
+		shard.table.mergeOperator = op
+	}
+	return m
 }
 
 // Put adds KeyValue and returns the size in bytes of the KeyValue added
 func (m *Memtable) Put(key []byte, value []byte) int64 {
+	shard := m.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	return shard.table.put(key, value)
+}
+
+// PutEntry puts or deletes the key of the entry, preserving the sequence number
+// and creation time of the entry, and returns the size in bytes of the entry added
+func (m *Memtable) PutEntry(entry types.RowEntry) int64 {
+	shard := m.shard(entry.Key)
+	shard.Lock()
+	defer shard.Unlock()
+	common.StoreMax(&m.lastSeq, entry.Seq)
+	return shard.table.set(entry)
+}
+
+// ApplyBatch puts or deletes the key of each entry like PutEntry, and returns the
+// size in bytes of the entries added. The Memtable is locked while the batch is
+// applied, so a Clone of the Memtable, or an iterator created concurrently with
+// ApplyBatch, observes either every entry of the batch or none of them.
+// Iterators over the Memtable itself observe writes made after they were
+// created, so readers which must not observe part of a later batch iterate a Clone.
+func (m *Memtable) ApplyBatch(entries []types.RowEntry) int64 {
 	m.Lock()
 	defer m.Unlock()
-	return m.table.put(key, value)
+	var size int64
+	for _, entry := range entries {
+		shard := m.shard(entry.Key)
+		shard.Lock()
+		common.StoreMax(&m.lastSeq, entry.Seq)
+		size += shard.table.set(entry)
+		shard.Unlock()
+	}
+	return size
 }
 
 func (m *Memtable) Get(key []byte) mo.Option[types.Value] {
-	m.RLock()
-	defer m.RUnlock()
-	return m.table.get(key)
+	shard := m.shard(key)
+	shard.RLock()
+	defer shard.RUnlock()
+	return shard.table.get(key)
+}
+
+// GetEntry returns the entry of the key like Get, including the sequence number
+// and creation time of the write. The size of the value is len(Value.Value).
+func (m *Memtable) GetEntry(key []byte) mo.Option[types.RowEntry] {
+	shard := m.shard(key)
+	shard.RLock()
+	defer shard.RUnlock()
+	return shard.table.getEntry(key)
 }
 
 func (m *Memtable) Delete(key []byte) {
+	shard := m.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.table.delete(key)
+}
+
+// DeleteRange deletes every key in the range [start, end) which was written with
+// a lower sequence number than seq, both in the Memtable and in older tables, and
+// returns the size in bytes of the range tombstone added
+func (m *Memtable) DeleteRange(start []byte, end []byte, seq uint64) int64 {
 	m.Lock()
 	defer m.Unlock()
-	m.table.delete(key)
+	common.StoreMax(&m.lastSeq, seq)
+
+	// Every shard holds the range tombstones, as the range spans the shards
+	tombstone := types.RangeTombstone{Start: start, End: end, Seq: seq}
+	var size int64
+	for _, shard := range m.shards {
+		shard.Lock()
+		size = shard.table.deleteRange(tombstone)
+		shard.Unlock()
+	}
+	m.shards[0].table.size.Add(size)
+	return size
+}
+
+// RangeTombstones returns the range tombstones added by DeleteRange
+func (m *Memtable) RangeTombstones() types.RangeTombstones {
+	shard := m.shards[0]
+	shard.RLock()
+	defer shard.RUnlock()
+	return shard.table.rangeTombstones
 }
 
 func (m *Memtable) Size() int64 {
-	m.RLock()
-	defer m.RUnlock()
-	return m.table.size.Load()
+	var size int64
+	for _, shard := range m.shards {
+		size += shard.table.size.Load()
+	}
+	return size
+}
+
+// Shards returns the number of skiplists the keys of the Memtable are partitioned into
+func (m *Memtable) Shards() int {
+	return len(m.shards)
+}
+
+// LastSeq returns the highest sequence number of the entries in the Memtable
+func (m *Memtable) LastSeq() uint64 {
+	return m.lastSeq.Load()
 }
 
 func (m *Memtable) LastWalID() mo.Option[uint64] {
@@ -67,25 +187,97 @@ func (m *Memtable) SetLastWalID(lastWalID uint64) {
 // RangeFrom returns a KVTableIterator that starts iterating from startKey,
 // if startKey is not present then the iterator starts from the next Key present which is higher than startKey
 func (m *Memtable) RangeFrom(startKey []byte) *KVTableIterator {
-	m.RLock()
-	defer m.RUnlock()
-	return m.table.rangeFrom(startKey)
+	return m.merge(func(t *KVTable) *KVTableIterator {
+		return t.rangeFrom(startKey)
+	})
+}
+
+// RangeBetween returns a KVTableIterator over the keys in the range [start, end),
+// or [start, end] if inclusivity is Closed. A nil start or end leaves the range
+// unbounded. The iterator stops at the end key without reading past it.
+func (m *Memtable) RangeBetween(start []byte, end []byte, inclusivity Inclusivity) *KVTableIterator {
+	return m.merge(func(t *KVTable) *KVTableIterator {
+		return t.rangeBetween(start, end, inclusivity)
+	})
+}
+
+// ReverseRangeBetween returns a KVTableIterator over the same keys as RangeBetween
+// in descending order, starting from the end of the range.
+func (m *Memtable) ReverseRangeBetween(start []byte, end []byte, inclusivity Inclusivity) *KVTableIterator {
+	return m.merge(func(t *KVTable) *KVTableIterator {
+		return t.reverseRangeBetween(start, end, inclusivity)
+	})
+}
+
+// RangeSize returns the size of the key value pairs in the range [start, end).
+// A nil start or end leaves the range unbounded.
+func (m *Memtable) RangeSize(start []byte, end []byte) int64 {
+	var size int64
+	for _, shard := range m.shards {
+		shard.RLock()
+		size += shard.table.rangeSize(start, end)
+		shard.RUnlock()
+	}
+	return size
 }
 
 func (m *Memtable) Iter() *KVTableIterator {
-	m.RLock()
-	defer m.RUnlock()
-	return m.table.iter()
+	return m.merge(func(t *KVTable) *KVTableIterator {
+		return t.iter()
+	})
+}
+
+// ReverseIter returns a KVTableIterator over every key in descending order
+func (m *Memtable) ReverseIter() *KVTableIterator {
+	return m.merge(func(t *KVTable) *KVTableIterator {
+		return t.reverseRangeBetween(nil, nil, HalfOpen)
+	})
 }
 
 func (m *Memtable) Clone() *Memtable {
 	m.RLock()
 	defer m.RUnlock()
 
-	return &Memtable{
-		table:     m.table.clone(),
+	clone := &Memtable{
+		shards:    make([]*memtableShard, len(m.shards)),
 		lastWalID: m.lastWalID,
 	}
+	clone.lastSeq.Store(m.lastSeq.Load())
+	for i, shard := range m.shards {
+		shard.RLock()
+		clone.shards[i] = &memtableShard{table: shard.table.clone()}
+		shard.RUnlock()
+	}
+	return clone
+}
+
+// shard returns the shard which holds the key
+func (m *Memtable) shard(key []byte) *memtableShard {
+	return m.shards[shardIndex(key, len(m.shards))]
+}
+
+// shardIndex returns the index of the shard which holds the key
+func shardIndex(key []byte, shards int) int {
+	if shards == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	return int(h.Sum32() % uint32(shards))
+}
+
+// merge returns an iterator which merges the iterator of each shard. The
+// Memtable is read locked, so the iterators aren't created during ApplyBatch.
+func (m *Memtable) merge(iter func(t *KVTable) *KVTableIterator) *KVTableIterator {
+	m.RLock()
+	defer m.RUnlock()
+	iters := make([]*KVTableIterator, 0, len(m.shards))
+	for _, shard := range m.shards {
+		shard.RLock()
+		iters = append(iters, iter(shard.table))
+		shard.RUnlock()
+	}
+	return mergeKVTableIterators(iters)
 }
 
 // ------------------------------------------------
@@ -94,21 +286,42 @@ func (m *Memtable) Clone() *Memtable {
 
 type ImmutableMemtable struct {
 	sync.RWMutex
-	table     *KVTable
+	// tables are the shards of the Memtable, which are no longer modified
+	tables    []*KVTable
 	lastWalID uint64
+	lastSeq   uint64
 }
 
 func NewImmutableMemtable(memtable *Memtable, lastWalID uint64) *ImmutableMemtable {
+	tables := make([]*KVTable, 0, len(memtable.shards))
+	for _, shard := range memtable.shards {
+		tables = append(tables, shard.table)
+	}
 	return &ImmutableMemtable{
-		table:     memtable.table,
+		tables:    tables,
 		lastWalID: lastWalID,
+		lastSeq:   memtable.LastSeq(),
 	}
 }
 
 func (im *ImmutableMemtable) Get(key []byte) mo.Option[types.Value] {
 	im.RLock()
 	defer im.RUnlock()
-	return im.table.get(key)
+	return im.tables[shardIndex(key, len(im.tables))].get(key)
+}
+
+// GetEntry returns the entry of the key, see Memtable.GetEntry
+func (im *ImmutableMemtable) GetEntry(key []byte) mo.Option[types.RowEntry] {
+	im.RLock()
+	defer im.RUnlock()
+	return im.tables[shardIndex(key, len(im.tables))].getEntry(key)
+}
+
+// RangeTombstones returns the range tombstones of the ImmutableMemtable, see Memtable.DeleteRange
+func (im *ImmutableMemtable) RangeTombstones() types.RangeTombstones {
+	im.RLock()
+	defer im.RUnlock()
+	return im.tables[0].rangeTombstones
 }
 
 func (im *ImmutableMemtable) LastWalID() uint64 {
@@ -117,18 +330,87 @@ func (im *ImmutableMemtable) LastWalID() uint64 {
 	return im.lastWalID
 }
 
-func (im *ImmutableMemtable) Iter() *KVTableIterator {
+// Size returns the size in bytes of the entries in the ImmutableMemtable
+func (im *ImmutableMemtable) Size() int64 {
 	im.RLock()
 	defer im.RUnlock()
-	return im.table.iter()
+	var size int64
+	for _, t := range im.tables {
+		size += t.size.Load()
+	}
+	return size
+}
+
+// LastSeq returns the highest sequence number of the entries in the ImmutableMemtable
+func (im *ImmutableMemtable) LastSeq() uint64 {
+	im.RLock()
+	defer im.RUnlock()
+	return im.lastSeq
+}
+
+// RangeBetween returns a KVTableIterator over the keys in the range [start, end),
+// or [start, end] if inclusivity is Closed. See Memtable.RangeBetween.
+func (im *ImmutableMemtable) RangeBetween(start []byte, end []byte, inclusivity Inclusivity) *KVTableIterator {
+	return im.merge(func(t *KVTable) *KVTableIterator {
+		return t.rangeBetween(start, end, inclusivity)
+	})
+}
+
+// ReverseRangeBetween returns a KVTableIterator over the same keys as RangeBetween
+// in descending order. See Memtable.ReverseRangeBetween.
+func (im *ImmutableMemtable) ReverseRangeBetween(start []byte, end []byte, inclusivity Inclusivity) *KVTableIterator {
+	return im.merge(func(t *KVTable) *KVTableIterator {
+		return t.reverseRangeBetween(start, end, inclusivity)
+	})
+}
+
+// RangeSize returns the size of the key value pairs in the range [start, end).
+// A nil start or end leaves the range unbounded.
+func (im *ImmutableMemtable) RangeSize(start []byte, end []byte) int64 {
+	im.RLock()
+	defer im.RUnlock()
+	var size int64
+	for _, t := range im.tables {
+		size += t.rangeSize(start, end)
+	}
+	return size
+}
+
+func (im *ImmutableMemtable) Iter() *KVTableIterator {
+	return im.merge(func(t *KVTable) *KVTableIterator {
+		return t.iter()
+	})
+}
+
+// ReverseIter returns a KVTableIterator over every key in descending order
+func (im *ImmutableMemtable) ReverseIter() *KVTableIterator {
+	return im.merge(func(t *KVTable) *KVTableIterator {
+		return t.reverseRangeBetween(nil, nil, HalfOpen)
+	})
 }
 
 func (im *ImmutableMemtable) Clone() *ImmutableMemtable {
 	im.RLock()
 	defer im.RUnlock()
 
+	tables := make([]*KVTable, 0, len(im.tables))
+	for _, t := range im.tables {
+		tables = append(tables, t.clone())
+	}
 	return &ImmutableMemtable{
-		table:     im.table.clone(),
+		tables:    tables,
 		lastWalID: im.lastWalID,
+		lastSeq:   im.lastSeq,
+	}
+}
+
+// merge returns an iterator which merges the iterator of each shard
+func (im *ImmutableMemtable) merge(iter func(t *KVTable) *KVTableIterator) *KVTableIterator {
+	im.RLock()
+	defer im.RUnlock()
+	iters := make([]*KVTableIterator, 0, len(im.tables))
+	for _, t := range im.tables {
+		iters = append(iters, iter(t))
 	}
+	return mergeKVTableIterators(iters)
 }
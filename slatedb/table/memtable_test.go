@@ -2,8 +2,13 @@ package table
 
 import (
 	"bytes"
+	"fmt"
+	"runtime"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/samber/mo"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/slatedb/slatedb-go/internal/types"
@@ -143,6 +148,113 @@ func TestMemtableRangeFromNonExistingKey(t *testing.T) {
 	}
 }
 
+func TestMemtableRangeBetween(t *testing.T) {
+	memtable := NewMemtable()
+	for _, key := range []string{"abc111", "abc222", "abc333", "abc444", "abc555"} {
+		memtable.Put([]byte(key), []byte("value"))
+	}
+	memtable.Delete([]byte("abc444"))
+
+	for _, tc := range []struct {
+		name        string
+		start       []byte
+		end         []byte
+		inclusivity Inclusivity
+		expected    []string
+	}{
+		{name: "half open", start: []byte("abc222"), end: []byte("abc444"),
+			expected: []string{"abc222", "abc333"}},
+		{name: "closed", start: []byte("abc222"), end: []byte("abc444"), inclusivity: Closed,
+			expected: []string{"abc222", "abc333", "abc444"}},
+		{name: "bounds between keys", start: []byte("abc2"), end: []byte("abc4"),
+			expected: []string{"abc222", "abc333"}},
+		{name: "unbounded start", end: []byte("abc333"),
+			expected: []string{"abc111", "abc222"}},
+		{name: "unbounded end", start: []byte("abc444"),
+			expected: []string{"abc444", "abc555"}},
+		{name: "empty", start: []byte("abc333"), end: []byte("abc333")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var keys []string
+			iter := memtable.RangeBetween(tc.start, tc.end, tc.inclusivity)
+			for {
+				entry, err := iter.NextEntry()
+				assert.NoError(t, err)
+				kv, ok := entry.Get()
+				if !ok {
+					break
+				}
+				keys = append(keys, string(kv.Key))
+			}
+			assert.Equal(t, tc.expected, keys)
+		})
+	}
+
+	immMemtable := NewImmutableMemtable(memtable, 1)
+	next, err := immMemtable.RangeBetween([]byte("abc444"), nil, HalfOpen).Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("abc555"), next.MustGet().Key)
+}
+
+func TestMemtableReverseRangeBetween(t *testing.T) {
+	memtable := NewMemtable()
+	for _, key := range []string{"abc111", "abc222", "abc333", "abc444", "abc555"} {
+		memtable.Put([]byte(key), []byte("value"))
+	}
+	memtable.Delete([]byte("abc444"))
+
+	for _, tc := range []struct {
+		name        string
+		start       []byte
+		end         []byte
+		inclusivity Inclusivity
+		expected    []string
+	}{
+		{name: "half open", start: []byte("abc222"), end: []byte("abc444"),
+			expected: []string{"abc333", "abc222"}},
+		{name: "closed", start: []byte("abc222"), end: []byte("abc444"), inclusivity: Closed,
+			expected: []string{"abc444", "abc333", "abc222"}},
+		{name: "bounds between keys", start: []byte("abc2"), end: []byte("abc4"), inclusivity: Closed,
+			expected: []string{"abc333", "abc222"}},
+		{name: "unbounded start", end: []byte("abc333"),
+			expected: []string{"abc222", "abc111"}},
+		{name: "unbounded end", start: []byte("abc444"),
+			expected: []string{"abc555", "abc444"}},
+		{name: "end past last key", start: []byte("abc5"), end: []byte("abc9"),
+			expected: []string{"abc555"}},
+		{name: "empty", start: []byte("abc333"), end: []byte("abc333")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var keys []string
+			iter := memtable.ReverseRangeBetween(tc.start, tc.end, tc.inclusivity)
+			for {
+				entry, err := iter.NextEntry()
+				assert.NoError(t, err)
+				kv, ok := entry.Get()
+				if !ok {
+					break
+				}
+				keys = append(keys, string(kv.Key))
+			}
+			assert.Equal(t, tc.expected, keys)
+		})
+	}
+
+	// Tombstones are skipped by Next
+	immMemtable := NewImmutableMemtable(memtable, 1)
+	iter := immMemtable.ReverseIter()
+	var keys []string
+	for {
+		kv, err := iter.Next()
+		assert.NoError(t, err)
+		if kv.IsAbsent() {
+			break
+		}
+		keys = append(keys, string(kv.MustGet().Key))
+	}
+	assert.Equal(t, []string{"abc555", "abc333", "abc222", "abc111"}, keys)
+}
+
 func TestImmMemtableOps(t *testing.T) {
 	kvPairs := []types.KeyValue{
 		{Key: []byte("abc111"), Value: []byte("value1")},
@@ -194,7 +306,7 @@ func TestMemtableClone(t *testing.T) {
 	clonedMemtable := memtable.Clone()
 	// verify that the contents are equal
 	assert.Equal(t, memtable.LastWalID(), clonedMemtable.LastWalID())
-	assert.True(t, bytes.Equal(memtable.table.toBytes(), clonedMemtable.table.toBytes()))
+	assert.True(t, bytes.Equal(memtable.shards[0].table.toBytes(), clonedMemtable.shards[0].table.toBytes()))
 
 	// verify that the clone does not point to same data in memory
 	key := []byte("abc333")
@@ -207,5 +319,326 @@ func TestMemtableClone(t *testing.T) {
 	clonedImmMemtable := immMemtable.Clone()
 	// verify that the contents are equal
 	assert.Equal(t, immMemtable.LastWalID(), clonedImmMemtable.LastWalID())
-	assert.True(t, bytes.Equal(immMemtable.table.toBytes(), clonedImmMemtable.table.toBytes()))
+	assert.True(t, bytes.Equal(immMemtable.tables[0].toBytes(), clonedImmMemtable.tables[0].toBytes()))
+}
+
+func TestShardedMemtable(t *testing.T) {
+	unsharded := NewMemtable()
+	memtable := NewShardedMemtable(4)
+	assert.Equal(t, 4, memtable.Shards())
+
+	var expected []string
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("key%03d", i))
+		memtable.Put(key, []byte("value"))
+		unsharded.Put(key, []byte("value"))
+		expected = append(expected, string(key))
+	}
+	memtable.Delete([]byte("key050"))
+	unsharded.Delete([]byte("key050"))
+	assert.Equal(t, unsharded.Size(), memtable.Size())
+	assert.Equal(t, unsharded.RangeSize([]byte("key010"), []byte("key020")),
+		memtable.RangeSize([]byte("key010"), []byte("key020")))
+
+	// Every shard holds some of the keys
+	for _, shard := range memtable.shards {
+		assert.NotZero(t, shard.table.size.Load())
+	}
+
+	collect := func(iter *KVTableIterator) []string {
+		var keys []string
+		for {
+			entry, err := iter.NextEntry()
+			assert.NoError(t, err)
+			kv, ok := entry.Get()
+			if !ok {
+				return keys
+			}
+			keys = append(keys, string(kv.Key))
+		}
+	}
+
+	// Iterators merge the shards in key order
+	assert.Equal(t, expected, collect(memtable.Iter()))
+	assert.Equal(t, expected[10:20], collect(memtable.RangeBetween([]byte("key010"), []byte("key020"), HalfOpen)))
+	assert.Equal(t, expected[95:], collect(memtable.RangeFrom([]byte("key095"))))
+	reversed := slices.Clone(expected[10:21])
+	slices.Reverse(reversed)
+	assert.Equal(t, reversed, collect(memtable.ReverseRangeBetween([]byte("key010"), []byte("key020"), Closed)))
+	assert.True(t, memtable.Get([]byte("key050")).MustGet().IsTombstone())
+
+	immMemtable := NewImmutableMemtable(memtable, 1)
+	assert.Equal(t, expected, collect(immMemtable.Iter()))
+	assert.Equal(t, []byte("value"), immMemtable.Get([]byte("key042")).MustGet().Value)
+	assert.Equal(t, expected, collect(immMemtable.Clone().Iter()))
+}
+
+func TestMemtablePutEntry(t *testing.T) {
+	created := time.UnixMilli(1700000000123)
+	memtable := NewShardedMemtable(2)
+	memtable.PutEntry(types.RowEntry{Key: []byte("key1"), Value: types.Value{Value: []byte("value1")}, Seq: 3, Created: created})
+	memtable.PutEntry(types.RowEntry{Key: []byte("key2"), Value: types.Value{Kind: types.KindTombStone}, Seq: 5})
+	memtable.PutEntry(types.RowEntry{Key: []byte("key3"), Value: types.Value{Value: []byte("value3")}, Seq: 4})
+	assert.Equal(t, uint64(5), memtable.LastSeq())
+
+	iter := memtable.Iter()
+	entry, err := iter.NextEntry()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("key1"), entry.MustGet().Key)
+	assert.Equal(t, []byte("value1"), entry.MustGet().Value.Value)
+	assert.Equal(t, uint64(3), entry.MustGet().Seq)
+	assert.True(t, created.Equal(entry.MustGet().Created))
+
+	entry, err = iter.NextEntry()
+	assert.NoError(t, err)
+	assert.True(t, entry.MustGet().Value.IsTombstone())
+	assert.Equal(t, uint64(5), entry.MustGet().Seq)
+	assert.True(t, entry.MustGet().Created.IsZero())
+
+	// GetEntry returns the metadata of the entry along with the value
+	got := memtable.GetEntry([]byte("key1")).MustGet()
+	assert.Equal(t, uint64(3), got.Seq)
+	assert.True(t, created.Equal(got.Created))
+	assert.Len(t, got.Value.Value, len("value1"))
+	assert.True(t, memtable.GetEntry([]byte("key2")).MustGet().Value.IsTombstone())
+	assert.True(t, memtable.GetEntry([]byte("key4")).IsAbsent())
+
+	// The sequence number is preserved when the memtable is frozen
+	immMemtable := NewImmutableMemtable(memtable, 1)
+	assert.Equal(t, uint64(4), immMemtable.GetEntry([]byte("key3")).MustGet().Seq)
+	assert.Equal(t, uint64(5), immMemtable.LastSeq())
+	assert.Equal(t, uint64(5), immMemtable.Clone().LastSeq())
+	entry, err = immMemtable.RangeBetween([]byte("key3"), nil, HalfOpen).NextEntry()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), entry.MustGet().Seq)
+}
+
+func TestMemtableApplyBatch(t *testing.T) {
+	memtable := NewShardedMemtable(4)
+	batch := func(n int) []types.RowEntry {
+		entries := make([]types.RowEntry, 0, 10)
+		for i := 0; i < 10; i++ {
+			entries = append(entries, types.RowEntry{
+				Key:   []byte(fmt.Sprintf("key%d", i)),
+				Value: types.Value{Value: []byte(fmt.Sprintf("batch%03d", n))},
+				Seq:   uint64(n*10 + i + 1),
+			})
+		}
+		return entries
+	}
+	size := memtable.ApplyBatch(batch(0))
+	assert.Equal(t, size, memtable.Size())
+	assert.Equal(t, uint64(10), memtable.LastSeq())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 1; n <= 100; n++ {
+			memtable.ApplyBatch(batch(n))
+		}
+	}()
+
+	// Every clone holds the keys of a single batch
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		iter := memtable.Clone().Iter()
+		var values [][]byte
+		for {
+			kv, err := iter.Next()
+			assert.NoError(t, err)
+			if kv.IsAbsent() {
+				break
+			}
+			values = append(values, kv.MustGet().Value)
+		}
+		assert.Len(t, values, 10)
+		for _, value := range values {
+			assert.Equal(t, values[0], value)
+		}
+	}
+	assert.Equal(t, uint64(1010), memtable.LastSeq())
+}
+
+func TestMemtableCopiesEntriesToArena(t *testing.T) {
+	memtable := NewMemtable()
+	key := []byte("key1")
+	value := []byte("value1")
+	memtable.Put(key, value)
+	memtable.Put([]byte("key2"), bytes.Repeat([]byte("v"), arenaBlockSize))
+	memtable.Delete([]byte("key3"))
+
+	// The memtable holds copies of the key and value passed to Put
+	copy(key, "xxxx")
+	copy(value, "xxxxxx")
+	assert.Equal(t, []byte("value1"), memtable.Get([]byte("key1")).MustGet().Value)
+	assert.Equal(t, arenaBlockSize, len(memtable.Get([]byte("key2")).MustGet().Value))
+	assert.True(t, memtable.Get([]byte("key3")).MustGet().IsTombstone())
+
+	// Entries allocated from the same block don't overlap
+	entry := memtable.Get([]byte("key1")).MustGet().Value
+	_ = append(entry, 'x')
+	assert.True(t, memtable.Get([]byte("key3")).MustGet().IsTombstone())
+}
+
+func BenchmarkMemtablePut(b *testing.B) {
+	value := bytes.Repeat([]byte("v"), 100)
+	keys := make([][]byte, 100_000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key%08d", i))
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		memtable := NewMemtable()
+		for _, key := range keys {
+			memtable.Put(key, value)
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N), "gc/op")
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+}
+
+// appendOperator merges operands by appending them to the existing value
+type appendOperator struct{}
+
+func (appendOperator) Merge(_ []byte, existing mo.Option[[]byte], operand []byte) []byte {
+	return append(bytes.Clone(existing.OrEmpty()), operand...)
+}
+
+func TestMemtableMergeOperator(t *testing.T) {
+	memtable := NewShardedMemtable(2).WithMergeOperator(appendOperator{})
+	merge := func(key string, operand string) {
+		memtable.PutEntry(types.RowEntry{Key: []byte(key), Value: types.Value{Kind: types.KindMerge, Value: []byte(operand)}})
+	}
+
+	// Operands without an older value are combined into a single operand
+	merge("key1", "a")
+	merge("key1", "b")
+	got := memtable.Get([]byte("key1")).MustGet()
+	assert.Equal(t, types.KindMerge, got.Kind)
+	assert.Equal(t, []byte("ab"), got.Value)
+
+	// Operands are applied to an existing value
+	memtable.Put([]byte("key2"), []byte("x"))
+	merge("key2", "y")
+	got = memtable.Get([]byte("key2")).MustGet()
+	assert.Equal(t, types.KindKeyValue, got.Kind)
+	assert.Equal(t, []byte("xy"), got.Value)
+
+	// Operands applied to a tombstone start from an absent value
+	memtable.Delete([]byte("key3"))
+	merge("key3", "z")
+	got = memtable.Get([]byte("key3")).MustGet()
+	assert.Equal(t, types.KindKeyValue, got.Kind)
+	assert.Equal(t, []byte("z"), got.Value)
+
+	// A clone keeps the operator
+	clone := memtable.Clone()
+	clone.PutEntry(types.RowEntry{Key: []byte("key1"), Value: types.Value{Kind: types.KindMerge, Value: []byte("c")}})
+	assert.Equal(t, []byte("abc"), clone.Get([]byte("key1")).MustGet().Value)
+}
+
+func TestMemtableDeleteRange(t *testing.T) {
+	memtable := NewShardedMemtable(2)
+	put := func(key string, seq uint64) {
+		memtable.PutEntry(types.RowEntry{Key: []byte(key), Value: types.Value{Value: []byte("value")}, Seq: seq})
+	}
+	put("a", 1)
+	put("b", 2)
+	put("c", 3)
+	size := memtable.Size()
+	memtable.DeleteRange([]byte("a"), []byte("c"), 4)
+	assert.Greater(t, memtable.Size(), size)
+	put("b", 5)
+
+	// Keys written before the tombstone are deleted, and the end of the range is excluded
+	assert.True(t, memtable.GetEntry([]byte("a")).MustGet().Value.IsTombstone())
+	assert.False(t, memtable.GetEntry([]byte("b")).MustGet().Value.IsTombstone())
+	assert.False(t, memtable.GetEntry([]byte("c")).MustGet().Value.IsTombstone())
+
+	var tombstones []string
+	iter := memtable.Iter()
+	for {
+		entry, err := iter.NextEntry()
+		assert.NoError(t, err)
+		if entry.IsAbsent() {
+			break
+		}
+		if entry.MustGet().Value.IsTombstone() {
+			tombstones = append(tombstones, string(entry.MustGet().Key))
+		}
+	}
+	assert.Equal(t, []string{"a"}, tombstones)
+
+	// The tombstone is kept by the ImmutableMemtable
+	imm := NewImmutableMemtable(memtable, 1)
+	assert.Equal(t, types.RangeTombstones{{Start: []byte("a"), End: []byte("c"), Seq: 4}}, imm.RangeTombstones())
+	assert.True(t, imm.GetEntry([]byte("a")).MustGet().Value.IsTombstone())
+}
+
+func TestMemtableVersions(t *testing.T) {
+	memtable := NewShardedMemtable(2)
+	put := func(key string, value string, seq uint64) {
+		v := types.Value{Value: []byte(value)}
+		if value == "" {
+			v = types.Value{Kind: types.KindTombStone}
+		}
+		memtable.PutEntry(types.RowEntry{Key: []byte(key), Value: v, Seq: seq})
+	}
+	put("a", "v1", 1)
+	put("b", "v1", 1)
+	put("ab", "v4", 4)
+	size := memtable.Size()
+
+	// A newer version replaces the older versions of its key
+	put("a", "v3", 3)
+	assert.Equal(t, size+int64(len("v3")-len("v1")), memtable.Size())
+	// A version older than the newest version of its key is discarded
+	put("a", "", 2)
+	assert.Equal(t, size+int64(len("v3")-len("v1")), memtable.Size())
+
+	assert.Equal(t, []byte("v3"), memtable.Get([]byte("a")).MustGet().Value)
+	assert.Equal(t, uint64(3), memtable.GetEntry([]byte("a")).MustGet().Seq)
+	assert.True(t, memtable.GetEntry([]byte("c")).IsAbsent())
+	assert.Equal(t, int64(0), memtable.RangeSize([]byte("c"), nil))
+
+	// Iterators return the newest version of each key
+	keys := func(iter *KVTableIterator) []string {
+		var result []string
+		for {
+			entry, err := iter.NextEntry()
+			assert.NoError(t, err)
+			if entry.IsAbsent() {
+				return result
+			}
+			e := entry.MustGet()
+			result = append(result, fmt.Sprintf("%s@%d", e.Key, e.Seq))
+		}
+	}
+	assert.Equal(t, []string{"a@3", "ab@4", "b@1"}, keys(memtable.Iter()))
+	assert.Equal(t, []string{"b@1", "ab@4", "a@3"}, keys(memtable.ReverseIter()))
+	assert.Equal(t, []string{"ab@4", "b@1"}, keys(memtable.RangeFrom([]byte("aa"))))
+	assert.Equal(t, []string{"a@3", "ab@4"}, keys(memtable.RangeBetween([]byte("a"), []byte("b"), HalfOpen)))
+	assert.Equal(t, []string{"ab@4", "a@3"}, keys(memtable.ReverseRangeBetween([]byte("a"), []byte("ab"), Closed)))
+	assert.Equal(t, []string{"a@3"}, keys(memtable.ReverseRangeBetween(nil, []byte("ab"), HalfOpen)))
+
+	// A version with the sequence number of an existing version replaces it
+	size = memtable.Size()
+	put("a", "", 3)
+	assert.Equal(t, size-int64(len("v3")), memtable.Size())
+	assert.True(t, memtable.Get([]byte("a")).MustGet().IsTombstone())
+
+	clone := memtable.Clone()
+	assert.True(t, clone.Get([]byte("a")).MustGet().IsTombstone())
+	assert.Equal(t, []string{"a@3", "ab@4", "b@1"}, keys(clone.Iter()))
 }
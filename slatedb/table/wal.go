@@ -23,6 +23,13 @@ func NewWAL() *WAL {
 	}
 }
 
+// WithMergeOperator sets the MergeOperator which combines merge operands with the
+// existing entry of their key, see Memtable.WithMergeOperator
+func (w *WAL) WithMergeOperator(op types.MergeOperator) *WAL {
+	w.table.mergeOperator = op
+	return w
+}
+
 func (w *WAL) Put(key []byte, value []byte) int64 {
 	w.Lock()
 	defer w.Unlock()
@@ -35,12 +42,48 @@ func (w *WAL) Get(key []byte) mo.Option[types.Value] {
 	return w.table.get(key)
 }
 
+// GetEntry returns the entry of the key, see Memtable.GetEntry
+func (w *WAL) GetEntry(key []byte) mo.Option[types.RowEntry] {
+	w.RLock()
+	defer w.RUnlock()
+	return w.table.getEntry(key)
+}
+
 func (w *WAL) Delete(key []byte) {
 	w.Lock()
 	defer w.Unlock()
 	w.table.delete(key)
 }
 
+// Write adds the range tombstones, and puts or deletes each of the entries while
+// holding the lock, so readers observe either none or all of the writes. The
+// sequence number and creation time of each entry are preserved.
+func (w *WAL) Write(tombstones types.RangeTombstones, entries []types.RowEntry) {
+	w.Lock()
+	defer w.Unlock()
+	for _, tombstone := range tombstones {
+		w.table.size.Add(w.table.deleteRange(tombstone))
+	}
+	for _, entry := range entries {
+		w.table.set(entry)
+	}
+}
+
+// DeleteRange adds a range tombstone, see Memtable.DeleteRange
+func (w *WAL) DeleteRange(start []byte, end []byte, seq uint64) {
+	w.Lock()
+	defer w.Unlock()
+	size := w.table.deleteRange(types.RangeTombstone{Start: start, End: end, Seq: seq})
+	w.table.size.Add(size)
+}
+
+// RangeTombstones returns the range tombstones added by DeleteRange
+func (w *WAL) RangeTombstones() types.RangeTombstones {
+	w.RLock()
+	defer w.RUnlock()
+	return w.table.rangeTombstones
+}
+
 func (w *WAL) Table() *KVTable {
 	w.RLock()
 	defer w.RUnlock()
@@ -59,6 +102,14 @@ func (w *WAL) Iter() *KVTableIterator {
 	return w.table.iter()
 }
 
+// RangeBetween returns a KVTableIterator over the keys in the range [start, end),
+// or [start, end] if inclusivity is Closed. See Memtable.RangeBetween.
+func (w *WAL) RangeBetween(start []byte, end []byte, inclusivity Inclusivity) *KVTableIterator {
+	w.RLock()
+	defer w.RUnlock()
+	return w.table.rangeBetween(start, end, inclusivity)
+}
+
 func (w *WAL) Clone() *WAL {
 	w.RLock()
 	defer w.RUnlock()
@@ -91,6 +142,20 @@ func (iw *ImmutableWAL) Get(key []byte) mo.Option[types.Value] {
 	return iw.table.get(key)
 }
 
+// GetEntry returns the entry of the key, see Memtable.GetEntry
+func (iw *ImmutableWAL) GetEntry(key []byte) mo.Option[types.RowEntry] {
+	iw.RLock()
+	defer iw.RUnlock()
+	return iw.table.getEntry(key)
+}
+
+// RangeTombstones returns the range tombstones of the ImmutableWAL, see WAL.DeleteRange
+func (iw *ImmutableWAL) RangeTombstones() types.RangeTombstones {
+	iw.RLock()
+	defer iw.RUnlock()
+	return iw.table.rangeTombstones
+}
+
 func (iw *ImmutableWAL) ID() uint64 {
 	iw.RLock()
 	defer iw.RUnlock()
@@ -109,6 +174,14 @@ func (iw *ImmutableWAL) Iter() *KVTableIterator {
 	return iw.table.iter()
 }
 
+// RangeBetween returns a KVTableIterator over the keys in the range [start, end),
+// or [start, end] if inclusivity is Closed. See Memtable.RangeBetween.
+func (iw *ImmutableWAL) RangeBetween(start []byte, end []byte, inclusivity Inclusivity) *KVTableIterator {
+	iw.RLock()
+	defer iw.RUnlock()
+	return iw.table.rangeBetween(start, end, inclusivity)
+}
+
 func (iw *ImmutableWAL) Clone() *ImmutableWAL {
 	iw.RLock()
 	defer iw.RUnlock()
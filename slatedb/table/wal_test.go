@@ -35,6 +35,18 @@ func TestWALOps(t *testing.T) {
 	assert.True(t, wal.Get(kvPairs[1].Key).MustGet().IsTombstone())
 }
 
+func TestWALWrite(t *testing.T) {
+	wal := NewWAL()
+	wal.Put([]byte("abc222"), []byte("value2"))
+	wal.Write(nil, []types.RowEntry{
+		{Key: []byte("abc111"), Value: types.Value{Value: []byte("value1")}},
+		{Key: []byte("abc222"), Value: types.Value{Kind: types.KindTombStone}},
+	})
+
+	assert.Equal(t, []byte("value1"), wal.Get([]byte("abc111")).MustGet().Value)
+	assert.True(t, wal.Get([]byte("abc222")).MustGet().IsTombstone())
+}
+
 func TestWALIter(t *testing.T) {
 	kvPairs := []types.KeyValue{
 		{Key: []byte("abc111"), Value: []byte("value1")},
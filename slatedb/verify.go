@@ -0,0 +1,209 @@
+package slatedb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/samber/mo"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/internal/iter"
+	"github.com/slatedb/slatedb-go/internal/sstable"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/state"
+	"github.com/slatedb/slatedb-go/slatedb/store"
+)
+
+// VerifyOptions are the options of VerifyCheckpoint
+type VerifyOptions struct {
+	// The range [Start, End) of keys compared. A nil Start or End leaves the
+	// range unbounded.
+	Start []byte
+	End   []byte
+
+	// The fraction of keys compared, chosen by the hash of the key so the same
+	// keys are compared each time. Zero or one compares every key.
+	SampleRate float64
+}
+
+// Divergence is a key whose value in the checkpoint differs from its value in the
+// live DB, although the key was not written after the checkpoint
+type Divergence struct {
+	Key []byte
+	// The values of the key in the checkpoint and the live DB, nil if the key is
+	// absent or deleted
+	Checkpoint []byte
+	Live       []byte
+}
+
+// VerifyReport is the result of VerifyCheckpoint
+type VerifyReport struct {
+	// The manifest versions compared and the sequence number of the most recent
+	// write included in the checkpoint
+	CheckpointManifestID uint64
+	LiveManifestID       uint64
+	CheckpointSeq        uint64
+
+	// Compared is the number of keys compared, and Skipped is the number of keys
+	// not compared because they were written to the live DB after the checkpoint
+	Compared int
+	Skipped  int
+
+	Divergences []Divergence
+}
+
+// VerifyCheckpoint compares the keys of the checkpoint stored in the manifest
+// version checkpointID with the latest manifest version of the DB at dbPath,
+// without opening the DB. Keys written to the live DB after the checkpoint
+// sequence number are expected to differ and are skipped, so any remaining
+// Divergence indicates the checkpoint no longer matches the data it was taken
+// from. Both manifest versions are pinned while they are compared.
+func VerifyCheckpoint(
+	ctx context.Context,
+	bucket objstore.Bucket,
+	dbPath string,
+	checkpointID uint64,
+	opts VerifyOptions,
+) (VerifyReport, error) {
+	layout, err := store.LoadLayout(ctx, store.NewBucketObjectStore(bucket), dbPath)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	manifestStore := store.NewManifestStore(dbPath, bucket)
+	manifestStore.SetLayout(layout)
+	tableStore := store.NewTableStore(bucket, sstable.DefaultConfig(), dbPath)
+	tableStore.SetLayout(layout)
+	tableStore = tableStore.WithIOClass(store.IOClassScan)
+
+	liveID, err := manifestStore.LatestManifestID()
+	if err != nil {
+		return VerifyReport{}, fmt.Errorf("while reading latest manifest: %w", err)
+	}
+
+	checkpoint, unpinCheckpoint, err := readPinnedManifest(manifestStore, checkpointID, opts)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	defer unpinCheckpoint()
+
+	live, unpinLive, err := readPinnedManifest(manifestStore, liveID, opts)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	defer unpinLive()
+
+	report := VerifyReport{
+		CheckpointManifestID: checkpointID,
+		LiveManifestID:       liveID,
+		CheckpointSeq:        checkpoint.LastL0Seq.Load(),
+	}
+
+	sstOpts := sstable.IteratorOptions{UpperBound: opts.End}
+	checkpointIter, err := newCoreIterator(ctx, checkpoint, tableStore, opts.Start, sstOpts)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	liveIter, err := newCoreIterator(ctx, live, tableStore, opts.Start, sstOpts)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	next := func(it *iter.MergeSort) (types.RowEntry, bool) {
+		entry, ok := it.NextEntry(ctx)
+		if !ok || (opts.End != nil && bytes.Compare(entry.Key, opts.End) >= 0) {
+			return types.RowEntry{}, false
+		}
+		return entry, true
+	}
+
+	cEntry, cOk := next(checkpointIter)
+	lEntry, lOk := next(liveIter)
+	for cOk || lOk {
+		var key []byte
+		var c, l mo.Option[types.RowEntry]
+		switch {
+		case !lOk || (cOk && bytes.Compare(cEntry.Key, lEntry.Key) < 0):
+			key, c = cEntry.Key, mo.Some(cEntry)
+			cEntry, cOk = next(checkpointIter)
+		case !cOk || bytes.Compare(lEntry.Key, cEntry.Key) < 0:
+			key, l = lEntry.Key, mo.Some(lEntry)
+			lEntry, lOk = next(liveIter)
+		default:
+			key, c, l = cEntry.Key, mo.Some(cEntry), mo.Some(lEntry)
+			cEntry, cOk = next(checkpointIter)
+			lEntry, lOk = next(liveIter)
+		}
+
+		if !sampleKey(key, opts.SampleRate) {
+			continue
+		}
+		if entry, ok := l.Get(); ok && entry.Seq > report.CheckpointSeq {
+			report.Skipped++
+			continue
+		}
+
+		report.Compared++
+		cValue, lValue := liveValue(c), liveValue(l)
+		if !bytes.Equal(cValue, lValue) || (cValue == nil) != (lValue == nil) {
+			report.Divergences = append(report.Divergences, Divergence{
+				Key:        key,
+				Checkpoint: cValue,
+				Live:       lValue,
+			})
+		}
+	}
+
+	if err := checkpointIter.Warnings().If(); err != nil {
+		return report, fmt.Errorf("while reading checkpoint: %w", err)
+	}
+	if err := liveIter.Warnings().If(); err != nil {
+		return report, fmt.Errorf("while reading live DB: %w", err)
+	}
+	return report, nil
+}
+
+// readPinnedManifest pins the manifest version compared by
+// VerifyCheckpoint and reads it, returning a func which removes the pin
+func readPinnedManifest(
+	manifestStore *store.ManifestStore,
+	id uint64,
+	opts VerifyOptions,
+) (*state.CoreStateSnapshot, func(), error) {
+	pin, err := manifestStore.PinManifestReader(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	unpin := func() { _ = manifestStore.UnpinManifestReader(pin) }
+
+	core, err := manifestStore.ReadManifest(id)
+	if err != nil {
+		unpin()
+		return nil, nil, err
+	}
+	return core.WithinRange(opts.Start, opts.End), unpin, nil
+}
+
+// liveValue returns the value of the entry, or nil if the entry is absent or a tombstone
+func liveValue(entry mo.Option[types.RowEntry]) []byte {
+	e, ok := entry.Get()
+	if !ok || e.Value.IsTombstone() {
+		return nil
+	}
+	if e.Value.Value == nil {
+		return []byte{}
+	}
+	return e.Value.Value
+}
+
+// sampleKey returns true if the key is in the sample of keys compared at the rate
+func sampleKey(key []byte, rate float64) bool {
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write(key)
+	return float64(h.Sum32()) < rate*math.MaxUint32
+}
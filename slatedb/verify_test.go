@@ -0,0 +1,66 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+func TestVerifyCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	dbPath := "/tmp/test_kv_store"
+	db, err := Open(ctx, dbPath, bucket, testDBOptions(0, 1024*1024))
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, db.Put(ctx, []byte(fmt.Sprintf("a-%03d", i)), []byte(fmt.Sprintf("value%02d", i))))
+	}
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+	checkpoint, err := db.manifestStore.LatestManifestID()
+	require.NoError(t, err)
+
+	// Writes after the checkpoint are not divergences
+	require.NoError(t, db.Put(ctx, []byte("a-003"), []byte("updated")))
+	require.NoError(t, db.Delete(ctx, []byte("a-004")))
+	require.NoError(t, db.Put(ctx, []byte("b-000"), []byte("new")))
+	require.NoError(t, db.FlushMemtableToL0(ctx))
+
+	report, err := VerifyCheckpoint(ctx, bucket, dbPath, checkpoint, VerifyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 8, report.Compared)
+	assert.Equal(t, 3, report.Skipped)
+	assert.Empty(t, report.Divergences)
+
+	// Ingested entries carry a sequence number after the checkpoint, so they are
+	// skipped like the writes after the checkpoint
+	require.NoError(t, db.IngestSST(ctx, writeIngestFile(t, t.TempDir(), "a", 2)))
+	report, err = VerifyCheckpoint(ctx, bucket, dbPath, checkpoint, VerifyOptions{End: []byte("a-005")})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Compared)
+	assert.Equal(t, 4, report.Skipped)
+	assert.Empty(t, report.Divergences)
+
+	// Both manifest versions are unpinned once verified
+	pins, err := db.manifestStore.ListPins()
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+}
+
+func TestSampleKey(t *testing.T) {
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key-%04d", i))
+		assert.True(t, sampleKey(key, 0))
+		if sampleKey(key, 0.25) {
+			sampled++
+		}
+		assert.Equal(t, sampleKey(key, 0.25), sampleKey(key, 0.25))
+	}
+	assert.InDelta(t, 250, sampled, 75)
+}
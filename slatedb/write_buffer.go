@@ -0,0 +1,45 @@
+package slatedb
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// writeBufferMember reports the memtables of a DB to the writebuffer.Manager of
+// DBOptions.WriteBufferManager, and flushes the memtable when requested
+type writeBufferMember struct {
+	db *DB
+
+	// flushPending is true from when a flush is requested until the memtable flush
+	// task freezes the memtable, so the Manager doesn't request it again meanwhile
+	flushPending atomic.Bool
+}
+
+func (w *writeBufferMember) MemtableBytes() (int64, int64) {
+	return w.db.state.Memtable().Size(), w.db.state.ImmMemtableBytes()
+}
+
+func (w *writeBufferMember) FlushMemtable() {
+	if !w.flushPending.CompareAndSwap(false, true) {
+		return
+	}
+	select {
+	case w.db.memtableFlushNotifierCh <- FlushMemtable:
+	default:
+		// The flush task is busy, the Manager requests the flush again after the next write
+		w.flushPending.Store(false)
+	}
+}
+
+// flushWriteBuffer freezes the memtable as of the last WAL applied to it in full, and
+// flushes it to L0 as requested by the writebuffer.Manager. The WAL being applied
+// to the memtable is replayed in full on recovery, which is idempotent.
+func (db *DB) flushWriteBuffer(ctx context.Context, flusher *MemtableFlusher) error {
+	db.writeBuffer.flushPending.Store(false)
+	lastWalID, ok := db.state.Memtable().LastWalID().Get()
+	if !ok || db.state.Memtable().Size() == 0 {
+		return nil
+	}
+	db.state.FreezeMemtable(lastWalID)
+	return flusher.flushImmMemtablesToL0(ctx)
+}
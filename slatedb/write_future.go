@@ -0,0 +1,101 @@
+package slatedb
+
+import (
+	"context"
+	"slices"
+
+	"github.com/slatedb/slatedb-go/internal/assert"
+	"github.com/slatedb/slatedb-go/internal/types"
+	"github.com/slatedb/slatedb-go/slatedb/table"
+)
+
+// WriteFuture resolves once a write is durably committed to object storage.
+//
+// Concurrent writes are applied to the same WAL, which is written to object
+// storage as a single object every DBOptions.FlushInterval, or once it reaches
+// DBOptions.MaxWALBytes. The futures of every write in the WAL resolve together,
+// so many writers share the latency and the cost of a single PUT. With
+// DBOptions.DisableWAL, the futures resolve once the writes are flushed to L0.
+type WriteFuture struct {
+	done <-chan bool
+	seq  uint64
+}
+
+// newWriteFuture returns the future of a write to the WAL, whose last write was
+// assigned the sequence number seq
+func newWriteFuture(wal *table.WAL, seq uint64) *WriteFuture {
+	return &WriteFuture{done: wal.Table().WALFlushed(), seq: seq}
+}
+
+// Seq returns the sequence number of the write, which is the sequence number of
+// the last write of a WriteBatch. The write is durable once DB.CommittedSeq
+// reaches it.
+func (f *WriteFuture) Seq() uint64 {
+	return f.seq
+}
+
+// Done returns a channel which is closed once the write is durable
+func (f *WriteFuture) Done() <-chan bool {
+	return f.done
+}
+
+// Wait blocks until the write is durable, or returns the error of the context
+// if it is done first. The write is still flushed when the context is done.
+func (f *WriteFuture) Wait(ctx context.Context) error {
+	select {
+	case <-f.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PutAsync writes the key and value without waiting for the write to be durable,
+// returning a WriteFuture which resolves once it is. The value is readable with
+// config.Uncommitted as soon as PutAsync returns. Returns common.ErrReadOnly if
+// the DB is read-only, see config.BackgroundErrorReadOnly, or the error of the
+// context if it is done while the write is stalled, in which case nothing is
+// written.
+func (db *DB) PutAsync(ctx context.Context, key []byte, value []byte) (*WriteFuture, error) {
+	assert.True(len(key) > 0, "key cannot be empty")
+
+	entries := []types.RowEntry{{Key: key, Value: types.Value{Value: value}}}
+	return db.writeAsync(ctx, entries)
+}
+
+// DeleteAsync deletes the key like PutAsync
+func (db *DB) DeleteAsync(ctx context.Context, key []byte) (*WriteFuture, error) {
+	assert.True(len(key) > 0, "key cannot be empty")
+
+	entries := []types.RowEntry{{Key: key, Value: types.Value{Kind: types.KindTombStone}}}
+	return db.writeAsync(ctx, entries)
+}
+
+// writeAsync writes the entry and returns its future
+func (db *DB) writeAsync(ctx context.Context, entries []types.RowEntry) (*WriteFuture, error) {
+	wal, err := db.writeEntries(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+	return newWriteFuture(wal, entries[0].Seq), nil
+}
+
+// WriteAsync applies the batch like PutAsync. The future of an empty batch is
+// already resolved. A batch with conditions must be written with DB.WriteIf.
+func (db *DB) WriteAsync(ctx context.Context, batch *WriteBatch) (*WriteFuture, error) {
+	assert.True(len(batch.conditions) == 0, "a batch with conditions must be written with WriteIf")
+	if batch.Len() == 0 {
+		done := make(chan bool)
+		close(done)
+		return &WriteFuture{done: done, seq: db.CommittedSeq()}, nil
+	}
+
+	// The writes are cloned, as the sequence numbers assigned to them must not
+	// leak into the batch, which the caller may write again
+	tombstones, entries := slices.Clone(batch.ranges), slices.Clone(batch.entries)
+	wal, err := db.writeEntriesWith(ctx, tombstones, entries, db.state.WriteEntriesToWAL)
+	if err != nil {
+		return nil, err
+	}
+	return newWriteFuture(wal, batchSeq(tombstones, entries)), nil
+}
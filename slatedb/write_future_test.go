@@ -0,0 +1,90 @@
+package slatedb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestWriteFuturesShareWAL(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024*1024)
+	options.FlushInterval = time.Hour
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	walsBefore, err := db.tableStore.GetWalSSTList(ctx, 0)
+	require.NoError(t, err)
+
+	futures := make([]*WriteFuture, 10)
+	errs := make([]error, len(futures))
+	var wg sync.WaitGroup
+	for i := range futures {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			futures[i], errs[i] = db.PutAsync(ctx, []byte(fmt.Sprintf("key-%d", i)), []byte("value"))
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, errors.Join(errs...))
+
+	for _, future := range futures {
+		select {
+		case <-future.Done():
+			t.Fatal("write resolved before the WAL was flushed")
+		default:
+		}
+	}
+
+	require.NoError(t, db.FlushWAL(ctx))
+	for _, future := range futures {
+		require.NoError(t, future.Wait(ctx))
+	}
+
+	walsAfter, err := db.tableStore.GetWalSSTList(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, len(walsBefore)+1, len(walsAfter))
+}
+
+func TestWriteFutureWaitReturnsContextError(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 1024*1024)
+	options.FlushInterval = time.Hour
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	future, err := db.DeleteAsync(ctx, []byte("key"))
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, future.Wait(waitCtx), context.DeadlineExceeded)
+
+	require.NoError(t, db.FlushWAL(ctx))
+	require.NoError(t, future.Wait(ctx))
+
+	// A write which awaits durability returns the error of the context, and is
+	// still flushed
+	waitCtx, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, db.Put(waitCtx, []byte("key"), []byte("value")), context.DeadlineExceeded)
+	require.NoError(t, db.FlushWAL(ctx))
+	val, err := db.GetWithOptions(ctx, []byte("key"), config.ReadOptions{ReadLevel: config.Committed})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), val)
+
+	empty, err := db.WriteAsync(ctx, NewWriteBatch())
+	require.NoError(t, err)
+	require.NoError(t, empty.Wait(ctx))
+}
@@ -0,0 +1,84 @@
+package slatedb
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// writeStalls stalls writes while the immutable memtables waiting to be flushed to
+// L0 reach DBOptions.MaxImmutableMemtables, so writes can't outpace the flush of
+// memtables to object storage and grow the memory of the DB without bound.
+type writeStalls struct {
+	mu sync.Mutex
+	// flushed is closed and replaced each time the memtable flush task makes
+	// progress, which wakes the stalled writes to check the queue again
+	flushed chan struct{}
+
+	count    atomic.Int64
+	duration atomic.Int64
+}
+
+func newWriteStalls() *writeStalls {
+	return &writeStalls{flushed: make(chan struct{})}
+}
+
+// notify wakes the stalled writes
+func (s *writeStalls) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.flushed)
+	s.flushed = make(chan struct{})
+}
+
+// wait blocks while full returns true, or until the context is done, and returns
+// true if the write was stalled
+func (s *writeStalls) wait(ctx context.Context, full func() bool) (bool, error) {
+	if !full() {
+		return false, nil
+	}
+
+	start := time.Now()
+	defer func() {
+		s.count.Add(1)
+		s.duration.Add(int64(time.Since(start)))
+	}()
+	for {
+		// Take the channel before checking, so a notify in between is not missed
+		s.mu.Lock()
+		flushed := s.flushed
+		s.mu.Unlock()
+		if !full() {
+			return true, nil
+		}
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
+
+// stallWrites stalls the write while the immutable memtable queue is full, and
+// returns common.ErrReadOnly if the DB is read-only, or the error of the context
+// if it is done first. The queue is not considered full once the DB is read-only,
+// so the stalled write fails rather than waiting for a flush which never happens.
+// Writes are never stalled without background tasks, as only DB.Maintenance
+// flushes the queue.
+func (db *DB) stallWrites(ctx context.Context) error {
+	if err := db.background.checkWritable(); err != nil {
+		return err
+	}
+	limit := db.opts.MaxImmutableMemtables
+	if limit <= 0 || db.maintenance != nil {
+		return nil
+	}
+	stalled, err := db.writeStalls.wait(ctx, func() bool {
+		return db.state.ImmMemtableCount() >= limit && db.background.checkWritable() == nil
+	})
+	if err != nil || !stalled {
+		return err
+	}
+	return db.background.checkWritable()
+}
@@ -0,0 +1,81 @@
+package slatedb
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/slatedb/slatedb-go/slatedb/config"
+)
+
+func TestWriteStallsWaitUntilNotified(t *testing.T) {
+	ctx := context.Background()
+	stalls := newWriteStalls()
+	stalled, err := stalls.wait(ctx, func() bool { return false })
+	require.NoError(t, err)
+	assert.False(t, stalled)
+
+	var full atomic.Bool
+	full.Store(true)
+	done := make(chan bool)
+	go func() {
+		stalled, _ := stalls.wait(ctx, full.Load)
+		done <- stalled
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("write did not stall while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	full.Store(false)
+	stalls.notify()
+	select {
+	case stalled := <-done:
+		assert.True(t, stalled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stalled write was not woken by notify")
+	}
+	assert.Equal(t, int64(1), stalls.count.Load())
+	assert.Greater(t, stalls.duration.Load(), int64(0))
+}
+
+func TestWriteStallsWaitReturnsContextError(t *testing.T) {
+	stalls := newWriteStalls()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	stalled, err := stalls.wait(ctx, func() bool { return true })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, stalled)
+	assert.Equal(t, int64(1), stalls.count.Load())
+}
+
+func TestMaxImmutableMemtables(t *testing.T) {
+	ctx := context.Background()
+	options := testDBOptions(0, 128)
+	options.MaxImmutableMemtables = 1
+	db, err := Open(ctx, "/tmp/test_kv_store", objstore.NewInMemBucket(), options)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, db.PutWithOptions(ctx, []byte(fmt.Sprintf("key-%02d", i)), repeatedChar('v', 64), config.WriteOptions{AwaitDurable: false}))
+		require.NoError(t, db.FlushWAL(ctx))
+	}
+
+	for i := 0; i < 20; i++ {
+		val, err := db.Get(ctx, []byte(fmt.Sprintf("key-%02d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, repeatedChar('v', 64), val)
+	}
+	require.Eventually(t, func() bool {
+		return db.Stats().ImmutableMemtables == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
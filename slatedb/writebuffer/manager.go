@@ -0,0 +1,96 @@
+// Package writebuffer limits the memory used by the memtables of every DB in a
+// process which shares a Manager.
+package writebuffer
+
+import (
+	"sync"
+)
+
+// Memtables is the memory used by the memtables of a DB registered with a Manager
+type Memtables interface {
+	// MemtableBytes returns the size of the mutable memtable, and the size of the
+	// immutable memtables which are waiting to be flushed to L0.
+	MemtableBytes() (mutable int64, immutable int64)
+
+	// FlushMemtable requests the mutable memtable is frozen and flushed to L0. The
+	// flush happens in the background, so the memory is released asynchronously.
+	FlushMemtable()
+}
+
+// Manager tracks the memtable memory of every DB it is shared with, through
+// DBOptions.WriteBufferManager. Once the memtables of all the DBs exceed the
+// budget, the largest mutable memtable is flushed to L0, which caps the memory
+// of a process serving many DBs without capping the memtable of any one DB.
+//
+// The budget is a soft limit, as writes are not stalled while the memtables are
+// flushed. A DB still flushes its memtable once it reaches L0SSTSizeBytes.
+type Manager struct {
+	mu      sync.Mutex
+	budget  int64
+	members map[Memtables]struct{}
+}
+
+// NewManager returns a Manager which flushes memtables once the memtables of all
+// the DBs which share the Manager exceed budgetBytes.
+func NewManager(budgetBytes uint64) *Manager {
+	return &Manager{
+		budget:  int64(budgetBytes),
+		members: make(map[Memtables]struct{}),
+	}
+}
+
+// Register adds the memtables of a DB to the Manager, called when the DB is opened
+func (m *Manager) Register(t Memtables) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members[t] = struct{}{}
+}
+
+// Unregister removes the memtables of a DB from the Manager, called when the DB is closed
+func (m *Manager) Unregister(t Memtables) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.members, t)
+}
+
+// Budget returns the number of bytes the memtables of all the DBs may use
+func (m *Manager) Budget() int64 {
+	return m.budget
+}
+
+// Usage returns the number of bytes used by the memtables of all the DBs
+func (m *Manager) Usage() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var usage int64
+	for t := range m.members {
+		mutable, immutable := t.MemtableBytes()
+		usage += mutable + immutable
+	}
+	return usage
+}
+
+// MaybeFlush requests the largest mutable memtable is flushed if the memtables of
+// all the DBs exceed the budget, and returns true if a flush was requested. Called
+// by a DB after it writes to its memtable.
+func (m *Manager) MaybeFlush() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var usage, largestSize int64
+	var largest Memtables
+	for t := range m.members {
+		mutable, immutable := t.MemtableBytes()
+		usage += mutable + immutable
+		if mutable > largestSize {
+			largest, largestSize = t, mutable
+		}
+	}
+	// Immutable memtables are already being flushed, so if the usage is over the
+	// budget while every mutable memtable is empty, there is nothing to flush.
+	if usage <= m.budget || largest == nil {
+		return false
+	}
+	largest.FlushMemtable()
+	return true
+}
@@ -0,0 +1,59 @@
+package writebuffer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slatedb/slatedb-go/slatedb/writebuffer"
+)
+
+type fakeMemtables struct {
+	mutable   int64
+	immutable int64
+	flushes   int
+}
+
+func (f *fakeMemtables) MemtableBytes() (int64, int64) {
+	return f.mutable, f.immutable
+}
+
+func (f *fakeMemtables) FlushMemtable() {
+	f.flushes++
+}
+
+func TestManagerFlushesLargestMemtable(t *testing.T) {
+	m := writebuffer.NewManager(100)
+	small := &fakeMemtables{mutable: 30, immutable: 20}
+	large := &fakeMemtables{mutable: 40}
+	m.Register(small)
+	m.Register(large)
+
+	// Within the budget nothing is flushed
+	assert.Equal(t, int64(90), m.Usage())
+	assert.False(t, m.MaybeFlush())
+
+	// Over the budget the largest mutable memtable is flushed, even though the
+	// other DB uses more memory including its immutable memtables
+	large.mutable = 45
+	small.immutable = 30
+	assert.Equal(t, int64(105), m.Usage())
+	assert.True(t, m.MaybeFlush())
+	assert.Equal(t, 1, large.flushes)
+	assert.Equal(t, 0, small.flushes)
+
+	// Once unregistered, the memtables of a DB are no longer counted
+	m.Unregister(large)
+	assert.Equal(t, int64(60), m.Usage())
+	assert.False(t, m.MaybeFlush())
+}
+
+func TestManagerSkipsEmptyMemtables(t *testing.T) {
+	m := writebuffer.NewManager(100)
+	flushing := &fakeMemtables{immutable: 150}
+	m.Register(flushing)
+
+	// The memory is held by memtables which are already being flushed
+	assert.False(t, m.MaybeFlush())
+	assert.Equal(t, 0, flushing.flushes)
+}